@@ -26,6 +26,7 @@ import (
 const DATABASE_HEARTBEAT = "AgbotDatabaseHeartBeat"
 const GOVERN_AGREEMENTS = "AgBotGovernAgreements"
 const GOVERN_ARCHIVED_AGREEMENTS = "AgBotGovernArchivedAgreements"
+const GOVERN_ROLLOUTS = "AgBotGovernRollouts"
 const SECRETS_PROVIDER = "AgbotSecretsProvider"
 const SECRETS_UPDATE = "AgbotSecretsUpdate"
 const AGENT_FILE_VERSION_UPDATE = "AgbotUpdateAgentFileVersion"
@@ -33,6 +34,8 @@ const AGENT_FILE_VERSION_UPDATE = "AgbotUpdateAgentFileVersion"
 //const GOVERN_BC_NEEDS = "AgBotGovernBlockchain"
 const POLICY_WATCHER = "AgBotPolicyWatcher"
 const STALE_PARTITIONS = "AgbotStaleDatabasePartition"
+const PARTITION_REBALANCE = "AgbotPartitionRebalance"
+const LEADER_ELECTION = "AgbotLeaderElection"
 const MESSAGE_KEY_CHECK = "AgbotMessageKeyCheck"
 
 // Agreement governance timing state. Used in the GovernAgreements subworker.
@@ -61,6 +64,7 @@ type AgreementBotWorker struct {
 	nodeSearch           *NodeSearch // The object that controls node searches and the state of search sessions.
 	secretProvider       secrets.AgbotSecrets
 	secretUpdateManager  *SecretUpdateManager
+	leader               *LeaderElection // Tracks whether this agbot runs the singleton background tasks.
 }
 
 func NewAgreementBotWorker(name string, cfg *config.HorizonConfig, db persistence.AgbotDatabase, s secrets.AgbotSecrets) *AgreementBotWorker {
@@ -82,6 +86,7 @@ func NewAgreementBotWorker(name string, cfg *config.HorizonConfig, db persistenc
 		nodeSearch:           NewNodeSearch(),
 		secretProvider:       s,
 		secretUpdateManager:  NewSecretUpdateManager(),
+		leader:               NewLeaderElection(),
 	}
 
 	patternManager = NewPatternManager()
@@ -342,7 +347,7 @@ func (w *AgreementBotWorker) Initialize() bool {
 
 	// Give the policy manager a chance to read in all the policies. The agbot worker will not proceed past this point
 	// until it has some policies to work with.
-	businessPolManager = NewBusinessPolicyManager(w.Messages())
+	businessPolManager = NewBusinessPolicyManager(w.Messages(), w.db)
 	w.MMSObjectPM = NewMMSObjectPolicyManager(w.BaseWorker.Manager.Config)
 	for {
 
@@ -377,7 +382,7 @@ func (w *AgreementBotWorker) Initialize() bool {
 	glog.Info("AgreementBot worker started")
 
 	// Tell the node search component to initialize itself.
-	w.nodeSearch.Init(w.db, w.pm, w.consumerPH, w.Messages(), w, w.Config)
+	w.nodeSearch.Init(w.db, w.pm, w.consumerPH, w.Messages(), w, w.Config, w.leader)
 
 	// Make sure that our public key is registered in the exchange so that other parties
 	// can send us messages.
@@ -410,12 +415,22 @@ func (w *AgreementBotWorker) Initialize() bool {
 	// Start the go thread that checks for stale partitions.
 	w.DispatchSubworker(STALE_PARTITIONS, w.stalePartitions, int(w.BaseWorker.Manager.Config.GetPartitionStale()), false)
 
+	// Start the go thread that evens out the agreements across the agbots, if it is configured.
+	if w.Config.AgreementBot.PartitionRebalanceS != 0 {
+		w.DispatchSubworker(PARTITION_REBALANCE, w.rebalancePartitions, w.Config.AgreementBot.PartitionRebalanceS, false)
+	}
+
+	// Start the go thread that elects the agbot that runs the singleton background tasks.
+	w.electLeader()
+	w.DispatchSubworker(LEADER_ELECTION, w.electLeader, int(w.BaseWorker.Manager.Config.GetPartitionStale()/3), false)
+
 	// The agbot worker is now ready to handle incoming messages
 	w.ready = true
 
 	// Start the governance routines using the subworker APIs.
 	w.DispatchSubworker(GOVERN_AGREEMENTS, w.GovernAgreements, int(w.BaseWorker.Manager.Config.AgreementBot.ProcessGovernanceIntervalS), false)
 	w.DispatchSubworker(GOVERN_ARCHIVED_AGREEMENTS, w.GovernArchivedAgreements, 1800, false)
+	w.DispatchSubworker(GOVERN_ROLLOUTS, w.governRollouts, 60, false)
 	//w.DispatchSubworker(GOVERN_BC_NEEDS, w.GovernBlockchainNeeds, 60, false)
 	w.DispatchSubworker(MESSAGE_KEY_CHECK, w.messageKeyCheck, w.BaseWorker.Manager.Config.AgreementBot.MessageKeyCheck, false)
 	w.DispatchSubworker(SECRETS_UPDATE, w.secretsUpdate, w.BaseWorker.Manager.Config.GetSecretsUpdateCheck(), false)
@@ -1345,7 +1360,7 @@ func (w *AgreementBotWorker) handleObjectPoliciesChange(msg *events.MMSObjectPol
 	org := objPolChanges[0].OrgID
 
 	// Check for policy metadata changes and update policies accordingly. Publish any status change events.
-	if events, err := w.MMSObjectPM.UpdatePolicies(org, &objPolChanges, exchange.GetHTTPObjectQueryHandler(w)); err != nil {
+	if events, err := w.MMSObjectPM.UpdatePolicies(org, &objPolChanges, exchange.GetHTTPObjectQueryHandler(w), w.leader.IsLeader()); err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("unable to update object policies for org %v, error %v", org, err)))
 	} else {
 		for _, ev := range events {
@@ -1435,8 +1450,11 @@ func (w *AgreementBotWorker) stalePartitions() int {
 	if hb, err := w.db.GetHeartbeat(); err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("Error obtaining heartbeat, error: %v", err)))
 	} else if (now - hb) < w.BaseWorker.Manager.Config.GetPartitionStale() {
-		// The heartbeat has been occurring, so it's safe to attempt to take-over an unused partition.
-		if claimed, err := w.db.MovePartition(w.Config.GetPartitionStale()); err != nil {
+		// The heartbeat has been occurring, so it's safe to attempt to take-over an unused partition, unless the
+		// rebalancer is still spreading its agreements across the running agbots.
+		if !w.takeOverPartitions() {
+			glog.V(3).Infof(AWlogString("leaving the unowned partitions to the partition rebalancer"))
+		} else if claimed, err := w.db.MovePartition(w.Config.GetPartitionStale()); err != nil {
 			glog.Errorf(AWlogString(fmt.Sprintf("Error claiming an unowned partition, error: %v", err)))
 		} else if claimed {
 			// Perform the same sanity checks on existing agreements when we pick up a new set of agreements
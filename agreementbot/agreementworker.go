@@ -19,6 +19,7 @@ import (
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/structlog"
 	"github.com/open-horizon/anax/worker"
 	"golang.org/x/text/message"
 	"math/rand"
@@ -372,6 +373,15 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 	if wi.ConsumerPolicy.PatternId == "" {
 		// non pattern case
 
+		// The remaining capacity of the node is a dynamic property, it is computed from the agreements with the node.
+		if usesNodeCapacity(wi.ConsumerPolicy.Constraints) {
+			if capacity, err := findNodeCapacity(b.db, nodePolicy, wi.Device.Id); err != nil {
+				glog.Warningf(BAWlogstring(workerId, fmt.Sprintf("unable to compute the capacity of node %v, error: %v", wi.Device.Id, err)))
+			} else {
+				addNodeCapacityProperties(nodePolicy, capacity)
+			}
+		}
+
 		// If a deployment policy is being used and multiple service versions are possible, do an initial check of just the policy constraints of the deployment policy
 		// with the node properties to see if those match before we get too far invested in checking matches of all the different service versions.
 		// In the case were have thousands of deployment policies, this can avoid lots of calls to check and create workload_usages in the DB if there isn't a match at this level
@@ -653,9 +663,19 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 			}
 		}
 
+		// If the workload is being rolled out in stages and the node's stage has not started yet, keep the node on a lower
+		// priority workload for now. The governance routine will cancel the agreement when it is time to upgrade the node.
+		rollout_match := true
+		if policy_match && userInput_match && wi.ConsumerPolicy.PatternId == "" {
+			if held, reason := businessPolManager.Rollouts.HoldsNode(wi.ConsumerPolicy.Header.Name, workload.Version, wi.Device.Id, nodePolicy.Properties); held {
+				glog.Infof(BAWlogstring(workerId, fmt.Sprintf("skipping workload %v for device %v because %v", workload.Version, wi.Device.Id, reason)))
+				rollout_match = false
+			}
+		}
+
 		// Make sure the deployment policy or pattern has all the right secret bindings in place and extract the secret details for the agent.
 		secrets_match := true
-		if policy_match && userInput_match && nodeType == persistence.DEVICE_TYPE_DEVICE {
+		if policy_match && userInput_match && rollout_match && nodeType == persistence.DEVICE_TYPE_DEVICE {
 
 			err := b.ValidateAndExtractSecrets(&wi.ConsumerPolicy, wi.Device.Id, &topSvcDef, depServices, workerId, msgPrinter)
 			if err != nil {
@@ -665,7 +685,7 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 		}
 
 		// All the error cases have been checked, now decide whether to propose this workload or try another version
-		if !policy_match || !userInput_match || !secrets_match {
+		if !policy_match || !userInput_match || !rollout_match || !secrets_match {
 			if !workload.HasEmptyPriority() {
 				// If this is not the first time through the loop, update the workload usage record, otherwise create it.
 				if lastWorkload != nil {
@@ -725,6 +745,11 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 			} else {
 				workload.Deployment = workloadDetails.GetDeploymentString()
 				workload.DeploymentSignature = workloadDetails.GetDeploymentSignature()
+
+				// Ask the node to run the images that the first node resolved, if any node has resolved them yet.
+				if wi.ConsumerPolicy.PinImageDigests {
+					workload.ImageDigests = businessPolManager.ImageDigests.GetDigests(wi.ConsumerPolicy.Header.Name, workload.Version, workload.Arch)
+				}
 			}
 
 			if glog.V(5) {
@@ -764,8 +789,10 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 	}
 
 	// Create pending agreement in database
+	agLogger := bawLogger(workerId, agreementIdString).With(structlog.DEVICE_ID, wi.Device.Id)
+	agLogger.V(3).Infof("initiating agreement with policy %v", wi.ConsumerPolicy.Header.Name)
 	if err := b.db.AgreementAttempt(agreementIdString, wi.Org, wi.Device.Id, nodeType, wi.ConsumerPolicy.Header.Name, bcType, bcName, bcOrg, cph.Name(), wi.ConsumerPolicy.PatternId, svcIds, wi.ConsumerPolicy.NodeH, b.config.AgreementBot.GetProtocolTimeout(nodeMaxHBInterval), b.config.AgreementBot.GetAgreementTimeout(nodeMaxHBInterval)); err != nil {
-		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error persisting agreement attempt: %v", err)))
+		agLogger.Errorf("error persisting agreement attempt: %v", err)
 
 		// Decoding device publicKey to []byte
 	} else if publicKeyBytes, err := base64.StdEncoding.DecodeString(wi.Device.PublicKey); err != nil {
@@ -777,7 +804,7 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 
 		// Initiate the protocol
 	} else if proposal, err := protocolHandler.InitiateAgreement(agreementIdString, &wi.ProducerPolicy, &wi.ConsumerPolicy, wi.Org, cph.GetExchangeId(), mt, workload, b.config.AgreementBot.DefaultWorkloadPW, b.config.AgreementBot.NoDataIntervalS, cph.GetSendMessage()); err != nil {
-		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error initiating agreement: %v", err)))
+		agLogger.Errorf("error initiating agreement: %v", err)
 
 		// Remove pending agreement from database
 		if err := b.db.DeleteAgreement(agreementIdString, cph.Name()); err != nil {
@@ -835,6 +862,10 @@ func (b *BaseAgreementWorker) SetServicePolicyPrivilege(svcPolicy *externalpolic
 	if runtimePriv {
 		svcPolicy.Constraints.Add_Constraint(fmt.Sprintf("%s = %t", externalpolicy.PROP_SVC_PRIVILEGED, runtimePriv))
 	}
+
+	if err := compcheck.AddContainerOptionConstraints(svcPolicy, topSvc, topSvcId, depServiceDefs, msgPrinter); err != nil {
+		return nil, err
+	}
 	return svcPolicy, nil
 }
 
@@ -1276,12 +1307,12 @@ func (b *BaseAgreementWorker) CancelAgreementWithLock(cph ConsumerProtocolHandle
 func (b *BaseAgreementWorker) CancelAgreement(cph ConsumerProtocolHandler, agreementId string, reason uint, workerId string) bool {
 
 	// Start timing out the agreement
-	glog.V(3).Infof(BAWlogstring(workerId, fmt.Sprintf("terminating agreement %v reason: %v.", agreementId, cph.GetTerminationReason(reason))))
+	bawLogger(workerId, agreementId).V(3).Infof("terminating agreement %v reason: %v.", agreementId, cph.GetTerminationReason(reason))
 
 	// Update the database. Returns an error if the agreement is not found.
 	ag, err := b.db.AgreementTimedout(agreementId, cph.Name())
 	if err != nil {
-		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error marking agreement %v terminated: %v", agreementId, err)))
+		bawLogger(workerId, agreementId).Errorf("error marking agreement %v terminated: %v", agreementId, err)
 	} else if ag != nil && ag.Archived {
 		// The agreement is not active and it is archived, so this message belongs to this agbot, but the cancel has already happened
 		// so we should just get rid of the protocol msg.
@@ -1389,6 +1420,11 @@ var BAWlogstring = func(workerID string, v interface{}) string {
 	return fmt.Sprintf("Base Agreement Worker (%v): %v", workerID, v)
 }
 
+// Returns a structured logger for the agreement worker, correlated to the agreement.
+func bawLogger(workerID string, agreementId string) *structlog.Logger {
+	return structlog.New(fmt.Sprintf("Base Agreement Worker (%v)", workerID)).With(structlog.AGREEMENT_ID, agreementId)
+}
+
 // This function checks the Exchange for every declared HA partner to verify that the partner is registered in the
 // exchange. As long as all partners are registered, agreements can be made. The partners dont have to be up and heart
 // beating, they just have to be registered. If not all partners are registered then no agreements will be attempted
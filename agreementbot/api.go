@@ -10,13 +10,16 @@ import (
 	"github.com/open-horizon/anax/agreementbot/secrets"
 	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/metrics"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/worker"
 	"io/ioutil"
 	"net/http"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -52,6 +55,7 @@ func NewAPIListener(name string, config *config.HorizonConfig, db persistence.Ag
 		secretProvider: s,
 	}
 
+	listener.registerMetricsCollector()
 	listener.listen(config.AgreementBot.APIListen)
 	return listener
 }
@@ -327,8 +331,10 @@ func (a *API) listen(apiListen string) {
 		router := mux.NewRouter()
 
 		router.HandleFunc("/agreement", a.agreement).Methods("GET", "OPTIONS")
+		router.HandleFunc("/agreement/history", a.agreementhistory).Methods("GET", "OPTIONS")
 		router.HandleFunc("/agreement/{id}", a.agreement).Methods("GET", "DELETE", "OPTIONS")
 		router.HandleFunc("/partition", a.partition).Methods("GET", "OPTIONS")
+		router.HandleFunc("/partition/drain", a.partitiondrain).Methods("POST", "OPTIONS")
 		router.HandleFunc("/policy", a.policy).Methods("GET", "OPTIONS")
 		router.HandleFunc("/policy/{org}", a.policy).Methods("GET", "OPTIONS")
 		router.HandleFunc("/policy/{org}/{name}", a.policy).Methods("GET", "OPTIONS")
@@ -337,6 +343,7 @@ func (a *API) listen(apiListen string) {
 		router.HandleFunc("/status", a.status).Methods("GET", "OPTIONS")
 		router.HandleFunc("/health", a.health).Methods("GET", "OPTIONS")
 		router.HandleFunc("/status/workers", a.workerstatus).Methods("GET", "OPTIONS")
+		router.HandleFunc("/metrics", a.metrics).Methods("GET", "OPTIONS")
 		router.HandleFunc("/node", a.node).Methods("GET", "DELETE", "OPTIONS")
 		router.HandleFunc("/config", a.config).Methods("GET", "OPTIONS")
 		router.HandleFunc("/cache/servedorg", a.ListServedOrgs).Methods("GET", "OPTIONS")
@@ -374,6 +381,30 @@ func (a *API) agreement(w http.ResponseWriter, r *http.Request) {
 				// write output
 				writeResponse(w, *ag, http.StatusOK)
 			}
+		} else if r.URL.RawQuery != "" {
+			// A page of the agreements that match the query parameters.
+			query, inputErr := getAgreementQuery(r)
+			if inputErr != nil {
+				writeInputErr(w, http.StatusBadRequest, inputErr)
+				return
+			}
+
+			ags, total, err := a.db.FindAgreementsPage(*query, policy.AllAgreementProtocols())
+			if err != nil {
+				glog.Error(APIlogString(fmt.Sprintf("error finding agreements for %v, error: %v", query, err)))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			for i := range ags {
+				if ags[i].Proposal, err = abstractprotocol.ObscureProposalSecret(ags[i].Proposal); err != nil {
+					glog.Error(APIlogString(fmt.Sprintf("failed to obscure secret details, error: %v", err)))
+				} else if ags[i].Policy, err = policy.ObscureSecretDetails(ags[i].Policy); err != nil {
+					glog.Error(APIlogString(fmt.Sprintf("failed to obscure secret details, error: %v", err)))
+				}
+			}
+
+			writeResponse(w, AgreementPage{Agreements: ags, Total: total, Offset: query.Offset, Limit: query.Limit}, http.StatusOK)
 		} else {
 			var agreementsKey = "agreements"
 			var archivedKey = "archived"
@@ -452,6 +483,53 @@ func (a *API) agreement(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Query the durable history of agreement lifecycle transitions. The query parameters are all optional:
+// node (org/id), policy (org/name), service (url or org/url), since and until (seconds since the epoch) and limit.
+func (a *API) agreementhistory(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		query := persistence.AgreementHistoryQuery{
+			DeviceId:   r.URL.Query().Get("node"),
+			PolicyName: r.URL.Query().Get("policy"),
+			Service:    r.URL.Query().Get("service"),
+		}
+
+		var err error
+		if since := r.URL.Query().Get("since"); since != "" {
+			if query.Since, err = strconv.ParseUint(since, 10, 64); err != nil {
+				writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "since", Error: fmt.Sprintf("must be the number of seconds since the epoch, error: %v", err)})
+				return
+			}
+		}
+		if until := r.URL.Query().Get("until"); until != "" {
+			if query.Until, err = strconv.ParseUint(until, 10, 64); err != nil {
+				writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "until", Error: fmt.Sprintf("must be the number of seconds since the epoch, error: %v", err)})
+				return
+			}
+		}
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 0 {
+				writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "limit", Error: "must be a non-negative integer"})
+				return
+			}
+		}
+
+		if records, err := a.db.FindAgreementHistory(query); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error finding agreement history for %v, error: %v", query, err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else {
+			writeResponse(w, records, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func (a *API) policy(w http.ResponseWriter, r *http.Request) {
 
 	serviceResolver := func(wURL string, wOrg string, wVersion string, wArch string) (*policy.APISpecList, error) {
@@ -604,7 +682,17 @@ func (a *API) workloadusage(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		if wlusages, err := a.db.FindWorkloadUsages([]persistence.WUFilter{}); err != nil {
+		if r.URL.RawQuery != "" {
+			// A page of the workload usages that match the query parameters.
+			if query, inputErr := getWorkloadUsageQuery(r); inputErr != nil {
+				writeInputErr(w, http.StatusBadRequest, inputErr)
+			} else if wlusages, total, err := a.db.FindWorkloadUsagesPage(*query); err != nil {
+				glog.Error(APIlogString(fmt.Sprintf("error finding workload usages for %v, error: %v", query, err)))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			} else {
+				writeResponse(w, WorkloadUsagePage{WorkloadUsages: wlusages, Total: total, Offset: query.Offset, Limit: query.Limit}, http.StatusOK)
+			}
+		} else if wlusages, err := a.db.FindWorkloadUsages([]persistence.WUFilter{}); err != nil {
 			glog.Error(APIlogString(fmt.Sprintf("error finding all workload usages, error: %v", err)))
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		} else {
@@ -638,6 +726,18 @@ func (a *API) status(w http.ResponseWriter, r *http.Request) {
 		}
 		info.LiveHealth = health
 
+		// The agbot that runs the singleton background tasks.
+		if leader, err := a.db.GetLeadership(); err != nil {
+			glog.Errorf(APIlogString(fmt.Sprintf("Unable to get the leader, error: %v", err)))
+		} else if leader != nil {
+			info.Leader = &apicommon.LeaderInfo{
+				Instance:      leader.Owner,
+				Partition:     leader.Partition,
+				LastHeartbeat: leader.Heartbeat,
+				ThisAgbot:     leader.Partition != "" && leader.Partition == a.db.PrimaryPartition(),
+			}
+		}
+
 		writeResponse(w, info, http.StatusOK)
 	case "OPTIONS":
 		w.Header().Set("Allow", "GET, OPTIONS")
@@ -685,6 +785,30 @@ func (a *API) workerstatus(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (a *API) metrics(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		metrics.DefaultRegistry().Handler().ServeHTTP(w, r)
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Refresh the agbot specific metrics from the database whenever the metrics are scraped.
+func (a *API) registerMetricsCollector() {
+	if a.db == nil {
+		return
+	}
+	metrics.DefaultRegistry().RegisterCollector("agbot", func() {
+		if err := UpdateAgbotMetrics(a.db); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("unable to collect agbot metrics, error: %v", err)))
+		}
+	})
+}
+
 func (a *API) node(w http.ResponseWriter, r *http.Request) {
 
 	resource := "node"
@@ -780,6 +904,8 @@ func (a *API) partition(w http.ResponseWriter, r *http.Request) {
 		// For each partition, how many agreements and other objects are in it. The top level keys in the output
 		// are the partition names, the sub maps are for each of agreements, workload usage, etc.
 		const PARTITION_OWNER = "owner"
+		const PARTITION_HEARTBEAT_AGE = "heartbeat age"
+		const PARTITION_THIS_AGBOT = "this agbot"
 		const AGREEMENT_ACTIVE_KEY = "active agreements"
 		const AGREEMENT_ARCHIVED_KEY = "archived agreements"
 		const WORKLOAD_USAGES_KEY = "workload usages"
@@ -789,12 +915,39 @@ func (a *API) partition(w http.ResponseWriter, r *http.Request) {
 		if partitions, err := a.db.FindPartitions(); err != nil {
 			glog.Error(APIlogString(fmt.Sprintf("error finding all partitions, error: %v", err)))
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else if ownership, err := a.db.FindPartitionOwnership(); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error finding partition ownership, error: %v", err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		} else {
 
-			// For each partition, get a count of records in the partition.
+			// The partitions of the agbots that did not make any agreements yet are only in the ownership list.
+			heartbeats := make(map[string]uint64, len(ownership))
+			for _, o := range ownership {
+				heartbeats[o.Id] = o.Heartbeat
+				if !cutil.SliceContains(partitions, o.Id) {
+					partitions = append(partitions, o.Id)
+				}
+			}
+			now := uint64(time.Now().Unix())
+
+			// For each partition, get a count of records in the partition. A partition can be listed more than once.
 			for _, p := range partitions {
+				if _, ok := output[p]; ok {
+					continue
+				}
 				partitionMaps := make(map[string]interface{}, 0)
 
+				// The age of the last heartbeat shows whether the owner is still running. A partition without an
+				// owner does not have a heartbeat.
+				if hb := heartbeats[p]; hb != 0 {
+					if hb >= now {
+						partitionMaps[PARTITION_HEARTBEAT_AGE] = 0
+					} else {
+						partitionMaps[PARTITION_HEARTBEAT_AGE] = now - hb
+					}
+				}
+				partitionMaps[PARTITION_THIS_AGBOT] = p == a.db.PrimaryPartition()
+
 				// First get the partition owner.
 				if owner, err := a.db.GetPartitionOwner(p); err != nil {
 					glog.Error(APIlogString(fmt.Sprintf("error finding partition %v owner, error: %v", p, err)))
@@ -839,6 +992,62 @@ func (a *API) partition(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Drain this agbot before it is stopped for maintenance. The agbot quiesces the same way as DELETE /node, so that it
+// stops making agreements and releases its partition. The agreements in the released partition are then taken over
+// by the other running agbots.
+func (a *API) partitiondrain(w http.ResponseWriter, r *http.Request) {
+
+	resource := "partition/drain"
+
+	switch r.Method {
+	case "POST":
+		glog.V(5).Infof(APIlogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		// Get the blocking option from the URL query parameters. If blocking is true, then the API will block
+		// until the Agbot quiesce is complete. True is the default.
+		block := r.URL.Query().Get("block")
+		if block != "" && block != "true" && block != "false" {
+			writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "block", Error: "must be true or false"})
+			return
+		}
+
+		// There has to be another running agbot to take over the agreements.
+		if loads, err := findPartitionLoads(a.db, a.Config.GetPartitionStale()); err != nil {
+			glog.Error(APIlogString(err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		} else if !otherLivePartition(a.db.PrimaryPartition(), loads) {
+			writeInputErr(w, http.StatusConflict, &APIUserInputError{Input: "partition", Error: "there is no other running agbot to take over the agreements"})
+			return
+		}
+		glog.Infof(APIlogString(fmt.Sprintf("draining partition %v", a.db.PrimaryPartition())))
+
+		// Quiesce the agbot so that it stops making agreements and releases its partition.
+		a.Messages() <- events.NewNodeShutdownMessage(events.START_AGBOT_QUIESCE, block != "false", false)
+
+		// Wait (if allowed) for the ShutdownComplete event
+		if block != "false" {
+			se := events.NewNodeShutdownCompleteMessage(events.AGBOT_QUIESCE_COMPLETE, "")
+			for {
+				if a.em.ReceivedEvent(se, nil) {
+					break
+				}
+				glog.V(5).Infof(APIlogString(fmt.Sprintf("Waiting for agbot shutdown to complete")))
+				time.Sleep(5 * time.Second)
+			}
+		}
+
+		glog.V(5).Infof(APIlogString(fmt.Sprintf("Handled %v on resource %v", r.Method, resource)))
+		writeResponse(w, map[string]interface{}{"partition": a.db.PrimaryPartition()}, http.StatusOK)
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 // ==========================================================================================
 // Utility functions used by many of the API endpoints.
 //
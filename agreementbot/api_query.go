@@ -0,0 +1,125 @@
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"net/http"
+	"strconv"
+)
+
+// The query parameters that select a page of the agreements or the workload usages. Without any of them, the APIs
+// return all the records.
+const (
+	QP_NODE    = "node"
+	QP_ORG     = "org"
+	QP_POLICY  = "policy"
+	QP_SERVICE = "service"
+	QP_VERSION = "version"
+	QP_STATE   = "state"
+	QP_SINCE   = "since"
+	QP_UNTIL   = "until"
+	QP_SORT    = "sort"
+	QP_ORDER   = "order"
+	QP_OFFSET  = "offset"
+	QP_LIMIT   = "limit"
+)
+
+// A page of the agreements that match the query parameters.
+type AgreementPage struct {
+	Agreements []persistence.Agreement `json:"agreements"`
+	Total      int                     `json:"total"`  // the number of agreements that match the query
+	Offset     int                     `json:"offset"` // the number of agreements before this page
+	Limit      int                     `json:"limit"`  // the maximum number of agreements in a page, 0 for no limit
+}
+
+// A page of the workload usages that match the query parameters.
+type WorkloadUsagePage struct {
+	WorkloadUsages []persistence.WorkloadUsage `json:"workloadUsages"`
+	Total          int                         `json:"total"`  // the number of workload usages that match the query
+	Offset         int                         `json:"offset"` // the number of workload usages before this page
+	Limit          int                         `json:"limit"`  // the maximum number of workload usages in a page, 0 for no limit
+}
+
+// Returns the agreement query from the query parameters of the request.
+func getAgreementQuery(r *http.Request) (*persistence.AgreementQuery, *APIUserInputError) {
+	params := r.URL.Query()
+	query := &persistence.AgreementQuery{
+		DeviceId:       params.Get(QP_NODE),
+		Org:            params.Get(QP_ORG),
+		PolicyName:     params.Get(QP_POLICY),
+		ServiceURL:     params.Get(QP_SERVICE),
+		ServiceVersion: params.Get(QP_VERSION),
+		State:          params.Get(QP_STATE),
+		Sort:           params.Get(QP_SORT),
+	}
+
+	var inputErr *APIUserInputError
+	if query.Since, inputErr = getTimeParam(r, QP_SINCE); inputErr != nil {
+		return nil, inputErr
+	} else if query.Until, inputErr = getTimeParam(r, QP_UNTIL); inputErr != nil {
+		return nil, inputErr
+	} else if query.Descending, query.Offset, query.Limit, inputErr = getPageParams(r); inputErr != nil {
+		return nil, inputErr
+	} else if err := query.Validate(); err != nil {
+		return nil, &APIUserInputError{Input: "query", Error: err.Error()}
+	}
+	return query, nil
+}
+
+// Returns the workload usage query from the query parameters of the request.
+func getWorkloadUsageQuery(r *http.Request) (*persistence.WorkloadUsageQuery, *APIUserInputError) {
+	params := r.URL.Query()
+	query := &persistence.WorkloadUsageQuery{
+		DeviceId:   params.Get(QP_NODE),
+		Org:        params.Get(QP_ORG),
+		PolicyName: params.Get(QP_POLICY),
+		Sort:       params.Get(QP_SORT),
+	}
+
+	var inputErr *APIUserInputError
+	if query.Descending, query.Offset, query.Limit, inputErr = getPageParams(r); inputErr != nil {
+		return nil, inputErr
+	} else if err := query.Validate(); err != nil {
+		return nil, &APIUserInputError{Input: "query", Error: err.Error()}
+	}
+	return query, nil
+}
+
+// Returns the time in seconds since the epoch of a query parameter, or 0 when it is not specified.
+func getTimeParam(r *http.Request, name string) (uint64, *APIUserInputError) {
+	if value := r.URL.Query().Get(name); value == "" {
+		return 0, nil
+	} else if t, err := strconv.ParseUint(value, 10, 64); err != nil {
+		return 0, &APIUserInputError{Input: name, Error: fmt.Sprintf("must be the number of seconds since the epoch, error: %v", err)}
+	} else {
+		return t, nil
+	}
+}
+
+// Returns the sort order, offset and limit query parameters.
+func getPageParams(r *http.Request) (bool, int, int, *APIUserInputError) {
+	params := r.URL.Query()
+
+	descending := false
+	switch order := params.Get(QP_ORDER); order {
+	case "", "asc":
+	case "desc":
+		descending = true
+	default:
+		return false, 0, 0, &APIUserInputError{Input: QP_ORDER, Error: "must be asc or desc"}
+	}
+
+	offset, limit := 0, 0
+	var err error
+	if value := params.Get(QP_OFFSET); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			return false, 0, 0, &APIUserInputError{Input: QP_OFFSET, Error: "must be a non-negative integer"}
+		}
+	}
+	if value := params.Get(QP_LIMIT); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			return false, 0, 0, &APIUserInputError{Input: QP_LIMIT, Error: "must be a non-negative integer"}
+		}
+	}
+	return descending, offset, limit, nil
+}
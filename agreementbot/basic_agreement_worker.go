@@ -1,6 +1,7 @@
 package agreementbot
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/agreementbot/persistence"
@@ -360,18 +361,27 @@ func (a *BasicAgreementWorker) start(work *PrioritizedWorkQueue, random *rand.Ra
 
 			// Assume the original message is always deleted.
 			deleteMessage := true
+			sendReply := true
+			accepted := false
 
-			// Agreement update request received from an agent.
-			glog.V(3).Infof(bwlogstring(a.workerID, fmt.Sprintf("no support for agreement update %v from an agent, replying with rejection.", wi.Update.ShortString())))
+			// Agreement update request received from an agent. The only update that an agent can send is the image digests
+			// that it resolved for the service of the agreement, any other update is rejected.
+			if wi.Update.IsImageDigestsUpdate() {
+				accepted, deleteMessage = a.recordImageDigests(&wi)
+				sendReply = deleteMessage
+			} else {
+				glog.V(3).Infof(bwlogstring(a.workerID, fmt.Sprintf("no support for agreement update %v from an agent, replying with rejection.", wi.Update.ShortString())))
+			}
 
-			// Always reply not accepted.
-			accepted := false
-			if mt, err := exchange.CreateMessageTarget(wi.SenderId, nil, wi.SenderPubKey, wi.From); err != nil {
-				glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error creating message target: %v", err)))
-			} else if aph, ok := a.protocolHandler.AgreementProtocolHandler("", "", "").(*basicprotocol.ProtocolHandler); !ok {
-				glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error casting to basic protocol handler (%T): %v", a.protocolHandler.AgreementProtocolHandler("", "", ""), err)))
-			} else if err := aph.SendAgreementUpdateReply(wi.Update.AgreementId(), wi.Update.UpdateType(), accepted, mt, a.protocolHandler.GetSendMessage()); err != nil {
-				glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error trying to send agreement update reply for %v to %v, error: %v", wi.Update.ShortString(), mt, err)))
+			// The agbot that owns the agreement replies to the update.
+			if sendReply {
+				if mt, err := exchange.CreateMessageTarget(wi.SenderId, nil, wi.SenderPubKey, wi.From); err != nil {
+					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error creating message target: %v", err)))
+				} else if aph, ok := a.protocolHandler.AgreementProtocolHandler("", "", "").(*basicprotocol.ProtocolHandler); !ok {
+					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error casting to basic protocol handler (%T): %v", a.protocolHandler.AgreementProtocolHandler("", "", ""), err)))
+				} else if err := aph.SendAgreementUpdateReply(wi.Update.AgreementId(), wi.Update.UpdateType(), accepted, mt, a.protocolHandler.GetSendMessage()); err != nil {
+					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error trying to send agreement update reply for %v to %v, error: %v", wi.Update.ShortString(), mt, err)))
+				}
 			}
 
 			// Get rid of the original agreement update message.
@@ -442,6 +452,36 @@ func (a *BasicAgreementWorker) start(work *PrioritizedWorkQueue, random *rand.Ra
 
 }
 
+// Record the image digests that the node resolved for the service of the agreement. The governance routine pins them
+// and checks them against the pin. Returns whether the update is accepted, and false for the second value when the
+// agreement belongs to another agbot.
+func (a *BasicAgreementWorker) recordImageDigests(wi *BAgreementUpdate) (bool, bool) {
+
+	// Get the agreement id lock to prevent any other thread from processing this same agreement.
+	lock := a.alm.getAgreementLock(wi.Update.AgreementId())
+	lock.Lock()
+	defer lock.Unlock()
+
+	var record persistence.ImageDigestRecord
+	if agreement, err := a.db.FindSingleAgreementByAgreementId(wi.Update.AgreementId(), a.protocolHandler.Name(), []persistence.AFilter{persistence.UnarchivedAFilter()}); err != nil {
+		glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error querying agreement %v, error: %v", wi.Update.AgreementId(), err)))
+		return false, true
+	} else if agreement == nil {
+		// Agreement must belong to other agbot
+		return false, false
+	} else if bytes, err := json.Marshal(wi.Update.Metadata); err != nil {
+		glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("agreement %v, unable to marshal update, error: %v", wi.Update.AgreementId(), err)))
+	} else if err := json.Unmarshal(bytes, &record); err != nil || len(record.Digests) == 0 {
+		glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("agreement %v, unable to unmarshal image digests update %v, error: %v", wi.Update.AgreementId(), wi.Update.Metadata, err)))
+	} else if _, err := a.db.AgreementImageDigests(wi.Update.AgreementId(), a.protocolHandler.Name(), &record); err != nil {
+		glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("unable to save the image digests for %v, error: %v", wi.Update.AgreementId(), err)))
+	} else {
+		glog.V(5).Infof(bwlogstring(a.workerID, fmt.Sprintf("recorded image digests %v for agreement %v", record, wi.Update.AgreementId())))
+		return true, true
+	}
+	return false, true
+}
+
 var bwlogstring = func(workerID string, v interface{}) string {
 	return fmt.Sprintf("BasicAgreementWorker (%v): %v", workerID, v)
 }
@@ -72,6 +72,8 @@ func (c *BasicProtocolHandler) Initialize() {
 		go agw.start(c.Work, random)
 	}
 
+	registerWorkQueueMetrics(c.Name(), c.Work)
+
 	worker.GetWorkerStatusManager().SetWorkerStatus("BasicProtocolHandler", worker.STATUS_INITIALIZED)
 }
 
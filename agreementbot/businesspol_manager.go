@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
 	"github.com/open-horizon/anax/businesspolicy"
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/events"
@@ -254,6 +255,9 @@ type BusinessPolicyManager struct {
 	eventChannel   chan events.Message                        // for sending policy change messages
 	ServedPolicies map[string]exchange.ServedBusinessPolicy   // served node org, business policy org and business policy triplets. The key is the triplet exchange id.
 	OrgPolicies    map[string]map[string]*BusinessPolicyEntry // all served policies by this agbot. The first key is org, the second key is business policy exchange id without org.
+
+	Rollouts     *RolloutManager     // the staged rollouts of the service versions in the served policies
+	ImageDigests *ImageDigestManager // the pinned image digests of the served policies that require the nodes to run the same images
 }
 
 func (pm *BusinessPolicyManager) String() string {
@@ -295,10 +299,12 @@ func (pm *BusinessPolicyManager) ShortString() string {
 	return res
 }
 
-func NewBusinessPolicyManager(eventChannel chan events.Message) *BusinessPolicyManager {
+func NewBusinessPolicyManager(eventChannel chan events.Message, db persistence.AgbotDatabase) *BusinessPolicyManager {
 	pm := &BusinessPolicyManager{
 		OrgPolicies:  make(map[string]map[string]*BusinessPolicyEntry),
 		eventChannel: eventChannel,
+		Rollouts:     NewRolloutManager(db),
+		ImageDigests: NewImageDigestManager(db),
 	}
 	return pm
 }
@@ -617,6 +623,14 @@ func (pm *BusinessPolicyManager) updateBusinessPolicy(org string, polId string,
 				// notify the policy manager
 				polManager.UpdatePolicy(org, newPol)
 
+				// start or update the staged rollout before the agreements are re-negotiated
+				pm.Rollouts.UpdateRollout(polId, pol)
+
+				// the pinned images are no longer needed when the policy stops pinning them
+				if !pol.Service.PinImageDigests {
+					pm.ImageDigests.DeletePins(polId)
+				}
+
 				// send a message so that other process can handle it by re-negotiating agreements
 				glog.V(3).Infof(fmt.Sprintf("Policy manager detected changed business policy %v", polId))
 				if policyString, err := policy.MarshalPolicy(newPol); err != nil {
@@ -638,6 +652,9 @@ func (pm *BusinessPolicyManager) updateBusinessPolicy(org string, polId string,
 			// notify the policy manager
 			polManager.AddPolicy(org, newPE.Policy)
 
+			// start the staged rollout before the agreements are negotiated
+			pm.Rollouts.UpdateRollout(polId, pol)
+
 			// send a message so that other process can handle it by re-negotiating agreements
 			glog.V(3).Infof(fmt.Sprintf("Policy manager detected new business policy %v", polId))
 			if policyString, err := policy.MarshalPolicy(newPE.Policy); err != nil {
@@ -705,6 +722,8 @@ func (pm *BusinessPolicyManager) deleteBusinessPolicy(org string, polName string
 
 				// notify the policy manager
 				polManager.DeletePolicy(org, pe.Policy)
+				pm.Rollouts.DeleteRollout(pe.Policy.Header.Name)
+				pm.ImageDigests.DeletePins(pe.Policy.Header.Name)
 
 				if policyString, err := policy.MarshalPolicy(pe.Policy); err != nil {
 					glog.Errorf(fmt.Sprintf("Policy manager error trying to marshal policy %v error: %v", polName, err))
@@ -6,6 +6,7 @@ import (
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/structlog"
 )
 
 // ==============================================================================================================
@@ -19,6 +20,10 @@ func (a AgreementTimeoutCommand) ShortString() string {
 	return fmt.Sprintf("%v", a)
 }
 
+func (a AgreementTimeoutCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: a.Protocol, structlog.AGREEMENT_ID: a.AgreementId}
+}
+
 func NewAgreementTimeoutCommand(agreementId string, protocol string, reason uint) *AgreementTimeoutCommand {
 	return &AgreementTimeoutCommand{
 		AgreementId: agreementId,
@@ -102,6 +107,10 @@ func (e WorkloadUpgradeCommand) ShortString() string {
 	return e.Msg.ShortString()
 }
 
+func (e WorkloadUpgradeCommand) LogFields() structlog.Fields {
+	return e.Msg.LogFields()
+}
+
 func NewWorkloadUpgradeCommand(msg events.ABApiWorkloadUpgradeMessage) *WorkloadUpgradeCommand {
 	return &WorkloadUpgradeCommand{
 		Msg: msg,
@@ -128,6 +137,10 @@ func (e MakeAgreementCommand) ShortString() string {
 	return fmt.Sprintf("Produder Policy: %v, ConsumerPolicy: %v, Org: %v, ConsumerPolicyName %v, Device: %v, ServicePolicies: %v", e.ProducerPolicy.Header.Name, e.ConsumerPolicy.Header.Name, e.Org, e.ConsumerPolicyName, e.Device, keys)
 }
 
+func (e MakeAgreementCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.DEVICE_ID: e.Device.Id}
+}
+
 func NewMakeAgreementCommand(pPol policy.Policy, cPol policy.Policy, org string, polname string, dev exchange.SearchResultDevice, cachedServicePolicies map[string]externalpolicy.ExternalPolicy) *MakeAgreementCommand {
 
 	copiedConsumerPolicy := cPol.DeepCopy()
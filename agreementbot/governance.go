@@ -86,6 +86,11 @@ func (w *AgreementBotWorker) GovernAgreements() int {
 						} else if checkrate != 0 && (discoveredNHWaitTime == 0 || (discoveredNHWaitTime != 0 && uint64(checkrate) < discoveredNHWaitTime)) {
 							discoveredNHWaitTime = uint64(checkrate)
 						}
+
+						// Check the image digests that the node resolved, when the policy pins them.
+						if ag.AgreementFinalizedTime != 0 {
+							w.governImageDigests(&ag)
+						}
 					}
 
 					// Govern agreements that havent seen a proposal reply yet
@@ -400,6 +405,159 @@ func (w *AgreementBotWorker) checkWorkloadUsageAgreement(partnerWLU *persistence
 	return partnerUpgrading, upgradedPartnerFound
 }
 
+// Pin the image digests that the node resolved for the service of the agreement, when the policy pins them and no
+// node has pinned them yet. The node sends the digests in an agreement update after it has loaded the images. When the
+// digests differ from the pinned ones, e.g. because the agreement was made before the digests were pinned and the image
+// tag has moved since, the agreement is cancelled so that a new agreement asks the node to run the pinned images.
+func (w *AgreementBotWorker) governImageDigests(ag *persistence.Agreement) {
+
+	if pol := w.pm.GetPolicy(ag.Org, ag.PolicyName); pol == nil || !pol.PinImageDigests {
+		return
+	} else if ag.ImageDigests == nil {
+		glog.V(5).Infof(logString(fmt.Sprintf("node %v has not sent the image digests for agreement %v yet.", ag.DeviceId, ag.CurrentAgreementId)))
+		return
+	}
+
+	pin := businessPolManager.ImageDigests.OfferDigests(ag.PolicyName, ag.CurrentAgreementId, ag.ImageDigests)
+	if pin != nil && !pin.Matches(ag.ImageDigests.Digests) {
+		glog.V(3).Infof(logString(fmt.Sprintf("agreement %v runs image digests %v instead of the pinned digests %v, cancelling it.", ag.CurrentAgreementId, ag.ImageDigests.Digests, pin.Digests)))
+		w.TerminateAgreement(ag, w.consumerPH.Get(ag.AgreementProtocol).GetTerminationCode(TERM_REASON_POLICY_CHANGED))
+	}
+}
+
+// Govern the staged rollouts of new service versions. The upgraded nodes that fail to run the new version are found
+// through their workload usage records (the agreement for the new version had to be retried) and through the errors
+// that the nodes surface to the exchange. When more nodes fail than the rollout policy allows, the rollout is halted,
+// and if the policy asks for it, the upgraded nodes are moved back to the previous version. Otherwise, when the next
+// stage of the rollout starts, the agreements of the nodes in that stage are cancelled so that they upgrade.
+func (w *AgreementBotWorker) governRollouts() int {
+
+	glog.V(5).Infof(logString(fmt.Sprintf("checking staged rollouts.")))
+
+	now := uint64(time.Now().Unix())
+	for _, ro := range businessPolManager.Rollouts.GetActiveRollouts() {
+
+		wlus, err := w.db.FindWorkloadUsages([]persistence.WUFilter{persistence.PWUFilter(ro.PolicyName)})
+		if err != nil {
+			glog.Errorf(logString(fmt.Sprintf("error searching for workload usages of policy %v, error: %v", ro.PolicyName, err)))
+			continue
+		}
+
+		if numFailed := businessPolManager.Rollouts.AddFailedNodes(ro.PolicyName, ro.Version, w.findRolloutFailures(&ro, wlus, ro.CurrentStage(now))); numFailed > ro.Rollout.MaxFailures {
+			businessPolManager.Rollouts.HaltRollout(ro.PolicyName, ro.Version, fmt.Sprintf("%v nodes failed to run the new version, the maximum is %v", numFailed, ro.Rollout.MaxFailures))
+
+			// The halted rollout holds all the nodes, so the re-made agreements will be for the previous version.
+			if ro.Rollout.AutoRollback {
+				for _, wlu := range wlus {
+					if wlu.Priority == ro.Priority && !wlu.ReqsNotMet {
+						glog.V(3).Infof(logString(fmt.Sprintf("rolling back node %v from version %v of policy %v.", wlu.DeviceId, ro.Version, ro.PolicyName)))
+						w.restartRolloutAgreement(&wlu)
+					}
+				}
+			}
+			continue
+		}
+
+		// Upgrade the held nodes that are in the stages that have started since the last time through here.
+		if stage := ro.CurrentStage(now); stage > businessPolManager.Rollouts.StartedStage(&ro) {
+			for _, wlu := range wlus {
+				if wlu.Priority > ro.Priority && wlu.ReqsNotMet && w.rolloutNodeStage(&ro, wlu.DeviceId) <= stage {
+					glog.V(3).Infof(logString(fmt.Sprintf("upgrading node %v to version %v of policy %v in rollout stage %v.", wlu.DeviceId, ro.Version, ro.PolicyName, stage)))
+					w.restartRolloutAgreement(&wlu)
+				}
+			}
+			businessPolManager.Rollouts.SetStartedStage(ro.PolicyName, ro.Version, stage)
+		}
+	}
+
+	return 0
+}
+
+// Returns the upgraded nodes that are failing to run the version being rolled out, and the reason for each one. The
+// surfaced errors are only read from the exchange for the nodes of the given stage, the stage that is soaking, and
+// that have not already been found failing, so that a pass does not read them for every upgraded node.
+func (w *AgreementBotWorker) findRolloutFailures(ro *persistence.Rollout, wlus []persistence.WorkloadUsage, stage int) map[string]string {
+
+	failed := make(map[string]string)
+	for _, wlu := range wlus {
+		if wlu.Priority != ro.Priority || wlu.ReqsNotMet {
+			continue
+		} else if wlu.RetryCount > 0 {
+			failed[wlu.DeviceId] = fmt.Sprintf("the agreement was retried %v times", wlu.RetryCount)
+			continue
+		} else if _, found := ro.FailedNodes[wlu.DeviceId]; found || !inRolloutStage(ro, wlu.DeviceId, stage) {
+			continue
+		}
+
+		if errs, err := exchange.GetSurfaceErrors(w, wlu.DeviceId); err != nil {
+			glog.Warningf(logString(fmt.Sprintf("unable to get the surfaced errors of node %v, error: %v", wlu.DeviceId, err)))
+		} else if errs != nil {
+			for _, se := range errs.ErrorList {
+				if !se.Hidden && se.Workload.URL == ro.ServiceURL && se.Workload.Org == ro.ServiceOrg && se.Workload.Version == ro.Version {
+					failed[wlu.DeviceId] = se.Message
+					break
+				}
+			}
+		}
+	}
+	return failed
+}
+
+// Returns true if the upgraded node is in the given stage of the rollout. Only the canaries are upgraded while the
+// canary stage soaks, and a canary is never in a wave, so the node policy is not needed to find out.
+func inRolloutStage(ro *persistence.Rollout, nodeId string, stage int) bool {
+	if stage == 0 {
+		return true
+	}
+	return ro.Rollout.NodeStage(nodeId, nil) == stage
+}
+
+// Returns the rollout stage of a node. The node policy is only needed to find out if the node is a canary.
+func (w *AgreementBotWorker) rolloutNodeStage(ro *persistence.Rollout, nodeId string) int {
+	if ro.Rollout.HasCanary() {
+		if _, nodePolicy, err := compcheck.GetNodePolicy(exchange.GetHTTPNodePolicyHandler(w), nodeId, nil); err != nil {
+			glog.Warningf(logString(fmt.Sprintf("unable to get the node policy of %v, error: %v", nodeId, err)))
+		} else if nodePolicy != nil {
+			return ro.Rollout.NodeStage(nodeId, nodePolicy.Properties)
+		}
+	}
+	return ro.Rollout.NodeStage(nodeId, nil)
+}
+
+// Cancel the agreement of a node so that it will get a new agreement for the version that the rollout allows. Like
+// a policy change, the other members of an HA group are marked for a pending upgrade so that they are upgraded one
+// at a time.
+func (w *AgreementBotWorker) restartRolloutAgreement(wlu *persistence.WorkloadUsage) {
+
+	if len(wlu.HAPartners) != 0 && wlu.PendingUpgradeTime != 0 {
+		// Another member of the HA group is upgrading, the HA governance will get to this one.
+		return
+	}
+
+	ag, err := w.db.FindSingleAgreementByAgreementIdAllProtocols(wlu.CurrentAgreementId, policy.AllAgreementProtocols(), []persistence.AFilter{persistence.UnarchivedAFilter()})
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to read agreement %v from database, error: %v", wlu.CurrentAgreementId, err)))
+		return
+	}
+
+	for _, partnerId := range wlu.HAPartners {
+		if _, err := w.db.UpdatePendingUpgrade(partnerId, wlu.PolicyName); err != nil {
+			glog.Warningf(logString(fmt.Sprintf("could not update pending workload upgrade for %v using policy %v, error: %v", partnerId, wlu.PolicyName, err)))
+		}
+	}
+
+	// Make sure the workload usage record is gone, this will allow the device to pick up the version that the rollout allows.
+	if err := w.db.DeleteWorkloadUsage(wlu.DeviceId, wlu.PolicyName); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("error deleting workload usage for %v using policy %v, error: %v", wlu.DeviceId, wlu.PolicyName, err)))
+	}
+
+	if ag == nil {
+		glog.V(5).Infof(logString(fmt.Sprintf("agreement for %v already terminated.", wlu.DeviceId)))
+	} else {
+		w.TerminateAgreement(ag, w.consumerPH.Get(ag.AgreementProtocol).GetTerminationCode(TERM_REASON_POLICY_CHANGED))
+	}
+}
+
 // This function is used to verify that a node is still functioning correctly
 func (w *AgreementBotWorker) VerifyNodeHealth(ag *persistence.Agreement, cph ConsumerProtocolHandler) (int, error) {
 
@@ -477,10 +635,16 @@ func GetDevice(httpClient *http.Client, deviceId string, url string, agbotId str
 }
 
 // Govern the archived agreements, periodically deleting them from the database if they are old enough. The
-// age limit is defined by the agbot configuration, PurgeArchivedAgreementHours.
+// age limit is defined by the agbot configuration, PurgeArchivedAgreementHours. The archived agreements of all
+// partitions are purged by the leader agbot only.
 //
 func (w *AgreementBotWorker) GovernArchivedAgreements() int {
 
+	if !w.leader.IsLeader() {
+		glog.V(5).Infof(logString("archive purge skipped, this agbot is not the leader."))
+		return 0
+	}
+
 	// Default to purging archived agreements an hour after they are terminated.
 	ageLimit := 1
 	if w.Config.AgreementBot.PurgeArchivedAgreementHours != 0 {
@@ -491,27 +655,13 @@ func (w *AgreementBotWorker) GovernArchivedAgreements() int {
 
 	glog.V(5).Infof(logString(fmt.Sprintf("archive purge scanning for agreements archived more than %v hour(s) ago.", ageLimit)))
 
-	// A filter for limiting the returned set of agreements to just those that are too old.
-	agedOutFilter := func(now int64, limitH int) persistence.AFilter {
-		return func(a persistence.Agreement) bool {
-			return a.AgreementTimedout != 0 && (a.AgreementTimedout+uint64(limitH*3600) <= uint64(now))
-		}
-	}
-
-	// Find all archived agreements that are old enough and delete them.
+	// Delete all archived agreements that are old enough.
 	for _, agp := range policy.AllAgreementProtocols() {
-		now := time.Now().Unix()
-		if agreements, err := w.db.FindAgreements([]persistence.AFilter{persistence.ArchivedAFilter(), agedOutFilter(now, ageLimit)}, agp); err == nil {
-			for _, ag := range agreements {
-				if err := w.db.DeleteAgreement(ag.CurrentAgreementId, agp); err != nil {
-					glog.Error(logString(fmt.Sprintf("error deleting archived agreement %v, error: %v", ag.CurrentAgreementId, err)))
-				} else {
-					glog.V(3).Infof(logString(fmt.Sprintf("archive purge deleted %v", ag.CurrentAgreementId)))
-				}
-			}
-
-		} else {
-			glog.Errorf(logString(fmt.Sprintf("unable to read archived agreements from database for protocol %v, error: %v", agp, err)))
+		timedoutBefore := uint64(time.Now().Unix()) - uint64(ageLimit*3600)
+		if num, err := w.db.PurgeArchivedAgreements(agp, timedoutBefore); err != nil {
+			glog.Errorf(logString(fmt.Sprintf("unable to purge archived agreements from database for protocol %v, error: %v", agp, err)))
+		} else if num != 0 {
+			glog.V(3).Infof(logString(fmt.Sprintf("archive purge deleted %v %v agreements", num, agp)))
 		}
 	}
 	return 0
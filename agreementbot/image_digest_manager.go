@@ -0,0 +1,80 @@
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"time"
+)
+
+// The image digest manager tracks the pinned image digests of the deployment policies that require all the nodes to run
+// the same images. The pins are kept in the agbot database, so they survive a restart of the agbot and all the agbots
+// that share the database give the same digests to their nodes.
+type ImageDigestManager struct {
+	db persistence.AgbotDatabase
+}
+
+func NewImageDigestManager(db persistence.AgbotDatabase) *ImageDigestManager {
+	return &ImageDigestManager{
+		db: db,
+	}
+}
+
+func (dm *ImageDigestManager) String() string {
+	return "Image Digest Manager"
+}
+
+// Offer the digests that a node resolved for the service of an agreement. The digests become the pinned digests of the
+// service version and arch when there are none yet, an existing pin is never replaced. Returns the pin, or nil if the
+// pin could not be read or written.
+func (dm *ImageDigestManager) OfferDigests(polName string, agreementId string, record *persistence.ImageDigestRecord) *persistence.ImageDigestPin {
+	if record == nil || len(record.Digests) == 0 {
+		return nil
+	}
+
+	if current, err := dm.db.FindImageDigestPin(polName, record.Version, record.Arch); err != nil {
+		glog.Errorf(fmt.Sprintf("Image digest manager unable to read the pin of policy %v version %v arch %v, error: %v", polName, record.Version, record.Arch, err))
+		return nil
+	} else if current != nil {
+		return current
+	}
+
+	digests := make(map[string]string, len(record.Digests))
+	for name, digest := range record.Digests {
+		digests[name] = digest
+	}
+	pin := &persistence.ImageDigestPin{
+		PolicyName:  polName,
+		Version:     record.Version,
+		Arch:        record.Arch,
+		AgreementId: agreementId,
+		PinTime:     uint64(time.Now().Unix()),
+		Digests:     digests,
+	}
+
+	current, err := dm.db.InsertImageDigestPin(pin)
+	if err != nil {
+		glog.Errorf(fmt.Sprintf("Image digest manager unable to save the pin %v, error: %v", pin, err))
+		return nil
+	} else if current != nil && current.AgreementId == agreementId {
+		glog.V(3).Infof(fmt.Sprintf("Image digest manager pinning images of policy %v version %v arch %v to %v from agreement %v", polName, record.Version, record.Arch, digests, agreementId))
+	}
+	return current
+}
+
+// Returns the pinned digests of the service version and arch of the policy, or nil if there are none.
+func (dm *ImageDigestManager) GetDigests(polName string, version string, arch string) map[string]string {
+	if pin, err := dm.db.FindImageDigestPin(polName, version, arch); err != nil {
+		glog.Errorf(fmt.Sprintf("Image digest manager unable to read the pin of policy %v version %v arch %v, error: %v", polName, version, arch, err))
+	} else if pin != nil {
+		return pin.Digests
+	}
+	return nil
+}
+
+func (dm *ImageDigestManager) DeletePins(polName string) {
+	glog.V(3).Infof(fmt.Sprintf("Image digest manager removing pinned images for policy %v", polName))
+	if err := dm.db.DeleteImageDigestPins(polName); err != nil {
+		glog.Errorf(fmt.Sprintf("Image digest manager unable to delete the pins of policy %v, error: %v", polName, err))
+	}
+}
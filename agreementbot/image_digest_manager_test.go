@@ -0,0 +1,87 @@
+//go:build unit
+// +build unit
+
+package agreementbot
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"testing"
+)
+
+// A database that only implements the functions that are used by the image digest manager.
+type imageDigestTestDB struct {
+	persistence.AgbotDatabase
+	pins map[string]persistence.ImageDigestPin
+}
+
+func newImageDigestTestDB() *imageDigestTestDB {
+	return &imageDigestTestDB{pins: map[string]persistence.ImageDigestPin{}}
+}
+
+func (db *imageDigestTestDB) FindImageDigestPin(policyName string, version string, arch string) (*persistence.ImageDigestPin, error) {
+	if p, ok := db.pins[policyName+"/"+version+"/"+arch]; ok {
+		return &p, nil
+	}
+	return nil, nil
+}
+
+func (db *imageDigestTestDB) InsertImageDigestPin(pin *persistence.ImageDigestPin) (*persistence.ImageDigestPin, error) {
+	key := pin.PolicyName + "/" + pin.Version + "/" + pin.Arch
+	if _, ok := db.pins[key]; !ok {
+		db.pins[key] = *pin
+	}
+	return db.FindImageDigestPin(pin.PolicyName, pin.Version, pin.Arch)
+}
+
+func (db *imageDigestTestDB) DeleteImageDigestPins(policyName string) error {
+	for key, p := range db.pins {
+		if p.PolicyName == policyName {
+			delete(db.pins, key)
+		}
+	}
+	return nil
+}
+
+func Test_image_digest_manager(t *testing.T) {
+
+	db := newImageDigestTestDB()
+	dm := NewImageDigestManager(db)
+
+	if digests := dm.GetDigests("myorg/bp1", "1.0.0", "amd64"); digests != nil {
+		t.Errorf("there should not be any pinned digests: %v", digests)
+	}
+
+	// the first digests offered are pinned
+	if pin := dm.OfferDigests("myorg/bp1", "ag2", &persistence.ImageDigestRecord{Version: "1.0.0", Arch: "amd64", Digests: map[string]string{"gps": "sha256:2222"}}); pin == nil || pin.AgreementId != "ag2" {
+		t.Errorf("the first digests should be pinned: %v", pin)
+	} else if digests := dm.GetDigests("myorg/bp1", "1.0.0", "amd64"); digests["gps"] != "sha256:2222" {
+		t.Errorf("wrong pinned digests: %v", digests)
+	}
+
+	// the digests of another agreement do not replace them
+	if pin := dm.OfferDigests("myorg/bp1", "ag1", &persistence.ImageDigestRecord{Version: "1.0.0", Arch: "amd64", Digests: map[string]string{"gps": "sha256:1111"}}); pin == nil || pin.AgreementId != "ag2" {
+		t.Errorf("the digests of another agreement should not be pinned: %v", pin)
+	} else if pin.Matches(map[string]string{"gps": "sha256:1111"}) {
+		t.Errorf("the digests of the other agreement should not match the pin: %v", pin)
+	} else if !pin.Matches(map[string]string{"gps": "sha256:2222"}) {
+		t.Errorf("the pinned digests should match the pin: %v", pin)
+	}
+
+	// the pins are per version and arch
+	if digests := dm.GetDigests("myorg/bp1", "1.0.0", "arm64"); digests != nil {
+		t.Errorf("there should not be pinned digests for another arch: %v", digests)
+	} else if digests := dm.GetDigests("myorg/bp1", "1.1.0", "amd64"); digests != nil {
+		t.Errorf("there should not be pinned digests for another version: %v", digests)
+	}
+
+	// the pins are shared with the other agbots and survive a restart
+	dm2 := NewImageDigestManager(db)
+	if digests := dm2.GetDigests("myorg/bp1", "1.0.0", "amd64"); digests["gps"] != "sha256:2222" {
+		t.Errorf("the pinned digests should be shared: %v", digests)
+	}
+
+	dm.DeletePins("myorg/bp1")
+	if digests := dm2.GetDigests("myorg/bp1", "1.0.0", "amd64"); digests != nil {
+		t.Errorf("the pins should have been deleted: %v", digests)
+	}
+}
@@ -0,0 +1,71 @@
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"sync"
+	"time"
+)
+
+// The agbots that share a database elect one of them to run the background tasks that only need to run once for all the
+// agbots: the purge of the archived agreements, the MMS object garbage collection and the full rescans of the nodes. The
+// leadership is a lease on the partition heartbeat of the leader, so when the leader quiesces or stops heartbeating, one
+// of the other agbots takes over.
+type LeaderElection struct {
+	lock   sync.Mutex
+	leader bool
+}
+
+func NewLeaderElection() *LeaderElection {
+	return &LeaderElection{
+		leader: false,
+	}
+}
+
+// Returns true if this agbot is the leader.
+func (l *LeaderElection) IsLeader() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.leader
+}
+
+// Record whether this agbot is the leader. Returns true if that changed.
+func (l *LeaderElection) set(leader bool) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	changed := l.leader != leader
+	l.leader = leader
+	return changed
+}
+
+// Claim or renew the leadership of this agbot. An agbot that is unable to heartbeat its own partition can not be the
+// leader, because the other agbots will claim the leadership from it.
+func (l *LeaderElection) elect(db persistence.AgbotDatabase, stale uint64) {
+
+	leader := false
+	now := uint64(time.Now().Unix())
+	if hb, err := db.GetHeartbeat(); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to obtain heartbeat for leader election, error: %v", err)))
+	} else if hb != 0 && hb < now && now-hb >= stale {
+		glog.Warningf(AWlogString(fmt.Sprintf("the partition heartbeat is stale, not claiming leadership")))
+	} else if claimed, err := db.ClaimLeadership(stale); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to claim leadership, error: %v", err)))
+	} else {
+		leader = claimed
+	}
+
+	if l.set(leader) {
+		if leader {
+			glog.Infof(AWlogString(fmt.Sprintf("this agbot is now the leader")))
+		} else {
+			glog.Infof(AWlogString(fmt.Sprintf("this agbot is no longer the leader")))
+		}
+	}
+}
+
+// Called by the leader election subworker.
+func (w *AgreementBotWorker) electLeader() int {
+	w.leader.elect(w.db, w.Config.GetPartitionStale())
+	return 0
+}
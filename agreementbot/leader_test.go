@@ -0,0 +1,49 @@
+//go:build unit
+// +build unit
+
+package agreementbot
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"testing"
+	"time"
+)
+
+// A database that only implements the functions that are used by the leader election.
+type leaderTestDB struct {
+	persistence.AgbotDatabase
+	heartbeat uint64
+	claimed   bool
+	claims    int
+}
+
+func (db *leaderTestDB) GetHeartbeat() (uint64, error) {
+	return db.heartbeat, nil
+}
+
+func (db *leaderTestDB) ClaimLeadership(timeout uint64) (bool, error) {
+	db.claims += 1
+	return db.claimed, nil
+}
+
+func Test_LeaderElection(t *testing.T) {
+	now := uint64(time.Now().Unix())
+	l := NewLeaderElection()
+
+	db := &leaderTestDB{heartbeat: now, claimed: true}
+	if l.elect(db, 60); !l.IsLeader() {
+		t.Errorf("the agbot should be the leader when it claims the leadership")
+	}
+
+	// Another agbot took over the leadership.
+	db.claimed = false
+	if l.elect(db, 60); l.IsLeader() {
+		t.Errorf("the agbot should not be the leader when the claim fails")
+	}
+
+	// An agbot that is unable to heartbeat does not claim the leadership.
+	db = &leaderTestDB{heartbeat: now - 120, claimed: true}
+	if l.elect(db, 60); l.IsLeader() || db.claims != 0 {
+		t.Errorf("the agbot should not claim the leadership with a stale heartbeat, claims: %v", db.claims)
+	}
+}
@@ -0,0 +1,97 @@
+package agreementbot
+
+import (
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/metrics"
+	"github.com/open-horizon/anax/policy"
+)
+
+// Agbot specific metrics. The agreement and workload usage gauges are computed from the database each time the
+// metrics are scraped, the work queue metrics are maintained by the prioritized work queues.
+var agbotAgreementsGauge = metrics.DefaultRegistry().NewGaugeVec("anax_agbot_agreements",
+	"Number of agreements owned by this agbot by agreement protocol and state.", "protocol", "state")
+
+var agbotWorkloadUsagesGauge = metrics.DefaultRegistry().NewGaugeVec("anax_agbot_workload_usages",
+	"Number of workload usage records owned by this agbot.")
+
+var workQueueDepthGauge = metrics.DefaultRegistry().NewGaugeVec("anax_agbot_work_queue_depth",
+	"Number of agreement work items buffered in the prioritized work queue.", "protocol", "priority")
+
+var workQueueItemsCounter = metrics.DefaultRegistry().NewCounterVec("anax_agbot_work_queue_items_total",
+	"Number of agreement work items moved through the prioritized work queue, queued from the inbound channels or dispatched to worker threads.", "priority", "direction")
+
+// Agreement states reported in the agbot agreement metrics.
+const (
+	AGBOT_AG_STATE_ATTEMPT   = "attempt"
+	AGBOT_AG_STATE_MADE      = "made"
+	AGBOT_AG_STATE_FINALIZED = "finalized"
+	AGBOT_AG_STATE_TIMEDOUT  = "timedout"
+	AGBOT_AG_STATE_ARCHIVED  = "archived"
+)
+
+// Work queue directions reported in the work queue metrics.
+const (
+	WQ_DIRECTION_QUEUED     = "queued"
+	WQ_DIRECTION_DISPATCHED = "dispatched"
+)
+
+// Returns the most advanced lifecycle state the agreement has reached.
+func agbotAgreementMetricsState(ag *persistence.Agreement) string {
+	if ag.Archived {
+		return AGBOT_AG_STATE_ARCHIVED
+	} else if ag.AgreementTimedout != 0 {
+		return AGBOT_AG_STATE_TIMEDOUT
+	} else if ag.AgreementFinalizedTime != 0 {
+		return AGBOT_AG_STATE_FINALIZED
+	} else if ag.AgreementCreationTime != 0 {
+		return AGBOT_AG_STATE_MADE
+	}
+	return AGBOT_AG_STATE_ATTEMPT
+}
+
+// Refresh the agbot agreement and workload usage gauges from the database.
+func UpdateAgbotMetrics(db persistence.AgbotDatabase) error {
+
+	counts := make(map[string]map[string]int)
+	for _, agp := range policy.AllAgreementProtocols() {
+		counts[agp] = map[string]int{
+			AGBOT_AG_STATE_ATTEMPT:   0,
+			AGBOT_AG_STATE_MADE:      0,
+			AGBOT_AG_STATE_FINALIZED: 0,
+			AGBOT_AG_STATE_TIMEDOUT:  0,
+			AGBOT_AG_STATE_ARCHIVED:  0,
+		}
+		if ags, err := db.FindAgreements([]persistence.AFilter{}, agp); err != nil {
+			return errors.New(fmt.Sprintf("unable to read %v agreements, error %v", agp, err))
+		} else {
+			for _, ag := range ags {
+				counts[agp][agbotAgreementMetricsState(&ag)] += 1
+			}
+		}
+	}
+
+	wlUsages, err := db.FindWorkloadUsages([]persistence.WUFilter{})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to read workload usages, error %v", err))
+	}
+
+	agbotAgreementsGauge.Reset()
+	for agp, states := range counts {
+		for state, num := range states {
+			agbotAgreementsGauge.WithLabelValues(agp, state).Set(float64(num))
+		}
+	}
+	agbotWorkloadUsagesGauge.WithLabelValues().Set(float64(len(wlUsages)))
+
+	return nil
+}
+
+// Register a collector that reports the depth of the work queue used by the given agreement protocol.
+func registerWorkQueueMetrics(protocol string, queue *PrioritizedWorkQueue) {
+	metrics.DefaultRegistry().RegisterCollector("work_queue_"+protocol, func() {
+		workQueueDepthGauge.WithLabelValues(protocol, HIGH_PRIORITY).Set(float64(queue.HighPriorityBufferLen()))
+		workQueueDepthGauge.WithLabelValues(protocol, LOW_PRIORITY).Set(float64(queue.LowPriorityBufferLen()))
+	})
+}
@@ -0,0 +1,105 @@
+package agreementbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/exchangecommon"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/policy"
+	"strings"
+)
+
+// Returns true if the constraints refer to the remaining capacity of the node. The capacity is only computed when a
+// deployment policy needs it, so that the agbot does not read the agreements of every node it evaluates.
+func usesNodeCapacity(constraints externalpolicy.ConstraintExpression) bool {
+	for _, c := range constraints {
+		if strings.Contains(c, externalpolicy.PROP_NODE_MEMORY_AVAILABLE) || strings.Contains(c, externalpolicy.PROP_NODE_CPU_AVAILABLE) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the resource capacity of the node as this agbot sees it. The memory and CPUs of the node are the built-in
+// properties of its node policy, the committed resources are the limits of the workloads of the unarchived agreements
+// that this agbot has with the node. The exchange does not store the capacity of the node, so the agreements the node
+// has with other agbots are not counted. The node checks its own capacity when it receives the proposal.
+func findNodeCapacity(db persistence.AgbotDatabase, nodePolicy *policy.Policy, deviceId string) (*exchangecommon.NodeCapacity, error) {
+
+	capacity := new(exchangecommon.NodeCapacity)
+	if prop, err := nodePolicy.Properties.GetProperty(externalpolicy.PROP_NODE_MEMORY); err == nil {
+		capacity.MemoryMb = int64(propertyNumber(prop))
+	}
+	if prop, err := nodePolicy.Properties.GetProperty(externalpolicy.PROP_NODE_CPU); err == nil {
+		capacity.CPUs = propertyNumber(prop)
+	}
+	if capacity.MemoryMb == 0 && capacity.CPUs == 0 {
+		return capacity, nil
+	}
+
+	for _, agp := range policy.AllAgreementProtocols() {
+		ags, err := db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter(), persistence.DeviceAFilter(deviceId)}, agp)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the agreements of node %v, error: %v", deviceId, err)
+		}
+		for _, ag := range ags {
+			memoryMb, cpus := agreementResourceLimits(&ag)
+			capacity.CommittedMemoryMb += memoryMb
+			capacity.CommittedCPUs += cpus
+		}
+	}
+	return capacity, nil
+}
+
+// Returns the memory and CPU limits of the native deployment of the workload that was chosen for the agreement. Only
+// the chosen workload of the policy saved with the agreement has a deployment.
+func agreementResourceLimits(ag *persistence.Agreement) (int64, float64) {
+	if ag.Policy == "" {
+		return 0, 0
+	} else if pol, err := policy.DemarshalPolicy(ag.Policy); err != nil {
+		return 0, 0
+	} else {
+		for _, wl := range pol.Workloads {
+			if wl.Deployment == "" {
+				continue
+			} else if deployment, err := containermessage.GetNativeDeployment(wl.Deployment); err == nil {
+				return deployment.ResourceLimits()
+			}
+			break
+		}
+	}
+	return 0, 0
+}
+
+// Add the memory and CPUs that the node has not committed to its agreements yet to the node policy, as dynamic
+// properties that the constraints of the deployment policy can match. A node whose node policy does not have the
+// memory or CPU built-in property does not get the corresponding property, so a constraint on it does not match.
+func addNodeCapacityProperties(nodePolicy *policy.Policy, capacity *exchangecommon.NodeCapacity) {
+	if capacity == nil {
+		return
+	}
+	if capacity.MemoryMb != 0 {
+		nodePolicy.Properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_NODE_MEMORY_AVAILABLE, float64(capacity.AvailableMemoryMb())), true)
+	}
+	if capacity.CPUs != 0 {
+		nodePolicy.Properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_NODE_CPU_AVAILABLE, capacity.AvailableCPUs()), true)
+	}
+}
+
+// Returns the numeric value of a built-in property, or 0 if it is not a number.
+func propertyNumber(prop externalpolicy.Property) float64 {
+	switch v := prop.Value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	}
+	return 0
+}
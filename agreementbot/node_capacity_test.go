@@ -0,0 +1,67 @@
+//go:build unit
+// +build unit
+
+package agreementbot
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/exchangecommon"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/policy"
+	"testing"
+)
+
+func Test_addNodeCapacityProperties(t *testing.T) {
+
+	constraints := externalpolicy.ConstraintExpression{"openhorizon.memory.available >= 512 && openhorizon.cpu.available >= 1"}
+	if !usesNodeCapacity(constraints) {
+		t.Errorf("the constraints use the node capacity")
+	} else if usesNodeCapacity(externalpolicy.ConstraintExpression{"openhorizon.memory >= 512"}) {
+		t.Errorf("the constraints do not use the node capacity")
+	}
+
+	capacity := &exchangecommon.NodeCapacity{MemoryMb: 1024, CPUs: 2, CommittedMemoryMb: 256, CommittedCPUs: 0.5}
+	nodePolicy := new(policy.Policy)
+	addNodeCapacityProperties(nodePolicy, capacity)
+
+	if prop, err := nodePolicy.Properties.GetProperty(externalpolicy.PROP_NODE_MEMORY_AVAILABLE); err != nil || prop.Value != float64(768) {
+		t.Errorf("wrong available memory property: %v %v", prop, err)
+	} else if prop, err := nodePolicy.Properties.GetProperty(externalpolicy.PROP_NODE_CPU_AVAILABLE); err != nil || prop.Value != float64(1.5) {
+		t.Errorf("wrong available CPUs property: %v %v", prop, err)
+	}
+
+	// the constraints match the remaining capacity
+	if rp, err := constraints.Validate(); err != nil {
+		t.Errorf("the constraints should be valid: %v", err)
+	} else if err := nodePolicy.Properties.Validate(); err != nil {
+		t.Errorf("the properties should be valid: %v", err)
+	} else if err := constraints.IsSatisfiedBy(nodePolicy.Properties); err != nil {
+		t.Errorf("the node capacity should satisfy the constraints %v: %v", rp, err)
+	}
+
+	// a node whose memory and CPUs are unknown does not get the properties
+	nodePolicy = new(policy.Policy)
+	addNodeCapacityProperties(nodePolicy, &exchangecommon.NodeCapacity{})
+	if len(nodePolicy.Properties) != 0 {
+		t.Errorf("the node should not have capacity properties: %v", nodePolicy.Properties)
+	} else if err := constraints.IsSatisfiedBy(nodePolicy.Properties); err == nil {
+		t.Errorf("the constraints should not be satisfied without the node capacity")
+	}
+}
+
+func Test_agreementResourceLimits(t *testing.T) {
+
+	// only the chosen workload has a deployment
+	pol := policy.Policy{Workloads: []policy.Workload{{WorkloadURL: "myorg/svc1"}, {WorkloadURL: "myorg/svc2", Deployment: `{"services":{"svc2":{"image":"svc2:1.0","max_memory_mb":256,"max_cpus":0.5}}}`}}}
+	if polBytes, err := json.Marshal(pol); err != nil {
+		t.Errorf("unable to marshal the policy: %v", err)
+	} else if memoryMb, cpus := agreementResourceLimits(&persistence.Agreement{Policy: string(polBytes)}); memoryMb != 256 || cpus != 0.5 {
+		t.Errorf("wrong limits %v MB and %v CPUs", memoryMb, cpus)
+	}
+
+	// an agreement that has not been proposed yet does not have a policy
+	if memoryMb, cpus := agreementResourceLimits(&persistence.Agreement{}); memoryMb != 0 || cpus != 0 {
+		t.Errorf("an agreement without a policy should not have limits, got %v MB and %v CPUs", memoryMb, cpus)
+	}
+}
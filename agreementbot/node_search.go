@@ -1,6 +1,7 @@
 package agreementbot
 
 import (
+	"errors"
 	"fmt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/agreementbot/persistence"
@@ -39,6 +40,7 @@ type NodeSearch struct {
 	policyOrder          bool            // When true, order policies most recently changed to least recently changed.
 	clearExchangeCache   bool            // When true, the exchange cache will be deleted after a seach is made with devices returned.
 	completedSearches    map[string]bool //Keeps track of the patterns/policies that have been searched to eliminate rescans until all are searched
+	leader               *LeaderElection // Only the leader runs the full rescans.
 }
 
 func NewNodeSearch() *NodeSearch {
@@ -56,7 +58,7 @@ func NewNodeSearch() *NodeSearch {
 }
 
 // Give the object a chance to initialize itself.
-func (n *NodeSearch) Init(db persistence.AgbotDatabase, pm *policy.PolicyManager, ph *ConsumerPHMgr, msgs chan events.Message, ec exchange.ExchangeContext, cfg *config.HorizonConfig) {
+func (n *NodeSearch) Init(db persistence.AgbotDatabase, pm *policy.PolicyManager, ph *ConsumerPHMgr, msgs chan events.Message, ec exchange.ExchangeContext, cfg *config.HorizonConfig, leader *LeaderElection) {
 
 	n.db = db
 	n.pm = pm
@@ -69,6 +71,7 @@ func (n *NodeSearch) Init(db persistence.AgbotDatabase, pm *policy.PolicyManager
 	n.activeDeviceTimeoutS = cfg.AgreementBot.ActiveDeviceTimeoutS
 	n.retryLookBack = cfg.GetAgbotRetryLookBackWindow()
 	n.policyOrder = cfg.GetAgbotPolicyOrder()
+	n.leader = leader
 
 	// Set the time of the worker restart to 1 minute ago. This time is used to indicate that the node searches need to go backward in time
 	// because this agbot just restarted, and therefore could have lost search results that were in memory but the database was
@@ -128,8 +131,9 @@ func (n *NodeSearch) Scan() {
 
 	// Now check to see if a new scan is needed. This function will periodically scan all nodes, to ensure that missed change events are eventually acted on.
 	// If there is no rescan needed but it's been a while since the last full scan, then do a full scan anyway.
-	// A full rescan uses its own changedSince time so that the full rescans overlap each other.
-	if n.lastSearchComplete && !n.IsRescanNeeded() && (n.fullRescanIntervalS != 0 && (uint64(time.Now().Unix())-n.lastSearchTime) >= n.fullRescanIntervalS) {
+	// A full rescan uses its own changedSince time so that the full rescans overlap each other. The search sessions are shared
+	// by the agbots, so only the leader runs the full rescans.
+	if n.lastSearchComplete && !n.IsRescanNeeded() && n.leader.IsLeader() && (n.fullRescanIntervalS != 0 && (uint64(time.Now().Unix())-n.lastSearchTime) >= n.fullRescanIntervalS) {
 		n.lastSearchTime = uint64(time.Now().Unix())
 		glog.V(3).Infof(AWlogString("Polling Exchange (full rescan)"))
 		n.lastSearchComplete = false
@@ -290,23 +294,9 @@ func (n *NodeSearch) searchNodesAndMakeAgreements(consumerPolicy *policy.Policy,
 		}
 
 		// Get all the agreements for this policy that are still active.
-		pendingAgreementFilter := func() persistence.AFilter {
-			return func(a persistence.Agreement) bool {
-				return a.PolicyName == consumerPolicy.Header.Name && a.AgreementTimedout == 0
-			}
-		}
-
-		ags := make(map[string][]persistence.Agreement)
-
-		// The agreements with this policy could be part of any supported agreement protocol.
-		for _, agp := range policy.AllAgreementProtocols() {
-			// Find all agreements that are in progress. They might be waiting for a reply or not yet finalized.
-			// TODO: To support more than 1 agreement (maxagreements > 1) with this device for this policy, we need to adjust this logic.
-			if agreements, err := n.db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter(), pendingAgreementFilter()}, agp); err != nil {
-				glog.Errorf(AWlogString(fmt.Sprintf("received error trying to find pending agreements for protocol %v: %v", agp, err)))
-			} else {
-				ags[agp] = agreements
-			}
+		ags, err := findPolicyAgreements(n.db, consumerPolicy.Header.Name)
+		if err != nil {
+			glog.Errorf(AWlogString(err))
 		}
 
 		// For each Scan(), clear the cache only once when there are devices returned from the search api.
@@ -380,23 +370,56 @@ func (n *NodeSearch) searchNodesAndMakeAgreements(consumerPolicy *policy.Policy,
 func (n *NodeSearch) alreadyMakingAgreementWith(dev *exchange.SearchResultDevice, consumerPolicy *policy.Policy, allAgreements map[string][]persistence.Agreement) bool {
 
 	// Check to see if we're already doing something with this device.
-	for _, ags := range allAgreements {
-		// Look for any agreements with the current node.
-		for _, ag := range ags {
-			if ag.DeviceId == dev.Id {
-				if ag.AgreementFinalizedTime != 0 {
-					glog.V(5).Infof(AWlogString(fmt.Sprintf("sending agreement verify for %v", ag.CurrentAgreementId)))
-					n.ph.Get(ag.AgreementProtocol).VerifyAgreement(&ag, n.ph.Get(ag.AgreementProtocol))
-					n.AddRetry(consumerPolicy.Header.Name, ag.AgreementFinalizedTime-n.retryLookBack)
-				}
-				return true
-			}
+	if ag := findNodeAgreement(dev.Id, allAgreements); ag != nil {
+		if ag.AgreementFinalizedTime != 0 {
+			glog.V(5).Infof(AWlogString(fmt.Sprintf("sending agreement verify for %v", ag.CurrentAgreementId)))
+			n.ph.Get(ag.AgreementProtocol).VerifyAgreement(ag, n.ph.Get(ag.AgreementProtocol))
+			n.AddRetry(consumerPolicy.Header.Name, ag.AgreementFinalizedTime-n.retryLookBack)
 		}
+		return true
 	}
 	return false
 
 }
 
+// Returns the agreements for the policy that are in progress, keyed by agreement protocol. They might be waiting for
+// a reply or not yet finalized. The node search does not make another agreement with the nodes in these agreements.
+// When the agreements of a protocol cannot be read, the agreements of the other protocols are returned with the error.
+func findPolicyAgreements(db persistence.AgbotDatabase, polName string) (map[string][]persistence.Agreement, error) {
+
+	pendingAgreementFilter := func() persistence.AFilter {
+		return func(a persistence.Agreement) bool {
+			return a.PolicyName == polName && a.AgreementTimedout == 0
+		}
+	}
+
+	ags := make(map[string][]persistence.Agreement)
+	var findErr error
+
+	// The agreements with this policy could be part of any supported agreement protocol.
+	for _, agp := range policy.AllAgreementProtocols() {
+		// TODO: To support more than 1 agreement (maxagreements > 1) with this device for this policy, we need to adjust this logic.
+		if agreements, err := db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter(), pendingAgreementFilter()}, agp); err != nil {
+			findErr = errors.New(fmt.Sprintf("received error trying to find pending agreements for protocol %v: %v", agp, err))
+		} else {
+			ags[agp] = agreements
+		}
+	}
+	return ags, findErr
+}
+
+// Returns the agreement with the node from the agreements of a policy, or nil if there is none.
+func findNodeAgreement(deviceId string, allAgreements map[string][]persistence.Agreement) *persistence.Agreement {
+	for _, ags := range allAgreements {
+		for i := range ags {
+			if ags[i].DeviceId == deviceId {
+				return &ags[i]
+			}
+		}
+	}
+	return nil
+}
+
 // Search the exchange for devices to make agreements with. The system should be operating such that devices are
 // not returned from the exchange (for any given set of search criteria) once an agreement which includes those
 // criteria has been reached. This prevents the agbot from continually sending proposals to devices that are
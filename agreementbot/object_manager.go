@@ -188,8 +188,9 @@ func (m *MMSObjectPolicyManager) SetCurrentPolicyOrgs(servedPols map[string]exch
 }
 
 // This function gets called when object policy updates are detected by the agbot. It will be common for no updates
-// to be received most of the time. It should be invoked on a regular basis.
-func (m *MMSObjectPolicyManager) UpdatePolicies(org string, updatedPolicies *exchange.ObjectDestinationPolicies, objQueryHandler exchange.ObjectQueryHandler) ([]events.Message, error) {
+// to be received most of the time. It should be invoked on a regular basis. The garbage collection of the deleted objects
+// only runs when collectGarbage is true, which is when this agbot is the leader of the agbots that share its database.
+func (m *MMSObjectPolicyManager) UpdatePolicies(org string, updatedPolicies *exchange.ObjectDestinationPolicies, objQueryHandler exchange.ObjectQueryHandler, collectGarbage bool) ([]events.Message, error) {
 	m.orgMapLock.Lock()
 	defer m.orgMapLock.Unlock()
 
@@ -203,7 +204,7 @@ func (m *MMSObjectPolicyManager) UpdatePolicies(org string, updatedPolicies *exc
 	// If there are object policies that have been deleted, we wont know until we ask the MMS if the object still exists.
 	// Loop through all the cached object policies checking to see if they still exist.
 	diff := time.Now().Unix() - m.garbageCollection
-	if diff >= m.config.AgreementBot.MMSGarbageCollectionInterval {
+	if collectGarbage && diff >= m.config.AgreementBot.MMSGarbageCollectionInterval {
 		m.garbageCollection = time.Now().Unix()
 		glog.V(5).Infof(mmsLogString(fmt.Sprintf("Starting object policy garbage collection")))
 		for org, serviceMap := range m.orgMap {
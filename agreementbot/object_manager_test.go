@@ -169,7 +169,7 @@ func Test_object_manager_setorgs2(t *testing.T) {
 		t.Errorf("Error: object manager not created")
 	} else if err := op.SetCurrentPolicyOrgs(servedOrgs1); err != nil {
 		t.Errorf("Error %v consuming served orgs %v", err, servedOrgs1)
-	} else if events, err := op.UpdatePolicies(myorg1, objPolicies1, getDummyObjectQueryHandler()); err != nil {
+	} else if events, err := op.UpdatePolicies(myorg1, objPolicies1, getDummyObjectQueryHandler(), true); err != nil {
 		t.Errorf("Error: error updating object policies, %v", err)
 	} else if len(op.orgMap) != 1 {
 		t.Errorf("Error: should have 1 org in the Object Manager, have %v", len(op.orgMap))
@@ -183,7 +183,7 @@ func Test_object_manager_setorgs2(t *testing.T) {
 		t.Errorf("Error wrong number of policies returned, expecting 2, was %v", len(*objPols))
 	} else if err := op.SetCurrentPolicyOrgs(servedOrgs2); err != nil {
 		t.Errorf("Error %v consuming served orgs %v", err, servedOrgs2)
-	} else if events, err := op.UpdatePolicies(myorg2, objPolicies2, getDummyObjectQueryHandler()); err != nil {
+	} else if events, err := op.UpdatePolicies(myorg2, objPolicies2, getDummyObjectQueryHandler(), true); err != nil {
 		t.Errorf("Error: error updating object policies, %v", err)
 	} else if len(op.orgMap) != 1 {
 		t.Errorf("Error: should have 1 org in the Object Manager, have %v", len(op.orgMap))
@@ -265,7 +265,7 @@ func Test_object_manager_replace1(t *testing.T) {
 		t.Errorf("Error: object manager not created")
 	} else if err := op.SetCurrentPolicyOrgs(servedOrgs1); err != nil {
 		t.Errorf("Error %v consuming served orgs %v", err, servedOrgs1)
-	} else if events, err := op.UpdatePolicies(myorg1, objPolicies1, getDummyObjectQueryHandler()); err != nil {
+	} else if events, err := op.UpdatePolicies(myorg1, objPolicies1, getDummyObjectQueryHandler(), true); err != nil {
 		t.Errorf("Error: error updating object policies, %v", err)
 	} else if len(op.orgMap) != 1 {
 		t.Errorf("Error: should have 1 org in the Object Manager, have %v", len(op.orgMap))
@@ -279,7 +279,7 @@ func Test_object_manager_replace1(t *testing.T) {
 		t.Errorf("Error wrong number of policies returned, expecting 1, was %v", len(*objPols))
 	} else if len((*objPols)[0].DestinationPolicy.Properties) != 0 {
 		t.Errorf("Error should not be any properties in the policy, have %v", (*objPols)[0].DestinationPolicy.Properties)
-	} else if events, err := op.UpdatePolicies(myorg1, objPolicies2, getDummyObjectQueryHandler()); err != nil {
+	} else if events, err := op.UpdatePolicies(myorg1, objPolicies2, getDummyObjectQueryHandler(), true); err != nil {
 		t.Errorf("Error: error updating object policies, %v", err)
 	} else if len(op.orgMap) != 1 {
 		t.Errorf("Error: should have 1 org in the Object Manager, have %v", len(op.orgMap))
@@ -0,0 +1,249 @@
+package agreementbot
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"sort"
+	"time"
+)
+
+// The agbots that share a postgresql database each own a partition of the agreements. The partition of an agbot that
+// quiesces, or that stops heartbeating, is orphaned. Without rebalancing, one of the running agbots takes over an
+// orphaned partition as a whole, so over time the agreements are not evenly spread across the agbots. The functions in
+// this file let every running agbot pull its share of the agreements out of the orphaned partitions first. When there
+// are no orphaned agreements left, a running agbot that has more than its share of the agreements, for example because
+// another agbot just started, hands some of its settled agreements off to the running agbots that have less than their
+// share. Agreements are only moved out of the partition of a running agbot by that agbot itself, because its workers
+// could be working on them.
+
+const PARTITION_REBALANCE_BATCH = 100    // The max number of agreements that are pulled or pushed in one rebalance pass.
+const PARTITION_REBALANCE_TOLERANCE = 10 // The number of active agreements an orphaned partition can have when it is taken over as a whole, and the number of agreements above its share that a running agbot keeps.
+
+// The number of active agreements in a partition, and whether its owner is running.
+type partitionLoad struct {
+	Id     string
+	Active int64
+	Live   bool
+}
+
+// Returns the active agreement counts of all the partitions. When the agbot uses the bolt database there is only one
+// partition and one agbot.
+func findPartitionLoads(db persistence.AgbotDatabase, stale uint64) ([]partitionLoad, error) {
+
+	owners, err := db.FindPartitionOwnership()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to find the partitions, error: %v", err))
+	}
+
+	loads := make([]partitionLoad, 0, len(owners))
+	now := uint64(time.Now().Unix())
+	for _, p := range owners {
+		if active, _, err := db.GetAgreementCount(p.Id); err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to count the agreements in partition %v, error: %v", p.Id, err))
+		} else {
+			loads = append(loads, partitionLoad{Id: p.Id, Active: active, Live: p.IsLive(now, stale)})
+		}
+	}
+	return loads, nil
+}
+
+// Returns how many agreements the local partition should pull out of the orphaned partitions, so that the live
+// partitions end up with the same number of active agreements once all the orphaned agreements are pulled. The share
+// is rounded up, so that the shares of the live partitions always add up to all the orphaned agreements.
+func planPull(local string, loads []partitionLoad) int {
+
+	var localLoad *partitionLoad
+	live, total, orphaned := int64(0), int64(0), int64(0)
+	for i := range loads {
+		total += loads[i].Active
+		if !loads[i].Live {
+			orphaned += loads[i].Active
+			continue
+		}
+		live += 1
+		if loads[i].Id == local {
+			localLoad = &loads[i]
+		}
+	}
+	if localLoad == nil || orphaned == 0 {
+		return 0
+	}
+
+	avg := (total + live - 1) / live
+	num := avg - localLoad.Active
+	if num > orphaned {
+		num = orphaned
+	}
+	if num > PARTITION_REBALANCE_BATCH {
+		num = PARTITION_REBALANCE_BATCH
+	}
+	if num <= 0 {
+		return 0
+	}
+	return int(num)
+}
+
+// The number of agreements to push into the partition of another running agbot.
+type partitionHandoff struct {
+	Id  string
+	Num int
+}
+
+// Returns the agreements that the local partition should push to the other live partitions, so that the live
+// partitions end up with the same number of active agreements. Nothing is pushed while there are orphaned agreements,
+// they are pulled by the partitions that have less than their share first. The local partition only pushes when it
+// has more than PARTITION_REBALANCE_TOLERANCE agreements above its share, and it pushes to the partitions with the
+// fewest agreements first.
+func planPush(local string, loads []partitionLoad) []partitionHandoff {
+
+	var localLoad *partitionLoad
+	live, total := int64(0), int64(0)
+	peers := make([]partitionLoad, 0, len(loads))
+	for i := range loads {
+		if !loads[i].Live {
+			if loads[i].Active != 0 {
+				return nil
+			}
+			continue
+		}
+		live += 1
+		total += loads[i].Active
+		if loads[i].Id == local {
+			localLoad = &loads[i]
+		} else {
+			peers = append(peers, loads[i])
+		}
+	}
+	if localLoad == nil || len(peers) == 0 {
+		return nil
+	}
+
+	avg := total / live
+	excess := localLoad.Active - avg
+	if excess <= PARTITION_REBALANCE_TOLERANCE {
+		return nil
+	} else if excess > PARTITION_REBALANCE_BATCH {
+		excess = PARTITION_REBALANCE_BATCH
+	}
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Active < peers[j].Active })
+
+	handoffs := make([]partitionHandoff, 0, len(peers))
+	for _, p := range peers {
+		num := avg - p.Active
+		if num > excess {
+			num = excess
+		}
+		if num <= 0 {
+			break
+		}
+		handoffs = append(handoffs, partitionHandoff{Id: p.Id, Num: int(num)})
+		excess -= num
+	}
+	return handoffs
+}
+
+// Returns true when every orphaned partition is small enough to be taken over as a whole.
+func orphansDrained(loads []partitionLoad) bool {
+	for _, l := range loads {
+		if !l.Live && l.Active > PARTITION_REBALANCE_TOLERANCE {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true when an agbot other than the local one is running, so that it can take over the agreements of the
+// local agbot.
+func otherLivePartition(local string, loads []partitionLoad) bool {
+	for _, l := range loads {
+		if l.Live && l.Id != local {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true when the stale partition subworker should take over an orphaned partition as a whole. When rebalancing
+// is turned on, the orphaned partitions are left to the rebalancer until only a few active agreements remain in them.
+func (w *AgreementBotWorker) takeOverPartitions() bool {
+
+	if w.Config.AgreementBot.PartitionRebalanceS == 0 {
+		return true
+	} else if loads, err := findPartitionLoads(w.db, w.Config.GetPartitionStale()); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to check the orphaned partitions, error: %v", err)))
+		return true
+	} else {
+		return orphansDrained(loads)
+	}
+}
+
+// Even out the active agreements across the running agbots. This function is called by the partition rebalance
+// subworker, every agbot pulls its share of the agreements out of the orphaned partitions, starting with the largest.
+// When there are no orphaned agreements, an agbot with more than its share pushes agreements to the other agbots.
+func (w *AgreementBotWorker) rebalancePartitions() int {
+
+	// Dont pull agreements if we are unable to heartbeat, the other agbots could be taking over our partition.
+	now := uint64(time.Now().Unix())
+	if hb, err := w.db.GetHeartbeat(); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("Error obtaining heartbeat, error: %v", err)))
+		return 0
+	} else if (now - hb) >= w.Config.GetPartitionStale() {
+		return 0
+	}
+
+	loads, err := findPartitionLoads(w.db, w.Config.GetPartitionStale())
+	if err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to rebalance the partitions, error: %v", err)))
+		return 0
+	}
+
+	num := planPull(w.db.PrimaryPartition(), loads)
+	if num == 0 {
+		w.pushAgreements(loads)
+		return 0
+	}
+	glog.V(3).Infof(AWlogString(fmt.Sprintf("pulling up to %v agreements from the orphaned partitions, partition loads: %v", num, loads)))
+
+	sort.Slice(loads, func(i, j int) bool { return loads[i].Active > loads[j].Active })
+
+	pulled := 0
+	for _, l := range loads {
+		if l.Live || l.Active == 0 || pulled >= num {
+			continue
+		} else if n, err := w.db.PullAgreements(l.Id, w.Config.GetPartitionStale(), num-pulled); err != nil {
+			glog.Errorf(AWlogString(fmt.Sprintf("unable to pull agreements from partition %v, error: %v", l.Id, err)))
+		} else {
+			glog.Infof(AWlogString(fmt.Sprintf("pulled %v agreements from partition %v", n, l.Id)))
+			pulled += n
+		}
+	}
+
+	// Perform the same sanity checks on existing agreements as when we take over a whole partition.
+	if pulled != 0 {
+		if err := w.syncOnInit(); err != nil {
+			glog.Errorf(AWlogString(fmt.Sprintf("unable to sync up, error: %v", err)))
+		}
+	}
+	return 0
+}
+
+// Hand some of the settled agreements of this agbot off to the running agbots that have less than their share.
+func (w *AgreementBotWorker) pushAgreements(loads []partitionLoad) {
+
+	handoffs := planPush(w.db.PrimaryPartition(), loads)
+	if len(handoffs) == 0 {
+		return
+	}
+	glog.V(3).Infof(AWlogString(fmt.Sprintf("pushing agreements to %v, partition loads: %v", handoffs, loads)))
+
+	for _, h := range handoffs {
+		if n, err := w.db.PushAgreements(h.Id, w.Config.GetPartitionStale(), h.Num); err != nil {
+			glog.Errorf(AWlogString(fmt.Sprintf("unable to push agreements to partition %v, error: %v", h.Id, err)))
+		} else {
+			glog.Infof(AWlogString(fmt.Sprintf("pushed %v agreements to partition %v", n, h.Id)))
+		}
+	}
+}
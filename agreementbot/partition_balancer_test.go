@@ -0,0 +1,106 @@
+//go:build unit
+// +build unit
+
+package agreementbot
+
+import (
+	"testing"
+)
+
+func Test_planPull(t *testing.T) {
+
+	// the agreements of a quiesced agbot are shared so that the live partitions end up with the same load
+	loads := []partitionLoad{{Id: "1", Active: 60, Live: true}, {Id: "2", Active: 30, Live: true}, {Id: "3", Active: 90}}
+	if num := planPull("1", loads); num != 30 {
+		t.Errorf("partition 1 should pull 30 agreements, planned %v", num)
+	} else if num := planPull("2", loads); num != 60 {
+		t.Errorf("partition 2 should pull 60 agreements, planned %v", num)
+	}
+
+	// the shares are rounded up so that all the orphaned agreements are pulled
+	loads = []partitionLoad{{Id: "1", Active: 0, Live: true}, {Id: "2", Active: 0, Live: true}, {Id: "3", Active: 3}}
+	if num1, num2 := planPull("1", loads), planPull("2", loads); num1+num2 < 3 {
+		t.Errorf("all the orphaned agreements should be pulled, planned %v and %v", num1, num2)
+	}
+
+	// a live partition above the average does not pull agreements
+	loads = []partitionLoad{{Id: "1", Active: 200, Live: true}, {Id: "2", Active: 0, Live: true}, {Id: "3", Active: 50}}
+	if num := planPull("1", loads); num != 0 {
+		t.Errorf("partition 1 should not pull agreements, planned %v", num)
+	} else if num := planPull("2", loads); num != 50 {
+		t.Errorf("partition 2 should pull all 50 agreements, planned %v", num)
+	}
+
+	// the number of agreements pulled in one pass is limited
+	loads = []partitionLoad{{Id: "1", Active: 0, Live: true}, {Id: "2", Active: 1000}}
+	if num := planPull("1", loads); num != PARTITION_REBALANCE_BATCH {
+		t.Errorf("partition 1 should pull %v agreements, planned %v", PARTITION_REBALANCE_BATCH, num)
+	}
+
+	// agreements are never pulled out of live partitions, and a partition that is not live does not pull
+	loads = []partitionLoad{{Id: "1", Active: 0, Live: true}, {Id: "2", Active: 1000, Live: true}}
+	if num := planPull("1", loads); num != 0 {
+		t.Errorf("agreements should not be pulled from a live partition, planned %v", num)
+	} else if num := planPull("3", []partitionLoad{{Id: "3", Active: 0}, {Id: "4", Active: 100}}); num != 0 {
+		t.Errorf("a partition that is not live should not pull agreements, planned %v", num)
+	}
+}
+
+func Test_planPush(t *testing.T) {
+
+	// a new agbot gets agreements from the agbots that have more than their share
+	loads := []partitionLoad{{Id: "1", Active: 150, Live: true}, {Id: "2", Active: 150, Live: true}, {Id: "3", Active: 0, Live: true}}
+	if h := planPush("1", loads); len(h) != 1 || h[0].Id != "3" || h[0].Num != 50 {
+		t.Errorf("partition 1 should push 50 agreements to partition 3, planned %v", h)
+	} else if h := planPush("3", loads); len(h) != 0 {
+		t.Errorf("partition 3 should not push agreements, planned %v", h)
+	}
+
+	// the agreements go to the partitions with the fewest agreements first, up to their share
+	loads = []partitionLoad{{Id: "1", Active: 90, Live: true}, {Id: "2", Active: 20, Live: true}, {Id: "3", Active: 10, Live: true}}
+	if h := planPush("1", loads); len(h) != 2 || h[0].Id != "3" || h[0].Num != 30 || h[1].Id != "2" || h[1].Num != 20 {
+		t.Errorf("partition 1 should push 30 agreements to partition 3 and 20 to partition 2, planned %v", h)
+	}
+
+	// the number of agreements pushed in one pass is limited
+	loads = []partitionLoad{{Id: "1", Active: 1000, Live: true}, {Id: "2", Active: 0, Live: true}}
+	if h := planPush("1", loads); len(h) != 1 || h[0].Num != PARTITION_REBALANCE_BATCH {
+		t.Errorf("partition 1 should push %v agreements, planned %v", PARTITION_REBALANCE_BATCH, h)
+	}
+
+	// a partition within the tolerance of its share keeps its agreements
+	loads = []partitionLoad{{Id: "1", Active: 60, Live: true}, {Id: "2", Active: 40, Live: true}}
+	if h := planPush("1", loads); len(h) != 0 {
+		t.Errorf("partition 1 should not push agreements, planned %v", h)
+	}
+
+	// nothing is pushed while there are orphaned agreements, or by a partition that is not live
+	loads = []partitionLoad{{Id: "1", Active: 200, Live: true}, {Id: "2", Active: 0, Live: true}, {Id: "3", Active: 5}}
+	if h := planPush("1", loads); len(h) != 0 {
+		t.Errorf("partition 1 should not push agreements while there are orphaned agreements, planned %v", h)
+	} else if h := planPush("3", []partitionLoad{{Id: "3", Active: 200}, {Id: "4", Active: 0, Live: true}}); len(h) != 0 {
+		t.Errorf("a partition that is not live should not push agreements, planned %v", h)
+	}
+}
+
+func Test_orphansDrained(t *testing.T) {
+
+	loads := []partitionLoad{{Id: "1", Active: 1000, Live: true}, {Id: "2", Active: PARTITION_REBALANCE_TOLERANCE}}
+	if !orphansDrained(loads) {
+		t.Errorf("an orphaned partition within the tolerance should be taken over: %v", loads)
+	}
+
+	loads = append(loads, partitionLoad{Id: "3", Active: PARTITION_REBALANCE_TOLERANCE + 1})
+	if orphansDrained(loads) {
+		t.Errorf("an orphaned partition above the tolerance should be left to the rebalancer: %v", loads)
+	}
+}
+
+func Test_otherLivePartition(t *testing.T) {
+
+	if otherLivePartition("1", []partitionLoad{{Id: "1", Live: true}, {Id: "2"}}) {
+		t.Errorf("a partition that is not live cannot take over the agreements")
+	} else if !otherLivePartition("1", []partitionLoad{{Id: "1", Live: true}, {Id: "2", Live: true}}) {
+		t.Errorf("a live partition can take over the agreements")
+	}
+}
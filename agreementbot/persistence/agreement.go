@@ -60,6 +60,20 @@ type Agreement struct {
 	AgreementTimeoutS              uint64   `json:"agreement_timeout_sec"`
 	LastSecretUpdateTime           uint64   `json:"last_secret_update_time"`     // The secret update time corresponding to the most recent secret update protocol msg sent for this agreement
 	LastSecretUpdateTimeAck        uint64   `json:"last_secret_update_time_ack"` // Will match the LastSecretUpdateTime when the agreement update ACK is received
+
+	ImageDigests *ImageDigestRecord `json:"image_digests,omitempty"` // The image digests that the node resolved for the service of this agreement
+}
+
+// The content digests of the images of a service, keyed by container name, as they were reported by the node that runs
+// the service. The digests are only valid for the version and the architecture of the service that was deployed.
+type ImageDigestRecord struct {
+	Version string            `json:"version"`
+	Arch    string            `json:"arch"`
+	Digests map[string]string `json:"digests"`
+}
+
+func (d ImageDigestRecord) String() string {
+	return fmt.Sprintf("Version: %v, Arch: %v, Digests: %v", d.Version, d.Arch, d.Digests)
 }
 
 func (a Agreement) String() string {
@@ -106,7 +120,8 @@ func (a Agreement) String() string {
 		"ProtocolTimeoutS: %v, "+
 		"AgreementTimeoutS: %v, "+
 		"LastSecretUpdateTime: %v, "+
-		"LastSecretUpdateTimeAck: %v",
+		"LastSecretUpdateTimeAck: %v, "+
+		"ImageDigests: %v",
 		a.Archived, a.CurrentAgreementId, a.Org, a.AgreementProtocol, a.AgreementProtocolVersion, a.DeviceId, a.DeviceType, a.HAPartners,
 		a.AgreementInceptionTime, a.AgreementCreationTime, a.AgreementFinalizedTime,
 		a.AgreementTimedout, a.ProposalSig, a.ProposalHash, a.ConsumerProposalSig, a.PolicyName, a.CounterPartyAddress,
@@ -115,7 +130,7 @@ func (a Agreement) String() string {
 		a.MeteringTokens, a.MeteringPerTimeUnit, a.MeteringNotificationInterval, a.MeteringNotificationSent, a.MeteringNotificationMsgs,
 		a.TerminatedReason, a.TerminatedDescription, a.BlockchainType, a.BlockchainName, a.BlockchainOrg, a.BCUpdateAckTime,
 		a.NHMissingHBInterval, a.NHCheckAgreementStatus, a.Pattern, a.ServiceId, a.ProtocolTimeoutS, a.AgreementTimeoutS,
-		a.LastSecretUpdateTime, a.LastSecretUpdateTimeAck)
+		a.LastSecretUpdateTime, a.LastSecretUpdateTimeAck, a.ImageDigests)
 }
 
 // Factory method for agreement w/out persistence safety.
@@ -230,6 +245,7 @@ func AgreementMade(db AgbotDatabase, agreementId string, counterParty string, si
 	}); err != nil {
 		return nil, err
 	} else {
+		RecordAgreementHistory(db, agreement, AH_MADE)
 		return agreement, nil
 	}
 }
@@ -260,23 +276,33 @@ func AgreementBlockchainUpdateAck(db AgbotDatabase, agreementId string, protocol
 }
 
 func AgreementFinalized(db AgbotDatabase, agreementid string, protocol string) (*Agreement, error) {
+	first := false
 	if agreement, err := db.SingleAgreementUpdate(agreementid, protocol, func(a Agreement) *Agreement {
+		first = a.AgreementFinalizedTime == 0
 		a.AgreementFinalizedTime = uint64(time.Now().Unix())
 		return &a
 	}); err != nil {
 		return nil, err
 	} else {
+		if first {
+			RecordAgreementHistory(db, agreement, AH_FINALIZED)
+		}
 		return agreement, nil
 	}
 }
 
 func AgreementTimedout(db AgbotDatabase, agreementid string, protocol string) (*Agreement, error) {
+	first := false
 	if agreement, err := db.SingleAgreementUpdate(agreementid, protocol, func(a Agreement) *Agreement {
+		first = a.AgreementTimedout == 0
 		a.AgreementTimedout = uint64(time.Now().Unix())
 		return &a
 	}); err != nil {
 		return nil, err
 	} else {
+		if first {
+			RecordAgreementHistory(db, agreement, AH_TIMEDOUT)
+		}
 		return agreement, nil
 	}
 }
@@ -331,7 +357,9 @@ func MeteringNotification(db AgbotDatabase, agreementid string, protocol string,
 }
 
 func ArchiveAgreement(db AgbotDatabase, agreementid string, protocol string, reason uint, desc string) (*Agreement, error) {
+	first := false
 	if agreement, err := db.SingleAgreementUpdate(agreementid, protocol, func(a Agreement) *Agreement {
+		first = !a.Archived
 		a.Archived = true
 		a.TerminatedReason = reason
 		a.TerminatedDescription = desc
@@ -339,6 +367,9 @@ func ArchiveAgreement(db AgbotDatabase, agreementid string, protocol string, rea
 	}); err != nil {
 		return nil, err
 	} else {
+		if first {
+			RecordAgreementHistory(db, agreement, AH_CANCELLED)
+		}
 		return agreement, nil
 	}
 }
@@ -377,6 +408,17 @@ func AgreementSecretUpdateAckTime(db AgbotDatabase, agreementid string, protocol
 	}
 }
 
+func AgreementImageDigests(db AgbotDatabase, agreementid string, protocol string, digests *ImageDigestRecord) (*Agreement, error) {
+	if agreement, err := db.SingleAgreementUpdate(agreementid, protocol, func(a Agreement) *Agreement {
+		a.ImageDigests = digests
+		return &a
+	}); err != nil {
+		return nil, err
+	} else {
+		return agreement, nil
+	}
+}
+
 // This code is running in a database transaction. Within the tx, the current record is
 // read and then updated according to the updates within the input update record. It is critical
 // to check for correct data transitions within the tx .
@@ -483,6 +525,9 @@ func ValidateStateTransition(mod *Agreement, update *Agreement) {
 	if mod.LastSecretUpdateTimeAck < update.LastSecretUpdateTimeAck { // Valid transitions must move forward
 		mod.LastSecretUpdateTimeAck = update.LastSecretUpdateTimeAck
 	}
+	if mod.ImageDigests == nil { // 1 transition from empty to non-empty
+		mod.ImageDigests = update.ImageDigests
+	}
 }
 
 // Filters used by the caller to control what comes back from the database.
@@ -496,6 +541,14 @@ func ArchivedAFilter() AFilter {
 	return func(e Agreement) bool { return e.Archived }
 }
 
+// Agreements in a steady state are finalized and are not waiting for a reply from the node, so they can be moved to
+// the partition of another agbot.
+func SteadyStateAFilter() AFilter {
+	return func(a Agreement) bool {
+		return a.AgreementFinalizedTime != 0 && a.AgreementTimedout == 0 && a.LastSecretUpdateTime == a.LastSecretUpdateTimeAck
+	}
+}
+
 func IdAFilter(id string) AFilter {
 	return func(a Agreement) bool { return a.CurrentAgreementId == id }
 }
@@ -0,0 +1,117 @@
+package persistence
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/policy"
+	"time"
+)
+
+// The lifecycle transitions of an agreement that are recorded in the agreement history.
+const (
+	AH_ATTEMPT   = "attempt"   // the agbot started to make an agreement with a node
+	AH_MADE      = "made"      // the node accepted the proposal
+	AH_FINALIZED = "finalized" // the agreement is finalized
+	AH_TIMEDOUT  = "timedout"  // the agreement timed out or is being terminated
+	AH_CANCELLED = "cancelled" // the agreement is terminated and archived, with a reason code
+)
+
+// A record of one lifecycle transition of an agreement. Unlike the archived agreements, these records are not purged
+// so that there is a durable audit trail of which node ran which service version and when.
+type AgreementHistoryRecord struct {
+	AgreementId       string `json:"agreement_id"`
+	AgreementProtocol string `json:"agreement_protocol"`
+	Event             string `json:"event"`                        // one of the AH_* constants
+	Timestamp         uint64 `json:"timestamp"`                    // when the transition happened
+	Org               string `json:"org"`                          // the org of the policy or pattern used to make the agreement
+	DeviceId          string `json:"device_id"`                    // the node (org/id) in the agreement
+	DeviceType        string `json:"device_type"`                  // device or cluster
+	PolicyName        string `json:"policy_name"`                  // the internal name of the policy used to make the agreement
+	Pattern           string `json:"pattern,omitempty"`            // the pattern used to make the agreement, pattern case only
+	ServiceURL        string `json:"service_url,omitempty"`        // the top level service, once the proposal has been made
+	ServiceOrg        string `json:"service_org,omitempty"`        // the org of the top level service
+	ServiceVersion    string `json:"service_version,omitempty"`    // the version of the top level service
+	ServiceArch       string `json:"service_arch,omitempty"`       // the arch of the top level service
+	ReasonCode        uint   `json:"reason_code,omitempty"`        // why the agreement was cancelled
+	ReasonDescription string `json:"reason_description,omitempty"` // the description of the reason code
+}
+
+func (r AgreementHistoryRecord) String() string {
+	return fmt.Sprintf("AgreementHistoryRecord: AgreementId: %v, Protocol: %v, Event: %v, Timestamp: %v, Org: %v, DeviceId: %v, DeviceType: %v, PolicyName: %v, Pattern: %v, Service: %v/%v %v %v, ReasonCode: %v, ReasonDescription: %v",
+		r.AgreementId, r.AgreementProtocol, r.Event, r.Timestamp, r.Org, r.DeviceId, r.DeviceType, r.PolicyName, r.Pattern, r.ServiceOrg, r.ServiceURL, r.ServiceVersion, r.ServiceArch, r.ReasonCode, r.ReasonDescription)
+}
+
+// Create a history record for the given agreement. The service is taken from the policy of the agreement, which is
+// only known after the proposal has been made.
+func NewAgreementHistoryRecord(ag *Agreement, event string) *AgreementHistoryRecord {
+	rec := &AgreementHistoryRecord{
+		AgreementId:       ag.CurrentAgreementId,
+		AgreementProtocol: ag.AgreementProtocol,
+		Event:             event,
+		Timestamp:         uint64(time.Now().Unix()),
+		Org:               ag.Org,
+		DeviceId:          ag.DeviceId,
+		DeviceType:        ag.GetDeviceType(),
+		PolicyName:        ag.PolicyName,
+		Pattern:           ag.Pattern,
+	}
+
+	if event == AH_CANCELLED {
+		rec.ReasonCode = ag.TerminatedReason
+		rec.ReasonDescription = ag.TerminatedDescription
+	}
+
+	if ag.Policy != "" {
+		if pol, err := policy.DemarshalPolicy(ag.Policy); err != nil {
+			glog.Warningf("unable to demarshal policy of agreement %v for the agreement history, error: %v", ag.CurrentAgreementId, err)
+		} else if len(pol.Workloads) != 0 {
+			rec.ServiceURL = pol.Workloads[0].WorkloadURL
+			rec.ServiceOrg = pol.Workloads[0].Org
+			rec.ServiceVersion = pol.Workloads[0].Version
+			rec.ServiceArch = pol.Workloads[0].Arch
+		}
+	}
+	return rec
+}
+
+// Record a lifecycle transition of an agreement. The history is an audit trail, so a failure to record it is logged
+// but does not fail the agreement protocol.
+func RecordAgreementHistory(db AgbotDatabase, ag *Agreement, event string) {
+	if ag == nil {
+		return
+	}
+	rec := NewAgreementHistoryRecord(ag, event)
+	if err := db.AddAgreementHistory(rec); err != nil {
+		glog.Errorf("unable to record agreement history %v, error: %v", rec, err)
+	}
+}
+
+// The criteria used to search the agreement history. Empty fields match everything.
+type AgreementHistoryQuery struct {
+	DeviceId   string // the node, org/id
+	PolicyName string // the internal policy name, org/name
+	Service    string // the top level service URL, optionally prefixed by its org as org/url
+	Since      uint64 // records at or after this time
+	Until      uint64 // records at or before this time
+	Limit      int    // the maximum number of records, the most recent ones are returned
+}
+
+func (q AgreementHistoryQuery) String() string {
+	return fmt.Sprintf("DeviceId: %v, PolicyName: %v, Service: %v, Since: %v, Until: %v, Limit: %v", q.DeviceId, q.PolicyName, q.Service, q.Since, q.Until, q.Limit)
+}
+
+// Returns true if the record meets the search criteria, except for the limit.
+func (q AgreementHistoryQuery) Matches(r *AgreementHistoryRecord) bool {
+	if q.DeviceId != "" && r.DeviceId != q.DeviceId {
+		return false
+	} else if q.PolicyName != "" && r.PolicyName != q.PolicyName {
+		return false
+	} else if q.Service != "" && r.ServiceURL != q.Service && fmt.Sprintf("%v/%v", r.ServiceOrg, r.ServiceURL) != q.Service {
+		return false
+	} else if q.Since != 0 && r.Timestamp < q.Since {
+		return false
+	} else if q.Until != 0 && r.Timestamp > q.Until {
+		return false
+	}
+	return true
+}
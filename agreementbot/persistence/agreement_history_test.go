@@ -0,0 +1,71 @@
+//go:build unit
+// +build unit
+
+package persistence
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/policy"
+	"testing"
+)
+
+func Test_NewAgreementHistoryRecord(t *testing.T) {
+
+	ag := &Agreement{
+		CurrentAgreementId: "ag1",
+		AgreementProtocol:  policy.BasicProtocol,
+		Org:                "myorg",
+		DeviceId:           "myorg/node1",
+		PolicyName:         "myorg/bp1",
+		TerminatedReason:   204,
+	}
+
+	// before the proposal is made, the service is not known
+	if rec := NewAgreementHistoryRecord(ag, AH_ATTEMPT); rec.ServiceURL != "" || rec.DeviceType != DEVICE_TYPE_DEVICE || rec.ReasonCode != 0 {
+		t.Errorf("wrong attempt record: %v", rec)
+	}
+
+	pol := policy.Policy_Factory("bp1")
+	pol.Workloads = append(pol.Workloads, policy.Workload{WorkloadURL: "svc", Org: "svcorg", Version: "1.2.0", Arch: "amd64"})
+	polBytes, _ := json.Marshal(pol)
+	ag.Policy = string(polBytes)
+	ag.TerminatedDescription = "agreement bot policy changed"
+
+	rec := NewAgreementHistoryRecord(ag, AH_CANCELLED)
+	if rec.ServiceURL != "svc" || rec.ServiceOrg != "svcorg" || rec.ServiceVersion != "1.2.0" || rec.ServiceArch != "amd64" {
+		t.Errorf("the record should have the service of the agreement: %v", rec)
+	} else if rec.ReasonCode != 204 || rec.ReasonDescription != "agreement bot policy changed" {
+		t.Errorf("the cancelled record should have the reason: %v", rec)
+	}
+}
+
+func Test_AgreementHistoryQuery_Matches(t *testing.T) {
+
+	rec := &AgreementHistoryRecord{DeviceId: "myorg/node1", PolicyName: "myorg/bp1", ServiceURL: "svc", ServiceOrg: "svcorg", Timestamp: 1000}
+
+	matching := []AgreementHistoryQuery{
+		AgreementHistoryQuery{},
+		AgreementHistoryQuery{DeviceId: "myorg/node1", PolicyName: "myorg/bp1"},
+		AgreementHistoryQuery{Service: "svc"},
+		AgreementHistoryQuery{Service: "svcorg/svc"},
+		AgreementHistoryQuery{Since: 1000, Until: 1000},
+	}
+	for _, q := range matching {
+		if !q.Matches(rec) {
+			t.Errorf("query %v should match record %v", q, rec)
+		}
+	}
+
+	notMatching := []AgreementHistoryQuery{
+		AgreementHistoryQuery{DeviceId: "myorg/node2"},
+		AgreementHistoryQuery{PolicyName: "myorg/bp2"},
+		AgreementHistoryQuery{Service: "otherorg/svc"},
+		AgreementHistoryQuery{Since: 1001},
+		AgreementHistoryQuery{Until: 999},
+	}
+	for _, q := range notMatching {
+		if q.Matches(rec) {
+			t.Errorf("query %v should not match record %v", q, rec)
+		}
+	}
+}
@@ -89,6 +89,7 @@ func (db *AgbotBoltDB) AgreementAttempt(agreementid string, org string, deviceid
 	} else if err := db.persistNew(agreement.CurrentAgreementId, bucketName(agreementProto), &agreement); err != nil {
 		return err
 	} else {
+		persistence.RecordAgreementHistory(db, agreement, persistence.AH_ATTEMPT)
 		return nil
 	}
 }
@@ -145,6 +146,10 @@ func (db *AgbotBoltDB) AgreementSecretUpdateAckTime(agreementid string, protocol
 	return persistence.AgreementSecretUpdateAckTime(db, agreementid, protocol, secretUpdateAckTime)
 }
 
+func (db *AgbotBoltDB) AgreementImageDigests(agreementid string, protocol string, digests *persistence.ImageDigestRecord) (*persistence.Agreement, error) {
+	return persistence.AgreementImageDigests(db, agreementid, protocol, digests)
+}
+
 // no error on not found, only nil
 func (db *AgbotBoltDB) FindSingleAgreementByAgreementId(agreementid string, protocol string, filters []persistence.AFilter) (*persistence.Agreement, error) {
 	filters = append(filters, persistence.IdAFilter(agreementid))
@@ -222,6 +227,28 @@ func (db *AgbotBoltDB) persistUpdatedAgreement(agreementid string, protocol stri
 	})
 }
 
+// Delete the archived agreements that timed out at or before the given time. Returns the number of agreements that
+// were deleted.
+func (db *AgbotBoltDB) PurgeArchivedAgreements(protocol string, timedoutBefore uint64) (int64, error) {
+	agedOut := func(a persistence.Agreement) bool {
+		return a.AgreementTimedout != 0 && a.AgreementTimedout <= timedoutBefore
+	}
+
+	agreements, err := db.FindAgreements([]persistence.AFilter{persistence.ArchivedAFilter(), agedOut}, protocol)
+	if err != nil {
+		return 0, err
+	}
+
+	num := int64(0)
+	for _, ag := range agreements {
+		if err := db.DeleteAgreement(ag.CurrentAgreementId, protocol); err != nil {
+			return num, err
+		}
+		num += 1
+	}
+	return num, nil
+}
+
 func (db *AgbotBoltDB) DeleteAgreement(pk string, protocol string) error {
 	if pk == "" {
 		return fmt.Errorf("Missing required arg pk")
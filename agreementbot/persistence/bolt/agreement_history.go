@@ -0,0 +1,64 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+const AGREEMENT_HISTORY = "agreement_history" // The bolt DB bucket name for agreement history records.
+
+// The history records are keyed by the bucket's sequence number, zero padded so that the keys sort in the order the
+// records were written.
+func (db *AgbotBoltDB) AddAgreementHistory(rec *persistence.AgreementHistoryRecord) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+
+		if b, err := tx.CreateBucketIfNotExists([]byte(AGREEMENT_HISTORY)); err != nil {
+			return err
+		} else if nextKey, err := b.NextSequence(); err != nil {
+			return fmt.Errorf("Unable to get sequence key for new agreement history record %v. Error: %v", rec, err)
+		} else if bytes, err := json.Marshal(rec); err != nil {
+			return fmt.Errorf("Unable to serialize agreement history record %v. Error: %v", rec, err)
+		} else if err := b.Put([]byte(fmt.Sprintf("%020d", nextKey)), bytes); err != nil {
+			return fmt.Errorf("Unable to write agreement history record %v to bucket %v. Error: %v", rec, AGREEMENT_HISTORY, err)
+		} else {
+			glog.V(5).Infof("Succeeded writing agreement history record %v", rec)
+			return nil
+		}
+	})
+}
+
+// Returns the history records that match the query, oldest first. When the query has a limit, the most recent
+// records are returned.
+func (db *AgbotBoltDB) FindAgreementHistory(query persistence.AgreementHistoryQuery) ([]persistence.AgreementHistoryRecord, error) {
+	records := make([]persistence.AgreementHistoryRecord, 0)
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+
+		if b := tx.Bucket([]byte(AGREEMENT_HISTORY)); b != nil {
+			b.ForEach(func(k, v []byte) error {
+
+				var rec persistence.AgreementHistoryRecord
+
+				if err := json.Unmarshal(v, &rec); err != nil {
+					glog.Errorf("Unable to deserialize agreement history record: %v", v)
+				} else if query.Matches(&rec) {
+					records = append(records, rec)
+				}
+				return nil
+			})
+		}
+
+		return nil // end the transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	} else if query.Limit > 0 && len(records) > query.Limit {
+		return records[len(records)-query.Limit:], nil
+	} else {
+		return records, nil
+	}
+}
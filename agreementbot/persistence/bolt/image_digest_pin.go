@@ -0,0 +1,89 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// The bolt DB bucket name for the pinned image digests. There is a nested bucket for each deployment policy, keyed by
+// the policy name, that holds the pins of the policy keyed by version and arch.
+const IMAGE_DIGEST_PINS = "image_digest_pins"
+
+func imageDigestPinKey(version string, arch string) []byte {
+	return []byte(version + "/" + arch)
+}
+
+// Returns the pin of the service version and arch of the deployment policy, or nil if there is none.
+func (db *AgbotBoltDB) FindImageDigestPin(policyName string, version string, arch string) (*persistence.ImageDigestPin, error) {
+	var pin *persistence.ImageDigestPin
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(IMAGE_DIGEST_PINS)); b != nil {
+			if pb := b.Bucket([]byte(policyName)); pb != nil {
+				if v := pb.Get(imageDigestPinKey(version, arch)); v != nil {
+					pin = new(persistence.ImageDigestPin)
+					if err := json.Unmarshal(v, pin); err != nil {
+						return fmt.Errorf("Unable to deserialize image digest pin record for policy %v: %v", policyName, err)
+					}
+				}
+			}
+		}
+		return nil
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return pin, nil
+}
+
+// Writes the pin unless there is already a pin for the same service version and arch of the deployment policy. Returns
+// the pin that is in the database after the insert.
+func (db *AgbotBoltDB) InsertImageDigestPin(pin *persistence.ImageDigestPin) (*persistence.ImageDigestPin, error) {
+	current := pin
+
+	updateErr := db.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(IMAGE_DIGEST_PINS))
+		if err != nil {
+			return err
+		}
+		pb, err := b.CreateBucketIfNotExists([]byte(pin.PolicyName))
+		if err != nil {
+			return err
+		}
+
+		key := imageDigestPinKey(pin.Version, pin.Arch)
+		if v := pb.Get(key); v != nil {
+			current = new(persistence.ImageDigestPin)
+			if err := json.Unmarshal(v, current); err != nil {
+				return fmt.Errorf("Unable to deserialize image digest pin record for policy %v: %v", pin.PolicyName, err)
+			}
+			return nil
+		}
+
+		if serialized, err := json.Marshal(pin); err != nil {
+			return fmt.Errorf("Unable to serialize image digest pin record %v. Error: %v", pin, err)
+		} else if err := pb.Put(key, serialized); err != nil {
+			return fmt.Errorf("Unable to write image digest pin record %v to bucket %v. Error: %v", pin, IMAGE_DIGEST_PINS, err)
+		}
+		glog.V(5).Infof("Succeeded writing image digest pin record %v", pin)
+		return nil
+	})
+
+	if updateErr != nil {
+		return nil, updateErr
+	}
+	return current, nil
+}
+
+func (db *AgbotBoltDB) DeleteImageDigestPins(policyName string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(IMAGE_DIGEST_PINS)); b != nil && b.Bucket([]byte(policyName)) != nil {
+			return b.DeleteBucket([]byte(policyName))
+		}
+		return nil
+	})
+}
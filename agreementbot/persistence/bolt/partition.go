@@ -1,6 +1,8 @@
 package bolt
 
-import ()
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
 
 // Functions related to partitions in the bolt database. It does not use partitions, or rather has only 1 global partition.
 func (db *AgbotBoltDB) FindPartitions() ([]string, error) {
@@ -32,3 +34,28 @@ func (db *AgbotBoltDB) GetPartitionOwner(id string) (string, error) {
 func (db *AgbotBoltDB) MovePartition(timeout uint64) (bool, error) {
 	return false, nil
 }
+
+func (db *AgbotBoltDB) PrimaryPartition() string {
+	return "global"
+}
+
+func (db *AgbotBoltDB) FindPartitionOwnership() ([]persistence.PartitionOwnership, error) {
+	return []persistence.PartitionOwnership{{Id: "global", Owner: "global"}}, nil
+}
+
+func (db *AgbotBoltDB) PullAgreements(fromPartition string, timeout uint64, max int) (int, error) {
+	return 0, nil
+}
+
+func (db *AgbotBoltDB) PushAgreements(toPartition string, timeout uint64, max int) (int, error) {
+	return 0, nil
+}
+
+// There is only 1 agbot when the bolt database is used, it is always the leader.
+func (db *AgbotBoltDB) ClaimLeadership(timeout uint64) (bool, error) {
+	return true, nil
+}
+
+func (db *AgbotBoltDB) GetLeadership() (*persistence.Leadership, error) {
+	return &persistence.Leadership{Owner: "global", Partition: "global"}, nil
+}
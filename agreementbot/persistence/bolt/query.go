@@ -0,0 +1,32 @@
+package bolt
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// Returns a page of the agreements that match the query, and the number of agreements that match it. The bolt
+// database is not indexed, so the filters of the query are applied while the agreements are read, and then the
+// matching agreements are sorted and paged.
+func (db *AgbotBoltDB) FindAgreementsPage(query persistence.AgreementQuery, protocols []string) ([]persistence.Agreement, int, error) {
+	ags := make([]persistence.Agreement, 0, 100)
+	for _, protocol := range protocols {
+		if protocolAgs, err := db.FindAgreements(query.Filters(), protocol); err != nil {
+			return nil, 0, err
+		} else {
+			ags = append(ags, protocolAgs...)
+		}
+	}
+
+	page, total := query.Page(ags)
+	return page, total, nil
+}
+
+// Returns a page of the workload usages that match the query, and the number of workload usages that match it.
+func (db *AgbotBoltDB) FindWorkloadUsagesPage(query persistence.WorkloadUsageQuery) ([]persistence.WorkloadUsage, int, error) {
+	if wus, err := db.FindWorkloadUsages(query.Filters()); err != nil {
+		return nil, 0, err
+	} else {
+		page, total := query.Page(wus)
+		return page, total, nil
+	}
+}
@@ -0,0 +1,107 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+const ROLLOUTS = "rollouts" // The bolt DB bucket name for the staged rollouts, keyed by the deployment policy name.
+
+func (db *AgbotBoltDB) FindRollouts() ([]persistence.Rollout, error) {
+	rollouts := make([]persistence.Rollout, 0)
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+
+		if b := tx.Bucket([]byte(ROLLOUTS)); b != nil {
+			b.ForEach(func(k, v []byte) error {
+
+				var r persistence.Rollout
+
+				if err := json.Unmarshal(v, &r); err != nil {
+					glog.Errorf("Unable to deserialize rollout record: %v", v)
+				} else {
+					rollouts = append(rollouts, r)
+				}
+				return nil
+			})
+		}
+
+		return nil // end the transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return rollouts, nil
+}
+
+// Returns the rollout of the deployment policy, or nil if there is none.
+func (db *AgbotBoltDB) FindRollout(policyName string) (*persistence.Rollout, error) {
+	var rollout *persistence.Rollout
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(ROLLOUTS)); b != nil {
+			if v := b.Get([]byte(policyName)); v != nil {
+				rollout = new(persistence.Rollout)
+				if err := json.Unmarshal(v, rollout); err != nil {
+					return fmt.Errorf("Unable to deserialize rollout record for policy %v: %v", policyName, err)
+				}
+			}
+		}
+		return nil
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return rollout, nil
+}
+
+// Calls the update function with the current rollout of the deployment policy (nil if there is none) and writes the
+// rollout that it returns, all within a single transaction. When the function returns nil, nothing is written. Returns
+// the rollout that is in the database after the update.
+func (db *AgbotBoltDB) SingleRolloutUpdate(policyName string, fn func(*persistence.Rollout) *persistence.Rollout) (*persistence.Rollout, error) {
+	var rollout *persistence.Rollout
+
+	updateErr := db.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(ROLLOUTS))
+		if err != nil {
+			return err
+		}
+
+		if v := b.Get([]byte(policyName)); v != nil {
+			rollout = new(persistence.Rollout)
+			if err := json.Unmarshal(v, rollout); err != nil {
+				return fmt.Errorf("Unable to deserialize rollout record for policy %v: %v", policyName, err)
+			}
+		}
+
+		if updated := fn(rollout); updated != nil {
+			if serialized, err := json.Marshal(updated); err != nil {
+				return fmt.Errorf("Unable to serialize rollout record %v. Error: %v", updated, err)
+			} else if err := b.Put([]byte(policyName), serialized); err != nil {
+				return fmt.Errorf("Unable to write rollout record %v to bucket %v. Error: %v", updated, ROLLOUTS, err)
+			}
+			glog.V(5).Infof("Succeeded writing rollout record %v", updated)
+			rollout = updated
+		}
+		return nil
+	})
+
+	if updateErr != nil {
+		return nil, updateErr
+	}
+	return rollout, nil
+}
+
+func (db *AgbotBoltDB) DeleteRollout(policyName string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(ROLLOUTS)); b != nil {
+			return b.Delete([]byte(policyName))
+		}
+		return nil
+	})
+}
@@ -24,9 +24,18 @@ type AgbotDatabase interface {
 	QuiescePartition() error
 	GetPartitionOwner(id string) (string, error)
 	MovePartition(timeout uint64) (bool, error)
+	PrimaryPartition() string
+	FindPartitionOwnership() ([]PartitionOwnership, error)
+	PullAgreements(fromPartition string, timeout uint64, max int) (int, error)
+	PushAgreements(toPartition string, timeout uint64, max int) (int, error)
+
+	// Leader election related functions.
+	ClaimLeadership(timeout uint64) (bool, error)
+	GetLeadership() (*Leadership, error)
 
 	// Persistent agreement related functions
 	FindAgreements(filters []AFilter, protocol string) ([]Agreement, error)
+	FindAgreementsPage(query AgreementQuery, protocols []string) ([]Agreement, int, error)
 	FindSingleAgreementByAgreementId(agreementid string, protocol string, filters []AFilter) (*Agreement, error)
 	FindSingleAgreementByAgreementIdAllProtocols(agreementid string, protocols []string, filters []AFilter) (*Agreement, error)
 
@@ -43,6 +52,7 @@ type AgbotDatabase interface {
 	AgreementTimedout(agreementid string, protocol string) (*Agreement, error)
 	AgreementSecretUpdateTime(agreementid string, protocol string, secretUpdateTime uint64) (*Agreement, error)
 	AgreementSecretUpdateAckTime(agreementid string, protocol string, secretUpdateAckTime uint64) (*Agreement, error)
+	AgreementImageDigests(agreementid string, protocol string, digests *ImageDigestRecord) (*Agreement, error)
 
 	DataNotification(agreementid string, protocol string) (*Agreement, error)
 	DataVerified(agreementid string, protocol string) (*Agreement, error)
@@ -50,12 +60,29 @@ type AgbotDatabase interface {
 	MeteringNotification(agreementid string, protocol string, mn string) (*Agreement, error)
 
 	DeleteAgreement(pk string, protocol string) error
+	PurgeArchivedAgreements(protocol string, timedoutBefore uint64) (int64, error)
 	ArchiveAgreement(agreementid string, protocol string, reason uint, desc string) (*Agreement, error)
 
+	// Agreement history related functions. The history is not purged with the archived agreements.
+	AddAgreementHistory(rec *AgreementHistoryRecord) error
+	FindAgreementHistory(query AgreementHistoryQuery) ([]AgreementHistoryRecord, error)
+
+	// Staged rollout related functions. The rollouts are not partitioned, they are shared by all the agbots.
+	FindRollouts() ([]Rollout, error)
+	FindRollout(policyName string) (*Rollout, error)
+	SingleRolloutUpdate(policyName string, fn func(*Rollout) *Rollout) (*Rollout, error)
+	DeleteRollout(policyName string) error
+
+	// Image digest pin related functions. The pins are not partitioned, they are shared by all the agbots.
+	FindImageDigestPin(policyName string, version string, arch string) (*ImageDigestPin, error)
+	InsertImageDigestPin(pin *ImageDigestPin) (*ImageDigestPin, error)
+	DeleteImageDigestPins(policyName string) error
+
 	// Workoad usage related functions
 	NewWorkloadUsage(deviceId string, hapartners []string, policy string, policyName string, priority int, retryDurationS int, verifiedDurationS int, reqsNotMet bool, agid string) error
 	FindSingleWorkloadUsageByDeviceAndPolicyName(deviceid string, policyName string) (*WorkloadUsage, error)
 	FindWorkloadUsages(filters []WUFilter) ([]WorkloadUsage, error)
+	FindWorkloadUsagesPage(query WorkloadUsageQuery) ([]WorkloadUsage, int, error)
 
 	GetWorkloadUsagesCount(partition string) (int64, error)
 
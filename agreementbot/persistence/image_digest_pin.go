@@ -0,0 +1,35 @@
+package persistence
+
+import (
+	"fmt"
+)
+
+// The image digests that are pinned for a service version and architecture of a deployment policy. The digests are the
+// ones that the first node resolved, the nodes that make an agreement after it are asked to run the same digests. A pin
+// is never replaced, so that all the agbots that share the database give the same digests to their nodes.
+type ImageDigestPin struct {
+	PolicyName  string            `json:"policyName"`  // the internal name (org/name) of the deployment policy
+	Version     string            `json:"version"`     // the service version
+	Arch        string            `json:"arch"`        // the architecture of the service
+	AgreementId string            `json:"agreementId"` // the agreement of the node that resolved the digests
+	PinTime     uint64            `json:"pinTime"`     // the time when the digests were pinned
+	Digests     map[string]string `json:"digests"`     // the image digests, keyed by container name
+}
+
+func (p *ImageDigestPin) String() string {
+	return fmt.Sprintf("ImageDigestPin: PolicyName: %v, Version: %v, Arch: %v, AgreementId: %v, PinTime: %v, Digests: %v",
+		p.PolicyName, p.Version, p.Arch, p.AgreementId, p.PinTime, p.Digests)
+}
+
+// Returns true if the digests of each container are the pinned digests.
+func (p *ImageDigestPin) Matches(digests map[string]string) bool {
+	if len(p.Digests) != len(digests) {
+		return false
+	}
+	for name, digest := range p.Digests {
+		if digests[name] != digest {
+			return false
+		}
+	}
+	return true
+}
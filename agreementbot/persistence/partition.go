@@ -0,0 +1,35 @@
+package persistence
+
+import (
+	"fmt"
+)
+
+// The ownership of a database partition. Each agbot instance owns one partition and heartbeats it while it is running.
+// A partition whose owner quiesced, or stopped heartbeating, is taken over by one of the other agbots.
+type PartitionOwnership struct {
+	Id        string `json:"id"`
+	Owner     string `json:"owner"`     // the instance id of the agbot that owns the partition, empty when there is no owner
+	Heartbeat uint64 `json:"heartbeat"` // the time of the last heartbeat, 0 when there is no owner
+}
+
+func (p PartitionOwnership) String() string {
+	return fmt.Sprintf("Id: %v, Owner: %v, Heartbeat: %v", p.Id, p.Owner, p.Heartbeat)
+}
+
+// Returns true if the partition has an owner that heartbeated within the stale timeout.
+func (p PartitionOwnership) IsLive(now uint64, stale uint64) bool {
+	return p.Owner != "" && p.Heartbeat != 0 && (p.Heartbeat >= now || now-p.Heartbeat < stale)
+}
+
+// The agbot that is elected to run the singleton background tasks of the agbots that share a database. The leadership
+// is a lease on the partition heartbeat of the leader, another agbot takes over the leadership when the partition of
+// the leader is quiesced or is not heartbeated within the stale timeout.
+type Leadership struct {
+	Owner     string `json:"owner"`     // the instance id of the leading agbot
+	Partition string `json:"partition"` // the partition of the leading agbot, empty when the leader no longer owns a partition
+	Heartbeat uint64 `json:"heartbeat"` // the time of the last heartbeat of the leader, 0 when it is unknown
+}
+
+func (l Leadership) String() string {
+	return fmt.Sprintf("Owner: %v, Partition: %v, Heartbeat: %v", l.Owner, l.Partition, l.Heartbeat)
+}
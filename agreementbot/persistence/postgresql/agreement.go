@@ -106,6 +106,11 @@ const AGREEMENT_PARTITION_EMPTY = `SELECT agreement_id FROM "agreements_;`
 
 const AGREEMENT_COUNT = `SELECT agreement FROM "agreements_;`
 
+const AGREEMENT_ACTIVE = `SELECT agreement_id, agreement->>'device_id', agreement->>'policy_name' FROM "agreements_ WHERE NOT (agreement->>'archived')::boolean LIMIT $1;`
+
+// The active agreements that are finalized and not being terminated, the ones that no worker is in the middle of.
+const AGREEMENT_SETTLED = `SELECT agreement_id, agreement->>'device_id', agreement->>'policy_name' FROM "agreements_ WHERE NOT (agreement->>'archived')::boolean AND (agreement->>'agreement_finalized_time')::bigint != 0 AND (agreement->>'agreement_timeout')::bigint = 0 LIMIT $1;`
+
 const AGREEMENT_INSERT = `INSERT INTO "agreements_ (agreement_id, protocol, partition, agreement) VALUES ($1, $2, $3, $4);`
 const AGREEMENT_UPDATE = `UPDATE "agreements_ SET agreement = $3, updated = current_timestamp WHERE agreement_id = $1 AND protocol = $2;`
 const AGREEMENT_DELETE = `DELETE FROM "agreements_ WHERE agreement_id = $1;`
@@ -117,10 +122,20 @@ const AGREEMENT_MOVE = `WITH moved_rows AS (
 INSERT INTO "agreements_ (agreement_id, protocol, partition, agreement) SELECT agreement_id, protocol, 'partition_name', agreement FROM moved_rows;
 `
 
+const AGREEMENT_MOVE_ONE = `WITH moved_rows AS (
+    DELETE FROM "agreements_ a WHERE a.agreement_id = $1
+    RETURNING a.agreement_id, a.protocol, a.agreement
+)
+INSERT INTO "agreements_ (agreement_id, protocol, partition, agreement) SELECT agreement_id, protocol, 'partition_name', agreement FROM moved_rows;
+`
+
 const AGREEMENT_PARTITIONS = `SELECT partition FROM agreements;`
 
 const AGREEMENT_DROP_PARTITION = `DROP TABLE "agreements_;`
 
+// The archived agreements are purged from the main table, which covers the agreements of all partitions.
+const AGREEMENT_PURGE_ARCHIVED = `DELETE FROM agreements WHERE protocol = $1 AND (agreement->>'archived')::boolean AND (agreement->>'agreement_timeout')::bigint <> 0 AND (agreement->>'agreement_timeout')::bigint <= $2;`
+
 // The fields in this object are initialized in the Initialize method in this package.
 type AgbotPostgresqlDB struct {
 	identity         string   // The identity of this agbot in the partitions table.
@@ -166,6 +181,16 @@ func (db *AgbotPostgresqlDB) GetAgreementPartitionTableCount(partition string) s
 	return sql
 }
 
+func (db *AgbotPostgresqlDB) GetAgreementPartitionTableActive(partition string) string {
+	sql := strings.Replace(AGREEMENT_ACTIVE, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(partition), 1)
+	return sql
+}
+
+func (db *AgbotPostgresqlDB) GetAgreementPartitionTableSettled(partition string) string {
+	sql := strings.Replace(AGREEMENT_SETTLED, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(partition), 1)
+	return sql
+}
+
 // The SQL template used by this function is slightly different than the others and therefore does it's own calculation
 // of how the table partition is substituted into the SQL. The difference is in the required use of single quotes.
 func (db *AgbotPostgresqlDB) GetAgreementPartitionTableExists(partition string) string {
@@ -181,6 +206,14 @@ func (db *AgbotPostgresqlDB) GetAgreementPartitionMove(fromPartition string, toP
 	return sql
 }
 
+// Same as GetAgreementPartitionMove, but for a single agreement.
+func (db *AgbotPostgresqlDB) GetAgreementMove(fromPartition string, toPartition string) string {
+	sql := strings.Replace(AGREEMENT_MOVE_ONE, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(toPartition), 2)
+	sql = strings.Replace(sql, db.GetAgreementPartitionTableName(toPartition), db.GetAgreementPartitionTableName(fromPartition), 1)
+	sql = strings.Replace(sql, AGREEMENT_PARTITION_FILLIN, toPartition, 1)
+	return sql
+}
+
 func (db *AgbotPostgresqlDB) FindAgreementPartitions() ([]string, error) {
 
 	// Find all the agreement partitions.
@@ -364,6 +397,7 @@ func (db *AgbotPostgresqlDB) AgreementAttempt(agreementid string, org string, de
 	} else if err := db.insertAgreement(agreement, agreementProto); err != nil {
 		return err
 	} else {
+		persistence.RecordAgreementHistory(db, agreement, persistence.AH_ATTEMPT)
 		return nil
 	}
 }
@@ -420,6 +454,10 @@ func (db *AgbotPostgresqlDB) AgreementSecretUpdateAckTime(agreementid string, pr
 	return persistence.AgreementSecretUpdateAckTime(db, agreementid, protocol, secretUpdateAckTime)
 }
 
+func (db *AgbotPostgresqlDB) AgreementImageDigests(agreementid string, protocol string, digests *persistence.ImageDigestRecord) (*persistence.Agreement, error) {
+	return persistence.AgreementImageDigests(db, agreementid, protocol, digests)
+}
+
 func (db *AgbotPostgresqlDB) DeleteAgreement(agreementid string, protocol string) error {
 	tx, err := db.db.Begin()
 	if err != nil {
@@ -434,6 +472,19 @@ func (db *AgbotPostgresqlDB) DeleteAgreement(agreementid string, protocol string
 	}
 }
 
+// Delete the archived agreements of all partitions that timed out at or before the given time. Returns the number of
+// agreements that were deleted.
+func (db *AgbotPostgresqlDB) PurgeArchivedAgreements(protocol string, timedoutBefore uint64) (int64, error) {
+	if res, err := db.db.Exec(AGREEMENT_PURGE_ARCHIVED, protocol, timedoutBefore); err != nil {
+		return 0, errors.New(fmt.Sprintf("unable to purge archived agreements, error: %v", err))
+	} else if num, err := res.RowsAffected(); err != nil {
+		return 0, errors.New(fmt.Sprintf("error getting rows affected, error: %v", err))
+	} else {
+		glog.V(3).Infof("AgreementBot %v purged %v archived %v agreements", db.identity, num, protocol)
+		return num, nil
+	}
+}
+
 func (db *AgbotPostgresqlDB) Close() {
 	glog.V(2).Infof("Closing Postgresql database")
 	db.db.Close()
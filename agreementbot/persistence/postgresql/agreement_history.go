@@ -0,0 +1,133 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"strings"
+)
+
+// Constants for the SQL statements that are used to manage the agreement history. The history is an audit trail of the
+// agreement lifecycle transitions, so unlike the agreements it is not partitioned. The records stay in the table when
+// a partition is moved to another agbot, and they are not purged with the archived agreements.
+//
+// schema:
+// id:          A sequence number that orders the records in the order they were written.
+// device_id:   The node (org/id) in the agreement.
+// policy_name: The internal name of the policy used to make the agreement.
+// service_url: The top level service of the agreement, empty before the proposal is made.
+// service_org: The org of the top level service.
+// event_time:  The time of the lifecycle transition.
+// record:      The JSON serialization of the history record.
+const AGREEMENT_HISTORY_CREATE_TABLE = `CREATE TABLE IF NOT EXISTS agreement_history (
+	id bigserial PRIMARY KEY,
+	device_id text NOT NULL,
+	policy_name text NOT NULL,
+	service_url text NOT NULL,
+	service_org text NOT NULL,
+	event_time bigint NOT NULL,
+	record jsonb NOT NULL,
+	updated timestamp with time zone DEFAULT current_timestamp
+);`
+
+const AGREEMENT_HISTORY_CREATE_DEVICE_INDEX = `CREATE INDEX IF NOT EXISTS device_id_index_on_agreement_history ON agreement_history (device_id);`
+const AGREEMENT_HISTORY_CREATE_POLICY_INDEX = `CREATE INDEX IF NOT EXISTS policy_name_index_on_agreement_history ON agreement_history (policy_name);`
+const AGREEMENT_HISTORY_CREATE_TIME_INDEX = `CREATE INDEX IF NOT EXISTS event_time_index_on_agreement_history ON agreement_history (event_time);`
+
+const AGREEMENT_HISTORY_INSERT = `INSERT INTO agreement_history (device_id, policy_name, service_url, service_org, event_time, record) VALUES ($1, $2, $3, $4, $5, $6);`
+
+// The WHERE clause and the limit are added based on the query.
+const AGREEMENT_HISTORY_QUERY = `SELECT record FROM agreement_history`
+
+func (db *AgbotPostgresqlDB) AddAgreementHistory(rec *persistence.AgreementHistoryRecord) error {
+
+	if recBytes, err := json.Marshal(rec); err != nil {
+		return errors.New(fmt.Sprintf("error marshalling agreement history record %v, error: %v", rec, err))
+	} else if _, err := db.db.Exec(AGREEMENT_HISTORY_INSERT, rec.DeviceId, rec.PolicyName, rec.ServiceURL, rec.ServiceOrg, rec.Timestamp, recBytes); err != nil {
+		return errors.New(fmt.Sprintf("error inserting agreement history record %v, error: %v", rec, err))
+	}
+
+	glog.V(5).Infof("Succeeded writing agreement history record %v", rec)
+	return nil
+}
+
+// Returns the history records that match the query, oldest first. When the query has a limit, the most recent
+// records are returned.
+func (db *AgbotPostgresqlDB) FindAgreementHistory(query persistence.AgreementHistoryQuery) ([]persistence.AgreementHistoryRecord, error) {
+
+	sql, args := makeAgreementHistoryQuery(query)
+	if glog.V(5) {
+		glog.Infof("Find agreement history using SQL: %v with args %v", sql, args)
+	}
+
+	rows, err := db.db.Query(sql, args...)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for agreement history, error: %v", err))
+	}
+
+	// If the rows object doesnt get closed, memory and connections will grow and/or leak.
+	defer rows.Close()
+
+	records := make([]persistence.AgreementHistoryRecord, 0)
+	for rows.Next() {
+		recBytes := make([]byte, 0, 1024)
+		var rec persistence.AgreementHistoryRecord
+		if err := rows.Scan(&recBytes); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning row: %v", err))
+		} else if err := json.Unmarshal(recBytes, &rec); err != nil {
+			return nil, errors.New(fmt.Sprintf("error demarshalling row: %v, error: %v", string(recBytes), err))
+		} else {
+			records = append(records, rec)
+		}
+	}
+
+	// The rows.Next() function will exit with false when done or an error occurred. Get any error encountered during iteration.
+	if err = rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating: %v", err))
+	}
+
+	// The query returns the most recent records first so that the limit applies to them, reverse them into time order.
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// Build the SQL statement and its arguments for the agreement history query.
+func makeAgreementHistoryQuery(query persistence.AgreementHistoryQuery) (string, []interface{}) {
+
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	addCondition := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.Replace(cond, "$?", fmt.Sprintf("$%v", len(args)), -1))
+	}
+
+	if query.DeviceId != "" {
+		addCondition("device_id = $?", query.DeviceId)
+	}
+	if query.PolicyName != "" {
+		addCondition("policy_name = $?", query.PolicyName)
+	}
+	if query.Service != "" {
+		addCondition("(service_url = $? OR service_org || '/' || service_url = $?)", query.Service)
+	}
+	if query.Since != 0 {
+		addCondition("event_time >= $?", query.Since)
+	}
+	if query.Until != 0 {
+		addCondition("event_time <= $?", query.Until)
+	}
+
+	sql := AGREEMENT_HISTORY_QUERY
+	if len(conditions) != 0 {
+		sql += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	sql += " ORDER BY id DESC"
+	if query.Limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %v", query.Limit)
+	}
+	return sql + ";", args
+}
@@ -0,0 +1,70 @@
+package postgresql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// Constants for the SQL statements that are used to manage the pinned image digests of the deployment policies. The
+// pins are shared by all the agbots, so they are not partitioned.
+//
+// schema:
+// policy_name: The internal name (org/name) of the deployment policy.
+// version:     The service version.
+// arch:        The architecture of the service.
+// pin:         The JSON serialization of the pin.
+// updated:     A timestamp to record the time the pin was written.
+const IMAGE_DIGEST_PIN_CREATE_TABLE = `CREATE TABLE IF NOT EXISTS image_digest_pins (
+	policy_name text NOT NULL,
+	version text NOT NULL,
+	arch text NOT NULL,
+	pin jsonb NOT NULL,
+	updated timestamp with time zone DEFAULT current_timestamp,
+	PRIMARY KEY (policy_name, version, arch)
+);`
+
+const IMAGE_DIGEST_PIN_QUERY = `SELECT pin FROM image_digest_pins WHERE policy_name = $1 AND version = $2 AND arch = $3;`
+
+// The first pin that is written wins, a pin is never replaced.
+const IMAGE_DIGEST_PIN_INSERT = `INSERT INTO image_digest_pins (policy_name, version, arch, pin) VALUES ($1, $2, $3, $4) ON CONFLICT (policy_name, version, arch) DO NOTHING;`
+const IMAGE_DIGEST_PIN_DELETE = `DELETE FROM image_digest_pins WHERE policy_name = $1;`
+
+// Returns the pin of the service version and arch of the deployment policy, or nil if there is none.
+func (db *AgbotPostgresqlDB) FindImageDigestPin(policyName string, version string, arch string) (*persistence.ImageDigestPin, error) {
+	pBytes := make([]byte, 0, 1024)
+	if err := db.db.QueryRow(IMAGE_DIGEST_PIN_QUERY, policyName, version, arch).Scan(&pBytes); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for image digest pin of policy %v version %v arch %v, error: %v", policyName, version, arch, err))
+	}
+
+	p := new(persistence.ImageDigestPin)
+	if err := json.Unmarshal(pBytes, p); err != nil {
+		return nil, errors.New(fmt.Sprintf("error demarshalling image digest pin: %v, error: %v", string(pBytes), err))
+	}
+	return p, nil
+}
+
+// Writes the pin unless there is already a pin for the same service version and arch of the deployment policy. Returns
+// the pin that is in the database after the insert.
+func (db *AgbotPostgresqlDB) InsertImageDigestPin(pin *persistence.ImageDigestPin) (*persistence.ImageDigestPin, error) {
+	if pBytes, err := json.Marshal(pin); err != nil {
+		return nil, errors.New(fmt.Sprintf("error marshalling image digest pin %v, error: %v", pin, err))
+	} else if _, err := db.db.Exec(IMAGE_DIGEST_PIN_INSERT, pin.PolicyName, pin.Version, pin.Arch, pBytes); err != nil {
+		return nil, errors.New(fmt.Sprintf("error inserting image digest pin %v, error: %v", pin, err))
+	}
+
+	glog.V(5).Infof("Succeeded inserting image digest pin %v", pin)
+	return db.FindImageDigestPin(pin.PolicyName, pin.Version, pin.Arch)
+}
+
+func (db *AgbotPostgresqlDB) DeleteImageDigestPins(policyName string) error {
+	if _, err := db.db.Exec(IMAGE_DIGEST_PIN_DELETE, policyName); err != nil {
+		return errors.New(fmt.Sprintf("error deleting image digest pins for policy %v, error: %v", policyName, err))
+	}
+	return nil
+}
@@ -59,6 +59,8 @@ func (db *AgbotPostgresqlDB) Initialize(cfg *config.HorizonConfig) error {
 			return errors.New(fmt.Sprintf("unable to create partition table, error: %v", err))
 		} else if _, err := db.db.Exec(PARTITION_CLAIM_UNOWNED_FUNCTION); err != nil {
 			return errors.New(fmt.Sprintf("unable to create claim unowned partition function, error: %v", err))
+		} else if _, err := db.db.Exec(LEADER_CREATE_TABLE); err != nil {
+			return errors.New(fmt.Sprintf("unable to create leader table, error: %v", err))
 		}
 
 		// Claim a partition for ourselves.
@@ -102,6 +104,27 @@ func (db *AgbotPostgresqlDB) Initialize(cfg *config.HorizonConfig) error {
 			return errors.New(fmt.Sprintf("unable to create secrets partition table index, error: %v", err))
 		}
 
+		// Create the agreement history table and indexes if necessary. The history is not partitioned.
+		if _, err := db.db.Exec(AGREEMENT_HISTORY_CREATE_TABLE); err != nil {
+			return errors.New(fmt.Sprintf("unable to create agreement history table, error: %v", err))
+		} else if _, err := db.db.Exec(AGREEMENT_HISTORY_CREATE_DEVICE_INDEX); err != nil {
+			return errors.New(fmt.Sprintf("unable to create agreement history device index, error: %v", err))
+		} else if _, err := db.db.Exec(AGREEMENT_HISTORY_CREATE_POLICY_INDEX); err != nil {
+			return errors.New(fmt.Sprintf("unable to create agreement history policy index, error: %v", err))
+		} else if _, err := db.db.Exec(AGREEMENT_HISTORY_CREATE_TIME_INDEX); err != nil {
+			return errors.New(fmt.Sprintf("unable to create agreement history time index, error: %v", err))
+		}
+
+		// Create the rollouts table if necessary. The rollouts are not partitioned.
+		if _, err := db.db.Exec(ROLLOUT_CREATE_TABLE); err != nil {
+			return errors.New(fmt.Sprintf("unable to create rollouts table, error: %v", err))
+		}
+
+		// Create the image digest pins table if necessary. The pins are not partitioned.
+		if _, err := db.db.Exec(IMAGE_DIGEST_PIN_CREATE_TABLE); err != nil {
+			return errors.New(fmt.Sprintf("unable to create image digest pins table, error: %v", err))
+		}
+
 		glog.V(3).Infof("Postgresql primary partition database tables exist.")
 
 		// Migrate the database tables if necessary. Extract the current schema version from the version table,
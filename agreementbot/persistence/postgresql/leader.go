@@ -0,0 +1,73 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// Constants for the SQL statements that are used to elect the agbot that runs the singleton background tasks, for example
+// the purge of the archived agreements. The table has at most 1 row, which records the current leader. The leadership is
+// a lease on the partition heartbeat of the leader. The leader renews the lease by claiming the leadership again. Another
+// agbot can claim the leadership only when the leader no longer owns a partition that was heartbeated within the "stale"
+// timeout, which happens when the leader quiesces or terminates unexpectedly.
+//
+// leader schema:
+// id:      Always 1, there is only 1 leader.
+// owner:   The UUID of the agbot that is the leader, the same as the owner in the partitions table.
+// updated: A timestamp to record the last time the leader claimed the leadership.
+//
+
+const LEADER_CREATE_TABLE = `CREATE TABLE IF NOT EXISTS leader (
+	id int PRIMARY KEY,
+	owner text NOT NULL,
+	updated timestamp with time zone DEFAULT current_timestamp
+);`
+
+// The row is inserted or updated only when the caller is already the leader or when the leader does not own a live
+// partition. The conflict on the primary key serializes agbots that claim the leadership at the same time.
+const LEADER_CLAIM = `INSERT INTO leader (id, owner) VALUES (1, $1)
+ON CONFLICT (id) DO UPDATE SET owner = $1, updated = current_timestamp
+WHERE leader.owner = $1 OR NOT EXISTS (
+	SELECT 1 FROM partitions WHERE partitions.owner = leader.owner AND EXTRACT (EPOCH FROM AGE(current_timestamp, partitions.heartbeat)) <= $2
+)
+RETURNING owner;`
+
+const LEADER_QUERY = `SELECT leader.owner, partitions.id, EXTRACT (EPOCH FROM partitions.heartbeat) FROM leader LEFT JOIN partitions ON partitions.owner = leader.owner WHERE leader.id = 1;`
+
+// Claim or renew the leadership for this agbot. Returns true if this agbot is the leader.
+func (db *AgbotPostgresqlDB) ClaimLeadership(timeout uint64) (bool, error) {
+
+	var owner string
+	if err := db.db.QueryRow(LEADER_CLAIM, db.identity, timeout).Scan(&owner); err == sql.ErrNoRows {
+		glog.V(5).Infof("AgreementBot %v is not the leader", db.identity)
+		return false, nil
+	} else if err != nil {
+		return false, errors.New(fmt.Sprintf("AgreementBot %v unable to claim leadership, error: %v", db.identity, err))
+	}
+	return owner == db.identity, nil
+}
+
+// Returns the current leader, or nil if no agbot has claimed the leadership yet.
+func (db *AgbotPostgresqlDB) GetLeadership() (*persistence.Leadership, error) {
+
+	var owner string
+	var partition sql.NullString
+	var hb sql.NullFloat64
+	if err := db.db.QueryRow(LEADER_QUERY).Scan(&owner, &partition, &hb); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read the leader, error: %v", err))
+	}
+
+	l := &persistence.Leadership{Owner: owner}
+	if partition.Valid {
+		l.Partition = partition.String
+	}
+	if hb.Valid {
+		l.Heartbeat = uint64(hb.Float64)
+	}
+	return l, nil
+}
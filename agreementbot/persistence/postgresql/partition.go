@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
 )
 
 // Constants for the SQL statements that are used to work with partitions. Each agbot owns a single partition. Each agbot has
@@ -48,6 +49,27 @@ const PARTITION_QUIESCE = `UPDATE partitions SET owner = NULL, heartbeat = NULL
 
 const PARTITION_DELETE = `DELETE FROM partitions WHERE id = $1;`
 
+const PARTITION_LIST = `SELECT id, owner, EXTRACT (EPOCH FROM heartbeat) FROM partitions;`
+
+// Locks the row of a partition that can be claimed, using the same conditions as the claim_ownerless function. No row
+// is returned when the partition is owned by a running agbot.
+const PARTITION_LOCK_ORPHANED = `SELECT id FROM partitions
+	WHERE id = $1 AND (
+		(owner IS NULL AND heartbeat IS NULL)
+		OR
+		(owner IS NOT NULL AND (
+			SELECT EXTRACT ('epoch' FROM (SELECT AGE(current_timestamp, heartbeat)))
+		) > $2)
+	)
+	FOR UPDATE;`
+
+// Locks the row of a partition that is owned by a running agbot. No row is returned when the partition can be claimed.
+const PARTITION_LOCK_LIVE = `SELECT id FROM partitions
+	WHERE id = $1 AND owner IS NOT NULL AND heartbeat IS NOT NULL AND (
+		SELECT EXTRACT ('epoch' FROM (SELECT AGE(current_timestamp, heartbeat)))
+	) <= $2
+	FOR SHARE;`
+
 // The complexity of the WHERE clause should not be underestimated. Each row is scanned whlie the table is locked
 // so we are sure that no other agbot can even read this table until this query is complete. This query runs in a
 // transaction that is controlled by the functions in this package.
@@ -174,6 +196,43 @@ func (db *AgbotPostgresqlDB) GetPartitionOwner(id string) (string, error) {
 
 }
 
+// Retrieve the ownership of all the partitions, including the partitions that do not have any agreements yet.
+func (db *AgbotPostgresqlDB) FindPartitionOwnership() ([]persistence.PartitionOwnership, error) {
+
+	partitions := make([]persistence.PartitionOwnership, 0, 10)
+
+	rows, err := db.db.Query(PARTITION_LIST)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for partitions: %v", err))
+	}
+
+	// If the rows object doesnt get closed, memory and connections will grow and/or leak.
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var owner sql.NullString
+		var hb sql.NullFloat64
+		if err := rows.Scan(&id, &owner, &hb); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning partition row: %v", err))
+		}
+		p := persistence.PartitionOwnership{Id: id}
+		if owner.Valid {
+			p.Owner = owner.String
+		}
+		if hb.Valid {
+			p.Heartbeat = uint64(hb.Float64)
+		}
+		partitions = append(partitions, p)
+	}
+
+	// The rows.Next() function will exit with false when done or an error occurred. Get any error encountered during iteration.
+	if err = rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating partitions: %v", err))
+	}
+
+	return partitions, nil
+}
+
 // If any partition is owned by this agbot where the table is missing for any of the persisted objects, then remove that
 // partition from the database.
 func (db *AgbotPostgresqlDB) VerifyPartitions(partitions []string) ([]string, error) {
@@ -284,3 +343,113 @@ func (db *AgbotPostgresqlDB) MovePartition(timeout uint64) (bool, error) {
 	// We found a partition and moved all the records.
 	return true, nil
 }
+
+// Pull up to max active agreements, and the workload usages of the same node and policy, out of a partition whose owner
+// quiesced or stopped heartbeating, into our primary partition. The partition row is locked and checked first, so the
+// agreements are never pulled out of a partition that a running agbot owns or that another agbot has claimed in the
+// meantime. This is done under a single transaction so that an agreement is never seen in both partitions, or in
+// neither. The rest of the records are moved when the partition is taken over as a whole. Returns the number of
+// agreements that were pulled.
+func (db *AgbotPostgresqlDB) PullAgreements(fromPartition string, timeout uint64, max int) (int, error) {
+
+	if fromPartition == db.PrimaryPartition() {
+		return 0, errors.New(fmt.Sprintf("unable to pull agreements from partition %v into itself", fromPartition))
+	}
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("unable to start transaction for pulling agreements, error: %v", err))
+	}
+	defer tx.Rollback()
+
+	var id string
+	if err := tx.QueryRow(PARTITION_LOCK_ORPHANED, fromPartition, timeout).Scan(&id); err == sql.ErrNoRows {
+		glog.V(3).Infof("AgreementBot %v did not pull agreements, partition %v is owned by a running agbot", db.identity, fromPartition)
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.New(fmt.Sprintf("unable to lock partition %v, error: %v", fromPartition, err))
+	}
+
+	n, err := db.moveAgreements(tx, db.GetAgreementPartitionTableActive(fromPartition), fromPartition, db.PrimaryPartition(), max)
+	if err != nil {
+		return 0, err
+	} else if err := tx.Commit(); err != nil {
+		return 0, errors.New(fmt.Sprintf("unable to commit transaction for pulling agreements, error: %v", err))
+	}
+	glog.V(3).Infof("AgreementBot %v pulled %v agreements from partition %v to %v", db.identity, n, fromPartition, db.PrimaryPartition())
+	return n, nil
+}
+
+// Push up to max settled agreements, and the workload usages of the same node and policy, out of our primary partition
+// into the partition of another running agbot. Only the agreements that are finalized and not being terminated are
+// pushed, so that the workers of this agbot are not in the middle of them. The partition row of the other agbot is
+// locked and checked first, so the agreements are never pushed into a partition that is being taken over. This is done
+// under a single transaction so that an agreement is never seen in both partitions, or in neither. Returns the number
+// of agreements that were pushed.
+func (db *AgbotPostgresqlDB) PushAgreements(toPartition string, timeout uint64, max int) (int, error) {
+
+	if toPartition == db.PrimaryPartition() {
+		return 0, errors.New(fmt.Sprintf("unable to push agreements from partition %v into itself", toPartition))
+	}
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("unable to start transaction for pushing agreements, error: %v", err))
+	}
+	defer tx.Rollback()
+
+	var id string
+	if err := tx.QueryRow(PARTITION_LOCK_LIVE, toPartition, timeout).Scan(&id); err == sql.ErrNoRows {
+		glog.V(3).Infof("AgreementBot %v did not push agreements, partition %v is not owned by a running agbot", db.identity, toPartition)
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.New(fmt.Sprintf("unable to lock partition %v, error: %v", toPartition, err))
+	}
+
+	n, err := db.moveAgreements(tx, db.GetAgreementPartitionTableSettled(db.PrimaryPartition()), db.PrimaryPartition(), toPartition, max)
+	if err != nil {
+		return 0, err
+	} else if err := tx.Commit(); err != nil {
+		return 0, errors.New(fmt.Sprintf("unable to commit transaction for pushing agreements, error: %v", err))
+	}
+	glog.V(3).Infof("AgreementBot %v pushed %v agreements from partition %v to %v", db.identity, n, db.PrimaryPartition(), toPartition)
+	return n, nil
+}
+
+// Move up to max of the agreements returned by the query, and the workload usages of the same node and policy, from
+// one partition to the other, within the input transaction. Returns the number of agreements that were moved.
+func (db *AgbotPostgresqlDB) moveAgreements(tx *sql.Tx, query string, fromPartition string, toPartition string, max int) (int, error) {
+
+	type agreementKey struct {
+		agreementId string
+		deviceId    string
+		policyName  string
+	}
+	keys := make([]agreementKey, 0, max)
+
+	rows, err := tx.Query(query, max)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("unable to query the agreements in partition %v, error: %v", fromPartition, err))
+	}
+	for rows.Next() {
+		var k agreementKey
+		if err := rows.Scan(&k.agreementId, &k.deviceId, &k.policyName); err != nil {
+			rows.Close()
+			return 0, errors.New(fmt.Sprintf("error scanning agreement row in partition %v, error: %v", fromPartition, err))
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, errors.New(fmt.Sprintf("error iterating agreements in partition %v, error: %v", fromPartition, err))
+	}
+
+	for _, k := range keys {
+		if _, err := tx.Exec(db.GetAgreementMove(fromPartition, toPartition), k.agreementId); err != nil {
+			return 0, errors.New(fmt.Sprintf("unable to move agreement %v from partition %v to %v, error: %v", k.agreementId, fromPartition, toPartition, err))
+		} else if _, err := tx.Exec(db.GetWorkloadUsageMove(fromPartition, toPartition), k.deviceId, k.policyName); err != nil {
+			return 0, errors.New(fmt.Sprintf("unable to move workload usage %v %v from partition %v to %v, error: %v", k.deviceId, k.policyName, fromPartition, toPartition, err))
+		}
+	}
+	return len(keys), nil
+}
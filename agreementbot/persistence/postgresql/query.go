@@ -0,0 +1,241 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/lib/pq"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"strings"
+)
+
+// Constants for the SQL statements that are used to query pages of agreements and workload usages. The queries run on
+// the main tables, which cover all the partition tables, and select the partitions of this agbot. The filters of the
+// query are pushed down into the WHERE clause, and the sorting and paging into the ORDER BY, LIMIT and OFFSET clauses,
+// so that only the requested page is read from the database.
+const AGREEMENT_PAGE_QUERY = `SELECT agreement FROM agreements`
+const AGREEMENT_COUNT_QUERY = `SELECT COUNT(*) FROM agreements`
+
+const WORKLOAD_USAGE_PAGE_QUERY = `SELECT workload_usage FROM workload_usages`
+const WORKLOAD_USAGE_COUNT_QUERY = `SELECT COUNT(*) FROM workload_usages`
+
+// The top level service of an agreement is the first workload in the policy of the agreement. The policy is a JSON
+// document in a string field of the agreement, it is empty until the proposal is made.
+const AGREEMENT_SERVICE = `(NULLIF(agreement->>'policy', '')::jsonb->'workloads'->0)`
+
+// The expressions of the agreement fields that are used in the queries.
+const (
+	AGREEMENT_DEVICE_ID      = `(agreement->>'device_id')`
+	AGREEMENT_ORG            = `(agreement->>'org')`
+	AGREEMENT_POLICY_NAME    = `(agreement->>'policy_name')`
+	AGREEMENT_ARCHIVED       = `(agreement->>'archived')::boolean`
+	AGREEMENT_TIMEDOUT       = `(agreement->>'agreement_timeout')::bigint`
+	AGREEMENT_FINALIZED_TIME = `(agreement->>'agreement_finalized_time')::bigint`
+	AGREEMENT_INCEPTION_TIME = `(agreement->>'agreement_inception_time')::bigint`
+	AGREEMENT_CREATION_TIME  = `(agreement->>'agreement_creation_time')::bigint`
+)
+
+var agreementSortFields = map[string]string{
+	persistence.Q_SORT_INCEPTION: AGREEMENT_INCEPTION_TIME,
+	persistence.Q_SORT_CREATION:  AGREEMENT_CREATION_TIME,
+	persistence.Q_SORT_TIMEOUT:   AGREEMENT_TIMEDOUT,
+	persistence.Q_SORT_NODE:      AGREEMENT_DEVICE_ID,
+	persistence.Q_SORT_POLICY:    AGREEMENT_POLICY_NAME,
+}
+
+var agreementStateConditions = map[string]string{
+	persistence.AQ_STATE_ACTIVE:      fmt.Sprintf("NOT %v AND %v = 0", AGREEMENT_ARCHIVED, AGREEMENT_TIMEDOUT),
+	persistence.AQ_STATE_PENDING:     fmt.Sprintf("NOT %v AND %v = 0 AND %v = 0", AGREEMENT_ARCHIVED, AGREEMENT_TIMEDOUT, AGREEMENT_FINALIZED_TIME),
+	persistence.AQ_STATE_FINALIZED:   fmt.Sprintf("NOT %v AND %v = 0 AND %v <> 0", AGREEMENT_ARCHIVED, AGREEMENT_TIMEDOUT, AGREEMENT_FINALIZED_TIME),
+	persistence.AQ_STATE_TERMINATING: fmt.Sprintf("NOT %v AND %v <> 0", AGREEMENT_ARCHIVED, AGREEMENT_TIMEDOUT),
+	persistence.AQ_STATE_ARCHIVED:    AGREEMENT_ARCHIVED,
+}
+
+// Returns a page of the agreements of this agbot that match the query, and the number of agreements that match it.
+func (db *AgbotPostgresqlDB) FindAgreementsPage(query persistence.AgreementQuery, protocols []string) ([]persistence.Agreement, int, error) {
+
+	where, args := makeAgreementQueryConditions(query, db.AllPartitions(), protocols)
+
+	var total int
+	if err := db.db.QueryRow(AGREEMENT_COUNT_QUERY+where+";", args...).Scan(&total); err != nil {
+		return nil, 0, errors.New(fmt.Sprintf("error counting agreements for %v, error: %v", query, err))
+	}
+
+	order := " DESC"
+	if !query.Descending {
+		order = " ASC"
+	}
+	sortField, ok := agreementSortFields[query.Sort]
+	if !ok {
+		sortField = AGREEMENT_INCEPTION_TIME
+	}
+	sqlStr := AGREEMENT_PAGE_QUERY + where + " ORDER BY " + sortField + order + ", agreement_id" + order + makePage(query.Offset, query.Limit) + ";"
+	if glog.V(5) {
+		glog.Infof("Find agreements page using SQL: %v with args %v", sqlStr, args)
+	}
+
+	rows, err := db.db.Query(sqlStr, args...)
+	if err != nil {
+		return nil, 0, errors.New(fmt.Sprintf("error querying for agreements error: %v", err))
+	}
+
+	// If the rows object doesnt get closed, memory and connections will grow and/or leak.
+	defer rows.Close()
+
+	ags := make([]persistence.Agreement, 0, 100)
+	for rows.Next() {
+		agBytes := make([]byte, 0, 2048)
+		var ag persistence.Agreement
+		if err := rows.Scan(&agBytes); err != nil {
+			return nil, 0, errors.New(fmt.Sprintf("error scanning row: %v", err))
+		} else if err := json.Unmarshal(agBytes, &ag); err != nil {
+			return nil, 0, errors.New(fmt.Sprintf("error demarshalling row: %v, error: %v", string(agBytes), err))
+		} else {
+			ags = append(ags, ag)
+		}
+	}
+
+	// The rows.Next() function will exit with false when done or an error occurred. Get any error encountered during iteration.
+	if err = rows.Err(); err != nil {
+		return nil, 0, errors.New(fmt.Sprintf("error iterating: %v", err))
+	}
+
+	return ags, total, nil
+}
+
+// Returns a page of the workload usages of this agbot that match the query, and the number of workload usages that
+// match it.
+func (db *AgbotPostgresqlDB) FindWorkloadUsagesPage(query persistence.WorkloadUsageQuery) ([]persistence.WorkloadUsage, int, error) {
+
+	where, args := makeWorkloadUsageQueryConditions(query, db.AllPartitions())
+
+	var total int
+	if err := db.db.QueryRow(WORKLOAD_USAGE_COUNT_QUERY+where+";", args...).Scan(&total); err != nil {
+		return nil, 0, errors.New(fmt.Sprintf("error counting workload usages for %v, error: %v", query, err))
+	}
+
+	order := " DESC"
+	if !query.Descending {
+		order = " ASC"
+	}
+	sortFields := []string{"device_id", "policy_name"}
+	if query.Sort == persistence.Q_SORT_POLICY {
+		sortFields = []string{"policy_name", "device_id"}
+	}
+	sqlStr := WORKLOAD_USAGE_PAGE_QUERY + where + " ORDER BY " + strings.Join(sortFields, order+", ") + order + makePage(query.Offset, query.Limit) + ";"
+	if glog.V(5) {
+		glog.Infof("Find workload usages page using SQL: %v with args %v", sqlStr, args)
+	}
+
+	rows, err := db.db.Query(sqlStr, args...)
+	if err != nil {
+		return nil, 0, errors.New(fmt.Sprintf("error querying for workload usages, error: %v", err))
+	}
+
+	// If the rows object doesnt get closed, memory and connections will grow and/or leak.
+	defer rows.Close()
+
+	wus := make([]persistence.WorkloadUsage, 0, 100)
+	for rows.Next() {
+		wuBytes := make([]byte, 0, 2048)
+		var wu persistence.WorkloadUsage
+		if err := rows.Scan(&wuBytes); err != nil {
+			return nil, 0, errors.New(fmt.Sprintf("error scanning row: %v", err))
+		} else if err := json.Unmarshal(wuBytes, &wu); err != nil {
+			return nil, 0, errors.New(fmt.Sprintf("error demarshalling row: %v, error: %v", string(wuBytes), err))
+		} else {
+			wus = append(wus, wu)
+		}
+	}
+
+	// The rows.Next() function will exit with false when done or an error occurred. Get any error encountered during iteration.
+	if err = rows.Err(); err != nil {
+		return nil, 0, errors.New(fmt.Sprintf("error iterating: %v", err))
+	}
+
+	return wus, total, nil
+}
+
+// Collects the conditions of a WHERE clause and their arguments. The $? placeholders in a condition are replaced by
+// the number of its argument.
+type sqlConditions struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (c *sqlConditions) add(cond string, arg interface{}) {
+	c.args = append(c.args, arg)
+	c.conditions = append(c.conditions, strings.Replace(cond, "$?", fmt.Sprintf("$%v", len(c.args)), -1))
+}
+
+func (c *sqlConditions) where() string {
+	if len(c.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.conditions, " AND ")
+}
+
+// Build the WHERE clause and its arguments for the agreement page query.
+func makeAgreementQueryConditions(query persistence.AgreementQuery, partitions []string, protocols []string) (string, []interface{}) {
+
+	c := new(sqlConditions)
+	c.add("partition = ANY($?)", pq.Array(partitions))
+	c.add("protocol = ANY($?)", pq.Array(protocols))
+
+	if query.DeviceId != "" {
+		c.add(AGREEMENT_DEVICE_ID+" = $?", query.DeviceId)
+	}
+	if query.Org != "" {
+		c.add(AGREEMENT_ORG+" = $?", query.Org)
+	}
+	if query.PolicyName != "" {
+		c.add(AGREEMENT_POLICY_NAME+" = $?", query.PolicyName)
+	}
+	if query.ServiceURL != "" {
+		c.add(fmt.Sprintf("((%[1]v->>'workloadUrl') = $? OR ((%[1]v->>'organization') || '/' || (%[1]v->>'workloadUrl')) = $?)", AGREEMENT_SERVICE), query.ServiceURL)
+	}
+	if query.ServiceVersion != "" {
+		c.add(fmt.Sprintf("(%v->>'version') = $?", AGREEMENT_SERVICE), query.ServiceVersion)
+	}
+	if cond, ok := agreementStateConditions[query.State]; ok {
+		c.conditions = append(c.conditions, cond)
+	}
+	if query.Since != 0 {
+		c.add(AGREEMENT_INCEPTION_TIME+" >= $?", query.Since)
+	}
+	if query.Until != 0 {
+		c.add(AGREEMENT_INCEPTION_TIME+" <= $?", query.Until)
+	}
+	return c.where(), c.args
+}
+
+// Build the WHERE clause and its arguments for the workload usage page query.
+func makeWorkloadUsageQueryConditions(query persistence.WorkloadUsageQuery, partitions []string) (string, []interface{}) {
+
+	c := new(sqlConditions)
+	c.add("partition = ANY($?)", pq.Array(partitions))
+
+	if query.DeviceId != "" {
+		c.add("device_id = $?", query.DeviceId)
+	}
+	if query.Org != "" {
+		c.add("left(policy_name, length($?::text) + 1) = ($?::text || '/')", query.Org)
+	}
+	if query.PolicyName != "" {
+		c.add("policy_name = $?", query.PolicyName)
+	}
+	return c.where(), c.args
+}
+
+// Returns the LIMIT and OFFSET clauses of a page.
+func makePage(offset int, limit int) string {
+	page := ""
+	if limit > 0 {
+		page += fmt.Sprintf(" LIMIT %v", limit)
+	}
+	if offset > 0 {
+		page += fmt.Sprintf(" OFFSET %v", offset)
+	}
+	return page
+}
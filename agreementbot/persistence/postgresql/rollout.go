@@ -0,0 +1,155 @@
+package postgresql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// Constants for the SQL statements that are used to manage the staged rollouts of the deployment policies. A rollout is
+// governed by all the agbots that serve the policy, so unlike the agreements the rollouts are not partitioned. Each
+// agbot records the stage that it has started in its own partitions within the rollout record.
+//
+// schema:
+// policy_name: The internal name (org/name) of the deployment policy.
+// rollout:     The JSON serialization of the rollout.
+// updated:     A timestamp to record the last time the rollout was updated.
+const ROLLOUT_CREATE_TABLE = `CREATE TABLE IF NOT EXISTS rollouts (
+	policy_name text PRIMARY KEY,
+	rollout jsonb NOT NULL,
+	updated timestamp with time zone DEFAULT current_timestamp
+);`
+
+const ROLLOUT_QUERY_ALL = `SELECT rollout FROM rollouts;`
+const ROLLOUT_QUERY = `SELECT rollout FROM rollouts WHERE policy_name = $1;`
+const ROLLOUT_QUERY_FOR_UPDATE = `SELECT rollout FROM rollouts WHERE policy_name = $1 FOR UPDATE;`
+
+// A new rollout is inserted only when no other agbot inserted it first, which the caller detects when no row is returned.
+const ROLLOUT_INSERT = `INSERT INTO rollouts (policy_name, rollout) VALUES ($1, $2) ON CONFLICT (policy_name) DO NOTHING RETURNING policy_name;`
+const ROLLOUT_UPDATE = `UPDATE rollouts SET rollout = $2, updated = current_timestamp WHERE policy_name = $1;`
+const ROLLOUT_DELETE = `DELETE FROM rollouts WHERE policy_name = $1;`
+
+func (db *AgbotPostgresqlDB) FindRollouts() ([]persistence.Rollout, error) {
+
+	rows, err := db.db.Query(ROLLOUT_QUERY_ALL)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for rollouts, error: %v", err))
+	}
+
+	// If the rows object doesnt get closed, memory and connections will grow and/or leak.
+	defer rows.Close()
+
+	rollouts := make([]persistence.Rollout, 0)
+	for rows.Next() {
+		rBytes := make([]byte, 0, 1024)
+		var r persistence.Rollout
+		if err := rows.Scan(&rBytes); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning row: %v", err))
+		} else if err := json.Unmarshal(rBytes, &r); err != nil {
+			return nil, errors.New(fmt.Sprintf("error demarshalling row: %v, error: %v", string(rBytes), err))
+		} else {
+			rollouts = append(rollouts, r)
+		}
+	}
+
+	// The rows.Next() function will exit with false when done or an error occurred. Get any error encountered during iteration.
+	if err = rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating: %v", err))
+	}
+	return rollouts, nil
+}
+
+// Returns the rollout of the deployment policy, or nil if there is none.
+func (db *AgbotPostgresqlDB) FindRollout(policyName string) (*persistence.Rollout, error) {
+	return scanRollout(db.db.QueryRow(ROLLOUT_QUERY, policyName), policyName)
+}
+
+// Calls the update function with the current rollout of the deployment policy (nil if there is none) and writes the
+// rollout that it returns. The rollout row is locked while the function runs, so that concurrent updates from several
+// agbots are serialized. When the function returns nil, nothing is written. Returns the rollout that is in the database
+// after the update.
+func (db *AgbotPostgresqlDB) SingleRolloutUpdate(policyName string, fn func(*persistence.Rollout) *persistence.Rollout) (*persistence.Rollout, error) {
+
+	// When another agbot inserts the rollout between the read and the insert, the update is retried on its row.
+	for attempt := 0; attempt < 2; attempt++ {
+		if rollout, inserted, err := db.rolloutUpdateTransaction(policyName, fn); err != nil {
+			return nil, err
+		} else if inserted {
+			return rollout, nil
+		}
+		glog.V(3).Infof("Rollout for policy %v was created by another agbot, retrying the update", policyName)
+	}
+	return nil, errors.New(fmt.Sprintf("unable to update rollout for policy %v, the rollout is changing concurrently", policyName))
+}
+
+// Runs the rollout update in a transaction. Returns false when the rollout could not be inserted because another agbot
+// inserted it first.
+func (db *AgbotPostgresqlDB) rolloutUpdateTransaction(policyName string, fn func(*persistence.Rollout) *persistence.Rollout) (*persistence.Rollout, bool, error) {
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+
+	current, err := scanRollout(tx.QueryRow(ROLLOUT_QUERY_FOR_UPDATE, policyName), policyName)
+	if err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+
+	updated := fn(current)
+	if updated == nil {
+		return current, true, tx.Commit()
+	}
+
+	rBytes, err := json.Marshal(updated)
+	if err != nil {
+		tx.Rollback()
+		return nil, false, errors.New(fmt.Sprintf("error marshalling rollout %v, error: %v", updated, err))
+	}
+
+	if current != nil {
+		if _, err := tx.Exec(ROLLOUT_UPDATE, policyName, rBytes); err != nil {
+			tx.Rollback()
+			return nil, false, errors.New(fmt.Sprintf("error updating rollout %v, error: %v", updated, err))
+		}
+	} else {
+		var name string
+		if err := tx.QueryRow(ROLLOUT_INSERT, policyName, rBytes).Scan(&name); err == sql.ErrNoRows {
+			tx.Rollback()
+			return nil, false, nil
+		} else if err != nil {
+			tx.Rollback()
+			return nil, false, errors.New(fmt.Sprintf("error inserting rollout %v, error: %v", updated, err))
+		}
+	}
+
+	glog.V(5).Infof("Succeeded writing rollout %v", updated)
+	return updated, true, tx.Commit()
+}
+
+func (db *AgbotPostgresqlDB) DeleteRollout(policyName string) error {
+	if _, err := db.db.Exec(ROLLOUT_DELETE, policyName); err != nil {
+		return errors.New(fmt.Sprintf("error deleting rollout for policy %v, error: %v", policyName, err))
+	}
+	return nil
+}
+
+// Returns the rollout in the row, or nil if there is no row.
+func scanRollout(row *sql.Row, policyName string) (*persistence.Rollout, error) {
+	rBytes := make([]byte, 0, 1024)
+	if err := row.Scan(&rBytes); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for rollout of policy %v, error: %v", policyName, err))
+	}
+
+	r := new(persistence.Rollout)
+	if err := json.Unmarshal(rBytes, r); err != nil {
+		return nil, errors.New(fmt.Sprintf("error demarshalling rollout: %v, error: %v", string(rBytes), err))
+	}
+	return r, nil
+}
@@ -92,6 +92,13 @@ const WORKLOAD_USAGE_MOVE = `WITH moved_rows AS (
 INSERT INTO "workload_usages_ (device_id, policy_name, partition, workload_usage) SELECT device_id, policy_name, 'partition_name', workload_usage FROM moved_rows;
 `
 
+const WORKLOAD_USAGE_MOVE_ONE = `WITH moved_rows AS (
+    DELETE FROM "workload_usages_ a WHERE a.device_id = $1 AND a.policy_name = $2
+    RETURNING a.device_id, a.policy_name, a.workload_usage
+)
+INSERT INTO "workload_usages_ (device_id, policy_name, partition, workload_usage) SELECT device_id, policy_name, 'partition_name', workload_usage FROM moved_rows;
+`
+
 const WORKLOAD_USAGE_DROP_PARTITION = `DROP TABLE "workload_usages_;`
 
 func (db *AgbotPostgresqlDB) GetWorkloadUsagePartitionTableName(partition string) string {
@@ -134,6 +141,14 @@ func (db *AgbotPostgresqlDB) GetWorkloadUsagePartitionMove(fromPartition string,
 	return sql
 }
 
+// Same as GetWorkloadUsagePartitionMove, but for the workload usage of a single node and policy.
+func (db *AgbotPostgresqlDB) GetWorkloadUsageMove(fromPartition string, toPartition string) string {
+	sql := strings.Replace(WORKLOAD_USAGE_MOVE_ONE, WORKLOAD_USAGE_TABLE_NAME_ROOT, db.GetWorkloadUsagePartitionTableName(toPartition), 2)
+	sql = strings.Replace(sql, db.GetWorkloadUsagePartitionTableName(toPartition), db.GetWorkloadUsagePartitionTableName(fromPartition), 1)
+	sql = strings.Replace(sql, WORKLOAD_USAGE_PARTITION_FILLIN, toPartition, 1)
+	return sql
+}
+
 // The partition table name replacement scheme used in this function is slightly different from the others above.
 func (db *AgbotPostgresqlDB) GetWorkloadUsagesCount(partition string) (int64, error) {
 	var num int64
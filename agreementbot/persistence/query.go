@@ -0,0 +1,287 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/policy"
+	"sort"
+	"strings"
+)
+
+// The states of an agreement that a query can select.
+const (
+	AQ_STATE_ACTIVE      = "active"      // not archived and not being terminated
+	AQ_STATE_PENDING     = "pending"     // active, but not finalized yet
+	AQ_STATE_FINALIZED   = "finalized"   // active and finalized
+	AQ_STATE_TERMINATING = "terminating" // timed out or being cancelled, but not archived yet
+	AQ_STATE_ARCHIVED    = "archived"    // archived
+)
+
+// The fields that the query results can be sorted on.
+const (
+	Q_SORT_INCEPTION = "inception_time" // agreements only, the default for agreements
+	Q_SORT_CREATION  = "creation_time"  // agreements only
+	Q_SORT_TIMEOUT   = "timeout_time"   // agreements only
+	Q_SORT_NODE      = "node"           // the default for workload usages
+	Q_SORT_POLICY    = "policy"
+)
+
+// Returns the policy name filter value for a policy in an org, the internal policy names are org/name.
+func orgPrefix(org string) string {
+	return org + "/"
+}
+
+// The criteria used to search a page of agreements. Empty fields match everything. The results are sorted on the sort
+// field, and the agreement id when the sort field is the same.
+type AgreementQuery struct {
+	DeviceId       string // the node, org/id
+	Org            string // the org of the policy or pattern used to make the agreement
+	PolicyName     string // the internal policy name, org/name
+	ServiceURL     string // the top level service URL, optionally prefixed by its org as org/url
+	ServiceVersion string // the version of the top level service
+	State          string // one of the AQ_STATE_* constants
+	Since          uint64 // agreements started at or after this time
+	Until          uint64 // agreements started at or before this time
+	Sort           string // one of the Q_SORT_* constants that apply to agreements
+	Descending     bool
+	Offset         int // the number of agreements to skip
+	Limit          int // the maximum number of agreements, 0 for no limit
+}
+
+func (q AgreementQuery) String() string {
+	return fmt.Sprintf("DeviceId: %v, Org: %v, PolicyName: %v, ServiceURL: %v, ServiceVersion: %v, State: %v, Since: %v, Until: %v, Sort: %v, Descending: %v, Offset: %v, Limit: %v",
+		q.DeviceId, q.Org, q.PolicyName, q.ServiceURL, q.ServiceVersion, q.State, q.Since, q.Until, q.Sort, q.Descending, q.Offset, q.Limit)
+}
+
+// Returns an error for a state or sort field that is not supported.
+func (q AgreementQuery) Validate() error {
+	switch q.State {
+	case "", AQ_STATE_ACTIVE, AQ_STATE_PENDING, AQ_STATE_FINALIZED, AQ_STATE_TERMINATING, AQ_STATE_ARCHIVED:
+	default:
+		return errors.New(fmt.Sprintf("state %v is not supported, it must be one of %v", q.State, []string{AQ_STATE_ACTIVE, AQ_STATE_PENDING, AQ_STATE_FINALIZED, AQ_STATE_TERMINATING, AQ_STATE_ARCHIVED}))
+	}
+	switch q.Sort {
+	case "", Q_SORT_INCEPTION, Q_SORT_CREATION, Q_SORT_TIMEOUT, Q_SORT_NODE, Q_SORT_POLICY:
+	default:
+		return errors.New(fmt.Sprintf("sort %v is not supported, it must be one of %v", q.Sort, []string{Q_SORT_INCEPTION, Q_SORT_CREATION, Q_SORT_TIMEOUT, Q_SORT_NODE, Q_SORT_POLICY}))
+	}
+	if q.Offset < 0 || q.Limit < 0 {
+		return errors.New("offset and limit must not be negative")
+	}
+	return nil
+}
+
+// Returns the filters that select the agreements of the query.
+func (q AgreementQuery) Filters() []AFilter {
+	filters := make([]AFilter, 0, 5)
+	if q.DeviceId != "" {
+		filters = append(filters, DeviceAFilter(q.DeviceId))
+	}
+	if q.Org != "" {
+		filters = append(filters, OrgAFilter(q.Org))
+	}
+	if q.PolicyName != "" {
+		filters = append(filters, PolicyAFilter(q.PolicyName))
+	}
+	if q.ServiceURL != "" || q.ServiceVersion != "" {
+		filters = append(filters, ServiceAFilter(q.ServiceURL, q.ServiceVersion))
+	}
+	if q.State != "" {
+		filters = append(filters, StateAFilter(q.State))
+	}
+	if q.Since != 0 || q.Until != 0 {
+		filters = append(filters, InceptionAFilter(q.Since, q.Until))
+	}
+	return filters
+}
+
+// Sort the agreements that match the query and return the page selected by the offset and limit, along with the
+// number of agreements that match the query.
+func (q AgreementQuery) Page(ags []Agreement) ([]Agreement, int) {
+	less := func(i, j int) bool {
+		var a, b interface{}
+		switch q.Sort {
+		case Q_SORT_CREATION:
+			a, b = ags[i].AgreementCreationTime, ags[j].AgreementCreationTime
+		case Q_SORT_TIMEOUT:
+			a, b = ags[i].AgreementTimedout, ags[j].AgreementTimedout
+		case Q_SORT_NODE:
+			a, b = ags[i].DeviceId, ags[j].DeviceId
+		case Q_SORT_POLICY:
+			a, b = ags[i].PolicyName, ags[j].PolicyName
+		default:
+			a, b = ags[i].AgreementInceptionTime, ags[j].AgreementInceptionTime
+		}
+		if c := compare(a, b); c != 0 {
+			return (c < 0) != q.Descending
+		}
+		return (ags[i].CurrentAgreementId < ags[j].CurrentAgreementId) != q.Descending
+	}
+	sort.SliceStable(ags, less)
+	start, end := pageBounds(len(ags), q.Offset, q.Limit)
+	return ags[start:end], len(ags)
+}
+
+// The criteria used to search a page of workload usages. Empty fields match everything. The results are sorted on the
+// sort field, and the node and policy when the sort field is the same.
+type WorkloadUsageQuery struct {
+	DeviceId   string // the node, org/id
+	Org        string // the org of the policy
+	PolicyName string // the internal policy name, org/name
+	Sort       string // Q_SORT_NODE or Q_SORT_POLICY
+	Descending bool
+	Offset     int // the number of workload usages to skip
+	Limit      int // the maximum number of workload usages, 0 for no limit
+}
+
+func (q WorkloadUsageQuery) String() string {
+	return fmt.Sprintf("DeviceId: %v, Org: %v, PolicyName: %v, Sort: %v, Descending: %v, Offset: %v, Limit: %v",
+		q.DeviceId, q.Org, q.PolicyName, q.Sort, q.Descending, q.Offset, q.Limit)
+}
+
+// Returns an error for a sort field that is not supported.
+func (q WorkloadUsageQuery) Validate() error {
+	switch q.Sort {
+	case "", Q_SORT_NODE, Q_SORT_POLICY:
+	default:
+		return errors.New(fmt.Sprintf("sort %v is not supported, it must be one of %v", q.Sort, []string{Q_SORT_NODE, Q_SORT_POLICY}))
+	}
+	if q.Offset < 0 || q.Limit < 0 {
+		return errors.New("offset and limit must not be negative")
+	}
+	return nil
+}
+
+// Returns the filters that select the workload usages of the query.
+func (q WorkloadUsageQuery) Filters() []WUFilter {
+	filters := make([]WUFilter, 0, 3)
+	if q.DeviceId != "" {
+		filters = append(filters, DWUFilter(q.DeviceId))
+	}
+	if q.Org != "" {
+		filters = append(filters, OrgWUFilter(q.Org))
+	}
+	if q.PolicyName != "" {
+		filters = append(filters, PWUFilter(q.PolicyName))
+	}
+	return filters
+}
+
+// Sort the workload usages that match the query and return the page selected by the offset and limit, along with the
+// number of workload usages that match the query.
+func (q WorkloadUsageQuery) Page(wus []WorkloadUsage) ([]WorkloadUsage, int) {
+	less := func(i, j int) bool {
+		first, second := wus[i].DeviceId, wus[j].DeviceId
+		tieFirst, tieSecond := wus[i].PolicyName, wus[j].PolicyName
+		if q.Sort == Q_SORT_POLICY {
+			first, second, tieFirst, tieSecond = tieFirst, tieSecond, first, second
+		}
+		if first != second {
+			return (first < second) != q.Descending
+		}
+		return (tieFirst < tieSecond) != q.Descending
+	}
+	sort.SliceStable(wus, less)
+	start, end := pageBounds(len(wus), q.Offset, q.Limit)
+	return wus[start:end], len(wus)
+}
+
+// Returns the slice bounds of a page of a result set of the given size.
+func pageBounds(size int, offset int, limit int) (int, int) {
+	start := offset
+	if start > size {
+		start = size
+	}
+	end := size
+	if limit > 0 && start+limit < size {
+		end = start + limit
+	}
+	return start, end
+}
+
+// Compares 2 values of the same sort field.
+func compare(a interface{}, b interface{}) int {
+	switch av := a.(type) {
+	case uint64:
+		if bv := b.(uint64); av < bv {
+			return -1
+		} else if av > bv {
+			return 1
+		}
+	case string:
+		return strings.Compare(av, b.(string))
+	}
+	return 0
+}
+
+// Filters for the agreement queries.
+func DeviceAFilter(deviceId string) AFilter {
+	return func(a Agreement) bool { return a.DeviceId == deviceId }
+}
+
+func OrgAFilter(org string) AFilter {
+	return func(a Agreement) bool { return a.Org == org }
+}
+
+func PolicyAFilter(policyName string) AFilter {
+	return func(a Agreement) bool { return a.PolicyName == policyName }
+}
+
+// Agreements whose top level service has the given URL, optionally prefixed by its org, and version. The service is
+// only known after the proposal is made.
+func ServiceAFilter(url string, version string) AFilter {
+	return func(a Agreement) bool {
+		org, svcURL, svcVersion := a.TopLevelService()
+		if url != "" && svcURL != url && orgPrefix(org)+svcURL != url {
+			return false
+		}
+		return version == "" || svcVersion == version
+	}
+}
+
+func StateAFilter(state string) AFilter {
+	return func(a Agreement) bool {
+		active := !a.Archived && a.AgreementTimedout == 0
+		switch state {
+		case AQ_STATE_ACTIVE:
+			return active
+		case AQ_STATE_PENDING:
+			return active && a.AgreementFinalizedTime == 0
+		case AQ_STATE_FINALIZED:
+			return active && a.AgreementFinalizedTime != 0
+		case AQ_STATE_TERMINATING:
+			return !a.Archived && a.AgreementTimedout != 0
+		case AQ_STATE_ARCHIVED:
+			return a.Archived
+		}
+		return true
+	}
+}
+
+// Agreements started within the time range, a zero bound is not checked.
+func InceptionAFilter(since uint64, until uint64) AFilter {
+	return func(a Agreement) bool {
+		return (since == 0 || a.AgreementInceptionTime >= since) && (until == 0 || a.AgreementInceptionTime <= until)
+	}
+}
+
+// Filters for the workload usage queries.
+func OrgWUFilter(org string) WUFilter {
+	return func(w WorkloadUsage) bool { return strings.HasPrefix(w.PolicyName, orgPrefix(org)) }
+}
+
+// Returns the org, URL and version of the top level service of the agreement, which are empty before the proposal
+// is made.
+func (a Agreement) TopLevelService() (string, string, string) {
+	if a.Policy == "" {
+		return "", "", ""
+	} else if pol, err := policy.DemarshalPolicy(a.Policy); err != nil {
+		glog.Warningf("unable to demarshal policy of agreement %v, error: %v", a.CurrentAgreementId, err)
+		return "", "", ""
+	} else if len(pol.Workloads) == 0 {
+		return "", "", ""
+	} else {
+		return pol.Workloads[0].Org, pol.Workloads[0].WorkloadURL, pol.Workloads[0].Version
+	}
+}
@@ -0,0 +1,81 @@
+//go:build unit
+// +build unit
+
+package persistence
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/policy"
+	"testing"
+)
+
+func Test_AgreementQuery_Filters(t *testing.T) {
+
+	pol := policy.Policy_Factory("bp1")
+	pol.Workloads = append(pol.Workloads, policy.Workload{WorkloadURL: "svc", Org: "svcorg", Version: "1.2.0", Arch: "amd64"})
+	polBytes, _ := json.Marshal(pol)
+
+	ags := []Agreement{
+		{CurrentAgreementId: "ag1", Org: "myorg", DeviceId: "myorg/node1", PolicyName: "myorg/bp1", AgreementInceptionTime: 100, AgreementFinalizedTime: 110, Policy: string(polBytes)},
+		{CurrentAgreementId: "ag2", Org: "myorg", DeviceId: "myorg/node2", PolicyName: "myorg/bp1", AgreementInceptionTime: 200},
+		{CurrentAgreementId: "ag3", Org: "other", DeviceId: "other/node3", PolicyName: "other/bp2", AgreementInceptionTime: 300, AgreementTimedout: 310},
+		{CurrentAgreementId: "ag4", Org: "other", DeviceId: "other/node3", PolicyName: "other/bp2", AgreementInceptionTime: 50, AgreementTimedout: 60, Archived: true},
+	}
+
+	queries := map[string]struct {
+		query AgreementQuery
+		ids   []string
+	}{
+		"all":         {AgreementQuery{}, []string{"ag4", "ag1", "ag2", "ag3"}},
+		"node":        {AgreementQuery{DeviceId: "other/node3"}, []string{"ag4", "ag3"}},
+		"org":         {AgreementQuery{Org: "myorg"}, []string{"ag1", "ag2"}},
+		"service":     {AgreementQuery{ServiceURL: "svcorg/svc", ServiceVersion: "1.2.0"}, []string{"ag1"}},
+		"version":     {AgreementQuery{ServiceVersion: "1.0.0"}, []string{}},
+		"active":      {AgreementQuery{State: AQ_STATE_ACTIVE}, []string{"ag1", "ag2"}},
+		"pending":     {AgreementQuery{State: AQ_STATE_PENDING}, []string{"ag2"}},
+		"terminating": {AgreementQuery{State: AQ_STATE_TERMINATING}, []string{"ag3"}},
+		"archived":    {AgreementQuery{State: AQ_STATE_ARCHIVED}, []string{"ag4"}},
+		"time range":  {AgreementQuery{Since: 100, Until: 200}, []string{"ag1", "ag2"}},
+		"node sort":   {AgreementQuery{Sort: Q_SORT_NODE, Descending: true}, []string{"ag4", "ag3", "ag2", "ag1"}},
+	}
+
+	for name, q := range queries {
+		matched := make([]Agreement, 0)
+		for _, ag := range ags {
+			if RunFilters(&ag, q.query.Filters()) != nil {
+				matched = append(matched, ag)
+			}
+		}
+		page, total := q.query.Page(matched)
+		if total != len(q.ids) || len(page) != len(q.ids) {
+			t.Errorf("%v: expected %v agreements, got %v of %v", name, q.ids, page, total)
+			continue
+		}
+		for i := range page {
+			if page[i].CurrentAgreementId != q.ids[i] {
+				t.Errorf("%v: expected %v at %v, got %v", name, q.ids[i], i, page[i].CurrentAgreementId)
+			}
+		}
+	}
+}
+
+func Test_Query_Page(t *testing.T) {
+
+	wus := []WorkloadUsage{{DeviceId: "n3", PolicyName: "o/p1"}, {DeviceId: "n1", PolicyName: "o/p2"}, {DeviceId: "n2", PolicyName: "o/p1"}}
+
+	if page, total := (WorkloadUsageQuery{Offset: 1, Limit: 1}).Page(wus); total != 3 || len(page) != 1 || page[0].DeviceId != "n2" {
+		t.Errorf("wrong page %v of %v", page, total)
+	}
+	if page, total := (WorkloadUsageQuery{Sort: Q_SORT_POLICY, Limit: 2}).Page(wus); total != 3 || len(page) != 2 || page[0].DeviceId != "n2" || page[1].DeviceId != "n3" {
+		t.Errorf("wrong page sorted by policy %v of %v", page, total)
+	}
+	if page, total := (WorkloadUsageQuery{Offset: 5}).Page(wus); total != 3 || len(page) != 0 {
+		t.Errorf("a page past the end should be empty, got %v of %v", page, total)
+	}
+
+	if err := (AgreementQuery{State: "done"}).Validate(); err == nil {
+		t.Errorf("an unknown state should not be valid")
+	} else if err := (WorkloadUsageQuery{Sort: Q_SORT_TIMEOUT}).Validate(); err == nil {
+		t.Errorf("the workload usages can not be sorted on the timeout time")
+	}
+}
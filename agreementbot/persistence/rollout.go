@@ -0,0 +1,104 @@
+package persistence
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/externalpolicy"
+	"time"
+)
+
+// The state of a staged rollout of a service version. A rollout starts when an agbot first sees the version with a
+// rollout policy as the highest priority version of a deployment policy. The rollout state is kept in the database, so
+// that it survives a restart of the agbot and so that all the agbots that share the database govern the same rollout.
+type Rollout struct {
+	PolicyName    string                       `json:"policyName"`           // the internal name (org/name) of the deployment policy
+	ServiceURL    string                       `json:"serviceUrl"`           // the top level service of the deployment policy
+	ServiceOrg    string                       `json:"serviceOrg"`           // the org of the top level service
+	Version       string                       `json:"version"`              // the service version being rolled out
+	Priority      int                          `json:"priority"`             // the priority value of the service version being rolled out
+	Rollout       businesspolicy.RolloutPolicy `json:"rollout"`              // the rollout policy of the service version
+	StartTime     uint64                       `json:"startTime"`            // the time when the rollout started
+	StartedStages map[string]int               `json:"startedStages"`        // the highest stage that the governance routine has started in each agbot partition
+	FailedNodes   map[string]string            `json:"failedNodes"`          // the upgraded nodes that failed to run the new version and why
+	Halted        bool                         `json:"halted"`               // the rollout was halted because too many nodes failed
+	HaltReason    string                       `json:"haltReason,omitempty"` // why the rollout was halted
+}
+
+func (r *Rollout) String() string {
+	return fmt.Sprintf("Rollout: "+
+		"PolicyName: %v "+
+		"Service: %v/%v "+
+		"Version: %v "+
+		"Priority: %v "+
+		"Rollout: %v "+
+		"StartTime: %v "+
+		"StartedStages: %v "+
+		"FailedNodes: %v "+
+		"Halted: %v "+
+		"HaltReason: %v",
+		r.PolicyName, r.ServiceOrg, r.ServiceURL, r.Version, r.Priority, r.Rollout, r.StartTime, r.StartedStages, r.FailedNodes, r.Halted, r.HaltReason)
+}
+
+// return a pointer to a copy of Rollout
+func (r *Rollout) DeepCopy() *Rollout {
+	newRollout := *r
+	newRollout.StartedStages = make(map[string]int, len(r.StartedStages))
+	for k, v := range r.StartedStages {
+		newRollout.StartedStages[k] = v
+	}
+	newRollout.FailedNodes = make(map[string]string, len(r.FailedNodes))
+	for k, v := range r.FailedNodes {
+		newRollout.FailedNodes[k] = v
+	}
+	return &newRollout
+}
+
+func NewRollout(polName string, service *businesspolicy.ServiceRef, choice *businesspolicy.WorkloadChoice) *Rollout {
+	return &Rollout{
+		PolicyName:    polName,
+		ServiceURL:    service.Name,
+		ServiceOrg:    service.Org,
+		Version:       choice.Version,
+		Priority:      choice.Priority.PriorityValue,
+		Rollout:       *choice.Upgrade.Rollout,
+		StartTime:     uint64(time.Now().Unix()),
+		StartedStages: make(map[string]int),
+		FailedNodes:   make(map[string]string),
+	}
+}
+
+// Returns the highest stage that the governance routine has started in the given agbot partition.
+func (r *Rollout) StartedStage(partition string) int {
+	return r.StartedStages[partition]
+}
+
+// Returns the number of seconds the rollout has been running at the given time.
+func (r *Rollout) elapsed(now uint64) int {
+	if now < r.StartTime {
+		return 0
+	}
+	return int(now - r.StartTime)
+}
+
+// Returns the highest stage that should be started at the given time.
+func (r *Rollout) CurrentStage(now uint64) int {
+	return r.Rollout.StartedStage(r.elapsed(now))
+}
+
+// A rollout is complete when all of its stages have started and the last one has soaked.
+func (r *Rollout) IsComplete(now uint64) bool {
+	return r.elapsed(now) >= r.Rollout.Duration()
+}
+
+// Returns true if the node should stay on the lower priority version, along with the reason. The node is held
+// when the rollout is halted or when the node's stage has not started yet.
+func (r *Rollout) HoldsNode(nodeId string, nodeProps externalpolicy.PropertyList, now uint64) (bool, string) {
+	if r.Halted {
+		return true, fmt.Sprintf("the rollout of version %v is halted: %v", r.Version, r.HaltReason)
+	} else if r.IsComplete(now) {
+		return false, ""
+	} else if stage := r.Rollout.NodeStage(nodeId, nodeProps); stage > r.CurrentStage(now) {
+		return true, fmt.Sprintf("rollout stage %v for version %v starts in %v seconds", stage, r.Version, r.Rollout.StageStartOffset(stage)-r.elapsed(now))
+	}
+	return false, ""
+}
@@ -220,6 +220,7 @@ func (n *PrioritizedWorkQueue) run() {
 				stats = n.queueHistory.Collect(stats, false)
 				n.AddToHighPriorityBuffer(i)
 				stats.consumedInboundHigh()
+				workQueueItemsCounter.WithLabelValues(HIGH_PRIORITY, WQ_DIRECTION_QUEUED).Inc()
 			} else {
 				// The channel must be closed now.
 				glog.V(3).Infof(pwqString("closing inbound high"))
@@ -231,6 +232,7 @@ func (n *PrioritizedWorkQueue) run() {
 				stats = n.queueHistory.Collect(stats, false)
 				n.AddToLowPriorityBuffer(i)
 				stats.consumedInboundLow()
+				workQueueItemsCounter.WithLabelValues(LOW_PRIORITY, WQ_DIRECTION_QUEUED).Inc()
 			} else {
 				// The channel must be closed now.
 				glog.V(3).Infof(pwqString("closing inbound low"))
@@ -241,9 +243,11 @@ func (n *PrioritizedWorkQueue) run() {
 			if whichInbound == HIGH_PRIORITY {
 				n.RemoveHighPriorityBufferHead()
 				stats.consumedHighBuffered()
+				workQueueItemsCounter.WithLabelValues(HIGH_PRIORITY, WQ_DIRECTION_DISPATCHED).Inc()
 			} else if whichInbound == LOW_PRIORITY {
 				n.RemoveLowPriorityBufferHead()
 				stats.consumedLowBuffered()
+				workQueueItemsCounter.WithLabelValues(LOW_PRIORITY, WQ_DIRECTION_DISPATCHED).Inc()
 			}
 			stats = n.queueHistory.Collect(stats, false)
 		}
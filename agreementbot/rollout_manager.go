@@ -0,0 +1,188 @@
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/externalpolicy"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// The rollout manager tracks the staged rollouts of the deployment policies served by this agbot. The rollouts are
+// kept in the agbot database, so a restarted agbot continues a rollout where it left off, and all the agbots that share
+// the database govern the same rollout. Each agbot starts the stages of a rollout for the nodes in its own partition.
+type RolloutManager struct {
+	db persistence.AgbotDatabase
+}
+
+func NewRolloutManager(db persistence.AgbotDatabase) *RolloutManager {
+	return &RolloutManager{
+		db: db,
+	}
+}
+
+func (rm *RolloutManager) String() string {
+	res := "Rollout Manager: "
+	if rollouts, err := rm.db.FindRollouts(); err != nil {
+		res += fmt.Sprintf("error: %v", err)
+	} else {
+		for _, r := range rollouts {
+			res += fmt.Sprintf("%v ", &r)
+		}
+	}
+	return res
+}
+
+// Start, update or remove the rollout of a deployment policy. Only the highest priority service version is rolled out.
+// Changing the rollout policy of the version being rolled out restarts the rollout, which also resumes a halted rollout.
+func (rm *RolloutManager) UpdateRollout(polName string, pol *businesspolicy.BusinessPolicy) {
+
+	choice := pol.Service.HighestPriorityChoice()
+	if choice == nil || choice.Upgrade.Rollout == nil {
+		rm.DeleteRollout(polName)
+		return
+	}
+
+	_, err := rm.db.SingleRolloutUpdate(polName, func(current *persistence.Rollout) *persistence.Rollout {
+		if current != nil && current.Version == choice.Version && current.Priority == choice.Priority.PriorityValue && reflect.DeepEqual(current.Rollout, *choice.Upgrade.Rollout) {
+			return nil
+		}
+		r := persistence.NewRollout(polName, &pol.Service, choice)
+		glog.V(3).Infof(logString(fmt.Sprintf("rollout manager starting rollout %v", r)))
+		return r
+	})
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("rollout manager unable to update the rollout of policy %v, error: %v", polName, err)))
+	}
+}
+
+func (rm *RolloutManager) DeleteRollout(polName string) {
+	if r, err := rm.db.FindRollout(polName); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("rollout manager unable to read the rollout of policy %v, error: %v", polName, err)))
+	} else if r == nil {
+		return
+	}
+
+	glog.V(3).Infof(logString(fmt.Sprintf("rollout manager removing rollout for policy %v", polName)))
+	if err := rm.db.DeleteRollout(polName); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("rollout manager unable to delete the rollout of policy %v, error: %v", polName, err)))
+	}
+}
+
+// Returns the rollout for the given deployment policy, or nil if there is none.
+func (rm *RolloutManager) GetRollout(polName string) *persistence.Rollout {
+	r, err := rm.db.FindRollout(polName)
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("rollout manager unable to read the rollout of policy %v, error: %v", polName, err)))
+	}
+	return r
+}
+
+// Returns the rollouts that still need to be governed, which are the ones that are neither halted nor complete.
+func (rm *RolloutManager) GetActiveRollouts() []persistence.Rollout {
+
+	res := make([]persistence.Rollout, 0)
+	rollouts, err := rm.db.FindRollouts()
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("rollout manager unable to read the rollouts, error: %v", err)))
+		return res
+	}
+
+	now := uint64(time.Now().Unix())
+	for _, r := range rollouts {
+		if !r.Halted && !r.IsComplete(now) {
+			res = append(res, r)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].PolicyName < res[j].PolicyName })
+	return res
+}
+
+// Returns true if the given node should stay on a lower priority version than the given version of the policy. When
+// the rollout can not be read, the node is held so that it is not upgraded ahead of its stage.
+func (rm *RolloutManager) HoldsNode(polName string, version string, nodeId string, nodeProps externalpolicy.PropertyList) (bool, string) {
+
+	if r, err := rm.db.FindRollout(polName); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("rollout manager unable to read the rollout of policy %v, error: %v", polName, err)))
+		return true, fmt.Sprintf("unable to read the rollout of policy %v", polName)
+	} else if r != nil && r.Version == version {
+		return r.HoldsNode(nodeId, nodeProps, uint64(time.Now().Unix()))
+	}
+	return false, ""
+}
+
+// Record the upgraded nodes that failed to run the new version. Returns the total number of failed nodes, including
+// the ones recorded by the other agbots.
+func (rm *RolloutManager) AddFailedNodes(polName string, version string, failed map[string]string) int {
+
+	r, err := rm.db.SingleRolloutUpdate(polName, func(current *persistence.Rollout) *persistence.Rollout {
+		if current == nil || current.Version != version {
+			return nil
+		}
+		updated := current.DeepCopy()
+		changed := false
+		for nodeId, reason := range failed {
+			if _, found := updated.FailedNodes[nodeId]; !found {
+				glog.Warningf(logString(fmt.Sprintf("rollout manager detected node %v failing version %v of policy %v: %v", nodeId, version, polName, reason)))
+				updated.FailedNodes[nodeId] = reason
+				changed = true
+			}
+		}
+		if !changed {
+			return nil
+		}
+		return updated
+	})
+
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("rollout manager unable to record the failed nodes of policy %v, error: %v", polName, err)))
+		return 0
+	} else if r == nil || r.Version != version {
+		return 0
+	}
+	return len(r.FailedNodes)
+}
+
+// Halt the rollout so that no more nodes are upgraded.
+func (rm *RolloutManager) HaltRollout(polName string, version string, reason string) {
+
+	_, err := rm.db.SingleRolloutUpdate(polName, func(current *persistence.Rollout) *persistence.Rollout {
+		if current == nil || current.Version != version || current.Halted {
+			return nil
+		}
+		glog.Warningf(logString(fmt.Sprintf("rollout manager halting rollout of version %v of policy %v: %v", version, polName, reason)))
+		updated := current.DeepCopy()
+		updated.Halted = true
+		updated.HaltReason = reason
+		return updated
+	})
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("rollout manager unable to halt the rollout of policy %v, error: %v", polName, err)))
+	}
+}
+
+// Returns the highest stage of the rollout that the governance routine of this agbot has started.
+func (rm *RolloutManager) StartedStage(r *persistence.Rollout) int {
+	return r.StartedStage(rm.db.PrimaryPartition())
+}
+
+// Record that the governance routine of this agbot has started the given stage.
+func (rm *RolloutManager) SetStartedStage(polName string, version string, stage int) {
+
+	partition := rm.db.PrimaryPartition()
+	_, err := rm.db.SingleRolloutUpdate(polName, func(current *persistence.Rollout) *persistence.Rollout {
+		if current == nil || current.Version != version || stage <= current.StartedStage(partition) {
+			return nil
+		}
+		glog.V(3).Infof(logString(fmt.Sprintf("rollout manager started stage %v of the rollout of version %v of policy %v in partition %v", stage, version, polName, partition)))
+		updated := current.DeepCopy()
+		updated.StartedStages[partition] = stage
+		return updated
+	})
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("rollout manager unable to record the started stage of policy %v, error: %v", polName, err)))
+	}
+}
@@ -0,0 +1,213 @@
+//go:build unit
+// +build unit
+
+package agreementbot
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/businesspolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"testing"
+	"time"
+)
+
+// A database that only implements the functions that are used by the rollout manager.
+type rolloutTestDB struct {
+	persistence.AgbotDatabase
+	rollouts map[string]*persistence.Rollout
+}
+
+func newRolloutTestDB() *rolloutTestDB {
+	return &rolloutTestDB{rollouts: map[string]*persistence.Rollout{}}
+}
+
+func (db *rolloutTestDB) PrimaryPartition() string {
+	return "global"
+}
+
+func (db *rolloutTestDB) FindRollouts() ([]persistence.Rollout, error) {
+	res := []persistence.Rollout{}
+	for _, r := range db.rollouts {
+		res = append(res, *r.DeepCopy())
+	}
+	return res, nil
+}
+
+func (db *rolloutTestDB) FindRollout(policyName string) (*persistence.Rollout, error) {
+	if r, ok := db.rollouts[policyName]; ok {
+		return r.DeepCopy(), nil
+	}
+	return nil, nil
+}
+
+func (db *rolloutTestDB) SingleRolloutUpdate(policyName string, fn func(*persistence.Rollout) *persistence.Rollout) (*persistence.Rollout, error) {
+	current, _ := db.FindRollout(policyName)
+	if updated := fn(current); updated != nil {
+		db.rollouts[policyName] = updated.DeepCopy()
+		return updated, nil
+	}
+	return current, nil
+}
+
+func (db *rolloutTestDB) DeleteRollout(policyName string) error {
+	delete(db.rollouts, policyName)
+	return nil
+}
+
+func getRolloutPolicy(newVersion string, rollout *businesspolicy.RolloutPolicy) *businesspolicy.BusinessPolicy {
+	return &businesspolicy.BusinessPolicy{
+		Service: businesspolicy.ServiceRef{
+			Name: "svc",
+			Org:  "myorg",
+			ServiceVersions: []businesspolicy.WorkloadChoice{
+				businesspolicy.WorkloadChoice{Version: "1.0.0", Priority: businesspolicy.WorkloadPriority{PriorityValue: 2}},
+				businesspolicy.WorkloadChoice{Version: newVersion, Priority: businesspolicy.WorkloadPriority{PriorityValue: 1}, Upgrade: businesspolicy.UpgradePolicy{Rollout: rollout}},
+			},
+		},
+	}
+}
+
+func Test_rollout_manager_update(t *testing.T) {
+
+	rm := NewRolloutManager(newRolloutTestDB())
+	rollout := &businesspolicy.RolloutPolicy{Waves: []int{50, 100}, SoakTimeS: 3600}
+
+	// a new version with a rollout starts a rollout
+	rm.UpdateRollout("myorg/bp1", getRolloutPolicy("1.1.0", rollout))
+	ro := rm.GetRollout("myorg/bp1")
+	if ro == nil {
+		t.Fatalf("there should be a rollout")
+	} else if ro.Version != "1.1.0" || ro.Priority != 1 || ro.ServiceURL != "svc" || ro.ServiceOrg != "myorg" {
+		t.Errorf("wrong rollout: %v", ro)
+	}
+
+	// the same policy does not restart the rollout
+	rm.HaltRollout("myorg/bp1", "1.1.0", "too many failures")
+	rm.UpdateRollout("myorg/bp1", getRolloutPolicy("1.1.0", rollout))
+	if ro := rm.GetRollout("myorg/bp1"); !ro.Halted {
+		t.Errorf("the rollout should still be halted: %v", ro)
+	} else if len(rm.GetActiveRollouts()) != 0 {
+		t.Errorf("a halted rollout should not be active")
+	}
+
+	// changing the rollout restarts it
+	rm.UpdateRollout("myorg/bp1", getRolloutPolicy("1.1.0", &businesspolicy.RolloutPolicy{Waves: []int{100}, SoakTimeS: 3600}))
+	if ro := rm.GetRollout("myorg/bp1"); ro.Halted {
+		t.Errorf("the rollout should have been restarted: %v", ro)
+	} else if len(rm.GetActiveRollouts()) != 1 {
+		t.Errorf("the restarted rollout should be active")
+	}
+
+	// a version without a rollout removes it
+	rm.UpdateRollout("myorg/bp1", getRolloutPolicy("1.2.0", nil))
+	if ro := rm.GetRollout("myorg/bp1"); ro != nil {
+		t.Errorf("the rollout should have been removed: %v", ro)
+	}
+
+	rm.UpdateRollout("myorg/bp1", getRolloutPolicy("1.2.0", rollout))
+	rm.DeleteRollout("myorg/bp1")
+	if ro := rm.GetRollout("myorg/bp1"); ro != nil {
+		t.Errorf("the rollout should have been deleted: %v", ro)
+	}
+}
+
+func Test_rollout_manager_hold(t *testing.T) {
+
+	rm := NewRolloutManager(newRolloutTestDB())
+	rollout := &businesspolicy.RolloutPolicy{Waves: []int{50, 100}, SoakTimeS: 3600}
+	rm.UpdateRollout("myorg/bp1", getRolloutPolicy("1.1.0", rollout))
+
+	// find a node in each wave
+	nodes := map[int]string{}
+	for _, id := range []string{"myorg/n1", "myorg/n2", "myorg/n3", "myorg/n4", "myorg/n5", "myorg/n6", "myorg/n7", "myorg/n8"} {
+		nodes[rollout.NodeStage(id, nil)] = id
+	}
+	if nodes[1] == "" || nodes[2] == "" {
+		t.Fatalf("test nodes should cover both waves: %v", nodes)
+	}
+
+	if held, _ := rm.HoldsNode("myorg/bp1", "1.1.0", nodes[1], nil); held {
+		t.Errorf("node %v in the first wave should not be held", nodes[1])
+	} else if held, reason := rm.HoldsNode("myorg/bp1", "1.1.0", nodes[2], nil); !held || reason == "" {
+		t.Errorf("node %v in the second wave should be held", nodes[2])
+	} else if held, _ := rm.HoldsNode("myorg/bp1", "1.0.0", nodes[2], nil); held {
+		t.Errorf("the previous version should not be held")
+	} else if held, _ := rm.HoldsNode("myorg/bp2", "1.1.0", nodes[2], nil); held {
+		t.Errorf("a policy without a rollout should not hold nodes")
+	}
+
+	// once the soak time has passed, the second wave is upgraded
+	ro := rm.GetRollout("myorg/bp1")
+	now := uint64(time.Now().Unix()) + 3600
+	if held, _ := ro.HoldsNode(nodes[2], nil, now); held {
+		t.Errorf("node %v should not be held after the soak time", nodes[2])
+	} else if ro.CurrentStage(now) != 2 || ro.IsComplete(now) || !ro.IsComplete(now+3600) {
+		t.Errorf("wrong rollout progress: %v", ro)
+	}
+
+	// the failed nodes are counted once, and a halted rollout holds all the nodes
+	if n := rm.AddFailedNodes("myorg/bp1", "1.1.0", map[string]string{nodes[1]: "failed"}); n != 1 {
+		t.Errorf("there should be 1 failed node but got %v", n)
+	} else if n := rm.AddFailedNodes("myorg/bp1", "1.1.0", map[string]string{nodes[1]: "failed again"}); n != 1 {
+		t.Errorf("there should still be 1 failed node but got %v", n)
+	} else if n := rm.AddFailedNodes("myorg/bp1", "1.0.0", map[string]string{nodes[2]: "failed"}); n != 0 {
+		t.Errorf("failures of another version should be ignored")
+	}
+	rm.HaltRollout("myorg/bp1", "1.1.0", "1 node failed")
+	if held, _ := rm.HoldsNode("myorg/bp1", "1.1.0", nodes[1], nil); !held {
+		t.Errorf("a halted rollout should hold all the nodes")
+	}
+}
+
+func Test_rollout_manager_shared_state(t *testing.T) {
+
+	// Two rollout managers that share a database, like a restarted agbot or several agbots, see the same rollout.
+	db := newRolloutTestDB()
+	rm1 := NewRolloutManager(db)
+	rm2 := NewRolloutManager(db)
+	rollout := &businesspolicy.RolloutPolicy{Waves: []int{50, 100}, SoakTimeS: 3600}
+
+	rm1.UpdateRollout("myorg/bp1", getRolloutPolicy("1.1.0", rollout))
+	ro := rm1.GetRollout("myorg/bp1")
+	db.rollouts["myorg/bp1"].StartTime -= 100
+
+	// the same policy seen by the other manager does not restart the rollout clock
+	rm2.UpdateRollout("myorg/bp1", getRolloutPolicy("1.1.0", rollout))
+	if ro2 := rm2.GetRollout("myorg/bp1"); ro2 == nil || ro2.StartTime != ro.StartTime-100 {
+		t.Errorf("the rollout should not have been restarted: %v", ro2)
+	}
+
+	// a halt and the failed nodes recorded by one manager are seen by the other
+	rm1.AddFailedNodes("myorg/bp1", "1.1.0", map[string]string{"myorg/n1": "failed"})
+	if n := rm2.AddFailedNodes("myorg/bp1", "1.1.0", map[string]string{"myorg/n2": "failed"}); n != 2 {
+		t.Errorf("there should be 2 failed nodes but got %v", n)
+	}
+	rm2.HaltRollout("myorg/bp1", "1.1.0", "2 nodes failed")
+	if held, _ := rm1.HoldsNode("myorg/bp1", "1.1.0", "myorg/n3", nil); !held {
+		t.Errorf("the rollout halted by the other manager should hold all the nodes")
+	} else if len(rm1.GetActiveRollouts()) != 0 {
+		t.Errorf("the halted rollout should not be active")
+	}
+
+	rm1.SetStartedStage("myorg/bp1", "1.1.0", 2)
+	if ro := rm2.GetRollout("myorg/bp1"); rm2.StartedStage(ro) != 2 {
+		t.Errorf("the started stage should be 2, got %v", ro.StartedStages)
+	}
+}
+
+func Test_inRolloutStage(t *testing.T) {
+
+	rollout := &businesspolicy.RolloutPolicy{Waves: []int{50, 100}, SoakTimeS: 3600}
+	ro := &persistence.Rollout{PolicyName: "myorg/bp1", Version: "1.1.0", Rollout: *rollout}
+
+	for _, id := range []string{"myorg/n1", "myorg/n2", "myorg/n3", "myorg/n4"} {
+		stage := rollout.NodeStage(id, nil)
+		if !inRolloutStage(ro, id, stage) {
+			t.Errorf("node %v should be in stage %v", id, stage)
+		} else if inRolloutStage(ro, id, 3-stage) {
+			t.Errorf("node %v should not be in stage %v", id, 3-stage)
+		} else if !inRolloutStage(ro, id, 0) {
+			t.Errorf("all the upgraded nodes should be canaries while the canary stage soaks")
+		}
+	}
+}
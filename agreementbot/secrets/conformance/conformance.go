@@ -0,0 +1,307 @@
+// Package conformance contains a test suite that verifies that an agbot secrets provider behaves the way the agbot
+// secure API expects. Every secrets plugin should run the suite from its own tests, the vault plugin runs it in the ci
+// tests because it needs a vault.
+package conformance
+
+import (
+	"github.com/open-horizon/anax/agreementbot/secrets"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The provider under test and the identities the suite uses to call it. The provider must already be initialized
+// and logged in. The admin, user and other user identities are all in Org, only the admin is an org admin.
+type Fixture struct {
+	Provider       secrets.AgbotSecrets
+	Org            string
+	AgbotId        string // The agbot's org qualified exchange id
+	AgbotToken     string
+	AdminId        string // An org qualified org admin
+	AdminToken     string
+	UserId         string // An org qualified user that is not an admin
+	UserToken      string
+	OtherUserId    string // Another org qualified user that is not an admin
+	OtherUserToken string
+}
+
+// Returns a new fixture with an empty secrets provider, it is called once for each test in the suite.
+type FixtureFunc func(t *testing.T) *Fixture
+
+// Run all the conformance tests against the provider returned by newFixture.
+func RunSuite(t *testing.T, newFixture FixtureFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, f *Fixture)
+	}{
+		{"ready", testReady},
+		{"org secret lifecycle", testOrgSecretLifecycle},
+		{"user secret lifecycle", testUserSecretLifecycle},
+		{"multi-part names", testMultiPartNames},
+		{"secret not found", testNotFound},
+		{"bad request", testBadRequest},
+		{"unauthenticated", testUnauthenticated},
+		{"permission denied", testPermissionDenied},
+		{"metadata", testMetadata},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newFixture(t))
+		})
+	}
+}
+
+// Returns the user name without the org.
+func shortName(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
+
+func userPath(id string, name string) string {
+	return "user/" + shortName(id) + "/" + name
+}
+
+func checkErrorType(t *testing.T, err error, expected interface{}, action string) {
+	t.Helper()
+	if err == nil {
+		t.Errorf("%v: expected error of type %T, got no error", action, expected)
+	} else if reflect.TypeOf(err) != reflect.TypeOf(expected) {
+		t.Errorf("%v: expected error of type %T, got %T: %v", action, expected, err, err)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func testReady(t *testing.T, f *Fixture) {
+	if !f.Provider.IsReady() {
+		t.Errorf("provider should be ready after login")
+	}
+	if err := f.Provider.Renew(); err != nil {
+		t.Errorf("unexpected error renewing: %v", err)
+	} else if !f.Provider.IsReady() {
+		t.Errorf("provider should be ready after renew")
+	}
+}
+
+func testOrgSecretLifecycle(t *testing.T, f *Fixture) {
+	p := f.Provider
+	details := secrets.SecretDetails{Key: "user", Value: "pw1"}
+
+	if err := p.CreateOrgSecret(f.AdminId, f.AdminToken, f.Org, "secret1", details); err != nil {
+		t.Fatalf("unexpected error creating org secret: %v", err)
+	}
+
+	// Any user in the org can see and read org secrets.
+	if err := p.ListOrgSecret(f.UserId, f.UserToken, f.Org, "secret1"); err != nil {
+		t.Errorf("unexpected error checking org secret: %v", err)
+	}
+	if names, err := p.ListOrgSecrets(f.UserId, f.UserToken, f.Org, ""); err != nil {
+		t.Errorf("unexpected error listing org secrets: %v", err)
+	} else if !reflect.DeepEqual(names, []string{"secret1"}) {
+		t.Errorf("expected [secret1], got %v", names)
+	}
+	if d, err := p.GetSecretDetails(f.AgbotId, f.AgbotToken, f.Org, "", "secret1"); err != nil {
+		t.Errorf("unexpected error reading org secret: %v", err)
+	} else if d != details {
+		t.Errorf("expected %v, got %v", details, d)
+	}
+
+	// Creating the secret again updates it.
+	updated := secrets.SecretDetails{Key: "user", Value: "pw2"}
+	if err := p.CreateOrgSecret(f.AdminId, f.AdminToken, f.Org, "secret1", updated); err != nil {
+		t.Errorf("unexpected error updating org secret: %v", err)
+	} else if d, err := p.GetSecretDetails(f.AdminId, f.AdminToken, f.Org, "", "secret1"); err != nil {
+		t.Errorf("unexpected error reading updated org secret: %v", err)
+	} else if d != updated {
+		t.Errorf("expected %v, got %v", updated, d)
+	}
+
+	if err := p.DeleteOrgSecret(f.AdminId, f.AdminToken, f.Org, "secret1"); err != nil {
+		t.Errorf("unexpected error deleting org secret: %v", err)
+	}
+	checkErrorType(t, p.ListOrgSecret(f.UserId, f.UserToken, f.Org, "secret1"), &secrets.NoSecretFound{}, "check deleted org secret")
+}
+
+func testUserSecretLifecycle(t *testing.T, f *Fixture) {
+	p := f.Provider
+	details := secrets.SecretDetails{Key: "token", Value: "abc"}
+	path := userPath(f.UserId, "mysecret")
+
+	if err := p.CreateOrgUserSecret(f.UserId, f.UserToken, f.Org, path, details); err != nil {
+		t.Fatalf("unexpected error creating user secret: %v", err)
+	}
+
+	if err := p.ListOrgUserSecret(f.UserId, f.UserToken, f.Org, path); err != nil {
+		t.Errorf("unexpected error checking user secret: %v", err)
+	}
+
+	// The user/<user> prefix is trimmed from user secret names.
+	if names, err := p.ListOrgUserSecrets(f.UserId, f.UserToken, f.Org, "user/"+shortName(f.UserId)); err != nil {
+		t.Errorf("unexpected error listing user secrets: %v", err)
+	} else if !reflect.DeepEqual(names, []string{"mysecret"}) {
+		t.Errorf("expected [mysecret], got %v", names)
+	}
+
+	// User secrets are not listed with the org secrets.
+	if names, err := p.ListOrgSecrets(f.UserId, f.UserToken, f.Org, ""); err != nil {
+		if _, ok := err.(*secrets.NoSecretFound); !ok {
+			t.Errorf("unexpected error listing org secrets: %v", err)
+		}
+	} else if len(names) != 0 {
+		t.Errorf("user secrets should not be listed as org secrets, got %v", names)
+	}
+
+	// The agbot reads user secrets by passing the secret user separately.
+	if d, err := p.GetSecretDetails(f.AgbotId, f.AgbotToken, f.Org, shortName(f.UserId), "mysecret"); err != nil {
+		t.Errorf("unexpected error reading user secret: %v", err)
+	} else if d != details {
+		t.Errorf("expected %v, got %v", details, d)
+	}
+
+	if err := p.DeleteOrgUserSecret(f.UserId, f.UserToken, f.Org, path); err != nil {
+		t.Errorf("unexpected error deleting user secret: %v", err)
+	}
+	checkErrorType(t, p.ListOrgUserSecret(f.UserId, f.UserToken, f.Org, path), &secrets.NoSecretFound{}, "check deleted user secret")
+}
+
+func testMultiPartNames(t *testing.T, f *Fixture) {
+	p := f.Provider
+	details := secrets.SecretDetails{Key: "k", Value: "v"}
+
+	for _, name := range []string{"top", "dir/a", "dir/sub/b"} {
+		if err := p.CreateOrgSecret(f.AdminId, f.AdminToken, f.Org, name, details); err != nil {
+			t.Fatalf("unexpected error creating secret %v: %v", name, err)
+		}
+	}
+
+	if names, err := p.ListOrgSecrets(f.UserId, f.UserToken, f.Org, ""); err != nil {
+		t.Errorf("unexpected error listing org secrets: %v", err)
+	} else if len(names) != 3 || !contains(names, "top") || !contains(names, "dir/a") || !contains(names, "dir/sub/b") {
+		t.Errorf("expected [top dir/a dir/sub/b], got %v", names)
+	}
+
+	if names, err := p.ListOrgSecrets(f.UserId, f.UserToken, f.Org, "dir"); err != nil {
+		t.Errorf("unexpected error listing org secrets in dir: %v", err)
+	} else if len(names) != 2 || !contains(names, "dir/a") || !contains(names, "dir/sub/b") {
+		t.Errorf("expected [dir/a dir/sub/b], got %v", names)
+	}
+}
+
+func testNotFound(t *testing.T, f *Fixture) {
+	p := f.Provider
+
+	checkErrorType(t, p.ListOrgSecret(f.UserId, f.UserToken, f.Org, "missing"), &secrets.NoSecretFound{}, "check missing secret")
+	checkErrorType(t, p.DeleteOrgSecret(f.AdminId, f.AdminToken, f.Org, "missing"), &secrets.NoSecretFound{}, "delete missing secret")
+	checkErrorType(t, p.DeleteOrgUserSecret(f.UserId, f.UserToken, f.Org, userPath(f.UserId, "missing")), &secrets.NoSecretFound{}, "delete missing user secret")
+
+	_, err := p.GetSecretDetails(f.AgbotId, f.AgbotToken, f.Org, "", "missing")
+	checkErrorType(t, err, &secrets.NoSecretFound{}, "read missing secret")
+
+	_, err = p.GetSecretMetadata(f.Org, "", "missing")
+	checkErrorType(t, err, &secrets.NoSecretFound{}, "read missing secret metadata")
+
+	// An empty list of secrets is either reported as not found or as an empty list.
+	if names, err := p.ListOrgUserSecrets(f.UserId, f.UserToken, f.Org, "user/"+shortName(f.UserId)); err != nil {
+		checkErrorType(t, err, &secrets.NoSecretFound{}, "list missing user secrets")
+	} else if len(names) != 0 {
+		t.Errorf("expected no user secrets, got %v", names)
+	}
+}
+
+func testBadRequest(t *testing.T, f *Fixture) {
+	p := f.Provider
+
+	_, err := p.GetSecretDetails(f.AgbotId, f.AgbotToken, "", "", "secret1")
+	checkErrorType(t, err, &secrets.BadRequest{}, "read secret without org")
+
+	_, err = p.GetSecretDetails(f.AgbotId, f.AgbotToken, f.Org, "", "")
+	checkErrorType(t, err, &secrets.BadRequest{}, "read secret without name")
+
+	_, err = p.GetSecretMetadata("", "", "secret1")
+	checkErrorType(t, err, &secrets.BadRequest{}, "read metadata without org")
+
+	_, err = p.GetSecretMetadata(f.Org, "", "")
+	checkErrorType(t, err, &secrets.BadRequest{}, "read metadata without name")
+}
+
+func testUnauthenticated(t *testing.T, f *Fixture) {
+	p := f.Provider
+	details := secrets.SecretDetails{Key: "k", Value: "v"}
+
+	checkErrorType(t, p.CreateOrgSecret(f.AdminId, "wrongtoken", f.Org, "secret1", details), &secrets.Unauthenticated{}, "create with bad token")
+	checkErrorType(t, p.ListOrgSecret(f.UserId, "wrongtoken", f.Org, "secret1"), &secrets.Unauthenticated{}, "check with bad token")
+
+	_, err := p.ListOrgSecrets(f.UserId, "wrongtoken", f.Org, "")
+	checkErrorType(t, err, &secrets.Unauthenticated{}, "list with bad token")
+
+	_, err = p.GetSecretDetails(f.UserId, "wrongtoken", f.Org, "", "secret1")
+	checkErrorType(t, err, &secrets.Unauthenticated{}, "read with bad token")
+}
+
+func testPermissionDenied(t *testing.T, f *Fixture) {
+	p := f.Provider
+	details := secrets.SecretDetails{Key: "k", Value: "v"}
+
+	// Only org admins can change org secrets.
+	checkErrorType(t, p.CreateOrgSecret(f.UserId, f.UserToken, f.Org, "secret1", details), &secrets.PermissionDenied{}, "create org secret as user")
+
+	if err := p.CreateOrgSecret(f.AdminId, f.AdminToken, f.Org, "secret1", details); err != nil {
+		t.Fatalf("unexpected error creating org secret: %v", err)
+	}
+	checkErrorType(t, p.DeleteOrgSecret(f.UserId, f.UserToken, f.Org, "secret1"), &secrets.PermissionDenied{}, "delete org secret as user")
+
+	// Users cannot see or change each other's secrets.
+	otherPath := userPath(f.OtherUserId, "private")
+	if err := p.CreateOrgUserSecret(f.OtherUserId, f.OtherUserToken, f.Org, otherPath, details); err != nil {
+		t.Fatalf("unexpected error creating other user secret: %v", err)
+	}
+	checkErrorType(t, p.ListOrgUserSecret(f.UserId, f.UserToken, f.Org, otherPath), &secrets.PermissionDenied{}, "check other user secret")
+	checkErrorType(t, p.CreateOrgUserSecret(f.UserId, f.UserToken, f.Org, otherPath, details), &secrets.PermissionDenied{}, "overwrite other user secret")
+	checkErrorType(t, p.DeleteOrgUserSecret(f.UserId, f.UserToken, f.Org, otherPath), &secrets.PermissionDenied{}, "delete other user secret")
+
+	_, err := p.GetSecretDetails(f.UserId, f.UserToken, f.Org, shortName(f.OtherUserId), "private")
+	checkErrorType(t, err, &secrets.PermissionDenied{}, "read other user secret")
+
+	_, err = p.ListOrgUserSecrets(f.UserId, f.UserToken, f.Org, "user/"+shortName(f.OtherUserId))
+	checkErrorType(t, err, &secrets.PermissionDenied{}, "list other user secrets")
+}
+
+func testMetadata(t *testing.T, f *Fixture) {
+	p := f.Provider
+	details := secrets.SecretDetails{Key: "k", Value: "v"}
+
+	if err := p.CreateOrgSecret(f.AdminId, f.AdminToken, f.Org, "secret1", details); err != nil {
+		t.Fatalf("unexpected error creating org secret: %v", err)
+	}
+
+	md, err := p.GetSecretMetadata(f.Org, "", "secret1")
+	if err != nil {
+		t.Fatalf("unexpected error reading metadata: %v", err)
+	} else if md.CreationTime == 0 || md.UpdateTime < md.CreationTime {
+		t.Errorf("unexpected metadata %v", md)
+	}
+
+	if err := p.CreateOrgSecret(f.AdminId, f.AdminToken, f.Org, "secret1", details); err != nil {
+		t.Fatalf("unexpected error updating org secret: %v", err)
+	} else if md2, err := p.GetSecretMetadata(f.Org, "", "secret1"); err != nil {
+		t.Errorf("unexpected error reading updated metadata: %v", err)
+	} else if md2.CreationTime != md.CreationTime || md2.UpdateTime < md.UpdateTime {
+		t.Errorf("updating a secret should keep the creation time and move the update time forward, was %v, is %v", md, md2)
+	}
+
+	path := userPath(f.UserId, "mysecret")
+	if err := p.CreateOrgUserSecret(f.UserId, f.UserToken, f.Org, path, details); err != nil {
+		t.Fatalf("unexpected error creating user secret: %v", err)
+	} else if md, err := p.GetSecretMetadata(f.Org, shortName(f.UserId), "mysecret"); err != nil {
+		t.Errorf("unexpected error reading user secret metadata: %v", err)
+	} else if md.CreationTime == 0 {
+		t.Errorf("unexpected user secret metadata %v", md)
+	}
+}
@@ -0,0 +1,97 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/exchange"
+	"strings"
+)
+
+// The identity of a caller of this plugin. Unlike the vault, the file store has no ACLs of its own, so the plugin
+// enforces the same rules that the openhorizon vault auth plugin does, based on the identity in the exchange.
+type SecretsUser struct {
+	Org   string // The org of the user.
+	Name  string // The user name, without the org.
+	Admin bool   // True when the user is an org admin.
+	Agbot bool   // True when the caller is this agbot, which can read every secret.
+}
+
+func (u *SecretsUser) String() string {
+	return fmt.Sprintf("%v/%v", u.Org, u.Name)
+}
+
+// Returns true if the user is allowed to read the secret at the input path in the input org.
+func (u *SecretsUser) canRead(org string, path string) bool {
+	if u.Agbot {
+		return true
+	} else if u.Org != org {
+		return false
+	} else if secretUser := pathUser(path); secretUser != "" {
+		return secretUser == u.Name
+	}
+	return true
+}
+
+// Returns true if the user is allowed to create or delete the secret at the input path in the input org. Org level
+// secrets can only be changed by org admins, user level secrets only by their owner.
+func (u *SecretsUser) canWrite(org string, path string) bool {
+	if u.Org != org {
+		return false
+	} else if secretUser := pathUser(path); secretUser != "" {
+		return secretUser == u.Name
+	}
+	return u.Admin
+}
+
+// Returns the user name embedded in a user level secret path of the form user/<user>/<secret>.
+func pathUser(path string) string {
+	if path == "user" || strings.HasPrefix(path, "user/") {
+		parts := strings.SplitN(path, "/", 3)
+		if len(parts) >= 2 {
+			return parts[1]
+		}
+	}
+	return ""
+}
+
+// The function used to verify the identity of a caller. It can be replaced by tests.
+type AuthenticateFunc func(user string, token string) (*SecretsUser, error)
+
+// Verify the input credentials. The agbot's own credentials are always accepted, user credentials are checked
+// with the exchange.
+func (fs *AgbotFileSecrets) loginUser(user, token string) (*SecretsUser, error) {
+	if user == fs.cfg.AgreementBot.ExchangeId && token == fs.cfg.AgreementBot.ExchangeToken {
+		org, name := cutil.SplitOrgSpecUrl(user)
+		return &SecretsUser{Org: org, Name: name, Agbot: true}, nil
+	}
+	return fs.authenticate(user, token)
+}
+
+// Verify the user credentials by reading the user's own definition from the exchange.
+func (fs *AgbotFileSecrets) exchangeAuthenticate(user, token string) (*SecretsUser, error) {
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("authenticating %s with the exchange", user)))
+
+	org, name := cutil.SplitOrgSpecUrl(user)
+	if org == "" || name == "" {
+		return nil, errors.New(fmt.Sprintf("user %s is not in the form org/user", user))
+	} else if fs.cfg.AgreementBot.ExchangeURL == "" {
+		return nil, errors.New(fmt.Sprintf("the agbot ExchangeURL is not configured"))
+	}
+
+	var resp interface{}
+	resp = new(exchange.GetUsersResponse)
+	targetURL := fmt.Sprintf("%vorgs/%v/users/%v", fs.cfg.AgreementBot.ExchangeURL, org, name)
+	if err := exchange.InvokeExchangeRetryOnTransportError(fs.cfg.Collaborators.HTTPClientFactory, "GET", targetURL, user, token, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	users, _ := resp.(*exchange.GetUsersResponse)
+	for key, def := range users.Users {
+		if _, userName := cutil.SplitOrgSpecUrl(key); userName == name {
+			return &SecretsUser{Org: org, Name: name, Admin: def.Admin}, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("user %s not found in the exchange", user))
+}
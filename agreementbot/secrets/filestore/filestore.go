@@ -0,0 +1,342 @@
+package filestore
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/secrets"
+	"github.com/open-horizon/anax/config"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// This function registers an uninitialized agbot secrets implementation with the secrets plugin registry. The plugin's Initialize
+// method is used to configure the object.
+func init() {
+	secrets.Register("file", new(AgbotFileSecrets))
+}
+
+// A secrets provider that keeps the secrets in a local file, optionally encrypted. It is intended for management hubs
+// that do not run a vault. The fields in this object are initialized in the Initialize method in this package.
+type AgbotFileSecrets struct {
+	cfg                  *config.HorizonConfig
+	path                 string           // The path of the secrets file
+	key                  []byte           // The key used to encrypt the secrets file, nil when the file is not encrypted
+	authenticate         AuthenticateFunc // Verifies user credentials, the exchange by default
+	lock                 sync.RWMutex     // Protects the in memory copy of the secrets file, the file itself is locked while it is changed
+	store                *SecretsFile     // The in memory copy of the secrets file, nil until the plugin has logged in
+	lastVaultInteraction uint64
+}
+
+func (fs *AgbotFileSecrets) String() string {
+	return fmt.Sprintf("Path: %v, Encrypted: %v", fs.path, fs.key != nil)
+}
+
+// Available to all users within the org
+func (fs *AgbotFileSecrets) ListOrgUserSecret(user, token, org, path string) error {
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("list secret %v in org %v as user %v", path, org, user)))
+	return fs.listSecret(user, token, org, path)
+}
+
+// Available to all users within the org
+func (fs *AgbotFileSecrets) ListOrgSecret(user, token, org, path string) error {
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("list secret %v in org %v", path, org)))
+	return fs.listSecret(user, token, org, path)
+}
+
+// Check that the secret at a specified path exists.
+func (fs *AgbotFileSecrets) listSecret(user, token, org, path string) error {
+
+	exUser, err := fs.loginUser(user, token)
+	if err != nil {
+		return &secrets.Unauthenticated{LoginError: err, ExchangeUser: user}
+	} else if !exUser.canRead(org, path) {
+		return &secrets.PermissionDenied{HttpMethod: http.MethodGet, SecretPath: secretPath(org, path), ExchangeUser: exUser.Name}
+	}
+
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	if fs.store == nil {
+		return notReadyError()
+	} else if _, ok := fs.store.Orgs[org][path]; !ok {
+		return &secrets.NoSecretFound{SecretPath: secretPath(org, path)}
+	}
+
+	fs.touch()
+	return nil
+}
+
+// List all org-level secrets at a specified path.
+func (fs *AgbotFileSecrets) ListOrgSecrets(user, token, org, path string) ([]string, error) {
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("list secrets in %v", org)))
+	return fs.listSecrets(user, token, org, path)
+}
+
+// List all user-level secrets at a specified path.
+func (fs *AgbotFileSecrets) ListOrgUserSecrets(user, token, org, path string) ([]string, error) {
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("listing secrets for user %v in %v", user, org)))
+
+	secretNames, err := fs.listSecrets(user, token, org, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// trim the user/<user> prefix from the names
+	secretList := make([]string, 0)
+	for _, secret := range secretNames {
+		secretList = append(secretList, strings.TrimPrefix(secret, path+"/"))
+	}
+	return secretList, nil
+}
+
+// List the full names of the secrets below a specified path. User level secrets are not included when listing
+// from the top of the org.
+func (fs *AgbotFileSecrets) listSecrets(user, token, org, path string) ([]string, error) {
+
+	exUser, err := fs.loginUser(user, token)
+	if err != nil {
+		return nil, &secrets.Unauthenticated{LoginError: err, ExchangeUser: user}
+	} else if !exUser.canRead(org, path) {
+		return nil, &secrets.PermissionDenied{HttpMethod: "LIST", SecretPath: secretPath(org, path), ExchangeUser: exUser.Name}
+	}
+
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	if fs.store == nil {
+		return nil, notReadyError()
+	}
+
+	orgSecrets := fs.store.Orgs[org]
+	secretList := make([]string, 0)
+	for name := range orgSecrets {
+		if path == "" && pathUser(name) == "" {
+			secretList = append(secretList, name)
+		} else if path != "" && strings.HasPrefix(name, path+"/") {
+			secretList = append(secretList, name)
+		}
+	}
+
+	// Like the vault, report an unknown path as not found. The top of an org that only has user secrets is empty.
+	if len(secretList) == 0 && (path != "" || len(orgSecrets) == 0) {
+		return nil, &secrets.NoSecretFound{SecretPath: secretPath(org, path)}
+	}
+
+	sort.Strings(secretList)
+	fs.touch()
+	return secretList, nil
+}
+
+// Available to all users within the org
+func (fs *AgbotFileSecrets) CreateOrgUserSecret(user, token, org, path string, data secrets.SecretDetails) error {
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("creating secret %s in org %s", path, org)))
+	return fs.createSecret(user, token, org, path, data)
+}
+
+// Available to only org admin users
+func (fs *AgbotFileSecrets) CreateOrgSecret(user, token, org, path string, data secrets.SecretDetails) error {
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("creating secret %s in org %s", path, org)))
+	return fs.createSecret(user, token, org, path, data)
+}
+
+// Create or update a secret, the creation time of an existing secret is preserved.
+func (fs *AgbotFileSecrets) createSecret(user, token, org, path string, data secrets.SecretDetails) error {
+
+	if err := checkSecretName(org, path, http.MethodPost); err != nil {
+		return err
+	}
+
+	exUser, err := fs.loginUser(user, token)
+	if err != nil {
+		return &secrets.Unauthenticated{LoginError: err, ExchangeUser: user}
+	} else if !exUser.canWrite(org, path) {
+		return &secrets.PermissionDenied{HttpMethod: http.MethodPost, SecretPath: secretPath(org, path), ExchangeUser: exUser.Name}
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if fs.store == nil {
+		return notReadyError()
+	}
+
+	// Make the change to the current content of the file, another agbot sharing the file might have changed it.
+	now := time.Now().Unix()
+	store, err := updateSecretsFile(fs.path, fs.key, func(sf *SecretsFile) error {
+		if _, ok := sf.Orgs[org]; !ok {
+			sf.Orgs[org] = make(map[string]SecretEntry)
+		}
+
+		entry := SecretEntry{Details: data, CreationTime: now, UpdateTime: now}
+		if previous, exists := sf.Orgs[org][path]; exists {
+			entry.CreationTime = previous.CreationTime
+		}
+		sf.Orgs[org][path] = entry
+		return nil
+	})
+	if err != nil {
+		return &secrets.SecretsProviderUnavailable{ProviderError: err}
+	}
+	fs.store = store
+
+	fs.touch()
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("done creating %s as user %s.", path, exUser)))
+	return nil
+}
+
+// Available to all users within the org
+func (fs *AgbotFileSecrets) DeleteOrgUserSecret(user, token, org, path string) error {
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("delete secret %s in org %s", path, org)))
+	return fs.deleteSecret(user, token, org, path)
+}
+
+// Available to only org admin users
+func (fs *AgbotFileSecrets) DeleteOrgSecret(user, token, org, path string) error {
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("delete secret %s in org %s", path, org)))
+	return fs.deleteSecret(user, token, org, path)
+}
+
+// Remove a secret, it is an error if the secret does not exist.
+func (fs *AgbotFileSecrets) deleteSecret(user, token, org, path string) error {
+
+	exUser, err := fs.loginUser(user, token)
+	if err != nil {
+		return &secrets.Unauthenticated{LoginError: err, ExchangeUser: user}
+	} else if !exUser.canWrite(org, path) {
+		return &secrets.PermissionDenied{HttpMethod: http.MethodDelete, SecretPath: secretPath(org, path), ExchangeUser: exUser.Name}
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if fs.store == nil {
+		return notReadyError()
+	}
+
+	store, err := updateSecretsFile(fs.path, fs.key, func(sf *SecretsFile) error {
+		if _, exists := sf.Orgs[org][path]; !exists {
+			return &secrets.NoSecretFound{SecretPath: secretPath(org, path)}
+		}
+		delete(sf.Orgs[org], path)
+		return nil
+	})
+	if _, ok := err.(*secrets.NoSecretFound); ok {
+		return err
+	} else if err != nil {
+		return &secrets.SecretsProviderUnavailable{ProviderError: err}
+	}
+	fs.store = store
+
+	fs.touch()
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("done deleting %s as user %s.", path, exUser)))
+	return nil
+}
+
+func (fs *AgbotFileSecrets) GetSecretDetails(user, token, org, secretUser, secretName string) (res secrets.SecretDetails, err error) {
+
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("extract secret details for %s in org %s as user %s", secretName, org, secretUser)))
+
+	if err = checkSecretName(org, secretName, http.MethodGet); err != nil {
+		return
+	}
+
+	fullSecretName := fullSecretName(secretUser, secretName)
+	exUser, lerr := fs.loginUser(user, token)
+	if lerr != nil {
+		err = &secrets.Unauthenticated{LoginError: lerr, ExchangeUser: user}
+		return
+	} else if !exUser.canRead(org, fullSecretName) {
+		err = &secrets.PermissionDenied{HttpMethod: http.MethodGet, SecretPath: secretPath(org, fullSecretName), ExchangeUser: exUser.Name}
+		return
+	}
+
+	entry, err := fs.getEntry(org, fullSecretName)
+	if err != nil {
+		return
+	}
+
+	res = entry.Details
+	glog.V(3).Infof(fileSecretsLogString("done extracting secret details"))
+	return
+}
+
+// Retrieve the metadata for a secret.
+func (fs *AgbotFileSecrets) GetSecretMetadata(secretOrg, secretUser, secretName string) (res secrets.SecretMetadata, err error) {
+
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("extract secret metadata for %s in org %s as user %s", secretName, secretOrg, secretUser)))
+
+	if err = checkSecretName(secretOrg, secretName, http.MethodGet); err != nil {
+		return
+	}
+
+	entry, err := fs.getEntry(secretOrg, fullSecretName(secretUser, secretName))
+	if err != nil {
+		return
+	}
+
+	res.CreationTime = entry.CreationTime
+	res.UpdateTime = entry.UpdateTime
+
+	glog.V(5).Infof(fileSecretsLogString(fmt.Sprintf("Metadata: %v", res)))
+	glog.V(3).Infof(fileSecretsLogString("done extracting secret metadata"))
+	return
+}
+
+func (fs *AgbotFileSecrets) getEntry(org, path string) (SecretEntry, error) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	if fs.store == nil {
+		return SecretEntry{}, notReadyError()
+	}
+
+	entry, ok := fs.store.Orgs[org][path]
+	if !ok {
+		return SecretEntry{}, &secrets.NoSecretFound{SecretPath: secretPath(org, path)}
+	}
+
+	fs.touch()
+	return entry, nil
+}
+
+// Record the time of the last successful interaction with the secrets file.
+func (fs *AgbotFileSecrets) touch() {
+	atomic.StoreUint64(&fs.lastVaultInteraction, uint64(time.Now().Unix()))
+}
+
+func checkSecretName(org, name, method string) error {
+	if org == "" {
+		return &secrets.BadRequest{Response: map[string][]string{"errors": {"Organization name must not be an empty string"}},
+			HttpMethod: method,
+			SecretPath: ""}
+	} else if name == "" {
+		return &secrets.BadRequest{Response: map[string][]string{"errors": {"Secret name must not be an empty string"}},
+			HttpMethod: method,
+			SecretPath: ""}
+	}
+	return nil
+}
+
+func fullSecretName(secretUser, secretName string) string {
+	if secretUser != "" {
+		return fmt.Sprintf("user/%s/%s", secretUser, secretName)
+	}
+	return secretName
+}
+
+func secretPath(org, path string) string {
+	return fmt.Sprintf("%s/%s", org, path)
+}
+
+func notReadyError() error {
+	return &secrets.SecretsProviderUnavailable{ProviderError: fmt.Errorf("the secrets file has not been loaded")}
+}
+
+// Log string prefix api
+var fileSecretsLogString = func(v interface{}) string {
+	return fmt.Sprintf("File Secrets Plugin: %v", v)
+}
@@ -0,0 +1,216 @@
+//go:build unit
+// +build unit
+
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/agreementbot/secrets"
+	"github.com/open-horizon/anax/agreementbot/secrets/conformance"
+	"github.com/open-horizon/anax/config"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Users known to the fake exchange, by org qualified id.
+var testUsers = map[string]struct {
+	token string
+	admin bool
+}{
+	"myorg/admin": {"adminpw", true},
+	"myorg/user1": {"user1pw", false},
+	"myorg/user2": {"user2pw", false},
+}
+
+func fakeAuthenticate(user string, token string) (*SecretsUser, error) {
+	if u, ok := testUsers[user]; !ok || u.token != token {
+		return nil, errors.New("invalid credentials")
+	} else {
+		parts := strings.SplitN(user, "/", 2)
+		return &SecretsUser{Org: parts[0], Name: parts[1], Admin: u.admin}, nil
+	}
+}
+
+func newTestConfig(dir string, keyPath string) *config.HorizonConfig {
+	return &config.HorizonConfig{
+		AgreementBot: config.AGConfig{
+			ExchangeId:    "myorg/agbot",
+			ExchangeToken: "agbotpw",
+			FileSecrets: config.FileSecretConfig{
+				Path:              path.Join(dir, "secrets.json"),
+				EncryptionKeyPath: keyPath,
+			},
+		},
+	}
+}
+
+func newTestProvider(t *testing.T, cfg *config.HorizonConfig) *AgbotFileSecrets {
+	fs := &AgbotFileSecrets{authenticate: fakeAuthenticate}
+	if err := fs.Initialize(cfg); err != nil {
+		t.Fatalf("unexpected error initializing: %v", err)
+	} else if err := fs.Login(); err != nil {
+		t.Fatalf("unexpected error logging in: %v", err)
+	}
+	return fs
+}
+
+func Test_FileSecrets_conformance(t *testing.T) {
+	conformance.RunSuite(t, func(t *testing.T) *conformance.Fixture {
+		dir, err := ioutil.TempDir("", "filesecrets")
+		if err != nil {
+			t.Fatalf("unable to create test dir: %v", err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+
+		return &conformance.Fixture{
+			Provider:       newTestProvider(t, newTestConfig(dir, "")),
+			Org:            "myorg",
+			AgbotId:        "myorg/agbot",
+			AgbotToken:     "agbotpw",
+			AdminId:        "myorg/admin",
+			AdminToken:     "adminpw",
+			UserId:         "myorg/user1",
+			UserToken:      "user1pw",
+			OtherUserId:    "myorg/user2",
+			OtherUserToken: "user2pw",
+		}
+	})
+}
+
+func Test_FileSecrets_encrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesecrets")
+	if err != nil {
+		t.Fatalf("unable to create test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyPath := path.Join(dir, "key")
+	if err := ioutil.WriteFile(keyPath, []byte("mykey\n"), 0600); err != nil {
+		t.Fatalf("unable to write key file: %v", err)
+	}
+
+	cfg := newTestConfig(dir, keyPath)
+	fs := newTestProvider(t, cfg)
+	details := secrets.SecretDetails{Key: "user", Value: "plaintextpassword"}
+	if err := fs.CreateOrgSecret("myorg/admin", "adminpw", "myorg", "secret1", details); err != nil {
+		t.Fatalf("unexpected error creating secret: %v", err)
+	}
+
+	// The secret value must not be readable in the file, and the file must only be readable by the owner.
+	if fileBytes, err := ioutil.ReadFile(cfg.AgreementBot.FileSecrets.Path); err != nil {
+		t.Errorf("unable to read secrets file: %v", err)
+	} else if strings.Contains(string(fileBytes), "plaintextpassword") || strings.Contains(string(fileBytes), "secret1") {
+		t.Errorf("secrets file is not encrypted: %v", string(fileBytes))
+	}
+	if info, err := os.Stat(cfg.AgreementBot.FileSecrets.Path); err != nil {
+		t.Errorf("unable to stat secrets file: %v", err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("secrets file permissions should be 0600, are %v", info.Mode().Perm())
+	}
+
+	// A new instance with the same key sees the secret.
+	fs2 := newTestProvider(t, cfg)
+	if d, err := fs2.GetSecretDetails("myorg/agbot", "agbotpw", "myorg", "", "secret1"); err != nil {
+		t.Errorf("unexpected error reading secret: %v", err)
+	} else if d != details {
+		t.Errorf("expected %v, got %v", details, d)
+	}
+
+	// The wrong key cannot load the file.
+	if err := ioutil.WriteFile(keyPath, []byte("otherkey"), 0600); err != nil {
+		t.Fatalf("unable to write key file: %v", err)
+	}
+	fs3 := &AgbotFileSecrets{authenticate: fakeAuthenticate}
+	if err := fs3.Initialize(cfg); err != nil {
+		t.Errorf("unexpected error initializing: %v", err)
+	} else if err := fs3.Login(); err == nil {
+		t.Errorf("login with the wrong key should fail")
+	} else if fs3.IsReady() {
+		t.Errorf("provider should not be ready when the secrets file cannot be read")
+	}
+}
+
+func Test_FileSecrets_renew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesecrets")
+	if err != nil {
+		t.Fatalf("unable to create test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Two agbots sharing a secrets file see each other's changes after a renew.
+	cfg := newTestConfig(dir, "")
+	fs1 := newTestProvider(t, cfg)
+	fs2 := newTestProvider(t, cfg)
+
+	if err := fs1.CreateOrgSecret("myorg/admin", "adminpw", "myorg", "secret1", secrets.SecretDetails{Key: "k", Value: "v"}); err != nil {
+		t.Fatalf("unexpected error creating secret: %v", err)
+	} else if _, err := fs2.GetSecretMetadata("myorg", "", "secret1"); err == nil {
+		t.Errorf("secret should not be seen before renew")
+	} else if err := fs2.Renew(); err != nil {
+		t.Errorf("unexpected error renewing: %v", err)
+	} else if _, err := fs2.GetSecretMetadata("myorg", "", "secret1"); err != nil {
+		t.Errorf("unexpected error reading secret metadata after renew: %v", err)
+	} else if fs2.GetLastVaultStatus() == 0 {
+		t.Errorf("last interaction time should be set")
+	}
+}
+
+func Test_FileSecrets_sharedWriters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesecrets")
+	if err != nil {
+		t.Fatalf("unable to create test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Two agbots sharing a secrets file do not lose each other's changes, even without a renew in between.
+	cfg := newTestConfig(dir, "")
+	providers := []*AgbotFileSecrets{newTestProvider(t, cfg), newTestProvider(t, cfg)}
+
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p *AgbotFileSecrets) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := p.CreateOrgSecret("myorg/admin", "adminpw", "myorg", fmt.Sprintf("secret%v-%v", i, j), secrets.SecretDetails{Key: "k", Value: "v"}); err != nil {
+					t.Errorf("unexpected error creating secret: %v", err)
+				}
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	if err := providers[0].DeleteOrgSecret("myorg/admin", "adminpw", "myorg", "secret1-0"); err != nil {
+		t.Errorf("the secret created by the other agbot should be deleted: %v", err)
+	}
+
+	fs := newTestProvider(t, cfg)
+	if names, err := fs.ListOrgSecrets("myorg/user1", "user1pw", "myorg", ""); err != nil {
+		t.Errorf("unexpected error listing secrets: %v", err)
+	} else if len(names) != 39 {
+		t.Errorf("expected 39 secrets, got %v: %v", len(names), names)
+	}
+}
+
+func Test_InitSecrets_file(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesecrets")
+	if err != nil {
+		t.Fatalf("unable to create test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if p, err := secrets.InitSecrets(newTestConfig(dir, "")); err != nil {
+		t.Errorf("unexpected error initializing secrets: %v", err)
+	} else if _, ok := p.(*AgbotFileSecrets); !ok {
+		t.Errorf("expected the file secrets provider, got %T", p)
+	}
+
+	if _, err := secrets.InitSecrets(&config.HorizonConfig{}); err == nil {
+		t.Errorf("expected an error when no secrets provider is configured")
+	}
+}
@@ -0,0 +1,97 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"sync/atomic"
+)
+
+// This function is called by the anax main to allow the plugin a chance to initialize itself.
+// This function is called every time the agbot starts. The secrets file is not read until the Login function
+// is called by the agbot worker.
+func (fs *AgbotFileSecrets) Initialize(cfg *config.HorizonConfig) (err error) {
+
+	glog.V(1).Infof(fileSecretsLogString("Initializing the secrets file as the secrets plugin."))
+
+	fs.cfg = cfg
+	fs.path = cfg.AgreementBot.FileSecrets.Path
+	if fs.path == "" {
+		return errors.New(fmt.Sprintf("the secrets file path is not configured"))
+	}
+
+	fs.key = nil
+	if keyPath := cfg.AgreementBot.FileSecrets.EncryptionKeyPath; keyPath != "" {
+		if fs.key, err = readEncryptionKey(keyPath); err != nil {
+			return err
+		}
+	}
+
+	if fs.authenticate == nil {
+		fs.authenticate = fs.exchangeAuthenticate
+	}
+
+	glog.V(1).Infof(fileSecretsLogString(fmt.Sprintf("Initialized the secrets file as the secrets plugin: %v", fs)))
+
+	return nil
+
+}
+
+// This function is called by the agbot worker when the plugin is not ready. It reads the secrets file into memory,
+// creating it if this is the first time the agbot has used it.
+func (fs *AgbotFileSecrets) Login() (err error) {
+
+	glog.V(3).Infof(fileSecretsLogString(fmt.Sprintf("loading secrets file %v", fs.path)))
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	store, err := updateSecretsFile(fs.path, fs.key, func(sf *SecretsFile) error { return nil })
+	if err != nil {
+		return err
+	}
+
+	fs.store = store
+	fs.touch()
+
+	glog.V(3).Infof(fileSecretsLogString("loaded secrets file."))
+
+	return nil
+}
+
+// Re-read the secrets file so that changes made by another agbot sharing the file are picked up. If the file cannot
+// be read, the secrets that are already in memory continue to be used.
+func (fs *AgbotFileSecrets) Renew() (err error) {
+
+	glog.V(3).Infof(fileSecretsLogString("reloading secrets file"))
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	store, err := readSecretsFile(fs.path, fs.key)
+	if err != nil {
+		return errors.New(fmt.Sprintf("agbot unable to reload secrets file, error: %v", err))
+	}
+
+	fs.store = store
+	fs.touch()
+
+	glog.V(3).Infof(fileSecretsLogString("done reloading secrets file"))
+
+	return nil
+}
+
+func (fs *AgbotFileSecrets) IsReady() bool {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	return fs.store != nil
+}
+
+func (fs *AgbotFileSecrets) Close() {
+	glog.V(2).Infof("Closed file secrets implementation")
+}
+
+func (fs *AgbotFileSecrets) GetLastVaultStatus() uint64 {
+	return atomic.LoadUint64(&fs.lastVaultInteraction)
+}
@@ -0,0 +1,228 @@
+package filestore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/agreementbot/secrets"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// The version of the secrets file format written by this plugin.
+const SECRETS_FILE_VERSION = 1
+
+// The algorithm used to encrypt the secrets file.
+const SECRETS_FILE_ALGORITHM = "AES-256-GCM"
+
+// A secret and its metadata, as it is kept in the secrets file.
+type SecretEntry struct {
+	Details      secrets.SecretDetails `json:"details"`
+	CreationTime int64                 `json:"creationTime"`
+	UpdateTime   int64                 `json:"updateTime"`
+}
+
+// The content of the secrets file. Secrets are indexed by org and then by the full secret path within the org,
+// e.g. "mysecret" or "user/myuser/mysecret".
+type SecretsFile struct {
+	Version int                               `json:"version"`
+	Orgs    map[string]map[string]SecretEntry `json:"orgs"`
+}
+
+func NewSecretsFile() *SecretsFile {
+	return &SecretsFile{
+		Version: SECRETS_FILE_VERSION,
+		Orgs:    make(map[string]map[string]SecretEntry),
+	}
+}
+
+// When the secrets file is encrypted, the file contains this envelope instead of the secrets.
+type EncryptedSecretsFile struct {
+	Version    int    `json:"version"`
+	Algorithm  string `json:"algorithm"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Read the encryption key file and derive the AES-256 key from its content.
+func readEncryptionKey(keyPath string) ([]byte, error) {
+	keyBytes, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read secrets encryption key file %v, error: %v", keyPath, err))
+	}
+
+	keyString := strings.TrimSpace(string(keyBytes))
+	if keyString == "" {
+		return nil, errors.New(fmt.Sprintf("secrets encryption key file %v is empty", keyPath))
+	}
+
+	key := sha256.Sum256([]byte(keyString))
+	return key[:], nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Read the secrets file from disk. A missing file is the same as an empty one. If a key is provided, the file
+// is expected to be encrypted.
+func loadSecretsFile(path string, key []byte) (*SecretsFile, error) {
+
+	fileBytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewSecretsFile(), nil
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read secrets file %v, error: %v", path, err))
+	} else if len(fileBytes) == 0 {
+		return NewSecretsFile(), nil
+	}
+
+	if key != nil {
+		envelope := new(EncryptedSecretsFile)
+		if err := json.Unmarshal(fileBytes, envelope); err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to parse encrypted secrets file %v, error: %v", path, err))
+		} else if envelope.Algorithm != SECRETS_FILE_ALGORITHM {
+			return nil, errors.New(fmt.Sprintf("secrets file %v is not encrypted with %v", path, SECRETS_FILE_ALGORITHM))
+		}
+
+		gcm, err := newGCM(key)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to create secrets file cipher, error: %v", err))
+		}
+
+		fileBytes, err = gcm.Open(nil, envelope.Nonce, envelope.Ciphertext, nil)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to decrypt secrets file %v, the encryption key might be wrong, error: %v", path, err))
+		}
+	}
+
+	sf := NewSecretsFile()
+	if err := json.Unmarshal(fileBytes, sf); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to parse secrets file %v, error: %v", path, err))
+	} else if sf.Version > SECRETS_FILE_VERSION {
+		return nil, errors.New(fmt.Sprintf("secrets file %v has unsupported version %v", path, sf.Version))
+	}
+
+	if sf.Orgs == nil {
+		sf.Orgs = make(map[string]map[string]SecretEntry)
+	}
+	return sf, nil
+}
+
+// Write the secrets file to disk, encrypting it if a key is provided. The file is written to a temporary file
+// first and then renamed, so that a failure never leaves a partially written secrets file behind.
+func saveSecretsFile(path string, key []byte, sf *SecretsFile) error {
+
+	fileBytes, err := json.Marshal(sf)
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to serialize secrets, error: %v", err))
+	}
+
+	if key != nil {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return errors.New(fmt.Sprintf("unable to create secrets file cipher, error: %v", err))
+		}
+
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return errors.New(fmt.Sprintf("unable to generate secrets file nonce, error: %v", err))
+		}
+
+		envelope := EncryptedSecretsFile{
+			Version:    SECRETS_FILE_VERSION,
+			Algorithm:  SECRETS_FILE_ALGORITHM,
+			Nonce:      nonce,
+			Ciphertext: gcm.Seal(nil, nonce, fileBytes, nil),
+		}
+		if fileBytes, err = json.Marshal(envelope); err != nil {
+			return errors.New(fmt.Sprintf("unable to serialize encrypted secrets, error: %v", err))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.New(fmt.Sprintf("unable to create directory for secrets file %v, error: %v", path, err))
+	}
+
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, fileBytes, 0600); err != nil {
+		return errors.New(fmt.Sprintf("unable to write secrets file %v, error: %v", tmpPath, err))
+	} else if err := os.Rename(tmpPath, path); err != nil {
+		return errors.New(fmt.Sprintf("unable to replace secrets file %v, error: %v", path, err))
+	}
+	return nil
+}
+
+// Lock the secrets file so that the agbots that share it do not overwrite each other's changes. The lock is taken on a
+// separate lock file, because the secrets file itself is replaced every time it is written. A shared lock is enough to
+// read the file, changing it needs the exclusive lock.
+func lockSecretsFile(path string, exclusive bool) (*os.File, error) {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to create directory for secrets file %v, error: %v", path, err))
+	}
+
+	lockFile, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to open secrets lock file %v.lock, error: %v", path, err))
+	}
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if err := syscall.Flock(int(lockFile.Fd()), how); err != nil {
+		lockFile.Close()
+		return nil, errors.New(fmt.Sprintf("unable to lock secrets file %v, error: %v", path, err))
+	}
+	return lockFile, nil
+}
+
+func unlockSecretsFile(lockFile *os.File) {
+	syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+	lockFile.Close()
+}
+
+// Read the secrets file while holding the shared lock.
+func readSecretsFile(path string, key []byte) (*SecretsFile, error) {
+	lockFile, err := lockSecretsFile(path, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockSecretsFile(lockFile)
+
+	return loadSecretsFile(path, key)
+}
+
+// Read the secrets file, let the update function change it and write it back, all while holding the exclusive lock, so
+// that the changes made by another agbot since this agbot last read the file are not lost. When the update function
+// returns an error, the file is not written and the error is returned as is. The updated content is returned.
+func updateSecretsFile(path string, key []byte, update func(sf *SecretsFile) error) (*SecretsFile, error) {
+	lockFile, err := lockSecretsFile(path, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockSecretsFile(lockFile)
+
+	sf, err := loadSecretsFile(path, key)
+	if err != nil {
+		return nil, err
+	} else if err := update(sf); err != nil {
+		return nil, err
+	} else if err := saveSecretsFile(path, key, sf); err != nil {
+		return nil, err
+	}
+	return sf, nil
+}
@@ -20,15 +20,24 @@ func Register(name string, as AgbotSecrets) {
 	SecretsProviders[name] = as
 }
 
-// Initialize the underlying Agbot Secrets implementation depending on what is configured. If vault is configured, it is used.
+// Initialize the underlying Agbot Secrets implementation depending on what is configured. If a provider is explicitly
+// configured, it is used. Otherwise vault is used if it is configured, followed by the file based secrets store.
 // If nothing is configured, an error is returned.
 func InitSecrets(cfg *config.HorizonConfig) (AgbotSecrets, error) {
 
-	if cfg.IsVaultConfigured() {
-		secretsObj := SecretsProviders["vault"]
-		return secretsObj, secretsObj.Initialize(cfg)
+	providerName := cfg.GetAgbotSecretsProvider()
+	if providerName == "" {
+		return nil, errors.New(fmt.Sprintf("No secrets provider is configured, configure either Vault or FileSecrets."))
+	} else if providerName == config.AGBOT_SECRETS_PROVIDER_VAULT && !cfg.IsVaultConfigured() {
+		return nil, errors.New(fmt.Sprintf("Vault is not configured correctly."))
+	} else if providerName == config.AGBOT_SECRETS_PROVIDER_FILE && !cfg.IsFileSecretsConfigured() {
+		return nil, errors.New(fmt.Sprintf("FileSecrets is not configured correctly."))
+	}
 
+	secretsObj, ok := SecretsProviders[providerName]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Secrets provider %v is not registered.", providerName))
 	}
-	return nil, errors.New(fmt.Sprintf("Vault is not configured correctly."))
+	return secretsObj, secretsObj.Initialize(cfg)
 
 }
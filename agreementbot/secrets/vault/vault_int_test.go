@@ -0,0 +1,101 @@
+//go:build ci
+// +build ci
+
+// N.B. !! In order for a test in this suite to succeed you must do the following config:
+// - set the envvar HZN_VAULT_ADDR to the URL of a vault that has the openhorizon auth plugin configured with an exchange,
+//   and VAULT_TEST_CERT_PATH to its CA certificate if it uses TLS
+// - set the envvars VAULT_TEST_ORG, VAULT_TEST_AGBOT_ID and VAULT_TEST_AGBOT_TOKEN, and the VAULT_TEST_ADMIN_*,
+//   VAULT_TEST_USER_* and VAULT_TEST_OTHER_USER_* ID and TOKEN envvars to an agbot, an org admin and two users of the org
+// - use an org that is dedicated to the test, its secrets are deleted before each test of the suite
+
+package vault
+
+import (
+	"github.com/open-horizon/anax/agreementbot/secrets"
+	"github.com/open-horizon/anax/agreementbot/secrets/conformance"
+	"github.com/open-horizon/anax/config"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Run the secrets conformance suite against the vault. The test is skipped when HZN_VAULT_ADDR is not set.
+func Test_VaultSecrets_conformance(t *testing.T) {
+
+	vaultURL := os.Getenv(config.VaultURLEnvvarName)
+	if vaultURL == "" {
+		t.Skipf("%v is not set, skipping the vault conformance suite", config.VaultURLEnvvarName)
+	}
+
+	env := func(name string) string {
+		value := os.Getenv(name)
+		if value == "" {
+			t.Fatalf("%v must be set to run the vault conformance suite", name)
+		}
+		return value
+	}
+
+	conformance.RunSuite(t, func(t *testing.T) *conformance.Fixture {
+		cfg := &config.HorizonConfig{
+			AgreementBot: config.AGConfig{
+				ExchangeId:    env("VAULT_TEST_AGBOT_ID"),
+				ExchangeToken: env("VAULT_TEST_AGBOT_TOKEN"),
+				Vault: config.VaultConfig{
+					VaultURL:    vaultURL,
+					SSLCertPath: os.Getenv("VAULT_TEST_CERT_PATH"),
+				},
+			},
+		}
+
+		vs := new(AgbotVaultSecrets)
+		if err := vs.Initialize(cfg); err != nil {
+			t.Fatalf("unexpected error initializing: %v", err)
+		} else if err := vs.Login(); err != nil {
+			t.Fatalf("unexpected error logging in: %v", err)
+		}
+
+		f := &conformance.Fixture{
+			Provider:       vs,
+			Org:            env("VAULT_TEST_ORG"),
+			AgbotId:        cfg.AgreementBot.ExchangeId,
+			AgbotToken:     cfg.AgreementBot.ExchangeToken,
+			AdminId:        env("VAULT_TEST_ADMIN_ID"),
+			AdminToken:     env("VAULT_TEST_ADMIN_TOKEN"),
+			UserId:         env("VAULT_TEST_USER_ID"),
+			UserToken:      env("VAULT_TEST_USER_TOKEN"),
+			OtherUserId:    env("VAULT_TEST_OTHER_USER_ID"),
+			OtherUserToken: env("VAULT_TEST_OTHER_USER_TOKEN"),
+		}
+		deleteAllSecrets(t, f)
+		return f
+	})
+}
+
+// The suite expects an empty provider, so remove the org secrets and the user secrets of the test users.
+func deleteAllSecrets(t *testing.T, f *conformance.Fixture) {
+	p := f.Provider
+
+	if names, err := p.ListOrgSecrets(f.AdminId, f.AdminToken, f.Org, ""); err == nil {
+		for _, name := range names {
+			if err := p.DeleteOrgSecret(f.AdminId, f.AdminToken, f.Org, name); err != nil {
+				t.Fatalf("unable to delete org secret %v: %v", name, err)
+			}
+		}
+	} else if _, ok := err.(*secrets.NoSecretFound); !ok {
+		t.Fatalf("unable to list the org secrets: %v", err)
+	}
+
+	users := [][]string{{f.UserId, f.UserToken}, {f.OtherUserId, f.OtherUserToken}}
+	for _, u := range users {
+		userDir := "user/" + u[0][strings.LastIndex(u[0], "/")+1:]
+		if names, err := p.ListOrgUserSecrets(u[0], u[1], f.Org, userDir); err == nil {
+			for _, name := range names {
+				if err := p.DeleteOrgUserSecret(u[0], u[1], f.Org, userDir+"/"+name); err != nil {
+					t.Fatalf("unable to delete user secret %v: %v", name, err)
+				}
+			}
+		} else if _, ok := err.(*secrets.NoSecretFound); !ok {
+			t.Fatalf("unable to list the secrets of user %v: %v", u[0], err)
+		}
+	}
+}
@@ -185,6 +185,7 @@ func (a *SecureAPI) listen() {
 		router.HandleFunc("/deploycheck/userinputcompatible", a.userinput_compatible).Methods("GET", "OPTIONS")
 		router.HandleFunc("/deploycheck/deploycompatible", a.deploy_compatible).Methods("GET", "OPTIONS")
 		router.HandleFunc("/deploycheck/secretbindingcompatible", a.secretbinding_compatible).Methods("GET", "OPTIONS")
+		router.HandleFunc("/deploycheck/deploysimulate", a.deploy_simulate).Methods("POST", "OPTIONS")
 		router.HandleFunc("/org/{org}/secrets/user/{user}", a.userSecrets).Methods("LIST", "OPTIONS")
 		router.HandleFunc(`/org/{org}/secrets/user/{user}/{secret:[\w\/\-]+}`, a.userSecret).Methods("GET", "LIST", "PUT", "POST", "DELETE", "OPTIONS")
 		router.HandleFunc("/org/{org}/secrets", a.orgSecrets).Methods("LIST", "OPTIONS")
@@ -736,6 +737,154 @@ func (a *SecureAPI) deploy_compatible(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (a *SecureAPI) deploy_simulate(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	// swagger:operation POST /deploycheck/deploysimulate deploy_simulate
+	//
+	// Simulate a deployment policy.
+	//
+	// This API checks the given deployment policy against all the nodes in scope, without making any agreements. For each node, it returns whether the node is compatible with the policy and what the agbot would do with the node: propose a new agreement, keep or cancel the existing agreement for the policy, or nothing. It uses the same compatibility check as /deploycheck/deploycompatible.
+	//
+	// ---
+	// consumes:
+	//  - application/json
+	// produces:
+	//  - application/json
+	// parameters:
+	//  - name: business_policy_id
+	//    in: body
+	//    type: string
+	//    required: false
+	//    description: "The exchange id of the deployment policy. If business_policy is also specified, business_policy is simulated as a replacement for this policy."
+	//  - name: business_policy
+	//    in: body
+	//    required: false
+	//    description: "The defintion of the deployment policy to simulate."
+	//    schema:
+	//     "$ref": "#/definitions/BusinessPolicy"
+	//  - name: node_orgs
+	//    in: body
+	//    type: array
+	//    required: false
+	//    description: "The organizations of the nodes to check. If omitted, the node organizations that the agbot serves for the deployment policy are used, or the organization of the deployment policy."
+	//  - name: service_policy
+	//    in: body
+	//    required: false
+	//    description: "The service policy that will be put in the exchange for the top level service referenced in the deployment policy. If omitted, the service policy will be retrieved from the exchange."
+	//    schema:
+	//     "$ref": "#/definitions/ExternalPolicy"
+	// responses:
+	//  '200':
+	//    description: "Success"
+	//    schema:
+	//     type: compcheck.DeploySimulationOutput
+	//     "$ref": "#/definitions/DeploySimulationOutput"
+	//  '400':
+	//    description: "Failure - No input found"
+	//    schema:
+	//     type: string
+	//  '401':
+	//    description: "Failure - Failed to authenticate"
+	//    schema:
+	//     type: string
+	//  '500':
+	//    description: "Failure - Error"
+	//    schema:
+	//      type: string
+	case "POST":
+		glog.V(5).Infof(APIlogString(fmt.Sprintf("/deploycheck/deploysimulate called.")))
+
+		if user_ec, exUser, msgPrinter, ok := a.processUserCred("/deploycheck/deploysimulate", w, r); ok {
+			body, _ := ioutil.ReadAll(r.Body)
+			if len(body) == 0 {
+				glog.Errorf(APIlogString(fmt.Sprintf("No input found.")))
+				writeResponse(w, msgPrinter.Sprintf("No input found."), http.StatusBadRequest)
+			} else if input, err := a.decodeDeploySimulationBody(body, msgPrinter); err != nil {
+				writeResponse(w, err.Error(), http.StatusBadRequest)
+			} else {
+				// use the user org for the deployment policy id if it does not include an org id
+				userOrg := exchange.GetOrg(user_ec.GetExchangeId())
+				if input.BusinessPolId != "" && !strings.Contains(input.BusinessPolId, "/") {
+					input.BusinessPolId = cutil.FormOrgSpecUrl(input.BusinessPolId, userOrg)
+				}
+
+				nodeOrgs := input.NodeOrgs
+				if len(nodeOrgs) == 0 {
+					nodeOrgs = getSimulationNodeOrgs(input.BusinessPolId, userOrg)
+				}
+
+				// the agreements that the policy already has, so that the output shows which ones would be kept or cancelled
+				agreements, err := a.getPolicyAgreements(input.BusinessPolId)
+				if err != nil {
+					glog.Errorf(APIlogString(err.Error()))
+					writeResponse(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				// do the bound secret name varification in the secret manager for each compatible node
+				secretCheck := func(nodeId string, output *compcheck.CompCheckOutput) error {
+					if output.Input == nil || len(output.Input.NeededSB) == 0 {
+						return nil
+					} else if ok, msg, err := a.verifySecretNames(user_ec, exUser, output.Input.NeededSB, output.Input.NodeOrg, msgPrinter); err != nil {
+						return err
+					} else if !ok {
+						output.Compatible = false
+						output.Reason["general"] = msg
+					}
+					return nil
+				}
+
+				output, err := compcheck.SimulateDeployment(user_ec, input, nodeOrgs, agreements, secretCheck, msgPrinter)
+
+				// write the output
+				a.writeCompCheckResponse(w, output, err, msgPrinter)
+			}
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Return the node orgs to simulate a deployment policy against. These are the node orgs that the agbot serves
+// for the policy, or the policy org when the agbot does not serve the policy yet.
+func getSimulationNodeOrgs(polId string, userOrg string) []string {
+	polOrg := userOrg
+	if polId != "" {
+		polOrg = exchange.GetOrg(polId)
+		if businessPolManager != nil {
+			if nodeOrgs := businessPolManager.GetServedNodeOrgs(polOrg, exchange.GetId(polId)); len(nodeOrgs) != 0 {
+				return nodeOrgs
+			}
+		}
+	}
+	return []string{polOrg}
+}
+
+// Return the node ids and agreement ids of the agreements that are in progress or finalized for the given
+// deployment policy. These are the agreements that stop the node search from proposing to the node again.
+func (a *SecureAPI) getPolicyAgreements(polId string) (map[string]string, error) {
+	agreements := make(map[string]string)
+	if polId == "" {
+		return agreements, nil
+	}
+
+	allAgreements, err := findPolicyAgreements(a.db, polId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find agreements for deployment policy %v, error: %v", polId, err)
+	}
+	for _, ags := range allAgreements {
+		for _, ag := range ags {
+			agreements[ag.DeviceId] = ag.CurrentAgreementId
+		}
+	}
+	return agreements, nil
+}
+
 // This function checks user cred and writes corrsponding response. It also creates a message printer with given language from the http request.
 func (a *SecureAPI) processUserCred(resource string, w http.ResponseWriter, r *http.Request) (exchange.ExchangeContext, string, *message.Printer, bool) {
 	// get message printer with the language passed in from the header
@@ -853,6 +1002,17 @@ func (a *SecureAPI) decodeCompCheckBody(body []byte, msgPrinter *message.Printer
 	}
 }
 
+func (a *SecureAPI) decodeDeploySimulationBody(body []byte, msgPrinter *message.Printer) (*compcheck.DeploySimulationInput, error) {
+
+	var input compcheck.DeploySimulationInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		glog.Errorf(APIlogString(fmt.Sprintf("Input body couldn't be deserialized to DeploySimulationInput object. %v", err)))
+		return nil, fmt.Errorf(msgPrinter.Sprintf("Input body couldn't be deserialized to DeploySimulationInput object. %v", err))
+	}
+	// verification of the input is done in the compcheck component, no need to validate the policies here.
+	return &input, nil
+}
+
 // This function verifies the given exchange user name and password.
 // The user must be in the format of orgId/userId.
 func (a *SecureAPI) authenticateWithExchange(user string, userPasswd string, msgPrinter *message.Printer) (exchange.ExchangeContext, string, error) {
@@ -61,6 +61,7 @@ func NewAPIListener(name string, cfg *config.HorizonConfig, db *bolt.DB, pm *pol
 		listener.EC = worker.NewExchangeContext(fmt.Sprintf("%v/%v", pDevice.Org, pDevice.Id), pDevice.Token, cfg.Edge.ExchangeURL, cfg.GetCSSURL(), cfg.Collaborators.HTTPClientFactory)
 	}
 
+	listener.registerMetricsCollector()
 	listener.listen(cfg)
 	return listener
 }
@@ -86,6 +87,9 @@ func (a *API) router(includeStaticRedirects bool) *mux.Router {
 	router.HandleFunc("/status", a.status).Methods("GET", "OPTIONS")
 	router.HandleFunc("/status/workers", a.workerstatus).Methods("GET", "OPTIONS")
 
+	// Counters and gauges in the Prometheus text format
+	router.HandleFunc("/metrics", a.metrics).Methods("GET", "OPTIONS")
+
 	// Used by the Registration UI to obtain a random token string
 	router.HandleFunc("/token/random", tokenRandom).Methods("GET", "OPTIONS")
 
@@ -94,12 +98,19 @@ func (a *API) router(includeStaticRedirects bool) *mux.Router {
 	router.HandleFunc("/node/configstate", a.nodeconfigstate).Methods("GET", "HEAD", "PUT", "OPTIONS")
 	router.HandleFunc("/node/policy", a.nodepolicy).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
 	router.HandleFunc("/node/userinput", a.nodeuserinput).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
+	router.HandleFunc("/node/admissionpolicy", a.nodeadmissionpolicy).Methods("GET", "HEAD", "PUT", "POST", "DELETE", "OPTIONS")
+
+	// Used to side-load the offline bundles of services whose images cannot be pulled by the node
+	router.HandleFunc("/bundle", a.bundle).Methods("GET", "POST", "OPTIONS")
+	router.HandleFunc("/bundle/{id}", a.bundle).Methods("GET", "DELETE", "OPTIONS")
 
 	// Used to get the event logs on this node.
 	// get the eventlogs for current registration.
 	router.HandleFunc("/eventlog", a.eventlog).Methods("GET", "OPTIONS")
 	// get the eventlogs for all registrations.
 	router.HandleFunc("/eventlog/all", a.eventlog).Methods("GET", "OPTIONS")
+	// stream the new eventlogs as server-sent events.
+	router.HandleFunc("/eventlog/stream", a.eventlogstream).Methods("GET", "OPTIONS")
 	//get the active surface errors for this node
 	router.HandleFunc("/eventlog/surface", a.surface).Methods("GET", "OPTIONS")
 
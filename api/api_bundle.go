@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/persistence"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+func (a *API) bundle(w http.ResponseWriter, r *http.Request) {
+
+	resource := "bundle"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	pathVars := mux.Vars(r)
+	id := pathVars["id"]
+
+	switch r.Method {
+	case "GET":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		if out, err := FindOfflineBundlesForOutput(a.db); err != nil {
+			errorHandler(NewSystemError(fmt.Sprintf("Error getting %v for output, error %v", resource, err)))
+		} else if id == "" {
+			writeResponse(w, out, http.StatusOK)
+		} else if bundle, ok := out[id]; !ok {
+			errorHandler(NewNotFoundError(fmt.Sprintf("Offline bundle %v is not found.", id), "id"))
+		} else {
+			writeResponse(w, bundle, http.StatusOK)
+		}
+
+	case "POST":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		// A JSON body names the MMS object that holds the bundle, any other body is the bundle itself.
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			var obj BundleObject
+			body, _ := ioutil.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &obj); err != nil {
+				LogDeviceEvent(a.db, persistence.SEVERITY_ERROR,
+					persistence.NewMessageMeta(EL_API_ERR_PARSING_INPUT_FOR_BUNDLE, string(body), err.Error()),
+					persistence.EC_API_USER_INPUT_ERROR, nil)
+				errorHandler(NewAPIUserInputError(fmt.Sprintf("Input body could not be deserialized to %v object: %v, error: %v", resource, string(body), err), "body"))
+				return
+			}
+
+			download_bundle_error_handler := func(device interface{}, err error) bool {
+				LogDeviceEvent(a.db, persistence.SEVERITY_ERROR, persistence.NewMessageMeta(EL_API_ERR_IN_BUNDLE_LOAD, err.Error()), persistence.EC_ERROR_OFFLINE_BUNDLE_LOAD, device)
+				return errorHandler(err)
+			}
+
+			errHandled, msg := DownloadOfflineBundle(&obj, download_bundle_error_handler, a.db)
+			if errHandled {
+				return
+			}
+
+			a.Messages() <- msg
+
+			glog.V(5).Infof(apiLogString(fmt.Sprintf("Handled %v on resource %v", r.Method, resource)))
+
+			writeResponse(w, obj, http.StatusAccepted)
+			return
+		}
+
+		load_bundle_error_handler := func(device interface{}, err error) bool {
+			LogDeviceEvent(a.db, persistence.SEVERITY_ERROR, persistence.NewMessageMeta(EL_API_ERR_IN_BUNDLE_LOAD, err.Error()), persistence.EC_ERROR_OFFLINE_BUNDLE_LOAD, device)
+			return errorHandler(err)
+		}
+
+		getDevice := exchange.GetHTTPDeviceHandler(a)
+		patchDevice := exchange.GetHTTPPatchDeviceHandler(a)
+		getService := exchange.GetHTTPServiceHandler(a)
+
+		errHandled, bundle, msgs := LoadOfflineBundle(r.Body, load_bundle_error_handler, getDevice, patchDevice, getService, a.Config, a.db)
+		if errHandled {
+			return
+		}
+
+		// Send out the user input change events
+		for _, msg := range msgs {
+			a.Messages() <- msg
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handled %v on resource %v", r.Method, resource)))
+
+		writeResponse(w, bundle, http.StatusCreated)
+
+	case "DELETE":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v/%v", r.Method, resource, id)))
+
+		delete_bundle_error_handler := func(device interface{}, err error) bool {
+			LogDeviceEvent(a.db, persistence.SEVERITY_ERROR, persistence.NewMessageMeta(EL_API_ERR_IN_BUNDLE_DEL, err.Error()), persistence.EC_ERROR_OFFLINE_BUNDLE_DELETE, device)
+			return errorHandler(err)
+		}
+
+		if errHandled := DeleteOfflineBundle(id, delete_bundle_error_handler, a.Config, a.db); errHandled {
+			return
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handled %v on resource %v/%v", r.Method, resource, id)))
+
+		w.WriteHeader(http.StatusNoContent)
+
+	case "OPTIONS":
+		if id == "" {
+			w.Header().Set("Allow", "GET, POST, OPTIONS")
+		} else {
+			w.Header().Set("Allow", "GET, DELETE, OPTIONS")
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
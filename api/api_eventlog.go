@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // get the eventlogs for current registration.
@@ -77,3 +80,128 @@ func (a *API) surface(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
+
+// The interval between the comments sent on an idle event log stream, so that intermediaries keep the connection
+// open and a client that went away is detected.
+const EVENTLOG_STREAM_KEEPALIVE_S = 30
+
+// The number of event logs buffered for a stream client that is slow to read them.
+const EVENTLOG_STREAM_BUFFER = 100
+
+// Stream the new event logs as server-sent events. The same selectors as the eventlog API can be used to filter the
+// event logs. When the client sends the Last-Event-ID header, the saved event logs after that record are sent first.
+func (a *API) eventlogstream(w http.ResponseWriter, r *http.Request) {
+
+	resource := "eventlog/stream"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		lan := r.Header.Get("Accept-Language")
+		if lan == "" {
+			lan = i18n.DEFAULT_LANGUAGE
+		}
+		msgPrinter := i18n.GetMessagePrinterWithLocale(lan)
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			errorHandler(NewSystemError(msgPrinter.Sprintf("Streaming is not supported by the connection.")))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			errorHandler(NewAPIUserInputError(msgPrinter.Sprintf("Error parsing the selections %v. %v", r.Form, err), "selection"))
+			return
+		}
+
+		selectors, err := persistence.ConvertToSelectors(r.Form)
+		if err != nil {
+			errorHandler(NewAPIUserInputError(msgPrinter.Sprintf("Error converting the selections into Selectors: %v", err), "selection"))
+			return
+		}
+
+		lastId := uint64(0)
+		if lastEventId := r.Header.Get("Last-Event-ID"); lastEventId != "" {
+			if lastId, err = strconv.ParseUint(lastEventId, 10, 64); err != nil {
+				errorHandler(NewAPIUserInputError(msgPrinter.Sprintf("Last-Event-ID %v is not an event log record id.", lastEventId), "Last-Event-ID"))
+				return
+			}
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v with selection %v. Language: %v", r.Method, resource, r.Form, lan)))
+
+		// Subscribe before reading the saved event logs so that no event log is missed in between.
+		sub := persistence.SubscribeEventLogs(EVENTLOG_STREAM_BUFFER)
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		// Send an event log unless it was already sent. Returns false when the client is gone.
+		send := func(el persistence.EventLog) bool {
+			if id, err := strconv.ParseUint(el.Id, 10, 64); err == nil {
+				if id <= lastId {
+					return true
+				}
+				lastId = id
+			}
+			if sse, err := EventLogToSSE(el); err != nil {
+				glog.Errorf(apiLogString(fmt.Sprintf("unable to serialize event log %v, error: %v", el, err)))
+				return true
+			} else if _, err := w.Write(sse); err != nil {
+				return false
+			}
+			flusher.Flush()
+			return true
+		}
+
+		if lastId != 0 {
+			if saved, err := FindEventLogsForOutput(a.db, true, r.Form, msgPrinter); err != nil {
+				glog.Errorf(apiLogString(fmt.Sprintf("unable to get the saved event logs for %v, error: %v", resource, err)))
+			} else {
+				for _, el := range saved {
+					if !send(el) {
+						return
+					}
+				}
+			}
+		}
+		flusher.Flush()
+
+		keepalive := time.NewTicker(EVENTLOG_STREAM_KEEPALIVE_S * time.Second)
+		defer keepalive.Stop()
+
+		dropped := uint64(0)
+		for {
+			select {
+			case <-r.Context().Done():
+				glog.V(5).Infof(apiLogString(fmt.Sprintf("%v client disconnected", resource)))
+				return
+			case el := <-sub.C:
+				if out, matches := EventLogForOutput(el, selectors, msgPrinter); matches && !send(out) {
+					return
+				}
+			case <-keepalive.C:
+				// Tell the client when event logs were dropped because it was not reading them fast enough.
+				comment := ": keepalive\n\n"
+				if d := sub.Dropped(); d != dropped {
+					comment = fmt.Sprintf(": %v event logs were dropped\n\n", d-dropped)
+					dropped = d
+				}
+				if _, err := w.Write([]byte(comment)); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
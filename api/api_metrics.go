@@ -0,0 +1,30 @@
+package api
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/metrics"
+	"net/http"
+)
+
+// Serve the agent metrics in the Prometheus exposition format.
+func (a *API) metrics(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		metrics.DefaultRegistry().Handler().ServeHTTP(w, r)
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Refresh the agent specific metrics from the local database whenever the metrics are scraped.
+func (a *API) registerMetricsCollector() {
+	metrics.DefaultRegistry().RegisterCollector("agent", func() {
+		if err := UpdateAgentMetrics(a.db); err != nil {
+			glog.Errorf(apiLogString(fmt.Sprintf("Unable to collect agent metrics, error %v", err)))
+		}
+	})
+}
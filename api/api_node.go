@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/containermessage"
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
@@ -567,3 +568,83 @@ func (a *API) nodeuserinput(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
+
+func (a *API) nodeadmissionpolicy(w http.ResponseWriter, r *http.Request) {
+
+	resource := "node/admissionpolicy"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		if out, err := FindNodeAdmissionPolicyForOutput(a.db); err != nil {
+			errorHandler(NewSystemError(fmt.Sprintf("Error getting %v for output, error %v", resource, err)))
+		} else {
+			writeResponse(w, out, http.StatusOK)
+		}
+
+	case "HEAD":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		if out, err := FindNodeAdmissionPolicyForOutput(a.db); err != nil {
+			errorHandler(NewSystemError(fmt.Sprintf("Error getting %v for output, error %v", resource, err)))
+		} else if serial, errWritten := serializeResponse(w, out); !errWritten {
+			w.Header().Add("Content-Length", strconv.Itoa(len(serial)))
+			w.WriteHeader(http.StatusOK)
+		}
+
+	case "PUT", "POST":
+		// Because there is one admission policy object, POST and PUT are interchangeable. Either can be used to create the object and either
+		// can be used to replace the existing object.
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		var admissionPolicy containermessage.AdmissionPolicy
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &admissionPolicy); err != nil {
+			LogDeviceEvent(a.db, persistence.SEVERITY_ERROR,
+				persistence.NewMessageMeta(EL_API_ERR_PARSING_INPUT_FOR_NODE_ADM, string(body), err.Error()),
+				persistence.EC_API_USER_INPUT_ERROR, nil)
+			errorHandler(NewAPIUserInputError(fmt.Sprintf("Input body could not be deserialized to %v object: %v, error: %v", resource, string(body), err), "body"))
+			return
+		}
+
+		update_admission_policy_error_handler := func(device interface{}, err error) bool {
+			LogDeviceEvent(a.db, persistence.SEVERITY_ERROR, persistence.NewMessageMeta(EL_API_ERR_IN_NODE_ADM_UPDATE, err.Error()), persistence.EC_ERROR_NODE_ADMISSION_POLICY_UPDATE, device)
+			return errorHandler(err)
+		}
+
+		// Validate and create or replace the admission policy.
+		errHandled, cfg := UpdateNodeAdmissionPolicy(&admissionPolicy, update_admission_policy_error_handler, a.db)
+		if errHandled {
+			return
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handled %v on resource %v", r.Method, resource)))
+
+		writeResponse(w, cfg, http.StatusCreated)
+
+	case "DELETE":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		delete_admission_policy_error_handler := func(device interface{}, err error) bool {
+			LogDeviceEvent(a.db, persistence.SEVERITY_ERROR, persistence.NewMessageMeta(EL_API_ERR_IN_NODE_ADM_DEL, err.Error()), persistence.EC_ERROR_NODE_ADMISSION_POLICY_UPDATE, device)
+			return errorHandler(err)
+		}
+
+		if errHandled := DeleteNodeAdmissionPolicy(delete_admission_policy_error_handler, a.db); errHandled {
+			return
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handled %v on resource %v", r.Method, resource)))
+
+		w.WriteHeader(http.StatusNoContent)
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
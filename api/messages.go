@@ -14,6 +14,7 @@ const (
 	EL_API_ERR_PARSING_INPUT_FOR_NODE_POLICY_PATCH = "Error parsing input for node policy patch. Input body could not be deserialized into a Constraint Expression or Property List: %v, error: %v"
 	EL_API_ERR_POLICY_PATCH_INPUT_PROPERTY_ERROR2  = "Error parsing input for node policy patch. Input body does not contain 'properties', 'constraints', 'deployment' or 'management' attribute: %v"
 	EL_API_ERR_PARSING_INPUT_FOR_NODE_UI           = "Error parsing input for node user input. Input body could not be deserialized as a UserInput object: %v, error: %v"
+	EL_API_ERR_PARSING_INPUT_FOR_NODE_ADM          = "Error parsing input for node admission policy. Input body could not be deserialized as an admission policy object: %v, error: %v"
 
 	EL_API_ERR_IN_NODE_REG            = "Error in node configuration/registration for node %v. %v"
 	EL_API_ERR_IN_NODE_UPDATE         = "Error in updating node %v. %v"
@@ -25,6 +26,13 @@ const (
 	EL_API_ERR_IN_NODE_UI_UPDATE      = "Error in updating node user input. %v"
 	EL_API_ERR_IN_NODE_UI_PATCH       = "Error in patching node user input. %v"
 	EL_API_ERR_IN_NODE_UI_DEL         = "Error in deleting node userinput. %v"
+	EL_API_ERR_IN_NODE_ADM_UPDATE     = "Error in updating node admission policy. %v"
+	EL_API_ERR_IN_NODE_ADM_DEL        = "Error in deleting node admission policy. %v"
+
+	// from api_bundle.go
+	EL_API_ERR_PARSING_INPUT_FOR_BUNDLE = "Error parsing input for offline bundle. Input body could not be deserialized as a bundle object: %v, error: %v"
+	EL_API_ERR_IN_BUNDLE_LOAD           = "Error in loading offline bundle. %v"
+	EL_API_ERR_IN_BUNDLE_DEL            = "Error in deleting offline bundle. %v"
 
 	// from path_node.go
 	EL_API_START_NODE_REG       = "Start node configuration/registration for node %v."
@@ -63,6 +71,15 @@ const (
 	EL_API_NO_NODE_UI_TO_DEL   = "No node user input to detele"
 	EL_API_DELETED_ALL_NODE_UI = "Deleted all node user input"
 
+	// from path_node_admissionpolicy.go
+	EL_API_NEW_NODE_ADMISSION_POL     = "New node admission policy: %v"
+	EL_API_NODE_ADMISSION_POL_DELETED = "Deleted node admission policy"
+
+	// from path_bundle.go
+	EL_API_OFFLINE_BUNDLE_LOADED   = "Loaded offline bundle %v"
+	EL_API_OFFLINE_BUNDLE_DOWNLOAD = "Start downloading offline bundle from object %v/%v/%v"
+	EL_API_OFFLINE_BUNDLE_DELETED  = "Deleted offline bundle %v"
+
 	// from path_service_config.go
 	EL_API_START_SVC_CONFIG           = "Start service configuration with user input for %v/%v."
 	EL_API_START_SVC_AUTO_CONFIG      = "Start service auto configuration for %v/%v."
@@ -96,6 +113,7 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_API_ERR_PARSING_INPUT_FOR_NODE_POLICY_PATCH)
 	msgPrinter.Sprintf(EL_API_ERR_POLICY_PATCH_INPUT_PROPERTY_ERROR2)
 	msgPrinter.Sprintf(EL_API_ERR_PARSING_INPUT_FOR_NODE_UI)
+	msgPrinter.Sprintf(EL_API_ERR_PARSING_INPUT_FOR_NODE_ADM)
 
 	msgPrinter.Sprintf(EL_API_ERR_IN_NODE_REG)
 	msgPrinter.Sprintf(EL_API_ERR_IN_NODE_UPDATE)
@@ -107,6 +125,13 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_API_ERR_IN_NODE_UI_UPDATE)
 	msgPrinter.Sprintf(EL_API_ERR_IN_NODE_UI_PATCH)
 	msgPrinter.Sprintf(EL_API_ERR_IN_NODE_UI_DEL)
+	msgPrinter.Sprintf(EL_API_ERR_IN_NODE_ADM_UPDATE)
+	msgPrinter.Sprintf(EL_API_ERR_IN_NODE_ADM_DEL)
+
+	// from api_bundle.go
+	msgPrinter.Sprintf(EL_API_ERR_PARSING_INPUT_FOR_BUNDLE)
+	msgPrinter.Sprintf(EL_API_ERR_IN_BUNDLE_LOAD)
+	msgPrinter.Sprintf(EL_API_ERR_IN_BUNDLE_DEL)
 
 	// from path_node.go
 	msgPrinter.Sprintf(EL_API_START_NODE_REG)
@@ -145,6 +170,15 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_API_NO_NODE_UI_TO_DEL)
 	msgPrinter.Sprintf(EL_API_DELETED_ALL_NODE_UI)
 
+	// from path_node_admissionpolicy.go
+	msgPrinter.Sprintf(EL_API_NEW_NODE_ADMISSION_POL)
+	msgPrinter.Sprintf(EL_API_NODE_ADMISSION_POL_DELETED)
+
+	// from path_bundle.go
+	msgPrinter.Sprintf(EL_API_OFFLINE_BUNDLE_LOADED)
+	msgPrinter.Sprintf(EL_API_OFFLINE_BUNDLE_DOWNLOAD)
+	msgPrinter.Sprintf(EL_API_OFFLINE_BUNDLE_DELETED)
+
 	// from path_service_config.go
 	msgPrinter.Sprintf(EL_API_START_SVC_CONFIG)
 	msgPrinter.Sprintf(EL_API_START_SVC_AUTO_CONFIG)
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/imagefetch"
+	"github.com/open-horizon/anax/persistence"
+	"io"
+)
+
+// The MMS object that holds an offline bundle. The agent downloads the object and loads the bundle in the background.
+type BundleObject struct {
+	Org        string `json:"org"`
+	ObjectType string `json:"objectType"`
+	ObjectId   string `json:"objectId"`
+}
+
+// Return all the offline bundles that are loaded on the node.
+func FindOfflineBundlesForOutput(db *bolt.DB) (map[string]persistence.OfflineBundle, error) {
+
+	out := make(map[string]persistence.OfflineBundle)
+	if bundles, err := persistence.FindOfflineBundles(db); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read offline bundles, error %v", err))
+	} else {
+		for _, b := range bundles {
+			out[b.Id] = b
+		}
+	}
+	return out, nil
+}
+
+// Verify the offline bundle that is read from the reader and load it into the bundle store. The user input that comes
+// with the bundle is added to the node user input when the node is registered.
+func LoadOfflineBundle(r io.Reader,
+	errorhandler DeviceErrorHandler,
+	getDevice exchange.DeviceHandler,
+	patchDevice exchange.PatchDeviceHandler,
+	getService exchange.ServiceHandler,
+	cfg *config.HorizonConfig,
+	db *bolt.DB) (bool, *persistence.OfflineBundle, []*events.NodeUserInputMessage) {
+
+	// A bundle can be loaded before the node is registered.
+	var device interface{}
+	pDevice, err := persistence.FindExchangeDevice(db)
+	if err != nil {
+		return errorhandler(nil, NewSystemError(fmt.Sprintf("Unable to read node object, error %v", err))), nil, nil
+	} else if pDevice != nil {
+		device = pDevice
+	}
+
+	pemFiles, err := cfg.Collaborators.KeyFileNamesFetcher.GetKeyFileNames(cfg.Edge.PublicKeyPath, cfg.UserPublicKeyPath())
+	if err != nil {
+		return errorhandler(device, NewSystemError(fmt.Sprintf("Unable to get the trusted public keys, error %v", err))), nil, nil
+	}
+
+	bundle, err := imagefetch.LoadBundle(db, cfg.Edge.GetOfflineBundleDirectory(), pemFiles, r, "upload")
+	if err != nil {
+		return errorhandler(device, NewAPIUserInputError(fmt.Sprintf("Unable to load the offline bundle, error: %v", err), "bundle")), nil, nil
+	}
+
+	LogDeviceEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta(EL_API_OFFLINE_BUNDLE_LOADED, bundle.Id), persistence.EC_OFFLINE_BUNDLE_LOADED, device)
+
+	if len(bundle.UserInput) == 0 {
+		return false, bundle, nil
+	} else if pDevice == nil {
+		glog.Warningf(apiLogString(fmt.Sprintf("The user input of offline bundle %v is not added to the node user input because the node is not registered.", bundle.Id)))
+		return false, bundle, nil
+	}
+
+	errHandled, _, msgs := PatchNodeUserInput(bundle.UserInput, errorhandler, getDevice, patchDevice, getService, db)
+	if errHandled {
+		return true, nil, nil
+	}
+	return false, bundle, msgs
+}
+
+// Validate the MMS object that holds an offline bundle and return the event that asks the agent to download it.
+func DownloadOfflineBundle(obj *BundleObject, errorhandler DeviceErrorHandler, db *bolt.DB) (bool, *events.BundleDownloadMessage) {
+
+	var device interface{}
+	pDevice, err := persistence.FindExchangeDevice(db)
+	if err != nil {
+		return errorhandler(nil, NewSystemError(fmt.Sprintf("Unable to read node object, error %v", err))), nil
+	} else if pDevice == nil {
+		return errorhandler(nil, NewAPIUserInputError("The node must be registered to download an offline bundle from the model management system.", "bundle")), nil
+	} else {
+		device = pDevice
+	}
+
+	if obj.ObjectType == "" || obj.ObjectId == "" {
+		return errorhandler(device, NewAPIUserInputError("The objectType and objectId of the bundle object must be specified.", "bundle")), nil
+	}
+
+	org := obj.Org
+	if org == "" {
+		org = pDevice.Org
+	}
+
+	LogDeviceEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta(EL_API_OFFLINE_BUNDLE_DOWNLOAD, org, obj.ObjectType, obj.ObjectId), persistence.EC_OFFLINE_BUNDLE_DOWNLOAD, device)
+	return false, events.NewBundleDownloadMessage(events.BUNDLE_DOWNLOAD, org, obj.ObjectType, obj.ObjectId)
+}
+
+// Remove the offline bundle from the node. The images that were already loaded into docker are not removed.
+func DeleteOfflineBundle(id string, errorhandler DeviceErrorHandler, cfg *config.HorizonConfig, db *bolt.DB) bool {
+
+	var device interface{}
+	if pDevice, err := persistence.FindExchangeDevice(db); err != nil {
+		return errorhandler(nil, NewSystemError(fmt.Sprintf("Unable to read node object, error %v", err)))
+	} else if pDevice != nil {
+		device = pDevice
+	}
+
+	if bundle, err := persistence.FindOfflineBundle(db, id); err != nil {
+		return errorhandler(device, NewSystemError(fmt.Sprintf("Unable to read offline bundle %v, error %v", id, err)))
+	} else if bundle == nil {
+		return errorhandler(device, NewNotFoundError(fmt.Sprintf("Offline bundle %v is not found.", id), "id"))
+	} else if err := imagefetch.RemoveBundle(db, cfg.Edge.GetOfflineBundleDirectory(), id); err != nil {
+		return errorhandler(device, NewSystemError(fmt.Sprintf("Offline bundle %v could not be deleted. %v", id, err)))
+	}
+
+	LogDeviceEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta(EL_API_OFFLINE_BUNDLE_DELETED, id), persistence.EC_OFFLINE_BUNDLE_DELETED, device)
+	return false
+}
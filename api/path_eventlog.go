@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/boltdb/bolt"
 	"github.com/golang/glog"
@@ -45,3 +46,23 @@ func FindSurfaceLogsForOutput(db *bolt.DB, msgPrinter *message.Printer) ([]persi
 	}
 	return outputLogs, nil
 }
+
+// Returns the event log with its message translated for output, and whether it matches the selectors. The event logs
+// are matched after the translation so that the message selectors work the same way as they do for the stored event logs.
+func EventLogForOutput(el persistence.EventLog, selectors map[string][]persistence.Selector, msgPrinter *message.Printer) (persistence.EventLog, bool) {
+	if el.MessageMeta != nil && el.MessageMeta.MessageKey != "" {
+		el.Message = msgPrinter.Sprintf(el.MessageMeta.MessageKey, el.MessageMeta.MessageArgs...)
+		el.MessageMeta = nil
+	}
+	return el, el.Matches(selectors)
+}
+
+// Returns the event log as a server-sent event. The record id is the event id, so a client that reconnects
+// with the Last-Event-ID header gets the event logs it has missed.
+func EventLogToSSE(el persistence.EventLog) ([]byte, error) {
+	if data, err := json.Marshal(el); err != nil {
+		return nil, err
+	} else {
+		return []byte(fmt.Sprintf("id: %v\ndata: %s\n\n", el.Id, data)), nil
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/stretchr/testify/assert"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -116,3 +117,29 @@ func Test_FindEventLogsForOutput(t *testing.T) {
 	}
 
 }
+
+func Test_EventLogForOutput(t *testing.T) {
+
+	msgPrinter := i18n.GetMessagePrinterWithLocale("en")
+
+	source := persistence.NewNodeEventSource("mynode", "myorg", "", "configured")
+	el := persistence.NewEventLog(persistence.SEVERITY_INFO, persistence.NewMessageMeta("Node %v configured.", "mynode"), persistence.EC_NODE_CONFIG_REG_COMPLETE, persistence.SRC_TYPE_NODE, *source)
+	el.Id = "12"
+
+	selectors, err := persistence.ConvertToSelectors(map[string][]string{"message": []string{"~configured"}, "source_type": []string{persistence.SRC_TYPE_NODE}})
+	assert.Nil(t, err, "converting the selections should not fail")
+
+	out, matches := EventLogForOutput(*el, selectors, msgPrinter)
+	assert.True(t, matches, "the translated message should match the selectors")
+	assert.Equal(t, "Node mynode configured.", out.Message, "the message should be translated")
+	assert.Nil(t, out.MessageMeta, "the message meta should not be in the output")
+
+	selectors, _ = persistence.ConvertToSelectors(map[string][]string{"source_type": []string{persistence.SRC_TYPE_AG}})
+	_, matches = EventLogForOutput(*el, selectors, msgPrinter)
+	assert.False(t, matches, "the event log should not match another source type")
+
+	sse, err := EventLogToSSE(out)
+	assert.Nil(t, err, "serializing the event log should not fail")
+	assert.True(t, strings.HasPrefix(string(sse), "id: 12\ndata: {"), "the event id should be the record id")
+	assert.True(t, strings.HasSuffix(string(sse), "}\n\n"), "the event should be terminated by a blank line")
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/metrics"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+)
+
+// Agent specific metrics, computed from the local database each time the metrics are scraped.
+var agreementsGauge = metrics.DefaultRegistry().NewGaugeVec("anax_agreements",
+	"Number of agreements on the node by agreement protocol and state.", "protocol", "state")
+
+var serviceInstancesGauge = metrics.DefaultRegistry().NewGaugeVec("anax_service_instances",
+	"Number of dependent and agreement-less service instances on the node by state.", "state")
+
+var agreementContainersGauge = metrics.DefaultRegistry().NewGaugeVec("anax_agreement_containers",
+	"Number of containers deployed for executing agreements on the node.")
+
+// Agreement states reported in the agreement metrics.
+const (
+	AG_STATE_CREATED     = "created"
+	AG_STATE_ACCEPTED    = "accepted"
+	AG_STATE_FINALIZED   = "finalized"
+	AG_STATE_EXECUTING   = "executing"
+	AG_STATE_TERMINATING = "terminating"
+	AG_STATE_ARCHIVED    = "archived"
+)
+
+// Service instance states reported in the service instance metrics.
+const (
+	MS_STATE_STARTING = "starting"
+	MS_STATE_RUNNING  = "running"
+	MS_STATE_FAILED   = "failed"
+	MS_STATE_CLEANUP  = "cleanup"
+	MS_STATE_ARCHIVED = "archived"
+)
+
+// Returns the most advanced lifecycle state the agreement has reached.
+func agreementMetricsState(ag *persistence.EstablishedAgreement) string {
+	if ag.Archived {
+		return AG_STATE_ARCHIVED
+	} else if ag.AgreementTerminatedTime != 0 {
+		return AG_STATE_TERMINATING
+	} else if ag.AgreementExecutionStartTime != 0 {
+		return AG_STATE_EXECUTING
+	} else if ag.AgreementFinalizedTime != 0 {
+		return AG_STATE_FINALIZED
+	} else if ag.AgreementAcceptedTime != 0 {
+		return AG_STATE_ACCEPTED
+	}
+	return AG_STATE_CREATED
+}
+
+// Returns the state of the service instance.
+func serviceInstanceMetricsState(msi *persistence.MicroserviceInstance) string {
+	if msi.Archived {
+		return MS_STATE_ARCHIVED
+	} else if msi.CleanupStartTime != 0 {
+		return MS_STATE_CLEANUP
+	} else if msi.ExecutionFailureCode != 0 {
+		return MS_STATE_FAILED
+	} else if msi.ExecutionStartTime != 0 {
+		return MS_STATE_RUNNING
+	}
+	return MS_STATE_STARTING
+}
+
+// Refresh the agent specific gauges from the local database.
+func UpdateAgentMetrics(db *bolt.DB) error {
+
+	agreements, err := persistence.FindEstablishedAgreementsAllProtocols(db, policy.AllAgreementProtocols(), []persistence.EAFilter{})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to read agreement objects, error %v", err))
+	}
+
+	msinsts, err := persistence.FindMicroserviceInstances(db, []persistence.MIFilter{})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to read service instance objects, error %v", err))
+	}
+
+	agreementsGauge.Reset()
+	for _, agp := range policy.AllAgreementProtocols() {
+		for _, state := range []string{AG_STATE_CREATED, AG_STATE_ACCEPTED, AG_STATE_FINALIZED, AG_STATE_EXECUTING, AG_STATE_TERMINATING, AG_STATE_ARCHIVED} {
+			agreementsGauge.WithLabelValues(agp, state).Set(0)
+		}
+	}
+
+	containers := 0
+	for _, ag := range agreements {
+		state := agreementMetricsState(&ag)
+		agreementsGauge.WithLabelValues(ag.AgreementProtocol, state).Inc()
+		if state == AG_STATE_EXECUTING {
+			containers += len(ag.CurrentDeployment)
+		}
+	}
+	agreementContainersGauge.WithLabelValues().Set(float64(containers))
+
+	serviceInstancesGauge.Reset()
+	for _, state := range []string{MS_STATE_STARTING, MS_STATE_RUNNING, MS_STATE_FAILED, MS_STATE_CLEANUP, MS_STATE_ARCHIVED} {
+		serviceInstancesGauge.WithLabelValues(state).Set(0)
+	}
+	for _, msi := range msinsts {
+		serviceInstancesGauge.WithLabelValues(serviceInstanceMetricsState(&msi)).Inc()
+	}
+
+	return nil
+}
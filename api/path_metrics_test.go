@@ -0,0 +1,53 @@
+//go:build unit
+// +build unit
+
+package api
+
+import (
+	"github.com/open-horizon/anax/persistence"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"testing"
+)
+
+func Test_UpdateAgentMetrics(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	// Agreement 1 is executing, agreement 2 is archived and agreement 3 has only been created.
+	sp := persistence.ServiceSpec{Url: "http://sensor.org", Org: "myorg"}
+	sps := []persistence.ServiceSpec{sp}
+
+	wi, _ := persistence.NewWorkloadInfo("url", "org", "version", "")
+	if _, err := persistence.NewEstablishedAgreement(db, "name1", "agreementId1", "consumerId", "{}", "Basic", 1, sps, "signature", "address", "bcType", "bcName", "bcOrg", wi, 180); err != nil {
+		t.Errorf("error writing agreement1: %v", err)
+	} else if _, err := persistence.NewEstablishedAgreement(db, "name1", "agreementId2", "consumerId", "{}", "Basic", 1, sps, "signature", "address", "bcType", "bcName", "bcOrg", wi, 180); err != nil {
+		t.Errorf("error writing agreement2: %v", err)
+	} else if _, err := persistence.NewEstablishedAgreement(db, "name1", "agreementId3", "consumerId", "{}", "Basic", 1, sps, "signature", "address", "bcType", "bcName", "bcOrg", wi, 180); err != nil {
+		t.Errorf("error writing agreement3: %v", err)
+	} else if _, err := persistence.AgreementStateExecutionStarted(db, "agreementId1", "Basic"); err != nil {
+		t.Errorf("error starting agreement1: %v", err)
+	} else if _, err := persistence.ArchiveEstablishedAgreement(db, "agreementId2", "Basic"); err != nil {
+		t.Errorf("error archiving agreement2: %v", err)
+	}
+
+	if err := UpdateAgentMetrics(db); err != nil {
+		t.Errorf("error updating agent metrics: %v", err)
+	}
+
+	expected := []struct {
+		state string
+		num   float64
+	}{{AG_STATE_EXECUTING, 1}, {AG_STATE_ARCHIVED, 1}, {AG_STATE_CREATED, 1}, {AG_STATE_TERMINATING, 0}}
+	for _, e := range expected {
+		if num := testutil.ToFloat64(agreementsGauge.WithLabelValues("Basic", e.state)); num != e.num {
+			t.Errorf("there should be %v %v agreements, but there are %v", e.num, e.state, num)
+		}
+	}
+	if num := testutil.ToFloat64(serviceInstancesGauge.WithLabelValues(MS_STATE_RUNNING)); num != 0 {
+		t.Errorf("there should not be running service instances, but there are %v", num)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/persistence"
+)
+
+// Return an empty admission policy object or the object that's in the local database. An empty admission policy
+// does not restrict the deployments.
+func FindNodeAdmissionPolicyForOutput(db *bolt.DB) (*containermessage.AdmissionPolicy, error) {
+
+	if admissionPolicy, err := persistence.FindNodeAdmissionPolicy(db); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read node admission policy object, error %v", err))
+	} else if admissionPolicy == nil {
+		return &containermessage.AdmissionPolicy{}, nil
+	} else {
+		return admissionPolicy, nil
+	}
+}
+
+// Validate and save the admission policy object in the local node database. The admission policy is not stored in the
+// exchange, it is applied by the agent to the proposals it receives and to the containers it starts.
+func UpdateNodeAdmissionPolicy(admissionPolicy *containermessage.AdmissionPolicy,
+	errorhandler DeviceErrorHandler,
+	db *bolt.DB) (bool, *containermessage.AdmissionPolicy) {
+
+	// The admission policy can be set before the node is registered.
+	var device interface{}
+	if pDevice, err := persistence.FindExchangeDevice(db); err != nil {
+		return errorhandler(nil, NewSystemError(fmt.Sprintf("Unable to read node object, error %v", err))), nil
+	} else if pDevice != nil {
+		device = pDevice
+	}
+
+	if err := admissionPolicy.Validate(); err != nil {
+		return errorhandler(device, NewAPIUserInputError(fmt.Sprintf("Admission policy is not valid, error: %v", err), "node.admissionpolicy")), nil
+	} else if err := persistence.SaveNodeAdmissionPolicy(db, admissionPolicy); err != nil {
+		return errorhandler(device, NewSystemError(fmt.Sprintf("Unable to save node admission policy, error %v", err))), nil
+	}
+
+	LogDeviceEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta(EL_API_NEW_NODE_ADMISSION_POL, *admissionPolicy), persistence.EC_NODE_ADMISSION_POLICY_UPDATED, device)
+	return false, admissionPolicy
+}
+
+// Delete the admission policy object, the node then admits all the deployments again.
+func DeleteNodeAdmissionPolicy(errorhandler DeviceErrorHandler, db *bolt.DB) bool {
+
+	var device interface{}
+	if pDevice, err := persistence.FindExchangeDevice(db); err != nil {
+		return errorhandler(nil, NewSystemError(fmt.Sprintf("Unable to read node object, error %v", err)))
+	} else if pDevice != nil {
+		device = pDevice
+	}
+
+	if err := persistence.DeleteNodeAdmissionPolicy(db); err != nil {
+		return errorhandler(device, NewSystemError(fmt.Sprintf("Node admission policy could not be deleted. %v", err)))
+	}
+
+	LogDeviceEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta(EL_API_NODE_ADMISSION_POL_DELETED), persistence.EC_NODE_ADMISSION_POLICY_DELETED, device)
+	return false
+}
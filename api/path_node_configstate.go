@@ -207,6 +207,17 @@ func nodeAllowPrivilegedService(db *bolt.DB) (bool, error) {
 	return nodePriv, nil
 }
 
+// get the restricted container options that the node allows in the 'openhorizon.allowedContainerOptions' property
+func nodeAllowedContainerOptions(db *bolt.DB) ([]string, error) {
+	nodePol, err := FindNodePolicyForOutput(db)
+	if err != nil {
+		return nil, err
+	} else if nodePol == nil {
+		return []string{}, nil
+	}
+	return compcheck.NodeAllowedContainerOptions(nodePol.Properties), nil
+}
+
 // Common function used to create/configure a service on an edge node. The boolean response indicates that an error occurred
 // and was handled (or no error occurred).
 func configureService(service *Service,
@@ -377,6 +388,15 @@ func getSpecRefsForPattern(nodeType string, patName string,
 		}
 	}
 
+	// get the restricted container options that the node allows, a privileged node allows all of them
+	nodeOpts := []string{}
+	if checkNodePrivilege && !nodePriv {
+		nodeOpts, err1 = nodeAllowedContainerOptions(db)
+		if err1 != nil {
+			return nil, nil, NewSystemError(fmt.Sprintf("Error getting node %v setting. %v", externalpolicy.PROP_NODE_CONTAINER_OPTIONS, err1))
+		}
+	}
+
 	for _, service := range patternDef.Services {
 
 		// Ignore top-level services that don't match this node's hardware architecture.
@@ -419,6 +439,14 @@ func getSpecRefsForPattern(nodeType string, patName string,
 				}
 			}
 
+			if checkNodePrivilege && !nodePriv {
+				if svcOpts, err := compcheck.DeploymentContainerOptions(serviceDef.GetDeploymentString(), nil); err != nil {
+					return nil, nil, NewSystemError(fmt.Sprintf("Error checking the container options of service %v. %v", topSvcID, err))
+				} else if disallowed := compcheck.DisallowedContainerOptions(svcOpts, nodeOpts); len(disallowed) != 0 {
+					return nil, nil, NewSystemError(fmt.Sprintf("Service %v uses the container options %v, but the node does not allow them in the %v property or have openhorizon.allowPrivileged property set to true.", topSvcID, disallowed, externalpolicy.PROP_NODE_CONTAINER_OPTIONS))
+				}
+			}
+
 			// Look for inconsistencies in the hardware architecture of the list of dependencies.
 			if apiSpecList != nil {
 				for _, apiSpec := range *apiSpecList {
@@ -435,6 +463,18 @@ func getSpecRefsForPattern(nodeType string, patName string,
 					}
 				}
 
+				if checkNodePrivilege && !nodePriv {
+					if svcOpts, err := compcheck.ServicesRequireContainerOptions(nil, "", dependentDefs, nil); err != nil {
+						return nil, nil, NewSystemError(fmt.Sprintf("Error checking the container options of the dependent services for %v. %v", topSvcID, err))
+					} else {
+						for sId, opts := range svcOpts {
+							if disallowed := compcheck.DisallowedContainerOptions(opts, nodeOpts); len(disallowed) != 0 {
+								return nil, nil, NewSystemError(fmt.Sprintf("Dependent service %v for %v uses the container options %v, but the node does not allow them in the %v property or have openhorizon.allowPrivileged property set to true.", sId, topSvcID, disallowed, externalpolicy.PROP_NODE_CONTAINER_OPTIONS))
+							}
+						}
+					}
+				}
+
 				// MergeWith will omit exact duplicates when merging the 2 lists.
 				(*completeAPISpecList) = completeAPISpecList.MergeWith(apiSpecList)
 			}
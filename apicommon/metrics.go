@@ -0,0 +1,36 @@
+package apicommon
+
+import (
+	"github.com/open-horizon/anax/metrics"
+	"github.com/open-horizon/anax/worker"
+)
+
+// Metrics that are common to the agent and the agbot /metrics API.
+var workerStatusGauge = metrics.DefaultRegistry().NewGaugeVec("anax_worker_status",
+	"Current status of each worker, the value is 1 for the status the worker is in.", "worker", "status")
+
+var subworkerStatusGauge = metrics.DefaultRegistry().NewGaugeVec("anax_subworker_status",
+	"Current status of each subworker, the value is 1 for the status the subworker is in.", "worker", "subworker", "status")
+
+func init() {
+	metrics.DefaultRegistry().RegisterCollector("worker_status", CollectWorkerStatusMetrics)
+}
+
+// Refresh the worker status gauges from the worker status manager.
+func CollectWorkerStatusMetrics() {
+	wsm := worker.GetWorkerStatusManager()
+
+	wsm.ManagerLock.Lock()
+	defer wsm.ManagerLock.Unlock()
+
+	workerStatusGauge.Reset()
+	subworkerStatusGauge.Reset()
+	for name, ws := range wsm.Workers {
+		ws.StatusLock.Lock()
+		workerStatusGauge.WithLabelValues(name, ws.Status).Set(1)
+		for subname, status := range ws.SubworkerStatus {
+			subworkerStatusGauge.WithLabelValues(name, subname, status).Set(1)
+		}
+		ws.StatusLock.Unlock()
+	}
+}
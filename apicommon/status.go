@@ -30,10 +30,19 @@ type HealthTimestamps struct {
 	LastVaultInteraction uint64 `json:"lastVaultInteraction"`
 }
 
+// The agbot that runs the singleton background tasks of the agbots that share a database. Filled in by the agbot API.
+type LeaderInfo struct {
+	Instance      string `json:"instance"`      // the instance id of the leading agbot
+	Partition     string `json:"partition"`     // the database partition of the leading agbot
+	LastHeartbeat uint64 `json:"lastHeartbeat"` // the last partition heartbeat of the leading agbot
+	ThisAgbot     bool   `json:"thisAgbot"`     // true when the agbot that serves the API is the leader
+}
+
 type Info struct {
 	Configuration *Configuration    `json:"configuration"`
 	Connectivity  map[string]bool   `json:"connectivity,omitempty"`
 	LiveHealth    *HealthTimestamps `json:"liveHealth"`
+	Leader        *LeaderInfo       `json:"leader,omitempty"`
 }
 
 func NewInfo(httpClientFactory *config.HTTPClientFactory, exchangeUrl string, mmsUrl string,
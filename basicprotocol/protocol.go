@@ -78,6 +78,19 @@ func NewBAgreementVerifyReply(bp *abstractprotocol.BaseProtocolMessage, exists b
 // imply that the rejecting party is cancelling the agreement. However, the sending party is free to cancel the agreement upon
 // receipt of a rejection.
 const MsgUpdateTypeSecret = "basicagreementupdatesecret"
+const MsgUpdateTypeImageDigests = "basicagreementupdateimagedigests"
+
+// The metadata of an image digests update. The producer sends the content digests of the images that it resolved for the
+// service of the agreement, keyed by container name, so that the consumer can pin them for the other nodes.
+type ImageDigestsUpdate struct {
+	Version string            `json:"version"`
+	Arch    string            `json:"arch"`
+	Digests map[string]string `json:"digests"`
+}
+
+func (d ImageDigestsUpdate) String() string {
+	return fmt.Sprintf("Version: %v, Arch: %v, Digests: %v", d.Version, d.Arch, d.Digests)
+}
 
 type BAgreementUpdate struct {
 	*abstractprotocol.BaseProtocolMessage
@@ -104,6 +117,10 @@ func (b *BAgreementUpdate) IsSecretUpdate() bool {
 	return b.Updatetype == MsgUpdateTypeSecret
 }
 
+func (b *BAgreementUpdate) IsImageDigestsUpdate() bool {
+	return b.Updatetype == MsgUpdateTypeImageDigests
+}
+
 func (b *BAgreementUpdate) UpdateType() string {
 	return b.Updatetype
 }
@@ -139,6 +156,10 @@ func (b *BAgreementUpdateReply) IsSecretUpdate() bool {
 	return b.Updatetype == MsgUpdateTypeSecret
 }
 
+func (b *BAgreementUpdateReply) IsImageDigestsUpdate() bool {
+	return b.Updatetype == MsgUpdateTypeImageDigests
+}
+
 func (b *BAgreementUpdateReply) IsAccepted() bool {
 	return b.Accepted
 }
@@ -43,15 +43,39 @@ type ServiceRef struct {
 	Arch            string           `json:"arch,omitempty"`            // the hardware architecture of the service definition
 	ServiceVersions []WorkloadChoice `json:"serviceVersions,omitempty"` // a list of service version for rollback
 	NodeH           NodeHealth       `json:"nodeHealth"`                // policy for determining when a node's health is violating its agreements
+	PinImageDigests bool             `json:"pinImageDigests,omitempty"` // all the nodes run the image digests that the first node resolved for a service version
 }
 
 func (w ServiceRef) String() string {
-	return fmt.Sprintf("Name: %v, Org: %v, Arch: %v, ServiceVersions: %v, NodeH: %v",
+	return fmt.Sprintf("Name: %v, Org: %v, Arch: %v, ServiceVersions: %v, NodeH: %v, PinImageDigests: %v",
 		w.Name,
 		w.Org,
 		w.Arch,
 		w.ServiceVersions,
-		w.NodeH)
+		w.NodeH,
+		w.PinImageDigests)
+}
+
+// Returns the highest priority service version choice, or nil if the versions do not have priorities.
+func (w *ServiceRef) HighestPriorityChoice() *WorkloadChoice {
+	var choice *WorkloadChoice
+	for ix, wl := range w.ServiceVersions {
+		if wl.Priority.PriorityValue != 0 && (choice == nil || wl.Priority.PriorityValue < choice.Priority.PriorityValue) {
+			choice = &w.ServiceVersions[ix]
+		}
+	}
+	return choice
+}
+
+// Returns the service version choice with the next lower priority after the given priority value.
+func (w *ServiceRef) NextPriorityChoice(priorityValue int) *WorkloadChoice {
+	var choice *WorkloadChoice
+	for ix, wl := range w.ServiceVersions {
+		if wl.Priority.PriorityValue > priorityValue && (choice == nil || wl.Priority.PriorityValue < choice.Priority.PriorityValue) {
+			choice = &w.ServiceVersions[ix]
+		}
+	}
+	return choice
 }
 
 type WorkloadPriority struct {
@@ -72,12 +96,15 @@ func (w WorkloadPriority) String() string {
 type UpgradePolicy struct {
 	Lifecycle string `json:"lifecycle,omitempty"` // immediate, never, agreement
 	Time      string `json:"time,omitempty"`      // the time of the upgrade
+
+	Rollout *RolloutPolicy `json:"rollout,omitempty"` // roll this version out to the nodes in stages
 }
 
 func (w UpgradePolicy) String() string {
-	return fmt.Sprintf("Lifecycle: %v, Time: %v",
+	return fmt.Sprintf("Lifecycle: %v, Time: %v, Rollout: %v",
 		w.Lifecycle,
-		w.Time)
+		w.Time,
+		w.Rollout)
 }
 
 type WorkloadChoice struct {
@@ -118,6 +145,18 @@ func (b *BusinessPolicy) Validate() error {
 		return fmt.Errorf(msgPrinter.Sprintf("The serviceVersions array is empty."))
 	}
 
+	// Validate the rollout policies. A staged rollout needs a lower priority version to keep the nodes on until
+	// they are upgraded.
+	for _, wl := range b.Service.ServiceVersions {
+		if wl.Upgrade.Rollout == nil {
+			continue
+		} else if err := wl.Upgrade.Rollout.Validate(); err != nil {
+			return fmt.Errorf(msgPrinter.Sprintf("The rollout for service version %v is not valid: %v", wl.Version, err))
+		} else if wl.Priority.PriorityValue == 0 || b.Service.NextPriorityChoice(wl.Priority.PriorityValue) == nil {
+			return fmt.Errorf(msgPrinter.Sprintf("The rollout for service version %v requires a priority and a lower priority service version.", wl.Version))
+		}
+	}
+
 	// Validate the PropertyList.
 	if b != nil && len(b.Properties) != 0 {
 		if err := b.Properties.Validate(); err != nil {
@@ -196,6 +235,9 @@ func (b *BusinessPolicy) GenPolicyFromBusinessPolicy(policyName string) (*policy
 	// node health
 	ConvertNodeHealth(service.NodeH, pol)
 
+	// image digest pinning
+	pol.PinImageDigests = service.PinImageDigests
+
 	pol.MaxAgreements = DEFAULT_MAX_AGREEMENT
 
 	// add default agreement protocol
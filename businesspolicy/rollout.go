@@ -0,0 +1,133 @@
+package businesspolicy
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/i18n"
+	"hash/fnv"
+)
+
+// The rollout policy of a service version. When a version with a rollout policy is the highest priority version in
+// the deployment policy, the nodes are moved to it in stages instead of all at once. The nodes that match the canary
+// constraints are upgraded first. The rest of the nodes are split into waves; each wave is a cumulative percentage
+// of the nodes. Each stage starts when the previous stage has soaked for SoakTimeS seconds. Nodes that are not in a
+// started stage stay on the next highest priority version.
+type RolloutPolicy struct {
+	CanaryConstraints externalpolicy.ConstraintExpression `json:"canaryConstraints,omitempty"` // the nodes whose properties satisfy these constraints get the new version first
+	Waves             []int                               `json:"waves,omitempty"`             // cumulative percentages of the nodes upgraded in each wave after the canary, the last one must be 100
+	SoakTimeS         int                                 `json:"soakTime,omitempty"`          // the number of seconds to wait after a stage has started before starting the next one
+	MaxFailures       int                                 `json:"maxFailures,omitempty"`       // the rollout halts when more than this number of upgraded nodes fail to run the new version
+	AutoRollback      bool                                `json:"autoRollback,omitempty"`      // when the rollout halts, move the upgraded nodes back to the next highest priority version
+}
+
+func (r RolloutPolicy) String() string {
+	return fmt.Sprintf("CanaryConstraints: %v, Waves: %v, SoakTimeS: %v, MaxFailures: %v, AutoRollback: %v",
+		r.CanaryConstraints,
+		r.Waves,
+		r.SoakTimeS,
+		r.MaxFailures,
+		r.AutoRollback)
+}
+
+func (r *RolloutPolicy) Validate() error {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if r.SoakTimeS < 0 {
+		return fmt.Errorf(msgPrinter.Sprintf("The rollout soakTime cannot be negative."))
+	} else if r.MaxFailures < 0 {
+		return fmt.Errorf(msgPrinter.Sprintf("The rollout maxFailures cannot be negative."))
+	}
+
+	last := 0
+	for _, w := range r.Waves {
+		if w <= last || w > 100 {
+			return fmt.Errorf(msgPrinter.Sprintf("The rollout waves must be increasing percentages between 1 and 100: %v", r.Waves))
+		}
+		last = w
+	}
+	if len(r.Waves) != 0 && last != 100 {
+		return fmt.Errorf(msgPrinter.Sprintf("The last rollout wave must be 100 so that all the nodes are upgraded: %v", r.Waves))
+	}
+
+	if len(r.CanaryConstraints) != 0 {
+		if _, err := r.CanaryConstraints.Validate(); err != nil {
+			return fmt.Errorf(msgPrinter.Sprintf("canaryConstraints is not valid: %v", err))
+		}
+	}
+	return nil
+}
+
+// Returns true if the rollout starts with a canary stage.
+func (r *RolloutPolicy) HasCanary() bool {
+	for _, c := range r.CanaryConstraints {
+		if c != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// The waves after the canary stage. A rollout without waves upgrades all the remaining nodes at once.
+func (r *RolloutPolicy) waves() []int {
+	if len(r.Waves) == 0 {
+		return []int{100}
+	}
+	return r.Waves
+}
+
+// Returns the number of stages in the rollout, including the canary stage.
+func (r *RolloutPolicy) NumStages() int {
+	return len(r.waves()) + 1
+}
+
+// Returns the rollout stage of a node. Stage 0 is the canary stage, stages 1 to n are the waves. A node that is
+// not a canary is assigned to a wave by hashing its id, so that the same node always lands in the same wave.
+func (r *RolloutPolicy) NodeStage(nodeId string, nodeProps externalpolicy.PropertyList) int {
+	if r.HasCanary() {
+		if err := r.CanaryConstraints.IsSatisfiedBy(nodeProps); err == nil {
+			return 0
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(nodeId))
+	bucket := int(h.Sum32() % 100)
+
+	waves := r.waves()
+	for ix, w := range waves {
+		if bucket < w {
+			return ix + 1
+		}
+	}
+	return len(waves)
+}
+
+// Returns the number of seconds after the start of the rollout when the given stage starts. When there is no
+// canary stage, the first wave starts right away.
+func (r *RolloutPolicy) StageStartOffset(stage int) int {
+	if r.HasCanary() {
+		return stage * r.SoakTimeS
+	} else if stage <= 1 {
+		return 0
+	}
+	return (stage - 1) * r.SoakTimeS
+}
+
+// Returns the highest stage that has started after the rollout has been running for the given number of seconds.
+func (r *RolloutPolicy) StartedStage(elapsedS int) int {
+	started := 0
+	for stage := 1; stage < r.NumStages(); stage++ {
+		if elapsedS >= r.StageStartOffset(stage) {
+			started = stage
+		}
+	}
+	return started
+}
+
+// Returns the number of seconds after the start of the rollout when the last stage has soaked and the rollout
+// is complete.
+func (r *RolloutPolicy) Duration() int {
+	return r.StageStartOffset(r.NumStages()-1) + r.SoakTimeS
+}
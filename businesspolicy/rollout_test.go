@@ -0,0 +1,136 @@
+//go:build unit
+// +build unit
+
+package businesspolicy
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/externalpolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"strings"
+	"testing"
+)
+
+func getRolloutBusinessPolicy(rollout *RolloutPolicy) BusinessPolicy {
+	return BusinessPolicy{
+		Service: ServiceRef{
+			Name: "cpu",
+			Org:  "mycomp",
+			Arch: "amd64",
+			ServiceVersions: []WorkloadChoice{
+				WorkloadChoice{Version: "1.0.0", Priority: WorkloadPriority{PriorityValue: 2}},
+				WorkloadChoice{Version: "1.1.0", Priority: WorkloadPriority{PriorityValue: 1}, Upgrade: UpgradePolicy{Rollout: rollout}},
+			},
+		},
+	}
+}
+
+func Test_Validate_Rollout(t *testing.T) {
+
+	valid := []RolloutPolicy{
+		RolloutPolicy{},
+		RolloutPolicy{Waves: []int{10, 50, 100}, SoakTimeS: 600, MaxFailures: 2, AutoRollback: true},
+		RolloutPolicy{CanaryConstraints: []string{"canary == true"}, Waves: []int{100}},
+	}
+	for _, r := range valid {
+		bp := getRolloutBusinessPolicy(&r)
+		if err := bp.Validate(); err != nil {
+			t.Errorf("rollout %v should be valid but got error: %v", r, err)
+		}
+	}
+
+	invalid := []struct {
+		rollout RolloutPolicy
+		errMsg  string
+	}{
+		{RolloutPolicy{SoakTimeS: -1}, "soakTime cannot be negative"},
+		{RolloutPolicy{MaxFailures: -1}, "maxFailures cannot be negative"},
+		{RolloutPolicy{Waves: []int{50, 10, 100}}, "must be increasing"},
+		{RolloutPolicy{Waves: []int{10, 200}}, "between 1 and 100"},
+		{RolloutPolicy{Waves: []int{10, 50}}, "last rollout wave must be 100"},
+		{RolloutPolicy{CanaryConstraints: []string{"canary === true"}}, "canaryConstraints is not valid"},
+	}
+	for _, tc := range invalid {
+		bp := getRolloutBusinessPolicy(&tc.rollout)
+		if err := bp.Validate(); err == nil {
+			t.Errorf("rollout %v should not be valid", tc.rollout)
+		} else if !strings.Contains(err.Error(), tc.errMsg) {
+			t.Errorf("wrong error for rollout %v: %v", tc.rollout, err)
+		}
+	}
+
+	// a rollout needs a lower priority version to keep the nodes on
+	bp := getRolloutBusinessPolicy(&RolloutPolicy{})
+	bp.Service.ServiceVersions = bp.Service.ServiceVersions[1:]
+	if err := bp.Validate(); err == nil || !strings.Contains(err.Error(), "requires a priority and a lower priority service version") {
+		t.Errorf("rollout without a lower priority version should not be valid, error: %v", err)
+	}
+
+	bp = getRolloutBusinessPolicy(&RolloutPolicy{})
+	bp.Service.ServiceVersions[1].Priority.PriorityValue = 0
+	if err := bp.Validate(); err == nil || !strings.Contains(err.Error(), "requires a priority and a lower priority service version") {
+		t.Errorf("rollout for a version without priority should not be valid, error: %v", err)
+	}
+}
+
+func Test_HighestPriorityChoice(t *testing.T) {
+
+	bp := getRolloutBusinessPolicy(nil)
+	if choice := bp.Service.HighestPriorityChoice(); choice == nil || choice.Version != "1.1.0" {
+		t.Errorf("wrong highest priority choice: %v", choice)
+	} else if next := bp.Service.NextPriorityChoice(choice.Priority.PriorityValue); next == nil || next.Version != "1.0.0" {
+		t.Errorf("wrong next priority choice: %v", next)
+	} else if last := bp.Service.NextPriorityChoice(next.Priority.PriorityValue); last != nil {
+		t.Errorf("there should be no choice after the lowest priority but got: %v", last)
+	}
+
+	bp.Service.ServiceVersions = []WorkloadChoice{WorkloadChoice{Version: "1.0.0"}}
+	if choice := bp.Service.HighestPriorityChoice(); choice != nil {
+		t.Errorf("versions without priority should have no highest priority choice but got: %v", choice)
+	}
+}
+
+func Test_RolloutStages(t *testing.T) {
+
+	r := RolloutPolicy{Waves: []int{10, 50, 100}, SoakTimeS: 100}
+
+	// without a canary, the first wave starts right away
+	if r.HasCanary() {
+		t.Errorf("rollout should not have a canary")
+	} else if r.NumStages() != 4 {
+		t.Errorf("wrong number of stages: %v", r.NumStages())
+	} else if r.StartedStage(0) != 1 || r.StartedStage(99) != 1 || r.StartedStage(100) != 2 || r.StartedStage(250) != 3 {
+		t.Errorf("wrong started stages for a rollout without canary")
+	} else if r.Duration() != 300 {
+		t.Errorf("wrong duration: %v", r.Duration())
+	}
+
+	// the waves hold about the right share of the nodes, and a node is always in the same wave
+	counts := make([]int, r.NumStages())
+	for i := 0; i < 10000; i++ {
+		nodeId := fmt.Sprintf("myorg/node%v", i)
+		stage := r.NodeStage(nodeId, nil)
+		if stage != r.NodeStage(nodeId, nil) {
+			t.Errorf("node %v should always be in the same stage", nodeId)
+		}
+		counts[stage]++
+	}
+	if counts[0] != 0 || counts[1] < 700 || counts[1] > 1300 || counts[2] < 3500 || counts[2] > 4500 || counts[3] < 4500 || counts[3] > 5500 {
+		t.Errorf("wrong distribution of nodes across the waves: %v", counts)
+	}
+
+	// with a canary, the canary nodes are stage 0 and the first wave starts after the soak time
+	r.CanaryConstraints = externalpolicy.ConstraintExpression{"canary == true"}
+	canaryProps := externalpolicy.PropertyList{*externalpolicy.Property_Factory("canary", true)}
+	if !r.HasCanary() {
+		t.Errorf("rollout should have a canary")
+	} else if r.NodeStage("myorg/node1", canaryProps) != 0 {
+		t.Errorf("canary node should be in stage 0")
+	} else if r.NodeStage("myorg/node1", nil) == 0 {
+		t.Errorf("node without the canary property should be in a wave")
+	} else if r.StartedStage(0) != 0 || r.StartedStage(100) != 1 || r.StartedStage(300) != 3 {
+		t.Errorf("wrong started stages for a rollout with canary")
+	} else if r.Duration() != 400 {
+		t.Errorf("wrong duration: %v", r.Duration())
+	}
+}
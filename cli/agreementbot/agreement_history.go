@@ -0,0 +1,95 @@
+package agreementbot
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	agbot "github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// The columns of the csv output, in the same order as the fields of the agreement history record.
+var agreementHistoryColumns = []string{"agreement_id", "agreement_protocol", "event", "time", "org", "device_id", "device_type", "policy_name", "pattern", "service_url", "service_org", "service_version", "service_arch", "reason_code", "reason_description"}
+
+// Convert a --since or --until flag value into seconds since the epoch. The value is either an RFC3339 time or a
+// duration before now.
+func parseHistoryTime(flagName string, value string) uint64 {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if value == "" {
+		return 0
+	} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return uint64(t.Unix())
+	} else if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return uint64(time.Now().Add(-d).Unix())
+	}
+	cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("invalid --%v value %v, it must be an RFC3339 time such as 2021-06-01T00:00:00Z or a duration such as 24h.", flagName, value))
+	return 0
+}
+
+func AgreementHistory(node string, policy string, service string, since string, until string, limit int, output string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if limit < 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("--limit cannot be negative."))
+	}
+
+	params := url.Values{}
+	if node != "" {
+		params.Set("node", node)
+	}
+	if policy != "" {
+		params.Set("policy", policy)
+	}
+	if service != "" {
+		params.Set("service", service)
+	}
+	if s := parseHistoryTime("since", since); s != 0 {
+		params.Set("since", strconv.FormatUint(s, 10))
+	}
+	if u := parseHistoryTime("until", until); u != 0 {
+		params.Set("until", strconv.FormatUint(u, 10))
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	urlSuffix := "agreement/history"
+	if len(params) != 0 {
+		urlSuffix += "?" + params.Encode()
+	}
+
+	// set env to call agbot url
+	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
+	}
+
+	records := make([]agbot.AgreementHistoryRecord, 0)
+	cliutils.HorizonGet(urlSuffix, []int{200}, &records, false)
+
+	if output == "csv" {
+		writer := csv.NewWriter(os.Stdout)
+		writer.Write(agreementHistoryColumns)
+		for _, r := range records {
+			writer.Write([]string{r.AgreementId, r.AgreementProtocol, r.Event, time.Unix(int64(r.Timestamp), 0).UTC().Format(time.RFC3339), r.Org, r.DeviceId, r.DeviceType, r.PolicyName, r.Pattern, r.ServiceURL, r.ServiceOrg, r.ServiceVersion, r.ServiceArch, strconv.FormatUint(uint64(r.ReasonCode), 10), r.ReasonDescription})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("failed to write 'agreement history' output: %v", err))
+		}
+		return
+	}
+
+	jsonBytes, err := json.MarshalIndent(records, "", cliutils.JSON_INDENT)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'agreement history' output: %v", err))
+	}
+	fmt.Printf("%s\n", jsonBytes)
+}
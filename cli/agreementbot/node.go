@@ -18,6 +18,7 @@ type AgbotAndStatus struct {
 	// from apicommon.Info
 	Configuration *apicommon.Configuration `json:"configuration"`
 	Connectivity  map[string]bool          `json:"connectivity,omitempty"`
+	Leader        *apicommon.LeaderInfo    `json:"leader,omitempty"`
 }
 
 // CopyNodeInto copies the node info into our output struct
@@ -31,6 +32,7 @@ func (n *AgbotAndStatus) CopyNodeInto(horDevice *agreementbot.HorizonAgbot) {
 func (n *AgbotAndStatus) CopyStatusInto(status *apicommon.Info) {
 	//todo: I don't like having to repeat all of these fields, hard to maintain. Maybe use reflection?
 	n.Configuration = status.Configuration
+	n.Leader = status.Leader
 }
 
 func List() {
@@ -0,0 +1,67 @@
+package agreementbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"net/http"
+	"os"
+)
+
+// List the database partitions of the agbots that share this agbot's database, with their owner, the age of the
+// owner's last heartbeat and the number of records in each partition.
+func PartitionList() {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// set env to call agbot url
+	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
+	}
+
+	partitions := make(map[string]map[string]interface{})
+	cliutils.HorizonGet("partition", []int{200}, &partitions, false)
+
+	jsonBytes, err := json.MarshalIndent(partitions, "", cliutils.JSON_INDENT)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn agbot partition list' output: %v", err))
+	}
+	fmt.Printf("%s\n", jsonBytes)
+}
+
+// Drain this agbot before maintenance. The agbot quiesces and the other running agbots take over its agreements.
+func PartitionDrain(noWait bool, force bool) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if !force {
+		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to stop this agbot from making new agreements and hand its agreements over to the other agbots?"))
+	}
+
+	// set env to call agbot url
+	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
+	}
+
+	block := "true"
+	if noWait {
+		block = "false"
+	}
+
+	msgPrinter.Printf("Draining the agbot ...")
+	msgPrinter.Println()
+	_, resp, _ := cliutils.HorizonPutPost(http.MethodPost, "partition/drain?block="+block, []int{200}, nil, true)
+
+	var output map[string]string
+	if err := json.Unmarshal([]byte(resp), &output); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("Unable to unmarshal the drain output: %v", err))
+	}
+
+	if noWait {
+		msgPrinter.Printf("The agbot is quiescing, the agreements in partition %v are taken over by the other agbots once it is released.", output["partition"])
+	} else {
+		msgPrinter.Printf("The agbot is quiesced, the agreements in partition %v are taken over by the other agbots.", output["partition"])
+	}
+	msgPrinter.Println()
+}
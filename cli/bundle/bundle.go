@@ -0,0 +1,87 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/api"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"net/http"
+	"os"
+)
+
+// Display the offline bundles that are loaded on the node, or a single bundle.
+func List(id string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	var output string
+	var err error
+	if id == "" {
+		bundles := make(map[string]persistence.OfflineBundle)
+		cliutils.HorizonGet("bundle", []int{200}, &bundles, false)
+		output, err = cliutils.DisplayAsJson(bundles)
+	} else {
+		var bundle persistence.OfflineBundle
+		cliutils.HorizonGet("bundle/"+id, []int{200}, &bundle, false)
+		output, err = cliutils.DisplayAsJson(bundle)
+	}
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("Unable to marshal offline bundle object: %v", err))
+	}
+	fmt.Println(output)
+}
+
+// Upload an offline bundle file to the agent. The agent verifies the bundle before it is stored.
+func Load(filePath string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// The bundle contains the image tarballs, so it is streamed to the agent instead of being read into memory.
+	bundleFile, err := os.Open(filePath)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("unable to open bundle file %v: %v", filePath, err))
+	}
+	defer bundleFile.Close()
+
+	_, resp, _ := cliutils.HorizonPutPost(http.MethodPost, "bundle", []int{201}, bundleFile, true)
+
+	var bundle persistence.OfflineBundle
+	if err := json.Unmarshal([]byte(resp), &bundle); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("Unable to unmarshal offline bundle object: %v", err))
+	}
+	msgPrinter.Printf("Offline bundle %v loaded.", bundle.Id)
+	msgPrinter.Println()
+}
+
+// Ask the agent to download an offline bundle from the Model Management System and load it.
+func LoadObject(org string, objType string, objId string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	obj := api.BundleObject{
+		Org:        org,
+		ObjectType: objType,
+		ObjectId:   objId,
+	}
+	cliutils.HorizonPutPost(http.MethodPost, "bundle", []int{202}, obj, true)
+
+	msgPrinter.Printf("The Horizon agent is downloading the offline bundle from object %v/%v. Use 'hzn bundle list' to check that the bundle is loaded.", objType, objId)
+	msgPrinter.Println()
+}
+
+// Remove an offline bundle from the node.
+func Remove(id string, force bool) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if !force {
+		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove offline bundle %v?", id))
+	}
+
+	cliutils.HorizonDelete("bundle/"+id, []int{204}, []int{}, false)
+
+	msgPrinter.Printf("Offline bundle %v removed.", id)
+	msgPrinter.Println()
+}
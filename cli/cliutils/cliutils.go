@@ -834,6 +834,7 @@ func HorizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body i
 
 	// Prepare body
 	var jsonBytes []byte
+	var bodyFile *os.File
 	bodyIsBytes := false
 	switch b := body.(type) {
 	// If the body is a byte array or string, we treat it like a file being uploaded (not multi-part)
@@ -843,6 +844,9 @@ func HorizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body i
 	case string:
 		jsonBytes = []byte(b)
 		bodyIsBytes = true
+	// If the body is a file, it is streamed from the file
+	case *os.File:
+		bodyFile = b
 	// Else it is a struct so assume it should be sent as json
 	default:
 		var err error
@@ -853,7 +857,19 @@ func HorizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body i
 			return 0, "", err
 		}
 	}
-	requestBody := bytes.NewBuffer(jsonBytes)
+	var requestBody io.Reader = bytes.NewBuffer(jsonBytes)
+	bodyLen := int64(len(jsonBytes))
+	if bodyFile != nil {
+		fileInfo, err := bodyFile.Stat()
+		if exitOnErr && err != nil {
+			Fatal(FILE_IO_ERROR, msgPrinter.Sprintf("failed to get file info of %v for %s: %v", bodyFile.Name(), apiMsg, err))
+		} else if err != nil {
+			return 0, "", err
+		}
+		requestBody = bodyFile
+		bodyLen = fileInfo.Size()
+		bodyIsBytes = true
+	}
 
 	// Create the request and run it
 	req, err := http.NewRequest(method, url, requestBody)
@@ -865,7 +881,8 @@ func HorizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body i
 	req.Close = true
 	req.Header.Add("Accept", "application/json")
 	if bodyIsBytes {
-		req.Header.Add("Content-Length", strconv.Itoa(len(jsonBytes)))
+		req.ContentLength = bodyLen
+		req.Header.Add("Content-Length", strconv.FormatInt(bodyLen, 10))
 	} else {
 		req.Header.Add("Content-Type", "application/json")
 	}
@@ -215,6 +215,46 @@ func BusinessUpdatePolicy(org string, credToUse string, policyName string, fileP
 	msgPrinter.Println()
 }
 
+//BusinessSimulatePolicy asks the agbot which nodes a deployment policy would form agreements with, and which existing
+//agreements it would cancel, without publishing the policy or making any agreements.
+func BusinessSimulatePolicy(org string, credToUse string, policy string, jsonFilePath string, nodeOrgs []string) {
+	cliutils.SetWhetherUsingApiKey(credToUse)
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	input := compcheck.DeploySimulationInput{NodeOrgs: nodeOrgs}
+	if policy != "" {
+		polOrg, polName := cliutils.TrimOrg(org, policy)
+		input.BusinessPolId = polOrg + "/" + polName
+	}
+
+	//read in the business policy from file if given, otherwise the agbot gets the published policy from the Horizon Exchange
+	if jsonFilePath != "" {
+		newBytes := cliconfig.ReadJsonFileWithLocalConfig(jsonFilePath)
+		var policyFile businesspolicy.BusinessPolicy
+		if err := json.Unmarshal(newBytes, &policyFile); err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal json input file %s: %v", jsonFilePath, err))
+		} else if err := policyFile.Validate(); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Incorrect deployment policy format in file %s: %v", jsonFilePath, err))
+		}
+		input.BusinessPolicy = &policyFile
+	} else if policy == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Either the deployment policy name or -f must be specified."))
+	}
+
+	cliutils.Verbose(msgPrinter.Sprintf("Using deployment simulation input: %v", input))
+
+	var output compcheck.DeploySimulationOutput
+	cliutils.AgbotPutPost(http.MethodPost, "deploycheck/deploysimulate", cliutils.OrgAndCreds(org, credToUse), []int{200}, input, &output)
+
+	jsonBytes, err := json.MarshalIndent(output, "", cliutils.JSON_INDENT)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange deployment simulate' output: %v", err))
+	}
+	fmt.Println(string(jsonBytes))
+}
+
 //BusinessRemovePolicy will remove an existing business policy in the Horizon Exchange
 func BusinessRemovePolicy(org string, credToUse string, policy string, force bool) {
 	cliutils.SetWhetherUsingApiKey(credToUse)
@@ -11,6 +11,7 @@ import (
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/exchangecommon"
 	"github.com/open-horizon/anax/externalpolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/json_language"
 	_ "github.com/open-horizon/anax/externalpolicy/text_language"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
@@ -30,6 +31,7 @@ type ContainerStat struct {
 	Image   string `json:"image"`
 	Created int    `json:"created"`
 	State   string `json:"state"`
+	Health  string `json:"health,omitempty"`
 }
 
 type ExNodeStatusService struct {
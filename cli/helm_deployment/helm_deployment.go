@@ -99,6 +99,12 @@ func (p *HelmDeploymentConfigPlugin) Validate(dep interface{}, cdep interface{})
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
+	// A Helm chart is deployed to a cluster, it can be in the cluster deployment config when there is no native
+	// deployment config.
+	if dep == nil {
+		dep = cdep
+	}
+
 	if dc, ok := dep.(map[string]interface{}); !ok {
 		return false, nil
 	} else if c, ok := dc["chart_archive"]; !ok {
@@ -111,11 +117,25 @@ func (p *HelmDeploymentConfigPlugin) Validate(dep interface{}, cdep interface{})
 		return true, errors.New(msgPrinter.Sprintf("release_name must have a string type value, has %T", r))
 	} else if len(ca) == 0 || len(rn) == 0 {
 		return true, errors.New(msgPrinter.Sprintf("chart_archive and release_name must be non-empty strings"))
+	} else if ns, ok := dc["namespace"]; ok && !isString(ns) {
+		return true, errors.New(msgPrinter.Sprintf("namespace must have a string type value, has %T", ns))
+	} else if v, ok := dc["values"]; ok && !isObject(v) {
+		return true, errors.New(msgPrinter.Sprintf("values must have an object type value, has %T", v))
 	} else {
 		return true, nil
 	}
 }
 
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+func isObject(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
+
 func (p *HelmDeploymentConfigPlugin) StartTest(homeDirectory string, userInputFile string, configFiles []string, configType string, noFSS bool, userCreds string, secretsFiles map[string]string) bool {
 
 	// get message printer
@@ -6,6 +6,7 @@ import (
 	"github.com/open-horizon/anax/cli/agreement"
 	"github.com/open-horizon/anax/cli/agreementbot"
 	"github.com/open-horizon/anax/cli/attribute"
+	"github.com/open-horizon/anax/cli/bundle"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/cli/deploycheck"
@@ -76,6 +77,7 @@ Subcommands Description:
   agreement: List or manage the active or archived agreements this edge node has made with a Horizon agreement bot.
   architecture: Show the architecture of this machine (as defined by Horizon and golang). 
   attribute: List or manage the global attributes that are currently registered on this Horizon edge node.
+  bundle: List or manage the offline bundles of services that are loaded on this Horizon edge node.
   deploycheck: Check deployment compatibility.
   dev: Development tools for creation of services.
   env: Show the Horizon Environment Variables.
@@ -136,6 +138,14 @@ Environment Variables:
 	agbotAgreementCancelCmd := agbotAgreementCmd.Command("cancel | can", msgPrinter.Sprintf("Cancel 1 or all of the active agreements this Horizon agreement bot has with edge nodes. Usually an agbot will immediately negotiated a new agreement. ")).Alias("can").Alias("cancel")
 	agbotCancelAllAgreements := agbotAgreementCancelCmd.Flag("all", msgPrinter.Sprintf("Cancel all of the current agreements.")).Short('a').Bool()
 	agbotCancelAgreementId := agbotAgreementCancelCmd.Arg("agreement", msgPrinter.Sprintf("The active agreement to cancel.")).String()
+	agbotAgreementHistoryCmd := agbotAgreementCmd.Command("history | hist", msgPrinter.Sprintf("List the recorded lifecycle transitions (attempt, made, finalized, timedout, cancelled) of the agreements this Horizon agreement bot has made with edge nodes. Unlike archived agreements, the history is not purged.")).Alias("hist").Alias("history")
+	agbotAgreementHistoryNode := agbotAgreementHistoryCmd.Flag("node", msgPrinter.Sprintf("Only list the history of this node, in the form org/id.")).String()
+	agbotAgreementHistoryPolicy := agbotAgreementHistoryCmd.Flag("policy", msgPrinter.Sprintf("Only list the history of this deployment policy or pattern, in the form org/name.")).String()
+	agbotAgreementHistoryService := agbotAgreementHistoryCmd.Flag("service", msgPrinter.Sprintf("Only list the history of this top level service, in the form url or org/url.")).String()
+	agbotAgreementHistorySince := agbotAgreementHistoryCmd.Flag("since", msgPrinter.Sprintf("Only list the history at or after this time. The time is in RFC3339 format, such as 2021-06-01T00:00:00Z, or is a duration before now, such as 24h.")).String()
+	agbotAgreementHistoryUntil := agbotAgreementHistoryCmd.Flag("until", msgPrinter.Sprintf("Only list the history at or before this time. The time has the same format as --since.")).String()
+	agbotAgreementHistoryLimit := agbotAgreementHistoryCmd.Flag("limit", msgPrinter.Sprintf("The maximum number of records to list, the most recent ones are listed. 0 means no limit.")).Default("0").Int()
+	agbotAgreementHistoryOutput := agbotAgreementHistoryCmd.Flag("output", msgPrinter.Sprintf("The output format, json or csv.")).Short('o').Default("json").Enum("json", "csv")
 	agbotAgreementListCmd := agbotAgreementCmd.Command("list | ls", msgPrinter.Sprintf("List the active or archived agreements this Horizon agreement bot has with edge nodes.")).Alias("ls").Alias("list")
 	agbotlistArchivedAgreements := agbotAgreementListCmd.Flag("archived", msgPrinter.Sprintf("List archived agreements instead of the active agreements.")).Short('r').Bool()
 	agbotAgreement := agbotAgreementListCmd.Arg("agreement-id", msgPrinter.Sprintf("Show the details of this active or archived agreement.")).String()
@@ -159,6 +169,11 @@ Environment Variables:
 	agbotPolicyListCmd := agbotPolicyCmd.Command("list | ls", msgPrinter.Sprintf("List policies this Horizon agreement bot hosts.")).Alias("ls").Alias("list")
 	agbotPolicyOrg := agbotPolicyListCmd.Arg("org", msgPrinter.Sprintf("The organization the policy belongs to.")).String()
 	agbotPolicyName := agbotPolicyListCmd.Arg("name", msgPrinter.Sprintf("The policy name.")).String()
+	agbotPartitionCmd := agbotCmd.Command("partition | part", msgPrinter.Sprintf("List or manage the database partitions of the agreement bots that share this agreement bot's database.")).Alias("part").Alias("partition")
+	agbotPartitionListCmd := agbotPartitionCmd.Command("list | ls", msgPrinter.Sprintf("List the database partitions with their owner, the age of the owner's last heartbeat and the number of agreements and workload usages in each partition.")).Alias("ls").Alias("list")
+	agbotPartitionDrainCmd := agbotPartitionCmd.Command("drain", msgPrinter.Sprintf("Drain this agreement bot before maintenance. This agreement bot stops making agreements and releases its partition, then the other running agreement bots take over its agreements."))
+	agbotPartitionDrainNoWait := agbotPartitionDrainCmd.Flag("no-wait", msgPrinter.Sprintf("Do not wait for the agreement bot to quiesce.")).Bool()
+	agbotPartitionDrainForce := agbotPartitionDrainCmd.Flag("force", msgPrinter.Sprintf("Drain the agreement bot without prompting for confirmation.")).Short('f').Bool()
 	agbotStatusCmd := agbotCmd.Command("status", msgPrinter.Sprintf("Display the current horizon internal status for the Horizon agreement bot."))
 	agbotStatusLong := agbotStatusCmd.Flag("long", msgPrinter.Sprintf("Show detailed status")).Short('l').Bool()
 
@@ -175,6 +190,18 @@ Environment Variables:
 	attributeCmd := app.Command("attribute | attr", msgPrinter.Sprintf("List or manage the global attributes that are currently registered on this Horizon edge node.")).Alias("attr").Alias("attribute")
 	attributeListCmd := attributeCmd.Command("list | ls", msgPrinter.Sprintf("List the global attributes that are currently registered on this Horizon edge node.")).Alias("ls").Alias("list")
 
+	bundleCmd := app.Command("bundle", msgPrinter.Sprintf("List or manage the offline bundles of services that are loaded on this Horizon edge node."))
+	bundleListCmd := bundleCmd.Command("list | ls", msgPrinter.Sprintf("List the offline bundles that are loaded on this Horizon edge node.")).Alias("ls").Alias("list")
+	bundleListId := bundleListCmd.Arg("bundle-id", msgPrinter.Sprintf("Show the offline bundle with this id. If omitted, all the offline bundles are shown.")).String()
+	bundleLoadCmd := bundleCmd.Command("load", msgPrinter.Sprintf("Load a signed offline bundle of a service on this Horizon edge node. The images of the service are loaded from the bundle instead of being pulled from the image registry."))
+	bundleLoadFile := bundleLoadCmd.Flag("file", msgPrinter.Sprintf("The offline bundle file. Specify -f- to read from stdin. Mutually exclusive with --object-id.")).Short('f').String()
+	bundleLoadObjOrg := bundleLoadCmd.Flag("object-org", msgPrinter.Sprintf("The organization of the Model Management System object that holds the offline bundle. If omitted, the organization of the node is used.")).String()
+	bundleLoadObjType := bundleLoadCmd.Flag("object-type", msgPrinter.Sprintf("The type of the Model Management System object that holds the offline bundle.")).String()
+	bundleLoadObjId := bundleLoadCmd.Flag("object-id", msgPrinter.Sprintf("The id of the Model Management System object that holds the offline bundle. The Horizon agent downloads the object and loads the bundle. Mutually exclusive with -f.")).String()
+	bundleRemoveCmd := bundleCmd.Command("remove | rm", msgPrinter.Sprintf("Remove an offline bundle from this Horizon edge node. The images that were already loaded are not removed.")).Alias("rm").Alias("remove")
+	bundleRemoveId := bundleRemoveCmd.Arg("bundle-id", msgPrinter.Sprintf("The id of the offline bundle to remove.")).Required().String()
+	bundleRemoveForce := bundleRemoveCmd.Flag("force", msgPrinter.Sprintf("Skip the 'Are you sure?' prompt.")).Short('f').Bool()
+
 	deploycheckCmd := app.Command("deploycheck | dc", msgPrinter.Sprintf("Check deployment compatibility.")).Alias("dc").Alias("deploycheck")
 	deploycheckOrg := deploycheckCmd.Flag("org", msgPrinter.Sprintf("The Horizon exchange organization ID. If not specified, HZN_ORG_ID will be used as a default.")).Short('o').String()
 	deploycheckUserPw := deploycheckCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon exchange user credential to query exchange resources. If not specified, HZN_EXCHANGE_USER_AUTH or HZN_EXCHANGE_NODE_AUTH will be used as a default. If you don't prepend it with the organization id, it will automatically be prepended with the -o value.")).Short('u').PlaceHolder("USER:PW").String()
@@ -338,6 +365,11 @@ Environment Variables:
 	exBusinessRemovePolicyIdTok := exBusinessRemovePolicyCmd.Flag("id-token", msgPrinter.Sprintf("The Horizon ID and password of the user.")).Short('n').PlaceHolder("ID:TOK").String()
 	exBusinessRemovePolicyForce := exBusinessRemovePolicyCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
 	exBusinessRemovePolicyPolicy := exBusinessRemovePolicyCmd.Arg("policy", msgPrinter.Sprintf("The name of the deployment policy to be removed.")).Required().String()
+	exBusinessSimulatePolicyCmd := exBusinessCmd.Command("simulate | sim", msgPrinter.Sprintf("Show which nodes a deployment policy would form agreements with and which existing agreements it would cancel, without publishing the policy or sending any proposals. The compatibility of each node is checked by the agbot.")).Alias("sim").Alias("simulate")
+	exBusinessSimulatePolicyIdTok := exBusinessSimulatePolicyCmd.Flag("id-token", msgPrinter.Sprintf("The Horizon ID and password of the user.")).Short('n').PlaceHolder("ID:TOK").String()
+	exBusinessSimulatePolicyPolicy := exBusinessSimulatePolicyCmd.Arg("policy", msgPrinter.Sprintf("The name of the deployment policy in the Horizon Exchange to simulate. If -f is also specified, the policy in the file is simulated as a replacement for this policy.")).String()
+	exBusinessSimulatePolicyJsonFile := exBusinessSimulatePolicyCmd.Flag("json-file", msgPrinter.Sprintf("The path of a JSON file containing a deployment policy to simulate before it is published. Specify -f- to read from stdin.")).Short('f').String()
+	exBusinessSimulatePolicyNodeOrg := exBusinessSimulatePolicyCmd.Flag("node-org", msgPrinter.Sprintf("The organization of the nodes to check. This flag can be repeated. If omitted, the node organizations that the agbot serves for the deployment policy are checked.")).Strings()
 	exBusinessUpdatePolicyCmd := exBusinessCmd.Command("updatepolicy | upp", msgPrinter.Sprintf("Update one attribute of an existing deployment policy in the Horizon Exchange. The supported attributes are the top level attributes in the policy definition as shown by the command 'hzn exchange deployment new'.")).Alias("upp").Alias("updatepolicy")
 	exBusinessUpdatePolicyIdTok := exBusinessUpdatePolicyCmd.Flag("id-token", msgPrinter.Sprintf("The Horizon ID and password of the user.")).Short('n').PlaceHolder("ID:TOK").String()
 	exBusinessUpdatePolicyPolicy := exBusinessUpdatePolicyCmd.Arg("policy", msgPrinter.Sprintf("The name of the policy to be updated in the Horizon Exchange.")).Required().String()
@@ -942,6 +974,8 @@ Environment Variables:
 			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exBusinessAddPolicyIdTok, false)
 		case "deployment | dep removepolicy | rmp":
 			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exBusinessRemovePolicyIdTok, false)
+		case "deployment | dep simulate | sim":
+			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exBusinessSimulatePolicyIdTok, false)
 		case "deployment | dep new":
 			// does not require exchange credentials
 		case "version":
@@ -1227,6 +1261,8 @@ Environment Variables:
 		exchange.BusinessUpdatePolicy(*exOrg, credToUse, *exBusinessUpdatePolicyPolicy, *exBusinessUpdatePolicyJsonFile)
 	case exBusinessRemovePolicyCmd.FullCommand():
 		exchange.BusinessRemovePolicy(*exOrg, credToUse, *exBusinessRemovePolicyPolicy, *exBusinessRemovePolicyForce)
+	case exBusinessSimulatePolicyCmd.FullCommand():
+		exchange.BusinessSimulatePolicy(*exOrg, credToUse, *exBusinessSimulatePolicyPolicy, *exBusinessSimulatePolicyJsonFile, *exBusinessSimulatePolicyNodeOrg)
 	case exCatalogServiceListCmd.FullCommand():
 		exchange.CatalogServiceList(*exOrg, *exUserPw, *exCatalogServiceListShort, *exCatalogServiceListLong)
 	case exCatalogPatternListCmd.FullCommand():
@@ -1271,6 +1307,23 @@ Environment Variables:
 		metering.List(*listArchivedMetering)
 	case attributeListCmd.FullCommand():
 		attribute.List()
+	case bundleListCmd.FullCommand():
+		bundle.List(*bundleListId)
+	case bundleLoadCmd.FullCommand():
+		if *bundleLoadFile != "" && *bundleLoadObjId != "" {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("-f and --object-id are mutually exclusive."))
+		} else if *bundleLoadObjId != "" {
+			if *bundleLoadObjType == "" {
+				cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("--object-type must be specified with --object-id."))
+			}
+			bundle.LoadObject(*bundleLoadObjOrg, *bundleLoadObjType, *bundleLoadObjId)
+		} else if *bundleLoadFile != "" {
+			bundle.Load(*bundleLoadFile)
+		} else {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Either -f or --object-id must be specified."))
+		}
+	case bundleRemoveCmd.FullCommand():
+		bundle.Remove(*bundleRemoveId, *bundleRemoveForce)
 	case userinputListCmd.FullCommand():
 		userinput.List()
 	case userinputNewCmd.FullCommand():
@@ -1319,6 +1372,8 @@ Environment Variables:
 		dev.DependencyRemove(*devHomeDirectory, *devDependencyCmdSpecRef, *devDependencyCmdURL, *devDependencyCmdVersion, *devDependencyCmdArch, *devDependencyCmdOrg)
 	case agbotAgreementListCmd.FullCommand():
 		agreementbot.AgreementList(*agbotlistArchivedAgreements, *agbotAgreement)
+	case agbotAgreementHistoryCmd.FullCommand():
+		agreementbot.AgreementHistory(*agbotAgreementHistoryNode, *agbotAgreementHistoryPolicy, *agbotAgreementHistoryService, *agbotAgreementHistorySince, *agbotAgreementHistoryUntil, *agbotAgreementHistoryLimit, *agbotAgreementHistoryOutput)
 	case agbotAgreementCancelCmd.FullCommand():
 		agreementbot.AgreementCancel(*agbotCancelAgreementId, *agbotCancelAllAgreements)
 	case agbotListCmd.FullCommand():
@@ -1329,6 +1384,10 @@ Environment Variables:
 		utilcmds.Sign(*utilSignPrivKeyFile)
 	case utilVerifyCmd.FullCommand():
 		utilcmds.Verify(*utilVerifyPubKeyFile, *utilVerifySig)
+	case agbotPartitionListCmd.FullCommand():
+		agreementbot.PartitionList()
+	case agbotPartitionDrainCmd.FullCommand():
+		agreementbot.PartitionDrain(*agbotPartitionDrainNoWait, *agbotPartitionDrainForce)
 	case agbotStatusCmd.FullCommand():
 		status.DisplayStatus(*agbotStatusLong, true)
 	case utilConfigConvCmd.FullCommand():
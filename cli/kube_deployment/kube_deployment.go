@@ -11,6 +11,7 @@ import (
 	"github.com/open-horizon/anax/cli/dev"
 	"github.com/open-horizon/anax/cli/plugin_registry"
 	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/rsapss-tool/sign"
 	"io/ioutil"
 	"os"
@@ -113,11 +114,40 @@ func (p *KubeDeploymentConfigPlugin) Validate(dep interface{}, cdep interface{})
 		return true, errors.New(msgPrinter.Sprintf("operatorYamlArchive must have a string type value, has %T", c))
 	} else if len(ca) == 0 {
 		return true, errors.New(msgPrinter.Sprintf("operatorYamlArchive must be non-empty strings"))
+	} else if i, ok := dc["isolatedNamespace"]; ok && !isBool(i) {
+		return true, errors.New(msgPrinter.Sprintf("isolatedNamespace must have a boolean type value, has %T", i))
+	} else if r, ok := dc["resources"]; ok && !isObject(r) {
+		return true, errors.New(msgPrinter.Sprintf("resources must have an object type value, has %T", r))
+	} else if ok {
+		for name, v := range r.(map[string]interface{}) {
+			if f, ok := v.(float64); !ok || f < 0 {
+				return true, errors.New(msgPrinter.Sprintf("resources %v must have a non-negative number value, has %v", name, v))
+			}
+		}
+		resources := new(persistence.KubeResourceRequirements)
+		if rBytes, err := json.Marshal(r); err != nil {
+			return true, errors.New(msgPrinter.Sprintf("failed to marshal resources %v: %v", r, err))
+		} else if err := json.Unmarshal(rBytes, resources); err != nil {
+			return true, errors.New(msgPrinter.Sprintf("resources %v are not valid: %v", r, err))
+		} else if err := resources.Validate(); err != nil {
+			return true, errors.New(msgPrinter.Sprintf("resources are not valid: %v", err))
+		}
+		return true, nil
 	} else {
 		return true, nil
 	}
 }
 
+func isBool(v interface{}) bool {
+	_, ok := v.(bool)
+	return ok
+}
+
+func isObject(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
+
 func (p *KubeDeploymentConfigPlugin) StartTest(homeDirectory string, userInputFile string, configFiles []string, configType string, noFSS bool, userCreds string, secretsFiles map[string]string) bool {
 
 	// get message printer
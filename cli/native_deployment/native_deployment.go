@@ -173,7 +173,7 @@ func (p *NativeDeploymentConfigPlugin) Validate(dep interface{}, cdep interface{
 }
 
 // This can't be a const because a map literal isn't a const in go
-var VALID_DEPLOYMENT_FIELDS = map[string]int8{"image": 1, "privileged": 1, "cap_add": 1, "environment": 1, "devices": 1, "binds": 1, "specific_ports": 1, "command": 1, "ports": 1, "ephemeral_ports": 1, "tmpfs": 1, "network": 1, "entrypoint": 1, "max_memory_mb": 1, "max_cpus": 1, "log_driver": 1, "secrets": 1}
+var VALID_DEPLOYMENT_FIELDS = map[string]int8{"image": 1, "privileged": 1, "cap_add": 1, "environment": 1, "devices": 1, "binds": 1, "specific_ports": 1, "command": 1, "ports": 1, "ephemeral_ports": 1, "tmpfs": 1, "network": 1, "entrypoint": 1, "max_memory_mb": 1, "max_cpus": 1, "log_driver": 1, "secrets": 1, "healthcheck": 1, "user": 1, "read_only": 1, "cap_drop": 1, "ulimits": 1, "sysctls": 1, "pids_limit": 1, "shm_size_mb": 1, "init": 1, "image_signature": 1}
 
 // CheckDeploymentService verifies it has the required 'image' key, and checks for keys we don't recognize.
 // For now it only prints a warning for unrecognized keys, in case we recently added a key to anax and haven't updated hzn yet.
@@ -192,6 +192,18 @@ func CheckDeploymentService(svcName string, depSvc map[string]interface{}) error
 			cliutils.Warning(msgPrinter.Sprintf("service '%s' defined under 'deployment.services' has unrecognized field '%s'. See https://github.com/open-horizon/anax/blob/master/doc/deployment_string.md", svcName, k))
 		}
 
+		// Check that the health check is well formed, the agent will refuse to start a service with an invalid health check.
+		if k == "healthcheck" {
+			var hc containermessage.HealthCheck
+			if bytes, err := json.Marshal(depSvc[k]); err != nil {
+				return errors.New(msgPrinter.Sprintf("service '%s' defined under 'deployment.services' has a malformed healthcheck value %v, error %v", svcName, depSvc[k], err))
+			} else if err := json.Unmarshal(bytes, &hc); err != nil {
+				return errors.New(msgPrinter.Sprintf("service '%s' defined under 'deployment.services' has a malformed healthcheck value %v, error %v", svcName, string(bytes), err))
+			} else if err := hc.Validate(); err != nil {
+				return errors.New(msgPrinter.Sprintf("service '%s' defined under 'deployment.services' has an invalid healthcheck, error %v", svcName, err))
+			}
+		}
+
 		// Check for the use of the default agent API port, which will cause a port conflict at runtime.
 		if k == "ports" {
 			// Marshal and unmarshal the ports deployment config so that we can reuse typed APIs for parsing the host port
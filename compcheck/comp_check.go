@@ -15,6 +15,7 @@ import (
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/semanticversion"
 	"golang.org/x/text/message"
+	"sort"
 	"strings"
 )
 
@@ -616,6 +617,7 @@ func EvaluatePatternPrivilegeCompatability(getServiceResolvedDef exchange.Servic
 	if nodePriv {
 		return NewCompCheckOutput(true, nil, nil), nil
 	}
+	nodeOpts := NodeAllowedContainerOptions(nodePol.Properties)
 
 	patternDef, err := processPattern(getPatterns, cc.PatternId, cc.Pattern, msgPrinter)
 	if err != nil {
@@ -641,6 +643,18 @@ func EvaluatePatternPrivilegeCompatability(getServiceResolvedDef exchange.Servic
 				return nil, err
 			}
 
+			// the workloads that use restricted container options that the node does not allow also need a privileged node
+			svcOpts, err := ServicesRequireContainerOptions(topSvc, topId, depSvcs, msgPrinter)
+			if err != nil {
+				return nil, err
+			}
+			for sId, opts := range svcOpts {
+				if len(DisallowedContainerOptions(opts, nodeOpts)) != 0 && !cutil.SliceContains(privWorkloads, sId) {
+					workLoadPriv = true
+					privWorkloads = append(privWorkloads, sId)
+				}
+			}
+
 			// save the incompatibles
 			for _, sId := range privWorkloads {
 				if topId == sId {
@@ -746,6 +760,136 @@ func DeploymentRequiresPrivilege(deploymentString string, msgPrinter *message.Pr
 	return false, nil
 }
 
+// Returns the restricted container options, e.g. sysctls, used by the deployment string. The services can only use these
+// options on nodes that allow them in the openhorizon.allowedContainerOptions property, or that allow privileged services.
+func DeploymentContainerOptions(deploymentString string, msgPrinter *message.Printer) ([]string, error) {
+	opts := []string{}
+	if deploymentString == "" {
+		return opts, nil
+	}
+	if msgPrinter == nil {
+		msgPrinter = i18n.GetMessagePrinter()
+	}
+	deploymentStruct := &containermessage.DeploymentDescription{}
+	err := json.Unmarshal([]byte(deploymentString), deploymentStruct)
+	if err != nil {
+		return nil, NewCompCheckError(fmt.Errorf(msgPrinter.Sprintf("Error unmarshaling deployment string to internal deployment structure: %v", err)), COMPCHECK_CONVERSION_ERROR)
+	}
+	for _, topSvc := range deploymentStruct.Services {
+		if topSvc != nil {
+			for _, opt := range topSvc.RestrictedContainerOptions() {
+				if !cutil.SliceContains(opts, opt) {
+					opts = append(opts, opt)
+				}
+			}
+		}
+	}
+	sort.Strings(opts)
+	return opts, nil
+}
+
+// Returns a map of the ids of the top level service and its dependent services to the restricted container options
+// they use. The services that do not use any restricted container options are not in the map.
+func ServicesRequireContainerOptions(topSvc common.AbstractServiceFile, topSvcId string, depServiceDefs map[string]exchange.ServiceDefinition, msgPrinter *message.Printer) (map[string][]string, error) {
+	svcOpts := map[string][]string{}
+
+	if msgPrinter == nil {
+		msgPrinter = i18n.GetMessagePrinter()
+	}
+
+	// handle top level service
+	if topSvc != nil {
+		depstring := ""
+		if _, ok := topSvc.GetDeployment().(string); ok {
+			depstring = topSvc.GetDeployment().(string)
+		} else {
+			depByte, err := json.Marshal(topSvc.GetDeployment())
+			if err != nil {
+				return nil, err
+			}
+			depstring = string(depByte)
+		}
+
+		if opts, err := DeploymentContainerOptions(depstring, msgPrinter); err != nil {
+			return nil, err
+		} else if len(opts) != 0 {
+			svcOpts[topSvcId] = opts
+		}
+	}
+
+	// handle dependent services
+	for sId, sDef := range depServiceDefs {
+		if opts, err := DeploymentContainerOptions(sDef.GetDeploymentString(), msgPrinter); err != nil {
+			return nil, err
+		} else if len(opts) != 0 {
+			svcOpts[sId] = opts
+		}
+	}
+	return svcOpts, nil
+}
+
+// Returns the restricted container options that the node properties allow.
+func NodeAllowedContainerOptions(nodeProps externalpolicy.PropertyList) []string {
+	allowed := []string{}
+	if !nodeProps.HasProperty(externalpolicy.PROP_NODE_CONTAINER_OPTIONS) {
+		return allowed
+	}
+	if prop, err := nodeProps.GetProperty(externalpolicy.PROP_NODE_CONTAINER_OPTIONS); err == nil {
+		if optsStr, ok := prop.Value.(string); ok {
+			for _, opt := range strings.Split(optsStr, ",") {
+				if opt = strings.TrimSpace(opt); opt != "" {
+					allowed = append(allowed, opt)
+				}
+			}
+		}
+	}
+	return allowed
+}
+
+// Returns the container options that are not in the allowed list.
+func DisallowedContainerOptions(opts []string, allowed []string) []string {
+	disallowed := []string{}
+	for _, opt := range opts {
+		if !cutil.SliceContains(allowed, opt) {
+			disallowed = append(disallowed, opt)
+		}
+	}
+	return disallowed
+}
+
+// Returns the constraint that a service using the given restricted container option adds to its service policy. The node
+// must either allow privileged services or allow the container option.
+func ContainerOptionConstraint(opt string) string {
+	return fmt.Sprintf("%s = true || %s in \"%s\"", externalpolicy.PROP_NODE_PRIVILEGED, externalpolicy.PROP_NODE_CONTAINER_OPTIONS, opt)
+}
+
+// Add a constraint to the service policy for each restricted container option used by the services.
+func AddContainerOptionConstraints(svcPolicy *externalpolicy.ExternalPolicy, topSvc common.AbstractServiceFile, topSvcId string,
+	depServiceDefs map[string]exchange.ServiceDefinition, msgPrinter *message.Printer) error {
+
+	svcOpts, err := ServicesRequireContainerOptions(topSvc, topSvcId, depServiceDefs, msgPrinter)
+	if err != nil {
+		return err
+	}
+
+	allOpts := []string{}
+	for _, opts := range svcOpts {
+		for _, opt := range opts {
+			if !cutil.SliceContains(allOpts, opt) {
+				allOpts = append(allOpts, opt)
+			}
+		}
+	}
+	sort.Strings(allOpts)
+
+	newConstraints := externalpolicy.ConstraintExpression{}
+	for _, opt := range allOpts {
+		newConstraints.Add_Constraint(ContainerOptionConstraint(opt))
+	}
+	svcPolicy.Constraints.MergeWith(&newConstraints)
+	return nil
+}
+
 // verifies the input node type has valid value and it matches the exchange node type.
 func VerifyNodeType(nodeType string, exchNodeType string, nodeId string, msgPrinter *message.Printer) (string, error) {
 	if msgPrinter == nil {
@@ -0,0 +1,80 @@
+//go:build unit
+// +build unit
+
+package compcheck
+
+import (
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"reflect"
+	"testing"
+)
+
+func Test_DeploymentContainerOptions(t *testing.T) {
+
+	// the options that only reduce the privileges are not restricted
+	deployment := `{"services":{"s1":{"image":"i1","user":"1000","read_only":true,"cap_drop":["ALL"],"pids_limit":100,"init":true}}}`
+	if opts, err := DeploymentContainerOptions(deployment, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if len(opts) != 0 {
+		t.Errorf("there should not be any restricted options, but got %v", opts)
+	}
+
+	deployment = `{"services":{"s1":{"image":"i1","sysctls":{"net.core.somaxconn":"1024"},"shm_size_mb":64},"s2":{"image":"i2","ulimits":[{"name":"nofile","soft":1024,"hard":2048}],"sysctls":{"kernel.msgmax":"65536"}}}}`
+	if opts, err := DeploymentContainerOptions(deployment, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if !reflect.DeepEqual(opts, []string{"shm_size_mb", "sysctls", "ulimits"}) {
+		t.Errorf("wrong restricted options: %v", opts)
+	}
+
+	if _, err := DeploymentContainerOptions(`{"services":`, nil); err == nil {
+		t.Errorf("an invalid deployment string should be an error")
+	}
+
+	depSvcs := map[string]exchange.ServiceDefinition{
+		"org1/s1_1.0.0_amd64": exchange.ServiceDefinition{Deployment: `{"services":{"s1":{"image":"i1","ulimits":[{"name":"nofile","soft":1024,"hard":2048}]}}}`},
+		"org1/s2_1.0.0_amd64": exchange.ServiceDefinition{Deployment: `{"services":{"s2":{"image":"i2"}}}`},
+	}
+	if svcOpts, err := ServicesRequireContainerOptions(nil, "", depSvcs, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if len(svcOpts) != 1 || !reflect.DeepEqual(svcOpts["org1/s1_1.0.0_amd64"], []string{"ulimits"}) {
+		t.Errorf("wrong restricted options for the services: %v", svcOpts)
+	}
+}
+
+func Test_ContainerOptionConstraint(t *testing.T) {
+
+	nodePol := externalpolicy.ExternalPolicy{
+		Properties: externalpolicy.PropertyList{
+			*externalpolicy.Property_Factory(externalpolicy.PROP_NODE_PRIVILEGED, false),
+			*externalpolicy.Property_Factory(externalpolicy.PROP_NODE_CONTAINER_OPTIONS, "sysctls, ulimits"),
+		},
+	}
+	if err := nodePol.ValidateAndNormalize(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	allowed := NodeAllowedContainerOptions(nodePol.Properties)
+	if !reflect.DeepEqual(allowed, []string{"sysctls", "ulimits"}) {
+		t.Errorf("wrong allowed options: %v", allowed)
+	} else if disallowed := DisallowedContainerOptions([]string{"shm_size_mb", "sysctls"}, allowed); !reflect.DeepEqual(disallowed, []string{"shm_size_mb"}) {
+		t.Errorf("wrong disallowed options: %v", disallowed)
+	}
+
+	ce := externalpolicy.ConstraintExpression{ContainerOptionConstraint("sysctls")}
+	if err := ce.IsSatisfiedBy(nodePol.Properties); err != nil {
+		t.Errorf("the node should allow sysctls: %v", err)
+	}
+
+	ce = externalpolicy.ConstraintExpression{ContainerOptionConstraint("shm_size_mb")}
+	if err := ce.IsSatisfiedBy(nodePol.Properties); err == nil {
+		t.Errorf("the node should not allow shm_size_mb")
+	}
+
+	// a privileged node allows all the options
+	privProps := externalpolicy.PropertyList{*externalpolicy.Property_Factory(externalpolicy.PROP_NODE_PRIVILEGED, true)}
+	if err := ce.IsSatisfiedBy(privProps); err != nil {
+		t.Errorf("a privileged node should allow shm_size_mb: %v", err)
+	}
+}
@@ -0,0 +1,287 @@
+package compcheck
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/policy"
+	"golang.org/x/text/message"
+	"sort"
+)
+
+// What the agbot would do with a node if the simulated deployment policy was published.
+const (
+	SIMULATION_ACTION_PROPOSE   = "propose"   // compatible, an agreement would be proposed to the node
+	SIMULATION_ACTION_KEEP      = "keep"      // compatible, the existing agreement would continue
+	SIMULATION_ACTION_CANCEL    = "cancel"    // not compatible, the existing agreement would be cancelled
+	SIMULATION_ACTION_NONE      = "none"      // not compatible, nothing would happen
+	SIMULATION_ACTION_NOT_READY = "not_ready" // compatible, but the node is not ready to receive proposals yet
+)
+
+// The input for simulating a deployment policy against all the nodes in scope. The deployment policy is given by
+// its exchange id or in full. When both are given, the full policy is simulated as a replacement for the published
+// one, so that the output shows the existing agreements that the change would cancel.
+type DeploySimulationInput struct {
+	BusinessPolId  string                         `json:"business_policy_id,omitempty"`
+	BusinessPolicy *businesspolicy.BusinessPolicy `json:"business_policy,omitempty"`
+	NodeOrgs       []string                       `json:"node_orgs,omitempty"` // defaults to the node orgs the agbot serves for the policy
+	ServicePolicy  *externalpolicy.ExternalPolicy `json:"service_policy,omitempty"`
+}
+
+func (p DeploySimulationInput) String() string {
+	return fmt.Sprintf("BusinessPolId: %v, BusinessPolicy: %v, NodeOrgs: %v, ServicePolicy: %v",
+		p.BusinessPolId, p.BusinessPolicy, p.NodeOrgs, p.ServicePolicy)
+}
+
+// The simulation result for one node.
+type NodeSimulationResult struct {
+	NodeId      string            `json:"node_id"`
+	Compatible  bool              `json:"compatible"`
+	Action      string            `json:"action"`
+	Reason      map[string]string `json:"reason,omitempty"`       // set when not compatible
+	AgreementId string            `json:"agreement_id,omitempty"` // the existing agreement for the policy with the node
+}
+
+type DeploySimulationOutput struct {
+	BusinessPolId string                 `json:"business_policy_id,omitempty"`
+	NodeOrgs      []string               `json:"node_orgs"`
+	Summary       map[string]int         `json:"summary"` // the number of nodes for each action
+	Nodes         []NodeSimulationResult `json:"nodes"`
+}
+
+func (p *DeploySimulationOutput) String() string {
+	return fmt.Sprintf("BusinessPolId: %v, NodeOrgs: %v, Summary: %v, Nodes: %v",
+		p.BusinessPolId, p.NodeOrgs, p.Summary, p.Nodes)
+}
+
+// A function that is called for each node that is compatible with the deployment policy, so that the caller can
+// do extra checks that need resources the compcheck component does not have. It can set the output to not compatible.
+type SimulationNodeCheck func(nodeId string, output *CompCheckOutput) error
+
+// Simulate the deployment policy against all the nodes in the given node orgs, without making any agreements. The
+// agreements map contains the existing agreements for the policy, keyed by node id.
+func SimulateDeployment(ec exchange.ExchangeContext, input *DeploySimulationInput, nodeOrgs []string, agreements map[string]string, nodeCheck SimulationNodeCheck, msgPrinter *message.Printer) (*DeploySimulationOutput, error) {
+
+	getOrgDevices := exchange.GetHTTPOrgDevicesHandler(ec)
+	getBusinessPolicies := exchange.GetHTTPBusinessPoliciesHandler(ec)
+	getDeviceHandler := exchange.GetHTTPDeviceHandler(ec)
+	nodePolicyHandler := exchange.GetHTTPNodePolicyHandler(ec)
+	getPatterns := exchange.GetHTTPExchangePatternHandler(ec)
+	vaultSecretExists := exchange.GetHTTPVaultSecretExistsHandler(ec)
+
+	// The services of the deployment policy and their policies are the same for all the nodes, so they are looked up
+	// once for the whole simulation instead of once for every node.
+	servicePolicyHandler := cachedServicePolicyHandler(exchange.GetHTTPServicePolicyHandler(ec))
+	getServiceHandler := cachedServiceHandler(exchange.GetHTTPServiceHandler(ec))
+	serviceDefResolverHandler := cachedServiceDefResolverHandler(exchange.GetHTTPServiceDefResolverHandler(ec))
+	getSelectedServices := cachedSelectedServicesHandler(exchange.GetHTTPSelectedServicesHandler(ec))
+
+	deployCheck := func(ccInput *CompCheck, dev *exchange.Device) (*CompCheckOutput, error) {
+		// The node was returned by the node list, there is no need to get it again.
+		getDevice := func(id string, token string) (*exchange.Device, error) {
+			if id == ccInput.NodeId {
+				return dev, nil
+			}
+			return getDeviceHandler(id, token)
+		}
+		return deployCompatible(getDevice, nodePolicyHandler, getBusinessPolicies, getPatterns, servicePolicyHandler, getServiceHandler, serviceDefResolverHandler, getSelectedServices, vaultSecretExists, "", ccInput, false, msgPrinter)
+	}
+
+	return simulateDeployment(getOrgDevices, getBusinessPolicies, deployCheck, input, nodeOrgs, agreements, nodeCheck, msgPrinter)
+}
+
+// Internal function for SimulateDeployment
+func simulateDeployment(getOrgDevices exchange.OrgDevicesHandler,
+	getBusinessPolicies exchange.BusinessPoliciesHandler,
+	deployCheck func(ccInput *CompCheck, dev *exchange.Device) (*CompCheckOutput, error),
+	input *DeploySimulationInput, nodeOrgs []string, agreements map[string]string,
+	nodeCheck SimulationNodeCheck, msgPrinter *message.Printer) (*DeploySimulationOutput, error) {
+
+	// get default message printer if nil
+	if msgPrinter == nil {
+		msgPrinter = i18n.GetMessagePrinter()
+	}
+
+	if input == nil || (input.BusinessPolId == "" && input.BusinessPolicy == nil) {
+		return nil, NewCompCheckError(fmt.Errorf(msgPrinter.Sprintf("Neither deployment policy nor deployment policy id is specified.")), COMPCHECK_INPUT_ERROR)
+	} else if len(nodeOrgs) == 0 {
+		return nil, NewCompCheckError(fmt.Errorf(msgPrinter.Sprintf("No node organizations are specified.")), COMPCHECK_INPUT_ERROR)
+	}
+
+	// Get the deployment policy once, instead of once for every node.
+	bPolicy := input.BusinessPolicy
+	if bPolicy == nil {
+		var err error
+		if bPolicy, _, err = GetBusinessPolicy(getBusinessPolicies, input.BusinessPolId, false, msgPrinter); err != nil {
+			return nil, err
+		}
+	} else if err := bPolicy.Validate(); err != nil {
+		return nil, NewCompCheckError(fmt.Errorf(msgPrinter.Sprintf("Validation failure for deployment policy %v. %v", input.BusinessPolId, err)), COMPCHECK_VALIDATION_ERROR)
+	}
+
+	output := &DeploySimulationOutput{
+		BusinessPolId: input.BusinessPolId,
+		NodeOrgs:      nodeOrgs,
+		Summary:       map[string]int{},
+		Nodes:         []NodeSimulationResult{},
+	}
+
+	for _, nodeOrg := range nodeOrgs {
+		devices, err := getOrgDevices(nodeOrg)
+		if err != nil {
+			return nil, NewCompCheckError(fmt.Errorf(msgPrinter.Sprintf("Unable to get the nodes in organization %v, %v", nodeOrg, err)), COMPCHECK_EXCHANGE_ERROR)
+		}
+
+		// Report the nodes in a predictable order.
+		nodeIds := make([]string, 0, len(devices))
+		for id := range devices {
+			nodeIds = append(nodeIds, id)
+		}
+		sort.Strings(nodeIds)
+
+		for _, nodeId := range nodeIds {
+			dev := devices[nodeId]
+			result := simulateNode(deployCheck, nodeId, &dev, bPolicy, input, nodeCheck, msgPrinter)
+			result.AgreementId = agreements[nodeId]
+			result.Action = simulationAction(result.Compatible, result.AgreementId != "", dev.PublicKey != "")
+
+			output.Summary[result.Action] += 1
+			output.Nodes = append(output.Nodes, *result)
+		}
+	}
+
+	return output, nil
+}
+
+// Check the deployment policy against one node. Errors are specific to the node, so they are reported
+// as the reason the node is not compatible rather than failing the whole simulation.
+func simulateNode(deployCheck func(ccInput *CompCheck, dev *exchange.Device) (*CompCheckOutput, error),
+	nodeId string, dev *exchange.Device, bPolicy *businesspolicy.BusinessPolicy,
+	input *DeploySimulationInput, nodeCheck SimulationNodeCheck, msgPrinter *message.Printer) *NodeSimulationResult {
+
+	result := &NodeSimulationResult{NodeId: nodeId}
+
+	// The agbot only searches for nodes without a pattern when it makes agreements for a deployment policy.
+	if dev.Pattern != "" {
+		result.Reason = map[string]string{"general": msgPrinter.Sprintf("The node is registered with pattern %v, deployment policies do not apply to it.", dev.Pattern)}
+		return result
+	}
+
+	ccInput := CompCheck{
+		NodeId:         nodeId,
+		BusinessPolId:  input.BusinessPolId,
+		BusinessPolicy: bPolicy,
+		ServicePolicy:  input.ServicePolicy,
+	}
+
+	ccOutput, err := deployCheck(&ccInput, dev)
+	if err == nil && ccOutput.Compatible && nodeCheck != nil {
+		err = nodeCheck(nodeId, ccOutput)
+	}
+
+	if err != nil {
+		result.Reason = map[string]string{"general": err.Error()}
+	} else {
+		result.Compatible = ccOutput.Compatible
+		if !ccOutput.Compatible {
+			result.Reason = ccOutput.Reason
+		}
+	}
+	return result
+}
+
+// Return what the agbot would do with a node, given its compatibility and whether it already has an agreement for the policy.
+func simulationAction(compatible bool, hasAgreement bool, ready bool) string {
+	if compatible {
+		if hasAgreement {
+			return SIMULATION_ACTION_KEEP
+		} else if !ready {
+			return SIMULATION_ACTION_NOT_READY
+		}
+		return SIMULATION_ACTION_PROPOSE
+	} else if hasAgreement {
+		return SIMULATION_ACTION_CANCEL
+	}
+	return SIMULATION_ACTION_NONE
+}
+
+// The exchange lookup handlers below remember the results of the lookups, including the errors, so that a simulation
+// does not query the exchange for the same service once for every node.
+func cachedServicePolicyHandler(h exchange.ServicePolicyHandler) exchange.ServicePolicyHandler {
+	type result struct {
+		pol *exchange.ExchangeServicePolicy
+		id  string
+		err error
+	}
+	cache := make(map[string]result)
+	return func(sUrl string, sOrg string, sVersion string, sArch string) (*exchange.ExchangeServicePolicy, string, error) {
+		key := simulationCacheKey(sUrl, sOrg, sVersion, sArch)
+		r, ok := cache[key]
+		if !ok {
+			r.pol, r.id, r.err = h(sUrl, sOrg, sVersion, sArch)
+			cache[key] = r
+		}
+		return r.pol, r.id, r.err
+	}
+}
+
+func cachedServiceHandler(h exchange.ServiceHandler) exchange.ServiceHandler {
+	type result struct {
+		def *exchange.ServiceDefinition
+		id  string
+		err error
+	}
+	cache := make(map[string]result)
+	return func(wUrl string, wOrg string, wVersion string, wArch string) (*exchange.ServiceDefinition, string, error) {
+		key := simulationCacheKey(wUrl, wOrg, wVersion, wArch)
+		r, ok := cache[key]
+		if !ok {
+			r.def, r.id, r.err = h(wUrl, wOrg, wVersion, wArch)
+			cache[key] = r
+		}
+		return r.def, r.id, r.err
+	}
+}
+
+func cachedServiceDefResolverHandler(h exchange.ServiceDefResolverHandler) exchange.ServiceDefResolverHandler {
+	type result struct {
+		apiSpecs *policy.APISpecList
+		deps     map[string]exchange.ServiceDefinition
+		def      *exchange.ServiceDefinition
+		id       string
+		err      error
+	}
+	cache := make(map[string]result)
+	return func(wUrl string, wOrg string, wVersion string, wArch string) (*policy.APISpecList, map[string]exchange.ServiceDefinition, *exchange.ServiceDefinition, string, error) {
+		key := simulationCacheKey(wUrl, wOrg, wVersion, wArch)
+		r, ok := cache[key]
+		if !ok {
+			r.apiSpecs, r.deps, r.def, r.id, r.err = h(wUrl, wOrg, wVersion, wArch)
+			cache[key] = r
+		}
+		return r.apiSpecs, r.deps, r.def, r.id, r.err
+	}
+}
+
+func cachedSelectedServicesHandler(h exchange.SelectedServicesHandler) exchange.SelectedServicesHandler {
+	type result struct {
+		defs map[string]exchange.ServiceDefinition
+		err  error
+	}
+	cache := make(map[string]result)
+	return func(wUrl string, wOrg string, wVersion string, wArch string) (map[string]exchange.ServiceDefinition, error) {
+		key := simulationCacheKey(wUrl, wOrg, wVersion, wArch)
+		r, ok := cache[key]
+		if !ok {
+			r.defs, r.err = h(wUrl, wOrg, wVersion, wArch)
+			cache[key] = r
+		}
+		return r.defs, r.err
+	}
+}
+
+func simulationCacheKey(url string, org string, version string, arch string) string {
+	return fmt.Sprintf("%v/%v/%v/%v", org, url, version, arch)
+}
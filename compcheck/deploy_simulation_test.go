@@ -0,0 +1,191 @@
+//go:build unit
+// +build unit
+
+package compcheck
+
+import (
+	"errors"
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/i18n"
+	"testing"
+)
+
+func getOrgDevicesHandler(devices map[string]map[string]exchange.Device) exchange.OrgDevicesHandler {
+	return func(org string) (map[string]exchange.Device, error) {
+		if devs, ok := devices[org]; ok {
+			return devs, nil
+		}
+		return nil, errors.New("org not found")
+	}
+}
+
+// A deploy check that finds the nodes in the compatible map compatible, and fails for the nodes in the errors map.
+func getDeployCheck(compatible map[string]bool, errs map[string]error, checked *[]string) func(ccInput *CompCheck, dev *exchange.Device) (*CompCheckOutput, error) {
+	return func(ccInput *CompCheck, dev *exchange.Device) (*CompCheckOutput, error) {
+		*checked = append(*checked, ccInput.NodeId)
+		if ccInput.BusinessPolicy == nil {
+			return nil, errors.New("the deployment policy should be resolved before the nodes are checked")
+		} else if dev == nil {
+			return nil, errors.New("the node should be passed to the check")
+		} else if err, ok := errs[ccInput.NodeId]; ok {
+			return nil, err
+		} else if compatible[ccInput.NodeId] {
+			return NewCompCheckOutput(true, map[string]string{}, nil), nil
+		}
+		return NewCompCheckOutput(false, map[string]string{"myorg/svc_1.0.0_amd64": "Policy Incompatible"}, nil), nil
+	}
+}
+
+func Test_simulateDeployment(t *testing.T) {
+
+	msgPrinter := i18n.GetMessagePrinter()
+
+	service := businesspolicy.ServiceRef{
+		Name:            "svc",
+		Org:             "myorg",
+		Arch:            "amd64",
+		ServiceVersions: []businesspolicy.WorkloadChoice{businesspolicy.WorkloadChoice{Version: "1.0.0"}},
+	}
+
+	devices := map[string]map[string]exchange.Device{
+		"myorg": {
+			"myorg/propose":   {PublicKey: "key"},
+			"myorg/keep":      {PublicKey: "key"},
+			"myorg/cancel":    {PublicKey: "key"},
+			"myorg/none":      {PublicKey: "key"},
+			"myorg/notready":  {},
+			"myorg/pattern":   {PublicKey: "key", Pattern: "myorg/pat"},
+			"myorg/nodeerror": {PublicKey: "key"},
+		},
+	}
+	compatible := map[string]bool{"myorg/propose": true, "myorg/keep": true, "myorg/notready": true}
+	errs := map[string]error{"myorg/nodeerror": errors.New("no node policy")}
+	agreements := map[string]string{"myorg/keep": "ag1", "myorg/cancel": "ag2"}
+
+	checked := []string{}
+	input := &DeploySimulationInput{BusinessPolId: "myorg/bp1"}
+	output, err := simulateDeployment(getOrgDevicesHandler(devices), getBusinessPolicyHandler(service, nil, nil), getDeployCheck(compatible, errs, &checked), input, []string{"myorg"}, agreements, nil, msgPrinter)
+	if err != nil {
+		t.Fatalf("simulateDeployment should not have returned error but got: %v", err)
+	}
+
+	expected := map[string]string{
+		"myorg/propose":   SIMULATION_ACTION_PROPOSE,
+		"myorg/keep":      SIMULATION_ACTION_KEEP,
+		"myorg/cancel":    SIMULATION_ACTION_CANCEL,
+		"myorg/none":      SIMULATION_ACTION_NONE,
+		"myorg/notready":  SIMULATION_ACTION_NOT_READY,
+		"myorg/pattern":   SIMULATION_ACTION_NONE,
+		"myorg/nodeerror": SIMULATION_ACTION_NONE,
+	}
+	if len(output.Nodes) != len(expected) {
+		t.Fatalf("expected %v nodes but got: %v", len(expected), output)
+	}
+	for i, node := range output.Nodes {
+		if i > 0 && output.Nodes[i-1].NodeId > node.NodeId {
+			t.Errorf("nodes should be sorted by id: %v", output.Nodes)
+		}
+		if node.Action != expected[node.NodeId] {
+			t.Errorf("node %v should have action %v but got: %v", node.NodeId, expected[node.NodeId], node)
+		} else if node.Compatible == (node.Reason != nil) {
+			t.Errorf("node %v should only have a reason when it is not compatible: %v", node.NodeId, node)
+		} else if node.AgreementId != agreements[node.NodeId] {
+			t.Errorf("node %v should have agreement %v but got: %v", node.NodeId, agreements[node.NodeId], node)
+		}
+	}
+	if output.Summary[SIMULATION_ACTION_NONE] != 3 || output.Summary[SIMULATION_ACTION_PROPOSE] != 1 {
+		t.Errorf("wrong summary: %v", output.Summary)
+	}
+
+	// nodes with a pattern are not checked, because the agbot does not search for them
+	for _, id := range checked {
+		if id == "myorg/pattern" {
+			t.Errorf("node with a pattern should not be checked")
+		}
+	}
+
+	// the extra node check can make a compatible node incompatible
+	nodeCheck := func(nodeId string, output *CompCheckOutput) error {
+		output.Compatible = false
+		output.Reason["general"] = "Secret does not exist."
+		return nil
+	}
+	devices = map[string]map[string]exchange.Device{"myorg": {"myorg/keep": {PublicKey: "key"}}}
+	output, err = simulateDeployment(getOrgDevicesHandler(devices), getBusinessPolicyHandler(service, nil, nil), getDeployCheck(compatible, errs, &checked), input, []string{"myorg"}, agreements, nodeCheck, msgPrinter)
+	if err != nil {
+		t.Errorf("simulateDeployment should not have returned error but got: %v", err)
+	} else if len(output.Nodes) != 1 || output.Nodes[0].Action != SIMULATION_ACTION_CANCEL || output.Nodes[0].Reason["general"] == "" {
+		t.Errorf("node should be cancelled by the node check: %v", output)
+	}
+}
+
+func Test_simulateDeployment_errors(t *testing.T) {
+
+	msgPrinter := i18n.GetMessagePrinter()
+	checked := []string{}
+	deployCheck := getDeployCheck(nil, nil, &checked)
+	devicesHandler := getOrgDevicesHandler(map[string]map[string]exchange.Device{"myorg": {}})
+
+	// no deployment policy
+	if _, err := simulateDeployment(devicesHandler, getBusinessPolicyHandler_Error(), deployCheck, &DeploySimulationInput{}, []string{"myorg"}, nil, nil, msgPrinter); err == nil {
+		t.Errorf("simulateDeployment should have returned error for missing deployment policy")
+	} else if err.(*CompCheckError).ErrCode != COMPCHECK_INPUT_ERROR {
+		t.Errorf("wrong error code: %v", err)
+	}
+
+	// the deployment policy cannot be found
+	input := &DeploySimulationInput{BusinessPolId: "myorg/bp1"}
+	if _, err := simulateDeployment(devicesHandler, getBusinessPolicyHandler_Error(), deployCheck, input, []string{"myorg"}, nil, nil, msgPrinter); err == nil {
+		t.Errorf("simulateDeployment should have returned error for a deployment policy that cannot be retrieved")
+	}
+
+	// an invalid deployment policy
+	input = &DeploySimulationInput{BusinessPolicy: &businesspolicy.BusinessPolicy{}}
+	if _, err := simulateDeployment(devicesHandler, getBusinessPolicyHandler_Error(), deployCheck, input, []string{"myorg"}, nil, nil, msgPrinter); err == nil {
+		t.Errorf("simulateDeployment should have returned error for an invalid deployment policy")
+	} else if err.(*CompCheckError).ErrCode != COMPCHECK_VALIDATION_ERROR {
+		t.Errorf("wrong error code: %v", err)
+	}
+
+	// the nodes cannot be retrieved
+	input = &DeploySimulationInput{BusinessPolId: "myorg/bp1"}
+	service := businesspolicy.ServiceRef{Name: "svc", Org: "myorg", ServiceVersions: []businesspolicy.WorkloadChoice{businesspolicy.WorkloadChoice{Version: "1.0.0"}}}
+	if _, err := simulateDeployment(devicesHandler, getBusinessPolicyHandler(service, nil, nil), deployCheck, input, []string{"otherorg"}, nil, nil, msgPrinter); err == nil {
+		t.Errorf("simulateDeployment should have returned error when the nodes cannot be retrieved")
+	} else if err.(*CompCheckError).ErrCode != COMPCHECK_EXCHANGE_ERROR {
+		t.Errorf("wrong error code: %v", err)
+	}
+}
+
+func Test_cachedServicePolicyHandler(t *testing.T) {
+
+	calls := 0
+	h := cachedServicePolicyHandler(func(sUrl string, sOrg string, sVersion string, sArch string) (*exchange.ExchangeServicePolicy, string, error) {
+		calls += 1
+		if sArch == "arm64" {
+			return nil, "", errors.New("not found")
+		}
+		return &exchange.ExchangeServicePolicy{}, sOrg + "/" + sUrl + "_" + sVersion + "_" + sArch, nil
+	})
+
+	// the same service is looked up once for all the nodes
+	for i := 0; i < 3; i++ {
+		if pol, id, err := h("svc", "myorg", "1.0.0", "amd64"); err != nil || pol == nil || id != "myorg/svc_1.0.0_amd64" {
+			t.Errorf("wrong service policy %v %v %v", pol, id, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("the service policy should have been looked up once, looked up %v times", calls)
+	}
+
+	// errors are remembered too, and another arch is another lookup
+	for i := 0; i < 2; i++ {
+		if _, _, err := h("svc", "myorg", "1.0.0", "arm64"); err == nil {
+			t.Errorf("the lookup error should be returned")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("the service policy should have been looked up twice, looked up %v times", calls)
+	}
+}
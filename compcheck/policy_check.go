@@ -756,6 +756,10 @@ func SetServicePolicyPrivilege(getServiceResolvedDef exchange.ServiceDefResolver
 	if runtimePriv {
 		svcPolicy.Constraints.Add_Constraint(fmt.Sprintf("%s = %t", externalpolicy.PROP_SVC_PRIVILEGED, runtimePriv))
 	}
+
+	if err := AddContainerOptionConstraints(svcPolicy, topSvcDef, topSvcId, depSvcList, msgPrinter); err != nil {
+		return nil, nil, "", nil, err
+	}
 	return svcPolicy, topSvcDef, topSvcId, depSvcList, nil
 }
 
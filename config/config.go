@@ -71,8 +71,19 @@ type Config struct {
 	InitialPollingBuffer             int       // the number of seconds to wait before increasing the polling interval while there is no agreement on the node.
 	MaxAgreementPrelaunchTimeM       int64     // The maximum numbers of minutes to wait for workload to start in an agreement
 	K8sCRInstallTimeoutS             int64     // The number of seconds to wait for the custom resouce to install successfully before it is considered a failure
+	HelmClient                       string    // The client used to deploy helm charts, "cli" for the helm CLI or "sdk" for the Helm SDK built into the agent, which does not need the helm binary. The default is "cli".
+	HelmUpgradeWindowS               int64     // The number of seconds a helm release is kept after its agreement ended, for an agreement for another version of the service to upgrade it in place. The default is 300 seconds, a negative value uninstalls it right away.
+	K8sUpgradeWindowS                int64     // The number of seconds a kube operator is kept after its agreement ended, for an agreement for another version of the service to upgrade it in place. The default is 300 seconds, a negative value uninstalls it right away.
 	SecretsManagerFilePath           string    // The filepath for the secrets manager to store secrets in the agent filesystem
 	NodeMgmtWorkDirectory            string    // The filepath for the node management policy updates to use
+	OfflineBundleDirectory           string    // The directory where the offline bundles are stored. The default is /var/horizon/bundles
+	EventLogMaxAgeS                  int64     // Event logs older than this number of seconds are removed from the local database. The default is 0, which keeps them until the node is unregistered.
+	EventLogMaxCount                 int       // The maximum number of event logs kept in the local database, the oldest are removed first. The default is 0, which means no limit.
+	EventLogPruneIntervalS           int       // How often the event log retention limits are enforced. The default is 3600 seconds.
+
+	// The image signature verification mode for the services of each org, enforce or warn. The "*" key applies to the
+	// orgs that are not listed. The default is no verification.
+	ImageVerification map[string]string
 
 	// these Ids could be provided in config or discovered after startup by the system
 	BlockchainAccountId        string
@@ -118,18 +129,21 @@ type AGConfig struct {
 	CheckUpdatedPolicyS           int              // The number of seconds to wait between checks for an updated policy file. Zero means auto checking is turned off.
 	CSSURL                        string           // The URL used to access the CSS.
 	CSSSSLCert                    string           // The path to the client side SSL certificate for the CSS.
-	MMSGarbageCollectionInterval  int64            // The amount of time to wait between MMS object cache garbage collection scans.
+	MMSGarbageCollectionInterval  int64            // The amount of time to wait between MMS object cache garbage collection scans. Only the leader agbot runs them.
 	AgreementBatchSize            uint64           // The number of nodes that the agbot will process in a batch.
 	AgreementQueueSize            uint64           // The agreement bot work queue max size.
 	MessageQueueScale             float64          // Scaling factor applied to the AgreementQueueSize when determining how deep to keep the queues.
 	QueueHistorySize              int              // The number of statistics records to retain in the prioritized queue history.
-	FullRescanS                   uint64           // The number of seconds between policy scans when there have been no changes reported by the exchange.
+	FullRescanS                   uint64           // The number of seconds between policy scans when there have been no changes reported by the exchange. Only the leader agbot runs them.
 	MaxExchangeChanges            int              // The maximum number of exchange changes to request on a given call the exchange /changes API.
 	RetryLookBackWindow           uint64           // The time window (in seconds) used by the agbot to look backward in time for node changes when node agreements are retried.
 	PolicySearchOrder             bool             // When true, search policies from most recently changed to least recently changed.
 	Vault                         VaultConfig      // The hashicorp vault config to connect to and fetch secrets from.
+	SecretsProvider               string           // The name of the secrets provider plugin to use (vault or file). If not set, it is inferred from the provider that is configured.
+	FileSecrets                   FileSecretConfig // The file based secrets store config, used when there is no vault.
 	SecretsUpdateCheck            int              // The number of seconds between checks for updated secrets.
 	CSSDestinationBatchSize       int              // The max number of destination updates to send to CSS in a single update.
+	PartitionRebalanceS           int              // The number of seconds between the pulls of agreements out of unowned partitions, and the handoffs of agreements between the running agbots, that even out the active agreements across the running agbots. Zero, the default, turns off rebalancing.
 }
 
 // Contains the hashicorp vault configuration used within AGConfig.
@@ -138,6 +152,16 @@ type VaultConfig struct {
 	SSLCertPath string // The SSL certificate for the vault.
 }
 
+// Contains the file based secrets store configuration used within AGConfig.
+type FileSecretConfig struct {
+	Path              string // The path of the file that holds the secrets.
+	EncryptionKeyPath string // The path of a file containing the key used to encrypt the secrets file. If not set, the file is not encrypted.
+}
+
+// The names of the supported agbot secrets providers.
+const AGBOT_SECRETS_PROVIDER_VAULT = "vault"
+const AGBOT_SECRETS_PROVIDER_FILE = "file"
+
 func (c *HorizonConfig) GetSecretsMount() string {
 	return HZN_SECRETS_MOUNT
 }
@@ -174,6 +198,23 @@ func (c *HorizonConfig) IsVaultConfigured() bool {
 	return c.AgreementBot.Vault != VaultConfig{}
 }
 
+func (c *HorizonConfig) IsFileSecretsConfigured() bool {
+	return c.AgreementBot.FileSecrets.Path != ""
+}
+
+// Returns the name of the secrets provider the agbot should use. An explicitly configured provider always wins,
+// otherwise vault is preferred over the file store. An empty string means that no provider is configured.
+func (c *HorizonConfig) GetAgbotSecretsProvider() string {
+	if c.AgreementBot.SecretsProvider != "" {
+		return c.AgreementBot.SecretsProvider
+	} else if c.IsVaultConfigured() {
+		return AGBOT_SECRETS_PROVIDER_VAULT
+	} else if c.IsFileSecretsConfigured() {
+		return AGBOT_SECRETS_PROVIDER_FILE
+	}
+	return ""
+}
+
 func (c *HorizonConfig) GetSecretsManagerFilePath() string {
 	secPath := c.Edge.SecretsManagerFilePath
 	if secPath == "" {
@@ -238,6 +279,20 @@ func (c *HorizonConfig) GetK8sCRInstallTimeouts() int64 {
 	return K8sCRInstallTimeoutS_DEFAULT
 }
 
+func (c *HorizonConfig) GetHelmUpgradeWindowS() int64 {
+	if c.Edge.HelmUpgradeWindowS != 0 {
+		return c.Edge.HelmUpgradeWindowS
+	}
+	return HelmUpgradeWindowS_DEFAULT
+}
+
+func (c *HorizonConfig) GetK8sUpgradeWindowS() int64 {
+	if c.Edge.K8sUpgradeWindowS != 0 {
+		return c.Edge.K8sUpgradeWindowS
+	}
+	return K8sUpgradeWindowS_DEFAULT
+}
+
 func (a *AGConfig) GetProtocolTimeout(maxHeartbeatInterval int) uint64 {
 	if a.ProtocolTimeoutS != 0 {
 		return a.ProtocolTimeoutS
@@ -298,6 +353,15 @@ func (a *AGConfig) GetExchangeMessageTTL(maxHeartbeatInterval int) int {
 	return int(float64(hbInterval) * scaleFactor)
 }
 
+// Returns the image signature verification mode for the services of the given org, or an empty string if the images
+// of the org are not verified.
+func (c *Config) GetImageVerificationMode(org string) string {
+	if mode, ok := c.ImageVerification[org]; ok {
+		return strings.ToLower(mode)
+	}
+	return strings.ToLower(c.ImageVerification["*"])
+}
+
 func (c *Config) GetNodeMgmtDirectory() string {
 	if c.NodeMgmtWorkDirectory == "" {
 		return fmt.Sprintf("%v/nmp", getDefaultBase())
@@ -305,6 +369,13 @@ func (c *Config) GetNodeMgmtDirectory() string {
 	return c.NodeMgmtWorkDirectory
 }
 
+func (c *Config) GetOfflineBundleDirectory() string {
+	if c.OfflineBundleDirectory == "" {
+		return fmt.Sprintf("%v/bundles", getDefaultBase())
+	}
+	return c.OfflineBundleDirectory
+}
+
 func getDefaultBase() string {
 	basePath := os.Getenv("HZN_VAR_BASE")
 	if basePath == "" {
@@ -430,6 +501,24 @@ func Read(file string) (*HorizonConfig, error) {
 			config.Edge.SurfaceErrorAgreementPersistentS = 90
 		}
 
+		if config.Edge.EventLogPruneIntervalS == 0 {
+			config.Edge.EventLogPruneIntervalS = 3600
+		}
+
+		for org, mode := range config.Edge.ImageVerification {
+			if m := strings.ToLower(mode); m != IMAGE_VERIFICATION_ENFORCE && m != IMAGE_VERIFICATION_WARN {
+				return nil, fmt.Errorf("Unsupported image verification mode %v for org %v, the mode must be %v or %v", mode, org, IMAGE_VERIFICATION_ENFORCE, IMAGE_VERIFICATION_WARN)
+			}
+		}
+
+		if config.Edge.HelmClient == "" {
+			config.Edge.HelmClient = HELM_CLIENT_CLI
+		} else if c := strings.ToLower(config.Edge.HelmClient); c != HELM_CLIENT_CLI && c != HELM_CLIENT_SDK {
+			return nil, fmt.Errorf("Unsupported helm client %v, the client must be %v or %v", config.Edge.HelmClient, HELM_CLIENT_CLI, HELM_CLIENT_SDK)
+		} else {
+			config.Edge.HelmClient = c
+		}
+
 		// set default retry parameters
 		// the default DefaultServiceRetryCount is 2. It means 2 tries including the original one.
 		// so it is actually 1 retry.
@@ -521,6 +610,7 @@ func (con *Config) String() string {
 		", DefaultServiceRetryDuration: %v"+
 		", NodeCheckIntervalS: %v"+
 		", FileSyncService: {%v}"+
+		", ImageVerification: %v"+
 		", InitialPollingBuffer: {%v}"+
 		", BlockchainAccountId: %v"+
 		", BlockchainDirectoryAddress %v",
@@ -530,7 +620,7 @@ func (con *Config) String() string {
 		con.DVPrefix, con.RegistrationDelayS, con.ExchangeMessageTTL, con.ExchangeMessageDynamicPoll, con.ExchangeMessagePollInterval,
 		con.ExchangeMessagePollMaxInterval, con.ExchangeMessagePollIncrement, con.UserPublicKeyPath, con.ReportDeviceStatus,
 		con.TrustCertUpdatesFromOrg, con.TrustDockerAuthFromOrg, con.ServiceUpgradeCheckIntervalS, con.MultipleAnaxInstances,
-		con.DefaultServiceRetryCount, con.DefaultServiceRetryDuration, con.NodeCheckIntervalS, con.FileSyncService.String(),
+		con.DefaultServiceRetryCount, con.DefaultServiceRetryDuration, con.NodeCheckIntervalS, con.FileSyncService.String(), con.ImageVerification,
 		con.InitialPollingBuffer, con.BlockchainAccountId, con.BlockchainDirectoryAddress)
 }
 
@@ -578,7 +668,10 @@ func (agc *AGConfig) String() string {
 		", MaxExchangeChanges: %v"+
 		", RetryLookBackWindow: %v"+
 		", PolicySearchOrder: %v"+
-		", Vault: {%v}",
+		", Vault: {%v}"+
+		", SecretsProvider: %v"+
+		", FileSecrets: {%v}"+
+		", PartitionRebalanceS: %v",
 		agc.TxLostDelayTolerationSeconds, agc.AgreementWorkers, agc.DBPath, agc.Postgresql.String(),
 		agc.PartitionStale, agc.ProtocolTimeoutS, agc.AgreementTimeoutS, agc.NoDataIntervalS, agc.ActiveAgreementsURL,
 		agc.ActiveAgreementsUser, mask, agc.PolicyPath, agc.NewContractIntervalS, agc.ProcessGovernanceIntervalS,
@@ -587,9 +680,13 @@ func (agc *AGConfig) String() string {
 		agc.SecureAPIListenHost, agc.SecureAPIListenPort, agc.SecureAPIServerCert, agc.SecureAPIServerKey,
 		agc.PurgeArchivedAgreementHours, agc.CheckUpdatedPolicyS, agc.CSSURL, agc.CSSSSLCert, agc.CSSDestinationBatchSize, agc.AgreementBatchSize,
 		agc.AgreementQueueSize, agc.MessageQueueScale, agc.QueueHistorySize, agc.FullRescanS, agc.MaxExchangeChanges,
-		agc.RetryLookBackWindow, agc.PolicySearchOrder, agc.Vault)
+		agc.RetryLookBackWindow, agc.PolicySearchOrder, agc.Vault, agc.SecretsProvider, agc.FileSecrets.String(), agc.PartitionRebalanceS)
 }
 
 func (c *VaultConfig) String() string {
 	return fmt.Sprintf("VaultURL: %v,", c.VaultURL)
 }
+
+func (c *FileSecretConfig) String() string {
+	return fmt.Sprintf("Path: %v, EncryptionKeyPath: %v", c.Path, c.EncryptionKeyPath)
+}
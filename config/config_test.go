@@ -4,7 +4,10 @@
 package config
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -72,3 +75,38 @@ func Test_enrichFromEnvvars_success(t *testing.T) {
 	}
 
 }
+
+func Test_Read_HelmClient(t *testing.T) {
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "anax.json")
+
+	tests := []struct {
+		client   string
+		expected string
+		ok       bool
+	}{
+		{"", HELM_CLIENT_CLI, true},
+		{"cli", HELM_CLIENT_CLI, true},
+		{"SDK", HELM_CLIENT_SDK, true},
+		{"unknown", "", false},
+	}
+
+	for _, tc := range tests {
+		content := fmt.Sprintf(`{"Edge": {"HelmClient": "%v"}}`, tc.client)
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatalf("unable to write config file: %v", err)
+		}
+
+		cfg, err := Read(file)
+		if !tc.ok {
+			if err == nil {
+				t.Errorf("expected an error for helm client %v", tc.client)
+			}
+		} else if err != nil {
+			t.Errorf("unexpected error for helm client %v: %v", tc.client, err)
+		} else if cfg.Edge.HelmClient != tc.expected {
+			t.Errorf("helm client %v should be %v, got %v", tc.client, tc.expected, cfg.Edge.HelmClient)
+		}
+	}
+}
@@ -126,8 +126,24 @@ const AgbotProtocolTimeoutScaleFactor_DEFAULT = 2
 // Time to allow a kube agent to attempt to install a custom resource before timing out
 const K8sCRInstallTimeoutS_DEFAULT = 180
 
+// Time to keep a helm release after its agreement ended, so that the next version of the service can upgrade it in place
+const HelmUpgradeWindowS_DEFAULT = 300
+
+// Time to keep a kube operator after its agreement ended, so that the next version of the service can upgrade it in place
+const K8sUpgradeWindowS_DEFAULT = 300
+
 // Time between secret update checks
 const SecretsUpdateCheck_DEFAULT = 60
 
 // Batch destination size to send to CSS
 const AgbotCSSDestinationBatchSize_DEFAULT = 200
+
+// The image signature verification modes. When the mode is enforce, a service whose image signature cannot be verified
+// is not started. When the mode is warn, the verification failure is only logged.
+const IMAGE_VERIFICATION_ENFORCE = "enforce"
+const IMAGE_VERIFICATION_WARN = "warn"
+
+// The clients that the agent can use to deploy helm charts. The cli client runs the helm CLI, the sdk client uses the
+// Helm Go SDK built into the agent.
+const HELM_CLIENT_CLI = "cli"
+const HELM_CLIENT_SDK = "sdk"
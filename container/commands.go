@@ -6,6 +6,7 @@ import (
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/structlog"
 )
 
 // ==============================================================================================================
@@ -26,6 +27,10 @@ func (c WorkloadConfigureCommand) ShortString() string {
 	return fmt.Sprintf("AgreementLaunchContext: %v, DeploymentDescription: %v", lc, c.DeploymentDescription)
 }
 
+func (c WorkloadConfigureCommand) LogFields() structlog.Fields {
+	return structlog.FieldsOf(c.AgreementLaunchContext)
+}
+
 func (b *ContainerWorker) NewWorkloadConfigureCommand(deploymentDescription *containermessage.DeploymentDescription, agreementLaunchContext *events.AgreementLaunchContext) *WorkloadConfigureCommand {
 	return &WorkloadConfigureCommand{
 		DeploymentDescription:  deploymentDescription,
@@ -51,6 +56,10 @@ func (c ContainerConfigureCommand) ShortString() string {
 	return fmt.Sprintf("ContainerLaunchContext: %v, DeploymentDescription: %v", lc, c.DeploymentDescription)
 }
 
+func (c ContainerConfigureCommand) LogFields() structlog.Fields {
+	return structlog.FieldsOf(c.ContainerLaunchContext)
+}
+
 func (b *ContainerWorker) NewContainerConfigureCommand(deploymentDescription *containermessage.DeploymentDescription, containerLaunchContext *events.ContainerLaunchContext) *ContainerConfigureCommand {
 	return &ContainerConfigureCommand{
 		DeploymentDescription:  deploymentDescription,
@@ -77,6 +86,10 @@ func (c ContainerMaintenanceCommand) ShortString() string {
 	return c.String()
 }
 
+func (c ContainerMaintenanceCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: c.AgreementProtocol, structlog.AGREEMENT_ID: c.AgreementId}
+}
+
 func (b *ContainerWorker) NewContainerMaintenanceCommand(protocol string, agreementId string, deployment persistence.DeploymentConfig) *ContainerMaintenanceCommand {
 	return &ContainerMaintenanceCommand{
 		AgreementProtocol: protocol,
@@ -105,6 +118,10 @@ func (c WorkloadShutdownCommand) ShortString() string {
 	return c.String()
 }
 
+func (c WorkloadShutdownCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: c.AgreementProtocol, structlog.AGREEMENT_ID: c.CurrentAgreementId}
+}
+
 func (b *ContainerWorker) NewWorkloadShutdownCommand(protocol string, currentAgreementId string, deployment persistence.DeploymentConfig, agreements []string) *WorkloadShutdownCommand {
 	return &WorkloadShutdownCommand{
 		AgreementProtocol:  protocol,
@@ -142,6 +159,10 @@ func (c MaintainMicroserviceCommand) ShortString() string {
 	return fmt.Sprintf("MaintainServiceCommand: MsInstKey %v", c.MsInstKey)
 }
 
+func (c MaintainMicroserviceCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.SERVICE_INSTANCE: c.MsInstKey}
+}
+
 func (b *ContainerWorker) NewMaintainMicroserviceCommand(key string) *MaintainMicroserviceCommand {
 	return &MaintainMicroserviceCommand{
 		MsInstKey: key,
@@ -157,6 +178,10 @@ func (c ShutdownMicroserviceCommand) ShortString() string {
 	return fmt.Sprintf("ShutdownMicroserviceCommand: MsInstKey %v", c.MsInstKey)
 }
 
+func (c ShutdownMicroserviceCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.SERVICE_INSTANCE: c.MsInstKey}
+}
+
 func (b *ContainerWorker) NewShutdownMicroserviceCommand(key string) *ShutdownMicroserviceCommand {
 	return &ShutdownMicroserviceCommand{
 		MsInstKey: key,
@@ -172,6 +197,10 @@ func (c CancelMicroserviceNetworkCommand) ShortString() string {
 	return fmt.Sprintf("CancelMicroserviceNetworkCommand: MsInstKey %v", c.MsInstKey)
 }
 
+func (c CancelMicroserviceNetworkCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.SERVICE_INSTANCE: c.MsInstKey}
+}
+
 func (b *ContainerWorker) NewCancelMicroserviceNetworkCommand(key string) *CancelMicroserviceNetworkCommand {
 	return &CancelMicroserviceNetworkCommand{
 		MsInstKey: key,
@@ -49,6 +49,7 @@ const (
 	EL_CONT_DEPLOYCONF_UNSUPPORT_CAP_FOR_CONT = "Deployment config %v contains unsupported capability for infrastructure container."
 	EL_CONT_DEPLOYCONF_UNSUPPORT_BIND         = "Deployment config %v contains unsupported bind for a workload, %v"
 	EL_CONT_DEPLOYCONF_UNSUPPORT_BIND_FOR     = "Deployment config %v contains unsupported bind for %v, %v"
+	EL_CONT_DEPLOYCONF_NOT_ADMITTED           = "Deployment config %v is not admitted by the node admission policy, %v"
 	EL_CONT_ERROR_UNMARSHAL_DEPLOY            = "Error Unmarshalling deployment string %v, error: %v"
 	EL_CONT_ERROR_UNMARSHAL_DEPLOY_OVERRIDE   = "Error Unmarshalling deployment override string %v for agreement %v, error: %v"
 	EL_CONT_START_CONTAINER_ERROR             = "Error starting containers: %v"
@@ -74,6 +75,7 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_CONT_DEPLOYCONF_UNSUPPORT_CAP_FOR_CONT)
 	msgPrinter.Sprintf(EL_CONT_DEPLOYCONF_UNSUPPORT_BIND)
 	msgPrinter.Sprintf(EL_CONT_DEPLOYCONF_UNSUPPORT_BIND_FOR)
+	msgPrinter.Sprintf(EL_CONT_DEPLOYCONF_NOT_ADMITTED)
 	msgPrinter.Sprintf(EL_CONT_ERROR_UNMARSHAL_DEPLOY)
 	msgPrinter.Sprintf(EL_CONT_ERROR_UNMARSHAL_DEPLOY_OVERRIDE)
 	msgPrinter.Sprintf(EL_CONT_START_CONTAINER_ERROR)
@@ -380,6 +382,34 @@ func (w *ContainerWorker) finalizeDeployment(agreementId string, deployment *con
 			serviceConfig.HostConfig.NanoCPUs = int64(service.MaxCPUs * 1000000000)
 		}
 
+		// Apply the hardened container options if they are defined in the service config
+		if err := service.ValidateContainerOptions(); err != nil {
+			return nil, fmt.Errorf("Invalid container options for service %v: %v", serviceName, err)
+		}
+		serviceConfig.Config.User = service.User
+		serviceConfig.HostConfig.ReadonlyRootfs = service.ReadOnly
+		serviceConfig.HostConfig.CapDrop = service.CapDrop
+		serviceConfig.HostConfig.Sysctls = service.Sysctls
+		serviceConfig.HostConfig.Init = service.Init
+		for _, u := range service.Ulimits {
+			serviceConfig.HostConfig.Ulimits = append(serviceConfig.HostConfig.Ulimits, docker.ULimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+		}
+		if service.PidsLimit != 0 {
+			pidsLimit := service.PidsLimit
+			serviceConfig.HostConfig.PidsLimit = &pidsLimit
+		}
+		if service.ShmSizeMb != 0 {
+			serviceConfig.HostConfig.ShmSize = service.ShmSizeMb * 1024 * 1024
+		}
+
+		// Set the health check if it is defined in the service config, docker reports the health state of the container
+		if service.HealthCheck != nil {
+			if err := service.HealthCheck.Validate(); err != nil {
+				return nil, fmt.Errorf("Invalid healthcheck for service %v: %v", serviceName, err)
+			}
+			serviceConfig.Config.Healthcheck = service.HealthCheck.DockerHealthConfig()
+		}
+
 		// Mark each container as infrastructure if the deployment description indicates infrastructure
 		if deployment.Infrastructure {
 			serviceConfig.Config.Labels[LABEL_PREFIX+".infrastructure"] = ""
@@ -1207,6 +1237,15 @@ func processPostCreate(ipt *iptables.IPTables, client *docker.Client, agreementI
 	return nil
 }
 
+// Check the deployment against the admission policy of the node, if there is one.
+func (b *ContainerWorker) admitDeployment(deployment *containermessage.DeploymentDescription) error {
+	if admissionPol, err := persistence.FindNodeAdmissionPolicy(b.db); err != nil {
+		return fmt.Errorf("unable to read the admission policy, error %v", err)
+	} else {
+		return admissionPol.Admit(deployment)
+	}
+}
+
 // Return the base workload rw storage directory.
 // If Config.Edge.ServiceStorage is empty then the docker volume is used, it returns the new volume name.
 func (b *ContainerWorker) workloadStorageDir(agreementId string) (string, bool) {
@@ -1513,7 +1552,7 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 	case *WorkloadConfigureCommand:
 		cmd := command.(*WorkloadConfigureCommand)
 
-		glog.V(3).Infof("ContainerWorker received workload configure command: %v", cmd.ShortString())
+		b.Logger(cmd).V(3).Infof("received workload configure command: %v", cmd.ShortString())
 
 		agreementId := cmd.AgreementLaunchContext.AgreementId
 
@@ -1563,6 +1602,16 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 				}
 			}
 
+			// The node owner may restrict what the deployment is allowed to do with an admission policy.
+			if err := b.admitDeployment(deploymentDesc); err != nil {
+				eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_ERROR,
+					persistence.NewMessageMeta(EL_CONT_DEPLOYCONF_NOT_ADMITTED, cmd.AgreementLaunchContext.Configure.Deployment, err.Error()),
+					persistence.EC_ADMISSION_POLICY_REJECT, ags[0])
+				glog.Errorf("Deployment config %v is not admitted by the node admission policy, %v", cmd.AgreementLaunchContext.Configure.Deployment, err)
+				b.Messages() <- events.NewWorkloadMessage(events.EXECUTION_FAILED, cmd.AgreementLaunchContext.AgreementProtocol, agreementId, nil)
+				return true
+			}
+
 			// Dynamically add in a filesystem mapping so that the workload container has a RO filesystem.
 			for serviceName, service := range deploymentDesc.Services {
 
@@ -1689,6 +1738,14 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 			glog.Errorf("Deployment config %v contains unsupported capability for infrastructure container.", lc.Configure.Deployment)
 			b.Messages() <- events.NewContainerMessage(events.EXECUTION_FAILED, *cmd.ContainerLaunchContext, "", "")
 			return true
+		} else if err := b.admitDeployment(deploymentDesc); err != nil {
+			eventlog.LogServiceEvent2(b.db, persistence.SEVERITY_ERROR,
+				persistence.NewMessageMeta(EL_CONT_DEPLOYCONF_NOT_ADMITTED, lc.Configure.Deployment, err.Error()),
+				persistence.EC_ADMISSION_POLICY_REJECT,
+				"", serviceInfo.URL, serviceInfo.Org, serviceInfo.Version, "", lc.AgreementIds)
+			glog.Errorf("Deployment config %v is not admitted by the node admission policy, %v", lc.Configure.Deployment, err)
+			b.Messages() <- events.NewContainerMessage(events.EXECUTION_FAILED, *cmd.ContainerLaunchContext, "", "")
+			return true
 		}
 
 		serviceNames := deploymentDesc.ServiceNames()
@@ -1812,8 +1869,12 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 
 				for _, name := range serviceNames {
 					if container.Labels[LABEL_PREFIX+".service_name"] == name && container.State == "running" {
-						cMatches = append(cMatches, *container)
-						glog.V(4).Infof("Matching container instance for agreement %v: %v", agreementId, container)
+						if ContainerHealth(container) == HEALTH_UNHEALTHY {
+							b.Logger(cmd).Errorf("Container %v for agreement %v is unhealthy.", container.Names, agreementId)
+						} else {
+							cMatches = append(cMatches, *container)
+							glog.V(4).Infof("Matching container instance for agreement %v: %v", agreementId, container)
+						}
 					}
 				}
 				return nil
@@ -1822,9 +1883,9 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 			b.ContainersMatchingAgreement([]string{cmd.AgreementId}, true, report)
 
 			if len(serviceNames) == len(cMatches) {
-				glog.V(3).Infof("Found expected count of running containers for agreement %v: %v", cmd.AgreementId, len(cMatches))
+				b.Logger(cmd).V(3).Infof("Found expected count of running containers for agreement %v: %v", cmd.AgreementId, len(cMatches))
 			} else {
-				glog.Errorf("Insufficient running containers found for agreement %v. Found: %v", cmd.AgreementId, cMatches)
+				b.Logger(cmd).Errorf("Insufficient running and healthy containers found for agreement %v. Found: %v", cmd.AgreementId, cMatches)
 
 				// ask governer to cancel the agreement
 				b.Messages() <- events.NewWorkloadMessage(events.EXECUTION_FAILED, cmd.AgreementProtocol, cmd.AgreementId, cmd.Deployment)
@@ -1843,7 +1904,7 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 		// This agreement should be handled by the container worker.
 		agreements := cmd.Agreements
 		if cmd.CurrentAgreementId != "" {
-			glog.Infof("ContainerWorker received shutdown command w/ current agreement id: %v. Shutting down resources", cmd.CurrentAgreementId)
+			b.Logger(cmd).Infof("received shutdown command w/ current agreement id: %v. Shutting down resources", cmd.CurrentAgreementId)
 			glog.V(5).Infof("Shutdown command for agreement id %v: %v", cmd.CurrentAgreementId, cmd)
 			agreements = append(agreements, cmd.CurrentAgreementId)
 		}
@@ -1886,6 +1947,8 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 					if container.Labels[LABEL_PREFIX+".service_name"] == name {
 						if container.State != "running" {
 							glog.Errorf("Service container for %v is not in the running state.", instance_key)
+						} else if ContainerHealth(container) == HEALTH_UNHEALTHY {
+							glog.Errorf("Service container for %v is unhealthy.", instance_key)
 						} else {
 							cMatches = append(cMatches, *container)
 							glog.V(4).Infof("Matching container instance for service instance %v: %v", instance_key, container)
@@ -1900,7 +1963,7 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 			if len(serviceNames) == len(cMatches) {
 				glog.V(3).Infof("Found expected count of running containers for service instance %v: %v", cmd.MsInstKey, len(cMatches))
 			} else {
-				glog.Errorf("Insufficient running containers found for service instance %v. Found: %v", cmd.MsInstKey, cMatches)
+				glog.Errorf("Insufficient running and healthy containers found for service instance %v. Found: %v", cmd.MsInstKey, cMatches)
 
 				// ask governer to record it into the db
 				cc := events.NewContainerConfig("", "", "", "", "", "", nil)
@@ -2622,6 +2685,26 @@ func (b *ContainerWorker) ContainersMatchingAgreement(agreements []string, inclu
 	return processingErr
 }
 
+// The health states of a container that has a health check.
+const (
+	HEALTH_STARTING  = "starting"
+	HEALTH_HEALTHY   = "healthy"
+	HEALTH_UNHEALTHY = "unhealthy"
+)
+
+// Returns the health state of the container, or an empty string if the container does not have a health check. The
+// container list API only reports the health state in the human readable status, e.g. "Up 2 minutes (unhealthy)".
+func ContainerHealth(container *docker.APIContainers) string {
+	if strings.HasSuffix(container.Status, "(health: starting)") {
+		return HEALTH_STARTING
+	} else if strings.HasSuffix(container.Status, "("+HEALTH_UNHEALTHY+")") {
+		return HEALTH_UNHEALTHY
+	} else if strings.HasSuffix(container.Status, "("+HEALTH_HEALTHY+")") {
+		return HEALTH_HEALTHY
+	}
+	return ""
+}
+
 // find the microservice definition from the db
 func (b *ContainerWorker) findMicroserviceDefContainerNames(api_spec string, org string, version string, msdef_key string) ([]string, error) {
 
@@ -272,3 +272,21 @@ func Test_CheckPermissions_0(t *testing.T) {
 	}
 
 }
+
+func Test_ContainerHealth(t *testing.T) {
+	statuses := map[string]string{
+		"Up 18 seconds":                    "",
+		"Up 18 seconds (health: starting)": HEALTH_STARTING,
+		"Up 5 minutes (healthy)":           HEALTH_HEALTHY,
+		"Up 5 minutes (unhealthy)":         HEALTH_UNHEALTHY,
+		"Exited (1) 2 minutes ago":         "",
+		"Restarting (1) 3 seconds ago":     "",
+		"Up About an hour (unhealthy)":     HEALTH_UNHEALTHY,
+	}
+	for status, health := range statuses {
+		c := docker.APIContainers{State: "running", Status: status}
+		if h := ContainerHealth(&c); h != health {
+			t.Errorf("Health of container with status %v should be %v, but is %v", status, health, h)
+		}
+	}
+}
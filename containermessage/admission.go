@@ -0,0 +1,231 @@
+package containermessage
+
+import (
+	"errors"
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// The admission policy is a declarative policy owned by the node owner which restricts what a service deployment
+// is allowed to do on the node. A list that is not set (null) does not restrict anything, an empty list does not
+// allow anything. An entry that ends with "*" matches every value that starts with the rest of the entry.
+//
+// ex:
+//
+//	{
+//	  "allowedRegistries": ["docker.io", "myregistry.example.com"],
+//	  "allowedImages": ["myregistry.example.com/edge/*"],
+//	  "allowedBindPaths": ["/var/run/edge"],
+//	  "allowedDevices": ["/dev/video*"],
+//	  "allowedCapabilities": ["NET_ADMIN"],
+//	  "allowedPorts": ["8080", "9000-9100"],
+//	  "denyPrivileged": true,
+//	  "denyHostNetwork": true
+//	}
+type AdmissionPolicy struct {
+	AllowedRegistries   []string `json:"allowedRegistries"`         // The registries the images can be pulled from, docker.io is used for images without a registry
+	AllowedImages       []string `json:"allowedImages"`             // The image repositories, an image is admitted if its registry or its repository is allowed
+	AllowedBindPaths    []string `json:"allowedBindPaths"`          // The host paths, and the paths under them, that can be bound into a container
+	AllowedDevices      []string `json:"allowedDevices"`            // The host devices that can be mapped into a container
+	AllowedCapabilities []string `json:"allowedCapabilities"`       // The linux capabilities that can be added to a container
+	AllowedPorts        []string `json:"allowedPorts"`              // The host ports, or port ranges like 8000-8999, that can be published
+	DenyPrivileged      bool     `json:"denyPrivileged,omitempty"`  // Refuse containers that run in privileged mode
+	DenyHostNetwork     bool     `json:"denyHostNetwork,omitempty"` // Refuse containers that use the host network
+}
+
+func (a AdmissionPolicy) String() string {
+	return fmt.Sprintf("AllowedRegistries: %v, AllowedImages: %v, AllowedBindPaths: %v, AllowedDevices: %v, AllowedCapabilities: %v, AllowedPorts: %v, DenyPrivileged: %v, DenyHostNetwork: %v",
+		a.AllowedRegistries, a.AllowedImages, a.AllowedBindPaths, a.AllowedDevices, a.AllowedCapabilities, a.AllowedPorts, a.DenyPrivileged, a.DenyHostNetwork)
+}
+
+// Verify that the admission policy is well formed.
+func (a *AdmissionPolicy) Validate() error {
+	for _, p := range a.AllowedBindPaths {
+		if !path.IsAbs(strings.TrimSuffix(p, "*")) {
+			return fmt.Errorf("allowed bind path %v must be an absolute path", p)
+		}
+	}
+	for _, d := range a.AllowedDevices {
+		if !path.IsAbs(strings.TrimSuffix(d, "*")) {
+			return fmt.Errorf("allowed device %v must be an absolute path", d)
+		}
+	}
+	for _, p := range a.AllowedPorts {
+		if _, _, err := parsePortRange(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Admit returns an error that describes the first violation of the admission policy by the services in the
+// deployment, or nil if the deployment is admitted. The deployment overrides are checked too, because they
+// replace the settings of the services when the containers are created.
+func (a *AdmissionPolicy) Admit(deployment *DeploymentDescription) error {
+	if a == nil || deployment == nil {
+		return nil
+	}
+	for _, services := range []map[string]*Service{deployment.Services, deployment.Overrides} {
+		for name, service := range services {
+			if service == nil {
+				continue
+			} else if err := a.admitService(service); err != nil {
+				return fmt.Errorf("service %v: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (a *AdmissionPolicy) admitService(s *Service) error {
+
+	if a.DenyPrivileged && s.Privileged {
+		return errors.New("privileged mode is not allowed")
+	} else if a.DenyHostNetwork && s.Network == "host" {
+		return errors.New("the host network is not allowed")
+	}
+
+	if s.Image != "" && (a.AllowedRegistries != nil || a.AllowedImages != nil) {
+		if !matchAny(a.AllowedRegistries, ImageRegistry(s.Image)) && !matchAny(a.AllowedImages, ImageRepository(s.Image)) {
+			return fmt.Errorf("image %v is not from an allowed registry or repository", s.Image)
+		}
+	}
+
+	if a.AllowedBindPaths != nil {
+		for _, bind := range s.Binds {
+			source := strings.Split(bind, ":")[0]
+			if !path.IsAbs(source) {
+				// a named volume is not a host path
+				continue
+			} else if !pathAllowed(a.AllowedBindPaths, source) {
+				return fmt.Errorf("bind of host path %v is not allowed", source)
+			}
+		}
+	}
+
+	if a.AllowedDevices != nil {
+		for _, device := range s.Devices {
+			hostDevice := strings.Split(device, ":")[0]
+			if !matchAny(a.AllowedDevices, path.Clean(hostDevice)) {
+				return fmt.Errorf("device %v is not allowed", hostDevice)
+			}
+		}
+	}
+
+	if a.AllowedCapabilities != nil {
+		allowed := make([]string, 0, len(a.AllowedCapabilities))
+		for _, c := range a.AllowedCapabilities {
+			allowed = append(allowed, normalizeCapability(c))
+		}
+		for _, c := range s.CapAdd {
+			if !matchAny(allowed, normalizeCapability(c)) {
+				return fmt.Errorf("capability %v is not allowed", c)
+			}
+		}
+	}
+
+	if a.AllowedPorts != nil {
+		for _, bindings := range [][]string{portBindingHostPorts(s.Ports), portBindingHostPorts(s.SpecificPorts)} {
+			for _, hostPort := range bindings {
+				if !a.portAllowed(hostPort) {
+					return fmt.Errorf("host port %v is not allowed", hostPort)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the registry of an image reference. Images without a registry are pulled from docker.io.
+func ImageRegistry(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0]
+	}
+	return "docker.io"
+}
+
+// Returns the repository of an image reference, without the tag or the digest. The registry is added for images
+// that do not have one so that the repository can be matched against a registry prefix.
+func ImageRepository(image string) string {
+	repo := strings.SplitN(image, "@", 2)[0]
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	if registry := ImageRegistry(image); !strings.HasPrefix(repo, registry+"/") {
+		repo = registry + "/" + repo
+	}
+	return repo
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if p == value || (strings.HasSuffix(p, "*") && strings.HasPrefix(value, strings.TrimSuffix(p, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// A host path is allowed if it is one of the allowed paths or is under one of them.
+func pathAllowed(allowed []string, hostPath string) bool {
+	hostPath = path.Clean(hostPath)
+	for _, a := range allowed {
+		a = path.Clean(strings.TrimSuffix(a, "*"))
+		if hostPath == a || a == "/" || strings.HasPrefix(hostPath, a+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeCapability(c string) string {
+	return strings.TrimPrefix(strings.ToUpper(c), "CAP_")
+}
+
+// Returns the specific host ports of the port bindings. A binding without a host port uses a port chosen by docker.
+func portBindingHostPorts(bindings []docker.PortBinding) []string {
+	ports := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		if p := GetSpecificHostPort(b.HostPort); p != "" {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+func (a *AdmissionPolicy) portAllowed(hostPort string) bool {
+	port, err := strconv.Atoi(hostPort)
+	if err != nil {
+		return false
+	}
+	for _, p := range a.AllowedPorts {
+		if low, high, err := parsePortRange(p); err == nil && port >= low && port <= high {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse a port, or a port range in the form low-high.
+func parsePortRange(p string) (int, int, error) {
+	bounds := strings.SplitN(strings.TrimSpace(p), "-", 2)
+	low, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("allowed port %v is not a port number or a port range", p)
+	}
+	high := low
+	if len(bounds) == 2 {
+		if high, err = strconv.Atoi(bounds[1]); err != nil {
+			return 0, 0, fmt.Errorf("allowed port %v is not a port number or a port range", p)
+		}
+	}
+	if low < 0 || high > 65535 || low > high {
+		return 0, 0, fmt.Errorf("allowed port %v is not a valid port range", p)
+	}
+	return low, high, nil
+}
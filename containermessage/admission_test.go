@@ -0,0 +1,108 @@
+//go:build unit
+// +build unit
+
+package containermessage
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_AdmissionPolicy_Admit(t *testing.T) {
+
+	var admissionPol AdmissionPolicy
+	pol := `{"allowedRegistries":["myregistry.example.com:5000"],"allowedImages":["docker.io/myorg/*"],"allowedBindPaths":["/var/run/edge"],"allowedDevices":["/dev/video*"],"allowedCapabilities":["NET_ADMIN"],"allowedPorts":["8080","9000-9100"],"denyPrivileged":true,"denyHostNetwork":true}`
+	if err := json.Unmarshal([]byte(pol), &admissionPol); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if err := admissionPol.Validate(); err != nil {
+		t.Errorf("the admission policy should be valid: %v", err)
+	}
+
+	admitted := []string{
+		`{"services":{"s1":{"image":"myregistry.example.com:5000/edge/s1:1.0"}}}`,
+		`{"services":{"s1":{"image":"myorg/s1@sha256:1234"}}}`,
+		`{"services":{"s1":{"image":"docker.io/myorg/s1:1.0","binds":["/var/run/edge/s1:/data:ro","myvolume:/vol"]}}}`,
+		`{"services":{"s1":{"image":"myorg/s1","devices":["/dev/video0:/dev/video0"],"cap_add":["cap_net_admin"]}}}`,
+		`{"services":{"s1":{"image":"myorg/s1","ports":[{"HostPort":"9050:80/tcp"},{"HostPort":""}],"specific_ports":[{"HostPort":"8080"}]}}}`,
+	}
+	for _, d := range admitted {
+		if dd, err := GetNativeDeployment(d); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		} else if err := admissionPol.Admit(dd); err != nil {
+			t.Errorf("deployment %v should be admitted: %v", d, err)
+		}
+	}
+
+	rejected := []string{
+		`{"services":{"s1":{"image":"otherorg/s1"}}}`,
+		`{"services":{"s1":{"image":"myregistry.example.com/edge/s1"}}}`,
+		`{"services":{"s1":{"image":"myorg/s1","privileged":true}}}`,
+		`{"services":{"s1":{"image":"myorg/s1","network":"host"}}}`,
+		`{"services":{"s1":{"image":"myorg/s1","binds":["/var/run/edge-other:/data"]}}}`,
+		`{"services":{"s1":{"image":"myorg/s1","binds":["/var/run/edge/../../../etc:/etc"]}}}`,
+		`{"services":{"s1":{"image":"myorg/s1","devices":["/dev/sda:/dev/sda"]}}}`,
+		`{"services":{"s1":{"image":"myorg/s1","cap_add":["SYS_ADMIN"]}}}`,
+		`{"services":{"s1":{"image":"myorg/s1","ports":[{"HostPort":"9101:80/tcp"}]}}}`,
+		`{"services":{"s1":{"image":"myorg/s1"},"s2":{"image":"otherorg/s2"}}}`,
+	}
+	for _, d := range rejected {
+		if dd, err := GetNativeDeployment(d); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		} else if err := admissionPol.Admit(dd); err == nil {
+			t.Errorf("deployment %v should not be admitted", d)
+		}
+	}
+
+	// the overrides are checked too
+	dd, _ := GetNativeDeployment(`{"services":{"s1":{"image":"myorg/s1"}}}`)
+	dd.Overrides = map[string]*Service{"s1": &Service{Image: "otherorg/s1"}}
+	if err := admissionPol.Admit(dd); err == nil {
+		t.Errorf("the overrides should not be admitted")
+	}
+
+	// an empty list does not allow anything, a list that is not set does not restrict anything
+	var emptyPol AdmissionPolicy
+	if err := json.Unmarshal([]byte(`{"allowedDevices":[]}`), &emptyPol); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	dd, _ = GetNativeDeployment(`{"services":{"s1":{"image":"anyorg/s1","privileged":true,"devices":["/dev/video0"]}}}`)
+	if err := emptyPol.Admit(dd); err == nil {
+		t.Errorf("the device should not be admitted")
+	}
+	dd.Services["s1"].Devices = nil
+	if err := emptyPol.Admit(dd); err != nil {
+		t.Errorf("deployment should be admitted: %v", err)
+	}
+}
+
+func Test_AdmissionPolicy_Validate(t *testing.T) {
+
+	invalid := []AdmissionPolicy{
+		AdmissionPolicy{AllowedBindPaths: []string{"var/run"}},
+		AdmissionPolicy{AllowedDevices: []string{"video0"}},
+		AdmissionPolicy{AllowedPorts: []string{"http"}},
+		AdmissionPolicy{AllowedPorts: []string{"9100-9000"}},
+		AdmissionPolicy{AllowedPorts: []string{"70000"}},
+	}
+	for _, pol := range invalid {
+		if err := pol.Validate(); err == nil {
+			t.Errorf("admission policy %v should not be valid", pol)
+		}
+	}
+}
+
+func Test_ImageRepository(t *testing.T) {
+
+	images := map[string]string{
+		"ubuntu":                               "docker.io/ubuntu",
+		"myorg/s1:1.0":                         "docker.io/myorg/s1",
+		"localhost/s1":                         "localhost/s1",
+		"myregistry.example.com:5000/s1:1.0":   "myregistry.example.com:5000/s1",
+		"myregistry.example.com/a/s1@sha256:1": "myregistry.example.com/a/s1",
+	}
+	for image, repo := range images {
+		if r := ImageRepository(image); r != repo {
+			t.Errorf("the repository of %v should be %v, but is %v", image, repo, r)
+		}
+	}
+}
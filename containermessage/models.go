@@ -8,6 +8,7 @@ import (
 	docker "github.com/fsouza/go-dockerclient"
 	"reflect"
 	"strings"
+	"time"
 )
 
 /*
@@ -120,6 +121,20 @@ func (d DeploymentDescription) ServiceNames() []string {
 	return names
 }
 
+// Returns the memory, in MB, and the CPUs that the containers of the deployment are limited to. A container that does
+// not have a limit does not count.
+func (d DeploymentDescription) ResourceLimits() (int64, float64) {
+	memoryMb := int64(0)
+	cpus := float64(0)
+	for _, service := range d.Services {
+		if service != nil {
+			memoryMb += service.MaxMemoryMb
+			cpus += float64(service.MaxCPUs)
+		}
+	}
+	return memoryMb, cpus
+}
+
 type Pattern struct {
 	Shared map[string][]string `json:"shared"`
 }
@@ -153,6 +168,7 @@ type Secret struct {
 // Service Only those marked "omitempty" may be omitted
 type Service struct {
 	Image            string               `json:"image"`
+	ImageSignature   string               `json:"image_signature,omitempty"` // The signature of the image digest, verified with the trusted public keys of the node
 	VariationLabel   string               `json:"variation_label,omitempty"`
 	Privileged       bool                 `json:"privileged"`
 	Network          string               `json:"network"`
@@ -172,6 +188,15 @@ type Service struct {
 	LogDriver        string               `json:"log_driver,omitempty"` // Docker's log-driver. Syslog will be used as default driver
 	Secrets          map[string]Secret    `json:"secrets"`
 	SecurityOpt      []string             `json:"security_opt,omitempty"`
+	HealthCheck      *HealthCheck         `json:"healthcheck,omitempty"`
+	User             string               `json:"user,omitempty"`      // The user (name or uid[:gid]) the container runs as
+	ReadOnly         bool                 `json:"read_only,omitempty"` // Mount the container's root filesystem as read only
+	CapDrop          []string             `json:"cap_drop,omitempty"`
+	Ulimits          []Ulimit             `json:"ulimits,omitempty"`
+	Sysctls          map[string]string    `json:"sysctls,omitempty"`
+	PidsLimit        int64                `json:"pids_limit,omitempty"`
+	ShmSizeMb        int64                `json:"shm_size_mb,omitempty"`
+	Init             bool                 `json:"init,omitempty"` // Run an init process in the container that reaps zombie processes
 }
 
 func (s *Service) AddFilesystemBinding(bind string) {
@@ -248,6 +273,103 @@ func (s *Service) AddSpecificPortBinding(b docker.PortBinding) {
 	s.Ports = append(s.Ports, b)
 }
 
+// A resource limit of a service container, equivalent to the docker run --ulimit flag.
+type Ulimit struct {
+	Name string `json:"name"`
+	Soft int64  `json:"soft"`
+	Hard int64  `json:"hard"`
+}
+
+// The container options that change the limits a node applies to its containers. A service can only use them on nodes
+// that allow them in the openhorizon.allowedContainerOptions property, or that allow privileged services. The options
+// that only reduce the privileges of a container, like read_only or cap_drop, can always be used.
+const (
+	CONTAINER_OPTION_SYSCTLS  = "sysctls"
+	CONTAINER_OPTION_ULIMITS  = "ulimits"
+	CONTAINER_OPTION_SHM_SIZE = "shm_size_mb"
+)
+
+// Returns the restricted container options that the service uses.
+func (s *Service) RestrictedContainerOptions() []string {
+	opts := make([]string, 0)
+	if len(s.Sysctls) != 0 {
+		opts = append(opts, CONTAINER_OPTION_SYSCTLS)
+	}
+	if len(s.Ulimits) != 0 {
+		opts = append(opts, CONTAINER_OPTION_ULIMITS)
+	}
+	if s.ShmSizeMb != 0 {
+		opts = append(opts, CONTAINER_OPTION_SHM_SIZE)
+	}
+	return opts
+}
+
+// Verify the values of the hardened container options.
+func (s *Service) ValidateContainerOptions() error {
+	for _, u := range s.Ulimits {
+		if u.Name == "" {
+			return errors.New(fmt.Sprintf("ulimit %v must have a name", u))
+		} else if u.Soft < 0 || u.Hard < 0 || u.Soft > u.Hard {
+			return errors.New(fmt.Sprintf("ulimit %v must have a soft limit that is not greater than the hard limit", u.Name))
+		}
+	}
+	for k, _ := range s.Sysctls {
+		if k == "" {
+			return errors.New(fmt.Sprintf("sysctls %v cannot have an empty name", s.Sysctls))
+		}
+	}
+	if s.PidsLimit < -1 {
+		return errors.New(fmt.Sprintf("pids_limit %v must be -1 for unlimited or a positive number", s.PidsLimit))
+	} else if s.ShmSizeMb < 0 {
+		return errors.New(fmt.Sprintf("shm_size_mb %v cannot be negative", s.ShmSizeMb))
+	}
+	return nil
+}
+
+// The health check of a service container, equivalent to the docker HEALTHCHECK instruction. The command is either
+// ["CMD", args...] to run the command directly, ["CMD-SHELL", command] to run the command with the container's
+// default shell, or ["NONE"] to disable the health check inherited from the image. A command that does not start
+// with one of these is run directly. The durations are in seconds, zero means the docker default is used.
+type HealthCheck struct {
+	Command      []string `json:"command"`
+	IntervalS    int64    `json:"interval_s,omitempty"`
+	TimeoutS     int64    `json:"timeout_s,omitempty"`
+	Retries      int      `json:"retries,omitempty"`
+	StartPeriodS int64    `json:"start_period_s,omitempty"`
+}
+
+func (h HealthCheck) String() string {
+	return fmt.Sprintf("Command: %v, IntervalS: %v, TimeoutS: %v, Retries: %v, StartPeriodS: %v", h.Command, h.IntervalS, h.TimeoutS, h.Retries, h.StartPeriodS)
+}
+
+func (h *HealthCheck) Validate() error {
+	if len(h.Command) == 0 {
+		return errors.New("healthcheck command must be specified")
+	} else if h.Command[0] == "CMD-SHELL" && len(h.Command) != 2 {
+		return errors.New(fmt.Sprintf("healthcheck command %v must have exactly one shell command after CMD-SHELL", h.Command))
+	} else if h.Command[0] == "CMD" && len(h.Command) == 1 {
+		return errors.New(fmt.Sprintf("healthcheck command %v must have a command after CMD", h.Command))
+	} else if h.IntervalS < 0 || h.TimeoutS < 0 || h.Retries < 0 || h.StartPeriodS < 0 {
+		return errors.New(fmt.Sprintf("healthcheck %v cannot have negative values", h))
+	}
+	return nil
+}
+
+// Returns the docker health check configuration.
+func (h *HealthCheck) DockerHealthConfig() *docker.HealthConfig {
+	test := h.Command
+	if len(test) != 0 && test[0] != "CMD" && test[0] != "CMD-SHELL" && test[0] != "NONE" {
+		test = append([]string{"CMD"}, h.Command...)
+	}
+	return &docker.HealthConfig{
+		Test:        test,
+		Interval:    time.Duration(h.IntervalS) * time.Second,
+		Timeout:     time.Duration(h.TimeoutS) * time.Second,
+		StartPeriod: time.Duration(h.StartPeriodS) * time.Second,
+		Retries:     h.Retries,
+	}
+}
+
 type Port struct {
 	LocalhostOnly   bool   `json:"localhost_only,omitempty"`
 	PortAndProtocol string `json:"port_and_protocol"`
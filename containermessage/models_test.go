@@ -4,8 +4,10 @@
 package containermessage
 
 import (
+	"encoding/json"
 	docker "github.com/fsouza/go-dockerclient"
 	"testing"
+	"time"
 )
 
 func Test_HasSpecificPortBinding(t *testing.T) {
@@ -179,3 +181,66 @@ func Test_AddSpecificPortBinding(t *testing.T) {
 		t.Errorf("Service should have 2 specific port bindings but not.")
 	}
 }
+
+func Test_HealthCheck(t *testing.T) {
+	var serv Service
+	if err := json.Unmarshal([]byte(`{"image": "an image", "healthcheck": {"command": ["curl", "-f", "http://localhost:8080/health"], "interval_s": 30, "timeout_s": 5, "retries": 3, "start_period_s": 60}}`), &serv); err != nil {
+		t.Errorf("Error unmarshalling service with a healthcheck: %v", err)
+	} else if serv.HealthCheck == nil {
+		t.Errorf("The healthcheck should be set in service %v", serv)
+	} else if err := serv.HealthCheck.Validate(); err != nil {
+		t.Errorf("The healthcheck %v should be valid: %v", serv.HealthCheck, err)
+	} else if hc := serv.HealthCheck.DockerHealthConfig(); len(hc.Test) != 4 || hc.Test[0] != "CMD" || hc.Interval != 30*time.Second || hc.Timeout != 5*time.Second || hc.StartPeriod != time.Minute || hc.Retries != 3 {
+		t.Errorf("Wrong docker health config %v", hc)
+	}
+
+	hc := HealthCheck{Command: []string{"CMD-SHELL", "curl -f http://localhost/ || exit 1"}}
+	if err := hc.Validate(); err != nil {
+		t.Errorf("The healthcheck %v should be valid: %v", hc, err)
+	} else if dhc := hc.DockerHealthConfig(); len(dhc.Test) != 2 || dhc.Test[0] != "CMD-SHELL" || dhc.Interval != 0 {
+		t.Errorf("Wrong docker health config %v", dhc)
+	}
+
+	invalid := []HealthCheck{
+		HealthCheck{},
+		HealthCheck{Command: []string{"CMD"}},
+		HealthCheck{Command: []string{"CMD-SHELL", "a", "b"}},
+		HealthCheck{Command: []string{"check"}, Retries: -1},
+	}
+	for _, hc := range invalid {
+		if err := hc.Validate(); err == nil {
+			t.Errorf("The healthcheck %v should not be valid", hc)
+		}
+	}
+}
+
+func Test_ContainerOptions(t *testing.T) {
+	serv := Service{Image: "an image", User: "1000:1000", ReadOnly: true, CapDrop: []string{"ALL"}, PidsLimit: 100, Init: true}
+	if err := serv.ValidateContainerOptions(); err != nil {
+		t.Errorf("The container options of service %v should be valid: %v", serv, err)
+	} else if opts := serv.RestrictedContainerOptions(); len(opts) != 0 {
+		t.Errorf("Service %v should not use restricted container options: %v", serv, opts)
+	}
+
+	serv.Sysctls = map[string]string{"net.core.somaxconn": "1024"}
+	serv.Ulimits = []Ulimit{{Name: "nofile", Soft: 1024, Hard: 2048}}
+	serv.ShmSizeMb = 64
+	if err := serv.ValidateContainerOptions(); err != nil {
+		t.Errorf("The container options of service %v should be valid: %v", serv, err)
+	} else if opts := serv.RestrictedContainerOptions(); len(opts) != 3 {
+		t.Errorf("Service %v should use 3 restricted container options: %v", serv, opts)
+	}
+
+	invalid := []Service{
+		Service{Ulimits: []Ulimit{{Soft: 1, Hard: 1}}},
+		Service{Ulimits: []Ulimit{{Name: "nofile", Soft: 2048, Hard: 1024}}},
+		Service{Sysctls: map[string]string{"": "1"}},
+		Service{PidsLimit: -2},
+		Service{ShmSizeMb: -1},
+	}
+	for _, s := range invalid {
+		if err := s.ValidateContainerOptions(); err == nil {
+			t.Errorf("The container options of service %v should not be valid", s)
+		}
+	}
+}
@@ -38,3 +38,21 @@ func NewNodeRegisteredCommand(msg *events.EdgeRegisteredExchangeMessage) *NodeRe
 		Msg: msg,
 	}
 }
+
+type LoadBundleCommand struct {
+	Msg *events.BundleDownloadMessage
+}
+
+func (l LoadBundleCommand) ShortString() string {
+	return fmt.Sprintf("Msg: %v", l.Msg)
+}
+
+func (l LoadBundleCommand) String() string {
+	return l.ShortString()
+}
+
+func NewLoadBundleCommand(msg *events.BundleDownloadMessage) *LoadBundleCommand {
+	return &LoadBundleCommand{
+		Msg: msg,
+	}
+}
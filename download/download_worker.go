@@ -9,10 +9,13 @@ import (
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/exchangecommon"
+	"github.com/open-horizon/anax/exchangesync"
 	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/imagefetch"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/semanticversion"
 	"github.com/open-horizon/anax/worker"
+	"io/ioutil"
 	"os"
 	"path"
 	"sort"
@@ -72,6 +75,11 @@ func (w *DownloadWorker) CommandHandler(command worker.Command) bool {
 		}
 	case *NodeRegisteredCommand:
 		w.EC = getEC(w.Config, w.db)
+	case *LoadBundleCommand:
+		cmd := command.(*LoadBundleCommand)
+		if err := w.DownloadBundle(cmd.Msg.Org, cmd.Msg.ObjectType, cmd.Msg.ObjectId); err != nil {
+			glog.Errorf(dwlog(fmt.Sprintf("Error downloading offline bundle %v/%v/%v: %v", cmd.Msg.Org, cmd.Msg.ObjectType, cmd.Msg.ObjectId, err)))
+		}
 	default:
 		return false
 	}
@@ -95,6 +103,15 @@ func (w *DownloadWorker) NewEvent(incoming events.Message) {
 			w.Commands <- cmd
 		}
 
+	case *events.BundleDownloadMessage:
+		msg, _ := incoming.(*events.BundleDownloadMessage)
+
+		switch msg.Event().Id {
+		case events.BUNDLE_DOWNLOAD:
+			cmd := NewLoadBundleCommand(msg)
+			w.Commands <- cmd
+		}
+
 	case *events.NodeShutdownCompleteMessage:
 		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
 		switch msg.Event().Id {
@@ -149,7 +166,9 @@ func (w *DownloadWorker) DownloadCSSObject(org string, objType string, objId str
 	} else {
 		err = exchange.GetObjectData(w, org, objType, objId, filePath, objId, objMeta, saveToTempFile)
 		if err != nil {
-			w.Messages() <- events.NewNMPDownloadCompleteMessage(events.NMP_DOWNLOAD_COMPLETE, exchangecommon.STATUS_DOWNLOAD_FAILED, err.Error(), nmpName, nil, nil)
+			if nmpName != "" {
+				w.Messages() <- events.NewNMPDownloadCompleteMessage(events.NMP_DOWNLOAD_COMPLETE, exchangecommon.STATUS_DOWNLOAD_FAILED, err.Error(), nmpName, nil, nil)
+			}
 			return fmt.Errorf("Failed to get data for object %v/%v/%v. Error was: %v", org, objType, objId, err)
 		}
 	}
@@ -171,6 +190,61 @@ func (w *DownloadWorker) DownloadCSSObject(org string, objType string, objId str
 	return nil
 }
 
+// Download an offline bundle from css and load it into the bundle store. The object is downloaded into a temporary
+// directory of the bundle store, it is removed once the bundle is loaded.
+func (w *DownloadWorker) DownloadBundle(org string, objType string, objId string) error {
+	storeDir := w.Config.Edge.GetOfflineBundleDirectory()
+	if err := os.MkdirAll(storeDir, 0700); err != nil {
+		return fmt.Errorf("Failed to create the bundle store %v. Error was: %v", storeDir, err)
+	}
+
+	dir, err := ioutil.TempDir(storeDir, ".download-")
+	if err != nil {
+		return fmt.Errorf("Failed to create a download directory in %v. Error was: %v", storeDir, err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := w.DownloadCSSObject(org, objType, objId, dir, ""); err != nil {
+		return err
+	}
+
+	f, err := os.Open(path.Join(dir, objId))
+	if err != nil {
+		return fmt.Errorf("Failed to open the downloaded bundle %v/%v/%v. Error was: %v", org, objType, objId, err)
+	}
+	defer f.Close()
+
+	pemFiles, err := w.Config.Collaborators.KeyFileNamesFetcher.GetKeyFileNames(w.Config.Edge.PublicKeyPath, w.Config.UserPublicKeyPath())
+	if err != nil {
+		return fmt.Errorf("Failed to get the trusted public keys. Error was: %v", err)
+	}
+
+	bundle, err := imagefetch.LoadBundle(w.db, storeDir, pemFiles, f, fmt.Sprintf("mms:%v/%v/%v", org, objType, objId))
+	if err != nil {
+		return err
+	}
+	glog.Infof(dwlog(fmt.Sprintf("Loaded offline bundle %v from css object %v/%v/%v", bundle.Id, org, objType, objId)))
+
+	// add the user input of the bundle to the node user input
+	if len(bundle.UserInput) != 0 {
+		pDevice, err := persistence.FindExchangeDevice(w.db)
+		if err != nil {
+			return fmt.Errorf("Failed to read the node object. Error was: %v", err)
+		} else if pDevice == nil {
+			return fmt.Errorf("Failed to add the user input of offline bundle %v, the node is not registered", bundle.Id)
+		} else if err := exchangesync.PatchNodeUserInput(pDevice, w.db, bundle.UserInput, exchange.GetHTTPDeviceHandler(w), exchange.GetHTTPPatchDeviceHandler(w)); err != nil {
+			return fmt.Errorf("Failed to add the user input of offline bundle %v. Error was: %v", bundle.Id, err)
+		}
+
+		changedSvcSpecs := new(persistence.ServiceSpecs)
+		for _, ui := range bundle.UserInput {
+			changedSvcSpecs.AppendServiceSpec(persistence.ServiceSpec{Url: ui.ServiceUrl, Org: ui.ServiceOrgid})
+		}
+		w.Messages() <- events.NewNodeUserInputMessage(events.UPDATE_NODE_USERINPUT, *changedSvcSpecs)
+	}
+	return nil
+}
+
 // Download the manifest, then all packages required by it
 func (w *DownloadWorker) DownloadAgentUpgradePackages(org string, filePath string, nmpName string, nmpStatus *exchangecommon.NodeManagementPolicyStatus) (string, error) {
 	// get device from the local db
@@ -8,6 +8,8 @@ import (
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/exchangecommon"
 	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/structlog"
+	"strings"
 	"time"
 )
 
@@ -108,6 +110,9 @@ const (
 	NM_STATUS_CHANGED        EventId = "NM_STATUS_CHANGED"
 	AGENT_PACKAGE_DOWNLOADED EventId = "AGENT_PACKAGE_DOWNLOADED"
 
+	// Offline bundle related
+	BUNDLE_DOWNLOAD EventId = "BUNDLE_DOWNLOAD"
+
 	// Exchange change related
 	CHANGE_MESSAGE_TYPE             EventId = "EXCHANGE_CHANGE_MESSAGE"
 	CHANGE_AGBOT_MESSAGE_TYPE       EventId = "EXCHANGE_CHANGE_AGBOT_MESSAGE"
@@ -168,10 +173,11 @@ type AgreementLaunchContext struct {
 	ConfigureRaw         []byte
 	EnvironmentAdditions *map[string]string // provided by platform, not but user
 	Microservices        []MicroserviceSpec // for ms split.
+	ImageDigests         map[string]string  // the image digests pinned by the consumer, keyed by container name
 }
 
 func (c AgreementLaunchContext) String() string {
-	return fmt.Sprintf("AgreementProtocol: %v, AgreementId: %v, Configure: %v, EnvironmentAdditions: %v, Microservices: %v", c.AgreementProtocol, c.AgreementId, c.Configure, c.EnvironmentAdditions, c.Microservices)
+	return fmt.Sprintf("AgreementProtocol: %v, AgreementId: %v, Configure: %v, EnvironmentAdditions: %v, Microservices: %v, ImageDigests: %v", c.AgreementProtocol, c.AgreementId, c.Configure, c.EnvironmentAdditions, c.Microservices, c.ImageDigests)
 }
 
 func (c AgreementLaunchContext) ShortString() string {
@@ -182,6 +188,10 @@ func (c AgreementLaunchContext) ContainerConfig() ContainerConfig {
 	return c.Configure
 }
 
+func (c AgreementLaunchContext) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: c.AgreementProtocol, structlog.AGREEMENT_ID: c.AgreementId}
+}
+
 type ImageDockerAuth struct {
 	Registry string `json:"registry"`
 	UserName string `json:"username"`
@@ -275,6 +285,16 @@ func (c ContainerLaunchContext) GetMicroservices() []MicroserviceSpec {
 	return c.Microservices
 }
 
+// The service instance key and the agreements that the container is started for. A service shared by several
+// agreements logs all of them.
+func (c ContainerLaunchContext) LogFields() structlog.Fields {
+	fields := structlog.Fields{structlog.SERVICE_INSTANCE: c.Name, structlog.AGREEMENT_ID: strings.Join(c.AgreementIds, ",")}
+	if len(c.ServicePath) != 0 {
+		fields[structlog.SERVICE_URL] = c.GetServicePathElement().URL
+	}
+	return fields
+}
+
 // GetServicePathElement returns the last element of service's path that represents the leaf of the service path.
 // The empty path element will be returned if the service path is empty.
 func (c ContainerLaunchContext) GetServicePathElement() *persistence.ServiceInstancePathElement {
@@ -338,6 +358,10 @@ func (e *LoadContainerMessage) LaunchContext() *ContainerLaunchContext {
 	return e.launchContext
 }
 
+func (e *LoadContainerMessage) LogFields() structlog.Fields {
+	return structlog.FieldsOf(e.launchContext)
+}
+
 func NewLoadContainerMessage(id EventId, lc *ContainerLaunchContext) *LoadContainerMessage {
 
 	return &LoadContainerMessage{
@@ -807,6 +831,10 @@ func (e *AgreementReachedMessage) LaunchContext() *AgreementLaunchContext {
 	return e.launchContext
 }
 
+func (e *AgreementReachedMessage) LogFields() structlog.Fields {
+	return structlog.FieldsOf(e.launchContext)
+}
+
 func NewAgreementMessage(id EventId, lc *AgreementLaunchContext) *AgreementReachedMessage {
 
 	return &AgreementReachedMessage{
@@ -822,6 +850,7 @@ type ImageFetchMessage struct {
 	DeploymentDescription *containermessage.DeploymentDescription
 	LaunchContext         interface{}
 	Error                 error
+	ImageDigests          map[string]string // the content digests of the pulled images, keyed by container name
 }
 
 // fulfill interface of events.Message
@@ -830,7 +859,7 @@ func (b *ImageFetchMessage) Event() Event {
 }
 
 func (b *ImageFetchMessage) String() string {
-	return fmt.Sprintf("event: %v, deploymentDescription: %v, launchContext: %v", b.event, b.DeploymentDescription, b.LaunchContext)
+	return fmt.Sprintf("event: %v, deploymentDescription: %v, launchContext: %v, imageDigests: %v", b.event, b.DeploymentDescription, b.LaunchContext, b.ImageDigests)
 }
 
 func (b *ImageFetchMessage) ShortString() string {
@@ -842,6 +871,10 @@ func (b *ImageFetchMessage) ShortString() string {
 	return fmt.Sprintf("event: %v, deploymentDescription: %v, launchContext: %v", b.event, b.DeploymentDescription, lc)
 }
 
+func (b *ImageFetchMessage) LogFields() structlog.Fields {
+	return structlog.FieldsOf(b.LaunchContext)
+}
+
 func NewImageFetchMessage(id EventId, deploymentDescription *containermessage.DeploymentDescription, launchContext interface{}, err error) *ImageFetchMessage {
 
 	return &ImageFetchMessage{
@@ -882,6 +915,10 @@ func (m GovernanceMaintenanceMessage) ShortString() string {
 	return fmt.Sprintf("Event: %v, AgreementProtocol: %v, AgreementId: %v, Deployment: %v", m.event, m.AgreementProtocol, m.AgreementId, depStr)
 }
 
+func (m GovernanceMaintenanceMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: m.AgreementProtocol, structlog.AGREEMENT_ID: m.AgreementId}
+}
+
 func NewGovernanceMaintenanceMessage(id EventId, protocol string, agreementId string, deployment persistence.DeploymentConfig) *GovernanceMaintenanceMessage {
 	return &GovernanceMaintenanceMessage{
 		event: Event{
@@ -949,6 +986,10 @@ func (b WorkloadMessage) Event() Event {
 	return b.event
 }
 
+func (m WorkloadMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: m.AgreementProtocol, structlog.AGREEMENT_ID: m.AgreementId}
+}
+
 func NewWorkloadMessage(id EventId, protocol string, agreementId string, deployment persistence.DeploymentConfig) *WorkloadMessage {
 
 	return &WorkloadMessage{
@@ -981,6 +1022,10 @@ func (b ContainerMessage) Event() Event {
 	return b.event
 }
 
+func (m ContainerMessage) LogFields() structlog.Fields {
+	return m.LaunchContext.LogFields()
+}
+
 func NewContainerMessage(id EventId, lc ContainerLaunchContext, serviceName string, servicePort string) *ContainerMessage {
 
 	return &ContainerMessage{
@@ -1078,6 +1123,10 @@ func (m ApiAgreementCancelationMessage) ShortString() string {
 	return m.String()
 }
 
+func (m ApiAgreementCancelationMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: m.AgreementProtocol, structlog.AGREEMENT_ID: m.AgreementId}
+}
+
 func NewApiAgreementCancelationMessage(id EventId, cause EndContractCause, protocol string, agreementId string, deployment persistence.DeploymentConfig) *ApiAgreementCancelationMessage {
 	return &ApiAgreementCancelationMessage{
 		event: Event{
@@ -1109,6 +1158,10 @@ func (m ABApiAgreementCancelationMessage) ShortString() string {
 	return m.String()
 }
 
+func (m ABApiAgreementCancelationMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: m.AgreementProtocol, structlog.AGREEMENT_ID: m.AgreementId}
+}
+
 func NewABApiAgreementCancelationMessage(id EventId, protocol string, agreementId string) *ABApiAgreementCancelationMessage {
 	return &ABApiAgreementCancelationMessage{
 		event: Event{
@@ -1139,6 +1192,10 @@ func (m ABApiWorkloadUpgradeMessage) ShortString() string {
 	return m.String()
 }
 
+func (m ABApiWorkloadUpgradeMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: m.AgreementProtocol, structlog.AGREEMENT_ID: m.AgreementId, structlog.DEVICE_ID: m.DeviceId}
+}
+
 func NewABApiWorkloadUpgradeMessage(id EventId, protocol string, agreementId string, deviceId string, policyName string) *ABApiWorkloadUpgradeMessage {
 	return &ABApiWorkloadUpgradeMessage{
 		event: Event{
@@ -1176,6 +1233,10 @@ func (m InitAgreementCancelationMessage) ShortString() string {
 	return m.String()
 }
 
+func (m InitAgreementCancelationMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: m.AgreementProtocol, structlog.AGREEMENT_ID: m.AgreementId}
+}
+
 func NewInitAgreementCancelationMessage(id EventId, reason uint, protocol string, agreementId string, deployment persistence.DeploymentConfig) *InitAgreementCancelationMessage {
 	return &InitAgreementCancelationMessage{
 		event: Event{
@@ -1656,6 +1717,10 @@ func (m MicroserviceMaintenanceMessage) ShortString() string {
 	return fmt.Sprintf("Event: %v, MsInstKey: %v", m.event, m.MsInstKey)
 }
 
+func (m MicroserviceMaintenanceMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.SERVICE_INSTANCE: m.MsInstKey}
+}
+
 func NewMicroserviceMaintenanceMessage(id EventId, key string) *MicroserviceMaintenanceMessage {
 	return &MicroserviceMaintenanceMessage{
 		event: Event{
@@ -1682,6 +1747,10 @@ func (m MicroserviceCancellationMessage) ShortString() string {
 	return fmt.Sprintf("Event: %v, MsInstKey: %v", m.event, m.MsInstKey)
 }
 
+func (m MicroserviceCancellationMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.SERVICE_INSTANCE: m.MsInstKey}
+}
+
 func NewMicroserviceCancellationMessage(id EventId, key string) *MicroserviceCancellationMessage {
 	return &MicroserviceCancellationMessage{
 		event: Event{
@@ -1708,6 +1777,10 @@ func (m MicroserviceContainersDestroyedMessage) ShortString() string {
 	return fmt.Sprintf("Event: %v, MsInstKey: %v", m.event, m.MsInstKey)
 }
 
+func (m MicroserviceContainersDestroyedMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.SERVICE_INSTANCE: m.MsInstKey}
+}
+
 func NewMicroserviceContainersDestroyedMessage(id EventId, key string) *MicroserviceContainersDestroyedMessage {
 	return &MicroserviceContainersDestroyedMessage{
 		event: Event{
@@ -2148,6 +2221,10 @@ func (n *NMPStartDownloadMessage) ShortString() string {
 	return n.String()
 }
 
+func (n *NMPStartDownloadMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.NMP_NAME: n.Message.NMPName}
+}
+
 func NewNMPStartDownloadMessage(id EventId, message StartDownloadMessage) *NMPStartDownloadMessage {
 	return &NMPStartDownloadMessage{
 		event: Event{
@@ -2178,6 +2255,10 @@ func (n *NMPDownloadCompleteMessage) ShortString() string {
 	return n.String()
 }
 
+func (n *NMPDownloadCompleteMessage) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.NMP_NAME: n.NMPName}
+}
+
 func NewNMPDownloadCompleteMessage(id EventId, status string, errMsg string, name string, vers *exchangecommon.AgentUpgradeVersions, latest *exchangecommon.AgentUpgradeLatest) *NMPDownloadCompleteMessage {
 	return &NMPDownloadCompleteMessage{
 		event: Event{
@@ -2216,3 +2297,34 @@ func NewAgentPackageDownloadedMessage(id EventId, message StartDownloadMessage)
 		Message: message,
 	}
 }
+
+// This event asks for an offline bundle to be downloaded from the MMS and loaded into the bundle store.
+type BundleDownloadMessage struct {
+	event      Event
+	Org        string
+	ObjectType string
+	ObjectId   string
+}
+
+func (m *BundleDownloadMessage) Event() Event {
+	return m.event
+}
+
+func (m *BundleDownloadMessage) String() string {
+	return fmt.Sprintf("event: %v, Org: %v, ObjectType: %v, ObjectId: %v", m.event, m.Org, m.ObjectType, m.ObjectId)
+}
+
+func (m *BundleDownloadMessage) ShortString() string {
+	return m.String()
+}
+
+func NewBundleDownloadMessage(id EventId, org string, objType string, objId string) *BundleDownloadMessage {
+	return &BundleDownloadMessage{
+		event: Event{
+			Id: id,
+		},
+		Org:        org,
+		ObjectType: objType,
+		ObjectId:   objId,
+	}
+}
@@ -128,6 +128,15 @@ func GetHTTPDeviceHandler2(cfg *config.HorizonConfig) DeviceHandler {
 	}
 }
 
+// A handler for getting all the devices in an org from the exchange
+type OrgDevicesHandler func(org string) (map[string]Device, error)
+
+func GetHTTPOrgDevicesHandler(ec ExchangeContext) OrgDevicesHandler {
+	return func(org string) (map[string]Device, error) {
+		return GetExchangeOrgDevices(ec.GetHTTPFactory(), org, ec.GetExchangeId(), ec.GetExchangeToken(), ec.GetExchangeURL())
+	}
+}
+
 // A handler for modifying the device information on the exchange
 type PutDeviceHandler func(deviceId string, deviceToken string, pdr *PutDeviceRequest) (*PutDeviceResponse, error)
 
@@ -0,0 +1,59 @@
+package exchange
+
+import (
+	"github.com/open-horizon/anax/metrics"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Metrics recorded for every invocation of the exchange API. The resource label is derived from the URL so that
+// the number of label values stays small, e.g. https://host/v1/orgs/myorg/nodes/n1/agreements/a1 is "nodes/agreements".
+var exchangeCallDuration = metrics.DefaultRegistry().NewSummaryVec("anax_exchange_call_duration_seconds",
+	"Latency of exchange API invocations in seconds.", "method", "resource")
+
+var exchangeCallErrors = metrics.DefaultRegistry().NewCounterVec("anax_exchange_call_errors_total",
+	"Number of exchange API invocations that failed, by type of error (transport or other).", "method", "resource", "type")
+
+const (
+	METRIC_ERROR_TRANSPORT = "transport"
+	METRIC_ERROR_OTHER     = "other"
+)
+
+func recordExchangeCall(method string, urlPath string, elapsed time.Duration, err error, tpErr error) {
+	resource := exchangeResourceLabel(urlPath)
+	exchangeCallDuration.WithLabelValues(method, resource).Observe(elapsed.Seconds())
+	if tpErr != nil {
+		exchangeCallErrors.WithLabelValues(method, resource, METRIC_ERROR_TRANSPORT).Inc()
+	} else if err != nil {
+		exchangeCallErrors.WithLabelValues(method, resource, METRIC_ERROR_OTHER).Inc()
+	}
+}
+
+// Reduce an exchange URL to the kinds of resources it refers to, dropping the org and the resource ids.
+func exchangeResourceLabel(urlPath string) string {
+	path := urlPath
+	if u, err := url.Parse(urlPath); err == nil {
+		path = u.Path
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for ix, seg := range segments {
+		if seg == "orgs" {
+			if ix+2 >= len(segments) {
+				return "orgs"
+			}
+			kinds := []string{}
+			for i := ix + 2; i < len(segments); i += 2 {
+				kinds = append(kinds, segments[i])
+			}
+			return strings.Join(kinds, "/")
+		}
+	}
+
+	// Not an org scoped resource, use the last segment of the path (e.g. admin/version).
+	if len(segments) == 0 || segments[len(segments)-1] == "" {
+		return "other"
+	}
+	return segments[len(segments)-1]
+}
@@ -249,6 +249,44 @@ func GetExchangeDevice(httpClientFactory *config.HTTPClientFactory, deviceId str
 	}
 }
 
+// Get all the devices in an org. The returned map is keyed by the org qualified device id.
+func GetExchangeOrgDevices(httpClientFactory *config.HTTPClientFactory, org string, credId string, credPasswd string, exchangeUrl string) (map[string]Device, error) {
+
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("retrieving devices in org %v from exchange", org)))
+
+	var resp interface{}
+	resp = new(GetDevicesResponse)
+	targetURL := exchangeUrl + "orgs/" + org + "/nodes"
+
+	retryCount := httpClientFactory.RetryCount
+	retryInterval := httpClientFactory.GetRetryInterval()
+	for {
+		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "GET", targetURL, credId, credPasswd, nil, &resp); err != nil {
+			glog.Errorf(err.Error())
+			return nil, err
+		} else if tpErr != nil {
+			glog.Warningf(rpclogString(fmt.Sprintf(tpErr.Error())))
+			if httpClientFactory.RetryCount == 0 {
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			} else if retryCount == 0 {
+				return nil, fmt.Errorf("Exceeded %v retries for error: %v", httpClientFactory.RetryCount, tpErr)
+			} else {
+				retryCount--
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			}
+		} else {
+			devs := resp.(*GetDevicesResponse).Devices
+			if devs == nil {
+				devs = make(map[string]Device)
+			}
+			glog.V(3).Infof(rpclogString(fmt.Sprintf("retrieved %v devices in org %v from exchange", len(devs), org)))
+			return devs, nil
+		}
+	}
+}
+
 // modify the the device
 func PutExchangeDevice(httpClientFactory *config.HTTPClientFactory, deviceId string, deviceToken string, exchangeUrl string, pdr *PutDeviceRequest) (*PutDeviceResponse, error) {
 	// create PUT body
@@ -979,7 +1017,15 @@ func DeleteSurfaceErrors(ec ExchangeContext, deviceId string) error {
 
 // This function is used to invoke an exchange API
 // For GET, the given resp parameter will be untouched when http returns code 404.
+// The latency and the outcome of each invocation are recorded in the exchange call metrics.
 func InvokeExchange(httpClient *http.Client, method string, urlPath string, user string, pw string, params interface{}, resp *interface{}) (error, error) {
+	start := time.Now()
+	err, tpErr := invokeExchange(httpClient, method, urlPath, user, pw, params, resp)
+	recordExchangeCall(method, urlPath, time.Since(start), err, tpErr)
+	return err, tpErr
+}
+
+func invokeExchange(httpClient *http.Client, method string, urlPath string, user string, pw string, params interface{}, resp *interface{}) (error, error) {
 
 	if len(method) == 0 {
 		return errors.New(fmt.Sprintf("Error invoking exchange, method name must be specified")), nil
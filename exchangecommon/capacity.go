@@ -0,0 +1,51 @@
+package exchangecommon
+
+import (
+	"fmt"
+)
+
+// The resource capacity of a node. The agent uses it to reject the proposals that would overcommit the node, and the
+// agbot uses it to match the remaining capacity of the node against the constraints of the deployment policies. The
+// committed resources are the memory and CPU limits (max_memory_mb and max_cpus) of the service containers that run on
+// the node or that will run for the agreements the node has made. A container without a limit does not count.
+type NodeCapacity struct {
+	MemoryMb          int64   `json:"memoryMb"`          // the memory of the node in MB, 0 if it is unknown
+	CPUs              float64 `json:"cpus"`              // the number of CPUs of the node, 0 if it is unknown
+	CommittedMemoryMb int64   `json:"committedMemoryMb"` // the memory in MB that the services are limited to
+	CommittedCPUs     float64 `json:"committedCpus"`     // the CPUs that the services are limited to
+}
+
+func (c NodeCapacity) String() string {
+	return fmt.Sprintf("MemoryMb: %v, CPUs: %v, CommittedMemoryMb: %v, CommittedCPUs: %v", c.MemoryMb, c.CPUs, c.CommittedMemoryMb, c.CommittedCPUs)
+}
+
+func (c NodeCapacity) IsSame(other *NodeCapacity) bool {
+	return other != nil && c == *other
+}
+
+// Returns the memory in MB that is not committed yet.
+func (c NodeCapacity) AvailableMemoryMb() int64 {
+	if c.CommittedMemoryMb >= c.MemoryMb {
+		return 0
+	}
+	return c.MemoryMb - c.CommittedMemoryMb
+}
+
+// Returns the CPUs that are not committed yet.
+func (c NodeCapacity) AvailableCPUs() float64 {
+	if c.CommittedCPUs >= c.CPUs {
+		return 0
+	}
+	return c.CPUs - c.CommittedCPUs
+}
+
+// Returns an error if the requested memory and CPUs do not fit in the capacity that is not committed yet. A resource
+// whose capacity is unknown is not checked.
+func (c NodeCapacity) Admit(memoryMb int64, cpus float64) error {
+	if c.MemoryMb != 0 && memoryMb != 0 && memoryMb > c.AvailableMemoryMb() {
+		return fmt.Errorf("the requested memory %v MB exceeds the available memory %v MB of the node (%v MB committed of %v MB)", memoryMb, c.AvailableMemoryMb(), c.CommittedMemoryMb, c.MemoryMb)
+	} else if c.CPUs != 0 && cpus != 0 && cpus > c.AvailableCPUs() {
+		return fmt.Errorf("the requested %v CPUs exceed the %v available CPUs of the node (%v committed of %v)", cpus, c.AvailableCPUs(), c.CommittedCPUs, c.CPUs)
+	}
+	return nil
+}
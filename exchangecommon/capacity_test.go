@@ -0,0 +1,41 @@
+//go:build unit
+// +build unit
+
+package exchangecommon
+
+import (
+	"testing"
+)
+
+func Test_NodeCapacity_Admit(t *testing.T) {
+
+	c := NodeCapacity{MemoryMb: 1024, CPUs: 2, CommittedMemoryMb: 768, CommittedCPUs: 1.5}
+
+	if c.AvailableMemoryMb() != 256 {
+		t.Errorf("wrong available memory: %v", c.AvailableMemoryMb())
+	} else if c.AvailableCPUs() != 0.5 {
+		t.Errorf("wrong available CPUs: %v", c.AvailableCPUs())
+	}
+
+	if err := c.Admit(256, 0.5); err != nil {
+		t.Errorf("a request that fits should be admitted: %v", err)
+	} else if err := c.Admit(0, 0); err != nil {
+		t.Errorf("a request without limits should be admitted: %v", err)
+	} else if err := c.Admit(512, 0); err == nil {
+		t.Errorf("a request that overcommits the memory should not be admitted")
+	} else if err := c.Admit(0, 1); err == nil {
+		t.Errorf("a request that overcommits the CPUs should not be admitted")
+	}
+
+	// an overcommitted node has nothing available
+	c = NodeCapacity{MemoryMb: 1024, CPUs: 2, CommittedMemoryMb: 2048, CommittedCPUs: 4}
+	if c.AvailableMemoryMb() != 0 || c.AvailableCPUs() != 0 {
+		t.Errorf("an overcommitted node should not have available resources: %v", c)
+	}
+
+	// a resource whose capacity is unknown is not checked
+	c = NodeCapacity{CommittedMemoryMb: 2048, CommittedCPUs: 4}
+	if err := c.Admit(512, 1); err != nil {
+		t.Errorf("a request should be admitted when the capacity is unknown: %v", err)
+	}
+}
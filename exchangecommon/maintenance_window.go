@@ -0,0 +1,215 @@
+package exchangecommon
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The longest a maintenance window can stay open, in seconds.
+const MAX_MAINTENANCE_WINDOW_DURATION = 7 * 24 * 3600
+
+// A recurring window of time in which the agent is allowed to disrupt the workloads running on the node, for example
+// to upgrade a service, to re-make an agreement that is out of policy or to upgrade the agent itself. The window opens
+// at the times matched by the cron expression and stays open for the given duration.
+type MaintenanceWindow struct {
+	Cron     string `json:"cron"`               // standard 5 field cron expression (minute hour day-of-month month day-of-week) for when the window opens
+	Duration int    `json:"duration"`           // how long the window stays open, in seconds
+	Timezone string `json:"timezone,omitempty"` // the IANA time zone of the cron expression, the default is UTC
+}
+
+func (m MaintenanceWindow) String() string {
+	return fmt.Sprintf("Cron: %v, Duration: %v, Timezone: %v", m.Cron, m.Duration, m.Timezone)
+}
+
+func (m *MaintenanceWindow) Validate() error {
+	if _, err := parseCron(m.Cron); err != nil {
+		return fmt.Errorf("maintenance window cron expression %v is not valid: %v", m.Cron, err)
+	} else if m.Duration <= 0 || m.Duration > MAX_MAINTENANCE_WINDOW_DURATION {
+		return fmt.Errorf("maintenance window duration %v must be between 1 and %v seconds", m.Duration, MAX_MAINTENANCE_WINDOW_DURATION)
+	} else if _, err := m.location(); err != nil {
+		return fmt.Errorf("maintenance window timezone %v is not valid: %v", m.Timezone, err)
+	}
+	return nil
+}
+
+func (m *MaintenanceWindow) location() (*time.Location, error) {
+	if m.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(m.Timezone)
+}
+
+// The parsed schedules and locations of the maintenance windows, keyed by cron expression and time zone. The node
+// policy is read from the database every time it is checked, so the windows are parsed once here instead.
+var parsedWindows = struct {
+	lock      sync.Mutex
+	schedules map[string]*cronSchedule
+	locations map[string]*time.Location
+}{
+	schedules: make(map[string]*cronSchedule),
+	locations: make(map[string]*time.Location),
+}
+
+// Returns the parsed schedule and location of the window, parsing them only the first time they are seen.
+func (m *MaintenanceWindow) parsed() (*cronSchedule, *time.Location, error) {
+	parsedWindows.lock.Lock()
+	defer parsedWindows.lock.Unlock()
+
+	sched, ok := parsedWindows.schedules[m.Cron]
+	if !ok {
+		var err error
+		if sched, err = parseCron(m.Cron); err != nil {
+			return nil, nil, err
+		}
+		parsedWindows.schedules[m.Cron] = sched
+	}
+	loc, ok := parsedWindows.locations[m.Timezone]
+	if !ok {
+		var err error
+		if loc, err = m.location(); err != nil {
+			return nil, nil, err
+		}
+		parsedWindows.locations[m.Timezone] = loc
+	}
+	return sched, loc, nil
+}
+
+// Returns true if the window is open at the given time, which is when the cron expression matched a minute within the
+// last Duration seconds. The days and hours that do not match are skipped as a whole.
+func (m *MaintenanceWindow) IsOpen(t time.Time) bool {
+	sched, loc, err := m.parsed()
+	if err != nil {
+		return false
+	}
+
+	t = t.In(loc)
+	start := t.Add(-time.Duration(m.Duration) * time.Second)
+	minute := t.Truncate(time.Minute)
+	for minute.After(start) {
+		if !sched.matchesDay(minute) {
+			minute = time.Date(minute.Year(), minute.Month(), minute.Day(), 0, 0, 0, 0, loc).Add(-time.Minute)
+		} else if !sched.hour[minute.Hour()] {
+			minute = time.Date(minute.Year(), minute.Month(), minute.Day(), minute.Hour(), 0, 0, 0, loc).Add(-time.Minute)
+		} else if sched.minute[minute.Minute()] {
+			return true
+		} else {
+			minute = minute.Add(-time.Minute)
+		}
+	}
+	return false
+}
+
+// Returns true if disruptive changes are allowed at the given time. Changes are always allowed when there are no
+// maintenance windows.
+func InMaintenanceWindow(windows []MaintenanceWindow, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		if w.IsOpen(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// The parsed form of a cron expression. Each field is the set of values that match.
+type cronSchedule struct {
+	minute map[int]bool
+	hour   map[int]bool
+	dom    map[int]bool
+	month  map[int]bool
+	dow    map[int]bool
+	anyDom bool
+	anyDow bool
+}
+
+// Returns true if the month and the day of the given time match.
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	if !c.month[int(t.Month())] {
+		return false
+	}
+
+	// Like standard cron, when both the day of month and the day of week are restricted, either one can match.
+	domMatch := c.dom[t.Day()]
+	dowMatch := c.dow[int(t.Weekday())]
+	if c.anyDom || c.anyDow {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.New("expected 5 fields: minute hour day-of-month month day-of-week")
+	}
+
+	var err error
+	sched := new(cronSchedule)
+	if sched.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %v", err)
+	} else if sched.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %v", err)
+	} else if sched.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day-of-month: %v", err)
+	} else if sched.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %v", err)
+	} else if sched.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day-of-week: %v", err)
+	}
+
+	// Sunday is both 0 and 7.
+	if sched.dow[7] {
+		sched.dow[0] = true
+	}
+	// Like standard cron, a field that starts with "*" is not a restriction, even with a step such as "*/2".
+	sched.anyDom = strings.HasPrefix(fields[2], "*")
+	sched.anyDow = strings.HasPrefix(fields[4], "*")
+	return sched, nil
+}
+
+// Parse one cron field, which is a comma separated list of "*", "n" or "n-m", each optionally followed by "/step".
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if ix := strings.Index(part, "/"); ix >= 0 {
+			var err error
+			rangePart = part[:ix]
+			if step, err = strconv.Atoi(part[ix+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %v", part)
+			}
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %v", part)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid range %v", part)
+				}
+			} else if step != 1 {
+				// "n/step" means from n to the maximum.
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("%v is out of the range %v-%v", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,142 @@
+//go:build unit
+// +build unit
+
+package exchangecommon
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_MaintenanceWindow_Validate(t *testing.T) {
+
+	valid := []MaintenanceWindow{
+		MaintenanceWindow{Cron: "0 22 * * 1-5", Duration: 3600},
+		MaintenanceWindow{Cron: "*/15 0-6 1,15 * *", Duration: 600, Timezone: "America/New_York"},
+		MaintenanceWindow{Cron: "30 2 * 1-12/2 7", Duration: MAX_MAINTENANCE_WINDOW_DURATION},
+	}
+	for _, mw := range valid {
+		if err := mw.Validate(); err != nil {
+			t.Errorf("maintenance window %v should be valid but got error: %v", mw, err)
+		}
+	}
+
+	invalid := []MaintenanceWindow{
+		MaintenanceWindow{Cron: "0 22 * *", Duration: 3600},
+		MaintenanceWindow{Cron: "60 22 * * *", Duration: 3600},
+		MaintenanceWindow{Cron: "0 24 * * *", Duration: 3600},
+		MaintenanceWindow{Cron: "0 0 0 * *", Duration: 3600},
+		MaintenanceWindow{Cron: "0 0 * 13 *", Duration: 3600},
+		MaintenanceWindow{Cron: "0 0 * * 8", Duration: 3600},
+		MaintenanceWindow{Cron: "0 5-1 * * *", Duration: 3600},
+		MaintenanceWindow{Cron: "*/0 * * * *", Duration: 3600},
+		MaintenanceWindow{Cron: "a * * * *", Duration: 3600},
+		MaintenanceWindow{Cron: "0 22 * * *", Duration: 0},
+		MaintenanceWindow{Cron: "0 22 * * *", Duration: MAX_MAINTENANCE_WINDOW_DURATION + 1},
+		MaintenanceWindow{Cron: "0 22 * * *", Duration: 3600, Timezone: "Not/AZone"},
+	}
+	for _, mw := range invalid {
+		if err := mw.Validate(); err == nil {
+			t.Errorf("maintenance window %v should not be valid", mw)
+		}
+	}
+}
+
+func Test_MaintenanceWindow_IsOpen(t *testing.T) {
+
+	// weekdays from 22:00 for 4 hours. 2021-06-07 is a Monday.
+	mw := MaintenanceWindow{Cron: "0 22 * * 1-5", Duration: 4 * 3600}
+	open := []time.Time{
+		time.Date(2021, 6, 7, 22, 0, 0, 0, time.UTC),
+		time.Date(2021, 6, 7, 23, 59, 0, 0, time.UTC),
+		time.Date(2021, 6, 8, 1, 59, 59, 0, time.UTC),
+		time.Date(2021, 6, 12, 1, 0, 0, 0, time.UTC), // opened on Friday night
+	}
+	closed := []time.Time{
+		time.Date(2021, 6, 7, 21, 59, 0, 0, time.UTC),
+		time.Date(2021, 6, 8, 2, 0, 0, 0, time.UTC),
+		time.Date(2021, 6, 7, 12, 0, 0, 0, time.UTC),
+		time.Date(2021, 6, 12, 22, 30, 0, 0, time.UTC), // Saturday
+	}
+	for _, tm := range open {
+		if !mw.IsOpen(tm) {
+			t.Errorf("maintenance window %v should be open at %v", mw, tm)
+		}
+	}
+	for _, tm := range closed {
+		if mw.IsOpen(tm) {
+			t.Errorf("maintenance window %v should be closed at %v", mw, tm)
+		}
+	}
+
+	// the cron expression is evaluated in the time zone of the window
+	mw = MaintenanceWindow{Cron: "0 22 * * *", Duration: 3600, Timezone: "Asia/Tokyo"}
+	if !mw.IsOpen(time.Date(2021, 6, 7, 13, 30, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be open at 13:30 UTC", mw)
+	} else if mw.IsOpen(time.Date(2021, 6, 7, 22, 30, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be closed at 22:30 UTC", mw)
+	}
+
+	// when both the day of month and the day of week are restricted, either one opens the window
+	mw = MaintenanceWindow{Cron: "0 0 1 * 0", Duration: 3600}
+	if !mw.IsOpen(time.Date(2021, 6, 1, 0, 30, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be open on the first of the month", mw)
+	} else if !mw.IsOpen(time.Date(2021, 6, 6, 0, 30, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be open on Sunday", mw)
+	} else if mw.IsOpen(time.Date(2021, 6, 2, 0, 30, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be closed on a Wednesday that is not the first", mw)
+	}
+
+	// a day of month with a step is not a restriction, so both days have to match
+	mw = MaintenanceWindow{Cron: "0 0 */2 * 1", Duration: 3600}
+	if !mw.IsOpen(time.Date(2021, 6, 7, 0, 30, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be open on Monday the 7th", mw)
+	} else if mw.IsOpen(time.Date(2021, 6, 14, 0, 30, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be closed on Monday the 14th", mw)
+	} else if mw.IsOpen(time.Date(2021, 6, 3, 0, 30, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be closed on Thursday the 3rd", mw)
+	}
+
+	// the hours are skipped in the time zone of the window, which can be off by half an hour from UTC
+	mw = MaintenanceWindow{Cron: "45 1 * * *", Duration: 600, Timezone: "Asia/Kolkata"}
+	if !mw.IsOpen(time.Date(2021, 6, 6, 20, 20, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be open at 20:20 UTC", mw)
+	} else if mw.IsOpen(time.Date(2021, 6, 6, 20, 10, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be closed at 20:10 UTC", mw)
+	}
+
+	// a window that opened at the start of the longest duration is still open
+	mw = MaintenanceWindow{Cron: "0 0 1 6 *", Duration: MAX_MAINTENANCE_WINDOW_DURATION}
+	if !mw.IsOpen(time.Date(2021, 6, 7, 23, 59, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be open a week after it opened", mw)
+	} else if mw.IsOpen(time.Date(2021, 6, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("maintenance window %v should be closed after a week", mw)
+	}
+}
+
+func Test_InMaintenanceWindow(t *testing.T) {
+
+	tm := time.Date(2021, 6, 7, 12, 0, 0, 0, time.UTC)
+
+	var nilPol *NodePolicy
+	if !nilPol.InMaintenanceWindow(tm) {
+		t.Errorf("a missing node policy should allow changes at any time")
+	}
+
+	pol := &NodePolicy{}
+	if !pol.InMaintenanceWindow(tm) {
+		t.Errorf("a node policy without maintenance windows should allow changes at any time")
+	}
+
+	pol.MaintenanceWindows = []MaintenanceWindow{MaintenanceWindow{Cron: "0 22 * * *", Duration: 3600}}
+	if pol.InMaintenanceWindow(tm) {
+		t.Errorf("node policy %v should not allow changes at %v", pol, tm)
+	}
+
+	pol.MaintenanceWindows = append(pol.MaintenanceWindows, MaintenanceWindow{Cron: "0 11 * * *", Duration: 7200})
+	if !pol.InMaintenanceWindow(tm) {
+		t.Errorf("node policy %v should allow changes at %v", pol, tm)
+	} else if copyPol := pol.DeepCopy(); len(copyPol.MaintenanceWindows) != 2 {
+		t.Errorf("the copy of node policy %v should have the maintenance windows", pol)
+	}
+}
@@ -3,6 +3,7 @@ package exchangecommon
 import (
 	"fmt"
 	"github.com/open-horizon/anax/externalpolicy"
+	"time"
 )
 
 const NODEPOLICY_VERSION_VERSION_2 = "v2"
@@ -20,10 +21,14 @@ type NodePolicy struct {
 	externalpolicy.ExternalPolicy                               // top level properties and constraints,
 	Deployment                    externalpolicy.ExternalPolicy `json:"deployment,omitempty"` // properties and constrians for deopoyment
 	Management                    externalpolicy.ExternalPolicy `json:"management,omitempty"` // properties and constrians for node management
+
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"` // when the agent is allowed to disrupt the node's workloads, any time if empty
+
+	IsolatedClusterNamespaces bool `json:"isolatedClusterNamespaces,omitempty"` // install the operator of each agreement of a cluster service into a namespace of its own
 }
 
 func (n NodePolicy) String() string {
-	return fmt.Sprintf("NodePolicy: Label: %v, Description: %v, Properties: %v, Constraints: %v, Deployment: %v, Management: %v", n.Label, n.Description, n.Properties, n.Constraints, n.Deployment, n.Management)
+	return fmt.Sprintf("NodePolicy: Label: %v, Description: %v, Properties: %v, Constraints: %v, Deployment: %v, Management: %v, MaintenanceWindows: %v, IsolatedClusterNamespaces: %v", n.Label, n.Description, n.Properties, n.Constraints, n.Deployment, n.Management, n.MaintenanceWindows, n.IsolatedClusterNamespaces)
 }
 
 // This function validates the properties and constrains. It also updates the node's
@@ -42,6 +47,12 @@ func (n *NodePolicy) ValidateAndNormalize() error {
 		return err
 	}
 
+	for ix := range n.MaintenanceWindows {
+		if err := n.MaintenanceWindows[ix].Validate(); err != nil {
+			return err
+		}
+	}
+
 	// We only get here if the input object is nil OR all of the top level fields are empty.
 	return nil
 }
@@ -60,6 +71,13 @@ func (n NodePolicy) DeepCopy() *NodePolicy {
 
 	copyN.Management = *(n.Management.DeepCopy())
 
+	if n.MaintenanceWindows != nil {
+		copyN.MaintenanceWindows = make([]MaintenanceWindow, len(n.MaintenanceWindows))
+		copy(copyN.MaintenanceWindows, n.MaintenanceWindows)
+	}
+
+	copyN.IsolatedClusterNamespaces = n.IsolatedClusterNamespaces
+
 	return &copyN
 }
 
@@ -120,6 +138,14 @@ func (n NodePolicy) GetManagementPolicy() *externalpolicy.ExternalPolicy {
 	return &copyE
 }
 
+// Returns true if the agent is allowed to disrupt the node's workloads at the given time.
+func (n *NodePolicy) InMaintenanceWindow(t time.Time) bool {
+	if n == nil {
+		return true
+	}
+	return InMaintenanceWindow(n.MaintenanceWindows, t)
+}
+
 // Convert the node policy from version v1 to version v2.
 // It keeps the node built-in properties on the top level and move other properties and
 // all the constraints to the Deployment level.
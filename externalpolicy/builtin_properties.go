@@ -23,6 +23,14 @@ const (
 	PROP_NODE_OS            = "openhorizon.operatingSystem"   // The operating system the agent is installed on. For containerized agents, this is the host os
 	PROP_NODE_CONTAINERIZED = "openhorizon.containerized"     // Boolean field indicating whether the agent is running in a container
 
+	// Property set to list the restricted container options (e.g. sysctls) that services may use on this device. Can be set by user, default is none.
+	PROP_NODE_CONTAINER_OPTIONS = "openhorizon.allowedContainerOptions"
+
+	// The memory in MBs and the number of CPUs of the node that are not committed to the services of its agreements yet.
+	// They are computed by the agbot from its agreements with the node when a deployment policy has a constraint on them.
+	PROP_NODE_MEMORY_AVAILABLE = "openhorizon.memory.available"
+	PROP_NODE_CPU_AVAILABLE    = "openhorizon.cpu.available"
+
 	// for install type
 	OS_CLUSTER   = "cluster"
 	OS_CONTAINER = "anax-in-container"
@@ -47,7 +55,7 @@ const (
 const MAX_MEMEORY = 1048576 // the unit is MB. This is 1000G
 
 func ListReadOnlyProperties() []string {
-	return []string{PROP_NODE_CPU, PROP_NODE_ARCH, PROP_NODE_MEMORY, PROP_NODE_HARDWAREID, PROP_NODE_K8S_VERSION, PROP_NODE_OS, PROP_NODE_CONTAINERIZED}
+	return []string{PROP_NODE_CPU, PROP_NODE_ARCH, PROP_NODE_MEMORY, PROP_NODE_HARDWAREID, PROP_NODE_K8S_VERSION, PROP_NODE_OS, PROP_NODE_CONTAINERIZED, PROP_NODE_MEMORY_AVAILABLE, PROP_NODE_CPU_AVAILABLE}
 }
 
 func ListSupportedOperatingSystems() []string {
@@ -215,7 +223,10 @@ func IsNodeBuiltinPropertyName(propName string) bool {
 		propName == PROP_NODE_PRIVILEGED ||
 		propName == PROP_NODE_K8S_VERSION ||
 		propName == PROP_NODE_OS ||
-		propName == PROP_NODE_CONTAINERIZED {
+		propName == PROP_NODE_CONTAINERIZED ||
+		propName == PROP_NODE_CONTAINER_OPTIONS ||
+		propName == PROP_NODE_MEMORY_AVAILABLE ||
+		propName == PROP_NODE_CPU_AVAILABLE {
 		return true
 	} else {
 		return false
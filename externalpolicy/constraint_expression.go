@@ -1,6 +1,8 @@
 package externalpolicy
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/externalpolicy/plugin_registry"
 	"strings"
@@ -9,14 +11,58 @@ import (
 // This type implements all the ConstraintLanguage Plugin methods and delegates to plugin system.
 type ConstraintExpression []string
 
+// Each constraint is validated by the language it is written in, so a constraint expression can mix constraints
+// written in different languages.
 func (c *ConstraintExpression) Validate() ([]string, error) {
-	return plugin_registry.ConstraintLanguagePlugins.ValidatedByOne((*c).GetStrings())
+	if len(*c) == 0 {
+		return plugin_registry.ConstraintLanguagePlugins.ValidatedByOne((*c).GetStrings())
+	}
+
+	validConstraints := make([]string, 0, len(*c))
+	for _, constraint := range *c {
+		if valid, err := plugin_registry.ConstraintLanguagePlugins.ValidatedByOne([]string{constraint}); err != nil {
+			return nil, err
+		} else {
+			validConstraints = append(validConstraints, valid...)
+		}
+	}
+	return validConstraints, nil
 }
 
 func (c *ConstraintExpression) GetLanguageHandler() (plugin_registry.ConstraintLanguagePlugin, error) {
 	return plugin_registry.ConstraintLanguagePlugins.GetLanguageHandlerByOne((*c).GetStrings())
 }
 
+// Constraints written in a structured language, like the json language, can be embedded in a policy as JSON objects
+// instead of strings. They are converted to strings so that the rest of the system handles all languages the same way.
+func (c *ConstraintExpression) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	} else if raw == nil {
+		(*c) = nil
+		return nil
+	}
+
+	constraints := make([]string, 0, len(raw))
+	for _, r := range raw {
+		var constraint string
+		if err := json.Unmarshal(r, &constraint); err == nil {
+			constraints = append(constraints, constraint)
+		} else if trimmed := bytes.TrimSpace(r); len(trimmed) != 0 && trimmed[0] == '{' {
+			compact := new(bytes.Buffer)
+			if err := json.Compact(compact, trimmed); err != nil {
+				return err
+			}
+			constraints = append(constraints, compact.String())
+		} else {
+			return fmt.Errorf("constraint %v is expected to be a string or a JSON object", string(r))
+		}
+	}
+	(*c) = constraints
+	return nil
+}
+
 // Create a simple, empty ConstraintExpression Object.
 func Constraint_Factory() *ConstraintExpression {
 	ce := new(ConstraintExpression)
@@ -132,8 +178,8 @@ func RequiredPropertyFromConstraint(extConstraint *ConstraintExpression) (*Requi
 	for _, remainder := range *extConstraint {
 		remainder := strings.Replace(remainder, "\a", " ", -1)
 
-		// Get a handle to the specific language handler we will be using.
-		handler, err = extConstraint.GetLanguageHandler()
+		// Get a handle to the specific language handler we will be using. Each constraint can be written in a different language.
+		handler, err = plugin_registry.ConstraintLanguagePlugins.GetLanguageHandlerByOne([]string{remainder})
 		if err != nil {
 			return nil, fmt.Errorf("unable to obtain policy constraint language handler, error %v", err)
 		}
@@ -4,6 +4,8 @@
 package externalpolicy
 
 import (
+	"encoding/json"
+	_ "github.com/open-horizon/anax/externalpolicy/json_language"
 	_ "github.com/open-horizon/anax/externalpolicy/text_language"
 	"testing"
 )
@@ -338,3 +340,62 @@ func Test_MergeWith(t *testing.T) {
 		t.Errorf("Error: constraints %v should have 4 elements but got %v", ce1, len(*ce1))
 	}
 }
+
+// Verify that constraints written in the json language are satisfied by the same properties as the equivalent text
+// constraints, and that the languages can be mixed in one constraint expression.
+func Test_json_language_IsSatisfiedBy(t *testing.T) {
+
+	pairs := [][]string{
+		[]string{"prop == true && prop2 == \"a b\"",
+			`{"and": [{"property": "prop", "value": true}, {"property": "prop2", "value": "a b"}]}`},
+		[]string{"iame2edev == true && cpu == 3 || memory <= 32",
+			`{"or": [{"and": [{"property": "iame2edev", "value": true}, {"property": "cpu", "value": 3}]}, {"property": "memory", "op": "<=", "value": 32}]}`},
+		[]string{"eggs == \"truckload\" AND certification in \"USDA,Organic\"",
+			`{"and": [{"property": "eggs", "value": "truckload"}, {"property": "certification", "op": "in", "value": ["USDA", "Organic"]}]}`},
+		[]string{"version in [1.1.1,INFINITY) OR cert == USDA",
+			`{"or": [{"property": "version", "op": "in", "value": "[1.1.1,INFINITY)"}, {"property": "cert", "value": "USDA"}]}`},
+		[]string{"memory >= 64 || (cpu == 3 && hello != world)",
+			`{"or": [{"property": "memory", "op": ">=", "value": 64}, {"and": [{"property": "cpu", "value": 3}, {"property": "hello", "op": "!=", "value": "world"}]}]}`},
+	}
+
+	satisfying := `[{"name":"prop", "value":true},{"name":"prop2", "value":"a b"},{"name":"iame2edev", "value":true},{"name":"cpu", "value":3},{"name":"memory", "value":32},{"name":"hello", "value":"there"},{"name":"eggs","value":"truckload"},{"name":"certification","value":"USDA"},{"name":"version","value":"1.2.1","type":"version"}]`
+	notSatisfying := `[{"name":"prop", "value":false},{"name":"prop2", "value":"a b"},{"name":"iame2edev", "value":false},{"name":"cpu", "value":3},{"name":"memory", "value":33},{"name":"hello", "value":"world"},{"name":"eggs","value":"truckload"},{"name":"certification","value":"other"},{"name":"version","value":"1.0.0","type":"version"}]`
+
+	for _, pair := range pairs {
+		text := ConstraintExpression{pair[0]}
+		js := ConstraintExpression{pair[1]}
+		if _, err := js.Validate(); err != nil {
+			t.Errorf("Error: constraint %v should be valid, error: %v", js, err)
+		}
+		for _, propList := range []string{satisfying, notSatisfying} {
+			props := create_property_list(propList, t)
+			textErr := text.IsSatisfiedBy(*props)
+			jsErr := js.IsSatisfiedBy(*props)
+			if (textErr == nil) != (jsErr == nil) {
+				t.Errorf("Error: constraint %v and %v should have the same result for %v, text: %v json: %v", text, js, propList, textErr, jsErr)
+			}
+		}
+	}
+
+	// mix both languages in one constraint expression
+	ce := ConstraintExpression{pairs[0][0], pairs[1][1], pairs[3][0], pairs[4][1]}
+	if _, err := ce.Validate(); err != nil {
+		t.Errorf("Error: constraint %v should be valid, error: %v", ce, err)
+	} else if err := ce.IsSatisfiedBy(*create_property_list(satisfying, t)); err != nil {
+		t.Errorf("Error: constraint %v should be satisfied, error: %v", ce, err)
+	} else if err := ce.IsSatisfiedBy(*create_property_list(notSatisfying, t)); err == nil {
+		t.Errorf("Error: constraint %v should not be satisfied", ce)
+	}
+
+	// json constraints can be embedded in a policy as objects
+	ce = ConstraintExpression{}
+	if err := json.Unmarshal([]byte(`["prop == true", {"property": "cpu", "op": ">", "value": 2}]`), &ce); err != nil {
+		t.Errorf("Error: unable to unmarshal constraints, error: %v", err)
+	} else if len(ce) != 2 || ce[1] != `{"property":"cpu","op":">","value":2}` {
+		t.Errorf("Error: wrong constraints: %v", ce)
+	} else if err := ce.IsSatisfiedBy(*create_property_list(satisfying, t)); err != nil {
+		t.Errorf("Error: constraint %v should be satisfied, error: %v", ce, err)
+	} else if err := json.Unmarshal([]byte(`["prop == true", 5]`), &ce); err == nil {
+		t.Errorf("Error: a number should not be a valid constraint")
+	}
+}
@@ -84,6 +84,19 @@ func (e *ExternalPolicy) ValidateAndNormalize() error {
 		}
 	}
 
+	// PROP_NODE_CONTAINER_OPTIONS is a comma separated list of container options, make sure it is compared as a list
+	if e.Properties.HasProperty(PROP_NODE_CONTAINER_OPTIONS) {
+		optsProp, err := e.Properties.GetProperty(PROP_NODE_CONTAINER_OPTIONS)
+		if err != nil {
+			return err
+		}
+		if optsStr, ok := optsProp.Value.(string); !ok {
+			return errors.New(msgPrinter.Sprintf("Property %s must be a comma separated list of container options.", PROP_NODE_CONTAINER_OPTIONS))
+		} else if optsProp.Type != LIST_TYPE {
+			e.Properties.Add_Property(&Property{Name: PROP_NODE_CONTAINER_OPTIONS, Value: optsStr, Type: LIST_TYPE}, true)
+		}
+	}
+
 	// Validate the Constraints expression by invoking the plugins.
 	if e != nil && len(e.Constraints) != 0 {
 		_, err := e.Constraints.Validate()
@@ -0,0 +1,303 @@
+package json_language
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/externalpolicy/plugin_registry"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/semanticversion"
+	"strconv"
+	"strings"
+)
+
+// The json constraint language expresses a constraint as a JSON document instead of a text expression, so that tools
+// which generate policies do not have to build and escape text constraint strings. A constraint is either a property
+// expression or a control operator with a list of constraints:
+//
+//   {"property": "memory", "op": ">=", "value": 1024}
+//   {"and": [ {"property": "location", "op": "in", "value": ["east", "west"]}, {"or": [ ... ]} ]}
+//
+// The constraint is converted into the same stream of property expressions and control operators that the text
+// language produces, so the policy compatibility checks work the same way for both languages.
+
+func init() {
+	plugin_registry.Register("json", NewJSONConstraintLanguagePlugin())
+}
+
+const OP_AND = "and"
+const OP_OR = "or"
+
+// The operators supported in a property expression, the same as the text language.
+var propertyOperators = map[string]bool{"==": true, "=": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true, "in": true}
+
+// The converted constraint is a list of tokens, each token is either a control operator or a property expression
+// in the form name\aop\avalue. The separator starts the list so that a converted constraint is never mistaken for
+// a JSON document.
+const tokenSeparator = "\x1f"
+
+type JSONConstraintLanguagePlugin struct {
+}
+
+func NewJSONConstraintLanguagePlugin() plugin_registry.ConstraintLanguagePlugin {
+	return new(JSONConstraintLanguagePlugin)
+}
+
+// A node of the JSON constraint document. Exactly one of And, Or or Property is set.
+type constraintNode struct {
+	And      []constraintNode `json:"and,omitempty"`
+	Or       []constraintNode `json:"or,omitempty"`
+	Property string           `json:"property,omitempty"`
+	Op       string           `json:"op,omitempty"`
+	Value    interface{}      `json:"value,omitempty"`
+}
+
+// Returns true if the constraint string is a JSON constraint document.
+func IsJSONConstraint(constraint string) bool {
+	return strings.HasPrefix(strings.TrimSpace(constraint), "{")
+}
+
+// The plugin owns the constraints only when all of them are JSON documents, text constraints are left to the
+// text language plugin.
+func (p *JSONConstraintLanguagePlugin) Validate(dconstraints interface{}) (bool, []string, error) {
+
+	// get message printer because this function is called by CLI
+	msgPrinter := i18n.GetMessagePrinter()
+
+	constraints, ok := dconstraints.([]string)
+	if !ok || len(constraints) == 0 {
+		return false, []string{}, nil
+	}
+	for _, constraint := range constraints {
+		if !IsJSONConstraint(constraint) {
+			return false, []string{}, nil
+		}
+	}
+
+	validConstraints := make([]string, 0, len(constraints))
+	for _, constraint := range constraints {
+		if _, err := parseConstraint(constraint); err != nil {
+			return true, nil, errors.New(msgPrinter.Sprintf("Error validating the JSON constraint %v. Error was: %v", constraint, err))
+		}
+		validConstraints = append(validConstraints, constraint)
+	}
+
+	return true, validConstraints, nil
+}
+
+// This function returns the next property expression and the remainder of the constraint. When the constraint is a
+// JSON document, it is converted into a list of tokens first. An empty expression is returned when the next token
+// is a parenthesis, just like the text language.
+func (p *JSONConstraintLanguagePlugin) GetNextExpression(expression string) (string, string, error) {
+	tokens, err := getTokens(expression)
+	if err != nil || len(tokens) == 0 {
+		return "", "", err
+	}
+
+	if isPropertyExpression(tokens[0]) {
+		return tokens[0], joinTokens(tokens[1:]), nil
+	} else if tokens[0] == "(" || tokens[0] == ")" {
+		return "", joinTokens(tokens), nil
+	}
+	return "", expression, fmt.Errorf("Next expression not found: %v", tokens[0])
+}
+
+// This function returns the next control operator and the remainder of the constraint.
+func (p *JSONConstraintLanguagePlugin) GetNextOperator(expression string) (string, string, error) {
+	tokens, err := getTokens(expression)
+	if err != nil || len(tokens) == 0 {
+		return "", "", err
+	}
+
+	if !isPropertyExpression(tokens[0]) {
+		return tokens[0], joinTokens(tokens[1:]), nil
+	}
+	return "", expression, fmt.Errorf("No control operator found. Found: %v", tokens[0])
+}
+
+// Returns the tokens of a JSON constraint document or of a converted constraint.
+func getTokens(expression string) ([]string, error) {
+	if strings.HasPrefix(expression, tokenSeparator) {
+		return strings.Split(expression[len(tokenSeparator):], tokenSeparator), nil
+	} else if strings.TrimSpace(expression) == "" {
+		return nil, nil
+	} else if node, err := parseConstraint(expression); err != nil {
+		return nil, err
+	} else {
+		return node.tokens(true), nil
+	}
+}
+
+func joinTokens(tokens []string) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	return tokenSeparator + strings.Join(tokens, tokenSeparator)
+}
+
+func isPropertyExpression(token string) bool {
+	return strings.Contains(token, "\a")
+}
+
+// Parse and validate a JSON constraint document.
+func parseConstraint(constraint string) (*constraintNode, error) {
+	var node constraintNode
+	decoder := json.NewDecoder(bytes.NewReader([]byte(constraint)))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+	if err := decoder.Decode(&node); err != nil {
+		return nil, fmt.Errorf("unable to parse the constraint, error: %v", err)
+	} else if err := node.validate(); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+func (n *constraintNode) validate() error {
+	set := 0
+	if n.And != nil {
+		set++
+	}
+	if n.Or != nil {
+		set++
+	}
+	if n.Property != "" {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("each constraint must have exactly one of %v, %v or property", OP_AND, OP_OR)
+	}
+
+	if n.Property == "" {
+		if n.Op != "" || n.Value != nil {
+			return fmt.Errorf("a control operator cannot have an op or a value")
+		}
+		children := n.And
+		if n.Or != nil {
+			children = n.Or
+		}
+		if len(children) == 0 {
+			return fmt.Errorf("a control operator must have at least one constraint")
+		}
+		for _, c := range children {
+			if err := c.validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if strings.ContainsAny(n.Property, " \t\n\a") {
+		return fmt.Errorf("property name %v cannot contain whitespace", n.Property)
+	} else if _, err := n.valueString(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns the tokens of the constraint. Nested control operators are enclosed in parentheses.
+func (n *constraintNode) tokens(top bool) []string {
+	if n.Property != "" {
+		value, _ := n.valueString()
+		return []string{fmt.Sprintf("%v\a%v\a%v", n.Property, n.operator(), value)}
+	}
+
+	op := "&&"
+	children := n.And
+	if n.Or != nil {
+		op = "||"
+		children = n.Or
+	}
+
+	tokens := make([]string, 0)
+	if !top {
+		tokens = append(tokens, "(")
+	}
+	for i, c := range children {
+		if i != 0 {
+			tokens = append(tokens, op)
+		}
+		tokens = append(tokens, c.tokens(false)...)
+	}
+	if !top {
+		tokens = append(tokens, ")")
+	}
+	return tokens
+}
+
+// The operator defaults to == when it is omitted.
+func (n *constraintNode) operator() string {
+	if n.Op == "" {
+		return "=="
+	}
+	return n.Op
+}
+
+// Returns the value of a property expression in the form used by the text language, and checks that the operator is
+// valid for the value. The rules are the same as the text language, a list of strings and a version range can only be
+// used with the 'in' operator and the numerical comparison operators can only be used with numbers.
+func (n *constraintNode) valueString() (string, error) {
+	op := n.operator()
+	if !propertyOperators[op] {
+		return "", fmt.Errorf("operator %v of property %v is not supported", op, n.Property)
+	}
+	numOp := op == "<" || op == ">" || op == "<=" || op == ">="
+
+	switch v := n.Value.(type) {
+	case json.Number:
+		if op == "in" {
+			return "", fmt.Errorf("The 'in' operator can only be used for types version and list of strings")
+		}
+		return v.String(), nil
+
+	case bool:
+		if numOp || op == "in" {
+			return "", fmt.Errorf("Cannot use operator %s with value %v.", op, v)
+		}
+		return strconv.FormatBool(v), nil
+
+	case string:
+		if strings.Contains(v, "\a") || strings.Contains(v, "\"") {
+			return "", fmt.Errorf("value %v of property %v cannot contain double quotes", v, n.Property)
+		} else if numOp {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return "", fmt.Errorf("Cannot use numerical comparison operator %s with value %v.", op, v)
+			}
+			return v, nil
+		} else if semanticversion.IsVersionExpression(v) && !semanticversion.IsVersionString(v) {
+			if op != "in" {
+				return "", fmt.Errorf("Version range can only use operator 'in'.")
+			} else if _, err := semanticversion.Version_Expression_Factory(v); err != nil {
+				return "", err
+			}
+			return v, nil
+		} else if strings.Contains(v, ",") && op != "in" {
+			return "", fmt.Errorf("Property type list of strings can only use operator 'in'.")
+		} else if strings.ContainsAny(v, " \t,") {
+			return fmt.Sprintf("\"%v\"", v), nil
+		}
+		return v, nil
+
+	case []interface{}:
+		if op != "in" {
+			return "", fmt.Errorf("Property type list of strings can only use operator 'in'.")
+		} else if len(v) == 0 {
+			return "", fmt.Errorf("the list of values of property %v is empty", n.Property)
+		}
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); !ok || s == "" || strings.ContainsAny(s, ",\"\a") {
+				return "", fmt.Errorf("the list of values of property %v must only have strings without commas or double quotes, found %v", n.Property, e)
+			} else {
+				values = append(values, s)
+			}
+		}
+		return fmt.Sprintf("\"%v\"", strings.Join(values, ",")), nil
+
+	case nil:
+		return "", fmt.Errorf("property %v does not have a value", n.Property)
+	}
+
+	return "", fmt.Errorf("value %v of property %v is type %T, but is expected to be a string, number, boolean or a list of strings", n.Value, n.Property, n.Value)
+}
@@ -0,0 +1,111 @@
+//go:build unit
+// +build unit
+
+package json_language
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Validate_succeed(t *testing.T) {
+
+	p := NewJSONConstraintLanguagePlugin()
+
+	constraints := []string{
+		`{"property": "prop", "op": "==", "value": true}`,
+		`{"property": "prop", "value": "a b"}`,
+		`{"property": "memory", "op": ">=", "value": 1024}`,
+		`{"property": "memory", "op": "<", "value": "2048"}`,
+		`{"property": "location", "op": "in", "value": ["east", "west"]}`,
+		`{"property": "version", "op": "in", "value": "[1.0.0,INFINITY)"}`,
+		`{"and": [{"property": "prop", "value": 1}, {"or": [{"property": "prop2", "value": "a"}, {"property": "prop3", "op": "!=", "value": false}]}]}`,
+	}
+
+	if owned, valid, err := p.Validate(constraints); !owned {
+		t.Errorf("the json constraints should be owned by the plugin")
+	} else if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if len(valid) != len(constraints) {
+		t.Errorf("all the constraints should be valid: %v", valid)
+	}
+}
+
+func Test_Validate_fail(t *testing.T) {
+
+	p := NewJSONConstraintLanguagePlugin()
+
+	// text constraints and mixed lists are not owned by the json plugin
+	notOwned := [][]string{
+		[]string{},
+		[]string{"prop == true"},
+		[]string{`{"property": "prop", "value": true}`, "prop == true"},
+	}
+	for _, c := range notOwned {
+		if owned, _, err := p.Validate(c); owned || err != nil {
+			t.Errorf("constraints %v should not be owned, error: %v", c, err)
+		}
+	}
+
+	invalid := []string{
+		`{"property": "prop", "value": true`,
+		`{"property": "prop", "values": true}`,
+		`{"property": "prop"}`,
+		`{"property": "my prop", "value": true}`,
+		`{"property": "prop", "op": "~", "value": true}`,
+		`{"property": "prop", "op": ">", "value": "abc"}`,
+		`{"property": "prop", "op": ">", "value": true}`,
+		`{"property": "prop", "op": "in", "value": 5}`,
+		`{"property": "prop", "op": "==", "value": ["a", "b"]}`,
+		`{"property": "prop", "op": "in", "value": ["a", 5]}`,
+		`{"property": "prop", "op": "in", "value": []}`,
+		`{"property": "prop", "value": "a,b"}`,
+		`{"property": "prop", "value": "a\"b"}`,
+		`{"property": "version", "op": "==", "value": "[1.0.0,INFINITY)"}`,
+		`{"property": "prop", "value": {"a": "b"}}`,
+		`{"and": []}`,
+		`{"and": [{"property": "prop", "value": 1}], "or": [{"property": "prop", "value": 2}]}`,
+		`{"and": [{"property": "prop", "value": 1}], "value": 2}`,
+		`{"or": [{"property": "prop", "op": "<", "value": "a"}]}`,
+	}
+	for _, c := range invalid {
+		if owned, _, err := p.Validate([]string{c}); !owned || err == nil {
+			t.Errorf("constraint %v should be owned and invalid, owned: %v error: %v", c, owned, err)
+		}
+	}
+}
+
+func Test_GetNextExpression_GetNextOperator(t *testing.T) {
+
+	p := NewJSONConstraintLanguagePlugin()
+
+	// prop == 1 && ( prop2 == "a b" || location in "east,west" )
+	remainder := `{"and": [{"property": "prop", "value": 1}, {"or": [{"property": "prop2", "value": "a b"}, {"property": "location", "op": "in", "value": ["east", "west"]}]}]}`
+
+	expected := []string{"prop\a==\a1", "&&", "", "(", "prop2\a==\a\"a b\"", "||", "location\ain\a\"east,west\"", ")", "", ""}
+	for i, exp := range expected {
+		var token string
+		var err error
+		if i%2 == 0 {
+			token, remainder, err = p.GetNextExpression(remainder)
+		} else {
+			token, remainder, err = p.GetNextOperator(remainder)
+		}
+		if err != nil {
+			t.Errorf("unexpected error at token %v: %v", i, err)
+		} else if token != exp {
+			t.Errorf("token %v should be %v, but is %v", i, strings.Replace(exp, "\a", " ", -1), strings.Replace(token, "\a", " ", -1))
+		}
+	}
+
+	if remainder != "" {
+		t.Errorf("the remainder should be empty, but is %v", remainder)
+	}
+
+	// an operator is not an expression and an expression is not an operator
+	if _, _, err := p.GetNextOperator(`{"property": "prop", "value": 1}`); err == nil {
+		t.Errorf("an expression should not be returned as an operator")
+	} else if _, _, err := p.GetNextExpression(joinTokens([]string{"&&"})); err == nil {
+		t.Errorf("an operator should not be returned as an expression")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/structlog"
 )
 
 type StartGovernExecutionCommand struct {
@@ -16,6 +17,10 @@ func (g StartGovernExecutionCommand) ShortString() string {
 	return fmt.Sprintf("GovernExecutionCommand: AgreementId %v, AgreementProtocol %v, Deployment %v", g.AgreementId, g.AgreementProtocol, g.Deployment.ToString())
 }
 
+func (g StartGovernExecutionCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: g.AgreementProtocol, structlog.AGREEMENT_ID: g.AgreementId}
+}
+
 func (w *GovernanceWorker) NewStartGovernExecutionCommand(deployment persistence.DeploymentConfig, protocol string, agreementId string) *StartGovernExecutionCommand {
 	return &StartGovernExecutionCommand{
 		AgreementId:       agreementId,
@@ -40,6 +45,10 @@ func (c CleanupExecutionCommand) ShortString() string {
 	return fmt.Sprintf("CleanupExecutionCommand: AgreementId %v, AgreementProtocol %v, Reason %v, Deployment %v", c.AgreementId, c.AgreementProtocol, c.Reason, depStr)
 }
 
+func (c CleanupExecutionCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: c.AgreementProtocol, structlog.AGREEMENT_ID: c.AgreementId}
+}
+
 func (w *GovernanceWorker) NewCleanupExecutionCommand(protocol string, agreementId string, reason uint, deployment persistence.DeploymentConfig) *CleanupExecutionCommand {
 	return &CleanupExecutionCommand{
 		AgreementProtocol: protocol,
@@ -61,6 +70,10 @@ func (c CleanupStatusCommand) ShortString() string {
 	return fmt.Sprintf("CleanupStatusCommand: AgreementId %v, AgreementProtocol %v, Status %v", c.AgreementId, c.AgreementProtocol, c.Status)
 }
 
+func (c CleanupStatusCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: c.AgreementProtocol, structlog.AGREEMENT_ID: c.AgreementId}
+}
+
 func (w *GovernanceWorker) NewCleanupStatusCommand(protocol string, agreementId string, status uint) *CleanupStatusCommand {
 	return &CleanupStatusCommand{
 		AgreementProtocol: protocol,
@@ -81,6 +94,10 @@ func (c AsyncTerminationCommand) ShortString() string {
 	return fmt.Sprintf("AsyncTerminationCommand: AgreementId %v, AgreementProtocol %v, Reason %v", c.AgreementId, c.AgreementProtocol, c.Reason)
 }
 
+func (c AsyncTerminationCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: c.AgreementProtocol, structlog.AGREEMENT_ID: c.AgreementId}
+}
+
 func NewAsyncTerminationCommand(agreementId string, agreementProtocol string, reason uint) *AsyncTerminationCommand {
 	return &AsyncTerminationCommand{
 		AgreementId:       agreementId,
@@ -102,6 +119,10 @@ func (c CancelAgreementCommand) ShortString() string {
 	return fmt.Sprintf("CancelAgreementCommand: AgreementId %v, AgreementProtocol %v, Reason %v, ReasonDescription: %v", c.AgreementId, c.AgreementProtocol, c.Reason, c.ReasonDescription)
 }
 
+func (c CancelAgreementCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.AGREEMENT_PROTOCOL: c.AgreementProtocol, structlog.AGREEMENT_ID: c.AgreementId}
+}
+
 func NewCancelAgreementCommand(agreementId string, agreementProtocol string, reason uint, desc string) *CancelAgreementCommand {
 	return &CancelAgreementCommand{
 		AgreementId:       agreementId,
@@ -124,6 +145,10 @@ func (c UpdateMicroserviceCommand) ShortString() string {
 		c.MsInstKey, c.ExecutionStarted, c.ExecutionFailureCode, c.ExecutionFailureDesc)
 }
 
+func (c UpdateMicroserviceCommand) LogFields() structlog.Fields {
+	return structlog.Fields{structlog.SERVICE_INSTANCE: c.MsInstKey}
+}
+
 func (w *GovernanceWorker) NewUpdateMicroserviceCommand(key string, started bool, failure_code uint, failure_desc string) *UpdateMicroserviceCommand {
 	return &UpdateMicroserviceCommand{
 		MsInstKey:            key,
@@ -40,6 +40,7 @@ const MICROSERVICE_GOVERNOR = "MicroserviceGovernor"
 const BC_GOVERNOR = "BlockchainGovernor"
 const SURFACEERRORS = "SurfaceExchErrors"
 const NODESTATUS = "NodeStatus"
+const EVENTLOG_PRUNER = "EventLogPruner"
 
 // Keys for the exchange errors cache in the worker
 const EXCHANGE_ERRORS = "ExchangeErrors"
@@ -58,6 +59,9 @@ type GovernanceWorker struct {
 	exchErrors        cache.Cache
 	noworkDispatch    int64 // The last time the NoWorkHandler was dispatched.
 	essCleanedUp      bool
+
+	deferredUpgrades map[string]bool // The service definitions whose upgrade is waiting for the node maintenance window.
+	pendingCancelAll bool            // All the agreements need to be cancelled when the node maintenance window opens.
 }
 
 func NewGovernanceWorker(name string, cfg *config.HorizonConfig, db *bolt.DB, pm *policy.PolicyManager) *GovernanceWorker {
@@ -86,6 +90,9 @@ func NewGovernanceWorker(name string, cfg *config.HorizonConfig, db *bolt.DB, pm
 		exchErrors:      cache.NewSimpleMapCache(),
 		noworkDispatch:  time.Now().Unix(),
 		essCleanedUp:    false,
+
+		deferredUpgrades: make(map[string]bool),
+		pendingCancelAll: false,
 	}
 
 	// Start the worker and set the no work interval to 10 seconds.
@@ -184,17 +191,32 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 						persistence.NewMessageMeta(EL_GOV_IMAGE_LOADED, ags[0].RunningWorkload.Org, ags[0].RunningWorkload.URL),
 						fmt.Sprintf(persistence.EC_IMAGE_LOADED),
 						ags[0])
+					if len(msg.ImageDigests) != 0 {
+						if _, err := persistence.SetAgreementImageDigests(w.db, lc.AgreementId, lc.AgreementProtocol, msg.ImageDigests); err != nil {
+							glog.Errorf(logString(fmt.Sprintf("unable to save image digests %v for agreement %v, error: %v", msg.ImageDigests, lc.AgreementId, err)))
+						}
+						w.sendImageDigests(&ags[0], msg.ImageDigests)
+					}
 				} else {
 					var errDetails = "unknown error"
 					if msg.Error != nil {
 						errDetails = msg.Error.Error()
 					}
-					eventlog.LogAgreementEvent(
-						w.db,
-						persistence.SEVERITY_ERROR,
-						persistence.NewMessageMeta(EL_GOV_ERR_LOADING_IMG, ags[0].RunningWorkload.Org, ags[0].RunningWorkload.URL, errDetails),
-						persistence.EC_ERROR_IMAGE_LOADE,
-						ags[0])
+					if msg.Event().Id == events.IMAGE_SIG_VERIF_ERROR {
+						eventlog.LogAgreementEvent(
+							w.db,
+							persistence.SEVERITY_ERROR,
+							persistence.NewMessageMeta(EL_GOV_ERR_VERIFYING_IMG, ags[0].RunningWorkload.Org, ags[0].RunningWorkload.URL, errDetails),
+							persistence.EC_ERROR_IMAGE_SIGNATURE,
+							ags[0])
+					} else {
+						eventlog.LogAgreementEvent(
+							w.db,
+							persistence.SEVERITY_ERROR,
+							persistence.NewMessageMeta(EL_GOV_ERR_LOADING_IMG, ags[0].RunningWorkload.Org, ags[0].RunningWorkload.URL, errDetails),
+							persistence.EC_ERROR_IMAGE_LOADE,
+							ags[0])
+					}
 					cmd := w.NewCleanupExecutionCommand(lc.AgreementProtocol, lc.AgreementId, reason, nil)
 					w.Commands <- cmd
 				}
@@ -209,6 +231,24 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 					persistence.NewMessageMeta(EL_GOV_IMAGE_LOADED_FOR_SVC, serviceInfo.Org, serviceInfo.URL),
 					persistence.EC_IMAGE_LOADED,
 					"", serviceInfo.URL, "", serviceInfo.Version, "", lc.AgreementIds)
+				if len(msg.ImageDigests) != 0 {
+					if _, err := persistence.UpdateMSInstanceImageDigests(w.db, lc.Name, msg.ImageDigests); err != nil {
+						glog.Errorf(logString(fmt.Sprintf("unable to save image digests %v for service instance %v, error: %v", msg.ImageDigests, lc.Name, err)))
+					}
+				}
+			} else if msg.Event().Id == events.IMAGE_SIG_VERIF_ERROR {
+				var errDetails = "unknown error"
+				if msg.Error != nil {
+					errDetails = msg.Error.Error()
+				}
+				eventlog.LogServiceEvent2(
+					w.db,
+					persistence.SEVERITY_ERROR,
+					persistence.NewMessageMeta(EL_GOV_ERR_VERIFYING_IMG, serviceInfo.Org, serviceInfo.URL, errDetails),
+					persistence.EC_ERROR_IMAGE_SIGNATURE,
+					"", serviceInfo.URL, "", serviceInfo.Version, "", lc.AgreementIds)
+				cmd := w.NewUpdateMicroserviceCommand(lc.Name, false, microservice.MS_IMAGE_FETCH_FAILED, microservice.DecodeReasonCode(microservice.MS_IMAGE_FETCH_FAILED))
+				w.Commands <- cmd
 			} else {
 				eventlog.LogServiceEvent2(
 					w.db,
@@ -397,6 +437,42 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// Returns true if the node policy allows disruptive changes to the workloads right now. When the node policy
+// cannot be read, the changes are allowed so that the node is not stuck forever.
+func (w *GovernanceWorker) inMaintenanceWindow() bool {
+	if nodePolicy, err := persistence.FindNodePolicy(w.db); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to read node policy from the local database. %v", err)))
+		return true
+	} else {
+		return nodePolicy.InMaintenanceWindow(time.Now())
+	}
+}
+
+// Perform the disruptive changes that were deferred until the node maintenance window opens.
+func (w *GovernanceWorker) governMaintenanceWindow() {
+
+	if (!w.pendingCancelAll && len(w.deferredUpgrades) == 0) || !w.inMaintenanceWindow() {
+		return
+	}
+
+	glog.V(3).Infof(logString(fmt.Sprintf("maintenance window is open, performing deferred changes")))
+
+	if w.pendingCancelAll {
+		w.pendingCancelAll = false
+		w.cancelAllAgreements()
+	}
+
+	// This runs on the worker thread, so the upgrades are handled directly instead of queueing commands to the worker.
+	// An upgrade that is deferred again is added back to the new map.
+	upgrades := w.deferredUpgrades
+	w.deferredUpgrades = make(map[string]bool)
+	for msdefId := range upgrades {
+		if !w.IsWorkerShuttingDown() {
+			w.handleMicroserviceUpgrade(msdefId)
+		}
+	}
+}
+
 // Make sure that every agreement we have is in a valid state or proceeding to valid states in a timely fashion. If not,
 // cancel the agreement and allow the agbots to re-make them if necessary.
 func (w *GovernanceWorker) governAgreements() {
@@ -520,6 +596,13 @@ func (w *GovernanceWorker) governAgreements() {
 						glog.V(5).Infof(logString(fmt.Sprintf("TsAndCs: %v", tcPolicy.ShortString())))
 						glog.V(5).Infof(logString(fmt.Sprintf("Merged Policy: %v", mergedPolicy.ShortString())))
 
+						// The agreement will be re-evaluated the next time through here, so just leave it alone until the
+						// node maintenance window opens.
+						if !w.inMaintenanceWindow() {
+							glog.V(3).Infof(logString(fmt.Sprintf("current proposal for %v is out of policy, deferring the cancellation until the maintenance window opens: %v", ag.CurrentAgreementId, err)))
+							continue
+						}
+
 						// The proposal for this agreement is no longer compatible with the node's policy, so cancel the agreement.
 						glog.V(3).Infof(logString(fmt.Sprintf("current proposal for %v is out of policy: %v", ag.CurrentAgreementId, err)))
 
@@ -770,6 +853,11 @@ func (w *GovernanceWorker) Initialize() bool {
 	// Fire up the microservice governor
 	w.DispatchSubworker(MICROSERVICE_GOVERNOR, w.governMicroservices, 60, false)
 
+	// Enforce the event log retention limits, if there are any
+	if w.BaseWorker.Manager.Config.Edge.EventLogMaxAgeS > 0 || w.BaseWorker.Manager.Config.Edge.EventLogMaxCount > 0 {
+		w.DispatchSubworker(EVENTLOG_PRUNER, w.pruneEventLogs, w.BaseWorker.Manager.Config.Edge.EventLogPruneIntervalS, false)
+	}
+
 	// for the policy case update the exchange with the latest registeredServices
 	if w.devicePattern == "" {
 		w.UpdateRegisteredServicesWithAgreement()
@@ -779,6 +867,20 @@ func (w *GovernanceWorker) Initialize() bool {
 
 }
 
+// Remove the event logs that are beyond the configured retention limits so that the local database does not
+// grow without bound on long lived nodes.
+func (w *GovernanceWorker) pruneEventLogs() int {
+	maxAge := w.BaseWorker.Manager.Config.Edge.EventLogMaxAgeS
+	maxCount := w.BaseWorker.Manager.Config.Edge.EventLogMaxCount
+
+	if removed, err := persistence.PruneEventLogs(w.db, maxAge, maxCount); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to prune event logs, error: %v", err)))
+	} else if removed != 0 {
+		glog.V(3).Infof(logString(fmt.Sprintf("removed %v event logs beyond the retention limits, max age %v seconds, max count %v", removed, maxAge, maxCount)))
+	}
+	return 0
+}
+
 func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 
 	// It's possible that the command handler stays busy enough that the noworkhandler never gets
@@ -794,10 +896,10 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 	case *StartGovernExecutionCommand:
 		// TODO: update db start time and tc so it can be governed
 		cmd, _ := command.(*StartGovernExecutionCommand)
-		glog.V(3).Infof(logString(fmt.Sprintf("Starting governance on resources in agreement: %v", cmd.AgreementId)))
+		w.Logger(cmd).V(3).Infof("Starting governance on resources in agreement: %v", cmd.AgreementId)
 
 		if ag, err := persistence.AgreementStateExecutionStarted(w.db, cmd.AgreementId, cmd.AgreementProtocol); err != nil {
-			glog.Errorf(logString(fmt.Sprintf("Failed to update local contract record to start governing Agreement: %v. Error: %v", cmd.AgreementId, err)))
+			w.Logger(cmd).Errorf("Failed to update local contract record to start governing Agreement: %v. Error: %v", cmd.AgreementId, err)
 		} else {
 			eventlog.LogAgreementEvent(
 				w.db,
@@ -818,7 +920,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 		} else if ags[0].AgreementTerminatedTime != 0 && ags[0].AgreementForceTerminatedTime == 0 {
 			glog.V(3).Infof(logString(fmt.Sprintf("ignoring the event, agreement %v is already terminating", agreementId)))
 		} else {
-			glog.V(3).Infof(logString(fmt.Sprintf("Ending the agreement: %v", agreementId)))
+			w.Logger(cmd).V(3).Infof("Ending the agreement: %v", agreementId)
 
 			eventlog.LogAgreementEvent(
 				w.db,
@@ -1411,6 +1513,7 @@ func (w *GovernanceWorker) NoWorkHandler() {
 
 	// Make sure that all known agreements are maintained, if we're not shutting down.
 	if !w.IsWorkerShuttingDown() {
+		w.governMaintenanceWindow()
 		w.governAgreements()
 	}
 
@@ -1431,6 +1534,22 @@ func (w *GovernanceWorker) NoWorkHandler() {
 
 }
 
+// When the deployment policy of the agreement pins the image digests, send the digests that were resolved for the
+// service to the agbot, so that the nodes which make an agreement for the same service version run the same images.
+func (w *GovernanceWorker) sendImageDigests(ag *persistence.EstablishedAgreement, digests map[string]string) {
+
+	protocolHandler := w.producerPH[ag.AgreementProtocol].AgreementProtocolHandler("", "", "")
+	if proposal, err := protocolHandler.DemarshalProposal(ag.Proposal); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to demarshal proposal for agreement %v, error %v", ag.CurrentAgreementId, err)))
+	} else if tcPolicy, err := policy.DemarshalPolicy(proposal.TsAndCs()); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("error demarshalling TsAndCs policy for agreement %v, error %v", ag.CurrentAgreementId, err)))
+	} else if !tcPolicy.PinImageDigests {
+		return
+	} else if err := w.producerPH[ag.AgreementProtocol].SendImageDigests(ag, ag.RunningWorkload.Version, ag.RunningWorkload.Arch, digests); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to send image digests for agreement %v, error: %v", ag.CurrentAgreementId, err)))
+	}
+}
+
 // This function encapsulates finalization of an agreement for re-use
 func (w *GovernanceWorker) finalizeAgreement(agreement persistence.EstablishedAgreement, protocolHandler abstractprotocol.ProtocolHandler) error {
 
@@ -1516,6 +1635,7 @@ func (w *GovernanceWorker) RecordReply(proposal abstractprotocol.Proposal, proto
 		lc.Configure = *cc
 		lc.AgreementId = proposal.AgreementId()
 		lc.AgreementProtocol = protocol
+		lc.ImageDigests = workload.ImageDigests
 
 		// get environmental settings for the workload
 
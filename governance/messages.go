@@ -35,6 +35,7 @@ const (
 	EL_GOV_IMAGE_LOADED_FOR_SVC    = "Image loaded for service %v/%v."
 	EL_GOV_ERR_LOADING_IMG         = "Error loading image for %v/%v. Reason: %v"
 	EL_GOV_ERR_LOADING_IMG_FOR_SVC = "Error loading image for service %v/%v."
+	EL_GOV_ERR_VERIFYING_IMG       = "Error verifying the image signature for %v/%v. Reason: %v"
 
 	// agreement
 	EL_GOV_START_TERM_AG_WITH_REASON    = "Start terminating agreement for %v. Termination reason: %v"
@@ -81,6 +82,7 @@ const (
 	EL_GOV_START_UPGRADE    = "Start upgrading service %v/%v from version %v to version %v."
 	EL_GOV_COMPLETE_UPGRADE = "Complete upgrading service %v/%v from version %v to version %v."
 	EL_GOV_FAILED_UPGRADE   = "Failed to upgrade service %v/%v from version %v to version %v, error: %v"
+	EL_GOV_DEFER_UPGRADE    = "Deferred upgrading service %v/%v from version %v to version %v until the node maintenance window opens."
 
 	// service downgrade
 	EL_GOV_START_DOWNGRADE_FOR_AG                 = "Start downgrading service %v/%v version %v because service for agreement failed to start."
@@ -147,6 +149,7 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_GOV_IMAGE_LOADED_FOR_SVC)
 	msgPrinter.Sprintf(EL_GOV_ERR_LOADING_IMG)
 	msgPrinter.Sprintf(EL_GOV_ERR_LOADING_IMG_FOR_SVC)
+	msgPrinter.Sprintf(EL_GOV_ERR_VERIFYING_IMG)
 
 	// agreement
 	msgPrinter.Sprintf(EL_GOV_START_TERM_AG_WITH_REASON)
@@ -193,6 +196,7 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_GOV_START_UPGRADE)
 	msgPrinter.Sprintf(EL_GOV_COMPLETE_UPGRADE)
 	msgPrinter.Sprintf(EL_GOV_FAILED_UPGRADE)
+	msgPrinter.Sprintf(EL_GOV_DEFER_UPGRADE)
 
 	// service downgrade
 	msgPrinter.Sprintf(EL_GOV_START_DOWNGRADE_FOR_AG)
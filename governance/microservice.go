@@ -847,6 +847,16 @@ func (w *GovernanceWorker) handleMicroserviceUpgrade(msdef_id string) {
 			glog.Errorf(logString(fmt.Sprintf("Error finding the new service definition to upgrade to for %v/%v version %v. %v", msdef.Org, msdef.SpecRef, msdef.Version, err)))
 		} else if new_msdef == nil {
 			glog.V(5).Infof(logString(fmt.Sprintf("No changes for service definition %v/%v, no need to upgrade.", msdef.Org, msdef.SpecRef)))
+		} else if !w.inMaintenanceWindow() {
+			// The upgrade will be retried by the governance worker when the maintenance window opens.
+			if !w.deferredUpgrades[msdef_id] {
+				eventlog.LogServiceEvent2(w.db, persistence.SEVERITY_INFO,
+					persistence.NewMessageMeta(EL_GOV_DEFER_UPGRADE, msdef.Org, msdef.SpecRef, msdef.Version, new_msdef.Version),
+					persistence.EC_DEFER_UPGRADE_SERVICE,
+					"", msdef.SpecRef, msdef.Org, msdef.Version, msdef.Arch, []string{})
+				w.deferredUpgrades[msdef_id] = true
+			}
+			glog.V(3).Infof(logString(fmt.Sprintf("Deferring upgrade of service %v/%v version %v until the maintenance window opens.", msdef.Org, msdef.SpecRef, msdef.Version)))
 		} else {
 			eventlog.LogServiceEvent2(w.db, persistence.SEVERITY_INFO,
 				persistence.NewMessageMeta(EL_GOV_START_UPGRADE, msdef.Org, msdef.SpecRef, msdef.Version, new_msdef.Version),
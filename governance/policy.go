@@ -113,7 +113,12 @@ func (w *GovernanceWorker) handleNodePolicyUpdateForDeployment(updateCode int) {
 
 	// If node's allowPrivileged built-in property is changed, cancel all agreements
 	if updateCode&externalpolicy.EP_ALLOWPRIVILEGED_CHANGED == externalpolicy.EP_ALLOWPRIVILEGED_CHANGED {
-		w.cancelAllAgreements()
+		if w.inMaintenanceWindow() {
+			w.cancelAllAgreements()
+		} else {
+			glog.V(3).Infof(logString(fmt.Sprintf("allowPrivileged changed, deferring the cancellation of all agreements until the maintenance window opens.")))
+			w.pendingCancelAll = true
+		}
 	}
 
 	// Let governAgreements() function handle the policy re-evaluation and the rest
@@ -23,14 +23,16 @@ type ContainerStatus struct {
 	Image   string `json:"image"`
 	Created int64  `json:"created"`
 	State   string `json:"state"`
+	Health  string `json:"health,omitempty"` // only set when the container has a health check
 }
 
 func (w ContainerStatus) String() string {
 	return fmt.Sprintf("Name: %v, "+
 		"Image: %v, "+
 		"Created: %v, "+
-		"State: %v",
-		w.Name, w.Image, w.Created, w.State)
+		"State: %v, "+
+		"Health: %v",
+		w.Name, w.Image, w.Created, w.State, w.Health)
 }
 
 type WorkloadStatus struct {
@@ -42,6 +44,7 @@ type WorkloadStatus struct {
 	Containers     []ContainerStatus `json:"containerStatus"`
 	OperatorStatus interface{}       `json:"operatorStatus,omitempty"`
 	ConfigState    string            `json:"configState,omitempty"`
+	ImageDigests   map[string]string `json:"imageDigests,omitempty"`
 }
 
 func (w WorkloadStatus) String() string {
@@ -52,8 +55,9 @@ func (w WorkloadStatus) String() string {
 		"Arch: %v, "+
 		"Containers: %v"+
 		"OperatorStatus: %v"+
-		"ConfigState: %v",
-		w.AgreementId, w.ServiceURL, w.Org, w.Version, w.Arch, w.Containers, w.OperatorStatus, w.ConfigState)
+		"ConfigState: %v, "+
+		"ImageDigests: %v",
+		w.AgreementId, w.ServiceURL, w.Org, w.Version, w.Arch, w.Containers, w.OperatorStatus, w.ConfigState, w.ImageDigests)
 }
 
 type DeviceStatus struct {
@@ -253,14 +257,6 @@ func (w *GovernanceWorker) getServiceStatus(containers []docker.APIContainers) (
 				deployment, _ = msdef.GetDeployment()
 			} else {
 				deployment = msdef.ClusterDeployment
-				if deployment != "" {
-					opStatus, err := GetOperatorStatus(deployment)
-					if err != nil {
-						glog.Errorf(logString(fmt.Sprintf("Error getting operator status: %v", err)))
-					} else {
-						msdef_status.OperatorStatus = opStatus
-					}
-				}
 			}
 			if msinsts, err := persistence.GetAllMicroserviceInstancesWithDefId(w.db, msdef.Id, false, false); err != nil {
 				return nil, fmt.Errorf(logString(fmt.Sprintf("Error retrieving all service instances for %v from database, error: %v", msdef.SpecRef, err)))
@@ -268,7 +264,11 @@ func (w *GovernanceWorker) getServiceStatus(containers []docker.APIContainers) (
 				for _, msi := range msinsts {
 					glog.V(3).Infof("Gathering status for msdef: %v/%v, working on instance %v", msdef.Org, msdef.SpecRef, msi.GetKey())
 					if deployment != "" {
-						if cstatus, err := GetContainerStatus(deployment, msi.GetKey(), !msi.IsTopLevelService(), containers); err != nil {
+						namespace := ""
+						if w.deviceType != persistence.DEVICE_TYPE_DEVICE {
+							namespace = kube_operator.FindAgreementNamespace(w.db, msi.GetKey())
+						}
+						if cstatus, err := GetContainerStatus(deployment, msi.GetKey(), !msi.IsTopLevelService(), containers, namespace); err != nil {
 							return nil, fmt.Errorf(logString(fmt.Sprintf("Error getting service container status for %v. %v", msdef.SpecRef, err)))
 						} else {
 							msdef_status.Containers = append(msdef_status.Containers, cstatus...)
@@ -278,8 +278,27 @@ func (w *GovernanceWorker) getServiceStatus(containers []docker.APIContainers) (
 					if msi.IsTopLevelService() {
 						msdef_status.AgreementId = msi.GetKey()
 					}
+
+					if digests := msi.GetImageDigests(); len(digests) != 0 {
+						if msdef_status.ImageDigests == nil {
+							msdef_status.ImageDigests = make(map[string]string)
+						}
+						for name, digest := range digests {
+							msdef_status.ImageDigests[name] = digest
+						}
+					}
 				}
 			}
+			// The operator of a cluster service can be in the namespace of its agreement
+			if w.deviceType != persistence.DEVICE_TYPE_DEVICE && deployment != "" {
+				opStatus, err := GetOperatorStatus(deployment, kube_operator.FindAgreementNamespace(w.db, msdef_status.AgreementId))
+				if err != nil {
+					glog.Errorf(logString(fmt.Sprintf("Error getting operator status: %v", err)))
+				} else {
+					msdef_status.OperatorStatus = opStatus
+				}
+			}
+
 			if msdef_status.ConfigState == "" {
 				msdef_status.ConfigState = exchange.SERVICE_CONFIGSTATE_ACTIVE
 			}
@@ -292,7 +311,8 @@ func (w *GovernanceWorker) getServiceStatus(containers []docker.APIContainers) (
 }
 
 // find container status
-func GetContainerStatus(deployment string, key string, infrastructure bool, containers []docker.APIContainers) ([]ContainerStatus, error) {
+// The namespace is the namespace of the agreement of a cluster service that is installed into a namespace of its own
+func GetContainerStatus(deployment string, key string, infrastructure bool, containers []docker.APIContainers, namespace string) ([]ContainerStatus, error) {
 	status := make([]ContainerStatus, 0)
 
 	if deploymentDesc, err := containermessage.GetNativeDeployment(deployment); err == nil {
@@ -306,14 +326,15 @@ func GetContainerStatus(deployment string, key string, infrastructure bool, cont
 			container_status.Name = serviceName
 			container_status.Image = s_details.Image
 			container_status.State = "not started"
-			for _, container := range containers {
-				if _, ok := container.Labels[label]; ok {
-					cname := container.Names[0]
+			for _, dc := range containers {
+				if _, ok := dc.Labels[label]; ok {
+					cname := dc.Names[0]
 					if cname == "/"+key+"-"+serviceName {
-						container_status.Name = container.Names[0]
-						container_status.Image = container.Image
-						container_status.Created = container.Created
-						container_status.State = container.State
+						container_status.Name = dc.Names[0]
+						container_status.Image = dc.Image
+						container_status.Created = dc.Created
+						container_status.State = dc.State
+						container_status.Health = container.ContainerHealth(&dc)
 						break
 					}
 				}
@@ -327,13 +348,13 @@ func GetContainerStatus(deployment string, key string, infrastructure bool, cont
 
 		hc := helm.NewHelmClient()
 		releaseState := "Not Running"
-		if rs, err := hc.Status(hdc.ReleaseName); err != nil {
+		if rs, err := hc.Status(hdc.ReleaseName, hdc.Namespace); err != nil {
 			releaseState = fmt.Sprintf("Unknown, error: %v", err)
 		} else {
 			releaseState = rs.Status
 			cDate := cutil.TimeInSeconds(rs.Updated, hc.ReleaseTimeFormat())
 			container_status.Created = cDate
-			container_status.Image = rs.ChartName
+			container_status.Image = fmt.Sprintf("%v:%v", rs.ChartName, rs.ChartVersion)
 		}
 		container_status.State = releaseState
 		status = append(status, container_status)
@@ -344,7 +365,7 @@ func GetContainerStatus(deployment string, key string, infrastructure bool, cont
 			container_status.State = fmt.Sprintf("Unknown, error: %v", err)
 			status = append(status, container_status)
 		} else {
-			if kubeStatus, err := kc.Status(kdc.OperatorYamlArchive, ""); err != nil {
+			if kubeStatus, err := kc.Status(kdc.OperatorYamlArchive, "", namespace); err != nil {
 				container_status.State = fmt.Sprintf("Unknown, error: %v", err)
 				status = append(status, container_status)
 			} else {
@@ -364,14 +385,30 @@ func GetContainerStatus(deployment string, key string, infrastructure bool, cont
 }
 
 // GetOperatorStatus will check if the given deployment is for a kube operator and return the operator defined status if it is
-// Will return nil for the interface and no error if the deployment is not for a kube operator
-func GetOperatorStatus(deployment string) (interface{}, error) {
+// For a helm chart it returns the status of the helm release, with its revision
+// Will return nil for the interface and no error if the deployment is not for a kube operator or a helm chart
+// The namespace is the namespace of the agreement of an operator that is installed into a namespace of its own
+func GetOperatorStatus(deployment string, namespace string) (interface{}, error) {
+	if hd, err := persistence.GetHelmDeployment(deployment); err == nil {
+		rs, err := helm.NewHelmClient().Status(hd.ReleaseName, hd.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf(logString(fmt.Sprintf("Error retrieving helm release status from cluster, error: %v", err)))
+		}
+		// Return the generic form of the status, it is compared with the status read back from the database.
+		var relStatus map[string]interface{}
+		if jBytes, err := json.Marshal(rs); err != nil {
+			return nil, fmt.Errorf(logString(fmt.Sprintf("Error marshalling helm release status %v, error: %v", rs, err)))
+		} else if err := json.Unmarshal(jBytes, &relStatus); err != nil {
+			return nil, fmt.Errorf(logString(fmt.Sprintf("Error unmarshalling helm release status %v, error: %v", string(jBytes), err)))
+		}
+		return relStatus, nil
+	}
 	if kd, err := persistence.GetKubeDeployment(deployment); err == nil {
 		client, err := kube_operator.NewKubeClient()
 		if err != nil {
 			return nil, fmt.Errorf(logString(fmt.Sprintf("Error retrieving operator status from cluster, error: %v", err)))
 		}
-		opStatus, err := client.OperatorStatus(kd.OperatorYamlArchive, "")
+		opStatus, err := client.OperatorStatus(kd.OperatorYamlArchive, "", namespace)
 		if err != nil {
 			return nil, fmt.Errorf(logString(fmt.Sprintf("Error retrieving operator status from cluster, error: %v", err)))
 		}
@@ -458,6 +495,9 @@ func changeInWorkloadStatuses(newStatuses []persistence.WorkloadStatus, oldStatu
 				if oldStatus.ConfigState != newStatus.ConfigState {
 					return true
 				}
+				if !reflect.DeepEqual(newStatus.ImageDigests, oldStatus.ImageDigests) {
+					return true
+				}
 				matches++
 			}
 		}
@@ -495,7 +535,7 @@ func changeInContainerStatuses(newContainers []persistence.ContainerStatus, oldC
 	for _, oldContainer := range oldContainers {
 		for _, newContainer := range newContainers {
 			if oldContainer.Name == newContainer.Name && oldContainer.Image == newContainer.Image && oldContainer.Created == newContainer.Created {
-				if oldContainer.State == newContainer.State {
+				if oldContainer.State == newContainer.State && oldContainer.Health == newContainer.Health {
 					matches++
 				} else {
 					return true
@@ -514,7 +554,8 @@ func convertToPersistenceType(workload []WorkloadStatus) []persistence.WorkloadS
 	for _, wlStatus := range workload {
 		newPersistentWlStatus := persistence.WorkloadStatus{AgreementId: wlStatus.AgreementId,
 			ServiceURL: wlStatus.ServiceURL, Org: wlStatus.Org, Version: wlStatus.Version,
-			Arch: wlStatus.Arch, OperatorStatus: wlStatus.OperatorStatus, ConfigState: wlStatus.ConfigState}
+			Arch: wlStatus.Arch, OperatorStatus: wlStatus.OperatorStatus, ConfigState: wlStatus.ConfigState,
+			ImageDigests: wlStatus.ImageDigests}
 		newPersistentWlStatus.Containers = converContainerStatusToPersistenceType(wlStatus.Containers)
 		persistentWls = append(persistentWls, newPersistentWlStatus)
 	}
@@ -524,7 +565,7 @@ func convertToPersistenceType(workload []WorkloadStatus) []persistence.WorkloadS
 func converContainerStatusToPersistenceType(containers []ContainerStatus) []persistence.ContainerStatus {
 	persistentCStatuses := []persistence.ContainerStatus{}
 	for _, cStatus := range containers {
-		persistentCStatuses = append(persistentCStatuses, persistence.ContainerStatus{Name: cStatus.Name, Image: cStatus.Image, Created: cStatus.Created, State: cStatus.State})
+		persistentCStatuses = append(persistentCStatuses, persistence.ContainerStatus{Name: cStatus.Name, Image: cStatus.Image, Created: cStatus.Created, State: cStatus.State, Health: cStatus.Health})
 	}
 	return persistentCStatuses
 }
@@ -65,7 +65,7 @@ func Test_GetContainerStatus(t *testing.T) {
 	// test fail with a wrong deployment string
 	deployment := "{\"services\":{\"netspeed5\":{st\":{\"image\":\"mycompany/x86/test:v1.0\"}}}"
 
-	status, err := GetContainerStatus(deployment, agreementId, false, containers)
+	status, err := GetContainerStatus(deployment, agreementId, false, containers, "")
 
 	assert.Error(t, err, "Error should be returned. ")
 
@@ -74,7 +74,7 @@ func Test_GetContainerStatus(t *testing.T) {
 	exp_status := []ContainerStatus{ContainerStatus{Name: "/aaaa-netspeed5", Image: "mycompany/x86/netspeed5:v2.5", Created: 1507728202, State: "running"},
 		{Name: "/aaaa-test", Image: "mycompany/x86/test:v1.0", Created: 1507728356, State: "running"}}
 
-	status, err = GetContainerStatus(deployment, agreementId, false, containers)
+	status, err = GetContainerStatus(deployment, agreementId, false, containers, "")
 
 	assert.Nil(t, err)
 	assert.True(t, statusArrayIsSame(exp_status, status), "The elements should be the same.")
@@ -85,7 +85,7 @@ func Test_GetContainerStatus(t *testing.T) {
 	exp_status = []ContainerStatus{ContainerStatus{Name: "netspeed5", Image: "mycompany/x86/netspeed5:v2.5", Created: 0, State: "not started"},
 		{Name: "test", Image: "mycompany/x86/test:v1.0", Created: 0, State: "not started"}}
 
-	status, err = GetContainerStatus(deployment, agreementId, false, containers)
+	status, err = GetContainerStatus(deployment, agreementId, false, containers, "")
 
 	assert.Nil(t, err)
 	assert.True(t, statusArrayIsSame(exp_status, status), "The elements should be the same.")
@@ -95,7 +95,7 @@ func Test_GetContainerStatus(t *testing.T) {
 	exp_status = []ContainerStatus{ContainerStatus{Name: "netspeed5", Image: "mycompany/x86/netspeed5:v2.5", Created: 0, State: "not started"},
 		{Name: "test", Image: "mycompany/x86/test:v1.0", Created: 0, State: "not started"}}
 
-	status, err = GetContainerStatus(deployment, agreementId, false, make([]docker.APIContainers, 0))
+	status, err = GetContainerStatus(deployment, agreementId, false, make([]docker.APIContainers, 0), "")
 
 	assert.Nil(t, err)
 	assert.True(t, statusArrayIsSame(exp_status, status), "The elements should be the same.")
@@ -106,7 +106,17 @@ func Test_GetContainerStatus(t *testing.T) {
 	exp_status = []ContainerStatus{ContainerStatus{Name: "/bluehorizon.network-microservices-gps_2.0.3_52df00-gps", Image: "mycompany/x86/gps:2.0.6", Created: 1507728188, State: "running"}}
 	containers = []docker.APIContainers{c1, c2, c3, c4}
 
-	status, err = GetContainerStatus(deployment, key, true, containers)
+	status, err = GetContainerStatus(deployment, key, true, containers, "")
+
+	assert.Nil(t, err)
+	assert.True(t, statusArrayIsSame(exp_status, status), "The elements should be the same.")
+
+	// test the health of containers that have a health check
+	c4.Status = "Up 5 minutes (unhealthy)"
+	exp_status = []ContainerStatus{ContainerStatus{Name: "/bluehorizon.network-microservices-gps_2.0.3_52df00-gps", Image: "mycompany/x86/gps:2.0.6", Created: 1507728188, State: "running", Health: "unhealthy"}}
+	containers = []docker.APIContainers{c1, c2, c3, c4}
+
+	status, err = GetContainerStatus(deployment, key, true, containers, "")
 
 	assert.Nil(t, err)
 	assert.True(t, statusArrayIsSame(exp_status, status), "The elements should be the same.")
@@ -1,119 +1,177 @@
 package helm
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/golang/glog"
+	"os"
 	"os/exec"
 	"strings"
+	"time"
 )
 
-// This client implements our abstract helm client interface, using the Helm CLI.
+// This client implements our abstract helm client interface, using the Helm 3 CLI.
 
 type CliClient struct {
 }
 
-const INSTALL_ARGS = "install -n %v %v"
-const UNINSTALL_ARGS = "delete --purge %v"
-const STATUS_ARGS = "list -a"
-const DEPLOYED = "DEPLOYED"
-
-const EOL = "\x0a"
-const TAB = "\x09"
+const INSTALL_ARGS = "install %v %v"
+const UPGRADE_ARGS = "upgrade %v %v"
+const ROLLBACK_ARGS = "rollback %v %v"
+const UNINSTALL_ARGS = "uninstall %v"
+const STATUS_ARGS = "status %v -o json"
+const NAMESPACE_ARGS = " --namespace %v"
+const CREATE_NAMESPACE_ARGS = " --create-namespace"
+const VALUES_ARGS = " -f %v"
+const DEPLOYED = "deployed"
 
 func NewCliClient() *CliClient {
 	return new(CliClient)
 }
 
-func (c *CliClient) Install(b64Package string, releaseName string) error {
+func (c *CliClient) Install(b64Package string, releaseName string, namespace string, values map[string]interface{}) error {
+	return c.installOrUpgrade(INSTALL_ARGS, "install", b64Package, releaseName, namespace, values)
+}
+
+func (c *CliClient) Upgrade(b64Package string, releaseName string, namespace string, values map[string]interface{}) error {
+	return c.installOrUpgrade(UPGRADE_ARGS, "upgrade", b64Package, releaseName, namespace, values)
+}
+
+func (c *CliClient) installOrUpgrade(cmdArgs string, action string, b64Package string, releaseName string, namespace string, values map[string]interface{}) error {
 
-	if fileName, err := ConvertB64StringToFile(b64Package); err != nil {
+	fileName, err := ConvertB64StringToFile(b64Package)
+	if err != nil {
 		return errors.New(fmt.Sprintf("error converting Helm package to file: %v", err))
-	} else {
-		glog.V(5).Infof(clilogString(fmt.Sprintf("Decoded Helm package to file: %v", fileName)))
-		args := fmt.Sprintf(INSTALL_ARGS, releaseName, fileName)
-		glog.V(5).Infof(clilogString(fmt.Sprintf("Installing Helm package: %v", args)))
-		argFields := strings.Fields(args)
-		if out, err := exec.Command("helm", argFields...).Output(); err != nil {
-			errMsg := ""
-			if exErr, ok := err.(*exec.ExitError); ok {
-				errMsg = string(exErr.Stderr)
-			}
-			return errors.New(fmt.Sprintf("error installing Helm package: (%T) %v error message: %v", err, err, errMsg))
-		} else {
-			glog.V(5).Infof(clilogString(fmt.Sprintf("Output from install: (%T) %s", out, string(out))))
+	}
+	defer os.Remove(fileName)
+	glog.V(5).Infof(clilogString(fmt.Sprintf("Decoded Helm package to file: %v", fileName)))
+
+	args := fmt.Sprintf(cmdArgs, releaseName, fileName)
+	if namespace != "" {
+		args += fmt.Sprintf(NAMESPACE_ARGS, namespace)
+		if action == "install" {
+			args += CREATE_NAMESPACE_ARGS
 		}
 	}
 
+	if len(values) != 0 {
+		valuesFile, err := ConvertValuesToFile(values)
+		if err != nil {
+			return errors.New(fmt.Sprintf("error writing Helm values to file: %v", err))
+		}
+		defer os.Remove(valuesFile)
+		args += fmt.Sprintf(VALUES_ARGS, valuesFile)
+	}
+
+	glog.V(5).Infof(clilogString(fmt.Sprintf("Running Helm %v: %v", action, args)))
+	if out, err := runHelm(args); err != nil {
+		return errors.New(fmt.Sprintf("error running Helm %v: %v", action, err))
+	} else {
+		glog.V(5).Infof(clilogString(fmt.Sprintf("Output from %v: %s", action, string(out))))
+	}
+
+	return nil
+}
+
+func (c *CliClient) Rollback(releaseName string, namespace string, revision int) error {
+
+	args := fmt.Sprintf(ROLLBACK_ARGS, releaseName, revision)
+	if namespace != "" {
+		args += fmt.Sprintf(NAMESPACE_ARGS, namespace)
+	}
+	glog.V(5).Infof(clilogString(fmt.Sprintf("Rolling back Helm release: %v", args)))
+	if out, err := runHelm(args); err != nil {
+		return errors.New(fmt.Sprintf("error rolling back Helm release: %v", err))
+	} else {
+		glog.V(5).Infof(clilogString(fmt.Sprintf("Output from rollback: %s", string(out))))
+	}
+
 	return nil
 }
 
-func (c *CliClient) UnInstall(releaseName string) error {
+func (c *CliClient) UnInstall(releaseName string, namespace string) error {
 
 	args := fmt.Sprintf(UNINSTALL_ARGS, releaseName)
+	if namespace != "" {
+		args += fmt.Sprintf(NAMESPACE_ARGS, namespace)
+	}
 	glog.V(5).Infof(clilogString(fmt.Sprintf("Uninstalling Helm package: %v", args)))
-	argFields := strings.Fields(args)
-	if out, err := exec.Command("helm", argFields...).Output(); err != nil {
-		errMsg := ""
-		if exErr, ok := err.(*exec.ExitError); ok {
-			errMsg = string(exErr.Stderr)
-		}
-		return errors.New(fmt.Sprintf("error uninstalling Helm package: (%T) %v error message: %v", err, err, errMsg))
+	if out, err := runHelm(args); err != nil {
+		return errors.New(fmt.Sprintf("error uninstalling Helm package: %v", err))
 	} else {
-		glog.V(5).Infof(clilogString(fmt.Sprintf("Output from uninstall: (%T) %s", out, string(out))))
+		glog.V(5).Infof(clilogString(fmt.Sprintf("Output from uninstall: %s", string(out))))
 	}
 
 	return nil
 }
 
-func (c *CliClient) Status(releaseName string) (*ReleaseStatus, error) {
+func (c *CliClient) Status(releaseName string, namespace string) (*ReleaseStatus, error) {
 
-	status := new(ReleaseStatus)
+	args := fmt.Sprintf(STATUS_ARGS, releaseName)
+	if namespace != "" {
+		args += fmt.Sprintf(NAMESPACE_ARGS, namespace)
+	}
+	glog.V(5).Infof(clilogString(fmt.Sprintf("Getting Helm release status: %v", args)))
+	if out, err := runHelm(args); err != nil {
+		return nil, errors.New(fmt.Sprintf("error getting Helm release status: %v", err))
+	} else {
+		glog.V(5).Infof(clilogString(fmt.Sprintf("Output from status: %s", string(out))))
+		return parseReleaseStatus(out)
+	}
 
-	args := fmt.Sprintf(STATUS_ARGS)
-	glog.V(5).Infof(clilogString(fmt.Sprintf("Listing Helm releases: %v, args %v", releaseName, args)))
-	argFields := strings.Fields(args)
-	if out, err := exec.Command("helm", argFields...).Output(); err != nil {
+}
+
+// The subset of the JSON output of the helm status command that is used by the agent.
+type cliRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Info      struct {
+		Status       string `json:"status"`
+		LastDeployed string `json:"last_deployed"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
+// Convert the JSON output of the helm status command into a release status.
+func parseReleaseStatus(out []byte) (*ReleaseStatus, error) {
+	var rel cliRelease
+	if err := json.Unmarshal(out, &rel); err != nil {
+		return nil, errors.New(fmt.Sprintf("error unmarshalling Helm release status %s, error: %v", string(out), err))
+	}
+	return &ReleaseStatus{
+		Name:         rel.Name,
+		Revision:     rel.Version,
+		Updated:      rel.Info.LastDeployed,
+		Status:       rel.Info.Status,
+		ChartName:    rel.Chart.Metadata.Name,
+		ChartVersion: rel.Chart.Metadata.Version,
+		Namespace:    rel.Namespace,
+	}, nil
+}
+
+// Run the helm CLI with the given arguments, the error includes the error output of the CLI.
+func runHelm(args string) ([]byte, error) {
+	out, err := exec.Command("helm", strings.Fields(args)...).Output()
+	if err != nil {
 		errMsg := ""
 		if exErr, ok := err.(*exec.ExitError); ok {
 			errMsg = string(exErr.Stderr)
 		}
-		return nil, errors.New(fmt.Sprintf("error listing Helm releases: (%T) %v error message: %v", err, err, errMsg))
-	} else {
-
-		glog.V(5).Infof(clilogString(fmt.Sprintf("Output from list releases: (%T) %s", out, string(out))))
-
-		// Split std out into lines (array of string). There should be at least 2 lines if there is anything deployed.
-		lines := strings.Split(string(out), EOL)
-		if len(lines) <= 1 {
-			return nil, errors.New(fmt.Sprintf("no active releases"))
-		}
-		glog.V(5).Infof(clilogString(fmt.Sprintf("Output as lines: %v", lines)))
-
-		// Find the line that starts with our release.
-		for _, line := range lines {
-			tabs := strings.Split(line, TAB)
-			glog.V(5).Infof(clilogString(fmt.Sprintf("Output as tabs: %v", tabs)))
-			if len(tabs) < 6 {
-				return nil, errors.New(fmt.Sprintf("not enough output, expecting 6 tabs: %v", line))
-			} else if tabs[0] == releaseName {
-				status.Name = tabs[0]
-				status.Revision = tabs[1]
-				status.Updated = tabs[2]
-				status.Status = tabs[3]
-				status.ChartName = tabs[4]
-				status.Namespace = tabs[5]
-				return status, nil
-			}
-		}
-		return nil, errors.New(fmt.Sprintf("release not found: %v", lines))
+		return nil, errors.New(fmt.Sprintf("(%T) %v error message: %v", err, err, errMsg))
 	}
-
+	return out, nil
 }
 
-// Helm time format. Golang requires the format string to be in reference to the specific time as shown.
-// This is so that the formatter and parser can figure out what goes where in the string.
-const HelmCLIReleaseStatusTimeFormat = "Mon Jan 2 15:04:05 2006"
+// Helm time format. The helm status command reports the last deployed time in RFC3339 format, with fractional seconds
+// that the parser accepts without them being in the format.
+const HelmCLIReleaseStatusTimeFormat = time.RFC3339
 
 func (c *CliClient) ReleaseTimeFormat() string {
 	return HelmCLIReleaseStatusTimeFormat
@@ -2,31 +2,73 @@ package helm
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
 	"io/ioutil"
 	"os"
+	"strings"
 )
 
 // Status object returned by our Helm client.
 type ReleaseStatus struct {
-	Name      string // Release name
-	Revision  string // Revision of the release
-	Updated   string // The date of last change
-	Status    string // The release's status
-	ChartName string // The name of the chart
-	Namespace string // The k8s namespace that the chart was deployed into
+	Name         string `json:"name"`         // Release name
+	Revision     int    `json:"revision"`     // Revision of the release
+	Updated      string `json:"updated"`      // The date of last change
+	Status       string `json:"status"`       // The release's status
+	ChartName    string `json:"chartName"`    // The name of the chart
+	ChartVersion string `json:"chartVersion"` // The version of the chart
+	Namespace    string `json:"namespace"`    // The k8s namespace that the chart was deployed into
 }
 
-// The Helm Client interface that we use, regardless of how its implemented under the covers.
+func (r ReleaseStatus) String() string {
+	return fmt.Sprintf("Name: %v, Revision: %v, Updated: %v, Status: %v, ChartName: %v, ChartVersion: %v, Namespace: %v",
+		r.Name, r.Revision, r.Updated, r.Status, r.ChartName, r.ChartVersion, r.Namespace)
+}
+
+// Returns true if the release is deployed.
+func (r ReleaseStatus) IsDeployed() bool {
+	return strings.EqualFold(r.Status, DEPLOYED)
+}
+
+// The Helm Client interface that we use, regardless of how its implemented under the covers. The namespace is the
+// helm default namespace when it is empty. The values override the default values of the chart, they can be nil.
 type HelmClient interface {
-	Install(b64Package string, releaseName string) error
-	UnInstall(releaseName string) error
-	Status(releaseName string) (*ReleaseStatus, error)
+	Install(b64Package string, releaseName string, namespace string, values map[string]interface{}) error
+	Upgrade(b64Package string, releaseName string, namespace string, values map[string]interface{}) error
+	Rollback(releaseName string, namespace string, revision int) error
+	UnInstall(releaseName string, namespace string) error
+	Status(releaseName string, namespace string) (*ReleaseStatus, error)
 	ReleaseTimeFormat() string
 }
 
+// The kinds of Helm client.
+const (
+	HELM_CLIENT_CLI = config.HELM_CLIENT_CLI // the helm CLI, the default
+	HELM_CLIENT_SDK = config.HELM_CLIENT_SDK // the Helm Go SDK built into the agent
+)
+
+// The kind of Helm client returned by NewHelmClient, it is set from the agent config when the agent starts. The config
+// rejects the kinds that are not supported, so the fallback to the CLI client only applies to other callers.
+var clientType = HELM_CLIENT_CLI
+
+func SetClientType(ct string) {
+	switch ct {
+	case "", HELM_CLIENT_CLI:
+		clientType = HELM_CLIENT_CLI
+	case HELM_CLIENT_SDK:
+		clientType = HELM_CLIENT_SDK
+	default:
+		glog.Warningf(fmt.Sprintf("Helm client %v is not supported, using the %v client", ct, HELM_CLIENT_CLI))
+		clientType = HELM_CLIENT_CLI
+	}
+}
+
 func NewHelmClient() HelmClient {
+	if clientType == HELM_CLIENT_SDK {
+		return NewSDKClient()
+	}
 	return NewCliClient()
 }
 
@@ -53,6 +95,24 @@ func ConvertB64StringToFile(b64Package string) (string, error) {
 	}
 }
 
+const TEMP_VALUES_PREFIX = "anax-helm-values-"
+
+// Write the chart values to a values file in the file system. JSON is a subset of YAML, so the values are written as
+// JSON.
+func ConvertValuesToFile(values map[string]interface{}) (string, error) {
+	if vBytes, err := json.Marshal(values); err != nil {
+		return "", err
+	} else if f, err := ioutil.TempFile("", TEMP_VALUES_PREFIX); err != nil {
+		return "", err
+	} else {
+		defer f.Close()
+		if _, err := f.Write(vBytes); err != nil {
+			return "", err
+		}
+		return f.Name(), nil
+	}
+}
+
 // Convert a Helm chart archive file into a base 64 encoded string. The input filepath is assumed to be absolute.
 func ConvertFileToB64String(filePath string) (string, error) {
 
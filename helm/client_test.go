@@ -5,9 +5,17 @@ package helm
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"flag"
+	"github.com/open-horizon/anax/persistence"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
 	"io/ioutil"
+	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -36,3 +44,105 @@ func Test_DecodeAndCreate(t *testing.T) {
 	}
 
 }
+
+func Test_ParseReleaseStatus(t *testing.T) {
+
+	out := `{"name":"myrelease","info":{"first_deployed":"2021-06-01T10:00:00.123456789-04:00","last_deployed":"2021-06-02T11:30:00.987654321-04:00","status":"deployed"},"chart":{"metadata":{"name":"mychart","version":"1.2.0"}},"version":3,"namespace":"myns"}`
+
+	if rs, err := parseReleaseStatus([]byte(out)); err != nil {
+		t.Errorf("error parsing %v, error: %v", out, err)
+	} else if rs.Name != "myrelease" || rs.Revision != 3 || rs.Status != "deployed" || rs.ChartName != "mychart" || rs.ChartVersion != "1.2.0" || rs.Namespace != "myns" {
+		t.Errorf("wrong release status: %v", rs)
+	} else if !rs.IsDeployed() {
+		t.Errorf("release should be deployed: %v", rs)
+	} else if updated, err := time.Parse(HelmCLIReleaseStatusTimeFormat, rs.Updated); err != nil {
+		t.Errorf("error parsing the updated time %v, error: %v", rs.Updated, err)
+	} else if updated.Unix() != 1622647800 {
+		t.Errorf("wrong updated time %v", updated.Unix())
+	}
+
+	if _, err := parseReleaseStatus([]byte("Error: release: not found")); err == nil {
+		t.Errorf("expected an error for the non-JSON output")
+	}
+
+}
+
+func Test_MakeReleaseValues(t *testing.T) {
+
+	hd := persistence.NewHelmDeployment("11223344", "test-release")
+	hd.Values = map[string]interface{}{"replicas": float64(2), "horizon": "overridden"}
+
+	values := MakeReleaseValues(hd, "agreement1", map[string]string{"HZN_AGREEMENTID": "agreement1", "MY_VAR": "value"})
+
+	if values["replicas"] != float64(2) {
+		t.Errorf("the deployment values should be kept: %v", values)
+	} else if hzn, ok := values[HORIZON_VALUES_KEY].(map[string]interface{}); !ok {
+		t.Errorf("the horizon values should be a map: %v", values)
+	} else if hzn["agreementId"] != "agreement1" {
+		t.Errorf("wrong agreement id in %v", hzn)
+	} else if env, ok := hzn["env"].(map[string]interface{}); !ok || env["MY_VAR"] != "value" || len(env) != 2 {
+		t.Errorf("wrong env in %v", hzn)
+	} else if _, ok := hd.Values[HORIZON_VALUES_KEY].(string); !ok {
+		t.Errorf("the deployment values should not be changed: %v", hd.Values)
+	}
+
+	if fileName, err := ConvertValuesToFile(values); err != nil {
+		t.Errorf("error writing values %v, error: %v", values, err)
+	} else if dat, err := ioutil.ReadFile(fileName); err != nil {
+		t.Errorf("error reading values file %v, error: %v", fileName, err)
+	} else {
+		defer os.Remove(fileName)
+		var read map[string]interface{}
+		if err := json.Unmarshal(dat, &read); err != nil {
+			t.Errorf("error unmarshalling values file %v, error: %v", string(dat), err)
+		} else if !reflect.DeepEqual(read, values) {
+			t.Errorf("values file %v does not match %v", read, values)
+		}
+	}
+
+}
+
+func Test_ConvertRelease(t *testing.T) {
+
+	deployed := helmtime.Date(2021, time.June, 2, 15, 30, 0, 0, time.UTC)
+	rel := &release.Release{
+		Name:      "myrelease",
+		Namespace: "myns",
+		Version:   3,
+		Info:      &release.Info{Status: release.StatusFailed, LastDeployed: deployed},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "mychart", Version: "1.2.0"}},
+	}
+
+	rs := convertRelease(rel)
+	if rs.Name != "myrelease" || rs.Revision != 3 || rs.Status != "failed" || rs.ChartName != "mychart" || rs.ChartVersion != "1.2.0" || rs.Namespace != "myns" {
+		t.Errorf("wrong release status: %v", rs)
+	} else if rs.IsDeployed() {
+		t.Errorf("release should not be deployed: %v", rs)
+	} else if updated, err := time.Parse(NewSDKClient().ReleaseTimeFormat(), rs.Updated); err != nil {
+		t.Errorf("error parsing the updated time %v, error: %v", rs.Updated, err)
+	} else if updated.Unix() != 1622647800 {
+		t.Errorf("wrong updated time %v", updated.Unix())
+	}
+
+	// A release without info or chart.
+	if rs := convertRelease(&release.Release{Name: "other"}); rs.Name != "other" || rs.Status != "" {
+		t.Errorf("wrong release status: %v", rs)
+	}
+
+}
+
+func Test_SetClientType(t *testing.T) {
+
+	defer SetClientType(HELM_CLIENT_CLI)
+
+	SetClientType(HELM_CLIENT_SDK)
+	if _, ok := NewHelmClient().(*SDKClient); !ok {
+		t.Errorf("expected the SDK client, got %T", NewHelmClient())
+	}
+
+	SetClientType("unknown")
+	if _, ok := NewHelmClient().(*CliClient); !ok {
+		t.Errorf("expected the CLI client, got %T", NewHelmClient())
+	}
+
+}
@@ -62,3 +62,14 @@ func NewMaintenanceCommand(protocol string, agreementId string, deployment persi
 		Deployment:        deployment,
 	}
 }
+
+type ShutdownCommand struct {
+}
+
+func (c ShutdownCommand) ShortString() string {
+	return "ShutdownCommand"
+}
+
+func NewShutdownCommand() *ShutdownCommand {
+	return &ShutdownCommand{}
+}
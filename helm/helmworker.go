@@ -9,11 +9,16 @@ import (
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/worker"
+	"time"
 )
 
+// How often the releases whose agreement ended are checked for uninstall, in seconds.
+const RELEASE_CHECK_INTERVAL_S = 30
+
 type HelmWorker struct {
 	worker.BaseWorker // embedded field
 	db                *bolt.DB
+	shuttingDown      bool
 }
 
 func NewHelmWorker(name string, config *config.HorizonConfig, db *bolt.DB) *HelmWorker {
@@ -23,8 +28,10 @@ func NewHelmWorker(name string, config *config.HorizonConfig, db *bolt.DB) *Helm
 		db:         db,
 	}
 
-	glog.Info(hpwlog(fmt.Sprintf("Starting Helm worker")))
-	worker.Start(worker, 0)
+	SetClientType(config.Edge.HelmClient)
+
+	glog.Info(hpwlog(fmt.Sprintf("Starting Helm worker with the %v client", clientType)))
+	worker.Start(worker, RELEASE_CHECK_INTERVAL_S)
 	return worker
 }
 
@@ -59,6 +66,13 @@ func (w *HelmWorker) NewEvent(incoming events.Message) {
 			w.Commands <- cmd
 		}
 
+	case *events.NodeShutdownMessage:
+		msg, _ := incoming.(*events.NodeShutdownMessage)
+		switch msg.Event().Id {
+		case events.START_UNCONFIGURE:
+			w.Commands <- NewShutdownCommand()
+		}
+
 	case *events.NodeShutdownCompleteMessage:
 		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
 		switch msg.Event().Id {
@@ -84,10 +98,14 @@ func (w *HelmWorker) CommandHandler(command worker.Command) bool {
 		} else {
 			glog.V(5).Infof(hpwlog(fmt.Sprintf("LaunchContext(%T): %v", lc, lc)))
 
-			// Check the deployment string to see if it's a Helm deployment.
-			deploymentConfig := lc.ContainerConfig().Deployment
+			// Check the deployment string to see if it's a Helm deployment. A service deployed to a cluster has its
+			// Helm chart in the cluster deployment, the deployment is checked for the services that predate it.
+			deploymentConfig := lc.ContainerConfig().ClusterDeployment
+			if deploymentConfig == "" {
+				deploymentConfig = lc.ContainerConfig().Deployment
+			}
 			if hd, err := persistence.GetHelmDeployment(deploymentConfig); err != nil {
-				glog.Warningf(hpwlog(fmt.Sprintf("ignoring non-Helm deployment: %v", err)))
+				glog.V(5).Infof(hpwlog(fmt.Sprintf("ignoring non-Helm deployment: %v", err)))
 				return true
 			} else if _, err := persistence.AgreementDeploymentStarted(w.db, lc.AgreementId, lc.AgreementProtocol, hd); err != nil {
 				glog.Errorf(hpwlog(fmt.Sprintf("received error updating database deployment state, %v", err)))
@@ -114,7 +132,7 @@ func (w *HelmWorker) CommandHandler(command worker.Command) bool {
 		if !ok {
 			glog.Warningf(hpwlog(fmt.Sprintf("ignoring non-Helm deployment: %v", cmd.Deployment)))
 			return true
-		} else if err := w.uninstallHelmPackage(hdc); err != nil {
+		} else if err := w.releaseAgreementEnded(hdc, cmd.CurrentAgreementId); err != nil {
 			// Since we have a Helm deployment package, uninstall it.
 			glog.Errorf(hpwlog(fmt.Sprintf("failed to uninstall helm package after agreement cancellation: %v", err)))
 		}
@@ -129,12 +147,17 @@ func (w *HelmWorker) CommandHandler(command worker.Command) bool {
 		if !ok {
 			glog.Warningf(hpwlog(fmt.Sprintf("ignoring non-Helm maintenance command: %v", cmd)))
 			return true
-		} else if err := w.releaseStatus(hdc, "DEPLOYED"); err != nil {
+		} else if err := w.releaseStatus(hdc, cmd.AgreementId); err != nil {
 			glog.Errorf(hpwlog(fmt.Sprintf("%v", err)))
 			// Ask governer to cancel the agreement.
 			w.Messages() <- events.NewWorkloadMessage(events.EXECUTION_FAILED, cmd.AgreementProtocol, cmd.AgreementId, hdc)
 		}
 
+	case *ShutdownCommand:
+		// The agreements are about to be cancelled, their releases are not going to be taken over.
+		w.shuttingDown = true
+		w.uninstallEndedReleases(true)
+
 	default:
 		return false
 	}
@@ -142,6 +165,11 @@ func (w *HelmWorker) CommandHandler(command worker.Command) bool {
 
 }
 
+// Uninstall the releases whose uninstall was deferred, when the deferral ran out.
+func (w *HelmWorker) NoWorkHandler() {
+	w.uninstallEndedReleases(false)
+}
+
 func (w *HelmWorker) getLaunchContext(launchContext interface{}) *events.AgreementLaunchContext {
 	switch launchContext.(type) {
 	case *events.AgreementLaunchContext:
@@ -151,46 +179,167 @@ func (w *HelmWorker) getLaunchContext(launchContext interface{}) *events.Agreeme
 	return nil
 }
 
+// Install the Helm chart of an agreement. When the release already exists because an agreement for another version of
+// the service installed it, the release is upgraded in place and taken over by the agreement. A failed upgrade is
+// rolled back to the revision that was running before it.
 func (w *HelmWorker) processHelmPackage(launchContext *events.AgreementLaunchContext, hd *persistence.HelmDeploymentConfig) error {
 
 	glog.V(5).Infof(hpwlog(fmt.Sprintf("begin install of Helm Deployment release %v", hd.ReleaseName)))
 
 	// TODO: Verify signature
 
+	envAdds := map[string]string{}
+	if launchContext.EnvironmentAdditions != nil {
+		envAdds = *launchContext.EnvironmentAdditions
+	}
+	values := MakeReleaseValues(hd, launchContext.AgreementId, envAdds)
+
 	c := NewHelmClient()
-	if err := c.Install(hd.ChartArchive, hd.ReleaseName); err != nil {
-		return errors.New(fmt.Sprintf("unable to install Helm package %v, error: %v", hd, err))
+
+	release, err := persistence.FindHelmRelease(w.db, hd.ReleaseName)
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to read Helm release %v from the database, error: %v", hd.ReleaseName, err))
+	}
+
+	// A release can not be upgraded into another namespace, the old one is replaced.
+	if release != nil && release.Namespace != hd.Namespace {
+		glog.Infof(hpwlog(fmt.Sprintf("replacing Helm release %v in namespace %v with a release in namespace %v", hd.ReleaseName, release.Namespace, hd.Namespace)))
+		w.uninstallRelease(release)
+		release = nil
+	}
+
+	newRelease := &persistence.HelmRelease{
+		ReleaseName: hd.ReleaseName,
+		Namespace:   hd.Namespace,
+		AgreementId: launchContext.AgreementId,
+	}
+
+	if release == nil {
+		if err := c.Install(hd.ChartArchive, hd.ReleaseName, hd.Namespace, values); err != nil {
+			return errors.New(fmt.Sprintf("unable to install Helm package %v, error: %v", hd, err))
+		}
+	} else {
+		newRelease.PreviousRevision = release.Revision
+		if status, err := c.Status(hd.ReleaseName, hd.Namespace); err == nil {
+			newRelease.PreviousRevision = status.Revision
+		}
+
+		glog.Infof(hpwlog(fmt.Sprintf("upgrading Helm release %v revision %v of agreement %v for agreement %v", hd.ReleaseName, newRelease.PreviousRevision, release.AgreementId, launchContext.AgreementId)))
+		if err := c.Upgrade(hd.ChartArchive, hd.ReleaseName, hd.Namespace, values); err != nil {
+			if newRelease.PreviousRevision != 0 {
+				if rbErr := c.Rollback(hd.ReleaseName, hd.Namespace, newRelease.PreviousRevision); rbErr != nil {
+					glog.Errorf(hpwlog(fmt.Sprintf("unable to roll back Helm release %v to revision %v, error: %v", hd.ReleaseName, newRelease.PreviousRevision, rbErr)))
+				} else {
+					glog.Infof(hpwlog(fmt.Sprintf("rolled back Helm release %v to revision %v", hd.ReleaseName, newRelease.PreviousRevision)))
+				}
+			}
+			return errors.New(fmt.Sprintf("unable to upgrade Helm package %v, error: %v", hd, err))
+		}
+	}
+
+	if status, err := c.Status(hd.ReleaseName, hd.Namespace); err != nil {
+		glog.Warningf(hpwlog(fmt.Sprintf("unable to get the status of Helm release %v, error: %v", hd.ReleaseName, err)))
+	} else {
+		newRelease.Revision = status.Revision
+	}
+
+	if err := persistence.SaveHelmRelease(w.db, newRelease); err != nil {
+		return errors.New(fmt.Sprintf("unable to save Helm release %v, error: %v", newRelease, err))
 	}
 
-	glog.V(5).Infof(hpwlog(fmt.Sprintf("completed install of Helm Deployment release %v", hd.ReleaseName)))
+	glog.V(5).Infof(hpwlog(fmt.Sprintf("completed install of Helm Deployment release %v", newRelease)))
 
 	return nil
 }
 
-func (w *HelmWorker) uninstallHelmPackage(hd *persistence.HelmDeploymentConfig) error {
+// Called when the agreement of a release ended. The release is left alone when another agreement took it over. The
+// uninstall is deferred for the configured upgrade window, so that an agreement for another version of the service
+// can upgrade the release in place instead of installing it again.
+func (w *HelmWorker) releaseAgreementEnded(hd *persistence.HelmDeploymentConfig, agreementId string) error {
 
-	glog.V(5).Infof(hpwlog(fmt.Sprintf("begin uninstall of Helm Deployment release %v", hd.ReleaseName)))
+	release, err := persistence.FindHelmRelease(w.db, hd.ReleaseName)
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to read Helm release %v from the database, error: %v", hd.ReleaseName, err))
+	} else if release == nil {
+		// Releases installed before the agent kept track of them.
+		release = &persistence.HelmRelease{ReleaseName: hd.ReleaseName, Namespace: hd.Namespace, AgreementId: agreementId}
+	} else if release.AgreementId != agreementId {
+		glog.Infof(hpwlog(fmt.Sprintf("Helm release %v was taken over by agreement %v, not uninstalling it for agreement %v", hd.ReleaseName, release.AgreementId, agreementId)))
+		return nil
+	}
+
+	if window := w.Config.GetHelmUpgradeWindowS(); window > 0 && !w.shuttingDown {
+		release.UninstallTime = uint64(time.Now().Unix() + window)
+		glog.Infof(hpwlog(fmt.Sprintf("deferring uninstall of Helm release %v for %v seconds", hd.ReleaseName, window)))
+		return persistence.SaveHelmRelease(w.db, release)
+	}
+
+	return w.uninstallRelease(release)
+}
+
+// Uninstall the releases whose agreement ended, when their uninstall time passed or regardless of it.
+func (w *HelmWorker) uninstallEndedReleases(all bool) {
+
+	releases, err := persistence.FindHelmReleases(w.db)
+	if err != nil {
+		glog.Errorf(hpwlog(fmt.Sprintf("unable to read Helm releases from the database, error: %v", err)))
+		return
+	}
+
+	now := uint64(time.Now().Unix())
+	for _, release := range releases {
+		if release.UninstallTime != 0 && (all || release.UninstallTime <= now) {
+			if err := w.uninstallRelease(&release); err != nil {
+				glog.Errorf(hpwlog(err))
+			}
+		}
+	}
+}
+
+func (w *HelmWorker) uninstallRelease(release *persistence.HelmRelease) error {
+
+	glog.V(5).Infof(hpwlog(fmt.Sprintf("begin uninstall of Helm Deployment release %v", release.ReleaseName)))
+
+	// The release is forgotten even if the uninstall fails, there is nothing more the agent can do with it.
+	if err := persistence.DeleteHelmRelease(w.db, release.ReleaseName); err != nil {
+		glog.Errorf(hpwlog(err))
+	}
 
 	c := NewHelmClient()
-	if err := c.UnInstall(hd.ReleaseName); err != nil {
-		return errors.New(fmt.Sprintf("unable to uninstall Helm package %v, error: %v", hd, err))
+	if err := c.UnInstall(release.ReleaseName, release.Namespace); err != nil {
+		return errors.New(fmt.Sprintf("unable to uninstall Helm release %v, error: %v", release, err))
 	}
 
-	glog.V(5).Infof(hpwlog(fmt.Sprintf("completed uninstall of Helm Deployment release %v", hd.ReleaseName)))
+	glog.V(5).Infof(hpwlog(fmt.Sprintf("completed uninstall of Helm Deployment release %v", release.ReleaseName)))
 
 	return nil
 }
 
-func (w *HelmWorker) releaseStatus(hd *persistence.HelmDeploymentConfig, desiredStatus string) error {
+// Returns an error if the release of the agreement is not deployed. A release that failed after an upgrade by the
+// agreement is rolled back to the revision that was running before the upgrade, the agreement is cancelled anyway.
+func (w *HelmWorker) releaseStatus(hd *persistence.HelmDeploymentConfig, agreementId string) error {
 
 	glog.V(5).Infof(hpwlog(fmt.Sprintf("begin listing Helm Deployment release %v", hd.ReleaseName)))
 
 	c := NewHelmClient()
-	status, err := c.Status(hd.ReleaseName)
+	status, err := c.Status(hd.ReleaseName, hd.Namespace)
 	if err != nil {
 		return errors.New(fmt.Sprintf("unable to list Helm release %v, error: %v", hd.ReleaseName, err))
-	} else if status.Status != desiredStatus {
-		return errors.New(fmt.Sprintf("Helm release %v is not in desiredStatus %v, in %v", hd.ReleaseName, desiredStatus, status))
+	} else if !status.IsDeployed() {
+		if release, err := persistence.FindHelmRelease(w.db, hd.ReleaseName); err != nil {
+			glog.Errorf(hpwlog(err))
+		} else if release != nil && release.AgreementId == agreementId && release.PreviousRevision != 0 {
+			if err := c.Rollback(hd.ReleaseName, hd.Namespace, release.PreviousRevision); err != nil {
+				glog.Errorf(hpwlog(fmt.Sprintf("unable to roll back Helm release %v to revision %v, error: %v", hd.ReleaseName, release.PreviousRevision, err)))
+			} else {
+				glog.Infof(hpwlog(fmt.Sprintf("rolled back Helm release %v to revision %v", hd.ReleaseName, release.PreviousRevision)))
+				release.PreviousRevision = 0
+				if err := persistence.SaveHelmRelease(w.db, release); err != nil {
+					glog.Errorf(hpwlog(err))
+				}
+			}
+		}
+		return errors.New(fmt.Sprintf("Helm release %v is not in desiredStatus %v, in %v", hd.ReleaseName, DEPLOYED, status))
 	}
 
 	glog.V(5).Infof(hpwlog(fmt.Sprintf("completed listing Helm Deployment release %v, status %v", hd.ReleaseName, status)))
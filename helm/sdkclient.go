@@ -0,0 +1,185 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/release"
+	"os"
+	"time"
+)
+
+// This client implements our abstract helm client interface, using the Helm 3 Go SDK. It talks to the cluster with the
+// in-cluster config of the agent, or the kube config named by the KUBECONFIG environment variable, so the agent does
+// not need the helm binary. The releases are stored by the storage driver named by the HELM_DRIVER environment
+// variable, secrets by default, the same as the CLI.
+
+type SDKClient struct {
+}
+
+func NewSDKClient() *SDKClient {
+	return new(SDKClient)
+}
+
+// Returns the action configuration for the releases in the namespace, the helm default namespace when it is empty.
+func (c *SDKClient) actionConfig(namespace string) (*action.Configuration, string, error) {
+	settings := cli.New()
+	if namespace != "" {
+		settings.SetNamespace(namespace)
+	}
+
+	cfg := new(action.Configuration)
+	if err := cfg.Init(settings.RESTClientGetter(), settings.Namespace(), os.Getenv("HELM_DRIVER"), sdklog); err != nil {
+		return nil, "", errors.New(fmt.Sprintf("error initializing the Helm configuration for namespace %v: %v", settings.Namespace(), err))
+	}
+	return cfg, settings.Namespace(), nil
+}
+
+// Load the chart from the base 64 encoded chart archive.
+func loadChart(b64Package string) (*chart.Chart, error) {
+	if sDec, err := base64.StdEncoding.DecodeString(b64Package); err != nil {
+		return nil, errors.New(fmt.Sprintf("error decoding Helm package: %v", err))
+	} else if ch, err := loader.LoadArchive(bytes.NewReader(sDec)); err != nil {
+		return nil, errors.New(fmt.Sprintf("error loading Helm package: %v", err))
+	} else {
+		return ch, nil
+	}
+}
+
+func (c *SDKClient) Install(b64Package string, releaseName string, namespace string, values map[string]interface{}) error {
+
+	ch, err := loadChart(b64Package)
+	if err != nil {
+		return err
+	}
+
+	cfg, ns, err := c.actionConfig(namespace)
+	if err != nil {
+		return err
+	}
+
+	install := action.NewInstall(cfg)
+	install.ReleaseName = releaseName
+	install.Namespace = ns
+	install.CreateNamespace = namespace != ""
+
+	glog.V(5).Infof(sdklogString(fmt.Sprintf("Installing Helm release %v of chart %v into namespace %v", releaseName, ch.Name(), ns)))
+	if rel, err := install.Run(ch, values); err != nil {
+		return errors.New(fmt.Sprintf("error installing Helm package: %v", err))
+	} else {
+		glog.V(5).Infof(sdklogString(fmt.Sprintf("Installed Helm release %v revision %v", rel.Name, rel.Version)))
+	}
+
+	return nil
+}
+
+func (c *SDKClient) Upgrade(b64Package string, releaseName string, namespace string, values map[string]interface{}) error {
+
+	ch, err := loadChart(b64Package)
+	if err != nil {
+		return err
+	}
+
+	cfg, ns, err := c.actionConfig(namespace)
+	if err != nil {
+		return err
+	}
+
+	upgrade := action.NewUpgrade(cfg)
+	upgrade.Namespace = ns
+
+	glog.V(5).Infof(sdklogString(fmt.Sprintf("Upgrading Helm release %v to chart %v in namespace %v", releaseName, ch.Name(), ns)))
+	if rel, err := upgrade.Run(releaseName, ch, values); err != nil {
+		return errors.New(fmt.Sprintf("error upgrading Helm release: %v", err))
+	} else {
+		glog.V(5).Infof(sdklogString(fmt.Sprintf("Upgraded Helm release %v to revision %v", rel.Name, rel.Version)))
+	}
+
+	return nil
+}
+
+func (c *SDKClient) Rollback(releaseName string, namespace string, revision int) error {
+
+	cfg, ns, err := c.actionConfig(namespace)
+	if err != nil {
+		return err
+	}
+
+	rollback := action.NewRollback(cfg)
+	rollback.Version = revision
+
+	glog.V(5).Infof(sdklogString(fmt.Sprintf("Rolling back Helm release %v in namespace %v to revision %v", releaseName, ns, revision)))
+	if err := rollback.Run(releaseName); err != nil {
+		return errors.New(fmt.Sprintf("error rolling back Helm release: %v", err))
+	}
+
+	return nil
+}
+
+func (c *SDKClient) UnInstall(releaseName string, namespace string) error {
+
+	cfg, ns, err := c.actionConfig(namespace)
+	if err != nil {
+		return err
+	}
+
+	glog.V(5).Infof(sdklogString(fmt.Sprintf("Uninstalling Helm release %v in namespace %v", releaseName, ns)))
+	if _, err := action.NewUninstall(cfg).Run(releaseName); err != nil {
+		return errors.New(fmt.Sprintf("error uninstalling Helm package: %v", err))
+	}
+
+	return nil
+}
+
+func (c *SDKClient) Status(releaseName string, namespace string) (*ReleaseStatus, error) {
+
+	cfg, _, err := c.actionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if rel, err := action.NewStatus(cfg).Run(releaseName); err != nil {
+		return nil, errors.New(fmt.Sprintf("error getting Helm release status: %v", err))
+	} else {
+		return convertRelease(rel), nil
+	}
+}
+
+// Convert a release of the Helm SDK into a release status.
+func convertRelease(rel *release.Release) *ReleaseStatus {
+	status := &ReleaseStatus{
+		Name:      rel.Name,
+		Revision:  rel.Version,
+		Namespace: rel.Namespace,
+	}
+	if rel.Info != nil {
+		status.Status = rel.Info.Status.String()
+		status.Updated = rel.Info.LastDeployed.Format(HelmSDKReleaseStatusTimeFormat)
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		status.ChartName = rel.Chart.Metadata.Name
+		status.ChartVersion = rel.Chart.Metadata.Version
+	}
+	return status
+}
+
+const HelmSDKReleaseStatusTimeFormat = time.RFC3339
+
+func (c *SDKClient) ReleaseTimeFormat() string {
+	return HelmSDKReleaseStatusTimeFormat
+}
+
+// The debug log of the Helm SDK.
+func sdklog(format string, v ...interface{}) {
+	glog.V(5).Infof(sdklogString(fmt.Sprintf(format, v...)))
+}
+
+var sdklogString = func(v interface{}) string {
+	return fmt.Sprintf("Helm SDKClient: %v", v)
+}
@@ -0,0 +1,31 @@
+package helm
+
+import (
+	"github.com/open-horizon/anax/persistence"
+)
+
+// The key of the chart values that the agent reserves for the per-agreement values.
+const HORIZON_VALUES_KEY = "horizon"
+
+// Returns the chart values of a release installed for an agreement. The values of the deployment config are extended
+// with a "horizon" section that holds the agreement id and the environment variables of the service, i.e. the user
+// input of the service, its default user input and the platform variables, the same environment variables that a
+// container of the service gets. A chart refers to them as .Values.horizon.env.<name>.
+func MakeReleaseValues(hd *persistence.HelmDeploymentConfig, agreementId string, envAdds map[string]string) map[string]interface{} {
+
+	values := make(map[string]interface{}, len(hd.Values)+1)
+	for k, v := range hd.Values {
+		values[k] = v
+	}
+
+	env := make(map[string]interface{}, len(envAdds))
+	for k, v := range envAdds {
+		env[k] = v
+	}
+
+	values[HORIZON_VALUES_KEY] = map[string]interface{}{
+		"agreementId": agreementId,
+		"env":         env,
+	}
+	return values
+}
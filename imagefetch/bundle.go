@@ -0,0 +1,335 @@
+package imagefetch
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/rsapss-tool/verify"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// An offline bundle delivers a service to a node that cannot reach the image registries. The bundle is a tar file, which
+// can be gzipped, with the following files:
+//
+//	bundle.json     - the bundle manifest, see BundleManifest.
+//	bundle.json.sig - the signature of bundle.json, created with the service publisher's private key (hzn util sign).
+//	the image tarballs that are listed in the manifest, created with docker save.
+//
+// The signature of the manifest is verified with the trusted public keys of the node and the manifest carries the hash
+// of each image tarball, so every file of the bundle is verified before the bundle is stored. The service definition is
+// only used to verify the bundle, it is not stored. When the images of a service are fetched, the images that were
+// delivered in the bundle of that service are loaded from the bundle store instead of pulled.
+const BUNDLE_MANIFEST = "bundle.json"
+const BUNDLE_MANIFEST_SIGNATURE = "bundle.json.sig"
+
+type BundleManifest struct {
+	Org       string                     `json:"org"`                 // the org of the service
+	Service   exchange.ServiceDefinition `json:"service"`             // the service definition, as it is published in the exchange
+	UserInput []policy.UserInput         `json:"userInput,omitempty"` // the user input for the service, it is added to the node user input
+	Images    []BundleImage              `json:"images"`              // the image tarballs of the service
+}
+
+type BundleImage struct {
+	Image  string `json:"image"`            // the image reference, exactly as it is used in the deployment string
+	File   string `json:"file"`             // the path of the image tarball in the bundle
+	Sha256 string `json:"sha256"`           // the hex encoded sha256 hash of the image tarball
+	Digest string `json:"digest,omitempty"` // the repository digest of the image (sha256:...), so that the image also matches a reference that is pinned by digest
+}
+
+// Returns the id of the bundle of a service, which is also the name of its directory in the bundle store.
+func BundleId(org string, url string, version string, arch string) string {
+	return org + "_" + cutil.FormExchangeIdForService(url, version, arch)
+}
+
+// Verify that the manifest describes a service with a signed deployment, and that every image of the deployment is
+// in the bundle.
+func (m *BundleManifest) Validate(pemFiles []string) error {
+	if m.Org == "" || m.Service.URL == "" || m.Service.Version == "" || m.Service.Arch == "" {
+		return errors.New("the bundle manifest must have the org, url, version and arch of the service")
+	} else if m.Service.Deployment == "" {
+		return errors.New("the service in the bundle does not have a deployment string")
+	} else if verified, _, failed_map := verify.InputVerifiedByAnyKey(pemFiles, m.Service.DeploymentSignature, []byte(m.Service.Deployment)); !verified {
+		glog.Errorf("Unable to verify the deployment signature of bundle service %v/%v: %v", m.Org, m.Service.URL, failed_map)
+		return errors.New("there is no trusted public key that verifies the deployment signature of the service")
+	}
+
+	deploymentDesc, err := containermessage.GetNativeDeployment(m.Service.Deployment)
+	if err != nil {
+		return fmt.Errorf("the deployment of the service is not valid: %v", err)
+	}
+
+	images := make(map[string]bool)
+	for _, img := range m.Images {
+		if img.Image == "" || img.File == "" || img.Sha256 == "" {
+			return fmt.Errorf("bundle image %v must have an image, a file and a sha256 hash", img)
+		} else if outsideBundle(img.File) {
+			return fmt.Errorf("the file of bundle image %v is outside of the bundle", img)
+		} else if img.Digest != "" && !strings.HasPrefix(img.Digest, "sha256:") {
+			return fmt.Errorf("the digest of bundle image %v must be a sha256 digest", img)
+		}
+		images[img.Image] = true
+	}
+	for name, service := range deploymentDesc.Services {
+		if !images[service.Image] {
+			return fmt.Errorf("image %v of service %v is not in the bundle", service.Image, name)
+		}
+	}
+	return nil
+}
+
+// Verify the bundle that is read from the reader and store it in the bundle store directory. A bundle for the same
+// service version and arch replaces the existing one.
+func LoadBundle(db *bolt.DB, storeDir string, pemFiles []string, r io.Reader, source string) (*persistence.OfflineBundle, error) {
+
+	if err := os.MkdirAll(storeDir, 0700); err != nil {
+		return nil, fmt.Errorf("unable to create the bundle store %v, error: %v", storeDir, err)
+	}
+
+	// The files are extracted next to their final location so that moving them is a rename.
+	staging, err := ioutil.TempDir(storeDir, ".staging-")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create a staging directory in %v, error: %v", storeDir, err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := extractBundle(r, staging); err != nil {
+		return nil, err
+	}
+
+	manifest, err := readBundleManifest(staging, pemFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, img := range manifest.Images {
+		if hash, err := fileSha256(path.Join(staging, path.Clean(img.File))); err != nil {
+			return nil, fmt.Errorf("unable to read the tarball of image %v, error: %v", img.Image, err)
+		} else if !strings.EqualFold(hash, img.Sha256) {
+			return nil, fmt.Errorf("the tarball of image %v does not match the hash in the bundle manifest", img.Image)
+		}
+	}
+
+	id := BundleId(manifest.Org, manifest.Service.URL, manifest.Service.Version, manifest.Service.Arch)
+	bundleDir := path.Join(storeDir, id)
+	if err := os.RemoveAll(bundleDir); err != nil {
+		return nil, fmt.Errorf("unable to remove the previous bundle %v, error: %v", id, err)
+	} else if err := os.Rename(staging, bundleDir); err != nil {
+		return nil, fmt.Errorf("unable to move bundle %v into the bundle store, error: %v", id, err)
+	}
+
+	bundle := &persistence.OfflineBundle{
+		Id:        id,
+		Org:       manifest.Org,
+		URL:       manifest.Service.URL,
+		Version:   manifest.Service.Version,
+		Arch:      manifest.Service.Arch,
+		Images:    make(map[string]string),
+		Digests:   make(map[string]string),
+		UserInput: manifest.UserInput,
+		Source:    source,
+		LoadTime:  uint64(time.Now().Unix()),
+	}
+	for _, img := range manifest.Images {
+		bundle.Images[img.Image] = path.Join(bundleDir, path.Clean(img.File))
+		if img.Digest != "" {
+			bundle.Digests[img.Image] = img.Digest
+		}
+	}
+
+	if err := persistence.SaveOfflineBundle(db, bundle); err != nil {
+		os.RemoveAll(bundleDir)
+		return nil, fmt.Errorf("unable to save bundle %v, error: %v", id, err)
+	}
+
+	glog.V(3).Infof("Loaded offline bundle %v from %v with images %v", id, source, bundle.Images)
+	return bundle, nil
+}
+
+// Remove the bundle from the local database and its files from the bundle store. The images that were already
+// loaded into docker are not removed.
+func RemoveBundle(db *bolt.DB, storeDir string, id string) error {
+	if err := persistence.DeleteOfflineBundle(db, id); err != nil {
+		return err
+	}
+	return os.RemoveAll(path.Join(storeDir, id))
+}
+
+// Returns true if the file name, which is relative to the bundle, is absolute or refers to a file outside of the bundle.
+func outsideBundle(name string) bool {
+	name = path.Clean(name)
+	return path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../")
+}
+
+// Extract the regular files of the bundle into the directory. The bundle can be gzipped.
+func extractBundle(r io.Reader, dir string) error {
+
+	br := bufio.NewReader(r)
+	var tr *tar.Reader
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("unable to read the gzipped bundle, error: %v", err)
+		}
+		defer gzr.Close()
+		tr = tar.NewReader(gzr)
+	} else {
+		tr = tar.NewReader(br)
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read the bundle, error: %v", err)
+		}
+
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		} else if hdr.Typeflag != tar.TypeReg {
+			return fmt.Errorf("bundle file %v is not a regular file", hdr.Name)
+		}
+
+		name := path.Clean(hdr.Name)
+		if outsideBundle(name) {
+			return fmt.Errorf("bundle file %v is outside of the bundle", hdr.Name)
+		}
+
+		fileName := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+			return fmt.Errorf("unable to create the directory for bundle file %v, error: %v", hdr.Name, err)
+		}
+		f, err := os.OpenFile(fileName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("unable to create bundle file %v, error: %v", hdr.Name, err)
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("unable to extract bundle file %v, error: %v", hdr.Name, err)
+		}
+	}
+}
+
+// Read the bundle manifest from the extracted bundle and verify its signature.
+func readBundleManifest(dir string, pemFiles []string) (*BundleManifest, error) {
+
+	manifestBytes, err := ioutil.ReadFile(path.Join(dir, BUNDLE_MANIFEST))
+	if err != nil {
+		return nil, fmt.Errorf("the bundle does not have a %v file, error: %v", BUNDLE_MANIFEST, err)
+	}
+	sig, err := ioutil.ReadFile(path.Join(dir, BUNDLE_MANIFEST_SIGNATURE))
+	if err != nil {
+		return nil, fmt.Errorf("the bundle is not signed, there is no %v file, error: %v", BUNDLE_MANIFEST_SIGNATURE, err)
+	}
+
+	if verified, _, failed_map := verify.InputVerifiedByAnyKey(pemFiles, strings.TrimSpace(string(sig)), manifestBytes); !verified {
+		glog.Errorf("Unable to verify the bundle manifest signature: %v", failed_map)
+		return nil, errors.New("there is no trusted public key that verifies the signature of the bundle manifest")
+	}
+
+	var manifest BundleManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal the bundle manifest, error: %v", err)
+	} else if err := manifest.Validate(pemFiles); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+func fileSha256(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns the image reference in the bundle and its tarball file that match the image of a service, or empty strings
+// when the image was not delivered in the bundle. An image that is pinned by digest matches the bundle image of the same
+// repository with that digest.
+func bundleImage(bundle *persistence.OfflineBundle, image string) (string, string) {
+	if bundle == nil {
+		return "", ""
+	} else if file, ok := bundle.Images[image]; ok {
+		return image, file
+	}
+
+	if _, _, _, digest := cutil.ParseDockerImagePath(image); digest != "" {
+		repo := containermessage.ImageRepository(image)
+		for ref, d := range bundle.Digests {
+			if d == digest && containermessage.ImageRepository(ref) == repo {
+				return ref, bundle.Images[ref]
+			}
+		}
+	}
+	return "", ""
+}
+
+// Load the images of the deployment that were delivered in the offline bundle of the service into docker. The services
+// are changed to use the image reference of the bundle, which is what the loaded image is tagged with. Returns a copy of
+// the deployment description with only the services whose images still have to be pulled.
+func loadBundleImages(client *docker.Client, bundle *persistence.OfflineBundle, deploymentDesc *containermessage.DeploymentDescription) (*containermessage.DeploymentDescription, error) {
+	if bundle == nil {
+		return deploymentDesc, nil
+	}
+
+	toPull := *deploymentDesc
+	toPull.Services = make(map[string]*containermessage.Service)
+	for name, service := range deploymentDesc.Services {
+		if ref, file := bundleImage(bundle, service.Image); file == "" {
+			toPull.Services[name] = service
+		} else if err := loadImageFile(client, ref, file); err != nil {
+			return nil, err
+		} else {
+			glog.V(3).Infof("Loaded image %v for service %v from offline bundle file %v", ref, name, file)
+			service.Image = ref
+		}
+	}
+	return &toPull, nil
+}
+
+// Load the image tarball into docker, unless the image is already there.
+func loadImageFile(client *docker.Client, image string, file string) error {
+	if _, err := client.InspectImage(image); err == nil {
+		return nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("unable to open the offline bundle file %v of image %v, error: %v", file, image, err)
+	}
+	defer f.Close()
+
+	if err := client.LoadImage(docker.LoadImageOptions{InputStream: f}); err != nil {
+		return fmt.Errorf("unable to load image %v from offline bundle file %v, error: %v", image, file, err)
+	} else if _, err := client.InspectImage(image); err != nil {
+		return fmt.Errorf("offline bundle file %v does not contain image %v", file, image)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+//go:build unit
+// +build unit
+
+package imagefetch
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/rsapss-tool/generatekeys"
+	"github.com/open-horizon/rsapss-tool/sign"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+const gpsDigest = "sha256:5b0d6b8a3c2f0e6f8d1a9c4b7e2f3a1d0c9b8a7f6e5d4c3b2a1f0e9d8c7b6a5f"
+
+type bundleFile struct {
+	name    string
+	content []byte
+}
+
+func Test_LoadBundle(t *testing.T) {
+	dir, db, pubKey, privKey := setupBundleTest(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	storeDir := path.Join(dir, "bundles")
+	image := []byte("the gps image tarball")
+	files := signedBundle(t, privKey, image, sha256Hex(image))
+
+	bundle, err := LoadBundle(db, storeDir, []string{pubKey}, tarBundle(t, files), "upload")
+	if err != nil {
+		t.Fatalf("the bundle should be loaded: %v", err)
+	} else if bundle.Id != "myorg_ibm.gps_2.0.3_amd64" {
+		t.Errorf("wrong bundle id: %v", bundle.Id)
+	}
+
+	stored, err := persistence.FindServiceOfflineBundle(db, "myorg", "ibm.gps", "2.0.3")
+	if err != nil || stored == nil {
+		t.Fatalf("unable to find the bundle of the service: %v %v", stored, err)
+	} else if other, err := persistence.FindServiceOfflineBundle(db, "otherorg", "ibm.gps", "2.0.3"); err != nil || other != nil {
+		t.Errorf("the bundle should only be found for its own service: %v %v", other, err)
+	}
+
+	if ref, file := bundleImage(stored, "openhorizon/gps:2.0.3"); ref != "openhorizon/gps:2.0.3" || file != path.Join(storeDir, bundle.Id, "images/gps.tar") {
+		t.Errorf("wrong bundle image: %v %v", ref, file)
+	} else if content, err := ioutil.ReadFile(file); err != nil || !bytes.Equal(content, image) {
+		t.Errorf("the image tarball was not stored, error: %v", err)
+	}
+
+	// an image that is pinned by digest matches the bundle image of the same repository and digest
+	if ref, file := bundleImage(stored, "openhorizon/gps@"+gpsDigest); ref != "openhorizon/gps:2.0.3" || file == "" {
+		t.Errorf("the pinned image should match the bundle image: %v %v", ref, file)
+	} else if ref, file := bundleImage(stored, "openhorizon/gps@sha256:0000"); ref != "" || file != "" {
+		t.Errorf("an image pinned to another digest should not match the bundle image: %v %v", ref, file)
+	} else if ref, file := bundleImage(stored, "openhorizon/other@"+gpsDigest); ref != "" || file != "" {
+		t.Errorf("an image of another repository should not match the bundle image: %v %v", ref, file)
+	}
+
+	if ref, file := bundleImage(stored, "openhorizon/other:1.0"); ref != "" || file != "" {
+		t.Errorf("an image that is not in the bundle should not be found: %v %v", ref, file)
+	}
+
+	if err := RemoveBundle(db, storeDir, bundle.Id); err != nil {
+		t.Errorf("unable to remove the bundle: %v", err)
+	} else if b, _ := persistence.FindOfflineBundle(db, bundle.Id); b != nil {
+		t.Errorf("the bundle record should be removed")
+	} else if _, err := os.Stat(path.Join(storeDir, bundle.Id)); !os.IsNotExist(err) {
+		t.Errorf("the bundle files should be removed")
+	}
+}
+
+func Test_LoadBundle_invalid(t *testing.T) {
+	dir, db, pubKey, privKey := setupBundleTest(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	storeDir := path.Join(dir, "bundles")
+	image := []byte("the gps image tarball")
+
+	// the tarball does not match the hash in the manifest
+	files := signedBundle(t, privKey, image, sha256Hex([]byte("another tarball")))
+	if _, err := LoadBundle(db, storeDir, []string{pubKey}, tarBundle(t, files), "upload"); err == nil {
+		t.Errorf("a bundle with a modified image tarball should not be loaded")
+	}
+
+	// the manifest is not signed
+	files = signedBundle(t, privKey, image, sha256Hex(image))
+	if _, err := LoadBundle(db, storeDir, []string{pubKey}, tarBundle(t, files[:1]), "upload"); err == nil {
+		t.Errorf("a bundle without a manifest signature should not be loaded")
+	}
+
+	// the manifest is signed with a key that is not trusted
+	if _, err := LoadBundle(db, storeDir, []string{}, tarBundle(t, files), "upload"); err == nil {
+		t.Errorf("a bundle that is not verified by a trusted key should not be loaded")
+	}
+
+	// a file is outside of the bundle
+	files = append(files, bundleFile{name: "../outside", content: []byte("x")})
+	if _, err := LoadBundle(db, storeDir, []string{pubKey}, tarBundle(t, files), "upload"); err == nil {
+		t.Errorf("a bundle with a file outside of the bundle should not be loaded")
+	} else if _, err := os.Stat(path.Join(dir, "outside")); !os.IsNotExist(err) {
+		t.Errorf("the file outside of the bundle should not be written")
+	}
+
+	// the manifest refers to an image file outside of the bundle, even one that matches the hash
+	if err := ioutil.WriteFile(path.Join(storeDir, "gps.tar"), image, 0600); err != nil {
+		t.Fatalf("unable to write the image tarball: %v", err)
+	}
+	for _, imageFile := range []string{"../gps.tar", "/tmp/gps.tar"} {
+		files = signedBundleFile(t, privKey, image, sha256Hex(image), imageFile)
+		if _, err := LoadBundle(db, storeDir, []string{pubKey}, tarBundle(t, files), "upload"); err == nil {
+			t.Errorf("a bundle with image file %v should not be loaded", imageFile)
+		}
+	}
+
+	if bundles, err := persistence.FindOfflineBundles(db); err != nil || len(bundles) != 0 {
+		t.Errorf("no bundle should be loaded: %v %v", bundles, err)
+	}
+}
+
+func setupBundleTest(t *testing.T) (string, *bolt.DB, string, string) {
+	dir, err := ioutil.TempDir("", "bundle-")
+	if err != nil {
+		t.Fatalf("unable to create the test directory: %v", err)
+	}
+
+	db, err := bolt.Open(path.Join(dir, "anax-bundle.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("unable to open the database: %v", err)
+	}
+
+	keys, err := generatekeys.Write(dir, 2048, "test", "testorg", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unable to generate the keys: %v", err)
+	}
+	var pubKey, privKey string
+	for _, k := range keys {
+		if strings.HasSuffix(k, "public.pem") {
+			pubKey = k
+		} else {
+			privKey = k
+		}
+	}
+	return dir, db, pubKey, privKey
+}
+
+// Returns the files of a bundle for the gps service, the manifest signature is the second file and the image tarball
+// is the last one.
+func signedBundle(t *testing.T, privKey string, image []byte, imageHash string) []bundleFile {
+	return signedBundleFile(t, privKey, image, imageHash, "images/gps.tar")
+}
+
+// Returns the files of a bundle for the gps service with the image file name in the manifest.
+func signedBundleFile(t *testing.T, privKey string, image []byte, imageHash string, imageFile string) []bundleFile {
+	deployment := `{"services":{"gps":{"image":"openhorizon/gps:2.0.3"}}}`
+	deploymentSig, err := sign.Input(privKey, []byte(deployment))
+	if err != nil {
+		t.Fatalf("unable to sign the deployment: %v", err)
+	}
+
+	manifest := BundleManifest{
+		Org: "myorg",
+		Service: exchange.ServiceDefinition{
+			URL:                 "ibm.gps",
+			Version:             "2.0.3",
+			Arch:                "amd64",
+			Deployment:          deployment,
+			DeploymentSignature: deploymentSig,
+		},
+		Images: []BundleImage{{Image: "openhorizon/gps:2.0.3", File: imageFile, Sha256: imageHash, Digest: gpsDigest}},
+	}
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("unable to marshal the manifest: %v", err)
+	}
+	manifestSig, err := sign.Input(privKey, manifestBytes)
+	if err != nil {
+		t.Fatalf("unable to sign the manifest: %v", err)
+	}
+
+	return []bundleFile{
+		{name: BUNDLE_MANIFEST, content: manifestBytes},
+		{name: BUNDLE_MANIFEST_SIGNATURE, content: []byte(manifestSig)},
+		{name: "images/gps.tar", content: image},
+	}
+}
+
+func tarBundle(t *testing.T, files []bundleFile) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0600, Size: int64(len(f.content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("unable to write the tar header of %v: %v", f.name, err)
+		} else if _, err := tw.Write(f.content); err != nil {
+			t.Fatalf("unable to write %v: %v", f.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("unable to close the tar writer: %v", err)
+	}
+	return buf
+}
+
+func sha256Hex(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,50 @@
+package imagefetch
+
+import (
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/cutil"
+	"strings"
+)
+
+// The images of a deployment are referenced by tag, which can be moved to a different image at any time. So that all
+// the containers of a service run the bits that were pulled, each image is pinned to its content digest after it is
+// pulled, the containers are then created from the repository@digest reference. The consumer can also pin the images
+// to the digests that another node resolved, in which case those digests are pulled instead of the tags.
+
+// Change the images of the services that have a pinned digest to be referenced by that digest.
+func pinImages(deploymentDesc *containermessage.DeploymentDescription, digests map[string]string) {
+	for name, service := range deploymentDesc.Services {
+		if digest, ok := digests[name]; ok && digest != "" {
+			pinned := pinnedImage(service.Image, digest)
+			glog.V(3).Infof("Pinning image %v of service %v to %v", service.Image, name, pinned)
+			service.Image = pinned
+		}
+	}
+}
+
+// Resolve the content digest of each pulled image and change the images of the services to be referenced by digest.
+// An image that does not have a repository digest, e.g. an image that was built locally, keeps its reference. Returns
+// the digests keyed by container name.
+func resolveImageDigests(client *docker.Client, deploymentDesc *containermessage.DeploymentDescription) map[string]string {
+	digests := make(map[string]string)
+	for name, service := range deploymentDesc.Services {
+		if digest, err := imageDigest(client, service.Image); err != nil {
+			glog.V(3).Infof("Unable to resolve the digest of image %v of service %v, the image will not be pinned: %v", service.Image, name, err)
+		} else {
+			digests[name] = digest
+			service.Image = pinnedImage(service.Image, digest)
+		}
+	}
+	return digests
+}
+
+// Returns the repository@digest reference of an image. The tag and the digest of the image reference are removed.
+func pinnedImage(image string, digest string) string {
+	repo := strings.SplitN(image, "@", 2)[0]
+	if _, _, tag, _ := cutil.ParseDockerImagePath(repo); tag != "" {
+		repo = strings.TrimSuffix(repo, ":"+tag)
+	}
+	return repo + "@" + digest
+}
@@ -0,0 +1,46 @@
+//go:build unit
+// +build unit
+
+package imagefetch
+
+import (
+	"github.com/open-horizon/anax/containermessage"
+	"testing"
+)
+
+func Test_pinnedImage(t *testing.T) {
+	digest := "sha256:2222"
+	for image, expected := range map[string]string{
+		"openhorizon/gps:2.0.3":                       "openhorizon/gps@sha256:2222",
+		"openhorizon/gps":                             "openhorizon/gps@sha256:2222",
+		"myregistry.example.com:5000/edge/gps:1.0":    "myregistry.example.com:5000/edge/gps@sha256:2222",
+		"myregistry.example.com:5000/edge/gps":        "myregistry.example.com:5000/edge/gps@sha256:2222",
+		"openhorizon/gps:2.0.3@sha256:1111":           "openhorizon/gps@sha256:2222",
+		"myregistry.example.com/edge/gps@sha256:1111": "myregistry.example.com/edge/gps@sha256:2222",
+	} {
+		if pinned := pinnedImage(image, digest); pinned != expected {
+			t.Errorf("image %v should be pinned to %v, got %v", image, expected, pinned)
+		}
+	}
+}
+
+func Test_pinImages(t *testing.T) {
+	deploymentDesc := &containermessage.DeploymentDescription{
+		Services: map[string]*containermessage.Service{
+			"gps": &containermessage.Service{Image: "openhorizon/gps:2.0.3"},
+			"cpu": &containermessage.Service{Image: "openhorizon/cpu:1.0"},
+		},
+	}
+
+	pinImages(deploymentDesc, map[string]string{"gps": "sha256:2222"})
+	if image := deploymentDesc.Services["gps"].Image; image != "openhorizon/gps@sha256:2222" {
+		t.Errorf("the gps image should be pinned, got %v", image)
+	} else if image := deploymentDesc.Services["cpu"].Image; image != "openhorizon/cpu:1.0" {
+		t.Errorf("the cpu image should not be pinned, got %v", image)
+	}
+
+	// the digests are taken from the image references that are already pinned, so the docker client is not used
+	if digests := resolveImageDigests(nil, &containermessage.DeploymentDescription{Services: map[string]*containermessage.Service{"gps": deploymentDesc.Services["gps"]}}); digests["gps"] != "sha256:2222" {
+		t.Errorf("wrong resolved digests: %v", digests)
+	}
+}
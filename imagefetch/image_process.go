@@ -192,7 +192,7 @@ func processDeployment(cfg *config.HorizonConfig, containerConfig events.Contain
 	return pemFiles, &deploymentDesc, nil
 }
 
-func processFetch(cfg *config.HorizonConfig, client *docker.Client, db *bolt.DB, deploymentDesc *containermessage.DeploymentDescription, imageDockerAuths []events.ImageDockerAuth) error {
+func processFetch(cfg *config.HorizonConfig, client *docker.Client, db *bolt.DB, bundle *persistence.OfflineBundle, deploymentDesc *containermessage.DeploymentDescription, imageDockerAuths []events.ImageDockerAuth) error {
 	if client == nil {
 		return fmt.Errorf("Docker client is nil. Please make sure DockerEndpoint is set in the configuration file.")
 	}
@@ -211,17 +211,25 @@ func processFetch(cfg *config.HorizonConfig, client *docker.Client, db *bolt.DB,
 		glog.Errorf("Failed to fetch authentication facts from the attributes before processing packages and / or Docker pulls: %v. Continuing anyway", err)
 	}
 
-	return fetchImage(cfg, client, db, deploymentDesc, dockerAuthConfigurations)
+	return fetchImage(cfg, client, bundle, deploymentDesc, dockerAuthConfigurations)
 }
 
-func fetchImage(cfg *config.HorizonConfig, client *docker.Client, db *bolt.DB, deploymentDesc *containermessage.DeploymentDescription, dockerAuthConfigurations map[string][]docker.AuthConfiguration) error {
+func fetchImage(cfg *config.HorizonConfig, client *docker.Client, bundle *persistence.OfflineBundle, deploymentDesc *containermessage.DeploymentDescription, dockerAuthConfigurations map[string][]docker.AuthConfiguration) error {
+
+	// The images that were delivered in the offline bundle of the service are loaded from the bundle store, only the others are pulled.
+	toPull, err := loadBundleImages(client, bundle, deploymentDesc)
+	if err != nil {
+		return err
+	} else if len(toPull.Services) == 0 {
+		return nil
+	}
 
 	skipCheckFn := SkipCheckFn(client)
 	// using Docker pull (newer option, uses docker client to pull images from repos in image names in deployment description)
 	// Note: we don't want to make this a fallback option, it's a potential security vector
 	glog.V(3).Infof("Using Docker pull mechanism to retrieve and load Docker images into local registry")
 
-	fetchErr := pullImageFromRepos(cfg.Edge, dockerAuthConfigurations, client, &skipCheckFn, deploymentDesc)
+	fetchErr := pullImageFromRepos(cfg.Edge, dockerAuthConfigurations, client, &skipCheckFn, toPull)
 	return fetchErr
 }
 
@@ -286,7 +294,7 @@ func (b *ImageFetchWorker) CommandHandler(command worker.Command) bool {
 				return true
 			}
 
-			_, deploymentDesc, err := processDeployment(b.Config, lc.ContainerConfig())
+			pemFiles, deploymentDesc, err := processDeployment(b.Config, lc.ContainerConfig())
 			if err != nil {
 				err = fmt.Errorf("Failed to process deployment description and signature after agreement negotiation: %v", err)
 				glog.Errorf(err.Error())
@@ -294,7 +302,20 @@ func (b *ImageFetchWorker) CommandHandler(command worker.Command) bool {
 				return true
 			}
 
-			if fetchErr := processFetch(b.Config, b.client, b.db, deploymentDesc, lc.ContainerConfig().ImageDockerAuths); fetchErr != nil {
+			// The consumer might require the images to be the ones that another node pulled.
+			if agLC, ok := cmd.LaunchContext.(*events.AgreementLaunchContext); ok && len(agLC.ImageDigests) != 0 {
+				pinImages(deploymentDesc, agLC.ImageDigests)
+			}
+
+			// The service might have been delivered in an offline bundle.
+			bundle, err := b.serviceBundle(cmd.LaunchContext)
+			if err != nil {
+				glog.Errorf("Failed to read the offline bundles: %v", err)
+				b.Messages() <- events.NewImageFetchMessage(events.IMAGE_FETCH_ERROR, deploymentDesc, lc, err)
+				return true
+			}
+
+			if fetchErr := processFetch(b.Config, b.client, b.db, bundle, deploymentDesc, lc.ContainerConfig().ImageDockerAuths); fetchErr != nil {
 				var id events.EventId
 				if strings.Contains(fetchErr.Error(), "Auth error") {
 					id = events.IMAGE_FETCH_AUTH_ERROR
@@ -303,8 +324,14 @@ func (b *ImageFetchWorker) CommandHandler(command worker.Command) bool {
 				}
 				glog.Errorf("Failed to fetch image files: %v", fetchErr)
 				b.Messages() <- events.NewImageFetchMessage(id, deploymentDesc, lc, fetchErr)
+			} else if verifyErr := b.verifyImages(cmd.LaunchContext, bundle, deploymentDesc, pemFiles); verifyErr != nil {
+				glog.Errorf("Failed to verify image signatures: %v", verifyErr)
+				b.Messages() <- events.NewImageFetchMessage(events.IMAGE_SIG_VERIF_ERROR, deploymentDesc, lc, verifyErr)
 			} else {
-				b.Messages() <- events.NewImageFetchMessage(events.IMAGE_FETCHED, deploymentDesc, lc, nil)
+				// The containers are created from the pulled images, even if their tags are moved later.
+				msg := events.NewImageFetchMessage(events.IMAGE_FETCHED, deploymentDesc, lc, nil)
+				msg.ImageDigests = resolveImageDigests(b.client, deploymentDesc)
+				b.Messages() <- msg
 			}
 
 		}
@@ -0,0 +1,149 @@
+package imagefetch
+
+import (
+	"fmt"
+	"github.com/boltdb/bolt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/rsapss-tool/verify"
+	"strings"
+)
+
+// The image signature of a service is an RSA-PSS signature of the content digest of its image (sha256:...) made with the
+// private key of the service publisher, the same kind of key that signs the deployment string. It is verified with the
+// trusted public keys of the node, the keys that are managed with the /trust API. The image verification mode of the
+// service org in the agent configuration decides whether a verification failure stops the service from starting.
+
+// Verify the signatures of the pulled images of the deployment. An error is returned only when the images of the
+// service org must be verified and one of the signatures cannot be verified.
+func (b *ImageFetchWorker) verifyImages(launchContext interface{}, bundle *persistence.OfflineBundle, deploymentDesc *containermessage.DeploymentDescription, pemFiles []string) error {
+	if len(b.Config.Edge.ImageVerification) == 0 {
+		return nil
+	}
+
+	service, err := launchContextService(b.db, launchContext)
+	if err != nil {
+		return err
+	}
+
+	mode := b.Config.Edge.GetImageVerificationMode(service.Org)
+	if mode == "" {
+		return nil
+	}
+
+	for name, svc := range deploymentDesc.Services {
+		// The images of the service's own bundle were verified with the signature of the bundle when it was loaded, and
+		// a loaded image does not have a repository digest to verify.
+		if _, file := bundleImage(bundle, svc.Image); file != "" {
+			glog.V(3).Infof("Image %v of service %v was delivered in the signed offline bundle %v", svc.Image, name, bundle.Id)
+			continue
+		}
+
+		if err := verifyImageSignature(b.client, svc, pemFiles); err != nil {
+			if mode == config.IMAGE_VERIFICATION_ENFORCE {
+				return fmt.Errorf("service %v: %v", name, err)
+			}
+			glog.Warningf("Image signature verification failed for service %v of org %v, the image is used because the verification mode is %v. %v", name, service.Org, mode, err)
+			eventlog.LogServiceEvent2(b.db, persistence.SEVERITY_WARN,
+				persistence.NewMessageMeta(EL_IMG_SIGNATURE_NOT_VERIFIED, service.Org, service.URL, mode, err.Error()),
+				persistence.EC_ERROR_IMAGE_SIGNATURE,
+				"", service.URL, service.Org, service.Version, "", launchContextAgreementIds(launchContext))
+		} else {
+			glog.V(3).Infof("Image signature of service %v verified for image %v", name, svc.Image)
+		}
+	}
+	return nil
+}
+
+// Returns the offline bundle of the service that is being launched, or nil if the service was not delivered in a bundle.
+func (b *ImageFetchWorker) serviceBundle(launchContext interface{}) (*persistence.OfflineBundle, error) {
+	if service, err := launchContextService(b.db, launchContext); err != nil {
+		return nil, err
+	} else {
+		return persistence.FindServiceOfflineBundle(b.db, service.Org, service.URL, service.Version)
+	}
+}
+
+// Returns the service that is being launched.
+func launchContextService(db *bolt.DB, launchContext interface{}) (*persistence.ServiceInstancePathElement, error) {
+	switch lc := launchContext.(type) {
+	case *events.ContainerLaunchContext:
+		return lc.GetServicePathElement(), nil
+	case *events.AgreementLaunchContext:
+		if ags, err := persistence.FindEstablishedAgreements(db, lc.AgreementProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(lc.AgreementId)}); err != nil {
+			return nil, fmt.Errorf("unable to retrieve agreement %v from database, error %v", lc.AgreementId, err)
+		} else if len(ags) != 1 {
+			return nil, fmt.Errorf("unable to retrieve single agreement %v from database", lc.AgreementId)
+		} else {
+			return persistence.NewServiceInstancePathElement(ags[0].RunningWorkload.URL, ags[0].RunningWorkload.Org, ags[0].RunningWorkload.Version), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown launch context type %T", launchContext)
+}
+
+// Returns the agreements that the service is being launched for.
+func launchContextAgreementIds(launchContext interface{}) []string {
+	switch lc := launchContext.(type) {
+	case *events.ContainerLaunchContext:
+		return lc.AgreementIds
+	case *events.AgreementLaunchContext:
+		return []string{lc.AgreementId}
+	}
+	return []string{}
+}
+
+// Verify the signature of the image of the service with the trusted public keys.
+func verifyImageSignature(client *docker.Client, service *containermessage.Service, pemFiles []string) error {
+	if service.ImageSignature == "" {
+		return fmt.Errorf("image %v is not signed", service.Image)
+	}
+
+	digest, err := imageDigest(client, service.Image)
+	if err != nil {
+		return err
+	}
+
+	if verified, fn_success, failed_map := verify.InputVerifiedByAnyKey(pemFiles, service.ImageSignature, []byte(digest)); !verified {
+		glog.Errorf("Unable to verify the signature of image %v with digest %v: %v", service.Image, digest, failed_map)
+		return fmt.Errorf("there is no trusted public key that verifies the signature of image %v with digest %v", service.Image, digest)
+	} else {
+		glog.V(5).Infof("Image %v verification successful with RSA pubkey in file: %v", service.Image, fn_success)
+	}
+	return nil
+}
+
+// Returns the content digest of a pulled image. The digest is taken from the image name when the image is referenced
+// by digest, otherwise it is the digest that the registry reported when the image was pulled.
+func imageDigest(client *docker.Client, image string) (string, error) {
+	if _, _, _, digest := cutil.ParseDockerImagePath(image); digest != "" {
+		return digest, nil
+	}
+
+	img, err := client.InspectImage(image)
+	if err != nil {
+		return "", fmt.Errorf("unable to inspect image %v, error: %v", image, err)
+	}
+
+	if digest := repoDigest(image, img.RepoDigests); digest != "" {
+		return digest, nil
+	}
+	return "", fmt.Errorf("image %v does not have a repository digest", image)
+}
+
+// Returns the digest of the repository of the image from the list of repository digests of a local image, which are in
+// the form repository@digest.
+func repoDigest(image string, repoDigests []string) string {
+	repo := containermessage.ImageRepository(image)
+	for _, rd := range repoDigests {
+		if parts := strings.SplitN(rd, "@", 2); len(parts) == 2 && containermessage.ImageRepository(parts[0]) == repo {
+			return parts[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+//go:build unit
+// +build unit
+
+package imagefetch
+
+import (
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/rsapss-tool/generatekeys"
+	"github.com/open-horizon/rsapss-tool/sign"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_repoDigest(t *testing.T) {
+	repoDigests := []string{
+		"myregistry.example.com/edge/gps@sha256:1111",
+		"openhorizon/gps@sha256:2222",
+	}
+
+	if d := repoDigest("openhorizon/gps:2.0.3", repoDigests); d != "sha256:2222" {
+		t.Errorf("wrong digest for the docker.io image: %v", d)
+	} else if d := repoDigest("myregistry.example.com/edge/gps:1.0", repoDigests); d != "sha256:1111" {
+		t.Errorf("wrong digest for the private registry image: %v", d)
+	} else if d := repoDigest("myregistry.example.com/edge/other:1.0", repoDigests); d != "" {
+		t.Errorf("there should not be a digest for an image of another repository: %v", d)
+	}
+}
+
+func Test_verifyImageSignature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "imageverify-")
+	if err != nil {
+		t.Fatalf("unable to create the key directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keys, err := generatekeys.Write(dir, 2048, "test", "testorg", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unable to generate the keys: %v", err)
+	}
+	var pubKey, privKey string
+	for _, k := range keys {
+		if strings.HasSuffix(k, "public.pem") {
+			pubKey = k
+		} else {
+			privKey = k
+		}
+	}
+
+	digest := "sha256:5b0d2e9b4f8a6bf0b0b3c1cd4f42f0bb7a3a8b0a6cb3ef6c6d32e4ab0a8e4c0f"
+	sig, err := sign.Input(privKey, []byte(digest))
+	if err != nil {
+		t.Fatalf("unable to sign the digest: %v", err)
+	}
+
+	// the image is referenced by digest, so the docker client is not used
+	service := &containermessage.Service{Image: "openhorizon/gps@" + digest, ImageSignature: sig}
+	if err := verifyImageSignature(nil, service, []string{pubKey}); err != nil {
+		t.Errorf("the image signature should be verified: %v", err)
+	}
+
+	service.Image = "openhorizon/gps@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	if err := verifyImageSignature(nil, service, []string{pubKey}); err == nil {
+		t.Errorf("the signature of another digest should not be verified")
+	}
+
+	service.ImageSignature = ""
+	if err := verifyImageSignature(nil, service, []string{pubKey}); err == nil {
+		t.Errorf("an image without a signature should not be verified")
+	}
+}
@@ -0,0 +1,21 @@
+package imagefetch
+
+import (
+	"github.com/open-horizon/anax/i18n"
+)
+
+// messages for event logs
+const (
+	EL_IMG_SIGNATURE_NOT_VERIFIED = "Image signature verification failed for service %v/%v, the image is used because the image verification mode is %v. Reason: %v"
+)
+
+// This is does nothing useful at run time.
+// This code is only used in compileing time to make the eventlog messages gets into the catalog so that
+// they can be translated.
+// The event log messages will be saved in English. But the CLI can request them in different languages.
+func MarkI18nMessages() {
+	// get message printer. anax default language is English
+	msgPrinter := i18n.GetMessagePrinter()
+
+	msgPrinter.Sprintf(EL_IMG_SIGNATURE_NOT_VERIFIED)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/open-horizon/anax/cutil"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	crdv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
@@ -16,13 +17,16 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamic "k8s.io/client-go/dynamic"
 	"time"
 )
 
 type APIObjectInterface interface {
 	Install(c KubeClient, namespace string) error
 	Uninstall(c KubeClient, namespace string)
+	Update(c KubeClient, namespace string) error
 	Status(c KubeClient, namespace string) (interface{}, error)
 	Name() string
 }
@@ -33,6 +37,7 @@ type APIObjectInterface interface {
 func sortAPIObjects(allObjects []APIObjects, customResource *unstructured.Unstructured, envVarMap map[string]string, agreementId string, crInstallTimeout int64) (map[string][]APIObjectInterface, string, error) {
 	namespace := ""
 	objMap := map[string][]APIObjectInterface{}
+	var err error
 	for _, obj := range allObjects {
 		switch obj.Type.Kind {
 		case K8S_NAMESPACE_TYPE:
@@ -120,7 +125,87 @@ func sortAPIObjects(allObjects []APIObjects, customResource *unstructured.Unstru
 			} else {
 				return objMap, namespace, fmt.Errorf(kwlog(fmt.Sprintf("Error: custom resource definition object has unrecognized type %T: %v", obj.Object, obj.Object)))
 			}
-
+		case K8S_CLUSTER_ROLE_TYPE:
+			if typedClusterRole, ok := obj.Object.(*rbacv1.ClusterRole); ok {
+				if err := addAPIObject(objMap, K8S_CLUSTER_ROLE_TYPE, ClusterRoleRbacV1{ClusterRoleObject: typedClusterRole}); err != nil {
+					return objMap, namespace, err
+				}
+			} else {
+				return objMap, namespace, unrecognizedTypeError(obj)
+			}
+		case K8S_CLUSTER_ROLEBINDING_TYPE:
+			if typedClusterRoleBinding, ok := obj.Object.(*rbacv1.ClusterRoleBinding); ok {
+				if err := addAPIObject(objMap, K8S_CLUSTER_ROLEBINDING_TYPE, ClusterRolebindingRbacV1{ClusterRolebindingObject: typedClusterRoleBinding}); err != nil {
+					return objMap, namespace, err
+				}
+			} else {
+				return objMap, namespace, unrecognizedTypeError(obj)
+			}
+		case K8S_CONFIGMAP_TYPE:
+			if typedConfigMap, ok := obj.Object.(*corev1.ConfigMap); !ok {
+				return objMap, namespace, unrecognizedTypeError(obj)
+			} else if namespace, err = checkNamespace(namespace, typedConfigMap.ObjectMeta.Namespace); err != nil {
+				return objMap, namespace, err
+			} else if err := addAPIObject(objMap, K8S_CONFIGMAP_TYPE, ConfigMapCoreV1{ConfigMapObject: typedConfigMap}); err != nil {
+				return objMap, namespace, err
+			}
+		case K8S_SECRET_TYPE:
+			if typedSecret, ok := obj.Object.(*corev1.Secret); !ok {
+				return objMap, namespace, unrecognizedTypeError(obj)
+			} else if namespace, err = checkNamespace(namespace, typedSecret.ObjectMeta.Namespace); err != nil {
+				return objMap, namespace, err
+			} else if err := addAPIObject(objMap, K8S_SECRET_TYPE, SecretCoreV1{SecretObject: typedSecret}); err != nil {
+				return objMap, namespace, err
+			}
+		case K8S_SERVICE_TYPE:
+			if typedService, ok := obj.Object.(*corev1.Service); !ok {
+				return objMap, namespace, unrecognizedTypeError(obj)
+			} else if namespace, err = checkNamespace(namespace, typedService.ObjectMeta.Namespace); err != nil {
+				return objMap, namespace, err
+			} else if err := addAPIObject(objMap, K8S_SERVICE_TYPE, ServiceCoreV1{ServiceObject: typedService}); err != nil {
+				return objMap, namespace, err
+			}
+		case K8S_STATEFULSET_TYPE:
+			if typedStatefulSet, ok := obj.Object.(*appsv1.StatefulSet); !ok {
+				return objMap, namespace, unrecognizedTypeError(obj)
+			} else if namespace, err = checkNamespace(namespace, typedStatefulSet.ObjectMeta.Namespace); err != nil {
+				return objMap, namespace, err
+			} else if err := addAPIObject(objMap, K8S_STATEFULSET_TYPE, StatefulSetAppsV1{StatefulSetObject: typedStatefulSet}); err != nil {
+				return objMap, namespace, err
+			}
+		case K8S_DAEMONSET_TYPE:
+			if typedDaemonSet, ok := obj.Object.(*appsv1.DaemonSet); !ok {
+				return objMap, namespace, unrecognizedTypeError(obj)
+			} else if namespace, err = checkNamespace(namespace, typedDaemonSet.ObjectMeta.Namespace); err != nil {
+				return objMap, namespace, err
+			} else if err := addAPIObject(objMap, K8S_DAEMONSET_TYPE, DaemonSetAppsV1{DaemonSetObject: typedDaemonSet}); err != nil {
+				return objMap, namespace, err
+			}
+		case K8S_PVC_TYPE:
+			if typedPVC, ok := obj.Object.(*corev1.PersistentVolumeClaim); !ok {
+				return objMap, namespace, unrecognizedTypeError(obj)
+			} else if namespace, err = checkNamespace(namespace, typedPVC.ObjectMeta.Namespace); err != nil {
+				return objMap, namespace, err
+			} else if err := addAPIObject(objMap, K8S_PVC_TYPE, PersistentVolumeClaimCoreV1{PVCObject: typedPVC}); err != nil {
+				return objMap, namespace, err
+			}
+		case K8S_NETWORK_POLICY_TYPE:
+			if typedNetworkPolicy, ok := obj.Object.(*networkingv1.NetworkPolicy); !ok {
+				return objMap, namespace, unrecognizedTypeError(obj)
+			} else if namespace, err = checkNamespace(namespace, typedNetworkPolicy.ObjectMeta.Namespace); err != nil {
+				return objMap, namespace, err
+			} else if err := addAPIObject(objMap, K8S_NETWORK_POLICY_TYPE, NetworkPolicyNetworkingV1{NetworkPolicyObject: typedNetworkPolicy}); err != nil {
+				return objMap, namespace, err
+			}
+		default:
+			// Any other kind of object is installed as an unstructured object.
+			if unstructObj, err := toUnstructured(obj); err != nil {
+				return objMap, namespace, err
+			} else if namespace, err = checkNamespace(namespace, unstructObj.GetNamespace()); err != nil {
+				return objMap, namespace, err
+			} else if err := addAPIObject(objMap, K8S_UNSTRUCTURED_TYPE, UnstructuredObject{Object: unstructObj}); err != nil {
+				return objMap, namespace, err
+			}
 		}
 
 	}
@@ -131,6 +216,31 @@ func sortAPIObjects(allObjects []APIObjects, customResource *unstructured.Unstru
 	return objMap, namespace, nil
 }
 
+// Add an object to the objects of its kind, the object must be named so that it can be found and uninstalled.
+func addAPIObject(objMap map[string][]APIObjectInterface, kind string, obj APIObjectInterface) error {
+	if obj.Name() == "" {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error: %s object must have a name in its metadata section.", kind)))
+	}
+	glog.V(4).Infof(kwlog(fmt.Sprintf("Found kubernetes %s object %s.", kind, obj.Name())))
+	objMap[kind] = append(objMap[kind], obj)
+	return nil
+}
+
+func unrecognizedTypeError(obj APIObjects) error {
+	return fmt.Errorf(kwlog(fmt.Sprintf("Error: %s object has unrecognized type %T: %v", obj.Type.Kind, obj.Object, obj.Object)))
+}
+
+// Convert a typed object of a kind that does not have its own object type into an unstructured object.
+func toUnstructured(obj APIObjects) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj.Object)
+	if err != nil {
+		return nil, fmt.Errorf(kwlog(fmt.Sprintf("Error: unable to convert %s object to an unstructured object: %v", obj.Type.Kind, err)))
+	}
+	unstructObj := &unstructured.Unstructured{Object: content}
+	unstructObj.SetGroupVersionKind(*obj.Type)
+	return unstructObj, nil
+}
+
 //----------------Namespace----------------
 
 type NamespaceCoreV1 struct {
@@ -155,6 +265,11 @@ func (n NamespaceCoreV1) Uninstall(c KubeClient, namespace string) {
 	}
 }
 
+// The namespace is not changed by an upgrade, it is created if it does not exist.
+func (n NamespaceCoreV1) Update(c KubeClient, namespace string) error {
+	return n.Install(c, namespace)
+}
+
 func (n NamespaceCoreV1) Status(c KubeClient, namespace string) (interface{}, error) {
 	nsStatus, err := c.Client.CoreV1().Namespaces().Get(context.Background(), n.Name(), metav1.GetOptions{})
 	if err != nil {
@@ -194,6 +309,22 @@ func (r RoleRbacV1) Uninstall(c KubeClient, namespace string) {
 	}
 }
 
+func (r RoleRbacV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating role %s", r.Name())))
+	existing, err := c.Client.RbacV1().Roles(namespace).Get(context.Background(), r.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return r.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the role %s: %v", r.Name(), err)))
+	}
+	obj := r.RoleObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	if _, err := c.Client.RbacV1().Roles(namespace).Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the role %s: %v", r.Name(), err)))
+	}
+	return nil
+}
+
 func (r RoleRbacV1) Status(c KubeClient, namespace string) (interface{}, error) {
 	return nil, nil
 }
@@ -229,6 +360,22 @@ func (rb RolebindingRbacV1) Uninstall(c KubeClient, namespace string) {
 	}
 }
 
+func (rb RolebindingRbacV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating rolebinding %s", rb.Name())))
+	existing, err := c.Client.RbacV1().RoleBindings(namespace).Get(context.Background(), rb.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return rb.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the rolebinding %s: %v", rb.Name(), err)))
+	}
+	obj := rb.RolebindingObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	if _, err := c.Client.RbacV1().RoleBindings(namespace).Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the rolebinding %s: %v", rb.Name(), err)))
+	}
+	return nil
+}
+
 func (rb RolebindingRbacV1) Status(c KubeClient, namespace string) (interface{}, error) {
 	return nil, nil
 }
@@ -263,6 +410,26 @@ func (sa ServiceAccountCoreV1) Uninstall(c KubeClient, namespace string) {
 	}
 }
 
+func (sa ServiceAccountCoreV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating service account %s", sa.Name())))
+	existing, err := c.Client.CoreV1().ServiceAccounts(namespace).Get(context.Background(), sa.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return sa.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the service account %s: %v", sa.Name(), err)))
+	}
+	obj := sa.ServiceAccountObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	// Keep the token secrets that the cluster added to the service account
+	if len(obj.Secrets) == 0 {
+		obj.Secrets = existing.Secrets
+	}
+	if _, err := c.Client.CoreV1().ServiceAccounts(namespace).Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the service account %s: %v", sa.Name(), err)))
+	}
+	return nil
+}
+
 func (sa ServiceAccountCoreV1) Status(c KubeClient, namespace string) (interface{}, error) {
 	return nil, nil
 }
@@ -317,19 +484,43 @@ func (d DeploymentAppsV1) Uninstall(c KubeClient, namespace string) {
 		glog.Errorf(kwlog(fmt.Sprintf("unable to delete deployment %s. Error: %v", d.DeploymentObject.ObjectMeta.Name, err)))
 	}
 
-	configMapName := fmt.Sprintf("%s-%s", HZN_ENV_VARS, d.AgreementId)
-	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting config map %v", configMapName)))
 	// Delete the agreement config map
-	err = c.Client.CoreV1().ConfigMaps(namespace).Delete(context.Background(), configMapName, metav1.DeleteOptions{})
+	c.deleteEnvConfigMap(d.AgreementId, namespace)
+}
+
+// Update creates the config map of the agreement, unless it already exists, and updates the deployment to use it. The
+// config map of the agreement that the deployment used before is deleted by the upgrade once it succeeded.
+func (d DeploymentAppsV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating deployment %s", d.Name())))
+
+	mapName := envConfigMapName(d.AgreementId)
+	if _, err := c.Client.CoreV1().ConfigMaps(namespace).Get(context.Background(), mapName, metav1.GetOptions{}); err != nil && errors.IsNotFound(err) {
+		envAdds := cutil.RemoveESSEnvVars(d.EnvVarMap, config.ENVVAR_PREFIX)
+		if _, err := c.CreateConfigMap(envAdds, d.AgreementId, namespace); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the config map %s: %v", mapName, err)))
+	}
+
+	dWithEnv := addConfigMapVarToDeploymentObject(*d.DeploymentObject.DeepCopy(), mapName)
+	existing, err := c.Client.AppsV1().Deployments(namespace).Get(context.Background(), d.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		_, err = c.Client.AppsV1().Deployments(namespace).Create(context.Background(), &dWithEnv, metav1.CreateOptions{})
+	} else if err == nil {
+		dWithEnv.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+		_, err = c.Client.AppsV1().Deployments(namespace).Update(context.Background(), &dWithEnv, metav1.UpdateOptions{})
+	}
 	if err != nil {
-		glog.Errorf(kwlog(fmt.Sprintf("unable to delete config map %s. Error: %v", configMapName, err)))
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the operator deployment: %v", err)))
 	}
+	return nil
 }
 
 // Status will be the status of the operator pod
 func (d DeploymentAppsV1) Status(c KubeClient, namespace string) (interface{}, error) {
 	opName := d.DeploymentObject.ObjectMeta.Name
-	podList, err := c.Client.CoreV1().Pods(ANAX_NAMESPACE).List(context.Background(), metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", "name", opName)})
+	podList, err := c.Client.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", "name", opName)})
 	if err != nil {
 		return nil, err
 	}
@@ -358,6 +549,7 @@ type CustomResourceV1Beta1 struct {
 	CustomResourceDefinitionObject *crdv1beta1.CustomResourceDefinition
 	CustomResourceObject           *unstructured.Unstructured
 	InstallTimeout                 int64
+	Shared                         bool // the definition is shared with the operators of other agreements
 }
 
 func (cr CustomResourceV1Beta1) Install(c KubeClient, namespace string) error {
@@ -368,7 +560,11 @@ func (cr CustomResourceV1Beta1) Install(c KubeClient, namespace string) error {
 	crds := apiClient.CustomResourceDefinitions()
 	glog.V(3).Infof(kwlog(fmt.Sprintf("creating custom resource definition %v", cr.CustomResourceDefinitionObject)))
 	_, err = crds.Create(context.Background(), cr.CustomResourceDefinitionObject, metav1.CreateOptions{})
-	if err != nil && errors.IsAlreadyExists(err) {
+	if err != nil && errors.IsAlreadyExists(err) && cr.Shared {
+		// The definition is in use by the operators of other agreements, it is not recreated.
+		glog.V(3).Infof(kwlog(fmt.Sprintf("using the existing custom resource definition %s", cr.Name())))
+		err = nil
+	} else if err != nil && errors.IsAlreadyExists(err) {
 		cr.Uninstall(c, namespace)
 		_, err = crds.Create(context.Background(), cr.CustomResourceDefinitionObject, metav1.CreateOptions{})
 	}
@@ -454,6 +650,11 @@ func (cr CustomResourceV1Beta1) Uninstall(c KubeClient, namespace string) {
 		}
 	}
 
+	if cr.Shared && customResourcesLeft(crClient) {
+		glog.V(3).Infof(kwlog(fmt.Sprintf("keeping custom resource definition %s, it is in use by other operators", cr.Name())))
+		return
+	}
+
 	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting operator custom resource definition %v", cr.CustomResourceDefinitionObject.ObjectMeta.Name)))
 	// CRDs need a different client
 	apiClient, err := NewCRDV1beta1Client()
@@ -468,6 +669,38 @@ func (cr CustomResourceV1Beta1) Uninstall(c KubeClient, namespace string) {
 	}
 }
 
+// Update updates the custom resource definition and the custom resource in place. The custom resource is not
+// recreated, so the operator keeps the state that it holds in the resource.
+func (cr CustomResourceV1Beta1) Update(c KubeClient, namespace string) error {
+	apiClient, err := NewCRDV1beta1Client()
+	if err != nil {
+		return err
+	}
+	crds := apiClient.CustomResourceDefinitions()
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating custom resource definition %s", cr.Name())))
+	existing, err := crds.Get(context.Background(), cr.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return cr.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the custom resource definition %s: %v", cr.Name(), err)))
+	}
+	crd := cr.CustomResourceDefinitionObject.DeepCopy()
+	crd.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	if _, err := crds.Update(context.Background(), crd, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the custom resource definition %s: %v", cr.Name(), err)))
+	}
+
+	dynClient, err := NewDynamicKubeClient()
+	if err != nil {
+		return err
+	}
+	gvr, err := cr.gvr()
+	if err != nil {
+		return err
+	}
+	return updateCustomResource(dynClient.Resource(*gvr).Namespace(namespace), cr.CustomResourceObject, cr.InstallTimeout)
+}
+
 func (cr CustomResourceV1Beta1) waitForCRUninstall(c KubeClient, namespace string, timeoutS int, crName string) error {
 	status, err := cr.Status(c, namespace)
 	if timeoutS < 1 {
@@ -557,6 +790,7 @@ type CustomResourceV1 struct {
 	CustomResourceDefinitionObject *crdv1.CustomResourceDefinition
 	CustomResourceObject           *unstructured.Unstructured
 	InstallTimeout                 int64
+	Shared                         bool // the definition is shared with the operators of other agreements
 }
 
 func (cr CustomResourceV1) Install(c KubeClient, namespace string) error {
@@ -567,7 +801,11 @@ func (cr CustomResourceV1) Install(c KubeClient, namespace string) error {
 	crds := apiClient.CustomResourceDefinitions()
 	glog.V(3).Infof(kwlog(fmt.Sprintf("creating custom resource definition %v", cr.CustomResourceDefinitionObject)))
 	_, err = crds.Create(context.Background(), cr.CustomResourceDefinitionObject, metav1.CreateOptions{})
-	if err != nil && errors.IsAlreadyExists(err) {
+	if err != nil && errors.IsAlreadyExists(err) && cr.Shared {
+		// The definition is in use by the operators of other agreements, it is not recreated.
+		glog.V(3).Infof(kwlog(fmt.Sprintf("using the existing custom resource definition %s", cr.Name())))
+		err = nil
+	} else if err != nil && errors.IsAlreadyExists(err) {
 		cr.Uninstall(c, namespace)
 		_, err = crds.Create(context.Background(), cr.CustomResourceDefinitionObject, metav1.CreateOptions{})
 	}
@@ -655,6 +893,12 @@ func (cr CustomResourceV1) Uninstall(c KubeClient, namespace string) {
 			glog.Errorf(fmt.Sprintf("%v", err))
 		}
 	}
+
+	if cr.Shared && customResourcesLeft(crClient) {
+		glog.V(3).Infof(kwlog(fmt.Sprintf("keeping custom resource definition %s, it is in use by other operators", cr.Name())))
+		return
+	}
+
 	// CRDs need a different client
 	apiClient, err := NewCRDV1Client()
 	if err != nil {
@@ -668,6 +912,38 @@ func (cr CustomResourceV1) Uninstall(c KubeClient, namespace string) {
 	}
 }
 
+// Update updates the custom resource definition and the custom resource in place. The custom resource is not
+// recreated, so the operator keeps the state that it holds in the resource.
+func (cr CustomResourceV1) Update(c KubeClient, namespace string) error {
+	apiClient, err := NewCRDV1Client()
+	if err != nil {
+		return err
+	}
+	crds := apiClient.CustomResourceDefinitions()
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating custom resource definition %s", cr.Name())))
+	existing, err := crds.Get(context.Background(), cr.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return cr.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the custom resource definition %s: %v", cr.Name(), err)))
+	}
+	crd := cr.CustomResourceDefinitionObject.DeepCopy()
+	crd.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	if _, err := crds.Update(context.Background(), crd, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the custom resource definition %s: %v", cr.Name(), err)))
+	}
+
+	dynClient, err := NewDynamicKubeClient()
+	if err != nil {
+		return err
+	}
+	gvr, err := cr.gvr()
+	if err != nil {
+		return err
+	}
+	return updateCustomResource(dynClient.Resource(*gvr).Namespace(namespace), cr.CustomResourceObject, cr.InstallTimeout)
+}
+
 func (cr CustomResourceV1) waitForCRUninstall(c KubeClient, namespace string, timeoutS int, crName string) error {
 	status, err := cr.Status(c, namespace)
 	if timeoutS < 1 {
@@ -741,3 +1017,615 @@ func (cr CustomResourceV1) gvr() (*schema.GroupVersionResource, error) {
 		return nil, fmt.Errorf(kwlog(fmt.Sprintf("Error: custom resource object does not have apiversion field: %v", cr.CustomResourceObject.Object)))
 	}
 }
+
+// Update the custom resource in place, keeping its status, or create it if it does not exist. The cluster can take some
+// time to serve the resources of a custom resource definition that was just created or changed.
+func updateCustomResource(crClient dynamic.ResourceInterface, crObj *unstructured.Unstructured, timeout int64) error {
+	resourceName := crObj.GetName()
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating the operator custom resource. Timeout is %v. Resource is %v", timeout, crObj)))
+	for {
+		existing, err := crClient.Get(context.Background(), resourceName, metav1.GetOptions{})
+		if err != nil && errors.IsNotFound(err) {
+			_, err = crClient.Create(context.Background(), crObj, metav1.CreateOptions{})
+		} else if err == nil {
+			obj := crObj.DeepCopy()
+			obj.SetResourceVersion(existing.GetResourceVersion())
+			_, err = crClient.Update(context.Background(), obj, metav1.UpdateOptions{})
+		}
+
+		if err != nil && timeout > 0 {
+			glog.Warningf(kwlog(fmt.Sprintf("Failed to update custom resource %s. Trying again in 5s. Error was: %v", resourceName, err)))
+			time.Sleep(time.Second * 5)
+		} else if err != nil {
+			return fmt.Errorf(kwlog(fmt.Sprintf("Failed to update custom resource %s. Timeout exceeded. Error was: %v", resourceName, err)))
+		} else {
+			glog.V(3).Infof(kwlog(fmt.Sprintf("Sucessfully updated custom resource %s.", resourceName)))
+			return nil
+		}
+		timeout = timeout - 5
+	}
+}
+
+// Returns the namespace of the operator after checking the namespace of an object against it. The namespaced objects
+// of an operator must all be in the same namespace, an object without a namespace goes into the operator namespace.
+func checkNamespace(namespace string, objNamespace string) (string, error) {
+	if objNamespace == "" || objNamespace == namespace {
+		return namespace, nil
+	} else if namespace == "" {
+		return objNamespace, nil
+	}
+	return namespace, fmt.Errorf(kwlog(fmt.Sprintf("Error: multiple namespaces specified in operator: %s and %s", namespace, objNamespace)))
+}
+
+// Returns true when there are custom resources of a definition in the cluster, in any namespace. When they can not be
+// listed, they are assumed to be there so that the definition is not deleted from under them.
+func customResourcesLeft(crClient dynamic.NamespaceableResourceInterface) bool {
+	crs, err := crClient.List(context.Background(), metav1.ListOptions{Limit: 1})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to list the custom resources. Error: %v", err)))
+		return true
+	}
+	return len(crs.Items) != 0
+}
+
+//----------------ClusterRole----------------
+// A cluster role can be shared with other operators, an existing one is updated in place instead of being replaced.
+
+type ClusterRoleRbacV1 struct {
+	ClusterRoleObject *rbacv1.ClusterRole
+}
+
+func (cr ClusterRoleRbacV1) Install(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating cluster role %v", cr)))
+	_, err := c.Client.RbacV1().ClusterRoles().Create(context.Background(), cr.ClusterRoleObject, metav1.CreateOptions{})
+	if err != nil && errors.IsAlreadyExists(err) {
+		return cr.Update(c, namespace)
+	}
+	if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the cluster role %s: %v", cr.Name(), err)))
+	}
+	return nil
+}
+
+func (cr ClusterRoleRbacV1) Uninstall(c KubeClient, namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting cluster role %s", cr.Name())))
+	err := c.Client.RbacV1().ClusterRoles().Delete(context.Background(), cr.Name(), metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete cluster role %s. Error: %v", cr.Name(), err)))
+	}
+}
+
+func (cr ClusterRoleRbacV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating cluster role %s", cr.Name())))
+	existing, err := c.Client.RbacV1().ClusterRoles().Get(context.Background(), cr.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return cr.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the cluster role %s: %v", cr.Name(), err)))
+	}
+	obj := cr.ClusterRoleObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	if _, err := c.Client.RbacV1().ClusterRoles().Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the cluster role %s: %v", cr.Name(), err)))
+	}
+	return nil
+}
+
+func (cr ClusterRoleRbacV1) Status(c KubeClient, namespace string) (interface{}, error) {
+	return nil, nil
+}
+
+func (cr ClusterRoleRbacV1) Name() string {
+	return cr.ClusterRoleObject.ObjectMeta.Name
+}
+
+//----------------ClusterRolebinding----------------
+// The service accounts bound by a cluster role binding must have a namespace, the operator namespace is used for the
+// service accounts that do not have one. An existing cluster role binding is updated in place.
+
+type ClusterRolebindingRbacV1 struct {
+	ClusterRolebindingObject *rbacv1.ClusterRoleBinding
+}
+
+func (crb ClusterRolebindingRbacV1) Install(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating cluster rolebinding %v", crb)))
+	binding := crb.ClusterRolebindingObject.DeepCopy()
+	for i, subject := range binding.Subjects {
+		if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == "" {
+			binding.Subjects[i].Namespace = namespace
+		}
+	}
+	_, err := c.Client.RbacV1().ClusterRoleBindings().Create(context.Background(), binding, metav1.CreateOptions{})
+	if err != nil && errors.IsAlreadyExists(err) {
+		return crb.Update(c, namespace)
+	}
+	if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the cluster rolebinding %s: %v", crb.Name(), err)))
+	}
+	return nil
+}
+
+func (crb ClusterRolebindingRbacV1) Uninstall(c KubeClient, namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting cluster role binding %s", crb.Name())))
+	err := c.Client.RbacV1().ClusterRoleBindings().Delete(context.Background(), crb.Name(), metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete cluster role binding %s. Error: %v", crb.Name(), err)))
+	}
+}
+
+func (crb ClusterRolebindingRbacV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating cluster rolebinding %s", crb.Name())))
+	existing, err := c.Client.RbacV1().ClusterRoleBindings().Get(context.Background(), crb.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return crb.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the cluster rolebinding %s: %v", crb.Name(), err)))
+	}
+	obj := crb.ClusterRolebindingObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	for i, subject := range obj.Subjects {
+		if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == "" {
+			obj.Subjects[i].Namespace = namespace
+		}
+	}
+	if _, err := c.Client.RbacV1().ClusterRoleBindings().Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the cluster rolebinding %s: %v", crb.Name(), err)))
+	}
+	return nil
+}
+
+func (crb ClusterRolebindingRbacV1) Status(c KubeClient, namespace string) (interface{}, error) {
+	return nil, nil
+}
+
+func (crb ClusterRolebindingRbacV1) Name() string {
+	return crb.ClusterRolebindingObject.ObjectMeta.Name
+}
+
+//----------------ConfigMap----------------
+
+type ConfigMapCoreV1 struct {
+	ConfigMapObject *corev1.ConfigMap
+}
+
+func (cm ConfigMapCoreV1) Install(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating config map %s", cm.Name())))
+	_, err := c.Client.CoreV1().ConfigMaps(namespace).Create(context.Background(), cm.ConfigMapObject, metav1.CreateOptions{})
+	if err != nil && errors.IsAlreadyExists(err) {
+		cm.Uninstall(c, namespace)
+		_, err = c.Client.CoreV1().ConfigMaps(namespace).Create(context.Background(), cm.ConfigMapObject, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the config map %s: %v", cm.Name(), err)))
+	}
+	return nil
+}
+
+func (cm ConfigMapCoreV1) Uninstall(c KubeClient, namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting config map %s", cm.Name())))
+	err := c.Client.CoreV1().ConfigMaps(namespace).Delete(context.Background(), cm.Name(), metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete config map %s. Error: %v", cm.Name(), err)))
+	}
+}
+
+func (cm ConfigMapCoreV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating config map %s", cm.Name())))
+	existing, err := c.Client.CoreV1().ConfigMaps(namespace).Get(context.Background(), cm.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return cm.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the config map %s: %v", cm.Name(), err)))
+	}
+	obj := cm.ConfigMapObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	if _, err := c.Client.CoreV1().ConfigMaps(namespace).Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the config map %s: %v", cm.Name(), err)))
+	}
+	return nil
+}
+
+func (cm ConfigMapCoreV1) Status(c KubeClient, namespace string) (interface{}, error) {
+	return nil, nil
+}
+
+func (cm ConfigMapCoreV1) Name() string {
+	return cm.ConfigMapObject.ObjectMeta.Name
+}
+
+//----------------Secret----------------
+// The content of a secret is never logged. An existing secret is updated in place.
+
+type SecretCoreV1 struct {
+	SecretObject *corev1.Secret
+}
+
+func (s SecretCoreV1) Install(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating secret %s", s.Name())))
+	_, err := c.Client.CoreV1().Secrets(namespace).Create(context.Background(), s.SecretObject, metav1.CreateOptions{})
+	if err != nil && errors.IsAlreadyExists(err) {
+		return s.Update(c, namespace)
+	}
+	if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the secret %s: %v", s.Name(), err)))
+	}
+	return nil
+}
+
+func (s SecretCoreV1) Uninstall(c KubeClient, namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting secret %s", s.Name())))
+	err := c.Client.CoreV1().Secrets(namespace).Delete(context.Background(), s.Name(), metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete secret %s. Error: %v", s.Name(), err)))
+	}
+}
+
+func (s SecretCoreV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating secret %s", s.Name())))
+	existing, err := c.Client.CoreV1().Secrets(namespace).Get(context.Background(), s.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return s.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the secret %s: %v", s.Name(), err)))
+	}
+	obj := s.SecretObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	if _, err := c.Client.CoreV1().Secrets(namespace).Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the secret %s: %v", s.Name(), err)))
+	}
+	return nil
+}
+
+func (s SecretCoreV1) Status(c KubeClient, namespace string) (interface{}, error) {
+	return nil, nil
+}
+
+func (s SecretCoreV1) Name() string {
+	return s.SecretObject.ObjectMeta.Name
+}
+
+//----------------Service----------------
+
+type ServiceCoreV1 struct {
+	ServiceObject *corev1.Service
+}
+
+func (s ServiceCoreV1) Install(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating service %v", s.ServiceObject)))
+	_, err := c.Client.CoreV1().Services(namespace).Create(context.Background(), s.ServiceObject, metav1.CreateOptions{})
+	if err != nil && errors.IsAlreadyExists(err) {
+		s.Uninstall(c, namespace)
+		_, err = c.Client.CoreV1().Services(namespace).Create(context.Background(), s.ServiceObject, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the service %s: %v", s.Name(), err)))
+	}
+	return nil
+}
+
+func (s ServiceCoreV1) Uninstall(c KubeClient, namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting service %s", s.Name())))
+	err := c.Client.CoreV1().Services(namespace).Delete(context.Background(), s.Name(), metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete service %s. Error: %v", s.Name(), err)))
+	}
+}
+
+func (s ServiceCoreV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating service %s", s.Name())))
+	existing, err := c.Client.CoreV1().Services(namespace).Get(context.Background(), s.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return s.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the service %s: %v", s.Name(), err)))
+	}
+	obj := s.ServiceObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	// The cluster IP of a service can not be changed
+	if obj.Spec.ClusterIP == "" {
+		obj.Spec.ClusterIP = existing.Spec.ClusterIP
+		obj.Spec.ClusterIPs = existing.Spec.ClusterIPs
+	}
+	if _, err := c.Client.CoreV1().Services(namespace).Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the service %s: %v", s.Name(), err)))
+	}
+	return nil
+}
+
+// Status is the status of the service, it holds the ingress points of a load balancer service
+func (s ServiceCoreV1) Status(c KubeClient, namespace string) (interface{}, error) {
+	svc, err := c.Client.CoreV1().Services(namespace).Get(context.Background(), s.Name(), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf(kwlog(fmt.Sprintf("Error getting service status: %v", err)))
+	}
+	return svc.Status, nil
+}
+
+func (s ServiceCoreV1) Name() string {
+	return s.ServiceObject.ObjectMeta.Name
+}
+
+//----------------StatefulSet----------------
+
+type StatefulSetAppsV1 struct {
+	StatefulSetObject *appsv1.StatefulSet
+}
+
+func (ss StatefulSetAppsV1) Install(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating stateful set %v", ss.StatefulSetObject)))
+	_, err := c.Client.AppsV1().StatefulSets(namespace).Create(context.Background(), ss.StatefulSetObject, metav1.CreateOptions{})
+	if err != nil && errors.IsAlreadyExists(err) {
+		ss.Uninstall(c, namespace)
+		_, err = c.Client.AppsV1().StatefulSets(namespace).Create(context.Background(), ss.StatefulSetObject, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the stateful set %s: %v", ss.Name(), err)))
+	}
+	return nil
+}
+
+func (ss StatefulSetAppsV1) Uninstall(c KubeClient, namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting stateful set %s", ss.Name())))
+	err := c.Client.AppsV1().StatefulSets(namespace).Delete(context.Background(), ss.Name(), metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete stateful set %s. Error: %v", ss.Name(), err)))
+	}
+}
+
+func (ss StatefulSetAppsV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating stateful set %s", ss.Name())))
+	existing, err := c.Client.AppsV1().StatefulSets(namespace).Get(context.Background(), ss.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return ss.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the stateful set %s: %v", ss.Name(), err)))
+	}
+	obj := ss.StatefulSetObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	if _, err := c.Client.AppsV1().StatefulSets(namespace).Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the stateful set %s: %v", ss.Name(), err)))
+	}
+	return nil
+}
+
+// Status is the status of the stateful set, with the number of ready replicas
+func (ss StatefulSetAppsV1) Status(c KubeClient, namespace string) (interface{}, error) {
+	set, err := c.Client.AppsV1().StatefulSets(namespace).Get(context.Background(), ss.Name(), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf(kwlog(fmt.Sprintf("Error getting stateful set status: %v", err)))
+	}
+	return set.Status, nil
+}
+
+func (ss StatefulSetAppsV1) Name() string {
+	return ss.StatefulSetObject.ObjectMeta.Name
+}
+
+//----------------DaemonSet----------------
+
+type DaemonSetAppsV1 struct {
+	DaemonSetObject *appsv1.DaemonSet
+}
+
+func (ds DaemonSetAppsV1) Install(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating daemon set %v", ds.DaemonSetObject)))
+	_, err := c.Client.AppsV1().DaemonSets(namespace).Create(context.Background(), ds.DaemonSetObject, metav1.CreateOptions{})
+	if err != nil && errors.IsAlreadyExists(err) {
+		ds.Uninstall(c, namespace)
+		_, err = c.Client.AppsV1().DaemonSets(namespace).Create(context.Background(), ds.DaemonSetObject, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the daemon set %s: %v", ds.Name(), err)))
+	}
+	return nil
+}
+
+func (ds DaemonSetAppsV1) Uninstall(c KubeClient, namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting daemon set %s", ds.Name())))
+	err := c.Client.AppsV1().DaemonSets(namespace).Delete(context.Background(), ds.Name(), metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete daemon set %s. Error: %v", ds.Name(), err)))
+	}
+}
+
+func (ds DaemonSetAppsV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating daemon set %s", ds.Name())))
+	existing, err := c.Client.AppsV1().DaemonSets(namespace).Get(context.Background(), ds.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return ds.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the daemon set %s: %v", ds.Name(), err)))
+	}
+	obj := ds.DaemonSetObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	if _, err := c.Client.AppsV1().DaemonSets(namespace).Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the daemon set %s: %v", ds.Name(), err)))
+	}
+	return nil
+}
+
+// Status is the status of the daemon set, with the number of nodes running the daemon pod
+func (ds DaemonSetAppsV1) Status(c KubeClient, namespace string) (interface{}, error) {
+	set, err := c.Client.AppsV1().DaemonSets(namespace).Get(context.Background(), ds.Name(), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf(kwlog(fmt.Sprintf("Error getting daemon set status: %v", err)))
+	}
+	return set.Status, nil
+}
+
+func (ds DaemonSetAppsV1) Name() string {
+	return ds.DaemonSetObject.ObjectMeta.Name
+}
+
+//----------------PersistentVolumeClaim----------------
+
+type PersistentVolumeClaimCoreV1 struct {
+	PVCObject *corev1.PersistentVolumeClaim
+}
+
+func (pvc PersistentVolumeClaimCoreV1) Install(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating persistent volume claim %v", pvc.PVCObject)))
+	_, err := c.Client.CoreV1().PersistentVolumeClaims(namespace).Create(context.Background(), pvc.PVCObject, metav1.CreateOptions{})
+	if err != nil && errors.IsAlreadyExists(err) {
+		// The claim is kept, with the data in its volume.
+		glog.Warningf(kwlog(fmt.Sprintf("persistent volume claim %s already exists. Continuing with installation.", pvc.Name())))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the persistent volume claim %s: %v", pvc.Name(), err)))
+	}
+	return nil
+}
+
+func (pvc PersistentVolumeClaimCoreV1) Uninstall(c KubeClient, namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting persistent volume claim %s", pvc.Name())))
+	err := c.Client.CoreV1().PersistentVolumeClaims(namespace).Delete(context.Background(), pvc.Name(), metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete persistent volume claim %s. Error: %v", pvc.Name(), err)))
+	}
+}
+
+// The claim is kept as it is, with the data in its volume, the spec of a bound claim can not be changed.
+func (pvc PersistentVolumeClaimCoreV1) Update(c KubeClient, namespace string) error {
+	return pvc.Install(c, namespace)
+}
+
+// Status is the status of the claim, with its phase and capacity
+func (pvc PersistentVolumeClaimCoreV1) Status(c KubeClient, namespace string) (interface{}, error) {
+	claim, err := c.Client.CoreV1().PersistentVolumeClaims(namespace).Get(context.Background(), pvc.Name(), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf(kwlog(fmt.Sprintf("Error getting persistent volume claim status: %v", err)))
+	}
+	return claim.Status, nil
+}
+
+func (pvc PersistentVolumeClaimCoreV1) Name() string {
+	return pvc.PVCObject.ObjectMeta.Name
+}
+
+//----------------NetworkPolicy----------------
+
+type NetworkPolicyNetworkingV1 struct {
+	NetworkPolicyObject *networkingv1.NetworkPolicy
+}
+
+func (np NetworkPolicyNetworkingV1) Install(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating network policy %v", np.NetworkPolicyObject)))
+	_, err := c.Client.NetworkingV1().NetworkPolicies(namespace).Create(context.Background(), np.NetworkPolicyObject, metav1.CreateOptions{})
+	if err != nil && errors.IsAlreadyExists(err) {
+		np.Uninstall(c, namespace)
+		_, err = c.Client.NetworkingV1().NetworkPolicies(namespace).Create(context.Background(), np.NetworkPolicyObject, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the network policy %s: %v", np.Name(), err)))
+	}
+	return nil
+}
+
+func (np NetworkPolicyNetworkingV1) Uninstall(c KubeClient, namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting network policy %s", np.Name())))
+	err := c.Client.NetworkingV1().NetworkPolicies(namespace).Delete(context.Background(), np.Name(), metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete network policy %s. Error: %v", np.Name(), err)))
+	}
+}
+
+func (np NetworkPolicyNetworkingV1) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating network policy %s", np.Name())))
+	existing, err := c.Client.NetworkingV1().NetworkPolicies(namespace).Get(context.Background(), np.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return np.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the network policy %s: %v", np.Name(), err)))
+	}
+	obj := np.NetworkPolicyObject.DeepCopy()
+	obj.ObjectMeta.ResourceVersion = existing.ObjectMeta.ResourceVersion
+	if _, err := c.Client.NetworkingV1().NetworkPolicies(namespace).Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the network policy %s: %v", np.Name(), err)))
+	}
+	return nil
+}
+
+func (np NetworkPolicyNetworkingV1) Status(c KubeClient, namespace string) (interface{}, error) {
+	return nil, nil
+}
+
+func (np NetworkPolicyNetworkingV1) Name() string {
+	return np.NetworkPolicyObject.ObjectMeta.Name
+}
+
+//----------------Unstructured----------------
+// Any other kind of object is installed with the dynamic client. The resource of the kind, and whether it is
+// namespaced, is found from the API resources that the cluster serves. An existing object is updated in place.
+
+type UnstructuredObject struct {
+	Object *unstructured.Unstructured
+}
+
+func (u UnstructuredObject) Install(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating %s %s", u.Object.GetKind(), u.Name())))
+	ri, err := c.resourceInterface(u.Object.GroupVersionKind(), namespace)
+	if err != nil {
+		return err
+	}
+	_, err = ri.Create(context.Background(), u.Object, metav1.CreateOptions{})
+	if err != nil && errors.IsAlreadyExists(err) {
+		return u.Update(c, namespace)
+	}
+	if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the %s %s: %v", u.Object.GetKind(), u.Name(), err)))
+	}
+	return nil
+}
+
+func (u UnstructuredObject) Uninstall(c KubeClient, namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting %s %s", u.Object.GetKind(), u.Name())))
+	ri, err := c.resourceInterface(u.Object.GroupVersionKind(), namespace)
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete %s %s. Error: %v", u.Object.GetKind(), u.Name(), err)))
+		return
+	}
+	if err := ri.Delete(context.Background(), u.Name(), metav1.DeleteOptions{}); err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete %s %s. Error: %v", u.Object.GetKind(), u.Name(), err)))
+	}
+}
+
+func (u UnstructuredObject) Update(c KubeClient, namespace string) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("updating %s %s", u.Object.GetKind(), u.Name())))
+	ri, err := c.resourceInterface(u.Object.GroupVersionKind(), namespace)
+	if err != nil {
+		return err
+	}
+	existing, err := ri.Get(context.Background(), u.Name(), metav1.GetOptions{})
+	if err != nil && errors.IsNotFound(err) {
+		return u.Install(c, namespace)
+	} else if err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error getting the %s %s: %v", u.Object.GetKind(), u.Name(), err)))
+	}
+	obj := u.Object.DeepCopy()
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	if _, err := ri.Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error updating the %s %s: %v", u.Object.GetKind(), u.Name(), err)))
+	}
+	return nil
+}
+
+// Status is the status section of the object, if it has one
+func (u UnstructuredObject) Status(c KubeClient, namespace string) (interface{}, error) {
+	ri, err := c.resourceInterface(u.Object.GroupVersionKind(), namespace)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ri.Get(context.Background(), u.Name(), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf(kwlog(fmt.Sprintf("Error getting %s status: %v", u.Object.GetKind(), err)))
+	}
+	if status, ok := res.Object["status"]; ok {
+		return status, nil
+	}
+	return nil, nil
+}
+
+func (u UnstructuredObject) Name() string {
+	return u.Object.GetName()
+}
@@ -0,0 +1,216 @@
+//go:build unit
+// +build unit
+
+package kube_operator
+
+import (
+	"flag"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"testing"
+)
+
+func init() {
+	// Enable glog tracing in the tested functions. The output will be displayed when -v is
+	// passed on the go test command.
+	flag.Set("alsologtostderr", "true")
+	flag.Set("v", "7")
+	// no need to parse flags, that's done by test framework
+}
+
+const testCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: mywidgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: MyWidget
+    plural: mywidgets
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+`
+
+const testCR = `apiVersion: example.com/v1
+kind: MyWidget
+metadata:
+  name: mywidget
+spec:
+  ports:
+  - 80
+  - 443
+`
+
+const testOtherCR = `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: mycert
+spec:
+  dnsNames:
+  - example.com
+`
+
+const testObjects = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: myns
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: mycr
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: mycrb
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: mycr
+subjects:
+- kind: ServiceAccount
+  name: mysa
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: mycm
+data:
+  key: value
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: mysecret
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: mysvc
+  namespace: myns
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: myss
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: myds
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: mypvc
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: mynp
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myjob
+`
+
+func Test_sortAPIObjects_kinds(t *testing.T) {
+
+	k8sObjs, customResources, err := getK8sObjectFromYaml([]YamlFile{{Body: testObjects}, {Body: testCRD}, {Body: testCR}}, nil)
+	if err != nil {
+		t.Fatalf("error decoding the objects: %v", err)
+	} else if len(customResources) != 1 {
+		t.Fatalf("expected 1 custom resource, got %v", len(customResources))
+	}
+
+	cr, err := unstructuredObjectFromYaml(customResources[0])
+	if err != nil {
+		t.Fatalf("error decoding the custom resource: %v", err)
+	} else if ports := cr.Object["spec"].(map[string]interface{})["ports"].([]interface{}); len(ports) != 2 || ports[0] != 80 {
+		t.Errorf("expected 2 ports in the custom resource, got %v", ports)
+	}
+
+	objMap, namespace, err := sortAPIObjects(k8sObjs, cr, map[string]string{}, "ag1", 0)
+	if err != nil {
+		t.Fatalf("error sorting the objects: %v", err)
+	} else if namespace != "myns" {
+		t.Errorf("expected namespace myns, got %v", namespace)
+	}
+
+	for _, kind := range []string{K8S_NAMESPACE_TYPE, K8S_CLUSTER_ROLE_TYPE, K8S_CLUSTER_ROLEBINDING_TYPE, K8S_CONFIGMAP_TYPE, K8S_SECRET_TYPE,
+		K8S_SERVICE_TYPE, K8S_STATEFULSET_TYPE, K8S_DAEMONSET_TYPE, K8S_PVC_TYPE, K8S_NETWORK_POLICY_TYPE, K8S_CRD_TYPE, K8S_UNSTRUCTURED_TYPE} {
+		if len(objMap[kind]) != 1 {
+			t.Errorf("expected 1 %v object, got %v", kind, objMap[kind])
+		}
+	}
+
+	if u, ok := objMap[K8S_UNSTRUCTURED_TYPE][0].(UnstructuredObject); !ok {
+		t.Errorf("expected an unstructured object, got %T", objMap[K8S_UNSTRUCTURED_TYPE][0])
+	} else if u.Object.GetKind() != "Job" || u.Object.GetAPIVersion() != "batch/v1" || u.Name() != "myjob" {
+		t.Errorf("wrong unstructured object %v", u.Object)
+	}
+
+}
+
+func Test_sortAPIObjects_namespaces(t *testing.T) {
+
+	objs := `apiVersion: v1
+kind: Service
+metadata:
+  name: mysvc
+  namespace: ns1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: mycm
+  namespace: ns2
+`
+
+	k8sObjs, _, err := getK8sObjectFromYaml([]YamlFile{{Body: objs}}, nil)
+	if err != nil {
+		t.Fatalf("error decoding the objects: %v", err)
+	}
+
+	if _, _, err := sortAPIObjects(k8sObjs, nil, map[string]string{}, "ag1", 0); err == nil {
+		t.Errorf("expected an error for the objects in different namespaces")
+	}
+
+}
+
+func Test_findOperatorCustomResource(t *testing.T) {
+
+	k8sObjs, customResources, err := getK8sObjectFromYaml([]YamlFile{{Body: testOtherCR}, {Body: testCRD}, {Body: testCR}}, nil)
+	if err != nil {
+		t.Fatalf("error decoding the objects: %v", err)
+	} else if len(customResources) != 2 {
+		t.Fatalf("expected 2 custom resources, got %v", len(customResources))
+	}
+
+	crs := make([]*unstructured.Unstructured, 0)
+	for _, customResource := range customResources {
+		if cr, err := unstructuredObjectFromYaml(customResource); err != nil {
+			t.Fatalf("error decoding the custom resource: %v", err)
+		} else {
+			crs = append(crs, cr)
+		}
+	}
+
+	if operatorCr, others, err := findOperatorCustomResource(k8sObjs, crs); err != nil {
+		t.Errorf("error finding the operator custom resource: %v", err)
+	} else if operatorCr.GetKind() != "MyWidget" {
+		t.Errorf("wrong operator custom resource %v", operatorCr)
+	} else if len(others) != 1 || others[0].GetKind() != "Certificate" {
+		t.Errorf("wrong other objects %v", others)
+	}
+
+	// Without the custom resource definition, the operator custom resource is unknown.
+	if _, _, err := findOperatorCustomResource([]APIObjects{}, crs); err == nil {
+		t.Errorf("expected an error without the custom resource definition")
+	}
+
+}
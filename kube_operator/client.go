@@ -13,16 +13,20 @@ import (
 	"io/ioutil"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	v1scheme "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	v1beta1scheme "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/discovery/cached/memory"
 	dynamic "k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/restmapper"
 	"reflect"
 	"strings"
 )
@@ -40,8 +44,42 @@ const (
 	K8S_SERVICEACCOUNT_TYPE = "ServiceAccount"
 	K8S_CRD_TYPE            = "CustomResourceDefinition"
 	K8S_NAMESPACE_TYPE      = "Namespace"
+
+	K8S_CLUSTER_ROLE_TYPE        = "ClusterRole"
+	K8S_CLUSTER_ROLEBINDING_TYPE = "ClusterRoleBinding"
+	K8S_CONFIGMAP_TYPE           = "ConfigMap"
+	K8S_SECRET_TYPE              = "Secret"
+	K8S_SERVICE_TYPE             = "Service"
+	K8S_STATEFULSET_TYPE         = "StatefulSet"
+	K8S_DAEMONSET_TYPE           = "DaemonSet"
+	K8S_PVC_TYPE                 = "PersistentVolumeClaim"
+	K8S_NETWORK_POLICY_TYPE      = "NetworkPolicy"
+	// The objects of any other kind
+	K8S_UNSTRUCTURED_TYPE = "Unstructured"
 )
 
+// The order in which the kinds of objects are installed, they are uninstalled in the reverse order. The namespace is
+// installed first, and the custom resource definitions, which also create the custom resource that starts the
+// operator, are installed last. The objects of other kinds come last, they can be custom resources of the custom
+// resource definitions.
+var installOrder = []string{
+	K8S_CLUSTER_ROLE_TYPE,
+	K8S_CLUSTER_ROLEBINDING_TYPE,
+	K8S_ROLE_TYPE,
+	K8S_ROLEBINDING_TYPE,
+	K8S_SERVICEACCOUNT_TYPE,
+	K8S_SECRET_TYPE,
+	K8S_CONFIGMAP_TYPE,
+	K8S_PVC_TYPE,
+	K8S_SERVICE_TYPE,
+	K8S_NETWORK_POLICY_TYPE,
+	K8S_DEPLOYMENT_TYPE,
+	K8S_STATEFULSET_TYPE,
+	K8S_DAEMONSET_TYPE,
+	K8S_CRD_TYPE,
+	K8S_UNSTRUCTURED_TYPE,
+}
+
 // Intermediate state for the objects used for k8s api objects that haven't had their exact type asserted yet
 type APIObjects struct {
 	Type   *schema.GroupVersionKind
@@ -56,7 +94,9 @@ type YamlFile struct {
 
 // Client to interact with all standard k8s objects
 type KubeClient struct {
-	Client *kubernetes.Clientset
+	Client     *kubernetes.Clientset
+	dynClient  dynamic.Interface                       // Used for the objects that are handled as unstructured.Unstructured.
+	restMapper *restmapper.DeferredDiscoveryRESTMapper // Maps kinds to resources, the API resources of the cluster are discovered once and cached.
 }
 
 // KubeStatus contains the status of operator pods and a user-defined status object
@@ -77,7 +117,12 @@ func NewKubeClient() (*KubeClient, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &KubeClient{Client: clientset}, nil
+	dynClient, err := NewDynamicKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	restMapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+	return &KubeClient{Client: clientset, dynClient: dynClient, restMapper: restMapper}, nil
 }
 
 // NewDynamicKubeClient returns a kube client that interacts with unstructured.Unstructured type objects
@@ -91,8 +136,9 @@ func NewDynamicKubeClient() (dynamic.Interface, error) {
 }
 
 // Install creates the objects specified in the operator deployment in the cluster and creates the custom resource to start the operator
-func (c KubeClient) Install(tar string, envVars map[string]string, agId string, crInstallTimeout int64) error {
-	apiObjMap, namespace, err := processDeployment(tar, envVars, agId, crInstallTimeout)
+// When a namespace is given, the objects are installed into it instead of the namespace of the deployment
+func (c KubeClient) Install(tar string, envVars map[string]string, agId string, namespace string, crInstallTimeout int64) error {
+	apiObjMap, namespace, err := processDeployment(tar, envVars, agId, namespace, crInstallTimeout)
 	if err != nil {
 		return err
 	}
@@ -107,39 +153,12 @@ func (c KubeClient) Install(tar string, envVars map[string]string, agId string,
 		nsDef.Install(c, namespace)
 	}
 
-	// Create the role types in the cluster
-	for _, roleDef := range apiObjMap[K8S_ROLE_TYPE] {
-		err = roleDef.Install(c, namespace)
-		if err != nil {
-			return err
-		}
-	}
-	// Create the rolebinding types in the cluster
-	for _, roleBindingDef := range apiObjMap[K8S_ROLEBINDING_TYPE] {
-		err = roleBindingDef.Install(c, namespace)
-		if err != nil {
-			return err
-		}
-	}
-	// Create the serviceaccount types in the cluster
-	for _, svcAcctDef := range apiObjMap[K8S_SERVICEACCOUNT_TYPE] {
-		err = svcAcctDef.Install(c, namespace)
-		if err != nil {
-			return err
-		}
-	}
-	// Create the deployment types in the cluster
-	for _, dep := range apiObjMap[K8S_DEPLOYMENT_TYPE] {
-		err = dep.Install(c, namespace)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, crd := range apiObjMap[K8S_CRD_TYPE] {
-		err := crd.Install(c, namespace)
-		if err != nil {
-			return err
+	// Create the other objects in the cluster
+	for _, kind := range installOrder {
+		for _, obj := range apiObjMap[kind] {
+			if err := obj.Install(c, namespace); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -149,31 +168,17 @@ func (c KubeClient) Install(tar string, envVars map[string]string, agId string,
 }
 
 // Install creates the objects specified in the operator deployment in the cluster and creates the custom resource to start the operator
-func (c KubeClient) Uninstall(tar string, agId string) error {
-	apiObjMap, namespace, err := processDeployment(tar, map[string]string{}, agId, 0)
+func (c KubeClient) Uninstall(tar string, agId string, namespace string) error {
+	apiObjMap, namespace, err := processDeployment(tar, map[string]string{}, agId, namespace, 0)
 	if err != nil {
 		return err
 	}
 
-	for _, crd := range apiObjMap[K8S_CRD_TYPE] {
-		crd.Uninstall(c, namespace)
-	}
-
-	// Delete the deployment types in the cluster
-	for _, dep := range apiObjMap[K8S_DEPLOYMENT_TYPE] {
-		dep.Uninstall(c, namespace)
-	}
-	// Delete the serviceaccount types in the cluster
-	for _, svcAcctDef := range apiObjMap[K8S_SERVICEACCOUNT_TYPE] {
-		svcAcctDef.Uninstall(c, namespace)
-	}
-	// Delete the rolebinding types in the cluster
-	for _, roleBindingDef := range apiObjMap[K8S_ROLEBINDING_TYPE] {
-		roleBindingDef.Uninstall(c, namespace)
-	}
-	// Delete the role types in the cluster
-	for _, roleDef := range apiObjMap[K8S_ROLE_TYPE] {
-		roleDef.Uninstall(c, namespace)
+	// Delete the objects from the cluster in the reverse order of their creation
+	for i := len(installOrder) - 1; i >= 0; i-- {
+		for _, obj := range apiObjMap[installOrder[i]] {
+			obj.Uninstall(c, namespace)
+		}
 	}
 	for _, namespaceDef := range apiObjMap[K8S_NAMESPACE_TYPE] {
 		namespaceDef.Uninstall(c, namespace)
@@ -182,8 +187,8 @@ func (c KubeClient) Uninstall(tar string, agId string) error {
 	glog.V(3).Infof(kwlog(fmt.Sprintf("Completed removal of all operator objects from the cluster.")))
 	return nil
 }
-func (c KubeClient) OperatorStatus(tar string, agId string) (interface{}, error) {
-	apiObjMap, namespace, err := processDeployment(tar, map[string]string{}, agId, 0)
+func (c KubeClient) OperatorStatus(tar string, agId string, namespace string) (interface{}, error) {
+	apiObjMap, namespace, err := processDeployment(tar, map[string]string{}, agId, namespace, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -198,8 +203,8 @@ func (c KubeClient) OperatorStatus(tar string, agId string) (interface{}, error)
 	}
 	return status, nil
 }
-func (c KubeClient) Status(tar string, agId string) ([]ContainerStatus, error) {
-	apiObjMap, namespace, err := processDeployment(tar, map[string]string{}, agId, 0)
+func (c KubeClient) Status(tar string, agId string, namespace string) ([]ContainerStatus, error) {
+	apiObjMap, namespace, err := processDeployment(tar, map[string]string{}, agId, namespace, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -244,7 +249,8 @@ func (c KubeClient) Status(tar string, agId string) ([]ContainerStatus, error) {
 }
 
 // processDeployment takes the deployment string and converts it to a map with the k8s objects, the namespace to be used, and an error if one occurs
-func processDeployment(tar string, envVars map[string]string, agId string, crInstallTimeout int64) (map[string][]APIObjectInterface, string, error) {
+// When a namespace is given, it is used instead of the namespace of the deployment
+func processDeployment(tar string, envVars map[string]string, agId string, namespace string, crInstallTimeout int64) (map[string][]APIObjectInterface, string, error) {
 	// Read the yaml files from the commpressed tar files
 	yamls, err := getYamlFromTarGz(tar)
 	if err != nil {
@@ -257,17 +263,170 @@ func processDeployment(tar string, envVars map[string]string, agId string, crIns
 		return nil, "", err
 	}
 
-	if len(customResources) != 1 {
-		return nil, "", fmt.Errorf(kwlog(fmt.Sprintf("Expected one custom resource in deployment. Got %d", len(customResources))))
+	if len(customResources) < 1 {
+		return nil, "", fmt.Errorf(kwlog(fmt.Sprintf("Expected at least one custom resource in deployment. Got %d", len(customResources))))
 	}
 
-	unstructCr, err := unstructuredObjectFromYaml(customResources[0])
+	unstructCrs := []*unstructured.Unstructured{}
+	for _, customResource := range customResources {
+		unstructCr, err := unstructuredObjectFromYaml(customResource)
+		if err != nil {
+			return nil, "", err
+		}
+		unstructCrs = append(unstructCrs, unstructCr)
+	}
+
+	// The operator is started by the custom resource of the custom resource definitions in the deployment. The objects
+	// of kinds that are not known to the agent are installed as unstructured objects.
+	operatorCr, otherObjs, err := findOperatorCustomResource(k8sObjs, unstructCrs)
 	if err != nil {
 		return nil, "", err
 	}
 
+	if namespace != "" {
+		k8sObjs = moveToNamespace(k8sObjs, unstructCrs, namespace)
+	}
+
 	// Sort the k8s api objects by kind
-	return sortAPIObjects(k8sObjs, unstructCr, envVars, agId, crInstallTimeout)
+	apiObjMap, deploymentNamespace, err := sortAPIObjects(k8sObjs, operatorCr, envVars, agId, crInstallTimeout)
+	if err != nil {
+		return nil, "", err
+	}
+	if namespace == "" {
+		namespace = deploymentNamespace
+	} else {
+		shareDefinitions(apiObjMap)
+	}
+	for _, obj := range otherObjs {
+		if namespace != ANAX_NAMESPACE && obj.GetNamespace() != "" && obj.GetNamespace() != namespace {
+			return nil, "", fmt.Errorf(kwlog(fmt.Sprintf("Error: multiple namespaces specified in operator: %s and %s", namespace, obj.GetNamespace())))
+		} else if err := addAPIObject(apiObjMap, K8S_UNSTRUCTURED_TYPE, UnstructuredObject{Object: obj}); err != nil {
+			return nil, "", err
+		}
+	}
+	return apiObjMap, namespace, nil
+}
+
+// Move the objects of a deployment into the given namespace. The namespaces of the deployment are left out, the
+// namespace of the objects is cleared so that they are installed into the given namespace, and the service accounts
+// bound by the role bindings are the ones in the given namespace. The cluster roles and cluster role bindings are not
+// namespaced, so the namespace is added to their names to keep them apart from those of the other agreements.
+func moveToNamespace(k8sObjs []APIObjects, unstructCrs []*unstructured.Unstructured, namespace string) []APIObjects {
+	clusterRoles := map[string]bool{}
+	for _, obj := range k8sObjs {
+		if typedClusterRole, ok := obj.Object.(*rbacv1.ClusterRole); ok {
+			clusterRoles[typedClusterRole.Name] = true
+			typedClusterRole.Name = clusterScopedName(typedClusterRole.Name, namespace)
+		}
+	}
+
+	movedObjs := []APIObjects{}
+	for _, obj := range k8sObjs {
+		if obj.Type != nil && obj.Type.Kind == K8S_NAMESPACE_TYPE {
+			continue
+		}
+		if objMeta, err := meta.Accessor(obj.Object); err == nil {
+			objMeta.SetNamespace("")
+		}
+		var subjects []rbacv1.Subject
+		var roleRef *rbacv1.RoleRef
+		if typedRoleBinding, ok := obj.Object.(*rbacv1.RoleBinding); ok {
+			subjects = typedRoleBinding.Subjects
+			roleRef = &typedRoleBinding.RoleRef
+		} else if typedClusterRoleBinding, ok := obj.Object.(*rbacv1.ClusterRoleBinding); ok {
+			typedClusterRoleBinding.Name = clusterScopedName(typedClusterRoleBinding.Name, namespace)
+			subjects = typedClusterRoleBinding.Subjects
+			roleRef = &typedClusterRoleBinding.RoleRef
+		}
+		for i := range subjects {
+			if subjects[i].Kind == rbacv1.ServiceAccountKind {
+				subjects[i].Namespace = namespace
+			}
+		}
+		if roleRef != nil && roleRef.Kind == K8S_CLUSTER_ROLE_TYPE && clusterRoles[roleRef.Name] {
+			roleRef.Name = clusterScopedName(roleRef.Name, namespace)
+		}
+		movedObjs = append(movedObjs, obj)
+	}
+	for _, cr := range unstructCrs {
+		cr.SetNamespace("")
+	}
+	return movedObjs
+}
+
+// Returns the name of a cluster scoped object of the operator that is installed into the given namespace.
+func clusterScopedName(name string, namespace string) string {
+	return fmt.Sprintf("%s-%s", name, namespace)
+}
+
+// The custom resource definitions can not be renamed, so the operators that are installed into the namespaces of
+// different agreements share them. A shared definition is kept as long as there are custom resources of it.
+func shareDefinitions(apiObjMap map[string][]APIObjectInterface) {
+	for i, obj := range apiObjMap[K8S_CRD_TYPE] {
+		switch typedCR := obj.(type) {
+		case CustomResourceV1:
+			typedCR.Shared = true
+			apiObjMap[K8S_CRD_TYPE][i] = typedCR
+		case CustomResourceV1Beta1:
+			typedCR.Shared = true
+			apiObjMap[K8S_CRD_TYPE][i] = typedCR
+		}
+	}
+}
+
+// Returns the custom resource that starts the operator, and the other objects that are not known to the agent. When
+// there is more than one, the custom resource is the one whose kind is defined by a custom resource definition in the
+// deployment.
+func findOperatorCustomResource(k8sObjs []APIObjects, unstructCrs []*unstructured.Unstructured) (*unstructured.Unstructured, []*unstructured.Unstructured, error) {
+	if len(unstructCrs) == 1 {
+		return unstructCrs[0], nil, nil
+	}
+
+	crdKinds := map[string]bool{}
+	for _, obj := range k8sObjs {
+		if typedCRD, ok := obj.Object.(*v1beta1scheme.CustomResourceDefinition); ok {
+			crdKinds[typedCRD.Spec.Names.Kind] = true
+		} else if typedCRD, ok := obj.Object.(*v1scheme.CustomResourceDefinition); ok {
+			crdKinds[typedCRD.Spec.Names.Kind] = true
+		}
+	}
+
+	var operatorCr *unstructured.Unstructured
+	otherObjs := []*unstructured.Unstructured{}
+	for _, cr := range unstructCrs {
+		if operatorCr == nil && crdKinds[cr.GetKind()] {
+			operatorCr = cr
+		} else {
+			otherObjs = append(otherObjs, cr)
+		}
+	}
+	if operatorCr == nil {
+		return nil, nil, fmt.Errorf(kwlog(fmt.Sprintf("Expected one custom resource of a custom resource definition in deployment. Got %d objects of unknown kinds", len(unstructCrs))))
+	}
+	return operatorCr, otherObjs, nil
+}
+
+// Returns the dynamic client for the objects of the given kind. The resource of the kind, and whether it is
+// namespaced, is found from the API resources that the cluster serves. They are cached by the client, and discovered
+// again when the kind is not found, because the kind might be defined by a CRD that was installed after the discovery.
+func (c KubeClient) resourceInterface(gvk schema.GroupVersionKind, namespace string) (dynamic.ResourceInterface, error) {
+	if c.restMapper == nil || c.dynClient == nil {
+		return nil, fmt.Errorf(kwlog(fmt.Sprintf("Error: the kube client was not created with NewKubeClient")))
+	}
+
+	mapping, err := c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		c.restMapper.Reset()
+		mapping, err = c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(kwlog(fmt.Sprintf("Error: failed to find the API resource of %v: %v", gvk, err)))
+	}
+
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return c.dynClient.Resource(mapping.Resource).Namespace(namespace), nil
+	}
+	return c.dynClient.Resource(mapping.Resource), nil
 }
 
 // CreateConfigMap will create a config map with the provided environment variable map
@@ -279,7 +438,7 @@ func (c KubeClient) CreateConfigMap(envVars map[string]string, agId string, name
 		}
 		delete(envVars, "")
 	}
-	hznEnvConfigMap := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%s", HZN_ENV_VARS, agId)}, Data: envVars}
+	hznEnvConfigMap := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: envConfigMapName(agId)}, Data: envVars}
 	res, err := c.Client.CoreV1().ConfigMaps(namespace).Create(context.Background(), &hznEnvConfigMap, metav1.CreateOptions{})
 	if err != nil {
 		return "", fmt.Errorf("Error: failed to create config map for %s: %v", agId, err)
@@ -287,6 +446,21 @@ func (c KubeClient) CreateConfigMap(envVars map[string]string, agId string, name
 	return res.ObjectMeta.Name, nil
 }
 
+// Delete the environment variable config map of the agreement
+func (c KubeClient) deleteEnvConfigMap(agId string, namespace string) {
+	configMapName := envConfigMapName(agId)
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting config map %v", configMapName)))
+	err := c.Client.CoreV1().ConfigMaps(namespace).Delete(context.Background(), configMapName, metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete config map %s. Error: %v", configMapName, err)))
+	}
+}
+
+// The name of the environment variable config map of the agreement
+func envConfigMapName(agId string) string {
+	return fmt.Sprintf("%s-%s", HZN_ENV_VARS, agId)
+}
+
 func unstructuredObjectFromYaml(crStr YamlFile) (*unstructured.Unstructured, error) {
 	cr := make(map[string]interface{})
 	err := yaml.UnmarshalStrict([]byte(crStr.Body), &cr)
@@ -326,7 +500,7 @@ func makeAllKeysStrings(unmarshYaml interface{}) interface{} {
 		}
 		return retMap
 	} else if reflect.ValueOf(unmarshYaml).Kind() == reflect.Slice {
-		correctedSlice := make([]interface{}, len(unmarshYaml.([]interface{})))
+		correctedSlice := make([]interface{}, 0, len(unmarshYaml.([]interface{})))
 		for _, elem := range unmarshYaml.([]interface{}) {
 			correctedSlice = append(correctedSlice, makeAllKeysStrings(elem))
 		}
@@ -377,10 +551,6 @@ func getK8sObjectFromYaml(yamlFiles []YamlFile, sch *runtime.Scheme) ([]APIObjec
 		}
 	}
 
-	if len(customResources) > 1 {
-		return retObjects, customResources, fmt.Errorf(kwlog(fmt.Sprintf("Error: kubernetes object not in recognized scheme.")))
-	}
-
 	return retObjects, customResources, nil
 }
 
@@ -0,0 +1,62 @@
+//go:build unit
+// +build unit
+
+package kube_operator
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/restmapper"
+	kubetesting "k8s.io/client-go/testing"
+	"testing"
+)
+
+func Test_resourceInterface(t *testing.T) {
+
+	discovery := &fakediscovery.FakeDiscovery{Fake: &kubetesting.Fake{}}
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+		},
+	}
+	c := KubeClient{
+		dynClient:  fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()),
+		restMapper: restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discovery)),
+	}
+
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	if _, err := c.resourceInterface(configMap, "myns"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The API resources are discovered once and then cached.
+	actions := len(discovery.Actions())
+	if _, err := c.resourceInterface(configMap, "myns"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if len(discovery.Actions()) != actions {
+		t.Errorf("the API resources should be cached, discovery actions went from %v to %v", actions, len(discovery.Actions()))
+	}
+
+	myResource := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "MyResource"}
+	if _, err := c.resourceInterface(myResource, "myns"); err == nil {
+		t.Errorf("expected an error for a kind the cluster does not serve")
+	}
+
+	// A kind defined by a CRD that was installed after the discovery is found by discovering the API resources again.
+	discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "myresources", Kind: "MyResource", Namespaced: true}},
+	})
+	if _, err := c.resourceInterface(myResource, "myns"); err != nil {
+		t.Errorf("unexpected error after the CRD was installed: %v", err)
+	}
+
+	if _, err := (KubeClient{}).resourceInterface(configMap, "myns"); err == nil {
+		t.Errorf("expected an error for a kube client without a dynamic client")
+	}
+}
@@ -1,6 +1,7 @@
 package kube_operator
 
 import (
+	"errors"
 	"fmt"
 	"github.com/boltdb/bolt"
 	"github.com/golang/glog"
@@ -8,11 +9,16 @@ import (
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/worker"
+	"time"
 )
 
+// How often the operators whose agreement ended are checked for uninstall, in seconds.
+const OPERATOR_CHECK_INTERVAL_S = 30
+
 type KubeWorker struct {
 	worker.BaseWorker
-	db *bolt.DB
+	db           *bolt.DB
+	shuttingDown bool
 }
 
 func NewKubeWorker(name string, config *config.HorizonConfig, db *bolt.DB) *KubeWorker {
@@ -21,7 +27,7 @@ func NewKubeWorker(name string, config *config.HorizonConfig, db *bolt.DB) *Kube
 		db:         db,
 	}
 	glog.Info(kwlog(fmt.Sprintf("Starting Kubernetes Worker")))
-	worker.Start(worker, 0)
+	worker.Start(worker, OPERATOR_CHECK_INTERVAL_S)
 	return worker
 }
 
@@ -54,6 +60,13 @@ func (w *KubeWorker) NewEvent(incoming events.Message) {
 			w.Commands <- cmd
 		}
 
+	case *events.NodeShutdownMessage:
+		msg, _ := incoming.(*events.NodeShutdownMessage)
+		switch msg.Event().Id {
+		case events.START_UNCONFIGURE:
+			w.Commands <- NewShutdownCommand()
+		}
+
 	case *events.NodeShutdownCompleteMessage:
 		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
 		switch msg.Event().Id {
@@ -84,7 +97,10 @@ func (w *KubeWorker) CommandHandler(command worker.Command) bool {
 
 			// Check the deployment to check if it is a kube deployment
 			deploymentConfig := lc.ContainerConfig().ClusterDeployment
-			if kd, err := persistence.GetKubeDeployment(deploymentConfig); err != nil {
+			if persistence.IsHelmDeployment(deploymentConfig) {
+				glog.V(5).Infof(kwlog(fmt.Sprintf("ignoring Helm deployment.")))
+				return true
+			} else if kd, err := persistence.GetKubeDeployment(deploymentConfig); err != nil {
 				glog.Errorf(kwlog(fmt.Sprintf("error getting kube deployment configuration: %v", err)))
 				return true
 			} else if _, err := persistence.AgreementDeploymentStarted(w.db, lc.AgreementId, lc.AgreementProtocol, kd); err != nil {
@@ -107,7 +123,7 @@ func (w *KubeWorker) CommandHandler(command worker.Command) bool {
 		if !ok {
 			glog.Warningf(kwlog(fmt.Sprintf("ignoring non-Kube cancelation command %v", cmd)))
 			return true
-		} else if err := w.uninstallKubeOperator(kdc, cmd.CurrentAgreementId); err != nil {
+		} else if err := w.operatorAgreementEnded(kdc, cmd.AgreementProtocol, cmd.CurrentAgreementId); err != nil {
 			glog.Errorf(kwlog(fmt.Sprintf("failed to uninstall kube operator %v", cmd.Deployment)))
 		}
 
@@ -123,12 +139,21 @@ func (w *KubeWorker) CommandHandler(command worker.Command) bool {
 			glog.Errorf(kwlog(fmt.Sprintf("%v", err)))
 			w.Messages() <- events.NewWorkloadMessage(events.EXECUTION_FAILED, cmd.AgreementProtocol, cmd.AgreementId, kdc)
 		}
+	case *ShutdownCommand:
+		// The agreements are about to be cancelled, their operators are not going to be taken over.
+		w.shuttingDown = true
+		w.uninstallEndedOperators(true)
 	default:
 		return true
 	}
 	return true
 }
 
+// Uninstall the operators whose uninstall was deferred, when the deferral ran out.
+func (w *KubeWorker) NoWorkHandler() {
+	w.uninstallEndedOperators(false)
+}
+
 func (w *KubeWorker) getLaunchContext(launchContext interface{}) *events.AgreementLaunchContext {
 	switch launchContext.(type) {
 	case *events.AgreementLaunchContext:
@@ -138,26 +163,171 @@ func (w *KubeWorker) getLaunchContext(launchContext interface{}) *events.Agreeme
 	return nil
 }
 
+// Install the operator of an agreement. When the operator already exists because an agreement for another version of
+// the service installed it and that agreement ended, its objects are upgraded in place and the operator is taken over
+// by the agreement. An operator that is still in use by another agreement is never taken over. A
+// failed upgrade is rolled back to the objects of the other agreement. When the service or the node policy asks for
+// an isolated namespace, the operator is installed into a namespace that is created for the agreement, with the
+// resource quota and limit range of the service, and it is never taken over.
 func (w *KubeWorker) processKubeOperator(lc *events.AgreementLaunchContext, kd *persistence.KubeDeploymentConfig, crInstallTimeout int64) error {
 	glog.V(3).Infof(kwlog(fmt.Sprintf("begin install of Kube Deployment %s", lc.AgreementId)))
 	client, err := NewKubeClient()
 	if err != nil {
 		return err
 	}
-	err = client.Install(kd.OperatorYamlArchive, *(lc.EnvironmentAdditions), lc.AgreementId, crInstallTimeout)
+
+	namespace := ""
+	if w.isolatedNamespace(kd) {
+		namespace = AgreementNamespaceName(lc.AgreementId)
+	}
+
+	serviceOrg, serviceURL, err := w.agreementService(lc.AgreementProtocol, lc.AgreementId)
+	if err != nil {
+		return err
+	}
+	opName, err := OperatorName(kd.OperatorYamlArchive, namespace, serviceOrg, serviceURL)
 	if err != nil {
 		return err
 	}
+	operator, err := persistence.FindKubeOperator(w.db, opName)
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to read kube operator %v from the database, error: %v", opName, err))
+	}
+
+	if namespace != "" {
+		// The namespace is deleted when anything fails, it might have been created before the quota or the limit range failed.
+		if err = client.CreateAgreementNamespace(namespace, lc.AgreementId, kd.Resources); err == nil {
+			err = client.Install(kd.OperatorYamlArchive, *(lc.EnvironmentAdditions), lc.AgreementId, namespace, crInstallTimeout)
+		}
+		if err != nil {
+			client.DeleteAgreementNamespace(namespace)
+		}
+	} else if operator == nil || operator.AgreementId == lc.AgreementId {
+		err = client.Install(kd.OperatorYamlArchive, *(lc.EnvironmentAdditions), lc.AgreementId, "", crInstallTimeout)
+	} else if operator.UninstallTime == 0 {
+		return errors.New(fmt.Sprintf("kube operator %v is in use by agreement %v", opName, operator.AgreementId))
+	} else {
+		glog.Infof(kwlog(fmt.Sprintf("upgrading kube operator %v of agreement %v for agreement %v", opName, operator.AgreementId, lc.AgreementId)))
+		err = client.Upgrade(operator.OperatorYamlArchive, operator.AgreementId, kd.OperatorYamlArchive, *(lc.EnvironmentAdditions), lc.AgreementId, crInstallTimeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	newOperator := &persistence.KubeOperator{OperatorName: opName, Namespace: namespace, AgreementId: lc.AgreementId, OperatorYamlArchive: kd.OperatorYamlArchive}
+	if err := persistence.SaveKubeOperator(w.db, newOperator); err != nil {
+		return errors.New(fmt.Sprintf("unable to save kube operator %v, error: %v", newOperator, err))
+	}
 	return nil
 }
 
-func (w *KubeWorker) uninstallKubeOperator(kd *persistence.KubeDeploymentConfig, agId string) error {
-	glog.V(3).Infof(kwlog(fmt.Sprintf("begin uninstall of Kube Deployment %s", agId)))
+// Returns true if the operator is installed into a namespace of its own for each agreement, when the service or the
+// node policy asks for it.
+func (w *KubeWorker) isolatedNamespace(kd *persistence.KubeDeploymentConfig) bool {
+	if kd.IsolatedNamespace {
+		return true
+	} else if nodePol, err := persistence.FindNodePolicy(w.db); err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to read the node policy, error: %v", err)))
+	} else if nodePol != nil {
+		return nodePol.IsolatedClusterNamespaces
+	}
+	return false
+}
+
+// Called when the agreement of an operator ended. The operator is left alone when another agreement took it over.
+// The uninstall is deferred for the configured upgrade window, so that an agreement for another version of the
+// service can upgrade the operator in place instead of installing it again. The operator in the namespace of the
+// agreement is uninstalled right away, with its namespace. When the install into the namespace of the agreement
+// failed, there is no operator to uninstall, only the namespace is deleted. The objects shared with the other
+// agreements of the service, like the CRDs, are never uninstalled in that case.
+func (w *KubeWorker) operatorAgreementEnded(kd *persistence.KubeDeploymentConfig, agProtocol string, agId string) error {
+	if operator, err := findAgreementOperator(w.db, agId); err != nil {
+		return err
+	} else if operator != nil && operator.Namespace != "" {
+		return w.uninstallKubeOperator(operator)
+	} else if operator == nil && w.isolatedNamespace(kd) {
+		client, err := NewKubeClient()
+		if err != nil {
+			return err
+		}
+		client.DeleteAgreementNamespace(AgreementNamespaceName(agId))
+		return nil
+	}
+
+	serviceOrg, serviceURL, err := w.agreementService(agProtocol, agId)
+	if err != nil {
+		return err
+	}
+	opName, err := OperatorName(kd.OperatorYamlArchive, "", serviceOrg, serviceURL)
+	if err != nil {
+		return err
+	}
+
+	operator, err := persistence.FindKubeOperator(w.db, opName)
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to read kube operator %v from the database, error: %v", opName, err))
+	} else if operator == nil {
+		// Operators installed before the agent kept track of them.
+		operator = &persistence.KubeOperator{OperatorName: opName, AgreementId: agId, OperatorYamlArchive: kd.OperatorYamlArchive}
+	} else if operator.AgreementId != agId {
+		glog.Infof(kwlog(fmt.Sprintf("kube operator %v was taken over by agreement %v, not uninstalling it for agreement %v", opName, operator.AgreementId, agId)))
+		return nil
+	}
+
+	if window := w.Config.GetK8sUpgradeWindowS(); window > 0 && !w.shuttingDown {
+		operator.UninstallTime = uint64(time.Now().Unix() + window)
+		glog.Infof(kwlog(fmt.Sprintf("deferring uninstall of kube operator %v for %v seconds", opName, window)))
+		return persistence.SaveKubeOperator(w.db, operator)
+	}
+
+	return w.uninstallKubeOperator(operator)
+}
+
+// Returns the org and URL of the service of the agreement.
+func (w *KubeWorker) agreementService(agProtocol string, agId string) (string, string, error) {
+	if ags, err := persistence.FindEstablishedAgreements(w.db, agProtocol, []persistence.EAFilter{persistence.IdEAFilter(agId)}); err != nil {
+		return "", "", errors.New(fmt.Sprintf("unable to read agreement %v from the database, error: %v", agId, err))
+	} else if len(ags) != 1 {
+		return "", "", errors.New(fmt.Sprintf("agreement %v is not in the database", agId))
+	} else {
+		return ags[0].RunningWorkload.Org, ags[0].RunningWorkload.URL, nil
+	}
+}
+
+// Uninstall the operators whose agreement ended, when their uninstall time passed or regardless of it.
+func (w *KubeWorker) uninstallEndedOperators(all bool) {
+	operators, err := persistence.FindKubeOperators(w.db)
+	if err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to read kube operators from the database, error: %v", err)))
+		return
+	}
+
+	now := uint64(time.Now().Unix())
+	for _, operator := range operators {
+		if operator.UninstallTime != 0 && (all || operator.UninstallTime <= now) {
+			if err := w.uninstallKubeOperator(&operator); err != nil {
+				glog.Errorf(kwlog(err))
+			}
+		}
+	}
+}
+
+func (w *KubeWorker) uninstallKubeOperator(operator *persistence.KubeOperator) error {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("begin uninstall of Kube Deployment %s", operator.AgreementId)))
+
+	// The operator is forgotten even if the uninstall fails, there is nothing more the agent can do with it.
+	if err := persistence.DeleteKubeOperator(w.db, operator.OperatorName); err != nil {
+		glog.Errorf(kwlog(err))
+	}
+
 	client, err := NewKubeClient()
 	if err != nil {
 		return err
 	}
-	err = client.Uninstall(kd.OperatorYamlArchive, agId)
+	err = client.Uninstall(operator.OperatorYamlArchive, operator.AgreementId, operator.Namespace)
+	if operator.Namespace != "" {
+		client.DeleteAgreementNamespace(operator.Namespace)
+	}
 	if err != nil {
 		return err
 	}
@@ -170,7 +340,7 @@ func (w *KubeWorker) operatorStatus(kd *persistence.KubeDeploymentConfig, intend
 	if err != nil {
 		return err
 	}
-	opStatus, err := client.Status(kd.OperatorYamlArchive, agId)
+	opStatus, err := client.Status(kd.OperatorYamlArchive, agId, FindAgreementNamespace(w.db, agId))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,172 @@
+package kube_operator
+
+import (
+	"context"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/persistence"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+)
+
+const (
+	// The prefix of the name of the namespace that the agent creates for an agreement
+	AGREEMENT_NAMESPACE_PREFIX = "hzn-"
+	// The annotation of the namespace of an agreement that holds the agreement id
+	AGREEMENT_ID_ANNOTATION = "openhorizon.org/agreement-id"
+	// The names of the resource quota and the limit range of the namespace of an agreement
+	AGREEMENT_QUOTA_NAME  = "hzn-resource-quota"
+	AGREEMENT_LIMITS_NAME = "hzn-limit-range"
+)
+
+// Returns the name of the namespace that the agent creates for an agreement. A namespace name is at most 63
+// characters long.
+func AgreementNamespaceName(agId string) string {
+	name := AGREEMENT_NAMESPACE_PREFIX + strings.ToLower(agId)
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// Returns the namespace that the agent created for the operator of the agreement, or an empty string when the operator
+// is installed into the namespace of its deployment.
+func FindAgreementNamespace(db *bolt.DB, agId string) string {
+	if operator, err := findAgreementOperator(db, agId); err != nil {
+		glog.Errorf(kwlog(err))
+	} else if operator != nil {
+		return operator.Namespace
+	}
+	return ""
+}
+
+// Returns the kube operator that is owned by the agreement, or nil if there is none.
+func findAgreementOperator(db *bolt.DB, agId string) (*persistence.KubeOperator, error) {
+	operators, err := persistence.FindKubeOperators(db)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read kube operators from the database, error: %v", err)
+	}
+	for _, operator := range operators {
+		if operator.AgreementId == agId {
+			return &operator, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateAgreementNamespace creates the namespace of an agreement, with the resource quota and the limit range of the
+// resource requirements of the service.
+func (c KubeClient) CreateAgreementNamespace(namespace string, agId string, resources *persistence.KubeResourceRequirements) error {
+	if err := resources.Validate(); err != nil {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error: the resources of namespace %s are not valid: %v", namespace, err)))
+	}
+	nsObj, quota, limits := agreementNamespaceObjects(namespace, agId, resources)
+
+	glog.V(3).Infof(kwlog(fmt.Sprintf("creating namespace %s for agreement %s", namespace, agId)))
+	if _, err := c.Client.CoreV1().Namespaces().Create(context.Background(), nsObj, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the namespace %s: %v", namespace, err)))
+	}
+
+	if quota != nil {
+		glog.V(3).Infof(kwlog(fmt.Sprintf("creating resource quota %v", quota.Spec.Hard)))
+		_, err := c.Client.CoreV1().ResourceQuotas(namespace).Create(context.Background(), quota, metav1.CreateOptions{})
+		if err != nil && errors.IsAlreadyExists(err) {
+			_, err = c.Client.CoreV1().ResourceQuotas(namespace).Update(context.Background(), quota, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the resource quota of namespace %s: %v", namespace, err)))
+		}
+	}
+
+	if limits != nil {
+		glog.V(3).Infof(kwlog(fmt.Sprintf("creating limit range %v", limits.Spec.Limits)))
+		_, err := c.Client.CoreV1().LimitRanges(namespace).Create(context.Background(), limits, metav1.CreateOptions{})
+		if err != nil && errors.IsAlreadyExists(err) {
+			_, err = c.Client.CoreV1().LimitRanges(namespace).Update(context.Background(), limits, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return fmt.Errorf(kwlog(fmt.Sprintf("Error creating the limit range of namespace %s: %v", namespace, err)))
+		}
+	}
+	return nil
+}
+
+// DeleteAgreementNamespace deletes the namespace of an agreement, with all the objects that are left in it.
+func (c KubeClient) DeleteAgreementNamespace(namespace string) {
+	glog.V(3).Infof(kwlog(fmt.Sprintf("deleting namespace %s", namespace)))
+	err := c.Client.CoreV1().Namespaces().Delete(context.Background(), namespace, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		glog.Errorf(kwlog(fmt.Sprintf("unable to delete namespace %s. Error: %v", namespace, err)))
+	}
+}
+
+// Returns the namespace of an agreement, and its resource quota and limit range. The quota and the limit range are nil
+// when the service does not have resource requirements. The quota limits the resources of all the containers in the
+// namespace, the limit range gives the containers that do not set their own limits the container values. The container
+// values are never the whole quota, or a second container would not fit into it.
+func agreementNamespaceObjects(namespace string, agId string, resources *persistence.KubeResourceRequirements) (*corev1.Namespace, *corev1.ResourceQuota, *corev1.LimitRange) {
+	nsObj := &corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: namespace, Annotations: map[string]string{AGREEMENT_ID_ANNOTATION: agId}},
+	}
+	if resources == nil {
+		return nsObj, nil, nil
+	}
+
+	hard := corev1.ResourceList{}
+	max := corev1.ResourceList{}
+	defaults := corev1.ResourceList{}
+	if resources.MaxMemoryMb > 0 {
+		hard[corev1.ResourceLimitsMemory] = memoryQuantity(resources.MaxMemoryMb)
+		hard[corev1.ResourceRequestsMemory] = memoryQuantity(resources.MaxMemoryMb)
+		max[corev1.ResourceMemory] = memoryQuantity(resources.MaxMemoryMb)
+	}
+	if resources.ContainerMemoryMb > 0 {
+		defaults[corev1.ResourceMemory] = memoryQuantity(resources.ContainerMemoryMb)
+	}
+	if resources.MaxCPUs > 0 {
+		hard[corev1.ResourceLimitsCPU] = cpuQuantity(resources.MaxCPUs)
+		hard[corev1.ResourceRequestsCPU] = cpuQuantity(resources.MaxCPUs)
+		max[corev1.ResourceCPU] = cpuQuantity(resources.MaxCPUs)
+	}
+	if resources.ContainerCPUs > 0 {
+		defaults[corev1.ResourceCPU] = cpuQuantity(resources.ContainerCPUs)
+	}
+	if resources.MaxPods > 0 {
+		hard[corev1.ResourcePods] = *resource.NewQuantity(resources.MaxPods, resource.DecimalSI)
+	}
+
+	var quota *corev1.ResourceQuota
+	if len(hard) != 0 {
+		quota = &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: AGREEMENT_QUOTA_NAME, Namespace: namespace},
+			Spec:       corev1.ResourceQuotaSpec{Hard: hard},
+		}
+	}
+
+	var limits *corev1.LimitRange
+	if len(defaults) != 0 {
+		item := corev1.LimitRangeItem{Type: corev1.LimitTypeContainer, Default: defaults, DefaultRequest: defaults}
+		if len(max) != 0 {
+			item.Max = max
+		}
+		limits = &corev1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{Name: AGREEMENT_LIMITS_NAME, Namespace: namespace},
+			Spec:       corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{item}},
+		}
+	}
+
+	return nsObj, quota, limits
+}
+
+func memoryQuantity(mb int64) resource.Quantity {
+	return *resource.NewQuantity(mb*1024*1024, resource.BinarySI)
+}
+
+func cpuQuantity(cpus float32) resource.Quantity {
+	return *resource.NewMilliQuantity(int64(cpus*1000), resource.DecimalSI)
+}
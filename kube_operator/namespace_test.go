@@ -0,0 +1,136 @@
+//go:build unit
+// +build unit
+
+package kube_operator
+
+import (
+	"github.com/open-horizon/anax/persistence"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"strings"
+	"testing"
+)
+
+func Test_AgreementNamespaceName(t *testing.T) {
+
+	agId := strings.Repeat("AB01", 16)
+	if name := AgreementNamespaceName(agId); len(name) != 63 {
+		t.Errorf("expected a 63 character namespace name, got %v", name)
+	} else if name != "hzn-"+strings.ToLower(agId)[:59] {
+		t.Errorf("wrong namespace name %v", name)
+	}
+
+	if name := AgreementNamespaceName("ag1"); name != "hzn-ag1" {
+		t.Errorf("wrong namespace name %v", name)
+	}
+
+}
+
+func Test_agreementNamespaceObjects(t *testing.T) {
+
+	nsObj, quota, limits := agreementNamespaceObjects("hzn-ag1", "ag1", nil)
+	if nsObj.Name != "hzn-ag1" || nsObj.Annotations[AGREEMENT_ID_ANNOTATION] != "ag1" {
+		t.Errorf("wrong namespace %v", nsObj)
+	} else if quota != nil || limits != nil {
+		t.Errorf("expected no quota and limit range without resource requirements, got %v and %v", quota, limits)
+	}
+
+	resources := &persistence.KubeResourceRequirements{MaxMemoryMb: 512, MaxCPUs: 1.5, MaxPods: 4, ContainerMemoryMb: 128, ContainerCPUs: 0.25}
+	_, quota, limits = agreementNamespaceObjects("hzn-ag1", "ag1", resources)
+	if quota == nil || limits == nil {
+		t.Fatalf("expected a quota and a limit range, got %v and %v", quota, limits)
+	}
+
+	hard := quota.Spec.Hard
+	if q := hard[corev1.ResourceLimitsMemory]; q.Value() != 512*1024*1024 {
+		t.Errorf("wrong memory limit %v", q.String())
+	} else if q := hard[corev1.ResourceLimitsCPU]; q.MilliValue() != 1500 {
+		t.Errorf("wrong cpu limit %v", q.String())
+	} else if q := hard[corev1.ResourcePods]; q.Value() != 4 {
+		t.Errorf("wrong pods limit %v", q.String())
+	} else if quota.Namespace != "hzn-ag1" {
+		t.Errorf("wrong quota namespace %v", quota.Namespace)
+	}
+
+	if len(limits.Spec.Limits) != 1 {
+		t.Fatalf("expected 1 limit range item, got %v", limits.Spec.Limits)
+	}
+	item := limits.Spec.Limits[0]
+	if q := item.Default[corev1.ResourceMemory]; q.Value() != 128*1024*1024 {
+		t.Errorf("wrong default memory %v", q.String())
+	} else if q := item.Default[corev1.ResourceCPU]; q.MilliValue() != 250 {
+		t.Errorf("wrong default cpu %v", q.String())
+	} else if q := item.Max[corev1.ResourceCPU]; q.MilliValue() != 1500 {
+		t.Errorf("wrong max cpu %v", q.String())
+	}
+
+	// The container values are required with the limits of the namespace, they are never the whole quota.
+	if err := (&persistence.KubeResourceRequirements{MaxMemoryMb: 512}).Validate(); err == nil {
+		t.Errorf("expected an error without the container memory")
+	} else if err := (&persistence.KubeResourceRequirements{MaxCPUs: 1, ContainerCPUs: 2}).Validate(); err == nil {
+		t.Errorf("expected an error with container CPUs larger than the namespace CPUs")
+	} else if err := resources.Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// Only the pods are limited, the containers do not get defaults.
+	_, quota, limits = agreementNamespaceObjects("hzn-ag1", "ag1", &persistence.KubeResourceRequirements{MaxPods: 2})
+	if quota == nil || len(quota.Spec.Hard) != 1 {
+		t.Errorf("expected a quota of pods, got %v", quota)
+	} else if limits != nil {
+		t.Errorf("expected no limit range, got %v", limits)
+	}
+
+}
+
+func Test_moveToNamespace(t *testing.T) {
+
+	k8sObjs, customResources, err := getK8sObjectFromYaml([]YamlFile{{Body: testObjects}, {Body: testCRD}, {Body: testCR}}, nil)
+	if err != nil {
+		t.Fatalf("error decoding the objects: %v", err)
+	}
+	cr, err := unstructuredObjectFromYaml(customResources[0])
+	if err != nil {
+		t.Fatalf("error decoding the custom resource: %v", err)
+	}
+	cr.SetNamespace("myns")
+
+	movedObjs := moveToNamespace(k8sObjs, []*unstructured.Unstructured{cr}, "hzn-ag1")
+	if len(movedObjs) != len(k8sObjs)-1 {
+		t.Errorf("expected the namespace object to be left out, got %v objects", len(movedObjs))
+	} else if cr.GetNamespace() != "" {
+		t.Errorf("expected the custom resource namespace to be cleared, got %v", cr.GetNamespace())
+	}
+
+	// The cluster scoped objects get names of their own, and the binding refers to the renamed role.
+	for _, obj := range movedObjs {
+		if binding, ok := obj.Object.(*rbacv1.ClusterRoleBinding); ok {
+			if binding.Subjects[0].Namespace != "hzn-ag1" {
+				t.Errorf("expected the service account to be in the new namespace, got %v", binding.Subjects[0])
+			} else if binding.Name != "mycrb-hzn-ag1" || binding.RoleRef.Name != "mycr-hzn-ag1" {
+				t.Errorf("expected the cluster role binding and its role to be renamed, got %v and %v", binding.Name, binding.RoleRef.Name)
+			}
+		} else if role, ok := obj.Object.(*rbacv1.ClusterRole); ok && role.Name != "mycr-hzn-ag1" {
+			t.Errorf("expected the cluster role to be renamed, got %v", role.Name)
+		}
+	}
+
+	objMap, namespace, err := sortAPIObjects(movedObjs, cr, map[string]string{}, "ag1", 0)
+	if err != nil {
+		t.Fatalf("error sorting the objects: %v", err)
+	} else if namespace != ANAX_NAMESPACE {
+		t.Errorf("expected the objects to have no namespace, got %v", namespace)
+	} else if len(objMap[K8S_NAMESPACE_TYPE]) != 0 {
+		t.Errorf("expected no namespace objects, got %v", objMap[K8S_NAMESPACE_TYPE])
+	}
+
+	// The custom resource definitions are shared with the operators of the other agreements.
+	shareDefinitions(objMap)
+	for _, obj := range objMap[K8S_CRD_TYPE] {
+		if typedCR, ok := obj.(CustomResourceV1); !ok || !typedCR.Shared {
+			t.Errorf("expected a shared custom resource definition, got %v", obj)
+		}
+	}
+
+}
@@ -0,0 +1,123 @@
+package kube_operator
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"reflect"
+)
+
+// The changes that turn the objects of one operator deployment into the objects of another
+type upgradePlan struct {
+	apply  []APIObjectInterface // the objects that are new or changed, in install order
+	remove []APIObjectInterface // the objects that are no longer in the deployment, in uninstall order
+}
+
+// Upgrade replaces the objects of the old operator deployment with those of the new one in place, without uninstalling
+// the operator. The objects that changed are updated, the new objects are installed and the objects that are no longer
+// in the deployment are deleted. The custom resource of the operator is updated, not recreated, so the operator keeps
+// the state that it holds in the resource. When the upgrade fails, the objects of the old deployment are restored.
+func (c KubeClient) Upgrade(oldTar string, oldAgId string, tar string, envVars map[string]string, agId string, crInstallTimeout int64) error {
+	oldObjMap, oldNamespace, err := processDeployment(oldTar, map[string]string{}, oldAgId, "", crInstallTimeout)
+	if err != nil {
+		return err
+	}
+	apiObjMap, namespace, err := processDeployment(tar, envVars, agId, "", crInstallTimeout)
+	if err != nil {
+		return err
+	}
+	if namespace != oldNamespace {
+		return fmt.Errorf(kwlog(fmt.Sprintf("Error: unable to upgrade the operator in namespace %s into namespace %s", oldNamespace, namespace)))
+	}
+
+	glog.V(3).Infof(kwlog(fmt.Sprintf("upgrading operator objects of agreement %s for agreement %s", oldAgId, agId)))
+	if err := c.applyUpgrade(planUpgrade(oldObjMap, apiObjMap), namespace); err != nil {
+		glog.Errorf(kwlog(fmt.Sprintf("rolling back the operator objects to agreement %s: %v", oldAgId, err)))
+		if rbErr := c.applyUpgrade(planUpgrade(apiObjMap, oldObjMap), namespace); rbErr != nil {
+			glog.Errorf(kwlog(fmt.Sprintf("unable to roll back the operator objects to agreement %s: %v", oldAgId, rbErr)))
+		}
+		if agId != oldAgId {
+			c.deleteEnvConfigMap(agId, namespace)
+		}
+		return err
+	}
+
+	// The operator deployment now uses the config map of the new agreement
+	if agId != oldAgId {
+		c.deleteEnvConfigMap(oldAgId, namespace)
+	}
+
+	glog.V(3).Infof(kwlog(fmt.Sprintf("all operator objects upgraded")))
+	return nil
+}
+
+// Apply the changes of an upgrade plan to the cluster. The objects are removed after all the others were updated, so
+// that a failed upgrade can be rolled back without losing any objects.
+func (c KubeClient) applyUpgrade(plan upgradePlan, namespace string) error {
+	for _, obj := range plan.apply {
+		if err := obj.Update(c, namespace); err != nil {
+			return err
+		}
+	}
+	for _, obj := range plan.remove {
+		obj.Uninstall(c, namespace)
+	}
+	return nil
+}
+
+// Returns the changes that turn the old objects into the new ones. Objects are matched by kind and name, an object in
+// both that is not the same is changed. The namespace is never removed, it can hold objects that are not part of the
+// deployment.
+func planUpgrade(oldObjMap map[string][]APIObjectInterface, newObjMap map[string][]APIObjectInterface) upgradePlan {
+	plan := upgradePlan{apply: []APIObjectInterface{}, remove: []APIObjectInterface{}}
+
+	oldObjs := map[string]APIObjectInterface{}
+	newObjs := map[string]APIObjectInterface{}
+	for kind, objs := range oldObjMap {
+		for _, obj := range objs {
+			oldObjs[objectKey(kind, obj)] = obj
+		}
+	}
+	for kind, objs := range newObjMap {
+		for _, obj := range objs {
+			newObjs[objectKey(kind, obj)] = obj
+		}
+	}
+
+	for _, kind := range append([]string{K8S_NAMESPACE_TYPE}, installOrder...) {
+		for _, obj := range newObjMap[kind] {
+			if oldObj, ok := oldObjs[objectKey(kind, obj)]; !ok || !reflect.DeepEqual(oldObj, obj) {
+				plan.apply = append(plan.apply, obj)
+			}
+		}
+	}
+	for i := len(installOrder) - 1; i >= 0; i-- {
+		for _, obj := range oldObjMap[installOrder[i]] {
+			if _, ok := newObjs[objectKey(installOrder[i], obj)]; !ok {
+				plan.remove = append(plan.remove, obj)
+			}
+		}
+	}
+	return plan
+}
+
+// The key of an object in its deployment. The unstructured objects can be of any kind.
+func objectKey(kind string, obj APIObjectInterface) string {
+	if unstructObj, ok := obj.(UnstructuredObject); ok {
+		kind = unstructObj.Object.GroupVersionKind().GroupKind().String()
+	}
+	return fmt.Sprintf("%s/%s", kind, obj.Name())
+}
+
+// Returns the name that identifies the operator of a deployment in the cluster, the org and URL of its service and the
+// namespace and name of its deployment object. The deployments of different versions of a service that have the same
+// operator name are upgraded in place. When a namespace is given, it is used instead of the namespace of the deployment.
+func OperatorName(tar string, namespace string, serviceOrg string, serviceURL string) (string, error) {
+	apiObjMap, namespace, err := processDeployment(tar, map[string]string{}, "", namespace, 0)
+	if err != nil {
+		return "", err
+	}
+	if len(apiObjMap[K8S_DEPLOYMENT_TYPE]) < 1 {
+		return "", fmt.Errorf(kwlog(fmt.Sprintf("Error: failed to find operator deployment object.")))
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", serviceOrg, serviceURL, namespace, apiObjMap[K8S_DEPLOYMENT_TYPE][0].Name()), nil
+}
@@ -0,0 +1,73 @@
+//go:build unit
+// +build unit
+
+package kube_operator
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"testing"
+)
+
+func Test_planUpgrade(t *testing.T) {
+
+	configMap := func(name string, value string) APIObjectInterface {
+		return ConfigMapCoreV1{ConfigMapObject: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}, Data: map[string]string{"key": value}}}
+	}
+	deployment := func(agId string) APIObjectInterface {
+		return DeploymentAppsV1{DeploymentObject: &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "myop"}}, AgreementId: agId}
+	}
+	job := func(kind string) APIObjectInterface {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetAPIVersion("batch/v1")
+		obj.SetKind(kind)
+		obj.SetName("myjob")
+		return UnstructuredObject{Object: obj}
+	}
+
+	oldObjMap := map[string][]APIObjectInterface{
+		K8S_CONFIGMAP_TYPE:    {configMap("same", "v1"), configMap("changed", "v1"), configMap("removed", "v1")},
+		K8S_DEPLOYMENT_TYPE:   {deployment("ag1")},
+		K8S_UNSTRUCTURED_TYPE: {job("Job")},
+	}
+	newObjMap := map[string][]APIObjectInterface{
+		K8S_CONFIGMAP_TYPE:    {configMap("same", "v1"), configMap("changed", "v2"), configMap("added", "v2")},
+		K8S_DEPLOYMENT_TYPE:   {deployment("ag2")},
+		K8S_UNSTRUCTURED_TYPE: {job("CronJob")},
+	}
+
+	plan := planUpgrade(oldObjMap, newObjMap)
+
+	applied := []string{}
+	for _, obj := range plan.apply {
+		applied = append(applied, obj.Name())
+	}
+	if expected := []string{"changed", "added", "myop", "myjob"}; len(applied) != len(expected) {
+		t.Errorf("expected applied objects %v, got %v", expected, applied)
+	} else {
+		for i := range expected {
+			if applied[i] != expected[i] {
+				t.Errorf("expected applied objects %v, got %v", expected, applied)
+				break
+			}
+		}
+	}
+
+	// The job is removed because it is of another kind than the cron job of the same name, the removed objects are in
+	// uninstall order.
+	if len(plan.remove) != 2 {
+		t.Errorf("expected 2 removed objects, got %v", plan.remove)
+	} else if u, ok := plan.remove[0].(UnstructuredObject); !ok || u.Object.GetKind() != "Job" {
+		t.Errorf("expected the job to be removed first, got %v", plan.remove[0])
+	} else if plan.remove[1].Name() != "removed" {
+		t.Errorf("expected the removed config map to be removed, got %v", plan.remove[1])
+	}
+
+	// Nothing changes between the same objects.
+	if plan := planUpgrade(newObjMap, newObjMap); len(plan.apply) != 0 || len(plan.remove) != 0 {
+		t.Errorf("expected no changes, got %v", plan)
+	}
+
+}
@@ -11,6 +11,7 @@ import (
 	_ "github.com/open-horizon/anax/agreementbot/persistence/bolt"
 	_ "github.com/open-horizon/anax/agreementbot/persistence/postgresql"
 	agbotSecretsImpl "github.com/open-horizon/anax/agreementbot/secrets"
+	_ "github.com/open-horizon/anax/agreementbot/secrets/filestore"
 	_ "github.com/open-horizon/anax/agreementbot/secrets/vault"
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/changes"
@@ -19,8 +20,10 @@ import (
 	"github.com/open-horizon/anax/container"
 	"github.com/open-horizon/anax/download"
 	"github.com/open-horizon/anax/exchange"
+	_ "github.com/open-horizon/anax/externalpolicy/json_language"
 	_ "github.com/open-horizon/anax/externalpolicy/text_language"
 	"github.com/open-horizon/anax/governance"
+	"github.com/open-horizon/anax/helm"
 	"github.com/open-horizon/anax/i18n"
 	_ "github.com/open-horizon/anax/i18n_messages"
 	"github.com/open-horizon/anax/imagefetch"
@@ -29,6 +32,7 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/resource"
+	"github.com/open-horizon/anax/structlog"
 	"github.com/open-horizon/anax/worker"
 	"os"
 	"os/signal"
@@ -47,9 +51,16 @@ import (
 func main() {
 	configFile := flag.String("config", "/etc/colonus/anax.config", "Config file location")
 	cpuprofile := flag.String("cpuprofile", "", "write cpu profile to file")
+	logFormat := flag.String("logformat", structlog.FORMAT_TEXT, "format of the structured worker logs, text or json")
 
 	flag.Parse()
 
+	if err := structlog.SetFormat(*logFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -logformat flag: %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
 		if err != nil {
@@ -190,6 +201,7 @@ func main() {
 			workers.Add(imageWorker)
 		}
 		workers.Add(kube_operator.NewKubeWorker("Kube", cfg, db))
+		workers.Add(helm.NewHelmWorker("Helm", cfg, db))
 		workers.Add(resource.NewResourceWorker("Resource", cfg, db, authm))
 		workers.Add(changes.NewChangesWorker("ExchangeChanges", cfg, db))
 		workers.Add(nodemanagement.NewNodeManagementWorker("NodeManagement", cfg, db))
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"net/http"
+	"sync"
+)
+
+// This package holds the prometheus registry of the counters and gauges exported by the agent and the agbot on their
+// /metrics API, so that fleets of agents and agbots can be monitored without transforming the status JSON.
+//
+// Metrics that are updated as things happen (e.g. exchange calls) are incremented by the code that does the work.
+// Metrics that reflect the current state of something (e.g. agreements by state) are computed at scrape time
+// by collector functions registered with the registry.
+
+// A collector is called just before the metrics are gathered so that it can refresh gauges from current state.
+type Collector func()
+
+// The registry holds all the metrics and collectors of a process.
+type Registry struct {
+	*prometheus.Registry
+	lock       sync.Mutex
+	collectors map[string]Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		Registry:   prometheus.NewRegistry(),
+		collectors: make(map[string]Collector),
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Returns the registry used by the agent or agbot process.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register a collector under the given name. Registering another collector with the same name replaces the old one.
+func (r *Registry) RegisterCollector(name string, c Collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.collectors[name] = c
+}
+
+func (r *Registry) UnregisterCollector(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.collectors, name)
+}
+
+// A counter only goes up, e.g. the number of exchange calls that failed.
+func (r *Registry) NewCounterVec(name string, help string, labelNames ...string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labelNames)
+	r.MustRegister(c)
+	return c
+}
+
+// A gauge can go up and down, e.g. the number of agreements in a given state. Collectors reset a gauge before setting
+// the current values so that label combinations which no longer exist are not reported with a stale value.
+func (r *Registry) NewGaugeVec(name string, help string, labelNames ...string) *prometheus.GaugeVec {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labelNames)
+	r.MustRegister(g)
+	return g
+}
+
+// A summary tracks the count and sum of observations, e.g. the latency of exchange calls in seconds.
+func (r *Registry) NewSummaryVec(name string, help string, labelNames ...string) *prometheus.SummaryVec {
+	s := prometheus.NewSummaryVec(prometheus.SummaryOpts{Name: name, Help: help}, labelNames)
+	r.MustRegister(s)
+	return s
+}
+
+// Run all the collectors and then gather the metrics from the prometheus registry.
+func (r *Registry) Gather() ([]*dto.MetricFamily, error) {
+
+	r.lock.Lock()
+	collectors := make([]Collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.lock.Unlock()
+
+	for _, c := range collectors {
+		c()
+	}
+
+	return r.Registry.Gather()
+}
+
+// Returns the handler that serves the metrics in the format negotiated with the scraper.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r, promhttp.HandlerOpts{})
+}
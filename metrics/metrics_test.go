@@ -0,0 +1,83 @@
+//go:build unit
+// +build unit
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Returns the metrics served by the handler of the registry in the text format.
+func scrape(t *testing.T, r *Registry) string {
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Errorf("unexpected error reading metrics: %v", err)
+	}
+	return string(body)
+}
+
+func Test_Handler_counter_gauge_summary(t *testing.T) {
+
+	r := NewRegistry()
+	c := r.NewCounterVec("test_calls_total", "Number of calls.", "method")
+	g := r.NewGaugeVec("test_agreements", "Agreements by state.", "state")
+	s := r.NewSummaryVec("test_latency_seconds", "Latency.")
+
+	c.WithLabelValues("GET").Inc()
+	c.WithLabelValues("GET").Inc()
+	c.WithLabelValues("PUT").Add(3)
+	g.WithLabelValues("active").Set(4)
+	g.WithLabelValues("archived").Set(1)
+	s.WithLabelValues().Observe(0.5)
+	s.WithLabelValues().Observe(1.5)
+
+	expected := []string{
+		"# HELP test_calls_total Number of calls.",
+		"# TYPE test_calls_total counter",
+		`test_calls_total{method="GET"} 2`,
+		`test_calls_total{method="PUT"} 3`,
+		"# TYPE test_agreements gauge",
+		`test_agreements{state="active"} 4`,
+		`test_agreements{state="archived"} 1`,
+		"# TYPE test_latency_seconds summary",
+		"test_latency_seconds_sum 2",
+		"test_latency_seconds_count 2",
+	}
+	out := scrape(t, r)
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("output should contain %v, but is:\n%v", e, out)
+		}
+	}
+}
+
+func Test_Handler_collectors(t *testing.T) {
+
+	r := NewRegistry()
+	g := r.NewGaugeVec("test_queue_depth", "Queue depth.", "queue")
+	g.WithLabelValues("stale").Set(10)
+
+	depth := 0
+	r.RegisterCollector("queue", func() {
+		depth += 1
+		g.Reset()
+		g.WithLabelValues("high").Set(float64(depth))
+	})
+
+	if out := scrape(t, r); !strings.Contains(out, `test_queue_depth{queue="high"} 1`) {
+		t.Errorf("collector was not run:\n%v", out)
+	} else if strings.Contains(out, "stale") {
+		t.Errorf("reset gauge should not contain old samples:\n%v", out)
+	}
+
+	r.UnregisterCollector("queue")
+	scrape(t, r)
+	if depth != 1 {
+		t.Errorf("collector should not run after it is unregistered, ran %v times", depth)
+	}
+}
@@ -3,6 +3,7 @@ package nodemanagement
 import (
 	"fmt"
 	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/structlog"
 )
 
 type NodeRegisteredCommand struct {
@@ -53,6 +54,10 @@ func (n NMPDownloadCompleteCommand) ShortString() string {
 	return n.String()
 }
 
+func (n NMPDownloadCompleteCommand) LogFields() structlog.Fields {
+	return structlog.FieldsOf(n.Msg)
+}
+
 func NewNMPDownloadCompleteCommand(msg *events.NMPDownloadCompleteMessage) *NMPDownloadCompleteCommand {
 	return &NMPDownloadCompleteCommand{Msg: msg}
 }
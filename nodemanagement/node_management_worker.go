@@ -22,6 +22,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 const STATUS_FILE_NAME = "status.json"
@@ -107,6 +108,14 @@ func (w *NodeManagementWorker) checkNMPTimeToRun() int {
 		return 60
 	}
 
+	// Agent upgrades restart the workloads, so they wait for the node maintenance window.
+	if nodePol, err := persistence.FindNodePolicy(w.db); err != nil {
+		glog.Errorf(nmwlog(fmt.Sprintf("Failed to get the node policy from the database. Error was %v", err)))
+	} else if !nodePol.InMaintenanceWindow(time.Now()) {
+		glog.Infof(nmwlog("The node is outside of its maintenance windows. Exiting without looking for the next nmp to run."))
+		return 60
+	}
+
 	if waitingNMPs, err := persistence.FindWaitingNMPStatuses(w.db); err != nil {
 		glog.Errorf(nmwlog(fmt.Sprintf("Failed to get nmp statuses from the database. Error was %v", err)))
 	} else {
@@ -197,10 +206,10 @@ func (n *NodeManagementWorker) HandleRegistration() {
 func (n *NodeManagementWorker) DownloadComplete(cmd *NMPDownloadCompleteCommand) {
 	status, err := persistence.FindNMPStatus(n.db, cmd.Msg.NMPName)
 	if err != nil {
-		glog.Errorf(nmwlog(fmt.Sprintf("Failed to get nmp status %v from the database: %v", cmd.Msg.NMPName, err)))
+		n.Logger(cmd).Errorf("Failed to get nmp status %v from the database: %v", cmd.Msg.NMPName, err)
 		return
 	} else if status == nil {
-		glog.Errorf(nmwlog(fmt.Sprintf("Failed to find status for nmp %v in the database.", cmd.Msg.NMPName)))
+		n.Logger(cmd).Errorf("Failed to find status for nmp %v in the database.", cmd.Msg.NMPName)
 		return
 	}
 	var msgMeta *persistence.MessageMeta
@@ -776,7 +785,7 @@ func (w *NodeManagementWorker) HandleNmpStatusReset() {
 	// get all the nmps that applies to this node from the exchange
 	allNmpStatus, err := exchange.GetNodeManagementAllStatuses(w, exchange.GetOrg(w.GetExchangeId()), exchange.GetId(w.GetExchangeId()))
 	if err != nil {
-		glog.Errorf(nmwlog(fmt.Sprintf("Error getting all nmp statuses for node from the exchange. %v", w.GetExchangeId(), err)))
+		glog.Errorf(nmwlog(fmt.Sprintf("Error getting all nmp statuses for node %v from the exchange. %v", w.GetExchangeId(), err)))
 	} else {
 		glog.V(5).Infof(nmwlog(fmt.Sprintf("GetNodeManagementAllStatuses returns: %v", allNmpStatus)))
 	}
@@ -0,0 +1,88 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/containermessage"
+)
+
+// The bucket name in the bolt DB. The admission policy is local to the node, it is not synchronized with the exchange.
+const NODE_ADMISSION_POLICY = "nodeadmissionpolicy"
+
+// Retrieve the admission policy object from the database. The bolt APIs assume there is more than 1 object in a bucket,
+// so this function has to be prepared for that case, even though there should only ever be 1.
+func FindNodeAdmissionPolicy(db *bolt.DB) (*containermessage.AdmissionPolicy, error) {
+
+	policy := make([]containermessage.AdmissionPolicy, 0)
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(NODE_ADMISSION_POLICY)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				var pol containermessage.AdmissionPolicy
+
+				if err := json.Unmarshal(v, &pol); err != nil {
+					return fmt.Errorf("Unable to deserialize admission policy record: %v", v)
+				}
+
+				policy = append(policy, pol)
+				return nil
+			})
+		}
+
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	if len(policy) > 1 {
+		return nil, fmt.Errorf("Unsupported db state: more than one admission policy stored in bucket. Policies: %v", policy)
+	} else if len(policy) == 1 {
+		return &policy[0], nil
+	} else {
+		return nil, nil
+	}
+}
+
+// There is only 1 object in the bucket so we can use the bucket name as the object key.
+func SaveNodeAdmissionPolicy(db *bolt.DB, admissionPolicy *containermessage.AdmissionPolicy) error {
+
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(NODE_ADMISSION_POLICY))
+		if err != nil {
+			return err
+		}
+
+		if serial, err := json.Marshal(admissionPolicy); err != nil {
+			return fmt.Errorf("Failed to serialize admission policy: %v. Error: %v", admissionPolicy, err)
+		} else {
+			return b.Put([]byte(NODE_ADMISSION_POLICY), serial)
+		}
+	})
+
+	return writeErr
+}
+
+// Remove the admission policy object from the local database.
+func DeleteNodeAdmissionPolicy(db *bolt.DB) error {
+
+	if pol, err := FindNodeAdmissionPolicy(db); err != nil {
+		return err
+	} else if pol == nil {
+		return nil
+	} else {
+
+		return db.Update(func(tx *bolt.Tx) error {
+
+			if b, err := tx.CreateBucketIfNotExists([]byte(NODE_ADMISSION_POLICY)); err != nil {
+				return err
+			} else if err := b.Delete([]byte(NODE_ADMISSION_POLICY)); err != nil {
+				return fmt.Errorf("Unable to delete admission policy object: %v", err)
+			} else {
+				return nil
+			}
+		})
+	}
+}
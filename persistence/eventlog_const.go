@@ -87,6 +87,8 @@ const (
 	EC_REJECT_PROPOSAL           = "reject_proposal"
 	EC_ERROR_IN_PROPOSAL         = "error_in_proposal"
 	EC_ERROR_PROCESSING_PROPOSAL = "error_processing_proposal"
+	EC_ADMISSION_POLICY_REJECT   = "admission_policy_reject"
+	EC_NODE_CAPACITY_REJECT      = "node_capacity_reject"
 
 	EC_RECEIVED_REPLYACK_MESSAGE         = "received_replyack_message"
 	EC_IGNORE_REPLYACK_MESSAGE           = "ignore_replyack_message"
@@ -115,6 +117,16 @@ const (
 	EC_ERROR_NODE_USERINPUT_UPDATE = "error_userinput_update"
 	EC_ERROR_NODE_USERINPUT_PATCH  = "error_userinput_patch"
 
+	EC_NODE_ADMISSION_POLICY_UPDATED      = "update_node_admission_policy"
+	EC_NODE_ADMISSION_POLICY_DELETED      = "delete_node_admission_policy"
+	EC_ERROR_NODE_ADMISSION_POLICY_UPDATE = "error_admission_policy_update"
+
+	EC_OFFLINE_BUNDLE_LOADED       = "offline_bundle_loaded"
+	EC_OFFLINE_BUNDLE_DOWNLOAD     = "offline_bundle_download"
+	EC_OFFLINE_BUNDLE_DELETED      = "offline_bundle_deleted"
+	EC_ERROR_OFFLINE_BUNDLE_LOAD   = "error_offline_bundle_load"
+	EC_ERROR_OFFLINE_BUNDLE_DELETE = "error_offline_bundle_delete"
+
 	EC_AGREEMENT_REACHED                  = "agreement_reached"
 	EC_CANCEL_AGREEMENT                   = "cancel_agreement"
 	EC_AGREEMENT_CANCELED                 = "agreement_canceled"
@@ -131,6 +143,7 @@ const (
 
 	EC_IMAGE_LOADED                       = "image_loaded"
 	EC_ERROR_IMAGE_LOADE                  = "error_image_load"
+	EC_ERROR_IMAGE_SIGNATURE              = "error_image_signature_verification"
 	EC_ERROR_AGREEMENT_VERIFICATION       = "error_in_agreement_verification"
 	EC_ERROR_DELETE_AGREEMENT_IN_EXCHANGE = "error_delete_agreement_in_exchange"
 
@@ -162,6 +175,7 @@ const (
 	EC_START_UPGRADE_SERVICE    = "start_rollback_service"
 	EC_COMPLETE_UPGRADE_SERVICE = "complete_rollback_service"
 	EC_ERROR_UPGRADE_SERVICE    = "error_rollback_service"
+	EC_DEFER_UPGRADE_SERVICE    = "defer_upgrade_service"
 
 	EC_START_CLEANUP_SERVICE    = "start_cleanup_service"
 	EC_COMPLETE_CLEANUP_SERVICE = "complete_cleanup_service"
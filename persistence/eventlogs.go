@@ -8,8 +8,10 @@ import (
 	"github.com/open-horizon/anax/i18n"
 	"golang.org/x/text/message"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -249,6 +251,10 @@ func SaveEventLog(db *bolt.DB, event_log *EventLog) error {
 		}
 	})
 
+	if writeErr == nil {
+		publishEventLog(*event_log)
+	}
+
 	NewErrorLog(db, *event_log)
 	return writeErr
 }
@@ -717,3 +723,129 @@ func GetEventLogObject(db *bolt.DB, msgPrinter *message.Printer, recordID string
 	}
 	return logs[0]
 }
+
+// The subscribers that receive the event logs as they are saved, used to stream the event logs to API clients.
+var eventLogSubscribersLock sync.Mutex
+var eventLogSubscribers = make(map[*EventLogSubscription]bool)
+
+// A subscription to the event logs saved after it was created. A subscriber that does not keep up with the
+// event logs misses the records that do not fit in its channel, the number of missed records is counted.
+type EventLogSubscription struct {
+	C       chan EventLog
+	dropped uint64
+}
+
+func SubscribeEventLogs(bufferSize int) *EventLogSubscription {
+	sub := &EventLogSubscription{
+		C: make(chan EventLog, bufferSize),
+	}
+
+	eventLogSubscribersLock.Lock()
+	defer eventLogSubscribersLock.Unlock()
+	eventLogSubscribers[sub] = true
+	return sub
+}
+
+// Stop receiving event logs. The channel is not closed so that a concurrent publisher can never send on a closed channel.
+func (s *EventLogSubscription) Close() {
+	eventLogSubscribersLock.Lock()
+	defer eventLogSubscribersLock.Unlock()
+	delete(eventLogSubscribers, s)
+}
+
+// Returns the number of event logs that were not delivered to the subscriber because its channel was full.
+func (s *EventLogSubscription) Dropped() uint64 {
+	eventLogSubscribersLock.Lock()
+	defer eventLogSubscribersLock.Unlock()
+	return s.dropped
+}
+
+// Deliver a saved event log to all the subscribers without blocking the caller.
+func publishEventLog(event_log EventLog) {
+	eventLogSubscribersLock.Lock()
+	defer eventLogSubscribersLock.Unlock()
+
+	for sub := range eventLogSubscribers {
+		select {
+		case sub.C <- event_log:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+// Remove the event logs that are older than maxAgeS seconds and the oldest event logs beyond maxCount records.
+// A zero maxAgeS or maxCount disables that limit. The event logs of the errors that are surfaced to the exchange are
+// never removed, the surfaced errors are read from them. Returns the number of records removed.
+func PruneEventLogs(db *bolt.DB, maxAgeS int64, maxCount int) (int, error) {
+
+	if maxAgeS <= 0 && maxCount <= 0 {
+		return 0, nil
+	}
+
+	surfaced := make(map[string]bool)
+	if surfaceErrors, err := FindSurfaceErrors(db); err != nil {
+		return 0, fmt.Errorf("Unable to read the surfaced errors. Error: %v", err)
+	} else {
+		for _, se := range surfaceErrors {
+			surfaced[se.Record_id] = true
+		}
+	}
+
+	type elKey struct {
+		seq       uint64
+		key       []byte
+		timestamp uint64
+	}
+
+	cutoff := uint64(0)
+	if maxAgeS > 0 && time.Now().Unix() > maxAgeS {
+		cutoff = uint64(time.Now().Unix() - maxAgeS)
+	}
+
+	removed := 0
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+
+		b := tx.Bucket([]byte(EVENT_LOGS))
+		if b == nil {
+			return nil
+		}
+
+		// The keys are sequence numbers in string form, so they do not sort in the order the records were written.
+		keys := make([]elKey, 0)
+		b.ForEach(func(k, v []byte) error {
+			var base EventLogBase
+			if seq, err := strconv.ParseUint(string(k), 10, 64); err != nil {
+				glog.Errorf("Unable to convert event log db key %v to a sequence number. Error: %v", string(k), err)
+			} else if err := json.Unmarshal(v, &base); err != nil {
+				glog.Errorf("Unable to deserialize event log db record: %v. Error: %v", v, err)
+			} else {
+				keys = append(keys, elKey{seq: seq, key: append([]byte{}, k...), timestamp: base.Timestamp})
+			}
+			return nil
+		})
+		sort.Slice(keys, func(i, j int) bool { return keys[i].seq < keys[j].seq })
+
+		// The records that exceed the count are the oldest ones, the others are removed only if they are too old.
+		excess := 0
+		if maxCount > 0 && len(keys) > maxCount {
+			excess = len(keys) - maxCount
+		}
+		for i, k := range keys {
+			if surfaced[string(k.key)] {
+				continue
+			} else if i < excess || k.timestamp < cutoff {
+				if err := b.Delete(k.key); err != nil {
+					return fmt.Errorf("Unable to delete event log %v. Error: %v", string(k.key), err)
+				}
+				removed++
+			}
+		}
+		return nil
+	})
+
+	if writeErr != nil {
+		return 0, writeErr
+	}
+	return removed, nil
+}
@@ -4,6 +4,7 @@
 package persistence
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"strings"
 	"testing"
@@ -476,3 +477,110 @@ func Test_SaveLastUnregistrationTime(t *testing.T) {
 	assert.False(t, e8.Matches(selectors), "Test eventlog Matches.")
 
 }
+
+func Test_PruneEventLogs(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	source := NewNodeEventSource("mynode", "myorg", "", "configured")
+
+	// 12 event logs so that the keys do not sort in the order they were saved, the first 2 are a day old.
+	for i := 0; i < 12; i++ {
+		el := newEventLog1(SEVERITY_INFO, fmt.Sprintf("message %v", i), nil, EC_START_NODE_CONFIG_REG, SRC_TYPE_NODE, *source)
+		if i < 2 {
+			el.Timestamp = uint64(time.Now().Unix() - 86400)
+		}
+		if err := SaveEventLog(db, el); err != nil {
+			t.Errorf("Erorr saving eventlog into db. %v", err)
+		}
+	}
+
+	// no limits
+	removed, err := PruneEventLogs(db, 0, 0)
+	assert.Nil(t, err, "pruning without limits should not fail")
+	assert.Equal(t, 0, removed, "nothing should be pruned without limits")
+
+	// by age
+	removed, err = PruneEventLogs(db, 3600, 0)
+	assert.Nil(t, err, "pruning by age should not fail")
+	assert.Equal(t, 2, removed, "the old event logs should be pruned")
+
+	// by count, the oldest are removed
+	removed, err = PruneEventLogs(db, 3600, 4)
+	assert.Nil(t, err, "pruning by count should not fail")
+	assert.Equal(t, 6, removed, "the event logs beyond the count should be pruned")
+
+	els, err := FindAllEventLogs(db)
+	assert.Nil(t, err, "finding the event logs should not fail")
+	assert.Equal(t, 4, len(els), "4 event logs should be left")
+	for _, el := range els {
+		assert.Contains(t, []string{"9", "10", "11", "12"}, el.Id, "only the most recent event logs should be left")
+	}
+}
+
+func Test_PruneEventLogs_surfaceErrors(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	source := NewNodeEventSource("mynode", "myorg", "", "configured")
+
+	// 4 event logs that are a day old.
+	for i := 0; i < 4; i++ {
+		el := newEventLog1(SEVERITY_ERROR, fmt.Sprintf("message %v", i), nil, EC_START_NODE_CONFIG_REG, SRC_TYPE_NODE, *source)
+		el.Timestamp = uint64(time.Now().Unix() - 86400)
+		if err := SaveEventLog(db, el); err != nil {
+			t.Errorf("Erorr saving eventlog into db. %v", err)
+		}
+	}
+
+	// The error of the second event log is surfaced to the exchange.
+	if err := SaveSurfaceErrors(db, []SurfaceError{{Record_id: "2", Message: "message 1"}}); err != nil {
+		t.Errorf("Error saving surface errors into db. %v", err)
+	}
+
+	removed, err := PruneEventLogs(db, 3600, 1)
+	assert.Nil(t, err, "pruning should not fail")
+	assert.Equal(t, 3, removed, "the event logs that are not surfaced should be pruned")
+
+	els, err := FindAllEventLogs(db)
+	assert.Nil(t, err, "finding the event logs should not fail")
+	if assert.Equal(t, 1, len(els), "1 event log should be left") {
+		assert.Equal(t, "2", els[0].Id, "the surfaced event log should be left")
+	}
+}
+
+func Test_SubscribeEventLogs(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	sub := SubscribeEventLogs(1)
+
+	source := NewNodeEventSource("mynode", "myorg", "", "configured")
+	for i := 0; i < 3; i++ {
+		if err := SaveEventLog(db, newEventLog1(SEVERITY_INFO, fmt.Sprintf("message %v", i), nil, EC_START_NODE_CONFIG_REG, SRC_TYPE_NODE, *source)); err != nil {
+			t.Errorf("Erorr saving eventlog into db. %v", err)
+		}
+	}
+
+	el := <-sub.C
+	assert.Equal(t, "1", el.Id, "the subscriber should get the saved event log")
+	assert.Equal(t, uint64(2), sub.Dropped(), "the event logs beyond the buffer should be dropped")
+
+	sub.Close()
+	if err := SaveEventLog(db, newEventLog1(SEVERITY_INFO, "message", nil, EC_START_NODE_CONFIG_REG, SRC_TYPE_NODE, *source)); err != nil {
+		t.Errorf("Erorr saving eventlog into db. %v", err)
+	}
+	assert.Equal(t, 0, len(sub.C), "a closed subscription should not get event logs")
+}
@@ -6,12 +6,15 @@ import (
 	"fmt"
 )
 
-// The structure of the json string in the deployment field of a service definition when the
-// service is deployed via Helm to a Kubernetes cluster.
+// The structure of the json string in the deployment or clusterDeployment field of a service definition when the
+// service is deployed via Helm to a Kubernetes cluster. The values are merged with the per-agreement values that the
+// agent generates from the user input of the service when the chart is installed.
 
 type HelmDeploymentConfig struct {
-	ChartArchive string `json:"chart_archive"` // base64 encoded binary of helm package tar file
-	ReleaseName  string `json:"release_name"`
+	ChartArchive string                 `json:"chart_archive"` // base64 encoded binary of helm package tar file
+	ReleaseName  string                 `json:"release_name"`
+	Namespace    string                 `json:"namespace,omitempty"` // the namespace to install the release into, the helm default when empty
+	Values       map[string]interface{} `json:"values,omitempty"`    // chart values that override the defaults of the chart
 }
 
 func NewHelmDeployment(chartArchive string, releaseName string) *HelmDeploymentConfig {
@@ -26,7 +29,7 @@ func (h HelmDeploymentConfig) String() string {
 	if len(h.ChartArchive) < maxArchiveLength {
 		maxArchiveLength = len(h.ChartArchive)
 	}
-	return fmt.Sprintf("Release Name %v, Package %v", h.ReleaseName, h.ChartArchive[:maxArchiveLength])
+	return fmt.Sprintf("Release Name %v, Namespace %v, Package %v", h.ReleaseName, h.Namespace, h.ChartArchive[:maxArchiveLength])
 }
 
 func IsHelm(dep map[string]interface{}) bool {
@@ -36,6 +39,12 @@ func IsHelm(dep map[string]interface{}) bool {
 	return false
 }
 
+// Returns true if the deployment string is a HelmDeployment.
+func IsHelmDeployment(depStr string) bool {
+	_, err := GetHelmDeployment(depStr)
+	return err == nil
+}
+
 // Functions that allow HelmDeploymentConfig to support the DeploymentConfig interface.
 
 func (h *HelmDeploymentConfig) IsNative() bool {
@@ -0,0 +1,116 @@
+package persistence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+)
+
+// The bucket name in the bolt DB. The helm releases are the charts that the agent installed in the cluster, keyed by
+// the release name.
+const HELM_RELEASES = "helmreleases"
+
+// A helm release installed by the agent. A release outlives the agreement that installed it when the agreement is
+// replaced by an agreement for another version of the same service, the new agreement takes the release over and
+// upgrades it in place. The uninstall of a release whose agreement ended is deferred until UninstallTime, to give the
+// new agreement a chance to take it over.
+type HelmRelease struct {
+	ReleaseName      string `json:"release_name"`
+	Namespace        string `json:"namespace,omitempty"`
+	AgreementId      string `json:"agreement_id"`      // the agreement that owns the release
+	Revision         int    `json:"revision"`          // the revision installed by the owning agreement
+	PreviousRevision int    `json:"previous_revision"` // the revision that was upgraded by the owning agreement, 0 for a new install
+	UninstallTime    uint64 `json:"uninstall_time"`    // when the release is uninstalled, 0 while it is owned by an active agreement
+}
+
+func (r HelmRelease) String() string {
+	return fmt.Sprintf("ReleaseName: %v, Namespace: %v, AgreementId: %v, Revision: %v, PreviousRevision: %v, UninstallTime: %v",
+		r.ReleaseName, r.Namespace, r.AgreementId, r.Revision, r.PreviousRevision, r.UninstallTime)
+}
+
+// Returns all the helm releases in the local database.
+func FindHelmReleases(db *bolt.DB) ([]HelmRelease, error) {
+
+	releases := make([]HelmRelease, 0)
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(HELM_RELEASES)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				var release HelmRelease
+
+				if err := json.Unmarshal(v, &release); err != nil {
+					return fmt.Errorf("Unable to deserialize helm release record: %v", v)
+				}
+
+				releases = append(releases, release)
+				return nil
+			})
+		}
+
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return releases, nil
+}
+
+// Returns the helm release with the given name, or nil if there is none.
+func FindHelmRelease(db *bolt.DB, releaseName string) (*HelmRelease, error) {
+
+	var release *HelmRelease
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(HELM_RELEASES)); b != nil {
+			if v := b.Get([]byte(releaseName)); v != nil {
+				release = new(HelmRelease)
+				if err := json.Unmarshal(v, release); err != nil {
+					return fmt.Errorf("Unable to deserialize helm release record: %v", v)
+				}
+			}
+		}
+
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return release, nil
+}
+
+func SaveHelmRelease(db *bolt.DB, release *HelmRelease) error {
+	if release == nil || release.ReleaseName == "" {
+		return errors.New("Helm release must have a release name")
+	}
+
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(HELM_RELEASES))
+		if err != nil {
+			return err
+		}
+
+		if serial, err := json.Marshal(release); err != nil {
+			return fmt.Errorf("Failed to serialize helm release: %v. Error: %v", release, err)
+		} else {
+			return b.Put([]byte(release.ReleaseName), serial)
+		}
+	})
+
+	return writeErr
+}
+
+// Remove the helm release with the given name from the local database.
+func DeleteHelmRelease(db *bolt.DB, releaseName string) error {
+
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(HELM_RELEASES)); b == nil {
+			return nil
+		} else if err := b.Delete([]byte(releaseName)); err != nil {
+			return fmt.Errorf("Unable to delete helm release %v: %v", releaseName, err)
+		}
+		return nil
+	})
+}
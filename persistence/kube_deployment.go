@@ -7,16 +7,52 @@ import (
 )
 
 type KubeDeploymentConfig struct {
-	OperatorYamlArchive string `json:"operatorYamlArchive"`
+	OperatorYamlArchive string                    `json:"operatorYamlArchive"`
+	IsolatedNamespace   bool                      `json:"isolatedNamespace,omitempty"` // install the operator of each agreement into a namespace of its own
+	Resources           *KubeResourceRequirements `json:"resources,omitempty"`         // the resources of the namespace of an agreement
 }
 
 func (k *KubeDeploymentConfig) ToString() string {
 	if k != nil {
-		return fmt.Sprintf("OperatorYamlArchive: %v", cutil.TruncateDisplayString(k.OperatorYamlArchive, 20))
+		return fmt.Sprintf("OperatorYamlArchive: %v, IsolatedNamespace: %v, Resources: %v", cutil.TruncateDisplayString(k.OperatorYamlArchive, 20), k.IsolatedNamespace, k.Resources)
 	}
 	return ""
 }
 
+// The resource requirements of a cluster service. The namespace that the agent creates for an agreement gets a resource
+// quota and a limit range from them. A value that is not set is not limited. When the memory or the CPUs of the
+// namespace are limited, the containers must be limited too, so the container values must be set with them.
+type KubeResourceRequirements struct {
+	MaxMemoryMb       int64   `json:"maxMemoryMb,omitempty"`       // the memory of all the containers in the namespace
+	MaxCPUs           float32 `json:"maxCPUs,omitempty"`           // the CPUs of all the containers in the namespace
+	MaxPods           int64   `json:"maxPods,omitempty"`           // the number of pods in the namespace
+	ContainerMemoryMb int64   `json:"containerMemoryMb,omitempty"` // the memory of a container that does not set its own limit
+	ContainerCPUs     float32 `json:"containerCPUs,omitempty"`     // the CPUs of a container that does not set its own limit
+}
+
+func (r *KubeResourceRequirements) String() string {
+	if r == nil {
+		return "none"
+	}
+	return fmt.Sprintf("MaxMemoryMb: %v, MaxCPUs: %v, MaxPods: %v, ContainerMemoryMb: %v, ContainerCPUs: %v", r.MaxMemoryMb, r.MaxCPUs, r.MaxPods, r.ContainerMemoryMb, r.ContainerCPUs)
+}
+
+// Returns an error when the container values are missing for the limits of the namespace, or are larger than them.
+func (r *KubeResourceRequirements) Validate() error {
+	if r == nil {
+		return nil
+	} else if r.MaxMemoryMb > 0 && r.ContainerMemoryMb <= 0 {
+		return fmt.Errorf("containerMemoryMb must be set when maxMemoryMb is set")
+	} else if r.MaxMemoryMb > 0 && r.ContainerMemoryMb > r.MaxMemoryMb {
+		return fmt.Errorf("containerMemoryMb %v must not be larger than maxMemoryMb %v", r.ContainerMemoryMb, r.MaxMemoryMb)
+	} else if r.MaxCPUs > 0 && r.ContainerCPUs <= 0 {
+		return fmt.Errorf("containerCPUs must be set when maxCPUs is set")
+	} else if r.MaxCPUs > 0 && r.ContainerCPUs > r.MaxCPUs {
+		return fmt.Errorf("containerCPUs %v must not be larger than maxCPUs %v", r.ContainerCPUs, r.MaxCPUs)
+	}
+	return nil
+}
+
 func GetKubeDeployment(deployStr string) (*KubeDeploymentConfig, error) {
 	kd := new(KubeDeploymentConfig)
 	err := json.Unmarshal([]byte(deployStr), kd)
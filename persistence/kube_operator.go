@@ -0,0 +1,114 @@
+package persistence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+)
+
+// The bucket name in the bolt DB. The kube operators are the operators that the agent installed in the cluster, keyed
+// by the operator name, the service org and URL and the namespace and name of the operator deployment object.
+const KUBE_OPERATORS = "kubeoperators"
+
+// A kube operator installed by the agent. An operator outlives the agreement that installed it when the agreement is
+// replaced by an agreement for another version of the same service, the new agreement takes the operator over and
+// upgrades its objects in place. The uninstall of an operator whose agreement ended is deferred until UninstallTime,
+// to give the new agreement a chance to take it over.
+type KubeOperator struct {
+	OperatorName        string `json:"operator_name"`
+	Namespace           string `json:"namespace,omitempty"`   // the namespace that the agent created for the agreement, empty when the operator is in the namespace of its deployment
+	AgreementId         string `json:"agreement_id"`          // the agreement that owns the operator
+	OperatorYamlArchive string `json:"operator_yaml_archive"` // the operator yaml archive installed by the owning agreement
+	UninstallTime       uint64 `json:"uninstall_time"`        // when the operator is uninstalled, 0 while it is owned by an active agreement
+}
+
+func (o KubeOperator) String() string {
+	return fmt.Sprintf("OperatorName: %v, Namespace: %v, AgreementId: %v, UninstallTime: %v", o.OperatorName, o.Namespace, o.AgreementId, o.UninstallTime)
+}
+
+// Returns all the kube operators in the local database.
+func FindKubeOperators(db *bolt.DB) ([]KubeOperator, error) {
+
+	operators := make([]KubeOperator, 0)
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(KUBE_OPERATORS)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				var operator KubeOperator
+
+				if err := json.Unmarshal(v, &operator); err != nil {
+					return fmt.Errorf("Unable to deserialize kube operator record: %v", v)
+				}
+
+				operators = append(operators, operator)
+				return nil
+			})
+		}
+
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return operators, nil
+}
+
+// Returns the kube operator with the given name, or nil if there is none.
+func FindKubeOperator(db *bolt.DB, operatorName string) (*KubeOperator, error) {
+
+	var operator *KubeOperator
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(KUBE_OPERATORS)); b != nil {
+			if v := b.Get([]byte(operatorName)); v != nil {
+				operator = new(KubeOperator)
+				if err := json.Unmarshal(v, operator); err != nil {
+					return fmt.Errorf("Unable to deserialize kube operator record: %v", v)
+				}
+			}
+		}
+
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return operator, nil
+}
+
+func SaveKubeOperator(db *bolt.DB, operator *KubeOperator) error {
+	if operator == nil || operator.OperatorName == "" {
+		return errors.New("Kube operator must have an operator name")
+	}
+
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(KUBE_OPERATORS))
+		if err != nil {
+			return err
+		}
+
+		if serial, err := json.Marshal(operator); err != nil {
+			return fmt.Errorf("Failed to serialize kube operator: %v. Error: %v", operator, err)
+		} else {
+			return b.Put([]byte(operator.OperatorName), serial)
+		}
+	})
+
+	return writeErr
+}
+
+// Remove the kube operator with the given name from the local database.
+func DeleteKubeOperator(db *bolt.DB, operatorName string) error {
+
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(KUBE_OPERATORS)); b == nil {
+			return nil
+		} else if err := b.Delete([]byte(operatorName)); err != nil {
+			return fmt.Errorf("Unable to delete kube operator %v: %v", operatorName, err)
+		}
+		return nil
+	})
+}
@@ -75,6 +75,7 @@ type MicroserviceInstInterface interface {
 	IsTopLevelService() bool
 	IsAgreementLess() bool
 	GetEnvVars() map[string]string
+	GetImageDigests() map[string]string
 	GetAssociatedAgreements() []string
 	GetParentPath() [][]ServiceInstancePathElement
 	GetInstanceCreationTime() uint64
@@ -226,6 +227,7 @@ func AgreementToMicroserviceInstance(ag EstablishedAgreement) *MicroserviceInsta
 		CleanupStartTime:     ag.AgreementTerminatedTime,
 		AssociatedAgreements: []string{ag.CurrentAgreementId},
 		MicroserviceDefId:    ag.ServiceDefId,
+		ImageDigests:         ag.ImageDigests,
 		ParentPath:           [][]ServiceInstancePathElement{[]ServiceInstancePathElement{*sipe}},
 	}
 }
@@ -600,6 +600,7 @@ type MicroserviceInstance struct {
 	RetryStartTime       uint64                         `json:"retry_start_time"`
 	EnvVars              map[string]string              `json:"env_vars"`
 	TopLevelService      bool                           `json:"top_level_service"`
+	ImageDigests         map[string]string              `json:"image_digests,omitempty"` // the content digests of the images that the containers run, keyed by container name
 }
 
 func (w MicroserviceInstance) String() string {
@@ -623,11 +624,12 @@ func (w MicroserviceInstance) String() string {
 		"CurrentRetryCount: %v, "+
 		"RetryStartTime: %v, "+
 		"EnvVars: %v, "+
-		"TopLevelService: %v",
+		"TopLevelService: %v, "+
+		"ImageDigests: %v",
 		w.SpecRef, w.Org, w.Version, w.Arch, w.InstanceId, w.Archived, w.InstanceCreationTime,
 		w.ExecutionStartTime, w.ExecutionFailureCode, w.ExecutionFailureDesc,
 		w.CleanupStartTime, w.AssociatedAgreements, w.MicroserviceDefId, w.ParentPath, w.AgreementLess,
-		w.MaxRetries, w.MaxRetryDuration, w.CurrentRetryCount, w.RetryStartTime, w.EnvVars, w.TopLevelService)
+		w.MaxRetries, w.MaxRetryDuration, w.CurrentRetryCount, w.RetryStartTime, w.EnvVars, w.TopLevelService, w.ImageDigests)
 }
 
 func (w *MicroserviceInstance) ShortString() string {
@@ -709,6 +711,10 @@ func (w *MicroserviceInstance) GetEnvVars() map[string]string {
 	return w.EnvVars
 }
 
+func (w *MicroserviceInstance) GetImageDigests() map[string]string {
+	return w.ImageDigests
+}
+
 func (w *MicroserviceInstance) GetAssociatedAgreements() []string {
 	return w.AssociatedAgreements
 }
@@ -1049,6 +1055,13 @@ func UpdateMSInstanceEnvVars(db *bolt.DB, key string, env_vars map[string]string
 	})
 }
 
+func UpdateMSInstanceImageDigests(db *bolt.DB, key string, digests map[string]string) (*MicroserviceInstance, error) {
+	return microserviceInstanceStateUpdate(db, key, func(c MicroserviceInstance) *MicroserviceInstance {
+		c.ImageDigests = digests
+		return &c
+	})
+}
+
 func MicroserviceInstanceCleanupStarted(db *bolt.DB, key string) (*MicroserviceInstance, error) {
 	return microserviceInstanceStateUpdate(db, key, func(c MicroserviceInstance) *MicroserviceInstance {
 		c.CleanupStartTime = uint64(time.Now().Unix())
@@ -1199,6 +1212,7 @@ func persistUpdatedMicroserviceInstance(db *bolt.DB, key string, update *Microse
 				mod.MaxRetryDuration = update.MaxRetryDuration
 				mod.CurrentRetryCount = update.CurrentRetryCount
 				mod.EnvVars = update.EnvVars
+				mod.ImageDigests = update.ImageDigests
 
 				if len(mod.ParentPath) != len(update.ParentPath) {
 					mod.ParentPath = update.ParentPath
@@ -0,0 +1,101 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/exchangecommon"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/policy"
+)
+
+// Returns the resource capacity of the node. The memory and CPUs of the node are the built-in properties of the node
+// policy, the committed resources are the limits of the service containers of the services that are not archived or
+// cleaned up, plus the limits of the agreements whose services are not started yet. Those agreements do not have a
+// service definition, the limits are in the TsAndCs of their proposal, which only the agreement protocol handler can
+// demarshal. The pendingLimits function returns the memory and CPU limits of such an agreement.
+func FindNodeCapacity(db *bolt.DB, pendingLimits func(ag EstablishedAgreement) (int64, float64)) (*exchangecommon.NodeCapacity, error) {
+
+	capacity := new(exchangecommon.NodeCapacity)
+
+	// The top level of the node policy has the built-in properties.
+	var topPol *externalpolicy.ExternalPolicy
+	if nodePol, err := FindNodePolicy(db); err != nil {
+		return nil, fmt.Errorf("Unable to read the node policy, error %v", err)
+	} else if nodePol != nil {
+		topPol = &nodePol.ExternalPolicy
+	}
+	if topPol == nil || !topPol.Properties.HasProperty(externalpolicy.PROP_NODE_MEMORY) || !topPol.Properties.HasProperty(externalpolicy.PROP_NODE_CPU) {
+		topPol, _ = externalpolicy.CreateNodeBuiltInPolicy(false, true, topPol, false)
+	}
+	if prop, err := topPol.Properties.GetProperty(externalpolicy.PROP_NODE_MEMORY); err == nil {
+		capacity.MemoryMb = int64(propertyFloat(prop))
+	}
+	if prop, err := topPol.Properties.GetProperty(externalpolicy.PROP_NODE_CPU); err == nil {
+		capacity.CPUs = propertyFloat(prop)
+	}
+
+	msdefs, err := FindMicroserviceDefs(db, []MSFilter{UnarchivedMSFilter()})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the service definitions, error %v", err)
+	}
+	for _, msdef := range msdefs {
+		deployment, _ := msdef.GetDeployment()
+		if deployment == "" {
+			continue
+		}
+		deploymentDesc, err := containermessage.GetNativeDeployment(deployment)
+		if err != nil {
+			continue
+		}
+		memoryMb, cpus := deploymentDesc.ResourceLimits()
+		if memoryMb == 0 && cpus == 0 {
+			continue
+		}
+
+		msinsts, err := GetAllMicroserviceInstancesWithDefId(db, msdef.Id, false, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, msi := range msinsts {
+			if msi.GetCleanupStartTime() == 0 {
+				capacity.CommittedMemoryMb += memoryMb
+				capacity.CommittedCPUs += cpus
+			}
+		}
+	}
+
+	notStarted := func() EAFilter {
+		return func(a EstablishedAgreement) bool {
+			return a.AgreementTerminatedTime == 0 && a.ServiceDefId == ""
+		}
+	}
+	ags, err := FindEstablishedAgreementsAllProtocols(db, policy.AllAgreementProtocols(), []EAFilter{notStarted(), UnarchivedEAFilter()})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the unarchived agreements, error %v", err)
+	}
+	for _, ag := range ags {
+		memoryMb, cpus := pendingLimits(ag)
+		capacity.CommittedMemoryMb += memoryMb
+		capacity.CommittedCPUs += cpus
+	}
+
+	return capacity, nil
+}
+
+// Returns the numeric value of a built-in property, or 0 if it is not a number.
+func propertyFloat(prop externalpolicy.Property) float64 {
+	switch v := prop.Value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	}
+	return 0
+}
@@ -17,6 +17,7 @@ type WorkloadStatus struct {
 	Containers     []ContainerStatus `json:"containerStatus"`
 	OperatorStatus interface{}       `json:"operatorStatus,omitempty"`
 	ConfigState    string            `json:"configState,omitempty"`
+	ImageDigests   map[string]string `json:"imageDigests,omitempty"`
 }
 
 type ContainerStatus struct {
@@ -24,6 +25,7 @@ type ContainerStatus struct {
 	Image   string `json:"image"`
 	Created int64  `json:"created"`
 	State   string `json:"state"`
+	Health  string `json:"health,omitempty"`
 }
 
 // FindNodeStatus returns the node status currently in the local db
@@ -0,0 +1,134 @@
+package persistence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/policy"
+)
+
+// The bucket name in the bolt DB. The offline bundles are local to the node, they are not synchronized with the exchange.
+const OFFLINE_BUNDLES = "offlinebundles"
+
+// An offline bundle that was side-loaded on the node. The image tarballs of the bundle are kept in the bundle store
+// directory of the node, the images are loaded into docker from there instead of being pulled from a registry.
+type OfflineBundle struct {
+	Id        string             `json:"id"`                  // the id of the bundle, formed from the org, url, version and arch of the service
+	Org       string             `json:"org"`                 // the org of the service
+	URL       string             `json:"url"`                 // the url of the service
+	Version   string             `json:"version"`             // the version of the service
+	Arch      string             `json:"arch"`                // the arch of the service
+	Images    map[string]string  `json:"images"`              // the image tarball files, keyed by the image reference used in the deployment string
+	Digests   map[string]string  `json:"digests,omitempty"`   // the repository digests of the images, keyed by the image reference used in the deployment string
+	UserInput []policy.UserInput `json:"userInput,omitempty"` // the user input that was delivered with the bundle
+	Source    string             `json:"source"`              // where the bundle was loaded from, a file upload or an MMS object
+	LoadTime  uint64             `json:"loadTime"`            // the time when the bundle was loaded
+}
+
+func (b OfflineBundle) String() string {
+	return fmt.Sprintf("Id: %v, Org: %v, URL: %v, Version: %v, Arch: %v, Images: %v, Digests: %v, UserInput: %v, Source: %v, LoadTime: %v",
+		b.Id, b.Org, b.URL, b.Version, b.Arch, b.Images, b.Digests, b.UserInput, b.Source, b.LoadTime)
+}
+
+// Returns all the offline bundles in the local database.
+func FindOfflineBundles(db *bolt.DB) ([]OfflineBundle, error) {
+
+	bundles := make([]OfflineBundle, 0)
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(OFFLINE_BUNDLES)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				var bundle OfflineBundle
+
+				if err := json.Unmarshal(v, &bundle); err != nil {
+					return fmt.Errorf("Unable to deserialize offline bundle record: %v", v)
+				}
+
+				bundles = append(bundles, bundle)
+				return nil
+			})
+		}
+
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return bundles, nil
+}
+
+// Returns the offline bundle with the given id, or nil if there is none.
+func FindOfflineBundle(db *bolt.DB, id string) (*OfflineBundle, error) {
+
+	var bundle *OfflineBundle
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(OFFLINE_BUNDLES)); b != nil {
+			if v := b.Get([]byte(id)); v != nil {
+				bundle = new(OfflineBundle)
+				if err := json.Unmarshal(v, bundle); err != nil {
+					return fmt.Errorf("Unable to deserialize offline bundle record: %v", v)
+				}
+			}
+		}
+
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return bundle, nil
+}
+
+// Returns the offline bundle of the given service version, or nil if there is none. The images of a bundle are only
+// used for the service that the bundle delivers.
+func FindServiceOfflineBundle(db *bolt.DB, org string, url string, version string) (*OfflineBundle, error) {
+	if bundles, err := FindOfflineBundles(db); err != nil {
+		return nil, err
+	} else {
+		for _, b := range bundles {
+			if b.Org == org && b.URL == url && b.Version == version {
+				return &b, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Save the offline bundle, a bundle with the same id is replaced.
+func SaveOfflineBundle(db *bolt.DB, bundle *OfflineBundle) error {
+	if bundle == nil || bundle.Id == "" {
+		return errors.New("Offline bundle must have an id")
+	}
+
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(OFFLINE_BUNDLES))
+		if err != nil {
+			return err
+		}
+
+		if serial, err := json.Marshal(bundle); err != nil {
+			return fmt.Errorf("Failed to serialize offline bundle: %v. Error: %v", bundle, err)
+		} else {
+			return b.Put([]byte(bundle.Id), serial)
+		}
+	})
+
+	return writeErr
+}
+
+// Remove the offline bundle with the given id from the local database.
+func DeleteOfflineBundle(db *bolt.DB, id string) error {
+
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(OFFLINE_BUNDLES)); b == nil {
+			return nil
+		} else if err := b.Delete([]byte(id)); err != nil {
+			return fmt.Errorf("Unable to delete offline bundle %v: %v", id, err)
+		}
+		return nil
+	})
+}
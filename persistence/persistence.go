@@ -85,7 +85,8 @@ type EstablishedAgreement struct {
 	BlockchainOrg                   string                   `json:"blockchain_org,omitempty"`        // the org of the blockchain instance
 	RunningWorkload                 WorkloadInfo             `json:"workload_to_run,omitempty"`       // For display purposes, a copy of the workload info that this agreement is managing. It should be the same info that is buried inside the proposal.
 	AgreementTimeout                uint64                   `json:"agreement_timeout"`
-	ServiceDefId                    string                   `json:"service_definition_id"` // stores the microservice definiton id
+	ServiceDefId                    string                   `json:"service_definition_id"`   // stores the microservice definiton id
+	ImageDigests                    map[string]string        `json:"image_digests,omitempty"` // the content digests of the images that the containers run, keyed by container name
 }
 
 func (c EstablishedAgreement) String() string {
@@ -119,13 +120,14 @@ func (c EstablishedAgreement) String() string {
 		"BlockchainOrg: %v, "+
 		"RunningWorkload: %v, "+
 		"AgreementTimeout: %v, "+
-		"ServiceDefId: %v",
+		"ServiceDefId: %v, "+
+		"ImageDigests: %v",
 		c.Name, c.DependentServices, c.Archived, c.CurrentAgreementId, c.ConsumerId, c.CounterPartyAddress, ServiceConfigNames(&c.CurrentDeployment),
 		"********", c.ProposalSig,
 		c.AgreementCreationTime, c.AgreementExecutionStartTime, c.AgreementAcceptedTime, c.AgreementBCUpdateAckTime, c.AgreementFinalizedTime,
 		c.AgreementDataReceivedTime, c.AgreementTerminatedTime, c.AgreementForceTerminatedTime, c.TerminatedReason, c.TerminatedDescription,
 		c.AgreementProtocol, c.ProtocolVersion, c.AgreementProtocolTerminatedTime, c.WorkloadTerminatedTime,
-		c.MeteringNotificationMsg, c.BlockchainType, c.BlockchainName, c.BlockchainOrg, c.RunningWorkload, c.AgreementTimeout, c.ServiceDefId)
+		c.MeteringNotificationMsg, c.BlockchainType, c.BlockchainName, c.BlockchainOrg, c.RunningWorkload, c.AgreementTimeout, c.ServiceDefId, c.ImageDigests)
 
 }
 
@@ -270,6 +272,10 @@ func (a *EstablishedAgreement) GetEnvVars() map[string]string {
 	return map[string]string{}
 }
 
+func (a *EstablishedAgreement) GetImageDigests() map[string]string {
+	return a.ImageDigests
+}
+
 func (a *EstablishedAgreement) GetAssociatedAgreements() []string {
 	return []string{a.CurrentAgreementId}
 }
@@ -484,6 +490,14 @@ func SetAgreementServiceDefId(db *bolt.DB, dbAgreementId string, protocol string
 	})
 }
 
+// set the content digests of the images that were pulled for the agreement
+func SetAgreementImageDigests(db *bolt.DB, dbAgreementId string, protocol string, digests map[string]string) (*EstablishedAgreement, error) {
+	return agreementStateUpdate(db, dbAgreementId, protocol, func(c EstablishedAgreement) *EstablishedAgreement {
+		c.ImageDigests = digests
+		return &c
+	})
+}
+
 func DeleteEstablishedAgreement(db *bolt.DB, agreementId string, protocol string) error {
 
 	if agreementId == "" {
@@ -614,6 +628,9 @@ func persistUpdatedAgreement(db *bolt.DB, dbAgreementId string, protocol string,
 				if mod.ServiceDefId == "" { // transition add microservice definition id
 					mod.ServiceDefId = update.ServiceDefId
 				}
+				if len(update.ImageDigests) != 0 { // the images are pulled again when the containers are restarted
+					mod.ImageDigests = update.ImageDigests
+				}
 
 				if serialized, err := json.Marshal(mod); err != nil {
 					return fmt.Errorf("Failed to serialize contract record: %v. Error: %v", mod, err)
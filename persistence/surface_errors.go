@@ -119,6 +119,7 @@ func NewErrorLog(db *bolt.DB, eventLog EventLog) bool {
 func getErrorTypeList() []string {
 	return []string{
 		EC_ERROR_IMAGE_LOADE,
+		EC_ERROR_IMAGE_SIGNATURE,
 		EC_ERROR_IN_DEPLOYMENT_CONFIG,
 		EC_ERROR_START_CONTAINER,
 		EC_CANCEL_AGREEMENT_EXECUTION_TIMEOUT,
@@ -127,6 +128,8 @@ func getErrorTypeList() []string {
 		EC_ERROR_START_SERVICE,
 		EC_ERROR_START_DEPENDENT_SERVICE,
 		EC_DEPENDENT_SERVICE_FAILED,
+		EC_ADMISSION_POLICY_REJECT,
+		EC_NODE_CAPACITY_REJECT,
 	}
 
 }
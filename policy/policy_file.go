@@ -70,8 +70,9 @@ type Policy struct {
 	HAGroup            HighAvailabilityGroup               `json:"ha_group,omitempty"`         // Version 2.0
 	NodeH              NodeHealth                          `json:"nodeHealth,omitempty"`       // Version 2.0
 	UserInput          []UserInput                         `json:"userInput,omitempty"`
-	SecretBinding      []exchangecommon.SecretBinding      `json:"secretBinding,omitempty"` // This structure has the servive secret name to secret provider name mappings
-	SecretDetails      []exchangecommon.SecretBinding      `json:"secretDetails,omitempty"` // This structure has the service secret name to secret details mappings
+	SecretBinding      []exchangecommon.SecretBinding      `json:"secretBinding,omitempty"`   // This structure has the servive secret name to secret provider name mappings
+	SecretDetails      []exchangecommon.SecretBinding      `json:"secretDetails,omitempty"`   // This structure has the service secret name to secret details mappings
+	PinImageDigests    bool                                `json:"pinImageDigests,omitempty"` // All the nodes run the image digests that the first node resolved for a service version
 }
 
 // These functions are used to create Policy objects. You can create the base object
@@ -113,6 +114,7 @@ func (self *Policy) DeepCopy() *Policy {
 	newPolicy.HAGroup = HighAvailabilityGroup{Partners: make([]string, len(self.HAGroup.Partners))}
 	copy(newPolicy.HAGroup.Partners, self.HAGroup.Partners)
 	newPolicy.NodeH = self.NodeH
+	newPolicy.PinImageDigests = self.PinImageDigests
 
 	for _, ui := range self.UserInput {
 		newUI := ui
@@ -521,6 +523,7 @@ func (self *Policy) String() string {
 	res += fmt.Sprintf("Data Verification: %v\n", self.DataVerify)
 	res += fmt.Sprintf("Node Health: %v\n", self.NodeH)
 	res += fmt.Sprintf("SecretBinding: %v\n", self.SecretBinding)
+	res += fmt.Sprintf("PinImageDigests: %v\n", self.PinImageDigests)
 
 	return res
 }
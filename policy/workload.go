@@ -68,6 +68,8 @@ type Workload struct {
 	Arch                         string           `json:"arch,omitempty"`                           // Added with MS split, refers to the hardware architecture of the workload definition
 	DeploymentOverrides          string           `json:"deployment_overrides,omitempty"`           // Added with MS split, env var overrides for the workload
 	DeploymentOverridesSignature string           `json:"deployment_overrides_signature,omitempty"` // Added with MS split, signature of env var overrides
+
+	ImageDigests map[string]string `json:"image_digests,omitempty"` // The image digests, keyed by container name, that the node must pull instead of the image tags
 }
 
 func (w Workload) String() string {
@@ -83,10 +85,11 @@ func (w Workload) String() string {
 		"Version: %v, "+
 		"Arch: %v, "+
 		"Deployment Overrides: %v, "+
-		"Deployment Overrides Signature: %v",
+		"Deployment Overrides Signature: %v, "+
+		"Image Digests: %v",
 		w.Priority, w.Deployment, w.DeploymentSignature, w.DeploymentUserInfo, w.WorkloadPassword,
 		w.ClusterDeployment, w.ClusterDeploymentSignature,
-		w.WorkloadURL, w.Org, w.Version, w.Arch, w.DeploymentOverrides, w.DeploymentOverridesSignature)
+		w.WorkloadURL, w.Org, w.Version, w.Arch, w.DeploymentOverrides, w.DeploymentOverridesSignature, w.ImageDigests)
 }
 
 func (w Workload) ShortString() string {
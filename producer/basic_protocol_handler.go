@@ -106,6 +106,20 @@ func (c *BasicProtocolHandler) VerifyAgreement(ag *persistence.EstablishedAgreem
 	}
 }
 
+// Send the image digests that were resolved for the service of the agreement to the agbot, so that it can pin them
+// for the other nodes that run the same service version.
+func (c *BasicProtocolHandler) SendImageDigests(ag *persistence.EstablishedAgreement, version string, arch string, digests map[string]string) error {
+	update := basicprotocol.ImageDigestsUpdate{Version: version, Arch: arch, Digests: digests}
+	if _, pubkey, err := c.BaseProducerProtocolHandler.GetAgbotMessageEndpoint(ag.ConsumerId); err != nil {
+		return errors.New(BPHlogString(fmt.Sprintf("error getting agbot message target: %v", err)))
+	} else if mt, err := exchange.CreateMessageTarget(ag.ConsumerId, nil, pubkey, ""); err != nil {
+		return errors.New(BPHlogString(fmt.Sprintf("error creating message target: %v", err)))
+	} else if err := c.agreementPH.UpdateAgreement(ag.CurrentAgreementId, basicprotocol.MsgUpdateTypeImageDigests, update, mt, c.GetSendMessage()); err != nil {
+		return errors.New(BPHlogString(fmt.Sprintf("error sending image digests %v for agreement %v, error %v", update, ag.CurrentAgreementId, err)))
+	}
+	return nil
+}
+
 // Returns 2 booleans, first is whether or not the message was handled, the second is whether or not to cancel the agreement in the protocol msg.
 func (c *BasicProtocolHandler) HandleExtensionMessages(msg *events.ExchangeDeviceMessage, exchangeMsg *exchange.DeviceMessage) (bool, bool, string, error) {
 
@@ -192,7 +206,7 @@ func (c *BasicProtocolHandler) HandleExtensionMessages(msg *events.ExchangeDevic
 
 	} else if reply, err := c.agreementPH.ValidateUpdateReply(msg.ProtocolMessage()); err == nil {
 		glog.Infof(BPHlogString(fmt.Sprintf("nothing to do for update reply %v", reply)))
-		return true, false, update.AgreementId(), nil
+		return true, false, reply.AgreementId(), nil
 
 	} else {
 
@@ -9,6 +9,7 @@ import (
 	"github.com/open-horizon/anax/abstractprotocol"
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/containermessage"
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
@@ -28,6 +29,8 @@ const (
 	EL_PROD_NODE_REJECTED_PROPOSAL_MSG = "Node received Proposal message using agreement %v for service %v/%v from the agbot %v."
 	EL_PROD_NODE_REJECTED_PROPOSAL     = "Node rejected the proposal for service %v/%v."
 	EL_PROD_ERR_HANDLE_PROPOSAL        = "Error handling proposal for service %v/%v. Error: %v"
+	EL_PROD_ADMISSION_REJECTED         = "Node admission policy rejected the proposal for service %v/%v. %v"
+	EL_PROD_CAPACITY_REJECTED          = "Node rejected the proposal for service %v/%v because it does not have the capacity. %v"
 )
 
 // This is does nothing useful at run time.
@@ -44,6 +47,8 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_PROD_NODE_REJECTED_PROPOSAL_MSG)
 	msgPrinter.Sprintf(EL_PROD_NODE_REJECTED_PROPOSAL)
 	msgPrinter.Sprintf(EL_PROD_ERR_HANDLE_PROPOSAL)
+	msgPrinter.Sprintf(EL_PROD_ADMISSION_REJECTED)
+	msgPrinter.Sprintf(EL_PROD_CAPACITY_REJECTED)
 }
 
 func CreateProducerPH(name string, cfg *config.HorizonConfig, db *bolt.DB, pm *policy.PolicyManager, ec exchange.ExchangeContext) ProducerProtocolHandler {
@@ -75,6 +80,7 @@ type ProducerProtocolHandler interface {
 	UpdateConsumers()
 	GetKnownBlockchain(ag *persistence.EstablishedAgreement) (string, string, string)
 	VerifyAgreement(ag *persistence.EstablishedAgreement) (bool, error)
+	SendImageDigests(ag *persistence.EstablishedAgreement, version string, arch string, digests map[string]string) error
 }
 
 type BaseProducerProtocolHandler struct {
@@ -300,6 +306,39 @@ func (w *BaseProducerProtocolHandler) HandleProposal(ph abstractprotocol.Protoco
 		} else if messageTarget, err := exchange.CreateMessageTarget(exchangeMsg.AgbotId, nil, exchangeMsg.AgbotPubKey, ""); err != nil {
 			glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("error creating message target: %v", err)))
 			err_log_event = fmt.Sprintf("Error creating message target: %v", err)
+		} else if err := w.AdmitWorkloads(tcPolicy); err != nil {
+			glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("proposal %v rejected by the node admission policy: %v", proposal.AgreementId(), err)))
+			eventlog.LogAgreementEvent2(
+				w.db,
+				persistence.SEVERITY_ERROR,
+				persistence.NewMessageMeta(EL_PROD_ADMISSION_REJECTED, worg, wls, err.Error()),
+				persistence.EC_ADMISSION_POLICY_REJECT,
+				proposal.AgreementId(),
+				persistence.WorkloadInfo{URL: wls, Org: worg, Version: wversion, Arch: warch},
+				ConvertToServiceSpecs(tcPolicy.APISpecs),
+				proposal.ConsumerId(),
+				proposal.Protocol())
+
+			// Reject the proposal so that the agbot does not have to wait for it to time out.
+			reply := abstractprotocol.NewProposalReply(ph.Name(), proposal.Version(), proposal.AgreementId(), w.ec.GetExchangeId())
+			abstractprotocol.SendResponse(ph, proposal, reply, exchange.GetOrg(w.ec.GetExchangeId()), err, messageTarget, w.sendMessage)
+			handled = true
+		} else if err := w.CheckCapacity(ph, tcPolicy); err != nil {
+			glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("proposal %v rejected because the node does not have the capacity: %v", proposal.AgreementId(), err)))
+			eventlog.LogAgreementEvent2(
+				w.db,
+				persistence.SEVERITY_ERROR,
+				persistence.NewMessageMeta(EL_PROD_CAPACITY_REJECTED, worg, wls, err.Error()),
+				persistence.EC_NODE_CAPACITY_REJECT,
+				proposal.AgreementId(),
+				persistence.WorkloadInfo{URL: wls, Org: worg, Version: wversion, Arch: warch},
+				ConvertToServiceSpecs(tcPolicy.APISpecs),
+				proposal.ConsumerId(),
+				proposal.Protocol())
+
+			reply := abstractprotocol.NewProposalReply(ph.Name(), proposal.Version(), proposal.AgreementId(), w.ec.GetExchangeId())
+			abstractprotocol.SendResponse(ph, proposal, reply, exchange.GetOrg(w.ec.GetExchangeId()), err, messageTarget, w.sendMessage)
+			handled = true
 		} else {
 			handled = true
 			producerPol, err := persistence.FindNodePolicy(w.db)
@@ -510,6 +549,88 @@ func (w *BaseProducerProtocolHandler) FindAgreementWithSameWorkload(ph abstractp
 	return nil, false, nil
 }
 
+// Check the deployments of the workloads in the TsAndCs against the node's admission policy. The dependent services are
+// not in the TsAndCs, their deployments are checked by the container worker before their containers are created.
+func (w *BaseProducerProtocolHandler) AdmitWorkloads(tcPolicy *policy.Policy) error {
+	admissionPol, err := persistence.FindNodeAdmissionPolicy(w.db)
+	if err != nil {
+		return fmt.Errorf("unable to read the admission policy, error %v", err)
+	} else if admissionPol == nil {
+		return nil
+	}
+
+	for _, wl := range tcPolicy.Workloads {
+		if wl.Deployment == "" {
+			continue
+		}
+
+		// Only the native deployments are subject to the admission policy.
+		deployment, err := containermessage.GetNativeDeployment(wl.Deployment)
+		if err != nil {
+			continue
+		}
+		if wl.DeploymentOverrides != "" {
+			overrides := new(containermessage.DeploymentDescription)
+			if err := json.Unmarshal([]byte(wl.DeploymentOverrides), overrides); err != nil {
+				return fmt.Errorf("unable to demarshal the deployment overrides of %v/%v, error %v", wl.Org, wl.WorkloadURL, err)
+			}
+			deployment.Overrides = overrides.Services
+		}
+
+		if err := admissionPol.Admit(deployment); err != nil {
+			return fmt.Errorf("the deployment of %v/%v is not admitted, %v", wl.Org, wl.WorkloadURL, err)
+		}
+	}
+	return nil
+}
+
+// Check that the memory and CPU limits of the workload in the TsAndCs fit in the capacity of the node that is not
+// committed to the services that run on the node, or to the agreements whose services are not started yet. The
+// dependent services of the workload are not in the TsAndCs, they are counted once they are started.
+func (w *BaseProducerProtocolHandler) CheckCapacity(ph abstractprotocol.ProtocolHandler, tcPolicy *policy.Policy) error {
+	if dev, err := persistence.FindExchangeDevice(w.db); err != nil {
+		return fmt.Errorf("unable to read the node, error %v", err)
+	} else if dev == nil || dev.IsEdgeCluster() {
+		return nil
+	}
+
+	memoryMb, cpus := workloadResourceLimits(tcPolicy)
+	if memoryMb == 0 && cpus == 0 {
+		return nil
+	}
+
+	// The limits of the agreements whose services are not started yet are in the TsAndCs of their proposal.
+	pendingLimits := func(ag persistence.EstablishedAgreement) (int64, float64) {
+		if proposal, err := ph.DemarshalProposal(ag.Proposal); err != nil {
+			glog.Warningf(BPPHlogString(w.Name(), fmt.Sprintf("error demarshalling agreement %v proposal: %v", ag.CurrentAgreementId, err)))
+		} else if agPolicy, err := policy.DemarshalPolicy(proposal.TsAndCs()); err != nil {
+			glog.Warningf(BPPHlogString(w.Name(), fmt.Sprintf("error demarshalling agreement %v TsAndCs: %v", ag.CurrentAgreementId, err)))
+		} else {
+			return workloadResourceLimits(agPolicy)
+		}
+		return 0, 0
+	}
+
+	capacity, err := persistence.FindNodeCapacity(w.db, pendingLimits)
+	if err != nil {
+		return fmt.Errorf("unable to compute the node capacity, error %v", err)
+	}
+
+	glog.V(5).Infof(BPPHlogString(w.Name(), fmt.Sprintf("node capacity %v, the proposal requests %v MB of memory and %v CPUs", capacity, memoryMb, cpus)))
+	return capacity.Admit(memoryMb, cpus)
+}
+
+// Returns the memory and CPU limits of the native deployment of the workload in the TsAndCs.
+func workloadResourceLimits(tcPolicy *policy.Policy) (int64, float64) {
+	if len(tcPolicy.Workloads) == 0 || tcPolicy.Workloads[0].Deployment == "" {
+		return 0, 0
+	} else if deployment, err := containermessage.GetNativeDeployment(tcPolicy.Workloads[0].Deployment); err != nil {
+		return 0, 0
+	} else {
+		return deployment.ResourceLimits()
+	}
+}
+
 func (w *BaseProducerProtocolHandler) PersistProposal(proposal abstractprotocol.Proposal, reply abstractprotocol.ProposalReply, tcPolicy *policy.Policy, protocolMsg string) {
 	if wi, err := persistence.NewWorkloadInfo(tcPolicy.Workloads[0].WorkloadURL, tcPolicy.Workloads[0].Org, tcPolicy.Workloads[0].Version, tcPolicy.Workloads[0].Arch); err != nil {
 		glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("error creating workload info object from %v, error: %v", tcPolicy.Workloads[0], err)))
@@ -614,6 +735,10 @@ func (b *BaseProducerProtocolHandler) UpdateConsumer(ag *persistence.Established
 
 func (b *BaseProducerProtocolHandler) UpdateConsumers() {}
 
+func (b *BaseProducerProtocolHandler) SendImageDigests(ag *persistence.EstablishedAgreement, version string, arch string, digests map[string]string) error {
+	return nil
+}
+
 func (c *BaseProducerProtocolHandler) SetBlockchainClientAvailable(cmd *BCInitializedCommand) {
 	return
 }
@@ -0,0 +1,244 @@
+package structlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The structured logging layer adds correlation fields to the log records written by the workers, so that a single
+// agreement (or service instance, or NMP) can be followed through all the workers that act on it. In text format, the
+// records are written through glog with the fields appended to the message. In json format, each record is written as
+// a single line JSON object so that it can be ingested and filtered by a log aggregator. Either way, the glog verbosity
+// still decides which records are written.
+//
+// Only the records written through a Logger are structured: the event and command dispatch records of every worker,
+// and the agreement, governance, container and node management call sites that use it. All other log lines are
+// written by glog directly, in text. See docs/structured_logging.md.
+
+const FORMAT_TEXT = "text"
+const FORMAT_JSON = "json"
+
+// The names of the correlation fields.
+const WORKER = "worker"
+const AGREEMENT_ID = "agreement_id"
+const AGREEMENT_PROTOCOL = "agreement_protocol"
+const SERVICE_INSTANCE = "service_instance"
+const SERVICE_URL = "service_url"
+const NMP_NAME = "nmp_name"
+const DEVICE_ID = "device_id"
+
+// The names of the fields that every json record has.
+const TIME = "time"
+const LEVEL = "level"
+const MESSAGE = "msg"
+
+const LEVEL_INFO = "info"
+const LEVEL_WARNING = "warning"
+const LEVEL_ERROR = "error"
+
+// The correlation fields of a log record.
+type Fields map[string]string
+
+// Events, commands and other objects that carry correlation ids implement this interface so that the logs written
+// about them can be correlated.
+type Correlated interface {
+	LogFields() Fields
+}
+
+// Returns the correlation fields of the input object, or nil if it does not carry any. A nil pointer to a
+// correlated type does not carry any fields.
+func FieldsOf(obj interface{}) Fields {
+	if c, ok := obj.(Correlated); ok {
+		if v := reflect.ValueOf(obj); v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil
+		}
+		return c.LogFields()
+	}
+	return nil
+}
+
+var lock sync.Mutex
+var format = FORMAT_TEXT
+var out io.Writer = os.Stderr
+
+// Set the format of the structured log records, either text or json.
+func SetFormat(f string) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	switch strings.ToLower(f) {
+	case "", FORMAT_TEXT:
+		format = FORMAT_TEXT
+	case FORMAT_JSON:
+		format = FORMAT_JSON
+	default:
+		return errors.New(fmt.Sprintf("log format %v is not supported, it must be %v or %v", f, FORMAT_TEXT, FORMAT_JSON))
+	}
+	return nil
+}
+
+// Returns the current format of the structured log records.
+func Format() string {
+	lock.Lock()
+	defer lock.Unlock()
+	return format
+}
+
+// Set the destination of the json records. The text records are always written through glog.
+func SetOutput(w io.Writer) {
+	lock.Lock()
+	defer lock.Unlock()
+	out = w
+}
+
+// A logger that writes records with a fixed set of correlation fields. Loggers are immutable, the With functions
+// return a new logger with the additional fields.
+type Logger struct {
+	worker string
+	fields Fields
+}
+
+func New(worker string) *Logger {
+	return &Logger{
+		worker: worker,
+		fields: Fields{},
+	}
+}
+
+// Returns a logger with the additional field. Empty values are ignored so that callers do not have to check
+// whether an id is known before adding it.
+func (l *Logger) With(key string, value string) *Logger {
+	if value == "" {
+		return l
+	}
+	return l.WithFields(Fields{key: value})
+}
+
+// Returns a logger with the additional fields.
+func (l *Logger) WithFields(fields Fields) *Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	nl := &Logger{
+		worker: l.worker,
+		fields: make(Fields, len(l.fields)+len(fields)),
+	}
+	for k, v := range l.fields {
+		nl.fields[k] = v
+	}
+	for k, v := range fields {
+		if v != "" {
+			nl.fields[k] = v
+		}
+	}
+	return nl
+}
+
+// Returns a logger with the correlation fields of the input event, command or other object.
+func (l *Logger) WithContext(obj interface{}) *Logger {
+	return l.WithFields(FieldsOf(obj))
+}
+
+// A logger that writes records only when the glog verbosity is high enough.
+type Verbose struct {
+	logger *Logger
+	on     bool
+}
+
+func (l *Logger) V(level glog.Level) Verbose {
+	return Verbose{logger: l, on: bool(glog.V(level))}
+}
+
+func (v Verbose) Infof(f string, args ...interface{}) {
+	if v.on {
+		v.logger.write(LEVEL_INFO, fmt.Sprintf(f, args...))
+	}
+}
+
+func (l *Logger) Infof(f string, args ...interface{}) {
+	l.write(LEVEL_INFO, fmt.Sprintf(f, args...))
+}
+
+func (l *Logger) Warningf(f string, args ...interface{}) {
+	l.write(LEVEL_WARNING, fmt.Sprintf(f, args...))
+}
+
+func (l *Logger) Errorf(f string, args ...interface{}) {
+	l.write(LEVEL_ERROR, fmt.Sprintf(f, args...))
+}
+
+func (l *Logger) write(level string, msg string) {
+
+	if Format() == FORMAT_JSON {
+		lock.Lock()
+		defer lock.Unlock()
+		if _, err := out.Write(l.record(level, msg, time.Now())); err != nil {
+			glog.Errorf("unable to write structured log record, error: %v", err)
+		}
+		return
+	}
+
+	// The depth skips this function and the exported logging function that called it, so that glog reports the
+	// caller's file and line.
+	text := l.text(msg)
+	switch level {
+	case LEVEL_ERROR:
+		glog.ErrorDepth(2, text)
+	case LEVEL_WARNING:
+		glog.WarningDepth(2, text)
+	default:
+		glog.InfoDepth(2, text)
+	}
+}
+
+// Returns the text form of the record, the worker name, the message and the sorted fields.
+func (l *Logger) text(msg string) string {
+	res := msg
+	if l.worker != "" {
+		res = fmt.Sprintf("%v: %v", l.worker, msg)
+	}
+	if len(l.fields) != 0 {
+		pairs := make([]string, 0, len(l.fields))
+		for _, k := range l.sortedKeys() {
+			pairs = append(pairs, fmt.Sprintf("%v=%v", k, l.fields[k]))
+		}
+		res = fmt.Sprintf("%v (%v)", res, strings.Join(pairs, ", "))
+	}
+	return res
+}
+
+// Returns the json form of the record, terminated by a newline.
+func (l *Logger) record(level string, msg string, t time.Time) []byte {
+	rec := make(map[string]string, len(l.fields)+4)
+	for k, v := range l.fields {
+		rec[k] = v
+	}
+	rec[TIME] = t.UTC().Format(time.RFC3339Nano)
+	rec[LEVEL] = level
+	if l.worker != "" {
+		rec[WORKER] = l.worker
+	}
+	rec[MESSAGE] = msg
+
+	// A map of strings always marshals, and the keys are written in sorted order.
+	bytes, _ := json.Marshal(rec)
+	return append(bytes, '\n')
+}
+
+func (l *Logger) sortedKeys() []string {
+	keys := make([]string, 0, len(l.fields))
+	for k := range l.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
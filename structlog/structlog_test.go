@@ -0,0 +1,80 @@
+//go:build unit
+// +build unit
+
+package structlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type testCommand struct {
+	AgreementId string
+}
+
+func (c *testCommand) LogFields() Fields {
+	return Fields{AGREEMENT_ID: c.AgreementId}
+}
+
+func Test_FieldsOf(t *testing.T) {
+
+	if f := FieldsOf(&testCommand{AgreementId: "ag1"}); f[AGREEMENT_ID] != "ag1" {
+		t.Errorf("wrong fields: %v", f)
+	}
+
+	var nilCmd *testCommand
+	if f := FieldsOf(nilCmd); f != nil {
+		t.Errorf("a nil command should not have fields: %v", f)
+	} else if f := FieldsOf("not correlated"); f != nil {
+		t.Errorf("a string should not have fields: %v", f)
+	} else if f := FieldsOf(nil); f != nil {
+		t.Errorf("nil should not have fields: %v", f)
+	}
+}
+
+func Test_Logger_With(t *testing.T) {
+
+	l := New("Governance")
+	l1 := l.With(AGREEMENT_ID, "ag1").With(SERVICE_INSTANCE, "")
+	l2 := l1.WithContext(&testCommand{AgreementId: "ag2"})
+
+	if len(l.fields) != 0 {
+		t.Errorf("the original logger should not be changed: %v", l.fields)
+	} else if len(l1.fields) != 1 || l1.fields[AGREEMENT_ID] != "ag1" {
+		t.Errorf("wrong fields, empty values should be ignored: %v", l1.fields)
+	} else if l2.fields[AGREEMENT_ID] != "ag2" {
+		t.Errorf("the context should override the fields: %v", l2.fields)
+	}
+
+	if txt := l1.With(NMP_NAME, "nmp1").text("hello"); txt != "Governance: hello (agreement_id=ag1, nmp_name=nmp1)" {
+		t.Errorf("wrong text record: %v", txt)
+	}
+}
+
+func Test_Logger_JSON(t *testing.T) {
+
+	if err := SetFormat("yaml"); err == nil {
+		t.Errorf("yaml should not be a supported format")
+	} else if err := SetFormat(FORMAT_JSON); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	defer SetFormat(FORMAT_TEXT)
+
+	buf := new(bytes.Buffer)
+	SetOutput(buf)
+
+	l := New("Container").With(AGREEMENT_ID, "ag1")
+	l.Errorf("failed %v", 1)
+	l.V(100).Infof("should not be written")
+
+	rec := make(map[string]string)
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Errorf("the output should be a single json record: %v, error: %v", buf.String(), err)
+	} else if rec[WORKER] != "Container" || rec[AGREEMENT_ID] != "ag1" || rec[LEVEL] != LEVEL_ERROR || rec[MESSAGE] != "failed 1" {
+		t.Errorf("wrong json record: %v", rec)
+	} else if _, err := time.Parse(time.RFC3339Nano, rec[TIME]); err != nil {
+		t.Errorf("wrong time in the json record: %v", rec)
+	}
+}
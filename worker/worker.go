@@ -5,6 +5,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/structlog"
 	"runtime"
 	"time"
 )
@@ -215,6 +216,12 @@ func (w *BaseWorker) GetName() string {
 	return w.Name
 }
 
+// Returns a structured logger for this worker, with the correlation fields of the input event or command (if any),
+// so that the work done for an agreement, service instance or NMP can be traced across workers.
+func (w *BaseWorker) Logger(obj interface{}) *structlog.Logger {
+	return structlog.New(w.GetName()).WithContext(obj)
+}
+
 func (w *BaseWorker) SetWorkerShuttingDown(retries int, interval int) {
 	w.ShuttingDown = true
 	if retries != 0 {
@@ -293,7 +300,7 @@ func (w *BaseWorker) HandleFrameworkCommands(command Command) (bool, bool) {
 
 // This function handles commands for the worker. Returns true when the worker should terminate.
 func (w *BaseWorker) internalCommandhandler(worker Worker, command Command) bool {
-	glog.V(2).Infof(cdLogString(fmt.Sprintf("%v received command (%T): %v", w.GetName(), command, command.ShortString())))
+	w.Logger(command).V(2).Infof("%s", cdLogString(fmt.Sprintf("received command (%T): %v", command, command.ShortString())))
 	glog.V(5).Infof(cdLogString(fmt.Sprintf("%v received command: %v", w.GetName(), command)))
 
 	// Let the framework handle the command first
@@ -307,7 +314,7 @@ func (w *BaseWorker) internalCommandhandler(worker Worker, command Command) bool
 	if handled := worker.CommandHandler(command); !handled {
 		glog.Errorf(cdLogString(fmt.Sprintf("%v received unknown command (%T): %v", w.GetName(), command, command)))
 	} else {
-		glog.V(2).Infof(cdLogString(fmt.Sprintf("%v handled command (%T)", w.GetName(), command)))
+		w.Logger(command).V(2).Infof("%s", cdLogString(fmt.Sprintf("handled command (%T)", command)))
 	}
 	return false
 }
@@ -582,7 +589,7 @@ func (workers *MessageHandlerRegistry) ProcessEventMessages() {
 		for !done {
 			select {
 			case msg := <-messageStream:
-				glog.V(3).Infof(mdLogString(fmt.Sprintf("Handling Message (%T): %v\n", msg, msg.ShortString())))
+				dispatchLogger.WithContext(msg).V(3).Infof("Handling Message (%T): %v", msg, msg.ShortString())
 				glog.V(5).Infof(mdLogString(fmt.Sprintf("Handling Message (%T): %v\n", msg, msg)))
 
 				// Push outbound messages into each worker.
@@ -609,6 +616,8 @@ func (workers *MessageHandlerRegistry) ProcessEventMessages() {
 	time.Sleep(6 * time.Second)
 }
 
+var dispatchLogger = structlog.New("MessageDispatcher")
+
 var mdLogString = func(v interface{}) string {
 	return fmt.Sprintf("MessageDispatcher: %v", v)
 }